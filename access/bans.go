@@ -0,0 +1,265 @@
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("access")
+
+const (
+	bansFile = "access/bans.json" //设置了有效期的allow、deny规则
+	interval = time.Minute        //检查过期规则的间隔
+)
+
+type Ban struct {
+	Domain  string    `json:"domain,omitempty"`
+	Address string    `json:"address"`
+	Expires time.Time `json:"expires"`
+	Reason  string    `json:"reason,omitempty"` //自动封禁的原因
+}
+
+func key(domain, address string) string {
+	return domain + "/" + address
+}
+
+//有效期管理：定时删除过期的allow、deny规则，有效期保存在存储中，重启后继续生效
+type Bans struct {
+	engine plugins.StorageEngine
+	client func() (*nginx.Client, error)
+	commit func(client *nginx.Client) error //测试、保存配置并重启nginx
+
+	lock   sync.Mutex
+	bans   map[string]*Ban
+	closeC chan struct{}
+}
+
+func New(engine plugins.StorageEngine, client func() (*nginx.Client, error), commit func(client *nginx.Client) error) *Bans {
+	b := &Bans{
+		engine: engine, client: client, commit: commit,
+		bans: make(map[string]*Ban), closeC: make(chan struct{}),
+	}
+	if err := b.load(); err != nil {
+		logger.WithError(err).Warn("load access bans")
+	}
+	return b
+}
+
+func (b *Bans) load() error {
+	file, err := b.engine.Get(bansFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	bans := make([]*Ban, 0)
+	if err = json.Unmarshal(file.Content, &bans); err != nil {
+		return err
+	}
+	for _, ban := range bans {
+		b.bans[key(ban.Domain, ban.Address)] = ban
+	}
+	return nil
+}
+
+func (b *Bans) list() []*Ban {
+	bans := make([]*Ban, 0, len(b.bans))
+	for _, ban := range b.bans {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Expires.Before(bans[j].Expires)
+	})
+	return bans
+}
+
+func (b *Bans) save() error {
+	content, err := json.MarshalIndent(b.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.engine.Put(bansFile, content)
+}
+
+//全部设置了有效期的规则，按照过期时间排序
+func (b *Bans) List() []*Ban {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	bans := make([]*Ban, 0, len(b.bans))
+	for _, ban := range b.list() {
+		copied := *ban
+		bans = append(bans, &copied)
+	}
+	return bans
+}
+
+//规则的过期时间，没有设置有效期时返回nil
+func (b *Bans) Expires(domain, address string) *time.Time {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if ban, has := b.bans[key(domain, address)]; has {
+		expires := ban.Expires
+		return &expires
+	}
+	return nil
+}
+
+//设置有效期，已经设置的规则将延长或者缩短有效期
+func (b *Bans) Add(domain string, ttl time.Duration, addresses ...string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	expires := time.Now().Add(ttl)
+	for _, address := range addresses {
+		b.bans[key(domain, address)] = &Ban{Domain: domain, Address: address, Expires: expires}
+	}
+	return b.save()
+}
+
+//取消有效期，规则被删除或者修改为永久规则时使用
+func (b *Bans) Remove(domain string, addresses ...string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	removed := false
+	for _, address := range addresses {
+		if _, has := b.bans[key(domain, address)]; has {
+			delete(b.bans, key(domain, address))
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return b.save()
+}
+
+//添加deny规则并设置有效期，测试、保存配置并重启nginx。已经存在永久规则（allow或者deny）的地址忽略
+func (b *Bans) Ban(domain string, ttl time.Duration, reason string, addresses ...string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool)
+	for _, rule := range client.AccessRules() {
+		if _, has := b.bans[key(rule.Domain, rule.Address)]; rule.Domain == domain && !has {
+			exists[rule.Address] = true
+		}
+	}
+	banned := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if !exists[address] {
+			banned = append(banned, address)
+		}
+	}
+	if addresses = banned; len(addresses) == 0 {
+		return nil
+	}
+	if err = client.AddAccessRules(domain, nginx.AccessDeny, addresses...); err != nil {
+		return err
+	}
+	if err = b.commit(client); err != nil {
+		return err
+	}
+	expires := time.Now().Add(ttl)
+	for _, address := range addresses {
+		logger.Info("ban ", domain, ": ", address, " ", reason)
+		b.bans[key(domain, address)] = &Ban{Domain: domain, Address: address, Expires: expires, Reason: reason}
+	}
+	return b.save()
+}
+
+//解除封禁：删除deny规则和有效期，规则已经被手动删除时只删除有效期。修改使用ctx绑定的存储，记录到解除封禁的请求中
+func (b *Bans) Lift(ctx context.Context, domain string, addresses ...string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, address := range addresses {
+		if _, has := b.bans[key(domain, address)]; !has {
+			return fmt.Errorf("%w: %s %s", os.ErrNotExist, domain, address)
+		}
+	}
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+	client.Engine = plugins.WithContext(ctx, client.Engine)
+	if err = client.RemoveAccessRules(domain, addresses...); err == nil {
+		if err = b.commit(client); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for _, address := range addresses {
+		delete(b.bans, key(domain, address))
+	}
+	return b.save()
+}
+
+//删除到期的规则
+func (b *Bans) Expire(now time.Time) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	expired := make(map[string][]string)
+	for _, ban := range b.bans {
+		if !ban.Expires.After(now) {
+			expired[ban.Domain] = append(expired[ban.Domain], ban.Address)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	client, err := b.client()
+	if err != nil {
+		return err
+	}
+	for domain, addresses := range expired {
+		//规则已经被手动删除时忽略
+		if err := client.RemoveAccessRules(domain, addresses...); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err = b.commit(client); err != nil {
+		return err
+	}
+	for domain, addresses := range expired {
+		for _, address := range addresses {
+			logger.Info("remove expired access rule ", domain, ": ", address)
+			delete(b.bans, key(domain, address))
+		}
+	}
+	return b.save()
+}
+
+func (b *Bans) Start() error {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.closeC:
+				return
+			case now := <-ticker.C:
+				if err := b.Expire(now); err != nil {
+					logger.WithError(err).Warn("remove expired access rules")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *Bans) Stop() error {
+	close(b.closeC)
+	return nil
+}
@@ -0,0 +1,95 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBansExpire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("http { deny 10.0.0.1; deny 10.0.0.2; deny 10.0.0.3; }"), 0644))
+	engine := file.New(conf)
+
+	newClient := func() (*nginx.Client, error) {
+		return nginx.NewClient("", engine, nil, nil)
+	}
+	commit := func(client *nginx.Client) error {
+		return client.Store()
+	}
+	addresses := func() []string {
+		client, err := newClient()
+		assert.Nil(t, err)
+		addresses := make([]string, 0)
+		for _, rule := range client.AccessRules() {
+			addresses = append(addresses, rule.Address)
+		}
+		return addresses
+	}
+
+	bans := New(engine, newClient, commit)
+	assert.Nil(t, bans.Add("", time.Minute, "10.0.0.1"))
+	assert.Nil(t, bans.Add("", time.Hour, "10.0.0.2", "10.0.0.3"))
+	//改为永久规则
+	assert.Nil(t, bans.Remove("", "10.0.0.3"))
+	assert.Nil(t, bans.Expires("", "10.0.0.3"))
+	assert.NotNil(t, bans.Expires("", "10.0.0.2"))
+
+	assert.Nil(t, bans.Expire(time.Now()))
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, addresses())
+
+	//重启后继续生效
+	bans = New(engine, newClient, commit)
+	assert.Len(t, bans.List(), 2)
+	assert.Nil(t, bans.Expire(time.Now().Add(time.Minute*2)))
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, addresses())
+	assert.Equal(t, "10.0.0.2", bans.List()[0].Address)
+
+	assert.Nil(t, bans.Expire(time.Now().Add(time.Hour*2)))
+	assert.Equal(t, []string{"10.0.0.3"}, addresses())
+	assert.Len(t, bans.List(), 0)
+}
+
+func TestBansBanAndLift(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("http { allow 10.0.0.1; }"), 0644))
+	engine := file.New(conf)
+
+	newClient := func() (*nginx.Client, error) {
+		return nginx.NewClient("", engine, nil, nil)
+	}
+	bans := New(engine, newClient, func(client *nginx.Client) error {
+		return client.Store()
+	})
+
+	//永久规则不修改
+	assert.Nil(t, bans.Ban("", time.Hour, "test", "10.0.0.1", "10.0.0.2"))
+	list := bans.List()
+	assert.Len(t, list, 1)
+	assert.Equal(t, "10.0.0.2", list[0].Address)
+	assert.Equal(t, "test", list[0].Reason)
+
+	client, err := newClient()
+	assert.Nil(t, err)
+	assert.Equal(t, []*nginx.AccessRule{{Action: "allow", Address: "10.0.0.1"}, {Action: "deny", Address: "10.0.0.2"}}, client.AccessRules())
+
+	assert.True(t, os.IsNotExist(errors.Unwrap(bans.Lift(context.Background(), "", "10.0.0.1"))))
+	assert.Nil(t, bans.Lift(context.Background(), "", "10.0.0.2"))
+	assert.Len(t, bans.List(), 0)
+	client, err = newClient()
+	assert.Nil(t, err)
+	assert.Len(t, client.AccessRules(), 1)
+}
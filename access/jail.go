@@ -0,0 +1,302 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/util"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const scanInterval = time.Second //读取访问日志的间隔
+
+//从访问日志中发现滥用的IP并自动封禁：findtime时间内状态码匹配的请求达到maxretry次时添加deny规则，bantime后自动删除
+type Jail struct {
+	Path     string        `json:"path"`             //访问日志，使用默认的combined格式（IP为第一列，状态码在请求之后）
+	Domain   string        `json:"domain,omitempty"` //deny规则添加到此域名的server，为空时添加到http中
+	Status   []string      `json:"status"`           //统计的状态码，例如：401, 403, 4xx
+	MaxRetry int           `json:"maxretry"`
+	FindTime time.Duration `json:"findtime"`
+	BanTime  time.Duration `json:"bantime"`
+	Ignore   []string      `json:"ignore,omitempty"` //不封禁的IP或者CIDR，127.0.0.1和::1总是忽略
+
+	ignores []*net.IPNet
+	hits    map[string][]time.Time
+
+	info    os.FileInfo
+	offset  int64
+	partial string
+}
+
+func positive(values url.Values, name string, def int) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i <= 0 {
+		return 0, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return i, nil
+}
+
+func duration(values url.Values, name string, def time.Duration) (time.Duration, error) {
+	value := values.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := util.ParseDuration(value)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return d, err
+}
+
+func split(value string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+//解析配置：/var/log/nginx/access.log?status=401,403&maxretry=10&findtime=1m&bantime=1h&domain=api.aginx.io&ignore=10.0.0.0/8
+func ParseJail(config string) (jail *Jail, err error) {
+	path, query := config, ""
+	if idx := strings.Index(config, "?"); idx != -1 {
+		path, query = config[:idx], config[idx+1:]
+	}
+	if path == "" {
+		return nil, errors.New("the access log of jail is empty: " + config)
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	jail = &Jail{Path: path, Domain: values.Get("domain"), Status: split(values.Get("status")), Ignore: split(values.Get("ignore"))}
+	if len(jail.Status) == 0 {
+		jail.Status = []string{"401", "403"}
+	}
+	for i, status := range jail.Status {
+		jail.Status[i] = strings.ToLower(status)
+		if len(status) != 3 || (!strings.HasSuffix(jail.Status[i], "xx") && !isNumber(status)) {
+			return nil, errors.New("invalid status: " + status + ", example: 401, 4xx")
+		}
+	}
+	for _, ignore := range append([]string{"127.0.0.1", "::1"}, jail.Ignore...) {
+		if !strings.Contains(ignore, "/") {
+			if ip := net.ParseIP(ignore); ip == nil {
+				return nil, errors.New("invalid ignore address: " + ignore)
+			} else if ip.To4() != nil {
+				ignore += "/32"
+			} else {
+				ignore += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(ignore)
+		if err != nil {
+			return nil, errors.New("invalid ignore address: " + ignore)
+		}
+		jail.ignores = append(jail.ignores, network)
+	}
+	if jail.MaxRetry, err = positive(values, "maxretry", 10); err != nil {
+		return
+	}
+	if jail.FindTime, err = duration(values, "findtime", time.Minute); err != nil {
+		return
+	}
+	jail.BanTime, err = duration(values, "bantime", time.Hour)
+	return
+}
+
+func ParseJails(configs []string) ([]*Jail, error) {
+	jails := make([]*Jail, 0, len(configs))
+	for _, config := range configs {
+		jail, err := ParseJail(config)
+		if err != nil {
+			return nil, err
+		}
+		jails = append(jails, jail)
+	}
+	return jails, nil
+}
+
+func isNumber(value string) bool {
+	_, err := strconv.Atoi(value)
+	return err == nil
+}
+
+func (j *Jail) matchStatus(status string) bool {
+	for _, s := range j.Status {
+		if s == status || (strings.HasSuffix(s, "xx") && len(status) == 3 && s[0] == status[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Jail) ignored(ip net.IP) bool {
+	for _, network := range j.ignores {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+//解析combined格式的日志：$remote_addr - $remote_user [$time_local] "$request" $status ...
+func parseLine(line string) (address, status string) {
+	fields := strings.SplitN(line, `"`, 3)
+	if len(fields) != 3 {
+		return "", ""
+	}
+	addresses, statuses := strings.Fields(fields[0]), strings.Fields(fields[2])
+	if len(addresses) == 0 || len(statuses) == 0 {
+		return "", ""
+	}
+	return addresses[0], statuses[0]
+}
+
+//统计日志行，返回达到封禁次数的IP
+func (j *Jail) scan(lines []string, now time.Time) []string {
+	if j.hits == nil {
+		j.hits = make(map[string][]time.Time)
+	}
+	addresses := make([]string, 0)
+	for _, line := range lines {
+		address, status := parseLine(line)
+		ip := net.ParseIP(address)
+		if ip == nil || !j.matchStatus(status) || j.ignored(ip) {
+			continue
+		}
+		address = ip.String()
+		hits := append(j.hits[address], now)
+		for len(hits) > 0 && now.Sub(hits[0]) >= j.FindTime {
+			hits = hits[1:]
+		}
+		if len(hits) >= j.MaxRetry {
+			delete(j.hits, address)
+			addresses = append(addresses, address)
+		} else {
+			j.hits[address] = hits
+		}
+	}
+	//删除超过统计时间的记录
+	for address, hits := range j.hits {
+		if now.Sub(hits[len(hits)-1]) >= j.FindTime {
+			delete(j.hits, address)
+		}
+	}
+	return addresses
+}
+
+//读取新增的日志行，日志被切割（文件改变或者变小）时从头读取新文件
+func (j *Jail) lines() ([]string, error) {
+	info, err := os.Stat(j.Path)
+	if err != nil {
+		return nil, err
+	}
+	if j.info != nil && (!os.SameFile(j.info, info) || info.Size() < j.offset) {
+		j.offset, j.partial = 0, ""
+	}
+	j.info = info
+	if info.Size() == j.offset {
+		return nil, nil
+	}
+	file, err := os.Open(j.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	if _, err = file.Seek(j.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(file, info.Size()-j.offset))
+	if err != nil {
+		return nil, err
+	}
+	j.offset += int64(len(content))
+	lines := strings.Split(j.partial+string(content), "\n")
+	j.partial = lines[len(lines)-1]
+	return lines[:len(lines)-1], nil
+}
+
+//从日志的末尾开始读取，不统计启动之前的日志
+func (j *Jail) seekEnd() {
+	if info, err := os.Stat(j.Path); err == nil {
+		j.info, j.offset = info, info.Size()
+	}
+}
+
+func (j *Jail) reason() string {
+	return fmt.Sprintf("%d times %s in %s, %s", j.MaxRetry, strings.Join(j.Status, ","), j.FindTime, j.Path)
+}
+
+//读取访问日志并自动封禁
+type Jails struct {
+	bans   *Bans
+	jails  []*Jail
+	closeC chan struct{}
+}
+
+func NewJails(bans *Bans, jails ...*Jail) *Jails {
+	return &Jails{bans: bans, jails: jails, closeC: make(chan struct{})}
+}
+
+func (js *Jails) List() []*Jail {
+	return js.jails
+}
+
+func (js *Jails) check(jail *Jail, now time.Time) error {
+	lines, err := jail.lines()
+	if err != nil {
+		return err
+	}
+	addresses := make([]string, 0)
+	for _, address := range jail.scan(lines, now) {
+		//已经封禁时忽略
+		if js.bans.Expires(jail.Domain, address) == nil {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+	return js.bans.Ban(jail.Domain, jail.BanTime, jail.reason(), addresses...)
+}
+
+func (js *Jails) Start() error {
+	for _, jail := range js.jails {
+		jail.seekEnd()
+	}
+	go func() {
+		ticker := time.NewTicker(scanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-js.closeC:
+				return
+			case now := <-ticker.C:
+				for _, jail := range js.jails {
+					if err := js.check(jail, now); err != nil && !os.IsNotExist(err) {
+						logger.WithError(err).Warn("jail ", jail.Path)
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (js *Jails) Stop() error {
+	close(js.closeC)
+	return nil
+}
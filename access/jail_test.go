@@ -0,0 +1,113 @@
+package access
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func logLine(address string, status int) string {
+	return fmt.Sprintf(`%s - - [01/Mar/2020:12:00:00 +0800] "GET /login?user=\x22admin\x22 HTTP/1.1" %d 153 "-" "curl/7.64.1"`+"\n", address, status)
+}
+
+func TestParseJail(t *testing.T) {
+	jail, err := ParseJail("/var/log/nginx/access.log")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"401", "403"}, jail.Status)
+	assert.Equal(t, 10, jail.MaxRetry)
+	assert.Equal(t, time.Minute, jail.FindTime)
+	assert.Equal(t, time.Hour, jail.BanTime)
+
+	jail, err = ParseJail("/var/log/nginx/access.log?status=401,4XX&maxretry=5&findtime=10m&bantime=1d&domain=api.aginx.io&ignore=10.0.0.0/8,192.168.1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"401", "4xx"}, jail.Status)
+	assert.Equal(t, "api.aginx.io", jail.Domain)
+	assert.Equal(t, time.Hour*24, jail.BanTime)
+	assert.True(t, jail.matchStatus("404"))
+	assert.False(t, jail.matchStatus("500"))
+
+	for _, config := range []string{"?status=401", "/access.log?status=40", "/access.log?maxretry=0", "/access.log?ignore=10.0.0"} {
+		_, err = ParseJail(config)
+		assert.NotNil(t, err, config)
+	}
+}
+
+func TestJailScan(t *testing.T) {
+	jail, err := ParseJail("access.log?status=401&maxretry=3&findtime=1m&ignore=10.0.0.0/8")
+	assert.Nil(t, err)
+	now := time.Now()
+	lines := func(address string, status, count int) []string {
+		out := make([]string, 0)
+		for i := 0; i < count; i++ {
+			out = append(out, logLine(address, status))
+		}
+		return out
+	}
+	assert.Len(t, jail.scan(lines("1.1.1.1", 401, 2), now), 0)
+	//超过统计时间重新计数
+	assert.Len(t, jail.scan(lines("1.1.1.1", 401, 2), now.Add(time.Minute)), 0)
+	assert.Equal(t, []string{"1.1.1.1"}, jail.scan(lines("1.1.1.1", 401, 1), now.Add(time.Minute)))
+
+	assert.Len(t, jail.scan(lines("1.1.1.2", 200, 5), now), 0)
+	assert.Len(t, jail.scan(lines("10.0.0.1", 401, 5), now), 0)
+	assert.Len(t, jail.scan(lines("127.0.0.1", 401, 5), now), 0)
+	assert.Len(t, jail.scan([]string{"invalid line", ""}, now), 0)
+}
+
+func TestJails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("http { }"), 0644))
+	accessLog := filepath.Join(dir, "access.log")
+	assert.Nil(t, ioutil.WriteFile(accessLog, []byte(logLine("1.1.1.1", 401)+logLine("1.1.1.1", 401)), 0644))
+	engine := file.New(conf)
+
+	newClient := func() (*nginx.Client, error) {
+		return nginx.NewClient("", engine, nil, nil)
+	}
+	bans := New(engine, newClient, func(client *nginx.Client) error {
+		return client.Store()
+	})
+	jail, err := ParseJail(accessLog + "?maxretry=2")
+	assert.Nil(t, err)
+	jails := NewJails(bans, jail)
+	//不统计启动之前的日志
+	jail.seekEnd()
+	now := time.Now()
+	assert.Nil(t, jails.check(jail, now))
+	assert.Len(t, bans.List(), 0)
+
+	appendLog := func(content string) {
+		f, err := os.OpenFile(accessLog, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.Nil(t, err)
+		_, _ = f.WriteString(content)
+		_ = f.Close()
+	}
+	//不完整的行等待下次读取
+	line := logLine("1.1.1.1", 403)
+	appendLog(logLine("1.1.1.1", 403) + line[:20])
+	assert.Nil(t, jails.check(jail, now))
+	assert.Len(t, bans.List(), 0)
+	appendLog(line[20:])
+	assert.Nil(t, jails.check(jail, now))
+	assert.Len(t, bans.List(), 1)
+	assert.NotNil(t, bans.Expires("", "1.1.1.1"))
+
+	//日志切割后从头读取
+	assert.Nil(t, os.Rename(accessLog, accessLog+".1"))
+	assert.Nil(t, ioutil.WriteFile(accessLog, []byte(logLine("2.2.2.2", 401)+logLine("2.2.2.2", 401)), 0644))
+	assert.Nil(t, jails.check(jail, now))
+	assert.Len(t, bans.List(), 2)
+
+	client, err := newClient()
+	assert.Nil(t, err)
+	assert.Len(t, client.AccessRules(), 2)
+}
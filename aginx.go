@@ -10,6 +10,7 @@ import (
 	"math/rand"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 )
 
@@ -35,11 +36,12 @@ var rootCmd = &cobra.Command{
 func init() {
 	cobra.OnInitialize(func() {
 		viper.SetEnvPrefix("AGINX")
+		viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 		viper.AutomaticEnv()
 	})
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "debug mode")
 	rootCmd.PersistentFlags().StringP("level", "l", "info", "log level")
-	rootCmd.AddCommand(cmd.ServerCmd, cmd.SyncCmd, cmd.RegistryCmd, cmd.ClientCmd)
+	rootCmd.AddCommand(cmd.ServerCmd, cmd.SyncCmd, cmd.RegistryCmd, cmd.DockerCmd, cmd.IngressCmd, cmd.ClientCmd, cmd.RollbackCmd, cmd.CertCmd, cmd.ImportCmd, cmd.BackupCmd, cmd.RestoreCmd, cmd.ShellCmd, cmd.LintCmd, cmd.FmtCmd, cmd.ConfigCmd, cmd.ServiceCmd)
 	_ = viper.BindPFlags(rootCmd.PersistentFlags())
 }
 
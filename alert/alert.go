@@ -0,0 +1,91 @@
+package alert
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/util"
+	"net/url"
+)
+
+var logger = logs.New("alert")
+
+//告警通知
+type Notifier interface {
+	Notify(event *util.Event) error
+}
+
+//http(s)://host/path 发送到webhook（slack+https、dingtalk+https 发送到slack、钉钉）, smtp://[user:password@]host:port?from=&to= 发送邮件
+func New(addresses []string) ([]Notifier, error) {
+	notifiers := make([]Notifier, 0)
+	for _, address := range addresses {
+		config, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		switch config.Scheme {
+		case "http", "https", "slack+http", "slack+https", "dingtalk+http", "dingtalk+https":
+			if notifier, err := newWebhook(config); err != nil {
+				return nil, err
+			} else {
+				notifiers = append(notifiers, notifier)
+			}
+		case "smtp", "smtps":
+			if notifier, err := newEmail(config); err != nil {
+				return nil, err
+			} else {
+				notifiers = append(notifiers, notifier)
+			}
+		default:
+			return nil, errors.New("alert not support: " + address)
+		}
+	}
+	return notifiers, nil
+}
+
+//是否需要告警：证书即将过期，证书更新失败
+func isAlert(event *util.Event) bool {
+	switch event.Name {
+	case util.EventCertExpiring:
+		return true
+	case util.EventCertRenewal:
+		return event.Error != ""
+	}
+	return false
+}
+
+//严重错误：证书告警，nginx重启失败，重启后恢复了上一次的配置。
+//配置测试失败（api校验配置）不是严重错误
+func isCritical(event *util.Event) bool {
+	switch event.Name {
+	case util.EventReload:
+		return event.Error != ""
+	case util.EventReloadRollback:
+		return true
+	}
+	return isAlert(event)
+}
+
+func subscribe(notifiers []Notifier, accept func(event *util.Event) bool) (unsubscribe func()) {
+	return util.SubscribeEvent(func(event *util.Event) {
+		if !accept(event) {
+			return
+		}
+		for _, notifier := range notifiers {
+			go func(notifier Notifier) {
+				if err := notifier.Notify(event); err != nil {
+					logger.WithError(err).Warn("send alert ", event.Name, " ", event.Message)
+				}
+			}(notifier)
+		}
+	})
+}
+
+//订阅证书事件发送告警（--ssl-alert），返回取消订阅的方法
+func Subscribe(notifiers []Notifier) (unsubscribe func()) {
+	return subscribe(notifiers, isAlert)
+}
+
+//订阅严重错误发送告警（--alert），返回取消订阅的方法
+func SubscribeCritical(notifiers []Notifier) (unsubscribe func()) {
+	return subscribe(notifiers, isCritical)
+}
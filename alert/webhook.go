@@ -0,0 +1,258 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatJson     = "json"
+	FormatSlack    = "slack"
+	FormatDingTalk = "dingtalk"
+)
+
+//配置文件修改的通知事件，data 为 util.ChangeEvent
+const EventConfigChange = "config-change"
+
+//webhook返回的错误，不需要重试
+type permanentError struct {
+	error
+}
+
+type webhook struct {
+	url     string
+	format  string
+	secret  string
+	retry   int
+	backoff time.Duration
+	events  []string
+	client  *http.Client
+}
+
+//[slack+|dingtalk+]http(s)://host/path[#secret=&retry=3&events=reload,config-change]
+//fragment中的参数不会发送到webhook
+func newWebhook(config *url.URL) (*webhook, error) {
+	wh := &webhook{format: FormatJson, retry: 3, backoff: time.Second, client: &http.Client{Timeout: time.Second * 10}}
+	if idx := strings.Index(config.Scheme, "+"); idx != -1 {
+		wh.format = config.Scheme[:idx]
+	}
+	if wh.format != FormatJson && wh.format != FormatSlack && wh.format != FormatDingTalk {
+		return nil, errors.New("webhook format not support: " + wh.format)
+	}
+
+	options, err := url.ParseQuery(config.Fragment)
+	if err != nil {
+		return nil, err
+	}
+	wh.secret = options.Get("secret")
+	if retry := options.Get("retry"); retry != "" {
+		if wh.retry, err = strconv.Atoi(retry); err != nil || wh.retry < 0 {
+			return nil, fmt.Errorf("invalid webhook retry: %s", retry)
+		}
+	}
+	for _, event := range strings.Split(options.Get("events"), ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			wh.events = append(wh.events, event)
+		}
+	}
+
+	address := *config
+	address.Scheme = strings.TrimPrefix(config.Scheme, wh.format+"+")
+	address.Fragment = ""
+	wh.url = address.String()
+	return wh, nil
+}
+
+//是否通知事件，没有配置events时通知：重启失败，证书续期，服务发现变更和配置修改
+func (wh *webhook) accept(event *util.Event) bool {
+	if len(wh.events) > 0 {
+		for _, name := range wh.events {
+			if name == "*" || name == event.Name {
+				return true
+			}
+		}
+		return false
+	}
+	switch event.Name {
+	case util.EventReload:
+		return event.Error != ""
+	case util.EventTestFailure, util.EventReloadRollback, util.EventCertRenewal,
+		util.EventUpstreamChange, EventConfigChange:
+		return true
+	}
+	return false
+}
+
+//发送失败（网络错误、5xx、429）时重试，每次间隔时间翻倍
+func (wh *webhook) Notify(event *util.Event) (err error) {
+	backoff := wh.backoff
+	for i := 0; ; i++ {
+		if err = wh.send(event); err == nil {
+			return
+		}
+		if _, ok := err.(*permanentError); ok || i >= wh.retry {
+			return
+		}
+		logger.WithError(err).Debug("retry webhook ", wh.url, " after ", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (wh *webhook) text(event *util.Event) string {
+	text := fmt.Sprintf("[AGINX] %s: %s", event.Name, event.Message)
+	if event.Error != "" {
+		text += "\nerror: " + event.Error
+	}
+	return text
+}
+
+func (wh *webhook) payload(event *util.Event) ([]byte, error) {
+	switch wh.format {
+	case FormatSlack:
+		return json.Marshal(map[string]interface{}{"text": wh.text(event)})
+	case FormatDingTalk:
+		return json.Marshal(map[string]interface{}{
+			"msgtype": "text", "text": map[string]string{"content": wh.text(event)},
+		})
+	default:
+		return json.Marshal(event)
+	}
+}
+
+func (wh *webhook) request(body []byte, now time.Time) (*http.Request, error) {
+	address := wh.url
+	//钉钉加签：timestamp + "\n" + secret 的 HmacSHA256 签名放在url参数中
+	if wh.format == FormatDingTalk && wh.secret != "" {
+		timestamp := strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+		mac := hmac.New(sha256.New, []byte(wh.secret))
+		mac.Write([]byte(timestamp + "\n" + wh.secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		separator := "?"
+		if strings.Contains(address, "?") {
+			separator = "&"
+		}
+		address += separator + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "aginx")
+	//签名：timestamp + "." + body 的 HmacSHA256
+	if wh.format != FormatDingTalk && wh.secret != "" {
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(wh.secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req.Header.Set("X-Aginx-Timestamp", timestamp)
+		req.Header.Set("X-Aginx-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return req, nil
+}
+
+func (wh *webhook) send(event *util.Event) error {
+	body, err := wh.payload(event)
+	if err != nil {
+		return &permanentError{err}
+	}
+	req, err := wh.request(body, time.Now())
+	if err != nil {
+		return &permanentError{err}
+	}
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		err = fmt.Errorf("webhook %s response %s", wh.url, resp.Status)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			err = &permanentError{err}
+		}
+		return err
+	}
+	//钉钉出错时也返回200，错误码在内容中
+	if wh.format == FormatDingTalk {
+		result := &struct {
+			Code    int    `json:"errcode"`
+			Message string `json:"errmsg"`
+		}{}
+		if bs, err := ioutil.ReadAll(resp.Body); err == nil && json.Unmarshal(bs, result) == nil && result.Code != 0 {
+			return &permanentError{fmt.Errorf("dingtalk %d: %s", result.Code, result.Message)}
+		}
+	}
+	return nil
+}
+
+//转换配置修改事件，其他节点同步过来的修改不通知，由修改的节点通知
+func changeEvent(change *util.ChangeEvent) *util.Event {
+	if change.Source == util.ChangeSourceCluster {
+		return nil
+	}
+	message := change.Type + " " + change.File
+	if change.User != "" {
+		message += " by " + change.User
+	}
+	return &util.Event{Time: change.Time, Name: EventConfigChange, Message: message, Data: change}
+}
+
+//发送事件到webhook（--webhook），返回取消订阅的方法
+func SubscribeWebhooks(addresses []string) (unsubscribe func(), err error) {
+	webhooks := make([]*webhook, 0)
+	for _, address := range addresses {
+		config, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		if scheme := config.Scheme[strings.Index(config.Scheme, "+")+1:]; scheme != "http" && scheme != "https" {
+			return nil, errors.New("webhook not support: " + address)
+		}
+		wh, err := newWebhook(config)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+	if len(webhooks) == 0 {
+		return func() {}, nil
+	}
+
+	notify := func(event *util.Event) {
+		for _, wh := range webhooks {
+			if !wh.accept(event) {
+				continue
+			}
+			go func(wh *webhook) {
+				if err := wh.Notify(event); err != nil {
+					logger.WithError(err).Warn("send webhook ", event.Name, " ", event.Message)
+				}
+			}(wh)
+		}
+	}
+	unsubscribeEvent := util.SubscribeEvent(notify)
+	unsubscribeChanged := util.SubscribeChanged(func(change *util.ChangeEvent) {
+		if event := changeEvent(change); event != nil {
+			notify(event)
+		}
+	})
+	return func() {
+		unsubscribeEvent()
+		unsubscribeChanged()
+	}, nil
+}
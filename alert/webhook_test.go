@@ -0,0 +1,123 @@
+package alert
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/ihaiker/aginx/util"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewWebhook(t *testing.T) {
+	config, _ := url.Parse("dingtalk+https://oapi.dingtalk.com/robot/send?access_token=abc#secret=SEC&retry=1&events=reload,config-change")
+	wh, err := newWebhook(config)
+	assert.Nil(t, err)
+	assert.Equal(t, FormatDingTalk, wh.format)
+	assert.Equal(t, "https://oapi.dingtalk.com/robot/send?access_token=abc", wh.url)
+	assert.Equal(t, "SEC", wh.secret)
+	assert.Equal(t, 1, wh.retry)
+	assert.Equal(t, []string{"reload", "config-change"}, wh.events)
+
+	assert.True(t, wh.accept(&util.Event{Name: util.EventReload}))
+	assert.False(t, wh.accept(&util.Event{Name: util.EventCertRenewal}))
+
+	req, err := wh.request([]byte("{}"), time.Unix(1600000000, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "1600000000000", req.URL.Query().Get("timestamp"))
+	assert.NotEmpty(t, req.URL.Query().Get("sign"))
+	assert.Equal(t, "abc", req.URL.Query().Get("access_token"))
+
+	config, _ = url.Parse("teams+https://example.com")
+	_, err = newWebhook(config)
+	assert.NotNil(t, err)
+}
+
+func TestWebhookAccept(t *testing.T) {
+	config, _ := url.Parse("https://hooks.example.com/aginx")
+	wh, err := newWebhook(config)
+	assert.Nil(t, err)
+	assert.True(t, wh.accept(&util.Event{Name: util.EventReload, Error: "nginx: [emerg]"}))
+	assert.False(t, wh.accept(&util.Event{Name: util.EventReload}))
+	assert.True(t, wh.accept(&util.Event{Name: util.EventCertRenewal}))
+	assert.True(t, wh.accept(&util.Event{Name: util.EventUpstreamChange}))
+	assert.True(t, wh.accept(&util.Event{Name: EventConfigChange}))
+	assert.False(t, wh.accept(&util.Event{Name: util.EventBackup}))
+
+	assert.Nil(t, changeEvent(&util.ChangeEvent{Source: util.ChangeSourceCluster, File: "nginx.conf"}))
+	event := changeEvent(&util.ChangeEvent{Source: util.ChangeSourceApi, Type: "update", File: "nginx.conf", User: "admin"})
+	assert.Equal(t, "update nginx.conf by admin", event.Message)
+}
+
+func TestWebhookSignAndRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("SEC"))
+		mac.Write([]byte(r.Header.Get("X-Aginx-Timestamp") + "."))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Aginx-Signature"))
+
+		payload := map[string]string{}
+		assert.Nil(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, "[AGINX] reload: reload NGINX\nerror: nginx: [emerg]", payload["text"])
+		if r.URL.Path == "/missing" {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		} else if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	config, _ := url.Parse("slack+" + server.URL + "#secret=SEC")
+	wh, err := newWebhook(config)
+	assert.Nil(t, err)
+	wh.backoff = time.Millisecond
+	event := &util.Event{Name: util.EventReload, Message: "reload NGINX", Error: "nginx: [emerg]"}
+	assert.Nil(t, wh.Notify(event))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	//4xx 不重试
+	config, _ = url.Parse("slack+" + server.URL + "/missing#secret=SEC")
+	wh, err = newWebhook(config)
+	assert.Nil(t, err)
+	atomic.StoreInt32(&calls, 0)
+	assert.NotNil(t, wh.Notify(event))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestSubscribeWebhooks(t *testing.T) {
+	events := make(chan *util.Event, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := new(util.Event)
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(event))
+		events <- event
+	}))
+	defer server.Close()
+
+	unsubscribe, err := SubscribeWebhooks([]string{server.URL + "#events=" + EventConfigChange})
+	assert.Nil(t, err)
+	defer unsubscribe()
+
+	util.PublishEvent(util.EventReload, "reload NGINX", errors.New("nginx: [emerg]"), nil)
+	util.PublishChanged(&util.ChangeEvent{Source: util.ChangeSourceApi, Type: "update", File: "nginx.conf"})
+	select {
+	case event := <-events:
+		assert.Equal(t, EventConfigChange, event.Name)
+		assert.Equal(t, "update nginx.conf", event.Message)
+	case <-time.After(time.Second * 3):
+		t.Fatal("webhook not called")
+	}
+
+	_, err = SubscribeWebhooks([]string{"smtp://smtp.example.com"})
+	assert.NotNil(t, err)
+}
@@ -3,8 +3,10 @@ package api
 import (
 	"crypto/tls"
 	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -30,6 +32,44 @@ func New(address string, maker ...func(client *http.Client)) *aginx {
 	}
 }
 
+//设置https使用的证书
+func WithTLS(tlsConfig *tls.Config) func(client *http.Client) {
+	return func(client *http.Client) {
+		if tp, match := client.Transport.(*BaseAuthTransport); match {
+			tp.Transport.TLSClientConfig = tlsConfig
+		}
+	}
+}
+
+//命令行使用的客户端，address为host:port，ca或者cert不为空时使用https，security格式为user:passwd
+func NewClient(address, security, ca, cert, key string) (Aginx, error) {
+	if !strings.Contains(address, "://") {
+		if ca != "" || cert != "" {
+			address = "https://" + address
+		} else {
+			address = "http://" + address
+		}
+	}
+	makers := make([]func(client *http.Client), 0)
+	if ca != "" || cert != "" {
+		tlsConfig, err := util.ClientTLSConfig(ca, cert, key)
+		if err != nil {
+			return nil, err
+		}
+		makers = append(makers, WithTLS(tlsConfig))
+	}
+	client := New(address, makers...)
+	//user:passwd 使用base auth，否则作为token
+	if security != "" {
+		if userAndPwd := strings.SplitN(security, ":", 2); len(userAndPwd) == 2 {
+			client.Auth(userAndPwd[0], userAndPwd[1])
+		} else {
+			client.Token(security)
+		}
+	}
+	return client, nil
+}
+
 func (self *aginx) Auth(name, password string) {
 	if tp, match := self.httpClient.Transport.(*BaseAuthTransport); match {
 		tp.Name = name
@@ -37,6 +77,12 @@ func (self *aginx) Auth(name, password string) {
 	}
 }
 
+func (self *aginx) Token(token string) {
+	if tp, match := self.httpClient.Transport.(*BaseAuthTransport); match {
+		tp.Token = token
+	}
+}
+
 func (self *aginx) Configuration() (*nginx.Configuration, error) {
 	if directives, err := self.Directive().Select(); err != nil {
 		return nil, err
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ihaiker/aginx/nginx"
+	"net/http"
+)
+
+type batch struct {
+	*client
+	operations []*nginx.Operation
+}
+
+func (self *aginx) Batch() AginxBatch {
+	return &batch{client: self.client, operations: make([]*nginx.Operation, 0)}
+}
+
+func (self *batch) Add(queries []string, addDirectives ...*nginx.Directive) AginxBatch {
+	self.operations = append(self.operations, &nginx.Operation{
+		Action: nginx.ActionAdd, Queries: queries, Directives: addDirectives,
+	})
+	return self
+}
+
+func (self *batch) Delete(queries ...string) AginxBatch {
+	self.operations = append(self.operations, &nginx.Operation{
+		Action: nginx.ActionDelete, Queries: queries,
+	})
+	return self
+}
+
+func (self *batch) Modify(queries []string, directive *nginx.Directive) AginxBatch {
+	self.operations = append(self.operations, &nginx.Operation{
+		Action: nginx.ActionModify, Queries: queries, Directives: []*nginx.Directive{directive},
+	})
+	return self
+}
+
+func (self *batch) Commit() error {
+	body, err := json.Marshal(self.operations)
+	if err != nil {
+		return err
+	}
+	return self.request(http.MethodPost, "/api/batch", bytes.NewBuffer(body), nil)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"github.com/ihaiker/aginx/nginx"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
@@ -59,3 +60,9 @@ func (a aginxFile) Search(relativePaths ...string) (files map[string]string, err
 	err = a.request(http.MethodGet, a.get("/file", relativePaths), nil, &files)
 	return
 }
+
+func (a aginxFile) Tree() (tree *nginx.IncludeFile, err error) {
+	tree = new(nginx.IncludeFile)
+	err = a.request(http.MethodGet, "/api/files", nil, tree)
+	return
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/history"
+	"net/http"
+)
+
+func (self *aginx) History() (versions []*history.Version, err error) {
+	versions = make([]*history.Version, 0)
+	err = self.request(http.MethodGet, "/api/history", nil, &versions)
+	return
+}
+
+func (self *aginx) Rollback(version int64) (versions []*history.Version, err error) {
+	versions = make([]*history.Version, 0)
+	err = self.request(http.MethodPost, fmt.Sprintf("/api/rollback?version=%d", version), nil, &versions)
+	return
+}
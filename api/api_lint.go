@@ -0,0 +1,17 @@
+package api
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"net/http"
+	"net/url"
+)
+
+func (self *aginx) Lint(skip ...string) (lints []*nginx.Lint, err error) {
+	uri := "/api/lint"
+	if len(skip) > 0 {
+		uri += "?" + url.Values{"skip": skip}.Encode()
+	}
+	lints = make([]*nginx.Lint, 0)
+	err = self.request(http.MethodGet, uri, nil, &lints)
+	return
+}
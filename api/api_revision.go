@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+//读取后配置被其他请求修改(409)，需要重新读取配置后再修改
+var ErrConflict = errors.New("the configuration has been modified by others")
+
+func (self *aginx) Revision() (string, error) {
+	return self.etag("/api/revision")
+}
+
+func (self *aginx) IfMatch(revision string) Aginx {
+	return &aginx{client: &client{
+		address: self.address, httpClient: self.httpClient, revision: revision,
+	}}
+}
+
+func (a aginxFile) Revision(relativePath string) (string, error) {
+	return a.etag("/api/files/" + relativePath)
+}
+
+//执行fn，返回ErrConflict时重新执行，最多执行attempts次。fn中需要重新读取版本和配置
+func Retry(attempts int, fn func() error) (err error) {
+	for i := 0; i < attempts; i++ {
+		if err = fn(); !errors.Is(err, ErrConflict) {
+			return
+		}
+		if i+1 < attempts {
+			time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
+		}
+	}
+	return
+}
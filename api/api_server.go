@@ -0,0 +1,43 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ihaiker/aginx/nginx"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type aginxServer struct {
+	*client
+}
+
+func (self *aginx) Server() AginxServer {
+	return &aginxServer{client: self.client}
+}
+
+func (self *aginxServer) List() (servers []*nginx.Server, err error) {
+	servers = make([]*nginx.Server, 0)
+	err = self.request(http.MethodGet, "/api/servers", nil, &servers)
+	return
+}
+
+func (self *aginxServer) Get(domain string) (servers []*nginx.Server, err error) {
+	servers = make([]*nginx.Server, 0)
+	err = self.request(http.MethodGet, "/api/servers/"+url.PathEscape(domain), nil, &servers)
+	return
+}
+
+func (self *aginxServer) New(server *nginx.Server, accountEmail string) error {
+	bs, err := json.Marshal(server)
+	if err != nil {
+		return err
+	}
+	uri := "/api/servers?email=" + url.QueryEscape(accountEmail)
+	return self.request(http.MethodPost, uri, bytes.NewBuffer(bs), nil, self.timeout(time.Second*7))
+}
+
+func (self *aginxServer) Delete(domain string) error {
+	return self.request(http.MethodDelete, "/api/servers/"+url.PathEscape(domain), nil, nil)
+}
@@ -25,3 +25,10 @@ func (a aginxSSL) ReNew(domain string) (sf *lego.StoreFile, err error) {
 	err = a.request(http.MethodPut, uri, nil, sf, a.timeout(time.Second*7))
 	return
 }
+
+func (a aginxSSL) SelfSigned(domain string, days int) (sf *lego.StoreFile, err error) {
+	uri := fmt.Sprintf("/api/certs/self-signed/%s?days=%d", domain, days)
+	sf = new(lego.StoreFile)
+	err = a.request(http.MethodPost, uri, nil, sf, a.timeout(time.Second*7))
+	return
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+func (self *aginx) IssueToken(role string, expire time.Duration) (result *TokenResult, err error) {
+	uri := "/api/token?role=" + role
+	if expire > 0 {
+		uri += "&expire=" + expire.String()
+	}
+	result = new(TokenResult)
+	err = self.request(http.MethodPost, uri, nil, result)
+	return
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ihaiker/aginx/nginx"
+	"net/http"
+	"net/url"
+)
+
+type aginxUpstream struct {
+	*client
+}
+
+func (self *aginx) Upstream() AginxUpstream {
+	return &aginxUpstream{client: self.client}
+}
+
+func (self *aginxUpstream) send(method, uri string, body interface{}) error {
+	bs, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return self.request(method, uri, bytes.NewBuffer(bs), nil)
+}
+
+func (self *aginxUpstream) List() (upstreams []*nginx.Upstream, err error) {
+	upstreams = make([]*nginx.Upstream, 0)
+	err = self.request(http.MethodGet, "/api/upstreams", nil, &upstreams)
+	return
+}
+
+func (self *aginxUpstream) Get(name string) (upstream *nginx.Upstream, err error) {
+	upstream = new(nginx.Upstream)
+	err = self.request(http.MethodGet, "/api/upstreams/"+url.PathEscape(name), nil, upstream)
+	return
+}
+
+func (self *aginxUpstream) New(upstream *nginx.Upstream) error {
+	return self.send(http.MethodPost, "/api/upstreams/"+url.PathEscape(upstream.Name), upstream)
+}
+
+func (self *aginxUpstream) Modify(upstream *nginx.Upstream) error {
+	return self.send(http.MethodPut, "/api/upstreams/"+url.PathEscape(upstream.Name), upstream)
+}
+
+func (self *aginxUpstream) Delete(name string) error {
+	return self.request(http.MethodDelete, "/api/upstreams/"+url.PathEscape(name), nil, nil)
+}
+
+func (self *aginxUpstream) SetServer(name string, server *nginx.UpstreamServer) error {
+	return self.send(http.MethodPut, "/api/upstreams/"+url.PathEscape(name)+"/servers/"+server.Address, server)
+}
+
+func (self *aginxUpstream) RemoveServer(name, address string) error {
+	return self.request(http.MethodDelete, "/api/upstreams/"+url.PathEscape(name)+"/servers/"+address, nil, nil)
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"bytes"
+	"github.com/ihaiker/aginx/nginx"
+	"net/http"
+)
+
+func (self *aginx) Validate(action string, queries []string, directives ...*nginx.Directive) (result *ValidateResult, err error) {
+	body := bytes.NewBufferString("")
+	for _, directive := range directives {
+		body.WriteString(directive.Pretty(0))
+		body.WriteString("\n")
+	}
+	uri := self.get("/api/validate", queries)
+	if len(queries) == 0 {
+		uri += "?action=" + action
+	} else {
+		uri += "&action=" + action
+	}
+	result = new(ValidateResult)
+	err = self.request(http.MethodPost, uri, body, result)
+	return
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/ihaiker/aginx/util"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (self *aginx) Watch(file string, closeC <-chan struct{}) (<-chan *util.ChangeEvent, error) {
+	uri := self.address + "/api/watch"
+	if file != "" {
+		uri += "?file=" + url.QueryEscape(file)
+	}
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	//长连接不能使用默认的超时时间
+	httpClient := &http.Client{Transport: self.httpClient.Transport}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, self.response(resp, nil)
+	}
+
+	events := make(chan *util.ChangeEvent)
+	go func() {
+		<-closeC
+		_ = resp.Body.Close()
+	}()
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			event := new(util.ChangeEvent)
+			if err := json.Unmarshal([]byte(strings.TrimSpace(line[5:])), event); err == nil {
+				select {
+				case events <- event:
+				case <-closeC:
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
@@ -5,11 +5,14 @@ import "net/http"
 type BaseAuthTransport struct {
 	Name     string
 	Password string
+	Token    string
 	*http.Transport
 }
 
 func (bat *BaseAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if bat.Name != "" {
+	if bat.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+bat.Token)
+	} else if bat.Name != "" {
 		req.SetBasicAuth(bat.Name, bat.Password)
 	}
 	return bat.Transport.RoundTrip(req)
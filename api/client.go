@@ -15,6 +15,7 @@ import (
 type client struct {
 	address    string
 	httpClient *http.Client
+	revision   string //修改时检查的版本(If-Match)
 }
 
 func (self *client) get(uri string, queries []string) string {
@@ -42,6 +43,9 @@ func (self *client) response(resp *http.Response, ret interface{}) error {
 				if errApi.Message == "file does not exist" {
 					return os.ErrNotExist
 				}
+				if resp.StatusCode == http.StatusConflict {
+					return ErrConflict
+				}
 				return errApi
 			}
 		}
@@ -61,6 +65,9 @@ func (self *client) request(method string, url string, body io.Reader, ret inter
 	if req, err := http.NewRequest(method, self.address+url, body); err != nil {
 		return err
 	} else {
+		if self.revision != "" && method != http.MethodGet {
+			req.Header.Set("If-Match", `"`+self.revision+`"`)
+		}
 		for _, extend := range extends {
 			extend(req)
 		}
@@ -71,3 +78,16 @@ func (self *client) request(method string, url string, body io.Reader, ret inter
 		}
 	}
 }
+
+//请求返回的版本(ETag)
+func (self *client) etag(url string) (string, error) {
+	resp, err := self.httpClient.Get(self.address + url)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", self.response(resp, nil)
+	}
+	_ = resp.Body.Close()
+	return strings.Trim(strings.TrimPrefix(resp.Header.Get("ETag"), "W/"), `"`), nil
+}
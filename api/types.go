@@ -1,8 +1,11 @@
 package api
 
 import (
+	"github.com/ihaiker/aginx/history"
 	"github.com/ihaiker/aginx/lego"
 	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"time"
 )
 
 func Queries(query ...string) []string {
@@ -18,6 +21,23 @@ func (err *ApiError) Error() string {
 	return err.Message
 }
 
+type TokenResult struct {
+	Token  string    `json:"token"`
+	Expire time.Time `json:"expire"`
+}
+
+type ValidateResult struct {
+	Success bool     `json:"success"`
+	Output  string   `json:"output"`
+	Files   []string `json:"files"` //保存时会写入的文件
+}
+
+const (
+	ValidateAdd    = nginx.ActionAdd
+	ValidateDelete = nginx.ActionDelete
+	ValidateModify = nginx.ActionModify
+)
+
 type AginxFile interface {
 	New(relativePath, localFileAbsPath string) error
 
@@ -28,11 +48,19 @@ type AginxFile interface {
 	Search(relativePaths ...string) (map[string]string, error)
 
 	Get(relativePath string) (string, error)
+
+	//配置文件的include树
+	Tree() (*nginx.IncludeFile, error)
+
+	//文件的版本，用于IfMatch
+	Revision(relativePath string) (string, error)
 }
 
 type AginxSSL interface {
 	New(accountEmail, domain string) (*lego.StoreFile, error)
 	ReNew(domain string) (*lego.StoreFile, error)
+	//生成自签名证书，days为证书有效期
+	SelfSigned(domain string, days int) (*lego.StoreFile, error)
 }
 
 type AginxDirective interface {
@@ -49,6 +77,17 @@ type AginxDirective interface {
 	Modify(queries []string, directive *nginx.Directive) error
 }
 
+//批量修改，Commit时所有修改全部成功才会保存并重启一次nginx，否则全部放弃
+type AginxBatch interface {
+	Add(queries []string, addDirectives ...*nginx.Directive) AginxBatch
+
+	Delete(queries ...string) AginxBatch
+
+	Modify(queries []string, directive *nginx.Directive) AginxBatch
+
+	Commit() error
+}
+
 type AginxSimple interface {
 
 	//查询 http.upstream，如果names参数存在将会命名在names参数中的upstream
@@ -67,12 +106,57 @@ type AginxSimple interface {
 	SimpleServer(domain string, ssl bool, addresses []string) error
 }
 
+type AginxUpstream interface {
+	//查询http和stream中的全部upstream
+	List() ([]*nginx.Upstream, error)
+
+	Get(name string) (*nginx.Upstream, error)
+
+	New(upstream *nginx.Upstream) error
+
+	//替换负载均衡方式和全部server
+	Modify(upstream *nginx.Upstream) error
+
+	Delete(name string) error
+
+	//添加server，地址已经存在时修改参数，Down为true时摘除流量
+	SetServer(name string, server *nginx.UpstreamServer) error
+
+	RemoveServer(name, address string) error
+}
+
+type AginxServer interface {
+	//查询全部http.server
+	List() ([]*nginx.Server, error)
+
+	//查询server_name包含domain的server
+	Get(domain string) ([]*nginx.Server, error)
+
+	//创建server，SSL为true时为每个域名申请证书，accountEmail为空时使用服务端的默认邮箱
+	New(server *nginx.Server, accountEmail string) error
+
+	//从server_name中删除域名，没有其他域名的server将被删除
+	Delete(domain string) error
+}
+
 type Aginx interface {
 	Auth(name, password string)
 
+	//使用JWT bearer token认证
+	Token(token string)
+
+	//签发token，role: viewer, editor, cert-manager, admin。expire为0使用服务端默认的过期时间
+	IssueToken(role string, expire time.Duration) (*TokenResult, error)
+
 	//获取全局配置
 	Configuration() (*nginx.Configuration, error)
 
+	//配置的版本，用于IfMatch
+	Revision() (string, error)
+
+	//修改时检查版本（配置或者文件的版本），读取后被其他请求修改过时返回ErrConflict，可以使用Retry重试
+	IfMatch(revision string) Aginx
+
 	//nginx -s reload
 	Reload() error
 
@@ -83,4 +167,26 @@ type Aginx interface {
 	SSL() AginxSSL
 
 	Simple() AginxSimple
+
+	Upstream() AginxUpstream
+
+	Server() AginxServer
+
+	//查询保存的历史版本
+	History() ([]*history.Version, error)
+
+	//回滚到指定版本修改后的状态
+	Rollback(version int64) ([]*history.Version, error)
+
+	//测试修改(ValidateAdd,ValidateDelete,ValidateModify)后的配置是否正确，不会影响当前的配置
+	Validate(action string, queries []string, directives ...*nginx.Directive) (*ValidateResult, error)
+
+	//检查配置中 nginx -t 不能发现的问题，skip为忽略的规则
+	Lint(skip ...string) ([]*nginx.Lint, error)
+
+	//开启批量修改
+	Batch() AginxBatch
+
+	//监听配置变更事件，file为空监听全部文件，关闭closeC结束监听
+	Watch(file string, closeC <-chan struct{}) (<-chan *util.ChangeEvent, error)
 }
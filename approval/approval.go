@@ -0,0 +1,239 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("approval")
+
+const changesFile = "approval/changes.json" //等待审批的修改
+
+var (
+	ErrApprovalRequired = errors.New("the change requires approval, use the http api to create it")
+	ErrSelfApproval     = errors.New("the change must be approved by another user")
+	ErrChangeConflict   = errors.New("the file is modified after the change is created")
+)
+
+//修改的文件
+type File struct {
+	Name    string  `json:"name"`
+	Remove  bool    `json:"remove,omitempty"`
+	Content string  `json:"content,omitempty"`
+	Base    *string `json:"base,omitempty"` //修改前的内容，文件不存在时为nil，审批时内容不同表示冲突
+	Diff    string  `json:"diff,omitempty"`
+}
+
+func (f *File) base(engine plugins.StorageEngine) (*string, error) {
+	file, err := engine.Get(f.Name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	content := string(file.Content)
+	return &content, nil
+}
+
+//一次修改请求的全部修改
+type Changeset struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user,omitempty"`
+	Method  string    `json:"method"`
+	Path    string    `json:"path"`
+	Queries []string  `json:"queries,omitempty"`
+	Files   []*File   `json:"files"`
+}
+
+func (c *Changeset) file(name string) *File {
+	for _, file := range c.Files {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+//修改请求需要另外一个用户审批后才会保存并重启nginx
+type Approvals struct {
+	engine  plugins.StorageEngine //保存审批通过的修改
+	store   plugins.StorageEngine //保存等待审批的修改
+	Enabled bool
+
+	lock    sync.Mutex
+	changes map[string]*Changeset
+}
+
+//engine为保存配置使用的存储（记录审计和历史版本），store保存等待审批的修改
+func New(engine, store plugins.StorageEngine, enabled bool) *Approvals {
+	a := &Approvals{engine: engine, store: store, Enabled: enabled, changes: make(map[string]*Changeset)}
+	if err := a.load(); err != nil {
+		logger.WithError(err).Warn("load pending changes")
+	}
+	return a
+}
+
+func (a *Approvals) load() error {
+	file, err := a.store.Get(changesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	changes := make([]*Changeset, 0)
+	if err = json.Unmarshal(file.Content, &changes); err != nil {
+		return err
+	}
+	for _, changeset := range changes {
+		a.changes[changeset.ID] = changeset
+	}
+	return nil
+}
+
+func (a *Approvals) list() []*Changeset {
+	changes := make([]*Changeset, 0, len(a.changes))
+	for _, changeset := range a.changes {
+		changes = append(changes, changeset)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Time.Before(changes[j].Time)
+	})
+	return changes
+}
+
+func (a *Approvals) save() error {
+	content, err := json.MarshalIndent(a.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return a.store.Put(changesFile, content)
+}
+
+//等待审批的修改，按照创建时间排序
+func (a *Approvals) List() []*Changeset {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.list()
+}
+
+func (a *Approvals) Get(id string) (*Changeset, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if changeset, has := a.changes[id]; has {
+		return changeset, nil
+	}
+	return nil, fmt.Errorf("%w: change %s", os.ErrNotExist, id)
+}
+
+type changesetKey struct{}
+
+//请求中正在记录的修改，不在修改请求中时返回nil
+func changesetOf(ctx context.Context) *Changeset {
+	changeset, _ := ctx.Value(changesetKey{}).(*Changeset)
+	return changeset
+}
+
+//开始记录一个修改请求，必须和End成对调用。
+//使用返回的context绑定存储引擎（plugins.WithContext）后，修改记录到此请求中
+func (a *Approvals) Begin(ctx context.Context, changeset *Changeset) context.Context {
+	changeset.Files = make([]*File, 0)
+	return context.WithValue(ctx, changesetKey{}, changeset)
+}
+
+//结束记录，有修改时保存为等待审批的修改并返回。discard为true时（请求失败）丢弃记录的修改
+func (a *Approvals) End(ctx context.Context, discard bool) (*Changeset, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	changeset := changesetOf(ctx)
+	if discard || changeset == nil || len(changeset.Files) == 0 {
+		return nil, nil
+	}
+	if changeset.ID == "" {
+		changeset.ID = fmt.Sprintf("%d", changeset.Time.UnixNano())
+	}
+	a.changes[changeset.ID] = changeset
+	if err := a.save(); err != nil {
+		delete(a.changes, changeset.ID)
+		return nil, err
+	}
+	logger.Info("change ", changeset.ID, " of ", changeset.User, " is waiting for approval")
+	return changeset, nil
+}
+
+//审批通过：检查冲突，test通过后保存修改。test参数为需要测试的文件内容，值为nil表示删除。
+//修改使用ctx绑定的存储保存，审计和历史版本记录到审批请求中
+func (a *Approvals) Approve(ctx context.Context, id, user string, test func(files map[string][]byte) error) (*Changeset, error) {
+	engine := plugins.WithContext(ctx, a.engine)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	changeset, has := a.changes[id]
+	if !has {
+		return nil, fmt.Errorf("%w: change %s", os.ErrNotExist, id)
+	}
+	if user == changeset.User {
+		return nil, ErrSelfApproval
+	}
+	files := make(map[string][]byte)
+	for _, file := range changeset.Files {
+		base, err := file.base(engine)
+		if err != nil {
+			return nil, err
+		}
+		if (base == nil) != (file.Base == nil) || (base != nil && *base != *file.Base) {
+			return nil, fmt.Errorf("%w: %s", ErrChangeConflict, file.Name)
+		}
+		if file.Remove {
+			files[file.Name] = nil
+		} else {
+			files[file.Name] = []byte(file.Content)
+		}
+	}
+	if err := test(files); err != nil {
+		return nil, err
+	}
+	if err := plugins.Batch(engine, func(engine plugins.StorageEngine) (err error) {
+		for _, file := range changeset.Files {
+			if file.Remove {
+				err = engine.Remove(file.Name)
+			} else {
+				err = engine.Put(file.Name, []byte(file.Content))
+			}
+			if err != nil {
+				return
+			}
+		}
+		return
+	}); err != nil {
+		return nil, err
+	}
+	delete(a.changes, id)
+	logger.Info("change ", id, " is approved by ", user)
+	return changeset, a.save()
+}
+
+//拒绝修改
+func (a *Approvals) Reject(id, user string) (*Changeset, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	changeset, has := a.changes[id]
+	if !has {
+		return nil, fmt.Errorf("%w: change %s", os.ErrNotExist, id)
+	}
+	delete(a.changes, id)
+	if err := a.save(); err != nil {
+		a.changes[id] = changeset
+		return nil, err
+	}
+	logger.Info("change ", id, " is rejected by ", user)
+	return changeset, nil
+}
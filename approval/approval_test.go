@@ -0,0 +1,130 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func pass(files map[string][]byte) error {
+	return nil
+}
+
+//模拟一个修改请求
+func request(approvals *Approvals, engine plugins.StorageEngine, user string, fn func(engine plugins.StorageEngine)) *Changeset {
+	ctx := approvals.Begin(context.Background(), &Changeset{Time: time.Now(), User: user, Method: "POST", Path: "/api"})
+	fn(plugins.WithContext(ctx, engine))
+	changeset, _ := approvals.End(ctx, false)
+	return changeset
+}
+
+func TestApprovalCapture(t *testing.T) {
+	storage := memory.Storage{"nginx.conf": []byte("v1"), "hosts.d/a.conf": []byte("a"), "hosts.d/b.conf": []byte("b")}
+	approvals := New(storage, storage, true)
+	engine := approvals.Engine(storage)
+
+	changeset := request(approvals, engine, "alice", func(engine plugins.StorageEngine) {
+		assert.Nil(t, engine.Put("nginx.conf", []byte("v2")))
+		assert.Nil(t, engine.Put("hosts.d/c.conf", []byte("c")))
+		assert.Nil(t, engine.Remove("hosts.d/b.conf"))
+
+		//请求中读取修改后的内容
+		file, err := engine.Get("nginx.conf")
+		assert.Nil(t, err)
+		assert.Equal(t, "v2", string(file.Content))
+		_, err = engine.Get("hosts.d/b.conf")
+		assert.True(t, os.IsNotExist(err))
+		files, err := engine.Search("hosts.d/*")
+		assert.Nil(t, err)
+		assert.Len(t, files, 2)
+	})
+	assert.NotNil(t, changeset)
+	assert.Len(t, changeset.Files, 3)
+	assert.Equal(t, "alice", changeset.User)
+
+	//没有写入存储
+	assert.Equal(t, "v1", string(storage["nginx.conf"]))
+	assert.Equal(t, "b", string(storage["hosts.d/b.conf"]))
+	assert.Len(t, approvals.List(), 1)
+
+	//重启后加载等待审批的修改
+	loaded := New(storage, storage, true)
+	assert.Len(t, loaded.List(), 1)
+
+	//不在修改请求中时直接写入
+	assert.Nil(t, engine.Put("hosts.d/d.conf", []byte("d")))
+	assert.Equal(t, "d", string(storage["hosts.d/d.conf"]))
+
+	//失败的请求丢弃修改
+	ctx := approvals.Begin(context.Background(), &Changeset{Time: time.Now(), User: "alice"})
+	assert.Nil(t, plugins.WithContext(ctx, engine).Put("nginx.conf", []byte("v3")))
+	discarded, err := approvals.End(ctx, true)
+	assert.Nil(t, err)
+	assert.Nil(t, discarded)
+	assert.Len(t, approvals.List(), 1)
+}
+
+func TestApprovalApprove(t *testing.T) {
+	storage := memory.Storage{"nginx.conf": []byte("v1"), "hosts.d/b.conf": []byte("b")}
+	approvals := New(storage, storage, true)
+	engine := approvals.Engine(storage)
+	changeset := request(approvals, engine, "alice", func(engine plugins.StorageEngine) {
+		_ = engine.Put("nginx.conf", []byte("v2"))
+		_ = engine.Remove("hosts.d/b.conf")
+	})
+
+	_, err := approvals.Approve(context.Background(), changeset.ID, "alice", pass)
+	assert.Equal(t, ErrSelfApproval, err)
+
+	_, err = approvals.Approve(context.Background(), changeset.ID, "bob", func(files map[string][]byte) error {
+		assert.Equal(t, map[string][]byte{"nginx.conf": []byte("v2"), "hosts.d/b.conf": nil}, files)
+		return errors.New("nginx: [emerg]")
+	})
+	assert.Equal(t, "nginx: [emerg]", err.Error())
+	assert.Equal(t, "v1", string(storage["nginx.conf"]))
+
+	approved, err := approvals.Approve(context.Background(), changeset.ID, "bob", pass)
+	assert.Nil(t, err)
+	assert.Equal(t, changeset.ID, approved.ID)
+	assert.Equal(t, "v2", string(storage["nginx.conf"]))
+	_, has := storage["hosts.d/b.conf"]
+	assert.False(t, has)
+	assert.Len(t, approvals.List(), 0)
+
+	_, err = approvals.Approve(context.Background(), changeset.ID, "bob", pass)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestApprovalConflict(t *testing.T) {
+	storage := memory.Storage{"nginx.conf": []byte("v1")}
+	approvals := New(storage, storage, true)
+	engine := approvals.Engine(storage)
+	first := request(approvals, engine, "alice", func(engine plugins.StorageEngine) {
+		_ = engine.Put("nginx.conf", []byte("v2"))
+	})
+	second := request(approvals, engine, "alice", func(engine plugins.StorageEngine) {
+		_ = engine.Put("nginx.conf", []byte("v3"))
+	})
+	_, err := approvals.Approve(context.Background(), first.ID, "bob", pass)
+	assert.Nil(t, err)
+
+	//文件在创建修改后被修改
+	_, err = approvals.Approve(context.Background(), second.ID, "bob", pass)
+	assert.True(t, errors.Is(err, ErrChangeConflict))
+
+	rejected, err := approvals.Reject(second.ID, "bob")
+	assert.Nil(t, err)
+	assert.Equal(t, second.ID, rejected.ID)
+	assert.Len(t, approvals.List(), 0)
+}
+
+func TestApprovalDisabled(t *testing.T) {
+	storage := memory.Storage{}
+	approvals := New(storage, storage, false)
+	assert.Equal(t, plugins.StorageEngine(storage), approvals.Engine(storage))
+}
@@ -0,0 +1,163 @@
+package approval
+
+import (
+	"context"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"os"
+	"path/filepath"
+)
+
+//修改请求中的修改记录为等待审批的修改，不会写入存储；不在修改请求中时（例如证书续期、健康检查）直接写入存储
+type approvalEngine struct {
+	plugins.StorageEngine
+	approvals *Approvals
+	ctx       context.Context
+}
+
+//开启审批时返回记录修改的存储，否则返回engine
+func (a *Approvals) Engine(engine plugins.StorageEngine) plugins.StorageEngine {
+	if !a.Enabled {
+		return engine
+	}
+	return &approvalEngine{StorageEngine: engine, approvals: a, ctx: context.Background()}
+}
+
+func (ae *approvalEngine) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &approvalEngine{StorageEngine: plugins.WithContext(ctx, ae.StorageEngine), approvals: ae.approvals, ctx: ctx}
+}
+
+func (ae *approvalEngine) Context() context.Context {
+	return ae.ctx
+}
+
+func (ae *approvalEngine) capturing() bool {
+	return changesetOf(ae.ctx) != nil
+}
+
+//记录修改，不在修改请求中时返回false
+func (ae *approvalEngine) capture(name string, content []byte, remove bool) (bool, error) {
+	changeset := changesetOf(ae.ctx)
+	if changeset == nil {
+		return false, nil
+	}
+	a := ae.approvals
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	file := changeset.file(name)
+	if file == nil {
+		file = &File{Name: name}
+		base, err := file.base(ae.StorageEngine)
+		if err != nil {
+			return true, err
+		}
+		file.Base = base
+		changeset.Files = append(changeset.Files, file)
+	}
+	old := ""
+	if file.Base != nil {
+		old = *file.Base
+	}
+	file.Remove, file.Content = remove, string(content)
+	file.Diff = util.Diff(old, file.Content)
+	return true, nil
+}
+
+func (ae *approvalEngine) Put(file string, content []byte) error {
+	if captured, err := ae.capture(file, content, false); captured || err != nil {
+		return err
+	}
+	return ae.StorageEngine.Put(file, content)
+}
+
+func (ae *approvalEngine) Remove(file string) error {
+	if !ae.capturing() {
+		return ae.StorageEngine.Remove(file)
+	}
+	if _, err := ae.Get(file); err == nil {
+		_, err = ae.capture(file, nil, true)
+		return err
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	//可能是文件夹
+	files, err := ae.Search(file + "/*")
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return os.ErrNotExist
+	}
+	for _, f := range files {
+		if _, err := ae.capture(f.Name, nil, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//读取时使用本次请求中修改后的内容
+func (ae *approvalEngine) Get(file string) (*plugins.ConfigurationFile, error) {
+	var captured *File
+	if changeset := changesetOf(ae.ctx); changeset != nil {
+		a := ae.approvals
+		a.lock.Lock()
+		captured = changeset.file(file)
+		a.lock.Unlock()
+	}
+	if captured == nil {
+		return ae.StorageEngine.Get(file)
+	} else if captured.Remove {
+		return nil, os.ErrNotExist
+	}
+	return plugins.NewFile(file, []byte(captured.Content)), nil
+}
+
+func (ae *approvalEngine) Search(patterns ...string) ([]*plugins.ConfigurationFile, error) {
+	files, err := ae.StorageEngine.Search(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	changeset := changesetOf(ae.ctx)
+	if changeset == nil {
+		return files, nil
+	}
+	a := ae.approvals
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	results := make([]*plugins.ConfigurationFile, 0, len(files))
+	for _, file := range files {
+		if captured := changeset.file(file.Name); captured == nil {
+			results = append(results, file)
+		} else if !captured.Remove {
+			results = append(results, plugins.NewFile(file.Name, []byte(captured.Content)))
+		}
+	}
+	//本次请求中新增的文件
+	for _, captured := range changeset.Files {
+		if captured.Base == nil && !captured.Remove && matched(captured.Name, patterns...) {
+			results = append(results, plugins.NewFile(captured.Name, []byte(captured.Content)))
+		}
+	}
+	return results, nil
+}
+
+func matched(name string, patterns ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, name); match {
+			return true
+		}
+	}
+	return false
+}
+
+func (ae *approvalEngine) Start() error {
+	return util.StartService(ae.StorageEngine)
+}
+
+func (ae *approvalEngine) Stop() error {
+	return util.StopService(ae.StorageEngine)
+}
@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/util"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("audit")
+
+type Change struct {
+	File string `json:"file"`
+	Type string `json:"type"` //update, remove
+	Diff string `json:"diff,omitempty"`
+}
+
+type Record struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user,omitempty"`
+	Remote  string    `json:"remote,omitempty"`
+	Method  string    `json:"method"`
+	Path    string    `json:"path"`
+	Queries []string  `json:"queries,omitempty"`
+	Status  int       `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Changes []Change  `json:"changes,omitempty"`
+}
+
+type Filter struct {
+	User  string
+	File  string
+	Since time.Time
+	Limit int
+}
+
+func (f Filter) Match(record *Record) bool {
+	if f.User != "" && f.User != record.User {
+		return false
+	}
+	if !f.Since.IsZero() && record.Time.Before(f.Since) {
+		return false
+	}
+	if f.File != "" {
+		for _, change := range record.Changes {
+			if change.File == f.File || strings.HasPrefix(change.File, f.File+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+type Auditor struct {
+	sinks []Sink
+
+	history     []*Record //没有可读取的sink时，使用内存保存最近的记录
+	historySize int
+	lock        *sync.Mutex //保护history，sink按照请求结束的顺序写入
+
+	//正在处理的修改请求，修改请求的审计记录保存在请求的context中
+	running    map[string]*Record
+	changeLock *sync.Mutex
+}
+
+func New(sinks ...Sink) *Auditor {
+	return &Auditor{
+		sinks: sinks, history: make([]*Record, 0), historySize: 1000,
+		lock: new(sync.Mutex), running: make(map[string]*Record), changeLock: new(sync.Mutex),
+	}
+}
+
+type recordKey struct{}
+
+//记录修改到ctx中的请求，不在修改请求中的修改（例如：健康检查、证书续期）不记录
+func (a *Auditor) change(ctx context.Context, change Change) {
+	record, match := ctx.Value(recordKey{}).(*Record)
+	if !match {
+		return
+	}
+	a.changeLock.Lock()
+	defer a.changeLock.Unlock()
+	record.Changes = append(record.Changes, change)
+}
+
+//开始一个变更请求，返回保存了审计记录的context，必须和End成对调用。
+//使用返回的context绑定存储引擎（plugins.WithContext）后，存储的修改记录到此请求中
+func (a *Auditor) Begin(ctx context.Context, record *Record) context.Context {
+	if record.ID == "" {
+		record.ID = fmt.Sprintf("%d", record.Time.UnixNano())
+	}
+	a.changeLock.Lock()
+	record.Changes = nil
+	a.running[record.ID] = record
+	a.changeLock.Unlock()
+	return context.WithValue(ctx, recordKey{}, record)
+}
+
+//正在处理的变更请求ID，按照ID排序
+func (a *Auditor) Running() []string {
+	a.changeLock.Lock()
+	defer a.changeLock.Unlock()
+	ids := make([]string, 0, len(a.running))
+	for id := range a.running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+//结束变更请求，并记录到所有的sink中
+func (a *Auditor) End(record *Record) {
+	a.changeLock.Lock()
+	delete(a.running, record.ID)
+	a.changeLock.Unlock()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.history = append(a.history, record)
+	if len(a.history) > a.historySize {
+		a.history = a.history[len(a.history)-a.historySize:]
+	}
+	for _, change := range record.Changes {
+		util.PublishChanged(&util.ChangeEvent{
+			Time: record.Time, Source: util.ChangeSourceApi, File: change.File, Type: change.Type,
+			User: record.User, Queries: record.Queries, Diff: change.Diff,
+		})
+	}
+	for _, sink := range a.sinks {
+		if err := sink.Write(record); err != nil {
+			logger.WithError(err).Warn("write audit record ", record.ID)
+		}
+	}
+}
+
+//查询审计记录，按时间倒序
+func (a *Auditor) Query(filter Filter) ([]*Record, error) {
+	a.lock.Lock()
+	records := a.history
+	a.lock.Unlock()
+	for _, sink := range a.sinks {
+		if reader, match := sink.(Reader); match {
+			var err error
+			if records, err = reader.Read(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	matched := make([]*Record, 0)
+	for _, record := range records {
+		if filter.Match(record) {
+			matched = append(matched, record)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Time.After(matched[j].Time)
+	})
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+)
+
+//记录存储引擎的修改内容
+type auditEngine struct {
+	plugins.StorageEngine
+	auditor *Auditor
+	ctx     context.Context
+}
+
+func (a *Auditor) Engine(engine plugins.StorageEngine) plugins.StorageEngine {
+	return &auditEngine{StorageEngine: engine, auditor: a, ctx: context.Background()}
+}
+
+func (ae *auditEngine) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &auditEngine{StorageEngine: plugins.WithContext(ctx, ae.StorageEngine), auditor: ae.auditor, ctx: ctx}
+}
+
+func (ae *auditEngine) Context() context.Context {
+	return ae.ctx
+}
+
+func (ae *auditEngine) Put(file string, content []byte) error {
+	old := ""
+	if cfgFile, err := ae.StorageEngine.Get(file); err == nil {
+		old = string(cfgFile.Content)
+	}
+	if err := ae.StorageEngine.Put(file, content); err != nil {
+		return err
+	}
+	ae.auditor.change(ae.ctx, Change{File: file, Type: string(plugins.FileEventTypeUpdate), Diff: util.Diff(old, string(content))})
+	return nil
+}
+
+func (ae *auditEngine) Remove(file string) error {
+	if err := ae.StorageEngine.Remove(file); err != nil {
+		return err
+	}
+	ae.auditor.change(ae.ctx, Change{File: file, Type: string(plugins.FileEventTypeRemove)})
+	return nil
+}
+
+func (ae *auditEngine) Start() error {
+	return util.StartService(ae.StorageEngine)
+}
+
+func (ae *auditEngine) Stop() error {
+	return util.StopService(ae.StorageEngine)
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Sink interface {
+	Write(record *Record) error
+}
+
+//可以读取历史记录的sink
+type Reader interface {
+	Read() ([]*Record, error)
+}
+
+//file:///var/log/aginx/audit.log, syslog://[127.0.0.1:514][?tag=aginx], storage://audit
+func NewSinks(addresses []string, engine plugins.StorageEngine) ([]Sink, error) {
+	sinks := make([]Sink, 0)
+	for _, address := range addresses {
+		config, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		switch config.Scheme {
+		case "file":
+			sinks = append(sinks, &fileSink{path: config.Path})
+		case "syslog":
+			if sink, err := newSyslogSink(config); err != nil {
+				return nil, err
+			} else {
+				sinks = append(sinks, sink)
+			}
+		case "storage":
+			folder := strings.Trim(config.Host+config.Path, "/")
+			if folder == "" {
+				folder = "audit"
+			}
+			plugins.Reserve(folder)
+			sinks = append(sinks, &storageSink{engine: engine, folder: folder})
+		default:
+			return nil, errors.New("audit sink not support: " + address)
+		}
+	}
+	return sinks, nil
+}
+
+func readRecords(content []byte) []*Record {
+	records := make([]*Record, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		record := new(Record)
+		if err := json.Unmarshal(scanner.Bytes(), record); err == nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+type fileSink struct {
+	path string
+}
+
+func (fs *fileSink) Write(record *Record) error {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fs.path), os.ModePerm); err != nil {
+		return err
+	}
+	fio, err := os.OpenFile(fs.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = fio.Close() }()
+	_, err = fio.Write(append(bs, '\n'))
+	return err
+}
+
+func (fs *fileSink) Read() ([]*Record, error) {
+	content, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return []*Record{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return readRecords(content), nil
+}
+
+//记录保存在存储引擎中，按天分文件
+type storageSink struct {
+	engine plugins.StorageEngine
+	folder string
+}
+
+func (ss *storageSink) Write(record *Record) error {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file := ss.folder + "/" + record.Time.Format("2006-01-02") + ".log"
+	content := make([]byte, 0)
+	if cfgFile, err := ss.engine.Get(file); err == nil {
+		content = cfgFile.Content
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	content = append(content, bs...)
+	content = append(content, '\n')
+	return ss.engine.Put(file, content)
+}
+
+func (ss *storageSink) Read() ([]*Record, error) {
+	files, err := ss.engine.Search(ss.folder + "/*.log")
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*Record, 0)
+	for _, file := range files {
+		records = append(records, readRecords(file.Content)...)
+	}
+	return records, nil
+}
@@ -0,0 +1,37 @@
+// +build !windows
+
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+	"net/url"
+)
+
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func newSyslogSink(config *url.URL) (Sink, error) {
+	tag := config.Query().Get("tag")
+	if tag == "" {
+		tag = "aginx"
+	}
+	network := ""
+	if config.Host != "" {
+		network = "udp"
+	}
+	writer, err := syslog.Dial(network, config.Host, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{writer: writer}, nil
+}
+
+func (ss *syslogSink) Write(record *Record) error {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return ss.writer.Info(string(bs))
+}
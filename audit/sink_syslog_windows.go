@@ -0,0 +1,10 @@
+package audit
+
+import (
+	"errors"
+	"net/url"
+)
+
+func newSyslogSink(config *url.URL) (Sink, error) {
+	return nil, errors.New("syslog audit sink not support on windows")
+}
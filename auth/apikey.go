@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("auth")
+
+const (
+	apiKeyDir    = "keys"
+	apiKeyPrefix = "aginx_"
+)
+
+type APIKey struct {
+	ID      string    `json:"id"`
+	Label   string    `json:"label"`
+	Role    string    `json:"role"`
+	User    string    `json:"user,omitempty"`   //创建者
+	Tenant  string    `json:"tenant,omitempty"` //绑定的租户
+	Created time.Time `json:"created"`
+	Hash    string    `json:"hash,omitempty"` //sha256(secret)，不会返回给客户端
+}
+
+func (key *APIKey) path() string {
+	return fmt.Sprintf("%s/%s.json", apiKeyDir, key.ID)
+}
+
+//长期使用的api key，保存在存储引擎中，使用 Authorization: Bearer aginx_<id>_<secret> 认证
+type APIKeys struct {
+	engine plugins.StorageEngine
+	lock   *sync.RWMutex
+	keys   map[string]*APIKey
+}
+
+func NewAPIKeys(engine plugins.StorageEngine) (*APIKeys, error) {
+	k := &APIKeys{engine: engine, lock: new(sync.RWMutex)}
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	//集群中其他节点修改后重新加载
+	util.SubscribeChanged(func(event *util.ChangeEvent) {
+		if event.Source != util.ChangeSourceApi && strings.HasPrefix(event.File, apiKeyDir+"/") {
+			if err := k.load(); err != nil {
+				logger.WithError(err).Warn("reload api keys")
+			}
+		}
+	})
+	return k, nil
+}
+
+func (k *APIKeys) load() error {
+	files, err := k.engine.Search(apiKeyDir + "/*.json")
+	if err != nil {
+		return err
+	}
+	keys := map[string]*APIKey{}
+	for _, file := range files {
+		key := new(APIKey)
+		if err := json.Unmarshal(file.Content, key); err != nil {
+			logger.WithError(err).Warn("load api key ", file.Name)
+			continue
+		}
+		keys[key.ID] = key
+	}
+	k.lock.Lock()
+	k.keys = keys
+	k.lock.Unlock()
+	return nil
+}
+
+func randomHex(size int) (string, error) {
+	bs := make([]byte, size)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bs), nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+//创建api key，返回的secret只有这一次可以获取
+func (k *APIKeys) Create(label, role, user, tenant string) (key *APIKey, secret string, err error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return
+	}
+	if secret, err = randomHex(24); err != nil {
+		return
+	}
+	key = &APIKey{ID: id, Label: label, Role: role, User: user, Tenant: tenant, Created: time.Now(), Hash: hash(secret)}
+	content, err := json.MarshalIndent(key, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = k.engine.Put(key.path(), content); err != nil {
+		return
+	}
+	k.lock.Lock()
+	k.keys[key.ID] = key
+	k.lock.Unlock()
+	secret = apiKeyPrefix + id + "_" + secret
+	return
+}
+
+func (k *APIKeys) List() []*APIKey {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	keys := make([]*APIKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, &APIKey{ID: key.ID, Label: key.Label, Role: key.Role, User: key.User, Tenant: key.Tenant, Created: key.Created})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.Before(keys[j].Created)
+	})
+	return keys
+}
+
+func (k *APIKeys) Revoke(id string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	key, has := k.keys[id]
+	if !has {
+		return os.ErrNotExist
+	}
+	if err := k.engine.Remove(key.path()); err != nil {
+		return err
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+func (k *APIKeys) Authenticate(authorization string) (*Identity, error) {
+	if !strings.HasPrefix(authorization, "Bearer "+apiKeyPrefix) {
+		return nil, nil
+	}
+	idAndSecret := strings.SplitN(strings.TrimPrefix(authorization, "Bearer "+apiKeyPrefix), "_", 2)
+	if len(idAndSecret) != 2 {
+		return nil, nil
+	}
+	k.lock.RLock()
+	key, has := k.keys[idAndSecret[0]]
+	k.lock.RUnlock()
+	if !has || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash(idAndSecret[1]))) != 1 {
+		return nil, nil
+	}
+	user := key.Label
+	if user == "" {
+		user = apiKeyPrefix + key.ID
+	}
+	return &Identity{User: user, Roles: []string{key.Role}, Tenant: key.Tenant}, nil
+}
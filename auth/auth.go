@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var (
+	ErrUnauthorized = errors.New("Authorization Required")
+	ErrForbidden    = errors.New("Permission denied")
+)
+
+//权限
+const (
+	PermRead  = "read"  //查询配置、文件、历史版本
+	PermWrite = "write" //修改配置、文件、回滚、重启
+	PermCert  = "cert"  //申请、更新证书
+	PermAdmin = "admin" //审计记录、给其他角色签发token
+)
+
+//角色
+const (
+	RoleViewer      = "viewer"
+	RoleEditor      = "editor"
+	RoleCertManager = "cert-manager"
+	RoleAdmin       = "admin"
+)
+
+var rolePermissions = map[string][]string{
+	RoleViewer:      {PermRead},
+	RoleEditor:      {PermRead, PermWrite},
+	RoleCertManager: {PermRead, PermCert},
+	RoleAdmin:       {PermRead, PermWrite, PermCert, PermAdmin},
+}
+
+//token的scope兼容写法
+var scopeRoles = map[string]string{
+	"read":  RoleViewer,
+	"write": RoleEditor,
+}
+
+//将scope或者角色名称转换为角色，不存在返回空
+func ParseRole(name string) string {
+	if role, has := scopeRoles[name]; has {
+		return role
+	}
+	if _, has := rolePermissions[name]; has {
+		return name
+	}
+	return ""
+}
+
+//租户名称，租户的配置文件保存在 tenants/<name>/ 中
+var tenantName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+func ValidTenant(name string) bool {
+	return tenantName.MatchString(name)
+}
+
+//认证通过的用户
+type Identity struct {
+	User   string   `json:"user"`
+	Roles  []string `json:"roles"`
+	Tenant string   `json:"tenant,omitempty"` //为空时可以管理全部配置，否则只能管理租户自己的配置
+}
+
+func (id *Identity) Can(permission string) bool {
+	for _, role := range id.Roles {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//是否拥有角色的全部权限，用于限制签发token的角色
+func (id *Identity) Covers(role string) bool {
+	for _, permission := range rolePermissions[role] {
+		if !id.Can(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+//根据 Authorization 头认证，不是当前认证方式处理的头返回 nil, nil
+type Authenticator interface {
+	Authenticate(authorization string) (*Identity, error)
+}
+
+type Auth struct {
+	authenticators []Authenticator
+}
+
+func New(authenticators ...Authenticator) *Auth {
+	return &Auth{authenticators: authenticators}
+}
+
+//没有任何认证方式时，不需要认证
+func (a *Auth) Enabled() bool {
+	return len(a.authenticators) > 0
+}
+
+func (a *Auth) Authenticate(authorization string) (*Identity, error) {
+	for _, authenticator := range a.authenticators {
+		if identity, err := authenticator.Authenticate(authorization); err != nil {
+			return nil, err
+		} else if identity != nil {
+			return identity, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+//签发token使用的认证方式，未开启返回nil
+func (a *Auth) JWT() *JWT {
+	for _, authenticator := range a.authenticators {
+		if jwt, match := authenticator.(*JWT); match {
+			return jwt
+		}
+	}
+	return nil
+}
+
+//api key管理，未开启返回nil
+func (a *Auth) APIKeys() *APIKeys {
+	for _, authenticator := range a.authenticators {
+		if keys, match := authenticator.(*APIKeys); match {
+			return keys
+		}
+	}
+	return nil
+}
+
+//OIDC登录，未开启返回nil
+func (a *Auth) OIDC() *OIDC {
+	for _, authenticator := range a.authenticators {
+		if oidc, match := authenticator.(*OIDC); match {
+			return oidc
+		}
+	}
+	return nil
+}
+
+//外部认证方式，例如：ldap://127.0.0.1:389/dc=example,dc=com?bind_dn=&bind_password=
+func NewExternal(config string) (Authenticator, error) {
+	u, err := url.Parse(config)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ldap", "ldaps":
+		return NewLDAP(u)
+	case "oidc":
+		return NewOIDC(u)
+	}
+	return nil, fmt.Errorf("not support auth: %s", config)
+}
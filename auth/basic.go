@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+type basicUser struct {
+	password string
+	roles    []string
+	tenant   string
+}
+
+//basic认证，--security user:passwd 拥有admin角色，--user user:passwd:role[:tenant] 指定角色和租户
+type basic struct {
+	users map[string]*basicUser
+}
+
+func NewBasic(security string, users ...string) (Authenticator, error) {
+	b := &basic{users: map[string]*basicUser{}}
+	if security != "" {
+		userAndPwd := strings.SplitN(security, ":", 2)
+		if len(userAndPwd) != 2 {
+			return nil, fmt.Errorf("security format error: %s, example: user:passwd", security)
+		}
+		b.users[userAndPwd[0]] = &basicUser{password: userAndPwd[1], roles: []string{RoleAdmin}}
+	}
+	for _, user := range users {
+		values := strings.SplitN(user, ":", 4)
+		if len(values) < 3 {
+			return nil, fmt.Errorf("user format error: %s, example: user:passwd:viewer", user)
+		}
+		tenant := ""
+		if len(values) == 4 {
+			if tenant = values[3]; !ValidTenant(tenant) {
+				return nil, fmt.Errorf("tenant format error: %s", tenant)
+			}
+		}
+		roles := make([]string, 0)
+		for _, name := range strings.Split(values[2], ",") {
+			role := ParseRole(name)
+			if role == "" {
+				return nil, fmt.Errorf("role not found: %s", name)
+			}
+			roles = append(roles, role)
+		}
+		b.users[values[0]] = &basicUser{password: values[1], roles: roles, tenant: tenant}
+	}
+	return b, nil
+}
+
+func parseBasic(authorization string) (user, password string, ok bool) {
+	if !strings.HasPrefix(authorization, "Basic ") {
+		return
+	}
+	bs, err := base64.StdEncoding.DecodeString(authorization[6:])
+	if err != nil {
+		return
+	}
+	userAndPwd := strings.SplitN(string(bs), ":", 2)
+	if len(userAndPwd) != 2 {
+		return
+	}
+	return userAndPwd[0], userAndPwd[1], true
+}
+
+func (b *basic) Authenticate(authorization string) (*Identity, error) {
+	name, password, ok := parseBasic(authorization)
+	if !ok {
+		return nil, nil
+	}
+	if user, has := b.users[name]; has && user.password == password {
+		return &Identity{User: name, Roles: user.roles, Tenant: user.tenant}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"strings"
+	"time"
+)
+
+type claims struct {
+	jwt.StandardClaims
+	Roles  []string `json:"roles"`
+	Tenant string   `json:"tenant,omitempty"`
+}
+
+//Bearer token 认证，使用 HS256 签名
+type JWT struct {
+	key    []byte
+	expire time.Duration
+}
+
+func NewJWT(key string, expire time.Duration) *JWT {
+	return &JWT{key: []byte(key), expire: expire}
+}
+
+func (j *JWT) Authenticate(authorization string) (*Identity, error) {
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return nil, nil
+	}
+	tokenString := strings.TrimSpace(authorization[7:])
+	c := new(claims)
+	token, err := jwt.ParseWithClaims(tokenString, c, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return j.key, nil
+	})
+	if err != nil || !token.Valid {
+		//可能是其他方式签发的token
+		return nil, nil
+	}
+	return &Identity{User: c.Subject, Roles: c.Roles, Tenant: c.Tenant}, nil
+}
+
+//签发token，expire为0时使用默认的过期时间，tenant不为空时只能管理租户的配置
+func (j *JWT) Issue(user string, roles []string, tenant string, expire time.Duration) (token string, expireAt time.Time, err error) {
+	if expire <= 0 {
+		expire = j.expire
+	}
+	now := time.Now()
+	expireAt = now.Add(expire)
+	c := &claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject: user, IssuedAt: now.Unix(), ExpiresAt: expireAt.Unix(), Issuer: "aginx",
+		},
+		Roles: roles, Tenant: tenant,
+	}
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(j.key)
+	return
+}
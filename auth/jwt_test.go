@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	jwt := NewJWT("aginx", time.Hour)
+	basic, err := NewBasic("aginx:aginx", "monitor:monitor:viewer")
+	assert.Nil(t, err)
+	authenticator := New(basic, jwt)
+
+	token, expire, err := jwt.Issue("aginx", []string{RoleViewer}, "", 0)
+	assert.Nil(t, err)
+	t.Log(token, expire)
+
+	identity, err := authenticator.Authenticate("Bearer " + token)
+	assert.Nil(t, err)
+	assert.Equal(t, "aginx", identity.User)
+	assert.True(t, identity.Can(PermRead))
+	assert.False(t, identity.Can(PermWrite))
+
+	identity, _ = NewJWT("other", time.Hour).Authenticate("Bearer " + token)
+	assert.Nil(t, identity)
+	_, err = authenticator.Authenticate("Bearer " + token + "x")
+	assert.Equal(t, ErrUnauthorized, err)
+
+	expired, _, err := NewJWT("aginx", -time.Hour).Issue("aginx", []string{RoleEditor}, "", 0)
+	assert.Nil(t, err)
+	identity, _ = jwt.Authenticate("Bearer " + expired)
+	assert.Nil(t, identity)
+}
+
+func TestRoles(t *testing.T) {
+	basic, err := NewBasic("aginx:aginx", "monitor:monitor:viewer", "lego:lego:cert-manager")
+	assert.Nil(t, err)
+
+	admin, _ := basic.Authenticate("Basic YWdpbng6YWdpbng=") //aginx:aginx
+	assert.True(t, admin.Can(PermAdmin))
+	assert.True(t, admin.Covers(RoleEditor))
+
+	viewer, _ := basic.Authenticate("Basic bW9uaXRvcjptb25pdG9y") //monitor:monitor
+	assert.True(t, viewer.Can(PermRead))
+	assert.False(t, viewer.Can(PermWrite))
+	assert.False(t, viewer.Covers(RoleEditor))
+
+	cert, _ := basic.Authenticate("Basic bGVnbzpsZWdv") //lego:lego
+	assert.True(t, cert.Can(PermCert))
+	assert.False(t, cert.Can(PermWrite))
+
+	_, err = NewBasic("", "user:passwd:root")
+	assert.NotNil(t, err)
+	assert.Equal(t, RoleEditor, ParseRole("write"))
+}
+
+func TestTenant(t *testing.T) {
+	jwt := NewJWT("aginx", time.Hour)
+	basic, err := NewBasic("", "team:team:editor:shop")
+	assert.Nil(t, err)
+	authenticator := New(basic, jwt)
+
+	identity, _ := authenticator.Authenticate("Basic dGVhbTp0ZWFt") //team:team
+	assert.Equal(t, "shop", identity.Tenant)
+	assert.True(t, identity.Can(PermWrite))
+
+	token, _, err := jwt.Issue(identity.User, identity.Roles, identity.Tenant, 0)
+	assert.Nil(t, err)
+	identity, _ = authenticator.Authenticate("Bearer " + token)
+	assert.Equal(t, "shop", identity.Tenant)
+
+	_, err = NewBasic("", "team:team:editor:../shop")
+	assert.NotNil(t, err)
+	assert.False(t, ValidTenant(""))
+}
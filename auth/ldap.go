@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+//LDAP/AD认证，使用basic认证的用户名和密码，用户所在的组映射为角色
+//
+//ldap[s]://host:389/dc=example,dc=com?bind_dn=&bind_password=&user_filter=(uid=%s)
+//	&group_filter=(member=%s)&group_attr=cn&roles=ops:editor,admins:admin&default_role=viewer&starttls=true
+type LDAP struct {
+	address      string
+	baseDN       string
+	bindDN       string
+	bindPassword string
+	userFilter   string //%s为用户名
+	userDN       string //没有bind_dn时直接使用用户DN绑定，例如：uid=%s,ou=people,dc=example,dc=com 或者 %s@example.com
+	groupFilter  string //%s为用户DN，为空时使用用户的memberOf属性
+	groupAttr    string
+	roles        map[string]string //组名 -> 角色
+	defaultRole  string
+	startTLS     bool
+	insecure     bool
+
+	cache    map[string]*ldapCached
+	cacheTTL time.Duration
+	lock     *sync.Mutex
+}
+
+type ldapCached struct {
+	identity *Identity
+	expire   time.Time
+}
+
+func NewLDAP(config *url.URL) (*LDAP, error) {
+	query := config.Query()
+	l := &LDAP{
+		address:      fmt.Sprintf("%s://%s", config.Scheme, config.Host),
+		baseDN:       strings.TrimPrefix(config.Path, "/"),
+		bindDN:       query.Get("bind_dn"),
+		bindPassword: query.Get("bind_password"),
+		userFilter:   query.Get("user_filter"),
+		userDN:       query.Get("user_dn"),
+		groupFilter:  query.Get("group_filter"),
+		groupAttr:    query.Get("group_attr"),
+		roles:        map[string]string{},
+		defaultRole:  query.Get("default_role"),
+		startTLS:     query.Get("starttls") == "true",
+		insecure:     query.Get("insecure") == "true",
+		cache:        map[string]*ldapCached{},
+		cacheTTL:     time.Minute,
+		lock:         new(sync.Mutex),
+	}
+	if l.userFilter == "" {
+		l.userFilter = "(uid=%s)"
+	}
+	if l.groupAttr == "" {
+		l.groupAttr = "cn"
+	}
+	if l.bindDN == "" && l.userDN == "" {
+		return nil, fmt.Errorf("ldap: bind_dn or user_dn is required")
+	}
+	if l.defaultRole != "" && ParseRole(l.defaultRole) == "" {
+		return nil, fmt.Errorf("ldap: role not found: %s", l.defaultRole)
+	}
+	l.defaultRole = ParseRole(l.defaultRole)
+	if roles := query.Get("roles"); roles != "" {
+		for _, groupRole := range strings.Split(roles, ",") {
+			kv := strings.SplitN(groupRole, ":", 2)
+			if len(kv) != 2 || ParseRole(kv[1]) == "" {
+				return nil, fmt.Errorf("ldap: roles format error: %s, example: ops:editor,admins:admin", groupRole)
+			}
+			l.roles[strings.ToLower(kv[0])] = ParseRole(kv[1])
+		}
+	}
+	return l, nil
+}
+
+func (l *LDAP) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: l.insecure}
+	conn, err := ldap.DialURL(l.address, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	if l.startTLS {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (l *LDAP) Authenticate(authorization string) (*Identity, error) {
+	user, password, ok := parseBasic(authorization)
+	if !ok || password == "" {
+		return nil, nil
+	}
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(user+":"+password)))
+
+	l.lock.Lock()
+	if cached, has := l.cache[key]; has && cached.expire.After(time.Now()) {
+		l.lock.Unlock()
+		return cached.identity, nil
+	}
+	l.lock.Unlock()
+
+	identity, err := l.login(user, password)
+	if err != nil {
+		logger.WithError(err).Debug("ldap login ", user)
+		return nil, nil
+	}
+	l.lock.Lock()
+	for k, cached := range l.cache {
+		if cached.expire.Before(time.Now()) {
+			delete(l.cache, k)
+		}
+	}
+	l.cache[key] = &ldapCached{identity: identity, expire: time.Now().Add(l.cacheTTL)}
+	l.lock.Unlock()
+	return identity, nil
+}
+
+func (l *LDAP) login(user, password string) (*Identity, error) {
+	conn, err := l.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	userDN := ""
+	groups := make([]string, 0)
+	if l.bindDN != "" {
+		if err = conn.Bind(l.bindDN, l.bindPassword); err != nil {
+			return nil, err
+		}
+		result, err := conn.Search(ldap.NewSearchRequest(l.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+			2, 0, false, fmt.Sprintf(l.userFilter, ldap.EscapeFilter(user)), []string{"dn", "memberOf"}, nil))
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Entries) != 1 {
+			return nil, fmt.Errorf("user %s not found or not unique", user)
+		}
+		userDN = result.Entries[0].DN
+		for _, memberOf := range result.Entries[0].GetAttributeValues("memberOf") {
+			groups = append(groups, groupName(memberOf))
+		}
+	} else {
+		userDN = fmt.Sprintf(l.userDN, user)
+	}
+
+	if err = conn.Bind(userDN, password); err != nil {
+		return nil, err
+	}
+
+	if l.groupFilter != "" {
+		result, err := conn.Search(ldap.NewSearchRequest(l.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+			0, 0, false, fmt.Sprintf(l.groupFilter, ldap.EscapeFilter(userDN)), []string{l.groupAttr}, nil))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range result.Entries {
+			groups = append(groups, entry.GetAttributeValue(l.groupAttr))
+		}
+	}
+
+	roles := l.mapRoles(groups)
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("user %s has no role, groups: %v", user, groups)
+	}
+	return &Identity{User: user, Roles: roles}, nil
+}
+
+func (l *LDAP) mapRoles(groups []string) []string {
+	roles := make([]string, 0)
+	for _, group := range groups {
+		if role, has := l.roles[strings.ToLower(group)]; has {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 && l.defaultRole != "" {
+		roles = append(roles, l.defaultRole)
+	}
+	return roles
+}
+
+//从 CN=ops,OU=Groups,DC=example,DC=com 中获取组名
+func groupName(dn string) string {
+	first := strings.SplitN(dn, ",", 2)[0]
+	if kv := strings.SplitN(first, "=", 2); len(kv) == 2 {
+		return kv[1]
+	}
+	return dn
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestLDAPConfig(t *testing.T) {
+	config, _ := url.Parse("ldap://127.0.0.1:389/dc=example,dc=com?bind_dn=cn=admin,dc=example,dc=com&bind_password=123456" +
+		"&roles=ops:editor,Admins:admin&default_role=read")
+	l, err := NewLDAP(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "ldap://127.0.0.1:389", l.address)
+	assert.Equal(t, "dc=example,dc=com", l.baseDN)
+	assert.Equal(t, "(uid=%s)", l.userFilter)
+
+	assert.Equal(t, []string{RoleAdmin}, l.mapRoles([]string{groupName("CN=admins,OU=Groups,DC=example,DC=com")}))
+	assert.Equal(t, []string{RoleViewer}, l.mapRoles([]string{"dev"}))
+
+	config, _ = url.Parse("ldap://127.0.0.1:389/dc=example,dc=com")
+	_, err = NewLDAP(config)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+//OIDC认证，验证 Keycloak/Okta/Google 等签发的 id_token 或 access_token
+//
+//oidc://keycloak.example.com/realms/aginx?client_id=aginx&client_secret=&redirect_url=http://127.0.0.1:8011/api/oidc/callback
+//	&user_claim=preferred_username&roles_claim=groups&roles=ops:editor,admins:admin&default_role=viewer
+type OIDC struct {
+	issuer       string
+	clientId     string
+	clientSecret string
+	redirectURL  string
+	scopes       string
+	userClaim    string
+	rolesClaim   string //支持多级，例如：realm_access.roles
+	roles        map[string]string
+	defaultRole  string
+
+	client    *http.Client
+	discovery *oidcDiscovery
+	keys      map[string]interface{}
+	refreshAt time.Time
+	lock      *sync.Mutex
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type oidcKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewOIDC(config *url.URL) (*OIDC, error) {
+	query := config.Query()
+	scheme := query.Get("scheme")
+	if scheme == "" {
+		scheme = "https"
+	}
+	o := &OIDC{
+		issuer:       strings.TrimSuffix(fmt.Sprintf("%s://%s%s", scheme, config.Host, config.Path), "/"),
+		clientId:     query.Get("client_id"),
+		clientSecret: query.Get("client_secret"),
+		redirectURL:  query.Get("redirect_url"),
+		scopes:       query.Get("scopes"),
+		userClaim:    query.Get("user_claim"),
+		rolesClaim:   query.Get("roles_claim"),
+		roles:        map[string]string{},
+		defaultRole:  query.Get("default_role"),
+		client:       &http.Client{Timeout: time.Second * 10},
+		keys:         map[string]interface{}{},
+		lock:         new(sync.Mutex),
+	}
+	if o.clientId == "" {
+		return nil, fmt.Errorf("oidc: client_id is required")
+	}
+	if o.scopes == "" {
+		o.scopes = "openid profile email"
+	}
+	if o.userClaim == "" {
+		o.userClaim = "preferred_username"
+	}
+	if o.rolesClaim == "" {
+		o.rolesClaim = "groups"
+	}
+	if o.defaultRole != "" && ParseRole(o.defaultRole) == "" {
+		return nil, fmt.Errorf("oidc: role not found: %s", o.defaultRole)
+	}
+	o.defaultRole = ParseRole(o.defaultRole)
+	if roles := query.Get("roles"); roles != "" {
+		for _, groupRole := range strings.Split(roles, ",") {
+			kv := strings.SplitN(groupRole, ":", 2)
+			if len(kv) != 2 || ParseRole(kv[1]) == "" {
+				return nil, fmt.Errorf("oidc: roles format error: %s, example: ops:editor,admins:admin", groupRole)
+			}
+			o.roles[strings.ToLower(kv[0])] = ParseRole(kv[1])
+		}
+	}
+	return o, nil
+}
+
+func (o *OIDC) getJSON(address string, out interface{}) error {
+	resp, err := o.client.Get(address)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: get %s: %s", address, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+//获取服务端配置，失败后下次使用时重试
+func (o *OIDC) configuration() (*oidcDiscovery, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+	discovery := new(oidcDiscovery)
+	if err := o.getJSON(o.issuer+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != o.issuer {
+		return nil, fmt.Errorf("oidc: issuer did not match, expected %s got %s", o.issuer, discovery.Issuer)
+	}
+	o.discovery = discovery
+	return discovery, nil
+}
+
+//获取签名公钥，找不到时刷新（最多一分钟刷新一次），应对服务端密钥轮换
+func (o *OIDC) key(kid string) (interface{}, error) {
+	discovery, err := o.configuration()
+	if err != nil {
+		return nil, err
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if key, has := o.keys[kid]; has {
+		return key, nil
+	}
+	if time.Since(o.refreshAt) < time.Minute {
+		return nil, fmt.Errorf("oidc: key not found: %s", kid)
+	}
+	o.refreshAt = time.Now()
+
+	jwks := &struct {
+		Keys []*oidcKey `json:"keys"`
+	}{}
+	if err := o.getJSON(discovery.JwksURI, jwks); err != nil {
+		return nil, err
+	}
+	keys := map[string]interface{}{}
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if publicKey, err := k.publicKey(); err != nil {
+			logger.WithError(err).Warn("oidc key ", k.Kid)
+		} else {
+			keys[k.Kid] = publicKey
+		}
+	}
+	o.keys = keys
+	if key, has := o.keys[kid]; has {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: key not found: %s", kid)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bs), nil
+}
+
+func (k *oidcKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("not support curve: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("not support key type: %s", k.Kty)
+}
+
+func (o *OIDC) Authenticate(authorization string) (*Identity, error) {
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return nil, nil
+	}
+	identity, err := o.Verify(strings.TrimSpace(authorization[7:]))
+	if err != nil {
+		//可能是其他方式签发的token
+		logger.WithError(err).Debug("oidc token")
+		return nil, nil
+	}
+	return identity, nil
+}
+
+//验证token并获取用户
+func (o *OIDC) Verify(tokenString string) (*Identity, error) {
+	c := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, c, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA, *jwt.SigningMethodRSAPSS:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return o.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, ErrUnauthorized
+	}
+	if !c.VerifyIssuer(o.issuer, true) {
+		return nil, fmt.Errorf("oidc: issuer did not match")
+	}
+	if !o.verifyAudience(c) {
+		return nil, fmt.Errorf("oidc: audience did not match")
+	}
+
+	user, _ := c[o.userClaim].(string)
+	if user == "" {
+		user, _ = c["sub"].(string)
+	}
+	roles := o.mapRoles(claimStrings(c, o.rolesClaim))
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("oidc: user %s has no role", user)
+	}
+	return &Identity{User: user, Roles: roles}, nil
+}
+
+//aud 可以是字符串或者数组，access_token 也可能使用 azp 表示客户端
+func (o *OIDC) verifyAudience(c jwt.MapClaims) bool {
+	if azp, _ := c["azp"].(string); azp == o.clientId {
+		return true
+	}
+	for _, aud := range claimStrings(c, "aud") {
+		if aud == o.clientId {
+			return true
+		}
+	}
+	return false
+}
+
+func claimStrings(c jwt.MapClaims, name string) []string {
+	var value interface{} = map[string]interface{}(c)
+	for _, key := range strings.Split(name, ".") {
+		if m, match := value.(map[string]interface{}); match {
+			value = m[key]
+		} else {
+			return nil
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, match := item.(string); match {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func (o *OIDC) mapRoles(groups []string) []string {
+	roles := make([]string, 0)
+	for _, group := range groups {
+		//keycloak 的组名称以/开头
+		if role, has := o.roles[strings.ToLower(strings.TrimPrefix(group, "/"))]; has {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 && o.defaultRole != "" {
+		roles = append(roles, o.defaultRole)
+	}
+	return roles
+}
+
+//登录地址，state用于防止CSRF
+func (o *OIDC) AuthCodeURL(state string) (string, error) {
+	discovery, err := o.configuration()
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", o.clientId)
+	params.Set("redirect_uri", o.redirectURL)
+	params.Set("scope", o.scopes)
+	params.Set("state", state)
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		return discovery.AuthorizationEndpoint + "&" + params.Encode(), nil
+	}
+	return discovery.AuthorizationEndpoint + "?" + params.Encode(), nil
+}
+
+//使用授权码获取 id_token
+func (o *OIDC) Exchange(code string) (idToken string, expire time.Time, err error) {
+	var discovery *oidcDiscovery
+	if discovery, err = o.configuration(); err != nil {
+		return
+	}
+	params := url.Values{}
+	params.Set("grant_type", "authorization_code")
+	params.Set("code", code)
+	params.Set("redirect_uri", o.redirectURL)
+	params.Set("client_id", o.clientId)
+	params.Set("client_secret", o.clientSecret)
+
+	var resp *http.Response
+	if resp, err = o.client.PostForm(discovery.TokenEndpoint, params); err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	result := &struct {
+		IdToken          string `json:"id_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return
+	}
+	if result.Error != "" {
+		err = fmt.Errorf("oidc: %s %s", result.Error, result.ErrorDescription)
+		return
+	}
+	if result.IdToken == "" {
+		err = fmt.Errorf("oidc: id_token not found")
+		return
+	}
+	//验证token并且用户有权限
+	if _, err = o.Verify(result.IdToken); err != nil {
+		return
+	}
+	idToken = result.IdToken
+	expire = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return
+}
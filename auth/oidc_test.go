@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestOIDC(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/realms/aginx/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"issuer": server.URL + "/realms/aginx", "jwks_uri": server.URL + "/realms/aginx/certs",
+				"authorization_endpoint": server.URL + "/realms/aginx/auth",
+			})
+		case "/realms/aginx/certs":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kid": "test", "kty": "RSA", "use": "sig",
+					"n": base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+					"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+				}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host, _ := url.Parse(server.URL)
+	config, _ := url.Parse("oidc://" + host.Host + "/realms/aginx?scheme=http&client_id=aginx" +
+		"&roles_claim=realm_access.roles&roles=ops:editor,admins:admin")
+	oidc, err := NewOIDC(config)
+	assert.Nil(t, err)
+
+	sign := func(kid string, c jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+		token.Header["kid"] = kid
+		tokenString, err := token.SignedString(privateKey)
+		assert.Nil(t, err)
+		return tokenString
+	}
+	claims := func(aud interface{}, roles ...interface{}) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": server.URL + "/realms/aginx", "aud": aud, "sub": "1", "preferred_username": "haiker",
+			"exp": time.Now().Add(time.Hour).Unix(), "realm_access": map[string]interface{}{"roles": roles},
+		}
+	}
+
+	identity, err := oidc.Authenticate("Bearer " + sign("test", claims([]interface{}{"account", "aginx"}, "ops")))
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{User: "haiker", Roles: []string{RoleEditor}}, identity)
+
+	//客户端不匹配
+	identity, _ = oidc.Authenticate("Bearer " + sign("test", claims("other", "admins")))
+	assert.Nil(t, identity)
+
+	//没有角色
+	identity, _ = oidc.Authenticate("Bearer " + sign("test", claims("aginx", "dev")))
+	assert.Nil(t, identity)
+
+	//未知的签名密钥
+	identity, _ = oidc.Authenticate("Bearer " + sign("unknown", claims("aginx", "ops")))
+	assert.Nil(t, identity)
+
+	address, err := oidc.AuthCodeURL("state")
+	assert.Nil(t, err)
+	assert.Contains(t, address, server.URL+"/realms/aginx/auth?client_id=aginx")
+}
@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//只支持测试使用的命令的ftp服务
+func fakeFtpServer(t *testing.T, files map[string][]byte) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer func() { _ = conn.Close() }()
+				var data net.Listener
+				reply := func(format string, args ...interface{}) {
+					_, _ = fmt.Fprintf(conn, format+"\r\n", args...)
+				}
+				reply("220 ready")
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+					switch fields[0] {
+					case "USER":
+						reply("331 password")
+					case "PASS":
+						reply("230 logged in")
+					case "TYPE":
+						reply("200 binary")
+					case "CWD":
+						reply("250 ok")
+					case "PASV":
+						data, _ = net.Listen("tcp", "127.0.0.1:0")
+						port := data.Addr().(*net.TCPAddr).Port
+						reply("227 Entering Passive Mode (10,0,0,1,%d,%d)", port>>8, port&0xff)
+					case "STOR":
+						reply("150 ok")
+						dc, _ := data.Accept()
+						files[fields[1]], _ = ioutil.ReadAll(dc)
+						_ = dc.Close()
+						_ = data.Close()
+						reply("226 done")
+					case "NLST":
+						reply("150 ok")
+						dc, _ := data.Accept()
+						for name := range files {
+							_, _ = fmt.Fprintf(dc, "%s\r\n", name)
+						}
+						_ = dc.Close()
+						_ = data.Close()
+						reply("226 done")
+					case "DELE":
+						delete(files, fields[1])
+						reply("250 deleted")
+					default:
+						reply("502 not implemented")
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestFtpTarget(t *testing.T) {
+	files := map[string][]byte{}
+	config, _ := url.Parse("ftp://user:password@" + fakeFtpServer(t, files) + "/backups")
+	target := newFtpTarget(config)
+
+	assert.Nil(t, target.Upload("aginx-20200301-120000.tar.gz", []byte("backup")))
+	assert.Equal(t, []byte("backup"), files["aginx-20200301-120000.tar.gz"])
+
+	names, err := target.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"aginx-20200301-120000.tar.gz"}, names)
+
+	assert.Nil(t, target.Remove("aginx-20200301-120000.tar.gz"))
+	assert.Empty(t, files)
+}
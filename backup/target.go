@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage"
+	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+//备份文件保存的位置
+type Target interface {
+	Upload(name string, content []byte) error
+
+	//全部文件名
+	List() ([]string, error)
+
+	Remove(name string) error
+}
+
+//本地目录：file:///var/backups/aginx
+type dirTarget string
+
+func (dir dirTarget) Upload(name string, content []byte) error {
+	if err := os.MkdirAll(string(dir), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(string(dir), name), content, 0600)
+}
+
+func (dir dirTarget) List() ([]string, error) {
+	infos, err := ioutil.ReadDir(string(dir))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
+
+func (dir dirTarget) Remove(name string) error {
+	return os.Remove(filepath.Join(string(dir), name))
+}
+
+//使用存储保存备份：s3://bucket/backups?endpoint=127.0.0.1:9000，consul://127.0.0.1:8500/backups 等
+type storageTarget struct {
+	engine plugins.StorageEngine
+}
+
+func (st *storageTarget) Upload(name string, content []byte) error {
+	return st.engine.Put(name, content)
+}
+
+func (st *storageTarget) List() ([]string, error) {
+	files, err := st.engine.Search()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names, nil
+}
+
+func (st *storageTarget) Remove(name string) error {
+	return st.engine.Remove(name)
+}
+
+//根据地址创建备份位置，支持：file://, ftp:// 和 --storage 支持的存储
+func NewTarget(address string) (target Target, err error) {
+	defer util.Catch(func(e error) {
+		err = e
+	})
+	config, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	switch config.Scheme {
+	case "":
+		return nil, errors.New("invalid backup target: " + address)
+	case "file":
+		return dirTarget(config.Path), nil
+	case "ftp":
+		return newFtpTarget(config), nil
+	}
+	return &storageTarget{engine: storage.FindStorage(address)}, nil
+}
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"sync"
+)
+
+var logger = logs.New("cluster")
+
+//多个aginx使用同一个集群存储时，只有leader运行的服务（证书续期、服务发现），防止重复申请证书和同时修改配置。
+//配置的修改通过存储同步，全部节点都会在本地应用。
+type Leader struct {
+	elector   plugins.Elector
+	node      string
+	factories []func() util.Service
+
+	lock     sync.Mutex
+	leader   bool
+	services []util.Service
+	started  bool
+	closeC   chan struct{}
+	doneC    chan struct{}
+}
+
+//elector为nil时（不支持选举的存储）当前节点就是leader
+func New(elector plugins.Elector, node string) *Leader {
+	return &Leader{
+		elector: elector, node: node, factories: make([]func() util.Service, 0),
+		closeC: make(chan struct{}), doneC: make(chan struct{}),
+	}
+}
+
+//成为leader时调用factory创建并启动服务，失去leader时停止。factory返回nil时忽略
+func (l *Leader) Run(factory ...func() util.Service) *Leader {
+	l.factories = append(l.factories, factory...)
+	return l
+}
+
+func (l *Leader) Node() string {
+	return l.node
+}
+
+func (l *Leader) IsLeader() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.leader
+}
+
+func (l *Leader) elected() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.leader {
+		return
+	}
+	l.leader = true
+	for _, factory := range l.factories {
+		if service := factory(); service != nil {
+			if err := service.Start(); err != nil {
+				logger.WithError(err).Warn("start leader service")
+				continue
+			}
+			l.services = append(l.services, service)
+		}
+	}
+	logger.Info("start leader services: ", l.node)
+	util.PublishEvent(util.EventLeaderChange, l.node, nil, map[string]interface{}{"node": l.node, "leader": true})
+}
+
+func (l *Leader) lost() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.leader {
+		return
+	}
+	l.leader = false
+	for i := len(l.services) - 1; i >= 0; i-- {
+		if err := l.services[i].Stop(); err != nil {
+			logger.WithError(err).Warn("stop leader service")
+		}
+	}
+	l.services = nil
+	logger.Info("stop leader services: ", l.node)
+	util.PublishEvent(util.EventLeaderChange, l.node, nil, map[string]interface{}{"node": l.node, "leader": false})
+}
+
+func (l *Leader) Start() error {
+	l.started = true
+	if l.elector == nil {
+		l.elected()
+		close(l.doneC)
+		return nil
+	}
+	leaderC := l.elector.Elect(l.node, l.closeC)
+	go func() {
+		defer close(l.doneC)
+		defer util.Catch()
+		for leader := range leaderC {
+			if leader {
+				l.elected()
+			} else {
+				l.lost()
+			}
+		}
+	}()
+	return nil
+}
+
+func (l *Leader) Stop() error {
+	if !l.started {
+		return nil
+	}
+	close(l.closeC)
+	<-l.doneC
+	l.lost()
+	return nil
+}
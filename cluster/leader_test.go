@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"github.com/ihaiker/aginx/util"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeElector struct {
+	leaderC chan bool
+}
+
+func (f *fakeElector) Elect(node string, closeC <-chan struct{}) <-chan bool {
+	out := make(chan bool)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-closeC:
+				return
+			case leader := <-f.leaderC:
+				out <- leader
+			}
+		}
+	}()
+	return out
+}
+
+type counter struct {
+	starts, stops int
+}
+
+func (c *counter) Start() error {
+	c.starts++
+	return nil
+}
+
+func (c *counter) Stop() error {
+	c.stops++
+	return nil
+}
+
+func await(t *testing.T, fn func() bool) {
+	for i := 0; i < 100 && !fn(); i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.True(t, fn())
+}
+
+func TestLeaderWithoutElector(t *testing.T) {
+	service := new(counter)
+	leader := New(nil, "node1")
+	leader.Run(func() util.Service { return service }, func() util.Service { return nil })
+	assert.False(t, leader.IsLeader())
+
+	assert.Nil(t, leader.Start())
+	assert.True(t, leader.IsLeader())
+	assert.Equal(t, 1, service.starts)
+
+	assert.Nil(t, leader.Stop())
+	assert.False(t, leader.IsLeader())
+	assert.Equal(t, 1, service.stops)
+}
+
+func TestLeaderElection(t *testing.T) {
+	elector := &fakeElector{leaderC: make(chan bool)}
+	created := make([]*counter, 0)
+	leader := New(elector, "node1").Run(func() util.Service {
+		service := new(counter)
+		created = append(created, service)
+		return service
+	})
+	assert.Nil(t, leader.Start())
+	assert.False(t, leader.IsLeader())
+
+	elector.leaderC <- true
+	await(t, leader.IsLeader)
+	assert.Len(t, created, 1)
+	assert.Equal(t, 1, created[0].starts)
+
+	//失去leader时停止服务，再次成为leader时重新创建
+	elector.leaderC <- false
+	await(t, func() bool { return !leader.IsLeader() })
+	assert.Equal(t, 1, created[0].stops)
+
+	elector.leaderC <- true
+	await(t, leader.IsLeader)
+	assert.Len(t, created, 2)
+
+	assert.Nil(t, leader.Stop())
+	assert.False(t, leader.IsLeader())
+	assert.Equal(t, 1, created[1].stops)
+}
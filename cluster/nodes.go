@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	refreshInterval = time.Second * 10 //更新节点信息的间隔
+	ReloadPath      = "/api/cluster/reload"
+)
+
+//最后一次重启nginx的结果
+type Reload struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+//注册在存储中的节点
+type Node struct {
+	Name    string    `json:"node"`
+	Address string    `json:"address,omitempty"` //api地址，例如：http://10.0.0.1:8011
+	Labels  []string  `json:"labels,omitempty"`  //节点标签，例如：region=eu
+	Leader  bool      `json:"leader"`
+	Started time.Time `json:"started"`
+	Reload  *Reload   `json:"reload,omitempty"`
+}
+
+//节点重启nginx的结果
+type Result struct {
+	Node    string `json:"node"`
+	Address string `json:"address,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+//节点注册：每个aginx把自己的api地址和最后一次重启nginx的结果注册到存储中，可以查看全部节点是否应用了配置的修改
+type Nodes struct {
+	membership plugins.Membership
+	leader     *Leader
+	client     *http.Client
+
+	lock    sync.Mutex
+	self    Node
+	updateC chan struct{}
+	closeC  chan struct{}
+
+	unsubscribe func()
+}
+
+//membership为nil时（不支持节点注册的存储）只有当前节点
+func NewNodes(membership plugins.Membership, leader *Leader, address string, labels ...string) *Nodes {
+	return &Nodes{
+		membership: membership, leader: leader,
+		client:  &http.Client{Timeout: time.Second * 30},
+		self:    Node{Name: leader.Node(), Address: address, Labels: labels, Started: time.Now()},
+		updateC: make(chan struct{}, 1), closeC: make(chan struct{}),
+	}
+}
+
+//当前节点
+func (n *Nodes) Self() *Node {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	self := n.self
+	self.Leader = n.leader.IsLeader()
+	return &self
+}
+
+//全部节点，按照名称排序
+func (n *Nodes) List() ([]*Node, error) {
+	self := n.Self()
+	if n.membership == nil {
+		return []*Node{self}, nil
+	}
+	members, err := n.membership.Members()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*Node{self}
+	for name, info := range members {
+		if name == self.Name {
+			continue
+		}
+		node := &Node{Name: name}
+		if err := json.Unmarshal(info, node); err != nil {
+			logger.WithError(err).Warn("invalid node info ", name)
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes, nil
+}
+
+func (n *Nodes) join() {
+	if n.membership == nil {
+		return
+	}
+	info, _ := json.Marshal(n.Self())
+	if err := n.membership.Join(n.self.Name, info, n.closeC); err != nil {
+		logger.WithError(err).Warn("register node ", n.self.Name)
+	}
+}
+
+func (n *Nodes) update() {
+	select {
+	case n.updateC <- struct{}{}:
+	default:
+	}
+}
+
+//记录重启nginx的结果，leader变化时更新节点信息
+func (n *Nodes) onEvent(event *util.Event) {
+	switch event.Name {
+	case util.EventReload:
+		n.lock.Lock()
+		n.self.Reload = &Reload{Time: event.Time, Error: event.Error}
+		n.lock.Unlock()
+		n.update()
+	case util.EventLeaderChange:
+		n.update()
+	}
+}
+
+//当前节点重启nginx
+func (n *Nodes) ReloadLocal(reload func() error) *Result {
+	self := n.Self()
+	result := &Result{Node: self.Name, Address: self.Address, Success: true}
+	if err := reload(); err != nil {
+		result.Success, result.Error = false, err.Error()
+	}
+	return result
+}
+
+//通知节点重启nginx，使用请求的认证信息访问节点的api
+func (n *Nodes) reloadRemote(node *Node, authorization string) *Result {
+	result := &Result{Node: node.Name, Address: node.Address}
+	err := func() error {
+		if node.Address == "" {
+			return errors.New("the address of node is unknown")
+		}
+		request, err := http.NewRequest(http.MethodPost, node.Address+ReloadPath+"?local=true", nil)
+		if err != nil {
+			return err
+		}
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		response, err := n.client.Do(request)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = response.Body.Close() }()
+		body, _ := ioutil.ReadAll(response.Body)
+		if response.StatusCode/100 != 2 {
+			return fmt.Errorf("%s %s", response.Status, string(body))
+		}
+		results := make([]*Result, 0)
+		if err = json.Unmarshal(body, &results); err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return errors.New("no result")
+		}
+		result.Success, result.Error = results[0].Success, results[0].Error
+		return nil
+	}()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+//全部节点重启nginx，返回每个节点的结果
+func (n *Nodes) Reload(reload func() error, authorization string) []*Result {
+	nodes, err := n.List()
+	if err != nil {
+		logger.WithError(err).Warn("list nodes")
+		nodes = []*Node{n.Self()}
+	}
+	results := make([]*Result, len(nodes))
+	wg := sync.WaitGroup{}
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *Node) {
+			defer wg.Done()
+			if node.Name == n.self.Name {
+				results[i] = n.ReloadLocal(reload)
+			} else {
+				results[i] = n.reloadRemote(node, authorization)
+			}
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
+
+func (n *Nodes) Start() error {
+	n.unsubscribe = util.SubscribeEvent(n.onEvent)
+	n.join()
+	go func() {
+		defer util.Catch()
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-n.closeC:
+				return
+			case <-ticker.C:
+				n.join()
+			case <-n.updateC:
+				n.join()
+			}
+		}
+	}()
+	return nil
+}
+
+func (n *Nodes) Stop() error {
+	if n.unsubscribe != nil {
+		n.unsubscribe()
+	}
+	close(n.closeC)
+	return nil
+}
@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ihaiker/aginx/util"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type fakeMembership struct {
+	lock    sync.Mutex
+	members map[string][]byte
+}
+
+func (f *fakeMembership) Join(node string, info []byte, closeC <-chan struct{}) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.members[node] = info
+	return nil
+}
+
+func (f *fakeMembership) Members() (map[string][]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	members := make(map[string][]byte)
+	for name, info := range f.members {
+		members[name] = info
+	}
+	return members, nil
+}
+
+func (f *fakeMembership) node(name string) *Node {
+	members, _ := f.Members()
+	node := new(Node)
+	if info, has := members[name]; has {
+		_ = json.Unmarshal(info, node)
+	}
+	return node
+}
+
+func TestNodesWithoutMembership(t *testing.T) {
+	leader := New(nil, "node1")
+	nodes := NewNodes(nil, leader, "http://10.0.0.1:8011")
+	assert.Nil(t, leader.Start())
+	defer func() { _ = leader.Stop() }()
+
+	list, err := nodes.List()
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "node1", list[0].Name)
+	assert.True(t, list[0].Leader)
+
+	results := nodes.Reload(func() error { return errors.New("nginx: [emerg]") }, "")
+	assert.Equal(t, []*Result{{Node: "node1", Address: "http://10.0.0.1:8011", Success: false, Error: "nginx: [emerg]"}}, results)
+}
+
+func TestNodesRegister(t *testing.T) {
+	membership := &fakeMembership{members: make(map[string][]byte)}
+	leader := New(nil, "node1")
+	nodes := NewNodes(membership, leader, "http://10.0.0.1:8011")
+	assert.Nil(t, leader.Start())
+	assert.Nil(t, nodes.Start())
+	defer func() {
+		_ = nodes.Stop()
+		_ = leader.Stop()
+	}()
+	assert.Equal(t, "http://10.0.0.1:8011", membership.node("node1").Address)
+
+	//重启nginx后更新节点信息
+	util.PublishEvent(util.EventReload, "reload NGINX", errors.New("nginx: [emerg]"), nil)
+	await(t, func() bool {
+		reload := membership.node("node1").Reload
+		return reload != nil && reload.Error == "nginx: [emerg]"
+	})
+}
+
+func TestNodesReload(t *testing.T) {
+	authorization := ""
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		assert.Equal(t, ReloadPath, r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("local"))
+		_ = json.NewEncoder(w).Encode([]*Result{{Node: "node2", Success: true}})
+	}))
+	defer remote.Close()
+	failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Authorization Required"))
+	}))
+	defer failed.Close()
+
+	membership := &fakeMembership{members: map[string][]byte{}}
+	info, _ := json.Marshal(&Node{Name: "node2", Address: remote.URL})
+	_ = membership.Join("node2", info, nil)
+	info, _ = json.Marshal(&Node{Name: "node3", Address: failed.URL})
+	_ = membership.Join("node3", info, nil)
+
+	nodes := NewNodes(membership, New(nil, "node1"), "http://10.0.0.1:8011")
+	results := nodes.Reload(func() error { return nil }, "Basic YWdpbng6YWdpbng=")
+	assert.Len(t, results, 3)
+	assert.Equal(t, &Result{Node: "node1", Address: "http://10.0.0.1:8011", Success: true}, results[0])
+	assert.Equal(t, &Result{Node: "node2", Address: remote.URL, Success: true}, results[1])
+	assert.Equal(t, "node3", results[2].Node)
+	assert.False(t, results[2].Success)
+	assert.Equal(t, "401 Unauthorized Authorization Required", results[2].Error)
+	assert.Equal(t, "Basic YWdpbng6YWdpbng=", authorization)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage"
+	fileStorage "github.com/ihaiker/aginx/storage/file"
+	. "github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+//--storage 指定的存储，为空时使用本地nginx的配置
+func backupStorage(cmd *cobra.Command) plugins.StorageEngine {
+	cluster, _ := cmd.Flags().GetString("storage")
+	if cluster == "" {
+		return fileStorage.MustSystem()
+	}
+	engine := storage.FindStorage(cluster)
+	AssertTrue(engine != nil, "the storage not found: "+cluster)
+	return engine
+}
+
+var BackupCmd = &cobra.Command{
+	Use: "backup", Short: "Backup all configuration files and certificates to a tar.gz file",
+	Example: "aginx backup -S consul://127.0.0.1:8500/aginx aginx.tar.gz",
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		engine := backupStorage(cmd)
+		output := fmt.Sprintf("aginx-%s.tar.gz", time.Now().Format("20060102-150405"))
+		if len(args) == 1 {
+			output = args[0]
+		}
+		file, err := os.Create(output)
+		PanicIfError(err)
+		defer func() { _ = file.Close() }()
+
+		count, err := storage.Backup(engine, file)
+		PanicIfError(err)
+		fmt.Printf("backup %d files to %s\n", count, output)
+		return nil
+	},
+}
+
+var RestoreCmd = &cobra.Command{
+	Use: "restore", Short: "Restore configuration files and certificates from a tar.gz backup",
+	Long:    "Restore configuration files and certificates from a tar.gz backup, the nginx of aginx server will reload after the files synchronized",
+	Example: "aginx restore -S consul://127.0.0.1:8500/aginx aginx.tar.gz",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		clean, _ := cmd.Flags().GetBool("clean")
+		engine := backupStorage(cmd)
+
+		file, err := os.Open(args[0])
+		PanicIfError(err)
+		defer func() { _ = file.Close() }()
+
+		files, err := storage.ReadBackup(file)
+		PanicIfError(err)
+		PanicIfError(storage.Restore(engine, files, clean))
+		fmt.Printf("restore %d files from %s\n", len(files), args[0])
+		return nil
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{BackupCmd, RestoreCmd} {
+		cmd.PersistentFlags().StringP("storage", "S", "", "the storage of configuration, default is the configuration of the local nginx")
+	}
+	RestoreCmd.PersistentFlags().BoolP("clean", "", false, "remove the files not in the backup")
+}
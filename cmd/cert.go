@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/api"
+	"github.com/spf13/cobra"
+)
+
+var selfSignedCmd = &cobra.Command{
+	Use: "self-signed", Short: "generate a self-signed certificate and use it in the matching server",
+	Example: "aginx cert self-signed --domain foo.local --days 365", Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		domain, _ := cmd.Flags().GetString("domain")
+		days, _ := cmd.Flags().GetInt("days")
+		if domain == "" {
+			return errors.New("the domain is empty")
+		}
+		address, _ := cmd.Flags().GetString("api")
+		security, _ := cmd.Flags().GetString("security")
+		ca, _ := cmd.Flags().GetString("tls-ca")
+		cert, _ := cmd.Flags().GetString("tls-cert")
+		key, _ := cmd.Flags().GetString("tls-key")
+		client, err := api.NewClient(address, security, ca, cert, key)
+		if err != nil {
+			return err
+		}
+		storeFile, err := client.SSL().SelfSigned(domain, days)
+		if err != nil {
+			return err
+		}
+		bs, _ := json.MarshalIndent(storeFile, "", "\t")
+		fmt.Println(string(bs))
+		return nil
+	},
+}
+
+var CertCmd = &cobra.Command{
+	Use: "cert", Short: "manage certificates",
+}
+
+func init() {
+	CertCmd.PersistentFlags().StringP("api", "i", "127.0.0.1:8011", "restful api address.")
+	CertCmd.PersistentFlags().StringP("security", "s", "", "base auth for restful api, example: user:passwd")
+	AddClientTLSFlags(CertCmd)
+
+	selfSignedCmd.Flags().StringP("domain", "d", "", "the domain of the certificate, example: foo.local or *.foo.local")
+	selfSignedCmd.Flags().IntP("days", "", 365, "the validity days of the certificate")
+	CertCmd.AddCommand(selfSignedCmd)
+}
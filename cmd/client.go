@@ -11,19 +11,47 @@ import (
 	"github.com/spf13/viper"
 	"io/ioutil"
 	"os"
-	"strings"
 )
 
 var aginx api.Aginx
 
+//命令行中指定的参数，未指定时使用viper（环境变量、配置文件）
+func flagValue(cmd *cobra.Command, name string) string {
+	if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+		return flag.Value.String()
+	}
+	return viper.GetString(name)
+}
+
 func preRun(cmd *cobra.Command, args []string) {
-	address := "http://" + viper.GetString("api")
-	security := viper.GetString("security")
-	aginx = api.New(address)
-	if security != "" {
-		userAndPwd := strings.SplitN(security, ":", 2)
-		aginx.Auth(userAndPwd[0], userAndPwd[1])
+	address, security := flagValue(cmd, "api"), flagValue(cmd, "security")
+	if server, _ := cmd.Flags().GetString("server"); server != "" {
+		address = server
+	}
+	if auth, _ := cmd.Flags().GetString("auth"); auth != "" {
+		security = auth
 	}
+	var err error
+	aginx, err = api.NewClient(address, security,
+		flagValue(cmd, "tls-ca"), flagValue(cmd, "tls-cert"), flagValue(cmd, "tls-key"))
+	util.PanicIfError(err)
+}
+
+//读取 --file 指定的文件内容，未指定时读取标准输入，并解析为nginx配置
+func readDirectives(cmd *cobra.Command) []*nginx.Directive {
+	var bs []byte
+	var err error
+	if file, _ := cmd.Flags().GetString("file"); file != "" {
+		bs, err = ioutil.ReadFile(file)
+	} else {
+		bs, err = ioutil.ReadAll(os.Stdin)
+	}
+	util.PanicIfError(err)
+	util.AssertTrue(len(bs) > 0, "the content is empty")
+
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("", bs))
+	util.PanicIfError(err)
+	return conf.Body
 }
 
 var reloadCmd = &cobra.Command{
@@ -51,53 +79,31 @@ var selectCmd = &cobra.Command{
 }
 var addCmd = &cobra.Command{
 	Use: "add", Short: "add configuration",
-	PreRun: preRun, Example: "cat <file> | aginx client add http",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		defer util.Catch(func(err error) {
-			fmt.Println(err)
+	PreRun: preRun, Example: "cat <file> | aginx client add http\naginx client add http --file hosts.d/api.conf",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer util.Catch(func(e error) {
+			err = e
 		})
-
-		bs, err := ioutil.ReadAll(os.Stdin)
-		util.PanicIfError(err)
-		if len(bs) == 0 {
-			return fmt.Errorf("add content is empty: %s", err)
-		}
-
-		conf, err := nginx.ReaderReadable(nil, plugins.NewFile("", bs))
-		util.PanicIfError(err)
-		if len(conf.Body) > 0 {
+		directives := readDirectives(cmd)
+		if len(directives) == 0 {
 			return fmt.Errorf("add content is empty")
 		}
-
-		err = aginx.Directive().Add(args, conf.Body...)
-		util.PanicIfError(err)
-		return err
+		return aginx.Directive().Add(args, directives...)
 	},
 }
 
 var modifyCmd = &cobra.Command{
 	Use: "modify", Short: "modify configuration",
 	PreRun: preRun, Example: "cat <file> | aginx client modify http",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		defer util.Catch(func(err error) {
-			fmt.Println(err)
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer util.Catch(func(e error) {
+			err = e
 		})
-
-		bs, err := ioutil.ReadAll(os.Stdin)
-		util.PanicIfError(err)
-		if len(bs) == 0 {
-			return fmt.Errorf("modify content is empty: %s", err)
-		}
-
-		conf, err := nginx.ReaderReadable(nil, plugins.NewFile("", bs))
-		util.PanicIfError(err)
-		if len(conf.Body) != 1 {
+		directives := readDirectives(cmd)
+		if len(directives) != 1 {
 			return fmt.Errorf("the modify content must be only one")
 		}
-
-		err = aginx.Directive().Modify(args, conf.Body[0])
-		util.PanicIfError(err)
-		return err
+		return aginx.Directive().Modify(args, directives[0])
 	},
 }
 
@@ -198,7 +204,8 @@ var ClientCmd = &cobra.Command{
 func init() {
 	ClientCmd.PersistentFlags().StringP("conf", "c", "", "AGINX configuration file location")
 	ClientCmd.PersistentFlags().StringP("api", "i", "127.0.0.1:8011", "restful api address.")
-	ClientCmd.PersistentFlags().StringP("security", "s", "", "base auth for restful api, example: user:passwd")
+	ClientCmd.PersistentFlags().StringP("security", "s", "", "base auth or token for restful api, example: user:passwd")
+	AddClientTLSFlags(ClientCmd)
 
 	ClientCmd.AddCommand(reloadCmd)
 	ClientCmd.AddCommand(selectCmd, addCmd, modifyCmd, deleteCmd)
@@ -208,4 +215,11 @@ func init() {
 	ClientCmd.AddCommand(sslCmd, simpleCmd)
 
 	_ = viper.BindPFlags(ClientCmd.PersistentFlags())
+
+	//不绑定到viper，避免和 aginx server --server 冲突
+	ClientCmd.PersistentFlags().StringP("server", "", "", "the address of aginx server, same as --api, example: http://127.0.0.1:8011")
+	ClientCmd.PersistentFlags().StringP("auth", "", "", "the user:password or token for restful api, same as --security")
+	for _, cmd := range []*cobra.Command{addCmd, modifyCmd} {
+		cmd.PersistentFlags().StringP("file", "f", "", "read the configuration from the file instead of stdin")
+	}
 }
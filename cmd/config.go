@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ihaiker/aginx/conf"
+	. "github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"sort"
+	"strings"
+)
+
+//包含密码的参数，打印时隐藏
+var secretFlags = []string{"security", "user", "jwt-key", "hmac", "password", "secret", "token"}
+
+func secretFlag(name string) bool {
+	for _, secret := range secretFlags {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+//参数值的来源：env, flag, file, default
+func flagSource(flag *pflag.Flag) string {
+	switch {
+	case os.Getenv(EnvKey(flag.Name)) != "":
+		return "env"
+	case flag.Changed:
+		return "flag"
+	case viper.InConfig(flag.Name):
+		return "file"
+	}
+	return "default"
+}
+
+//yaml格式的参数值，json的字符串和数组也是yaml
+func printValue(cmd *cobra.Command, flag *pflag.Flag) string {
+	var value interface{}
+	switch flag.Value.Type() {
+	case "bool":
+		value = viper.GetBool(flag.Name)
+	case "int":
+		value = viper.GetInt(flag.Name)
+	case "stringArray", "stringSlice":
+		value = GetStringArray(cmd, flag.Name)
+	default:
+		value = viper.GetString(flag.Name)
+	}
+	bs, _ := json.Marshal(value)
+	return string(bs)
+}
+
+var ConfigCmd = &cobra.Command{
+	Use: "config", Short: "the configuration of the AGINX server",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use: "print", Short: "print the effective configuration of the AGINX server",
+	Long: `print the effective configuration in yaml with the source of each value.
+precedence: env (AGINX_EXPOSE_PORT) > flag (--expose-port) > file (--conf or /etc/aginx/aginx.yaml) > default.`,
+	Example: "aginx config print --conf /etc/aginx/aginx.yaml",
+	Args:    cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		//和server命令使用相同名称的参数，这里重新绑定
+		if err := viper.BindPFlags(cmd.PersistentFlags()); err != nil {
+			return err
+		}
+		return conf.Load(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+		flags := make([]*pflag.Flag, 0)
+		cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Name != "conf" && flag.Name != "show-secrets" {
+				flags = append(flags, flag)
+			}
+		})
+		sort.Slice(flags, func(i, j int) bool {
+			return flags[i].Name < flags[j].Name
+		})
+		if configFile := viper.GetString("conf"); configFile != "" {
+			fmt.Printf("# config file: %s\n", configFile)
+		} else if configFile = conf.Find(); configFile != "" {
+			fmt.Printf("# config file: %s\n", configFile)
+		}
+		for _, flag := range flags {
+			value := printValue(cmd, flag)
+			if !showSecrets && secretFlag(flag.Name) && value != `""` && value != "[]" {
+				value = `"******"`
+			}
+			fmt.Printf("%s: %s # %s\n", flag.Name, value, flagSource(flag))
+		}
+		return nil
+	},
+}
+
+func init() {
+	AddServerFlags(configPrintCmd)
+	configPrintCmd.PersistentFlags().BoolP("show-secrets", "", false, "print the passwords and keys")
+	ConfigCmd.AddCommand(configPrintCmd)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+//只使用docker服务发现，等同于 aginx registry --docker
+var DockerCmd = &cobra.Command{
+	Use: "docker", Short: "Automatically configure docker containers or services to NGINX",
+	Long: `Watch the docker daemon, containers or services with labels like aginx.domain=example.com and aginx.port=8080
+will be automatically published to NGINX, and removed when they stop.`,
+	Example: "aginx docker --api 127.0.0.1:8011 --docker-ip 10.24.0.1",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		//和其他命令使用相同名称的参数，这里重新绑定
+		if err := viper.BindPFlags(cmd.Flags()); err != nil {
+			return err
+		}
+		viper.Set("docker", true)
+		return RegistryCmd.PreRunE(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RegistryCmd.RunE(cmd, args)
+	},
+}
+
+func init() {
+	AddRegistryFlag(DockerCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ihaiker/aginx/nginx"
+	. "github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//参数中的文件，目录时查找目录中的全部.conf文件
+func formatFiles(args []string) []string {
+	files := make([]string, 0)
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		PanicIfError(err)
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		PanicIfError(filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && filepath.Ext(path) == ".conf" {
+				files = append(files, path)
+			}
+			return err
+		}))
+	}
+	return files
+}
+
+var FmtCmd = &cobra.Command{
+	Use: "fmt [file|dir...]", Short: "Format the configuration files with consistent indentation and alignment",
+	Long: `Format the configuration files: indent with 4 spaces, merge the blank lines, align the values of map, geo, types
+and keep the comments. the files are rewritten in place, the .conf files in the directories are formatted,
+read the standard input and write to the standard output when no file is given.
+use --check in CI to print the difference and exit 1 when the files are not formatted.`,
+	Example: "aginx fmt /etc/nginx\naginx fmt --check nginx.conf conf.d",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		check, _ := cmd.Flags().GetBool("check")
+
+		if len(args) == 0 {
+			content, err := ioutil.ReadAll(os.Stdin)
+			PanicIfError(err)
+			formatted, err := nginx.Format("-", content)
+			PanicIfError(err)
+			if check {
+				if !bytes.Equal(content, formatted) {
+					fmt.Print(Diff(string(content), string(formatted)))
+					return fmt.Errorf("the configuration is not formatted")
+				}
+				return nil
+			}
+			_, err = os.Stdout.Write(formatted)
+			return err
+		}
+
+		unformatted := 0
+		for _, file := range formatFiles(args) {
+			content, err := ioutil.ReadFile(file)
+			PanicIfError(err)
+			formatted, err := nginx.Format(file, content)
+			PanicIfError(err)
+			if bytes.Equal(content, formatted) {
+				continue
+			}
+			unformatted++
+			if check {
+				fmt.Printf("--- %s\n%s", file, Diff(string(content), string(formatted)))
+			} else {
+				PanicIfError(ioutil.WriteFile(file, formatted, 0644))
+				fmt.Println(file)
+			}
+		}
+		if check && unformatted > 0 {
+			return fmt.Errorf("found %d files not formatted", unformatted)
+		}
+		return nil
+	},
+}
+
+func init() {
+	FmtCmd.PersistentFlags().BoolP("check", "", false, "only print the difference, exit 1 when the files are not formatted")
+}
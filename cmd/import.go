@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage"
+	fileStorage "github.com/ihaiker/aginx/storage/file"
+	. "github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+//检查配置中引用的路径，返回找不到的路径数量
+func checkPaths(conf *nginx.Configuration, configDir string) (missing int) {
+	for _, ref := range nginx.PathReferences(conf, configDir) {
+		switch {
+		case !ref.Exists:
+			missing++
+			fmt.Printf("[missing] %s: %s\n", ref.File, ref.Directive)
+		case !ref.Inside:
+			fmt.Printf("[outside] %s: %s (not imported, must exist on every node)\n", ref.File, ref.Directive)
+		}
+	}
+	return
+}
+
+var ImportCmd = &cobra.Command{
+	Use: "import", Short: "Import the configuration of an existing nginx installation into storage",
+	Long: `Import the configuration of an existing nginx installation into storage.
+Parse nginx.conf and all includes, check the paths used in the configuration, then upload all files in the configuration directory.`,
+	Example: "aginx import consul://127.0.0.1:8500/aginx --path /etc/nginx",
+	Args:    cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		path, _ := cmd.Flags().GetString("path")
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		conf := nginx.MustConf()
+		if path != "" {
+			if conf = path; filepath.Ext(path) != ".conf" {
+				conf = filepath.Join(path, nginx.NGINX_CONF)
+			}
+		}
+		conf, err = filepath.Abs(conf)
+		PanicIfError(err)
+		if _, err = os.Stat(conf); err != nil {
+			return err
+		}
+
+		local := fileStorage.New(conf)
+		cfg, err := nginx.Readable(local)
+		PanicIfError(err)
+		if missing := checkPaths(cfg, filepath.Dir(conf)); missing > 0 && !force {
+			return fmt.Errorf("%d paths not found, fix them or use --force", missing)
+		}
+
+		files, err := local.List()
+		PanicIfError(err)
+		if dryRun {
+			for _, file := range files {
+				fmt.Println("[import]", file.Name)
+			}
+			return nil
+		}
+
+		cluster := storage.FindStorage(args[0])
+		if cluster == nil || !cluster.IsCluster() {
+			return errors.New("the storage not found: " + args[0])
+		}
+		for _, file := range files {
+			PanicIfError(cluster.Put(file.Name, file.Content))
+			fmt.Println("[import]", file.Name)
+		}
+		fmt.Printf("imported %d files from %s\n", len(files), filepath.Dir(conf))
+		return nil
+	},
+}
+
+func init() {
+	ImportCmd.PersistentFlags().StringP("path", "p", "", "the nginx configuration directory or file, default is the configuration of the local nginx")
+	ImportCmd.PersistentFlags().BoolP("force", "f", false, "import even if some paths in configuration are not found")
+	ImportCmd.PersistentFlags().BoolP("dry-run", "", false, "only check the configuration, do not import")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/ingress"
+	"github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"time"
+)
+
+var IngressCmd = &cobra.Command{
+	Use: "ingress", Short: "the AGINX kubernetes ingress controller",
+	Long: `Watch kubernetes Ingress, Service and Endpoints resources and publish them to NGINX by the AGINX api,
+the configuration files are saved in ingress.d, and other configurations can still be modified by the api.`,
+	Example: "aginx ingress --api 127.0.0.1:8011 --ingress-class aginx",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		//和其他命令使用相同名称的参数，这里重新绑定
+		return viper.BindPFlags(cmd.Flags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer util.Catch(func(err error) {
+			fmt.Println(util.Stack())
+			cmd.PrintErrln(err)
+		})
+		kube, err := ingress.NewClient(viper.GetString("kube-api"), viper.GetString("kube-token-file"),
+			viper.GetString("kube-ca"), viper.GetBool("kube-insecure"))
+		util.PanicIfError(err)
+
+		client, err := api.NewClient(viper.GetString("api"), viper.GetString("security"),
+			viper.GetString("tls-ca"), viper.GetString("tls-cert"), viper.GetString("tls-key"))
+		util.PanicIfError(err)
+
+		controller := ingress.NewController(kube, client, viper.GetString("kube-namespace"),
+			viper.GetString("ingress-class"), viper.GetDuration("kube-interval"))
+		return util.NewDaemon().Add(controller).Start()
+	},
+}
+
+func init() {
+	IngressCmd.PersistentFlags().StringP("api", "i", "127.0.0.1:8011", "restful api address.")
+	IngressCmd.PersistentFlags().StringP("security", "s", "", "base auth for restful api, example: user:passwd")
+	AddClientTLSFlags(IngressCmd)
+
+	IngressCmd.PersistentFlags().StringP("kube-api", "", "", "The kubernetes api server address, use the in-cluster service account if empty. example: https://127.0.0.1:6443")
+	IngressCmd.PersistentFlags().StringP("kube-token-file", "", "", "The bearer token file to access the kubernetes api.")
+	IngressCmd.PersistentFlags().StringP("kube-ca", "", "", "The CA file to verify the kubernetes api server.")
+	IngressCmd.PersistentFlags().BoolP("kube-insecure", "", false, "Skip verify the kubernetes api server certificate.")
+	IngressCmd.PersistentFlags().StringP("kube-namespace", "", "", "Only watch the namespace, all namespaces if empty.")
+	IngressCmd.PersistentFlags().DurationP("kube-interval", "", time.Second*5, "Interval to sync the kubernetes resources.")
+	IngressCmd.PersistentFlags().StringP("ingress-class", "", "aginx", "Only handle the ingress of the class and ingress without class, all ingress if empty.")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/nginx"
+	. "github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+)
+
+var LintCmd = &cobra.Command{
+	Use: "lint", Short: "Check the configuration for problems that nginx -t does not report",
+	Long: `Check the configuration for problems that nginx -t does not report:
+	duplicate-server-name   the server_name is duplicated on the same port, nginx only uses the first server.
+	proxy-pass-slash        the trailing slash of location and proxy_pass uri is inconsistent.
+	ssl-protocols           the server enables ssl without ssl_protocols.
+	ssl-insecure-protocols  the ssl_protocols contains SSLv2, SSLv3, TLSv1 or TLSv1.1.
+	invalid-context         the directive is used in a context not allowed by the nginx documentation.
+	unknown-directive       the directive is not a known nginx directive, for example: porxy_pass.
+check the configuration of the local nginx, the storage (--storage) or the aginx server (--api), exit 1 when problems found.`,
+	Example: "aginx lint -S consul://127.0.0.1:8500/aginx --skip ssl-insecure-protocols",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		skip, _ := cmd.Flags().GetStringArray("skip")
+
+		var lints []*nginx.Lint
+		if address, _ := cmd.Flags().GetString("api"); address != "" {
+			security, _ := cmd.Flags().GetString("security")
+			ca, _ := cmd.Flags().GetString("tls-ca")
+			cert, _ := cmd.Flags().GetString("tls-cert")
+			key, _ := cmd.Flags().GetString("tls-key")
+			client, err := api.NewClient(address, security, ca, cert, key)
+			PanicIfError(err)
+			lints, err = client.Lint(skip...)
+			PanicIfError(err)
+		} else {
+			lints, err = nginx.LintConfiguration(backupStorage(cmd), skip...)
+			PanicIfError(err)
+		}
+
+		for _, lint := range lints {
+			fmt.Printf("%s:%d: [%s] %s\n", lint.File, lint.Line, lint.Rule, lint.Message)
+		}
+		if len(lints) > 0 {
+			return fmt.Errorf("found %d problems", len(lints))
+		}
+		return nil
+	},
+}
+
+func init() {
+	LintCmd.PersistentFlags().StringP("storage", "S", "", "the storage of configuration, default is the configuration of the local nginx")
+	LintCmd.PersistentFlags().StringP("api", "i", "", "check the configuration of the aginx server, example: 127.0.0.1:8011")
+	LintCmd.PersistentFlags().StringP("security", "s", "", "base auth for restful api, example: user:passwd")
+	LintCmd.PersistentFlags().StringArrayP("skip", "", []string{}, "the rules to skip")
+	AddClientTLSFlags(LintCmd)
+}
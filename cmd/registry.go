@@ -13,16 +13,21 @@ func AddRegistryFlag(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringP("conf", "c", "", "AGINX configuration file location")
 	cmd.PersistentFlags().StringP("api", "i", "127.0.0.1:8011", "restful api address.")
 	cmd.PersistentFlags().StringP("security", "s", "", "base auth for restful api, example: user:passwd")
+	AddClientTLSFlags(cmd)
 	registry.RegisterFlags(cmd)
 }
 
+//使用https访问api的证书，server命令中tls-cert、tls-key同时也是api服务使用的证书
+func AddClientTLSFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP("tls-ca", "", "", "the CA file to verify the api server certificate, use https if set.")
+	cmd.PersistentFlags().StringP("tls-cert", "", "", "the certificate file, use https if set.")
+	cmd.PersistentFlags().StringP("tls-key", "", "", "the private key file of --tls-cert.")
+}
+
 var RegistryCmd = &cobra.Command{
 	Use: "registry", Short: "the AGINX registry server", Example: "aginx registry --docker",
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if configFile := viper.GetString("conf"); configFile != "" {
-			return conf.ReadConfig(configFile, cmd)
-		}
-		return nil
+		return conf.Load(cmd)
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		defer util.Catch(func(err error) {
@@ -30,7 +35,7 @@ var RegistryCmd = &cobra.Command{
 			cmd.PrintErr(err)
 		})
 		bridge := registry.FindRegistry(cmd)
-		util.AssertTrue(bridge == nil, "Did not find any registry")
+		util.AssertTrue(bridge != nil, "Did not find any registry")
 		return util.NewDaemon().Add(bridge).Start()
 	},
 }
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/history"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+)
+
+func printVersions(versions []*history.Version) {
+	for _, version := range versions {
+		files := make([]string, len(version.Files))
+		for i, file := range version.Files {
+			files[i] = file.Name
+		}
+		fmt.Printf("%d\t%s\t%s\t%s\n", version.ID, version.Time.Format("2006-01-02 15:04:05"),
+			version.User, strings.Join(files, ","))
+	}
+}
+
+var RollbackCmd = &cobra.Command{
+	Use: "rollback", Short: "rollback configuration to the specified version",
+	Long:    "rollback configuration to the specified version, list all saved versions if version is not specified",
+	Example: "aginx rollback 12", Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		address, _ := cmd.Flags().GetString("api")
+		security, _ := cmd.Flags().GetString("security")
+		ca, _ := cmd.Flags().GetString("tls-ca")
+		cert, _ := cmd.Flags().GetString("tls-cert")
+		key, _ := cmd.Flags().GetString("tls-key")
+		client, err := api.NewClient(address, security, ca, cert, key)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			versions, err := client.History()
+			if err == nil {
+				printVersions(versions)
+			}
+			return err
+		}
+
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		versions, err := client.Rollback(version)
+		if err == nil {
+			fmt.Println("rollback versions:")
+			printVersions(versions)
+		}
+		return err
+	},
+}
+
+func init() {
+	RollbackCmd.PersistentFlags().StringP("api", "i", "127.0.0.1:8011", "restful api address.")
+	RollbackCmd.PersistentFlags().StringP("security", "s", "", "base auth for restful api, example: user:passwd")
+	AddClientTLSFlags(RollbackCmd)
+}
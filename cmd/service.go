@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	. "github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var serviceName = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+//服务停止的等待时间在 --shutdown-timeout 基础上增加的余量，aginx超时退出前不会被强制结束
+const stopTimeoutMargin = time.Second * 5
+
+//安装系统服务使用的配置
+type serviceConfig struct {
+	Name     string
+	Init     string        //systemd, sysv, windows
+	Exec     string        //aginx程序的绝对路径
+	Args     []string      //aginx server 的参数
+	Watchdog time.Duration //systemd WatchdogSec，0不开启
+	Start    bool          //安装后启动服务
+
+	StopTimeout time.Duration //systemd TimeoutStopSec，sysv停止时的等待时间
+}
+
+func newServiceConfig(cmd *cobra.Command) *serviceConfig {
+	name, _ := cmd.Flags().GetString("name")
+	AssertTrue(serviceName.MatchString(name), "invalid service name: "+name)
+	system, _ := cmd.Flags().GetString("init")
+	if system == "" || system == "auto" {
+		system = detectInit()
+	}
+	AssertTrue(validInit(system), "invalid init system: "+system)
+	watchdog, _ := cmd.Flags().GetDuration("watchdog")
+	noStart, _ := cmd.Flags().GetBool("no-start")
+	exe, err := os.Executable()
+	PanicIfError(err)
+	exe, err = filepath.EvalSymlinks(exe)
+	PanicIfError(err)
+	return &serviceConfig{Name: name, Init: system, Exec: exe, Watchdog: watchdog, Start: !noStart}
+}
+
+//使用 --keep-nginx 时停止服务不能结束nginx进程
+func (config *serviceConfig) KeepNginx() bool {
+	for _, arg := range config.Args {
+		if arg == "--keep-nginx" || arg == "--keep-nginx=true" {
+			return true
+		}
+	}
+	return false
+}
+
+var ServiceCmd = &cobra.Command{
+	Use: "service", Short: "Install AGINX server as a system service (systemd, sysv or windows service)",
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use: "install [-- server flags]", Short: "Install and start the AGINX server service",
+	Long: `Install the AGINX server as a system service, the arguments after -- are the flags of aginx server.
+systemd: write /etc/systemd/system/<name>.service (Type=notify with watchdog), enable and start it.
+sysv:    write /etc/init.d/<name> and register it with update-rc.d or chkconfig.
+windows: register the windows service <name> (start automatically), logs are written to aginx.log in the directory of aginx.exe.
+the environment file /etc/default/<name> is loaded if exists (linux), for example: AGINX_SECURITY=user:passwd`,
+	Example: "aginx service install -- --expose api.aginx.io,ssl --storage consul://127.0.0.1:8500/aginx",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		config := newServiceConfig(cmd)
+		//参数错误时服务无法启动，安装前检查
+		check := &cobra.Command{}
+		AddServerFlags(check)
+		PanicIfError(check.ParseFlags(args))
+		config.Args = args
+		shutdownTimeout, err := check.Flags().GetDuration("shutdown-timeout")
+		PanicIfError(err)
+		config.StopTimeout = shutdownTimeout + stopTimeoutMargin
+		PanicIfError(installService(config))
+		fmt.Printf("the service %s is installed (%s)\n", config.Name, config.Init)
+		return nil
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use: "uninstall", Short: "Stop and uninstall the AGINX server service", Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		config := newServiceConfig(cmd)
+		PanicIfError(uninstallService(config))
+		fmt.Printf("the service %s is uninstalled\n", config.Name)
+		return nil
+	},
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use: "status", Short: "Show the status of the AGINX server service", Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		return serviceStatus(newServiceConfig(cmd))
+	},
+}
+
+func init() {
+	ServiceCmd.PersistentFlags().StringP("name", "", "aginx", "the service name")
+	ServiceCmd.PersistentFlags().StringP("init", "", "auto", "the init system: auto, systemd, sysv, windows")
+	serviceInstallCmd.Flags().DurationP("watchdog", "", time.Second*30, "the systemd WatchdogSec, 0 to disable")
+	serviceInstallCmd.Flags().BoolP("no-start", "", false, "do not start the service after installed")
+	ServiceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd, serviceStatusCmd)
+}
@@ -0,0 +1,219 @@
+// +build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	. "github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const systemdUnit = `[Unit]
+Description=AGINX, api for nginx
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+EnvironmentFile=-/etc/default/{{.Name}}
+ExecStart={{systemdQuote .Exec}} server{{range .Args}} {{systemdQuote .}}{{end}}
+Restart=on-failure
+RestartSec=5s
+TimeoutStopSec={{seconds .StopTimeout}}{{if .KeepNginx}}
+KillMode=process{{end}}{{if .Watchdog}}
+WatchdogSec={{seconds .Watchdog}}{{end}}
+
+[Install]
+WantedBy=multi-user.target
+`
+
+const sysvScript = `#!/bin/sh
+### BEGIN INIT INFO
+# Provides:          {{.Name}}
+# Required-Start:    $network $remote_fs
+# Required-Stop:     $network $remote_fs
+# Default-Start:     2 3 4 5
+# Default-Stop:      0 1 6
+# Short-Description: AGINX, api for nginx
+### END INIT INFO
+
+NAME={{.Name}}
+PIDFILE=/var/run/$NAME.pid
+LOGFILE=/var/log/$NAME.log
+
+if [ -f /etc/default/$NAME ]; then
+    set -a
+    . /etc/default/$NAME
+    set +a
+fi
+
+status() {
+    if [ -f $PIDFILE ] && kill -0 $(cat $PIDFILE) 2>/dev/null; then
+        echo "$NAME is running, pid $(cat $PIDFILE)"
+        return 0
+    fi
+    echo "$NAME is stopped"
+    return 3
+}
+
+start() {
+    if status >/dev/null; then
+        echo "$NAME is already running"
+        return 0
+    fi
+    nohup {{shellQuote .Exec}} server{{range .Args}} {{shellQuote .}}{{end}} >> $LOGFILE 2>&1 &
+    echo $! > $PIDFILE
+    echo "$NAME started"
+}
+
+stop() {
+    if ! status >/dev/null; then
+        rm -f $PIDFILE
+        return 0
+    fi
+    kill -TERM $(cat $PIDFILE)
+    for i in $(seq 1 {{printf "%.0f" .StopTimeout.Seconds}}); do
+        kill -0 $(cat $PIDFILE) 2>/dev/null || break
+        sleep 1
+    done
+    rm -f $PIDFILE
+    echo "$NAME stopped"
+}
+
+case "$1" in
+    start) start ;;
+    stop) stop ;;
+    restart) stop; start ;;
+    status) status ;;
+    *) echo "Usage: $0 {start|stop|restart|status}"; exit 1 ;;
+esac
+`
+
+func validInit(system string) bool {
+	return system == "systemd" || system == "sysv"
+}
+
+func detectInit() string {
+	if stat, err := os.Stat("/run/systemd/system"); err == nil && stat.IsDir() {
+		return "systemd"
+	}
+	return "sysv"
+}
+
+//systemd ExecStart中的参数
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func renderService(content string, config *serviceConfig) ([]byte, error) {
+	t, err := template.New("").Funcs(template.FuncMap{
+		"systemdQuote": systemdQuote, "shellQuote": shellQuote,
+		"seconds": func(d time.Duration) string {
+			return fmt.Sprintf("%.0fs", d.Seconds())
+		},
+	}).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.NewBufferString("")
+	if err = t.Execute(out, config); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func servicePath(config *serviceConfig) string {
+	if config.Init == "systemd" {
+		return "/etc/systemd/system/" + config.Name + ".service"
+	}
+	return "/etc/init.d/" + config.Name
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func installService(config *serviceConfig) error {
+	if config.Init == "systemd" {
+		content, err := renderService(systemdUnit, config)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(servicePath(config), content, 0644); err != nil {
+			return err
+		}
+		if err = CmdRun("systemctl", "daemon-reload"); err != nil {
+			return err
+		}
+		if config.Start {
+			return CmdRun("systemctl", "enable", "--now", config.Name)
+		}
+		return CmdRun("systemctl", "enable", config.Name)
+	}
+
+	content, err := renderService(sysvScript, config)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(servicePath(config), content, 0755); err != nil {
+		return err
+	}
+	if hasCommand("update-rc.d") {
+		err = CmdRun("update-rc.d", config.Name, "defaults")
+	} else if hasCommand("chkconfig") {
+		err = CmdRun("chkconfig", "--add", config.Name)
+	}
+	if err != nil || !config.Start {
+		return err
+	}
+	return CmdRun(servicePath(config), "start")
+}
+
+func uninstallService(config *serviceConfig) error {
+	path := servicePath(config)
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	if config.Init == "systemd" {
+		_ = CmdRun("systemctl", "disable", "--now", config.Name)
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		return CmdRun("systemctl", "daemon-reload")
+	}
+
+	_ = CmdRun(path, "stop")
+	if hasCommand("update-rc.d") {
+		_ = CmdRun("update-rc.d", "-f", config.Name, "remove")
+	} else if hasCommand("chkconfig") {
+		_ = CmdRun("chkconfig", "--del", config.Name)
+	}
+	return os.Remove(path)
+}
+
+func serviceStatus(config *serviceConfig) error {
+	var cmd *exec.Cmd
+	if config.Init == "systemd" {
+		cmd = exec.Command("systemctl", "status", "--no-pager", config.Name)
+	} else {
+		cmd = exec.Command(servicePath(config), "status")
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	. "github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+func validInit(system string) bool {
+	return system == "windows"
+}
+
+func detectInit() string {
+	return "windows"
+}
+
+func installService(config *serviceConfig) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = m.Disconnect() }()
+	if s, err := m.OpenService(config.Name); err == nil {
+		_ = s.Close()
+		return fmt.Errorf("the service %s already exists", config.Name)
+	}
+
+	//服务使用SYSTEM账户运行，PATH中可能没有nginx，记录当前找到的nginx目录
+	args := []string{"service", "run", "--name", config.Name}
+	if nginx, err := exec.LookPath("nginx"); err == nil {
+		if nginx, err = filepath.Abs(nginx); err == nil {
+			args = append(args, "--nginx-dir", filepath.Dir(nginx))
+		}
+	}
+	args = append(append(args, "--"), config.Args...)
+
+	s, err := m.CreateService(config.Name, config.Exec, mgr.Config{
+		DisplayName: "AGINX", Description: "AGINX, api for nginx", StartType: mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+	_ = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: time.Second * 5},
+	}, uint32((time.Hour * 24).Seconds()))
+	if config.Start {
+		return s.Start()
+	}
+	return nil
+}
+
+func uninstallService(config *serviceConfig) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = m.Disconnect() }()
+	s, err := m.OpenService(config.Name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		_, _ = s.Control(svc.Stop)
+		for i := 0; i < 20 && status.State != svc.Stopped; i++ {
+			time.Sleep(time.Second)
+			if status, err = s.Query(); err != nil {
+				break
+			}
+		}
+	}
+	return s.Delete()
+}
+
+func serviceStatus(config *serviceConfig) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = m.Disconnect() }()
+	s, err := m.OpenService(config.Name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+	status, err := s.Query()
+	if err != nil {
+		return err
+	}
+	states := map[svc.State]string{
+		svc.Stopped: "stopped", svc.StartPending: "start pending", svc.StopPending: "stop pending", svc.Running: "running",
+		svc.ContinuePending: "continue pending", svc.PausePending: "pause pending", svc.Paused: "paused",
+	}
+	fmt.Printf("%s is %s\n", config.Name, states[status.State])
+	return nil
+}
+
+//windows服务控制管理器启动的aginx server
+type windowsService struct {
+	args []string
+}
+
+func (ws *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- Safe(func() {
+			PanicIfError(ServerCmd.ParseFlags(ws.args))
+			PanicIfError(ServerCmd.PreRunE(ServerCmd, nil))
+			PanicIfError(ServerCmd.RunE(ServerCmd, nil))
+		})
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-exited:
+			if err != nil {
+				logs.STD.WithError(err).Error("aginx server exited")
+				return true, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				StopDaemon()
+				<-exited
+				return false, 0
+			}
+		}
+	}
+}
+
+var serviceRunCmd = &cobra.Command{
+	Use: "run", Short: "Run the AGINX server as a windows service, used by the service control manager", Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		defer Catch(func(e error) {
+			err = e
+		})
+		name, _ := cmd.Flags().GetString("name")
+		if dir, _ := cmd.Flags().GetString("nginx-dir"); dir != "" {
+			PanicIfError(os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH")))
+		}
+		//服务没有控制台，日志输出到aginx.exe所在目录的aginx.log
+		exe, err := os.Executable()
+		PanicIfError(err)
+		logFile, err := os.OpenFile(filepath.Join(filepath.Dir(exe), "aginx.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		PanicIfError(err)
+		defer func() { _ = logFile.Close() }()
+		logs.SetOutput(logFile)
+		return svc.Run(name, &windowsService{args: args})
+	},
+}
+
+func init() {
+	serviceRunCmd.Flags().StringP("nginx-dir", "", "", "the directory of nginx.exe")
+	ServiceCmd.AddCommand(serviceRunCmd)
+}
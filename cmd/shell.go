@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const shellHelp = `commands:
+  ls   [query...]        list the directives in current path
+  cd   <query...>        change the path, 'cd ..' to parent, 'cd /' to root
+  show [query...]        show the configuration
+  pwd                    print the current path
+  add  <directive>       add directives to current path, example: add gzip on;
+  modify <directive>     replace the directive of current path
+  delete <query...>      delete the directives in current path
+  reload                 reload nginx
+  help                   show this help
+  exit                   exit the shell
+query: server, server.server_name('api.aginx.io'), location('/api'), include('hosts.d/*.conf'), *`
+
+//交互式命令行，路径为查询条件，类似文件系统使用cd、ls浏览配置
+type shell struct {
+	aginx  api.Aginx
+	path   []string
+	output io.Writer
+}
+
+func (sh *shell) println(args ...interface{}) {
+	_, _ = fmt.Fprintln(sh.output, args...)
+}
+
+func (sh *shell) prompt() string {
+	return "aginx:/" + strings.Join(sh.path, "/") + "> "
+}
+
+func (sh *shell) query(args ...string) []string {
+	return append(append([]string{}, sh.path...), args...)
+}
+
+//查询参数，包含单引号时使用反引号
+func quote(arg string) string {
+	if strings.Contains(arg, "'") {
+		return "`" + arg + "`"
+	}
+	return "'" + arg + "'"
+}
+
+//指令的简要信息，有子指令时使用 { ... } 表示
+func summary(directive *nginx.Directive) string {
+	if directive.Virtual == nginx.Include {
+		return "* (" + strings.Join(directive.Args, " ") + ")"
+	}
+	line := strings.TrimSpace(directive.Name + " " + strings.Join(directive.Args, " "))
+	if len(directive.Body) > 0 {
+		return line + " { ... }"
+	}
+	return line + ";"
+}
+
+//子指令可以使用的查询条件
+func candidates(directives []*nginx.Directive) []string {
+	names := make(map[string]bool)
+	for _, directive := range directives {
+		for _, body := range directive.Body {
+			if body.Virtual == nginx.Include {
+				names["*"] = true
+				continue
+			}
+			names[body.Name] = true
+			if len(body.Args) > 0 {
+				names[body.Name+"("+quote(body.Args[0])+")"] = true
+			}
+		}
+	}
+	values := make([]string, 0, len(names))
+	for name := range names {
+		values = append(values, name)
+	}
+	sort.Strings(values)
+	return values
+}
+
+var shellCommands = []string{"add", "cd", "delete", "exit", "help", "ls", "modify", "pwd", "reload", "show"}
+
+//tab补全命令和查询条件
+func (sh *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	words := strings.Fields(line[:pos])
+	prefix := ""
+	if len(words) > 0 && !strings.HasSuffix(line[:pos], " ") {
+		prefix, words = words[len(words)-1], words[:len(words)-1]
+	}
+
+	var values []string
+	if len(words) == 0 {
+		values = shellCommands
+	} else {
+		switch words[0] {
+		case "ls", "cd", "show", "delete":
+			directives, err := sh.aginx.Directive().Select(sh.query(words[1:]...)...)
+			if err != nil {
+				return "", 0, false
+			}
+			values = candidates(directives)
+		default:
+			return "", 0, false
+		}
+	}
+
+	matched := make([]string, 0)
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matched = append(matched, value)
+		}
+	}
+	if len(matched) == 0 {
+		return "", 0, false
+	}
+	//多个匹配时补全公共前缀并列出全部
+	common := matched[0]
+	for _, value := range matched[1:] {
+		for !strings.HasPrefix(value, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(matched) == 1 {
+		common += " "
+	} else if common == prefix {
+		sh.println(strings.Join(matched, "  "))
+	}
+	newLine := line[:pos-len(prefix)] + common + line[pos:]
+	return newLine, pos - len(prefix) + len(common), true
+}
+
+func parseDirectives(content string) []*nginx.Directive {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("", []byte(content)))
+	util.PanicIfError(err)
+	util.AssertTrue(len(conf.Body) > 0, "the directive is empty")
+	return conf.Body
+}
+
+//执行一行命令，返回false时退出
+func (sh *shell) execute(line string) (next bool) {
+	next = true
+	defer util.Catch(func(err error) {
+		sh.println("error:", err)
+	})
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	command, rest := line, ""
+	if idx := strings.IndexAny(line, " \t"); idx != -1 {
+		command, rest = line[:idx], strings.TrimSpace(line[idx+1:])
+	}
+	args := strings.Fields(rest)
+
+	switch command {
+	case "exit", "quit":
+		return false
+	case "help":
+		sh.println(shellHelp)
+	case "pwd":
+		sh.println("/" + strings.Join(sh.path, "/"))
+	case "ls":
+		directives, err := sh.aginx.Directive().Select(sh.query(args...)...)
+		util.PanicIfError(err)
+		for _, directive := range directives {
+			for _, body := range directive.Body {
+				sh.println(summary(body))
+			}
+		}
+	case "show":
+		directives, err := sh.aginx.Directive().Select(sh.query(args...)...)
+		util.PanicIfError(err)
+		for _, directive := range directives {
+			if len(sh.path) == 0 && len(args) == 0 {
+				for _, body := range directive.Body {
+					sh.println(body.Pretty(0))
+				}
+			} else {
+				sh.println(directive.Pretty(0))
+			}
+		}
+	case "cd":
+		switch {
+		case len(args) == 0 || rest == "/":
+			sh.path = []string{}
+		case rest == "..":
+			if len(sh.path) > 0 {
+				sh.path = sh.path[:len(sh.path)-1]
+			}
+		default:
+			_, err := sh.aginx.Directive().Select(sh.query(args...)...)
+			util.PanicIfError(err)
+			sh.path = sh.query(args...)
+		}
+	case "add":
+		util.PanicIfError(sh.aginx.Directive().Add(sh.path, parseDirectives(rest)...))
+		sh.println("added")
+	case "modify":
+		util.AssertTrue(len(sh.path) > 0, "cd to the directive to modify")
+		directives := parseDirectives(rest)
+		util.AssertTrue(len(directives) == 1, "the modify content must be only one")
+		util.PanicIfError(sh.aginx.Directive().Modify(sh.path, directives[0]))
+		sh.println("modified")
+	case "delete":
+		util.AssertTrue(len(args) > 0, "the query of directive to delete is empty")
+		util.PanicIfError(sh.aginx.Directive().Delete(sh.query(args...)...))
+		sh.println("deleted")
+	case "reload":
+		util.PanicIfError(sh.aginx.Reload())
+		sh.println("reloaded")
+	default:
+		sh.println("unknown command:", command, ", use help to show all commands")
+	}
+	return
+}
+
+//终端中使用tab补全，非终端（管道）时逐行执行
+func (sh *shell) run() error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		sh.output = os.Stdout
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if !sh.execute(scanner.Text()) {
+				break
+			}
+		}
+		return scanner.Err()
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = terminal.Restore(fd, state) }()
+
+	term := terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, sh.prompt())
+	term.AutoCompleteCallback = sh.complete
+	sh.output = term
+	sh.println("connected, use help to show all commands, tab to complete")
+	for {
+		line, err := term.ReadLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !sh.execute(line) {
+			return nil
+		}
+		term.SetPrompt(sh.prompt())
+	}
+}
+
+var ShellCmd = &cobra.Command{
+	Use: "shell", Short: "Interactive shell to explore and edit configuration",
+	Long:    "Interactive shell to explore and edit configuration of local or remote aginx, use tab to complete commands and queries",
+	Example: "aginx shell --server http://127.0.0.1:8011 --auth admin:aginx",
+	Args:    cobra.NoArgs,
+	PreRun:  preRun,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sh := &shell{aginx: aginx, path: []string{}, output: os.Stdout}
+		return sh.run()
+	},
+}
+
+func init() {
+	ShellCmd.PersistentFlags().StringP("api", "i", "127.0.0.1:8011", "restful api address.")
+	ShellCmd.PersistentFlags().StringP("security", "s", "", "base auth or token for restful api, example: user:passwd")
+	ShellCmd.PersistentFlags().StringP("server", "", "", "the address of aginx server, same as --api, example: http://127.0.0.1:8011")
+	ShellCmd.PersistentFlags().StringP("auth", "", "", "the user:password or token for restful api, same as --security")
+	AddClientTLSFlags(ShellCmd)
+}
@@ -6,12 +6,57 @@ import (
 	"fmt"
 	"github.com/ihaiker/aginx/nginx"
 	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
+//没有使用 --conf 时依次查找的配置文件
+var DefaultPaths = []string{
+	"/etc/aginx/aginx.yaml", "/etc/aginx/aginx.yml", "/etc/aginx/aginx.toml", "/etc/aginx/aginx.conf",
+}
+
+//加载配置，优先级：环境变量 > 命令行参数 > 配置文件 > 默认值
+func Load(cmd *cobra.Command) error {
+	configFile := viper.GetString("conf")
+	if configFile == "" {
+		configFile = Find()
+	}
+	if configFile != "" {
+		if err := ReadConfig(configFile, cmd); err != nil {
+			return fmt.Errorf("read config %s: %w", configFile, err)
+		}
+	}
+	BindEnv(cmd)
+	return nil
+}
+
+//默认位置存在的配置文件
+func Find() string {
+	for _, path := range DefaultPaths {
+		if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+//环境变量覆盖命令行参数，AGINX_EXPOSE_PORT 对应 --expose-port
+func BindEnv(cmd *cobra.Command) {
+	bind := func(flag *pflag.Flag) {
+		if value := os.Getenv(util.EnvKey(flag.Name)); value != "" {
+			viper.Set(flag.Name, value)
+		}
+	}
+	cmd.Flags().VisitAll(bind)
+	cmd.InheritedFlags().VisitAll(bind)
+}
+
 func ReadConfig(configPath string, cmd *cobra.Command) error {
 	if parameters, err := parse(cmd, configPath); err != nil {
 		return err
@@ -23,6 +68,10 @@ func ReadConfig(configPath string, cmd *cobra.Command) error {
 }
 
 func parse(cmd *cobra.Command, configPath string) (map[string]interface{}, error) {
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".yaml", ".yml", ".toml", ".json":
+		return parseStructured(cmd, configPath)
+	}
 	if content, err := ioutil.ReadFile(configPath); err != nil {
 		return nil, err
 	} else if cfg, err := nginx.ReaderReadable(nil, plugins.NewFile(configPath, content)); err != nil {
@@ -32,6 +81,51 @@ func parse(cmd *cobra.Command, configPath string) (map[string]interface{}, error
 	}
 }
 
+//yaml、toml、json格式的配置文件，嵌套的配置和nginx格式一样使用 - 连接，例如：docker.host 对应 --docker-host
+func parseStructured(cmd *cobra.Command, configPath string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	parameters := make(map[string]interface{})
+	if err := flatten(cmd, "", v.AllSettings(), parameters); err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
+
+func flatten(cmd *cobra.Command, previousLayer string, settings map[string]interface{}, parameters map[string]interface{}) error {
+	for name, value := range settings {
+		key := key(previousLayer, name)
+		flag := cmd.PersistentFlags().Lookup(key)
+		if sub, ok := value.(map[string]interface{}); ok {
+			//docker: {host: ...} 同时开启 --docker
+			if flag != nil {
+				parameters[key] = "true"
+			}
+			if err := flatten(cmd, key, sub, parameters); err != nil {
+				return err
+			}
+			continue
+		}
+		if flag == nil {
+			return fmt.Errorf("not flag found : %s ", key)
+		}
+		switch values := value.(type) {
+		case []interface{}:
+			array := make([]string, 0, len(values))
+			for _, item := range values {
+				array = append(array, fmt.Sprint(item))
+			}
+			parameters[key] = array
+		default:
+			parameters[key] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
 func key(pre, cur string) string {
 	if pre == "" {
 		return cur
@@ -0,0 +1,81 @@
+package conf_test
+
+import (
+	. "github.com/ihaiker/aginx/cmd"
+	"github.com/ihaiker/aginx/conf"
+	"github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func configCmd(t *testing.T, name, content string) (*cobra.Command, func()) {
+	dir, err := ioutil.TempDir("", "aginx-conf")
+	assert.Nil(t, err)
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	viper.Reset()
+	cmd := &cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	AddServerFlags(cmd)
+	_ = viper.BindPFlags(cmd.PersistentFlags())
+	viper.Set("conf", path)
+	return cmd, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestReadYaml(t *testing.T) {
+	cmd, cleanup := configCmd(t, "aginx.yaml", `
+email: yaml@aginx.io
+expose-port: 8443
+expose-allow:
+  - 10.0.0.0/8
+  - 192.168.0.0/16
+docker:
+  host: unix:///var/run/docker.sock
+`)
+	defer cleanup()
+	assert.Nil(t, conf.Load(cmd))
+	assert.Equal(t, "yaml@aginx.io", viper.GetString("email"))
+	assert.Equal(t, 8443, viper.GetInt("expose-port"))
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.0.0/16"}, util.GetStringArray(cmd, "expose-allow"))
+	assert.True(t, viper.GetBool("docker"))
+	assert.Equal(t, "unix:///var/run/docker.sock", viper.GetString("docker-host"))
+}
+
+func TestReadToml(t *testing.T) {
+	cmd, cleanup := configCmd(t, "aginx.toml", `
+email = "toml@aginx.io"
+[docker]
+host = "tcp://127.0.0.1:2375"
+`)
+	defer cleanup()
+	assert.Nil(t, conf.Load(cmd))
+	assert.Equal(t, "toml@aginx.io", viper.GetString("email"))
+	assert.Equal(t, "tcp://127.0.0.1:2375", viper.GetString("docker-host"))
+}
+
+func TestUnknownKey(t *testing.T) {
+	cmd, cleanup := configCmd(t, "aginx.yaml", "not-exists: true\n")
+	defer cleanup()
+	assert.NotNil(t, conf.Load(cmd))
+}
+
+func TestPrecedence(t *testing.T) {
+	cmd, cleanup := configCmd(t, "aginx.yaml", "email: file@aginx.io\nacme-server: zerossl\nexpose: file.aginx.io\n")
+	defer cleanup()
+	assert.Nil(t, cmd.ParseFlags([]string{"--email", "flag@aginx.io", "--expose", "flag.aginx.io"}))
+	_ = os.Setenv("AGINX_EXPOSE", "env.aginx.io")
+	defer func() {
+		_ = os.Unsetenv("AGINX_EXPOSE")
+	}()
+	assert.Nil(t, conf.Load(cmd))
+	assert.Equal(t, "env.aginx.io", viper.GetString("expose"))
+	assert.Equal(t, "flag@aginx.io", viper.GetString("email"))
+	assert.Equal(t, "zerossl", viper.GetString("acme-server"))
+}
@@ -0,0 +1,142 @@
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/util"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var logger = logs.New("geoip")
+
+//MaxMind数据库的下载地址
+const maxmindURL = "https://download.maxmind.com/app/geoip_download?edition_id=%s&license_key=%s&suffix=tar.gz"
+
+//GeoIP数据库（mmdb），nginx使用 ngx_http_geoip2_module 读取
+type Database struct {
+	Path     string        `json:"path"`              //mmdb文件的位置
+	Edition  string        `json:"edition"`           //默认为文件名，例如：GeoLite2-Country
+	License  string        `json:"-"`                 //MaxMind的license key
+	URL      string        `json:"url,omitempty"`     //代替MaxMind的下载地址（例如：内部镜像），内容为tar.gz或者mmdb文件
+	Interval time.Duration `json:"interval"`          //更新间隔，默认：7d
+	Updated  time.Time     `json:"updated,omitempty"` //文件的修改时间
+}
+
+//解析数据库配置：/var/lib/aginx/GeoLite2-Country.mmdb?license=key&interval=7d
+func Parse(config string) (*Database, error) {
+	path, query := config, ""
+	if idx := strings.Index(config, "?"); idx != -1 {
+		path, query = config[:idx], config[idx+1:]
+	}
+	if !strings.HasSuffix(path, ".mmdb") {
+		return nil, errors.New("the geoip database must be a mmdb file: " + config)
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	db := &Database{
+		Path: path, Edition: values.Get("edition"),
+		License: values.Get("license"), URL: values.Get("url"),
+		Interval: time.Hour * 24 * 7,
+	}
+	if db.Edition == "" {
+		db.Edition = strings.TrimSuffix(filepath.Base(path), ".mmdb")
+	}
+	if interval := values.Get("interval"); interval != "" {
+		if db.Interval, err = util.ParseDuration(interval); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+//是否可以下载，没有设置license和url时只使用已经存在的文件
+func (db *Database) Downloadable() bool {
+	return db.License != "" || db.URL != ""
+}
+
+//文件不存在或者超过更新间隔
+func (db *Database) Outdated() bool {
+	stat, err := os.Stat(db.Path)
+	return err != nil || time.Since(stat.ModTime()) > db.Interval
+}
+
+//数据库信息，包括文件的修改时间
+func (db *Database) Info() *Database {
+	info := *db
+	if stat, err := os.Stat(db.Path); err == nil {
+		info.Updated = stat.ModTime()
+	}
+	return &info
+}
+
+func (db *Database) downloadURL() string {
+	if db.URL != "" {
+		return db.URL
+	}
+	return fmt.Sprintf(maxmindURL, url.QueryEscape(db.Edition), url.QueryEscape(db.License))
+}
+
+//从tar.gz中读取mmdb文件，不是gzip时作为mmdb文件
+func extract(content []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return content, nil
+	}
+	defer func() { _ = reader.Close() }()
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, errors.New("not found mmdb file in the archive")
+		} else if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(header.Name, ".mmdb") {
+			return ioutil.ReadAll(archive)
+		}
+	}
+}
+
+//下载数据库，先写入临时文件再替换，nginx不会读到不完整的文件
+func (db *Database) Download() error {
+	if !db.Downloadable() {
+		return errors.New("the license or url of geoip database is not set")
+	}
+	resp, err := http.Get(db.downloadURL())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download geoip database: %s", resp.Status)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if content, err = extract(content); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(db.Path), os.ModePerm); err != nil {
+		return err
+	}
+	temp := db.Path + ".download"
+	if err = ioutil.WriteFile(temp, content, 0644); err != nil {
+		return err
+	}
+	logger.Info("download geoip database ", db.Edition, " to ", db.Path)
+	return os.Rename(temp, db.Path)
+}
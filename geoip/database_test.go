@@ -0,0 +1,94 @@
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	db, err := Parse("/var/lib/aginx/GeoLite2-Country.mmdb?license=key&interval=1d")
+	assert.Nil(t, err)
+	assert.Equal(t, &Database{
+		Path: "/var/lib/aginx/GeoLite2-Country.mmdb", Edition: "GeoLite2-Country",
+		License: "key", Interval: time.Hour * 24,
+	}, db)
+	assert.True(t, db.Downloadable())
+	assert.Equal(t, "https://download.maxmind.com/app/geoip_download?edition_id=GeoLite2-Country&license_key=key&suffix=tar.gz", db.downloadURL())
+
+	db, err = Parse("/var/lib/aginx/country.mmdb?edition=GeoLite2-City")
+	assert.Nil(t, err)
+	assert.Equal(t, "GeoLite2-City", db.Edition)
+	assert.Equal(t, time.Hour*24*7, db.Interval)
+	assert.False(t, db.Downloadable())
+
+	_, err = Parse("/var/lib/aginx/GeoLite2-Country.tar.gz")
+	assert.NotNil(t, err)
+}
+
+func archive(t *testing.T, name string, content []byte) []byte {
+	out := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(out)
+	writer := tar.NewWriter(gz)
+	assert.Nil(t, writer.WriteHeader(&tar.Header{Name: "GeoLite2-Country_20200301/COPYRIGHT.txt", Mode: 0644, Size: 4}))
+	_, _ = writer.Write([]byte("copy"))
+	assert.Nil(t, writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+	_, _ = writer.Write(content)
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, gz.Close())
+	return out.Bytes()
+}
+
+func TestDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files := map[string][]byte{
+		"/GeoLite2-Country.tar.gz": archive(t, "GeoLite2-Country_20200301/GeoLite2-Country.mmdb", []byte("mmdb")),
+		"/GeoLite2-Country.mmdb":   []byte("raw mmdb"),
+		"/empty.tar.gz":            archive(t, "README.txt", []byte("readme")),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if content, has := files[r.URL.Path]; has {
+			_, _ = w.Write(content)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(dir, "GeoLite2-Country.mmdb")
+	db, err := Parse(path + "?url=" + server.URL + "/GeoLite2-Country.tar.gz")
+	assert.Nil(t, err)
+	assert.True(t, db.Outdated())
+	assert.Nil(t, db.Download())
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "mmdb", string(content))
+	assert.False(t, db.Outdated())
+
+	reloaded := 0
+	updater := NewUpdater(&Database{Path: path, URL: server.URL + "/GeoLite2-Country.mmdb"}, func() error {
+		reloaded++
+		return nil
+	})
+	assert.Nil(t, updater.Update())
+	content, _ = ioutil.ReadFile(path)
+	assert.Equal(t, "raw mmdb", string(content))
+	assert.Equal(t, 1, reloaded)
+
+	for _, name := range []string{"/empty.tar.gz", "/none.mmdb"} {
+		db.URL = server.URL + name
+		assert.NotNil(t, db.Download())
+	}
+	content, _ = ioutil.ReadFile(path)
+	assert.Equal(t, "raw mmdb", string(content))
+}
@@ -0,0 +1,66 @@
+package geoip
+
+import (
+	"sync"
+	"time"
+)
+
+//定时更新数据库，更新后重启nginx加载新的数据库
+type Updater struct {
+	db     *Database
+	reload func() error
+
+	lock   sync.Mutex
+	closeC chan struct{}
+}
+
+func NewUpdater(db *Database, reload func() error) *Updater {
+	return &Updater{db: db, reload: reload, closeC: make(chan struct{})}
+}
+
+func (u *Updater) Database() *Database {
+	return u.db.Info()
+}
+
+//立即下载数据库并重启nginx
+func (u *Updater) Update() error {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if err := u.db.Download(); err != nil {
+		return err
+	}
+	return u.reload()
+}
+
+func (u *Updater) update() {
+	if err := u.Update(); err != nil {
+		logger.WithError(err).Warn("update geoip database")
+	}
+}
+
+func (u *Updater) Start() error {
+	if !u.db.Downloadable() {
+		return nil
+	}
+	go func() {
+		if u.db.Outdated() {
+			u.update()
+		}
+		ticker := time.NewTicker(u.db.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-u.closeC:
+				return
+			case <-ticker.C:
+				u.update()
+			}
+		}
+	}()
+	return nil
+}
+
+func (u *Updater) Stop() error {
+	close(u.closeC)
+	return nil
+}
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/util"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeHttp = "http"
+	TypeTcp  = "tcp"
+)
+
+//upstream的健康检查配置
+type Check struct {
+	Upstream string        `json:"upstream"` //upstream名称，*表示全部upstream
+	Type     string        `json:"type"`     //http, tcp
+	Path     string        `json:"path,omitempty"`
+	Interval time.Duration `json:"interval"`
+	Timeout  time.Duration `json:"timeout"`
+	Fall     int           `json:"fall"` //连续失败次数达到后摘除
+	Rise     int           `json:"rise"` //连续成功次数达到后恢复
+}
+
+func positive(values url.Values, name string, def int) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i <= 0 {
+		return 0, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return i, nil
+}
+
+func duration(values url.Values, name string, def time.Duration) (time.Duration, error) {
+	value := values.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := util.ParseDuration(value)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return d, err
+}
+
+//解析健康检查配置：api?type=http&path=/health&interval=10s&timeout=3s&fall=3&rise=2
+func ParseCheck(config string) (check *Check, err error) {
+	name, query := config, ""
+	if idx := strings.Index(config, "?"); idx != -1 {
+		name, query = config[:idx], config[idx+1:]
+	}
+	if name == "" {
+		return nil, errors.New("the upstream of health check is empty: " + config)
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	check = &Check{Upstream: name, Type: values.Get("type"), Path: values.Get("path")}
+	switch check.Type {
+	case "", TypeHttp:
+		check.Type = TypeHttp
+		if check.Path == "" {
+			check.Path = "/"
+		} else if !strings.HasPrefix(check.Path, "/") {
+			check.Path = "/" + check.Path
+		}
+	case TypeTcp:
+	default:
+		return nil, errors.New("the type of health check not support: " + check.Type)
+	}
+	if check.Interval, err = duration(values, "interval", time.Second*10); err != nil {
+		return
+	}
+	if check.Timeout, err = duration(values, "timeout", time.Second*3); err != nil {
+		return
+	}
+	if check.Fall, err = positive(values, "fall", 3); err != nil {
+		return
+	}
+	check.Rise, err = positive(values, "rise", 2)
+	return
+}
+
+func ParseChecks(configs []string) ([]*Check, error) {
+	checks := make([]*Check, 0, len(configs))
+	for _, config := range configs {
+		check, err := ParseCheck(config)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+func (c *Check) String() string {
+	values := url.Values{}
+	values.Set("type", c.Type)
+	if c.Type == TypeHttp {
+		values.Set("path", c.Path)
+	}
+	values.Set("interval", c.Interval.String())
+	values.Set("timeout", c.Timeout.String())
+	values.Set("fall", strconv.Itoa(c.Fall))
+	values.Set("rise", strconv.Itoa(c.Rise))
+	return c.Upstream + "?" + values.Encode()
+}
@@ -0,0 +1,152 @@
+package health
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseCheck(t *testing.T) {
+	check, err := ParseCheck("api")
+	assert.Nil(t, err)
+	assert.Equal(t, &Check{
+		Upstream: "api", Type: TypeHttp, Path: "/",
+		Interval: time.Second * 10, Timeout: time.Second * 3, Fall: 3, Rise: 2,
+	}, check)
+
+	check, err = ParseCheck("*?type=tcp&interval=5s&timeout=1s&fall=2&rise=1")
+	assert.Nil(t, err)
+	assert.Equal(t, &Check{
+		Upstream: "*", Type: TypeTcp,
+		Interval: time.Second * 5, Timeout: time.Second, Fall: 2, Rise: 1,
+	}, check)
+	assert.Equal(t, "*?fall=2&interval=5s&rise=1&timeout=1s&type=tcp", check.String())
+
+	check, err = ParseCheck("api?path=health")
+	assert.Nil(t, err)
+	assert.Equal(t, "/health", check.Path)
+
+	for _, config := range []string{"?type=tcp", "api?type=udp", "api?fall=0", "api?interval=abc"} {
+		_, err = ParseCheck(config)
+		assert.NotNil(t, err, config)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	status := int32(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/health", r.URL.Path)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	check := &Check{Type: TypeHttp, Path: "/health", Timeout: time.Second}
+	assert.Nil(t, probe(check, address))
+	atomic.StoreInt32(&status, http.StatusBadGateway)
+	assert.NotNil(t, probe(check, address))
+
+	check.Type = TypeTcp
+	assert.Nil(t, probe(check, address))
+	assert.NotNil(t, probe(check, closedAddress(t)))
+}
+
+func closedAddress(t *testing.T) string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestCheckerRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	status := int32(http.StatusOK)
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer flaky.Close()
+	healthyAddress := strings.TrimPrefix(healthy.URL, "http://")
+	flakyAddress := strings.TrimPrefix(flaky.URL, "http://")
+	drained := closedAddress(t)
+
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("http { upstream api { "+
+		"server "+healthyAddress+"; server "+flakyAddress+"; server "+drained+" down; } }"), 0644))
+	engine := file.New(conf)
+
+	newChecker := func(engine plugins.StorageEngine) *Checker {
+		return New(engine, func() (*nginx.Client, error) {
+			return nginx.NewClient("", engine, nil, nil)
+		}, func(client *nginx.Client) error {
+			return client.Store()
+		}, &Check{Upstream: "api", Type: TypeHttp, Path: "/", Timeout: time.Second, Fall: 2, Rise: 1})
+	}
+	servers := func() map[string]bool {
+		client, err := nginx.NewClient("", engine, nil, nil)
+		assert.Nil(t, err)
+		upstream, err := client.GetUpstream("api")
+		assert.Nil(t, err)
+		downs := make(map[string]bool)
+		for _, server := range upstream.Servers {
+			downs[server.Address] = server.Down
+		}
+		return downs
+	}
+	checker := newChecker(engine)
+	check := checker.Checks()[0]
+
+	checker.Run(check)
+	members, err := checker.Health("api")
+	assert.Nil(t, err)
+	//人工设置down的server不检查
+	assert.Len(t, members, 2)
+
+	//连续失败fall次后摘除
+	atomic.StoreInt32(&status, http.StatusInternalServerError)
+	checker.Run(check)
+	assert.False(t, servers()[flakyAddress])
+	checker.Run(check)
+	assert.Equal(t, map[string]bool{healthyAddress: false, flakyAddress: true, drained: true}, servers())
+
+	members, _ = checker.Health("api")
+	for _, member := range members {
+		if member.Address == flakyAddress {
+			assert.False(t, member.Healthy)
+			assert.True(t, member.Removed)
+			assert.Equal(t, "status code 500", member.Error)
+		} else {
+			assert.True(t, member.Healthy)
+		}
+	}
+
+	//重启后继续检查被摘除的server，恢复后去掉down
+	checker = newChecker(engine)
+	atomic.StoreInt32(&status, http.StatusOK)
+	checker.Run(check)
+	assert.Equal(t, map[string]bool{healthyAddress: false, flakyAddress: false, drained: true}, servers())
+
+	//不摘除最后一个server
+	atomic.StoreInt32(&status, http.StatusInternalServerError)
+	healthy.Close()
+	checker.Run(check)
+	checker.Run(check)
+	downs := servers()
+	assert.True(t, downs[healthyAddress] != downs[flakyAddress])
+
+	_, err = checker.Health("web")
+	assert.Equal(t, ErrNotChecked, err)
+}
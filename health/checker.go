@@ -0,0 +1,334 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("health")
+
+//被健康检查摘除的server，重启后继续检查并在恢复后添加回来
+const removedFile = "health/removed.json"
+
+var ErrNotChecked = errors.New("the upstream is not health checked, use --health-check")
+
+//upstream中server的健康状态
+type Member struct {
+	Upstream  string    `json:"upstream"`
+	Address   string    `json:"address"`
+	Healthy   bool      `json:"healthy"`
+	Removed   bool      `json:"removed"`   //已经被标记为down
+	Failures  int       `json:"failures"`  //连续失败次数
+	Successes int       `json:"successes"` //连续成功次数
+	Checked   time.Time `json:"checked,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func key(upstream, address string) string {
+	return upstream + "/" + address
+}
+
+//主动健康检查：定时探测upstream中的server，不健康的server标记为down，恢复后去掉down
+type Checker struct {
+	checks []*Check
+	engine plugins.StorageEngine
+	client func() (*nginx.Client, error)
+	commit func(client *nginx.Client) error //测试、保存配置并重启nginx
+
+	lock    sync.Mutex
+	members map[string]*Member
+	closeC  chan struct{}
+}
+
+func New(engine plugins.StorageEngine, client func() (*nginx.Client, error), commit func(client *nginx.Client) error, checks ...*Check) *Checker {
+	c := &Checker{
+		checks: checks, engine: engine, client: client, commit: commit,
+		members: make(map[string]*Member), closeC: make(chan struct{}),
+	}
+	if err := c.load(); err != nil {
+		logger.WithError(err).Warn("load removed upstream servers")
+	}
+	return c
+}
+
+func (c *Checker) Checks() []*Check {
+	return c.checks
+}
+
+func (c *Checker) load() error {
+	file, err := c.engine.Get(removedFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	removed := make([]*Member, 0)
+	if err = json.Unmarshal(file.Content, &removed); err != nil {
+		return err
+	}
+	for _, member := range removed {
+		member.Healthy, member.Removed = false, true
+		c.members[key(member.Upstream, member.Address)] = member
+	}
+	return nil
+}
+
+func (c *Checker) save() error {
+	removed := make([]*Member, 0)
+	for _, member := range c.members {
+		if member.Removed {
+			removed = append(removed, &Member{Upstream: member.Upstream, Address: member.Address})
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return key(removed[i].Upstream, removed[i].Address) < key(removed[j].Upstream, removed[j].Address)
+	})
+	content, err := json.MarshalIndent(removed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return c.engine.Put(removedFile, content)
+}
+
+func (c *Checker) checked(upstream string) bool {
+	for _, check := range c.checks {
+		if check.Upstream == "*" || check.Upstream == upstream {
+			return true
+		}
+	}
+	return false
+}
+
+//upstream中server的健康状态
+func (c *Checker) Health(upstream string) ([]*Member, error) {
+	if !c.checked(upstream) {
+		return nil, ErrNotChecked
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	members := make([]*Member, 0)
+	for _, member := range c.members {
+		if member.Upstream == upstream {
+			copied := *member
+			members = append(members, &copied)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Address < members[j].Address
+	})
+	return members, nil
+}
+
+//探测server，http状态码小于400为健康
+func probe(check *Check, address string) error {
+	network := "tcp"
+	if strings.HasPrefix(address, "unix:") {
+		network, address = "unix", address[len("unix:"):]
+	} else if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "80")
+	}
+	if check.Type == TypeTcp || network == "unix" {
+		conn, err := net.DialTimeout(network, address, check.Timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	client := &http.Client{Timeout: check.Timeout}
+	resp, err := client.Get("http://" + address + check.Path)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+//需要探测的server，人工设置down的server不探测
+func (c *Checker) targets(check *Check) (map[string]*nginx.Upstream, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	upstreams := make([]*nginx.Upstream, 0)
+	if check.Upstream == "*" {
+		upstreams = client.Upstreams()
+	} else if upstream, err := client.GetUpstream(check.Upstream); err == nil {
+		upstreams = append(upstreams, upstream)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	targets := make(map[string]*nginx.Upstream)
+	for _, upstream := range upstreams {
+		servers := make([]*nginx.UpstreamServer, 0)
+		for _, server := range upstream.Servers {
+			member, has := c.members[key(upstream.Name, server.Address)]
+			if !server.Down || (has && member.Removed) {
+				servers = append(servers, server)
+			}
+		}
+		upstream.Servers = servers
+		targets[upstream.Name] = upstream
+	}
+	return targets, nil
+}
+
+//探测一次，根据结果摘除或者恢复server
+func (c *Checker) Run(check *Check) {
+	targets, err := c.targets(check)
+	if err != nil {
+		logger.WithError(err).Warn("health check ", check.Upstream)
+		return
+	}
+
+	type result struct {
+		upstream, address string
+		err               error
+	}
+	results := make(chan *result)
+	count := 0
+	for name, upstream := range targets {
+		for _, server := range upstream.Servers {
+			count++
+			go func(name, address string) {
+				results <- &result{upstream: name, address: address, err: probe(check, address)}
+			}(name, server.Address)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	seen := make(map[string]bool)
+	for i := 0; i < count; i++ {
+		r := <-results
+		k := key(r.upstream, r.address)
+		seen[k] = true
+		member, has := c.members[k]
+		if !has {
+			member = &Member{Upstream: r.upstream, Address: r.address, Healthy: true}
+			c.members[k] = member
+		}
+		member.Checked = now
+		if r.err == nil {
+			member.Error, member.Failures = "", 0
+			if member.Successes++; !member.Healthy && member.Successes >= check.Rise {
+				member.Healthy = true
+			}
+		} else {
+			member.Error, member.Successes = r.err.Error(), 0
+			if member.Failures++; member.Healthy && member.Failures >= check.Fall {
+				member.Healthy = false
+			}
+		}
+	}
+	//删除已经不在upstream中的server
+	for k, member := range c.members {
+		if _, has := targets[member.Upstream]; has && !seen[k] {
+			delete(c.members, k)
+		}
+	}
+	c.apply()
+}
+
+//把健康状态同步到配置：不健康的server标记为down，恢复后去掉down，每个upstream至少保留一个server
+func (c *Checker) apply() {
+	client, err := c.client()
+	if err != nil {
+		logger.WithError(err).Warn("health check")
+		return
+	}
+	changed := make([]*Member, 0)
+	for _, member := range c.members {
+		if member.Healthy == !member.Removed {
+			continue
+		}
+		upstream, err := client.GetUpstream(member.Upstream)
+		if err != nil {
+			continue
+		}
+		active := 0
+		var server *nginx.UpstreamServer
+		for _, s := range upstream.Servers {
+			if s.Address == member.Address {
+				server = s
+			}
+			if !s.Down && !s.Backup {
+				active++
+			}
+		}
+		if server == nil {
+			continue
+		}
+		if !member.Healthy && active <= 1 && !server.Backup {
+			logger.Warn("keep the last server of upstream ", member.Upstream, ": ", member.Address, ", ", member.Error)
+			continue
+		}
+		server.Down = !member.Healthy
+		if err = client.SetUpstreamServer(member.Upstream, server); err != nil {
+			logger.WithError(err).Warn("health check ", member.Upstream, ": ", member.Address)
+			continue
+		}
+		changed = append(changed, member)
+	}
+	if len(changed) == 0 {
+		return
+	}
+	if err = c.commit(client); err != nil {
+		logger.WithError(err).Warn("apply health check")
+		return
+	}
+	for _, member := range changed {
+		member.Removed = !member.Healthy
+		if member.Removed {
+			logger.Warn("remove unhealthy server ", member.Upstream, ": ", member.Address, ", ", member.Error)
+		} else {
+			logger.Info("add recovered server ", member.Upstream, ": ", member.Address)
+		}
+		util.PublishEvent(util.EventUpstreamHealth, member.Upstream, nil, map[string]interface{}{
+			"upstream": member.Upstream, "address": member.Address, "healthy": member.Healthy,
+		})
+	}
+	if err = c.save(); err != nil {
+		logger.WithError(err).Warn("save removed upstream servers")
+	}
+}
+
+func (c *Checker) Start() error {
+	for _, check := range c.checks {
+		go func(check *Check) {
+			ticker := time.NewTicker(check.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-c.closeC:
+					return
+				case <-ticker.C:
+					c.Run(check)
+				}
+			}
+		}(check)
+	}
+	return nil
+}
+
+func (c *Checker) Stop() error {
+	close(c.closeC)
+	return nil
+}
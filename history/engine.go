@@ -0,0 +1,44 @@
+package history
+
+import (
+	"context"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+)
+
+//修改文件前记录文件原有内容
+type historyEngine struct {
+	plugins.StorageEngine
+	history *History
+	ctx     context.Context
+}
+
+func (h *History) Engine(engine plugins.StorageEngine) plugins.StorageEngine {
+	return &historyEngine{StorageEngine: engine, history: h, ctx: context.Background()}
+}
+
+func (he *historyEngine) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &historyEngine{StorageEngine: plugins.WithContext(ctx, he.StorageEngine), history: he.history, ctx: ctx}
+}
+
+func (he *historyEngine) Context() context.Context {
+	return he.ctx
+}
+
+func (he *historyEngine) Put(file string, content []byte) error {
+	he.history.record(he.ctx, file)
+	return he.StorageEngine.Put(file, content)
+}
+
+func (he *historyEngine) Remove(file string) error {
+	he.history.record(he.ctx, file)
+	return he.StorageEngine.Remove(file)
+}
+
+func (he *historyEngine) Start() error {
+	return util.StartService(he.StorageEngine)
+}
+
+func (he *historyEngine) Stop() error {
+	return util.StopService(he.StorageEngine)
+}
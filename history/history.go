@@ -0,0 +1,296 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("history")
+
+const historyDir = "history"
+
+type File struct {
+	Name    string `json:"name"`
+	Exists  bool   `json:"exists"` //修改前文件是否存在
+	Content string `json:"content,omitempty"`
+}
+
+//一次修改请求前，被修改文件的内容
+type Version struct {
+	ID    int64     `json:"id"`
+	Time  time.Time `json:"time"`
+	User  string    `json:"user,omitempty"`
+	Files []*File   `json:"files"`
+}
+
+func (v *Version) has(name string) bool {
+	for _, file := range v.Files {
+		if file.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type History struct {
+	engine   plugins.StorageEngine
+	size     int
+	versions []*Version
+
+	lock *sync.Mutex
+}
+
+func (v *Version) path() string {
+	return fmt.Sprintf("%s/%d.json", historyDir, v.ID)
+}
+
+//加载存储引擎中保存的历史版本, size 为保留的版本数量
+func New(engine plugins.StorageEngine, size int) (*History, error) {
+	h := &History{engine: engine, size: size, versions: make([]*Version, 0), lock: new(sync.Mutex)}
+	files, err := engine.Search(historyDir + "/*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		version := new(Version)
+		if err := json.Unmarshal(file.Content, version); err != nil {
+			logger.WithError(err).Warn("load history ", file.Name)
+			continue
+		}
+		h.versions = append(h.versions, version)
+	}
+	sort.Slice(h.versions, func(i, j int) bool {
+		return h.versions[i].ID < h.versions[j].ID
+	})
+	return h, nil
+}
+
+func (h *History) Versions() []*Version {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]*Version{}, h.versions...)
+}
+
+func (h *History) nextID() int64 {
+	if len(h.versions) == 0 {
+		return 1
+	}
+	return h.versions[len(h.versions)-1].ID + 1
+}
+
+type versionKey struct{}
+
+//开始记录一个版本，必须和End成对调用。
+//使用返回的context绑定存储引擎（plugins.WithContext）后，修改前的文件内容记录到此版本中
+func (h *History) Begin(ctx context.Context, user string) context.Context {
+	version := &Version{Time: time.Now(), User: user, Files: make([]*File, 0)}
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+//保存文件修改前的内容，同一版本中只记录第一次。不在修改请求中的修改不记录
+func (h *History) record(ctx context.Context, name string) {
+	version, match := ctx.Value(versionKey{}).(*Version)
+	if !match || version.has(name) {
+		return
+	}
+	if cfgFile, err := h.engine.Get(name); err == nil {
+		version.Files = append(version.Files, &File{Name: name, Exists: true, Content: string(cfgFile.Content)})
+	} else if os.IsNotExist(err) {
+		//可能是文件夹
+		if files, err := h.engine.Search(name + "/*"); err == nil && len(files) > 0 {
+			for _, file := range files {
+				version.Files = append(version.Files, &File{Name: file.Name, Exists: true, Content: string(file.Content)})
+			}
+		} else {
+			version.Files = append(version.Files, &File{Name: name, Exists: false})
+		}
+	} else {
+		logger.WithError(err).Warn("record history ", name)
+	}
+}
+
+//结束版本记录，版本号在保存时分配
+func (h *History) End(ctx context.Context) {
+	version, match := ctx.Value(versionKey{}).(*Version)
+	if !match || len(version.Files) == 0 {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	version.ID = h.nextID()
+	if err := h.store(version); err != nil {
+		logger.WithError(err).Warn("store history version ", version.ID)
+		return
+	}
+	h.versions = append(h.versions, version)
+	h.prune()
+}
+
+func (h *History) store(version *Version) error {
+	bs, err := json.MarshalIndent(version, "", "\t")
+	if err != nil {
+		return err
+	}
+	return h.engine.Put(version.path(), bs)
+}
+
+//清除超出保留数量的版本
+func (h *History) prune() {
+	for h.size > 0 && len(h.versions) > h.size {
+		if err := h.engine.Remove(h.versions[0].path()); err != nil {
+			logger.WithError(err).Warn("remove history version ", h.versions[0].ID)
+		}
+		h.versions = h.versions[1:]
+	}
+}
+
+//回滚到version版本修改后的状态，即撤销所有比version新的修改。version为0时撤销所有保存的修改
+//test 检查回滚后的文件（例如：在临时目录中执行 nginx -t），检查通过后才写入存储。
+//检查或者写入失败时不删除版本记录，已经写入的文件恢复为回滚前的内容。
+//回滚本身不会产生新的版本
+func (h *History) Rollback(version int64, test func(restored plugins.StorageEngine) error) (rollbacks []*Version, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	rollbacks = make([]*Version, 0)
+	for i := len(h.versions) - 1; i >= 0 && h.versions[i].ID > version; i-- {
+		rollbacks = append(rollbacks, h.versions[i])
+	}
+	if len(rollbacks) == 0 {
+		return nil, fmt.Errorf("version %d is the latest version or not found", version)
+	}
+
+	//文件恢复为最早的回滚版本中记录的内容
+	restores, restored := make([]*File, 0), make(map[string]*File)
+	for _, v := range rollbacks {
+		for _, file := range v.Files {
+			if _, has := restored[file.Name]; !has {
+				restores = append(restores, file)
+			}
+			restored[file.Name] = file
+		}
+	}
+	for i, file := range restores {
+		restores[i] = restored[file.Name]
+	}
+
+	if test != nil {
+		if err = test(&restoredEngine{StorageEngine: h.engine, files: restored}); err != nil {
+			return nil, err
+		}
+	}
+
+	currents := make([]*File, 0, len(restores))
+	for _, file := range restores {
+		if cfgFile, err := h.engine.Get(file.Name); err == nil {
+			currents = append(currents, &File{Name: file.Name, Exists: true, Content: string(cfgFile.Content)})
+		} else if os.IsNotExist(err) {
+			currents = append(currents, &File{Name: file.Name, Exists: false})
+		} else {
+			return nil, err
+		}
+	}
+	if err = h.write(restores); err != nil {
+		if restoreErr := h.write(currents); restoreErr != nil {
+			logger.WithError(restoreErr).Warn("restore files after rollback failed")
+		}
+		return nil, err
+	}
+
+	for _, v := range rollbacks {
+		logger.Info("rollback version ", v.ID)
+		if err = h.engine.Remove(v.path()); err != nil {
+			logger.WithError(err).Warn("remove history version ", v.ID)
+		}
+		h.versions = h.versions[:len(h.versions)-1]
+	}
+	return rollbacks, nil
+}
+
+func (h *History) write(files []*File) error {
+	return plugins.Batch(h.engine, func(engine plugins.StorageEngine) (err error) {
+		for _, file := range files {
+			if file.Exists {
+				err = engine.Put(file.Name, []byte(file.Content))
+			} else if err = engine.Remove(file.Name); os.IsNotExist(err) {
+				err = nil
+			}
+			if err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
+//回滚后的存储，只用于检查回滚后的配置，不能修改
+type restoredEngine struct {
+	plugins.StorageEngine
+	files map[string]*File
+}
+
+func (re *restoredEngine) Put(file string, content []byte) error {
+	return errors.New("the restored storage is read only")
+}
+
+func (re *restoredEngine) Remove(file string) error {
+	return errors.New("the restored storage is read only")
+}
+
+func (re *restoredEngine) Get(name string) (*plugins.ConfigurationFile, error) {
+	if file, has := re.files[name]; has {
+		if !file.Exists {
+			return nil, os.ErrNotExist
+		}
+		return plugins.NewFile(name, []byte(file.Content)), nil
+	}
+	return re.StorageEngine.Get(name)
+}
+
+func (re *restoredEngine) Search(patterns ...string) ([]*plugins.ConfigurationFile, error) {
+	files, err := re.StorageEngine.Search(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*plugins.ConfigurationFile, 0, len(files))
+	found := make(map[string]bool)
+	for _, file := range files {
+		found[file.Name] = true
+		if restore, has := re.files[file.Name]; !has {
+			results = append(results, file)
+		} else if restore.Exists {
+			results = append(results, plugins.NewFile(file.Name, []byte(restore.Content)))
+		}
+	}
+	//回滚后重新出现的文件
+	for name, restore := range re.files {
+		if restore.Exists && !found[name] && matched(name, patterns...) {
+			results = append(results, plugins.NewFile(name, []byte(restore.Content)))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+func matched(name string, patterns ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, name); match {
+			return true
+		}
+	}
+	return false
+}
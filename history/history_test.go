@@ -0,0 +1,128 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+//写入指定文件时失败的存储
+type failedEngine struct {
+	memory.Storage
+	failed string
+}
+
+func (f *failedEngine) Put(file string, content []byte) error {
+	if file == f.failed {
+		return errors.New("put " + file + " error")
+	}
+	return f.Storage.Put(file, content)
+}
+
+//模拟一个修改请求
+func modify(h *History, user string, fn func(engine plugins.StorageEngine)) {
+	ctx := h.Begin(context.Background(), user)
+	defer h.End(ctx)
+	fn(plugins.WithContext(ctx, h.Engine(h.engine)))
+}
+
+func newHistory(t *testing.T, storage plugins.StorageEngine) *History {
+	h, err := New(storage, 10)
+	assert.Nil(t, err)
+	modify(h, "alice", func(engine plugins.StorageEngine) {
+		_ = engine.Put("nginx.conf", []byte("include conf.d/*.conf;"))
+		_ = engine.Put("conf.d/a.conf", []byte("server { listen 80; }"))
+	})
+	modify(h, "bob", func(engine plugins.StorageEngine) {
+		_ = engine.Put("conf.d/a.conf", []byte("server { listen 81; }"))
+		_ = engine.Put("conf.d/b.conf", []byte("server { listen 82; }"))
+	})
+	assert.Len(t, h.Versions(), 2)
+	return h
+}
+
+func TestRollback(t *testing.T) {
+	storage := memory.Storage{"nginx.conf": []byte("http {}")}
+	h := newHistory(t, storage)
+
+	rollbacks, err := h.Rollback(1, func(restored plugins.StorageEngine) error {
+		files, err := restored.Search("conf.d/*.conf")
+		assert.Nil(t, err)
+		assert.Len(t, files, 1)
+		assert.Equal(t, "server { listen 80; }", string(files[0].Content))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, rollbacks, 1)
+	assert.Equal(t, "server { listen 80; }", string(storage["conf.d/a.conf"]))
+	_, has := storage["conf.d/b.conf"]
+	assert.False(t, has)
+	assert.Len(t, h.Versions(), 1)
+
+	rollbacks, err = h.Rollback(0, nil)
+	assert.Nil(t, err)
+	assert.Len(t, rollbacks, 1)
+	assert.Equal(t, "http {}", string(storage["nginx.conf"]))
+	_, has = storage["conf.d/a.conf"]
+	assert.False(t, has)
+	assert.Len(t, h.Versions(), 0)
+}
+
+//检查失败时不修改文件，保留版本记录
+func TestRollbackTestFailure(t *testing.T) {
+	storage := memory.Storage{"nginx.conf": []byte("http {}")}
+	h := newHistory(t, storage)
+
+	_, err := h.Rollback(0, func(restored plugins.StorageEngine) error {
+		file, err := restored.Get("nginx.conf")
+		assert.Nil(t, err)
+		assert.Equal(t, "http {}", string(file.Content))
+		_, err = restored.Get("conf.d/a.conf")
+		assert.True(t, os.IsNotExist(err))
+		return errors.New("nginx -t failed")
+	})
+	assert.EqualError(t, err, "nginx -t failed")
+	assert.Equal(t, "include conf.d/*.conf;", string(storage["nginx.conf"]))
+	assert.Equal(t, "server { listen 81; }", string(storage["conf.d/a.conf"]))
+	assert.Equal(t, "server { listen 82; }", string(storage["conf.d/b.conf"]))
+	assert.Len(t, h.Versions(), 2)
+	_, has := storage["history/1.json"]
+	assert.True(t, has)
+	_, has = storage["history/2.json"]
+	assert.True(t, has)
+}
+
+//写入失败时已经写入的文件恢复为回滚前的内容，保留版本记录
+func TestRollbackWriteFailure(t *testing.T) {
+	storage := &failedEngine{Storage: memory.Storage{"nginx.conf": []byte("http {}")}}
+	h := newHistory(t, storage)
+
+	storage.failed = "nginx.conf"
+	_, err := h.Rollback(0, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "include conf.d/*.conf;", string(storage.Storage["nginx.conf"]))
+	assert.Equal(t, "server { listen 81; }", string(storage.Storage["conf.d/a.conf"]))
+	assert.Equal(t, "server { listen 82; }", string(storage.Storage["conf.d/b.conf"]))
+	assert.Len(t, h.Versions(), 2)
+}
+
+//修改请求中不属于请求的修改（例如：健康检查）不记录到请求的版本中
+func TestBackgroundChange(t *testing.T) {
+	storage := memory.Storage{}
+	h, err := New(storage, 10)
+	assert.Nil(t, err)
+	background := h.Engine(storage)
+	modify(h, "alice", func(engine plugins.StorageEngine) {
+		_ = background.Put("conf.d/upstream.conf", []byte("upstream a {}"))
+		_ = engine.Put("conf.d/a.conf", []byte("server {}"))
+	})
+	versions := h.Versions()
+	assert.Len(t, versions, 1)
+	assert.Equal(t, "alice", versions[0].User)
+	assert.Len(t, versions[0].Files, 1)
+	assert.Equal(t, "conf.d/a.conf", versions[0].Files[0].Name)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/access"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type accessController struct {
+	process *nginx.Process
+	bans    *access.Bans
+	jails   *access.Jails
+}
+
+func (ac *accessController) List(client *nginx.Client) []*nginx.AccessRule {
+	rules := client.AccessRules()
+	for _, rule := range rules {
+		rule.Expires = ac.bans.Expires(rule.Domain, rule.Address)
+	}
+	return rules
+}
+
+//请求内容为地址列表，每行一个或者多个，#后面为注释
+func (ac *accessController) addresses(ctx iris.Context) []string {
+	body, err := ctx.GetBody()
+	util.PanicIfError(err)
+	addresses, err := nginx.ParseAccessList(body)
+	util.PanicIfError(err)
+	for _, address := range ctx.Request().URL.Query()["address"] {
+		address, err = nginx.ParseAccessAddress(address)
+		util.PanicIfError(err)
+		addresses = append(addresses, address)
+	}
+	util.AssertTrue(len(addresses) > 0, "the addresses is empty")
+	return addresses
+}
+
+//批量添加规则，action=allow|deny(默认)，设置ttl时过期后自动删除
+func (ac *accessController) Add(ctx iris.Context, client *nginx.Client) int {
+	domain, action := ctx.URLParam("domain"), ctx.URLParamDefault("action", nginx.AccessDeny)
+	addresses := ac.addresses(ctx)
+	util.PanicIfError(client.AddAccessRules(domain, action, addresses...))
+	ac.store(client)
+	if ttl := ctx.URLParam("ttl"); ttl != "" {
+		duration, err := util.ParseDuration(ttl)
+		util.PanicIfError(err)
+		util.PanicIfError(ac.bans.Add(domain, duration, addresses...))
+	} else {
+		util.PanicIfError(ac.bans.Remove(domain, addresses...))
+	}
+	return iris.StatusNoContent
+}
+
+func (ac *accessController) Delete(ctx iris.Context, client *nginx.Client) int {
+	domain, addresses := ctx.URLParam("domain"), ac.addresses(ctx)
+	util.PanicIfError(client.RemoveAccessRules(domain, addresses...))
+	ac.store(client)
+	util.PanicIfError(ac.bans.Remove(domain, addresses...))
+	return iris.StatusNoContent
+}
+
+//设置了有效期的规则，包含自动封禁的地址
+func (ac *accessController) Bans() []*access.Ban {
+	return ac.bans.List()
+}
+
+//解除封禁，删除deny规则和有效期
+func (ac *accessController) Lift(ctx iris.Context) int {
+	addresses := ctx.Request().URL.Query()["address"]
+	util.AssertTrue(len(addresses) > 0, "the address is empty")
+	util.PanicIfError(ac.bans.Lift(ctx.Request().Context(), ctx.URLParam("domain"), addresses...))
+	return iris.StatusNoContent
+}
+
+func (ac *accessController) Jails() []*access.Jail {
+	if ac.jails == nil {
+		return []*access.Jail{}
+	}
+	return ac.jails.List()
+}
+
+func (ac *accessController) store(client *nginx.Client) {
+	util.PanicIfError(ac.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(ac.process.Reload())
+}
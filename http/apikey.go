@@ -0,0 +1,48 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/auth"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type apiKeyController struct {
+	auth *auth.Auth
+}
+
+type apiKeyResult struct {
+	*auth.APIKey
+	Key string `json:"key"`
+}
+
+func (kc *apiKeyController) keys() *auth.APIKeys {
+	keys := kc.auth.APIKeys()
+	util.AssertTrue(keys != nil, "api key not enabled, use --security, --user or --auth")
+	return keys
+}
+
+func (kc *apiKeyController) List() []*auth.APIKey {
+	return kc.keys().List()
+}
+
+//创建api key，返回的key只有这一次可以获取
+func (kc *apiKeyController) Create(ctx iris.Context) *apiKeyResult {
+	keys := kc.keys()
+	name := ctx.URLParamDefault("role", ctx.URLParamDefault("scope", auth.RoleViewer))
+	role := auth.ParseRole(name)
+	util.AssertTrue(role != "", "role not found: "+name)
+
+	identity := requestIdentity(ctx)
+	util.AssertTrue(identity != nil && identity.Covers(role), auth.ErrForbidden.Error())
+
+	key, secret, err := keys.Create(ctx.URLParam("label"), role, identity.User, issueTenant(ctx, identity))
+	util.PanicIfError(err)
+	return &apiKeyResult{APIKey: &auth.APIKey{
+		ID: key.ID, Label: key.Label, Role: key.Role, User: key.User, Tenant: key.Tenant, Created: key.Created,
+	}, Key: secret}
+}
+
+func (kc *apiKeyController) Revoke(ctx iris.Context) int {
+	util.PanicIfError(kc.keys().Revoke(ctx.Params().Get("id")))
+	return iris.StatusNoContent
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/approval"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type approvalController struct {
+	approvals *approval.Approvals
+	process   *nginx.Process
+}
+
+//开启审批时，修改请求的修改保存为等待审批的修改，返回202和等待审批的修改
+func (ac *approvalController) Handler(ctx iris.Context) {
+	switch ctx.Method() {
+	case iris.MethodGet, iris.MethodHead, iris.MethodOptions:
+		ctx.Next()
+		return
+	}
+	if !ac.approvals.Enabled {
+		ctx.Next()
+		return
+	}
+
+	changeset := &approval.Changeset{
+		Time: time.Now(), User: requestUser(ctx),
+		Method: ctx.Method(), Path: ctx.Path(), Queries: ctx.Request().URL.Query()["q"],
+	}
+	ctx.Record()
+	requestCtx := ac.approvals.Begin(ctx.Request().Context(), changeset)
+	resetContext(ctx, requestCtx)
+	ended := false
+	defer func() {
+		if !ended {
+			_, _ = ac.approvals.End(requestCtx, true)
+		}
+	}()
+	ctx.Next()
+
+	ended = true
+	pending, err := ac.approvals.End(requestCtx, ctx.IsStopped() || ctx.GetStatusCode() >= 400)
+	util.PanicIfError(err)
+	if pending != nil {
+		if recorder, match := ctx.Recorder(); match {
+			recorder.ResetBody()
+		}
+		ctx.StatusCode(iris.StatusAccepted)
+		_, _ = ctx.JSON(pending)
+	}
+}
+
+func (ac *approvalController) List() []*approval.Changeset {
+	return ac.approvals.List()
+}
+
+func (ac *approvalController) Get(ctx iris.Context) *approval.Changeset {
+	changeset, err := ac.approvals.Get(ctx.Params().Get("id"))
+	util.PanicIfError(err)
+	return changeset
+}
+
+//审批通过：测试配置通过后保存修改并重启nginx，审批人不能是修改的创建人
+func (ac *approvalController) Approve(ctx iris.Context, client *nginx.Client) *approval.Changeset {
+	changeset, err := ac.approvals.Approve(ctx.Request().Context(), ctx.Params().Get("id"), requestUser(ctx), func(files map[string][]byte) error {
+		return ac.process.Test(client.Configuration(), func(testDir string) error {
+			for name, content := range files {
+				path := filepath.Join(testDir, name)
+				if content == nil {
+					if err := os.RemoveAll(path); err != nil {
+						return err
+					}
+				} else if err := util.WriteFile(path, content); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	util.PanicIfError(err)
+	util.PanicIfError(ac.process.Reload())
+	return changeset
+}
+
+func (ac *approvalController) Reject(ctx iris.Context) *approval.Changeset {
+	changeset, err := ac.approvals.Reject(ctx.Params().Get("id"), requestUser(ctx))
+	util.PanicIfError(err)
+	return changeset
+}
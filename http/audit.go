@@ -0,0 +1,63 @@
+package http
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/audit"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"time"
+)
+
+type auditController struct {
+	auditor *audit.Auditor
+}
+
+func requestUser(ctx iris.Context) string {
+	if identity := requestIdentity(ctx); identity != nil {
+		return identity.User
+	}
+	user, _, _ := ctx.Request().BasicAuth()
+	return user
+}
+
+//记录所有修改请求
+func (ac *auditController) Handler(ctx iris.Context) {
+	switch ctx.Method() {
+	case iris.MethodGet, iris.MethodHead, iris.MethodOptions:
+		ctx.Next()
+		return
+	}
+
+	record := &audit.Record{
+		ID: requestId(ctx), Time: time.Now(), User: requestUser(ctx), Remote: ctx.RemoteAddr(),
+		Method: ctx.Method(), Path: ctx.Path(), Queries: ctx.Request().URL.Query()["q"],
+	}
+	resetContext(ctx, ac.auditor.Begin(plugins.WithUser(ctx.Request().Context(), record.User), record))
+	defer func() {
+		if err := recover(); err != nil {
+			record.Status = iris.StatusInternalServerError
+			record.Error = fmt.Sprintf("%v", err)
+			ac.auditor.End(record)
+			panic(err)
+		}
+		record.Status = ctx.GetStatusCode()
+		ac.auditor.End(record)
+	}()
+	ctx.Next()
+}
+
+func (ac *auditController) Query(ctx iris.Context) []*audit.Record {
+	filter := audit.Filter{
+		User: ctx.URLParam("user"), File: ctx.URLParam("file"),
+		Limit: ctx.URLParamIntDefault("limit", 100),
+	}
+	if since := ctx.URLParam("since"); since != "" {
+		var err error
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		util.PanicMessage(err, "since format error, example: 2006-01-02T15:04:05Z07:00")
+	}
+	records, err := ac.auditor.Query(filter)
+	util.PanicIfError(err)
+	return records
+}
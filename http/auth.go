@@ -0,0 +1,119 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/auth"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"time"
+)
+
+const identityKey = "aginx.identity"
+
+type authController struct {
+	auth *auth.Auth
+}
+
+func requestIdentity(ctx iris.Context) *auth.Identity {
+	if identity, match := ctx.Values().Get(identityKey).(*auth.Identity); match {
+		return identity
+	}
+	return nil
+}
+
+//识别用户，需要在审计和历史版本之前执行，这样才能记录修改的用户
+func (ac *authController) Identify(ctx iris.Context) {
+	if ac.auth.Enabled() {
+		if identity, err := ac.auth.Authenticate(ctx.GetHeader("Authorization")); err == nil {
+			ctx.Values().Set(identityKey, identity)
+		}
+	}
+	ctx.Next()
+}
+
+func (ac *authController) check(ctx iris.Context, permission string) bool {
+	if !ac.auth.Enabled() {
+		return true
+	}
+	identity := requestIdentity(ctx)
+	if identity == nil {
+		ctx.Header("WWW-Authenticate", `Basic realm="Authorization Required"`)
+		ctx.StatusCode(iris.StatusUnauthorized)
+		_, _ = ctx.JSON(map[string]string{"error": "Unauthorized", "message": auth.ErrUnauthorized.Error()})
+		ctx.StopExecution()
+		return false
+	}
+	//租户只能使用租户的api
+	if identity.Tenant != "" && !tenantPath(ctx.Path()) {
+		ctx.StatusCode(iris.StatusForbidden)
+		_, _ = ctx.JSON(map[string]string{"error": "Forbidden", "message": auth.ErrForbidden.Error()})
+		ctx.StopExecution()
+		return false
+	}
+	if !identity.Can(permission) {
+		ctx.StatusCode(iris.StatusForbidden)
+		_, _ = ctx.JSON(map[string]string{"error": "Forbidden", "message": auth.ErrForbidden.Error()})
+		ctx.StopExecution()
+		return false
+	}
+	return true
+}
+
+//检查权限，查询请求需要read，其他请求需要write
+func (ac *authController) Handler(ctx iris.Context) {
+	permission := auth.PermWrite
+	switch ctx.Method() {
+	case iris.MethodGet, iris.MethodHead, iris.MethodOptions:
+		permission = auth.PermRead
+	}
+	if ac.check(ctx, permission) {
+		ctx.Next()
+	}
+}
+
+//需要指定的权限
+func (ac *authController) Require(permission string) iris.Handler {
+	return func(ctx iris.Context) {
+		if ac.check(ctx, permission) {
+			ctx.Next()
+		}
+	}
+}
+
+//签发的token和api key绑定的租户，租户用户只能签发自己租户的
+func issueTenant(ctx iris.Context, identity *auth.Identity) string {
+	if identity.Tenant != "" {
+		return identity.Tenant
+	}
+	tenant := ctx.URLParam("tenant")
+	util.AssertTrue(tenant == "" || auth.ValidTenant(tenant), "tenant format error: "+tenant)
+	return tenant
+}
+
+type tokenResult struct {
+	Token  string    `json:"token"`
+	Expire time.Time `json:"expire"`
+}
+
+//签发token，角色的权限不能超出当前用户的权限
+func (ac *authController) Token(ctx iris.Context) *tokenResult {
+	jwt := ac.auth.JWT()
+	util.AssertTrue(jwt != nil, "jwt not enabled, use --jwt-key")
+
+	identity := requestIdentity(ctx)
+	util.AssertTrue(identity != nil, auth.ErrUnauthorized.Error())
+
+	name := ctx.URLParamDefault("role", ctx.URLParamDefault("scope", auth.RoleViewer))
+	role := auth.ParseRole(name)
+	util.AssertTrue(role != "", "role not found: "+name)
+	util.AssertTrue(identity.Covers(role), auth.ErrForbidden.Error())
+
+	var expire time.Duration
+	if value := ctx.URLParam("expire"); value != "" {
+		var err error
+		expire, err = time.ParseDuration(value)
+		util.PanicMessage(err, "expire format error, example: 24h")
+	}
+	token, expireAt, err := jwt.Issue(identity.User, []string{role}, issueTenant(ctx, identity), expire)
+	util.PanicIfError(err)
+	return &tokenResult{Token: token, Expire: expireAt}
+}
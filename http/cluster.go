@@ -0,0 +1,33 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/cluster"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type clusterController struct {
+	nodes   *cluster.Nodes
+	process *nginx.Process
+}
+
+//当前节点名称和是否为leader
+func (cc *clusterController) Info() *cluster.Node {
+	return cc.nodes.Self()
+}
+
+//注册在存储中的全部节点和最后一次重启nginx的结果
+func (cc *clusterController) Nodes() []*cluster.Node {
+	nodes, err := cc.nodes.List()
+	util.PanicIfError(err)
+	return nodes
+}
+
+//全部节点重启nginx，返回每个节点的结果。local=true时只重启当前节点（其他节点转发的请求）
+func (cc *clusterController) Reload(ctx iris.Context) []*cluster.Result {
+	if ctx.URLParam("local") == "true" {
+		return []*cluster.Result{cc.nodes.ReloadLocal(cc.process.Reload)}
+	}
+	return cc.nodes.Reload(cc.process.Reload, ctx.GetHeader("Authorization"))
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type conflictController struct {
+	engine    plugins.StorageEngine
+	conflicts *storage.Conflicts
+	process   *nginx.Process
+}
+
+//本地和存储中同时修改并且没有处理的文件（--sync-conflict manual）
+func (cc *conflictController) List() []*storage.Conflict {
+	return cc.conflicts.List()
+}
+
+//处理冲突：use=local 使用本地文件，use=storage 使用存储中的文件，没有use时使用请求内容作为合并后的文件。
+//测试配置通过后同时覆盖本地和存储中的文件并重启nginx
+func (cc *conflictController) Resolve(ctx iris.Context, client *nginx.Client) *storage.Conflict {
+	engine := requestEngine(ctx, cc.engine)
+	file := ctx.Params().Get("file")
+	conflict, err := cc.conflicts.Get(file)
+	util.PanicIfError(err)
+
+	var content []byte
+	switch use := ctx.URLParam("use"); use {
+	case "local":
+		content = conflict.Local
+	case "storage":
+		content = conflict.Remote
+	case "":
+		content, err = ctx.GetBody()
+		util.PanicIfError(err)
+		util.AssertTrue(!strings.Contains(string(content), "<<<<<<< local"), "the content has conflict markers")
+	default:
+		util.AssertTrue(false, "the use must be local or storage")
+	}
+
+	util.PanicIfError(cc.process.Test(client.Configuration(), func(testDir string) error {
+		path := filepath.Join(testDir, file)
+		if content == nil {
+			return os.RemoveAll(path)
+		}
+		return util.WriteFile(path, content)
+	}))
+	if content == nil {
+		util.PanicIfError(engine.Remove(file))
+	} else {
+		util.PanicIfError(engine.Put(file, content))
+	}
+	util.PanicIfError(cc.process.Reload())
+	return conflict
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"path/filepath"
+	"time"
+)
+
+type diffController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+}
+
+type diffResult struct {
+	Storage  []*nginx.FileDiff `json:"storage"`            //存储中的配置和本地文件的差异
+	Loaded   []*nginx.FileDiff `json:"loaded"`             //nginx加载的配置和本地文件的差异
+	LoadedAt time.Time         `json:"loadedAt,omitempty"` //nginx最后一次加载配置的时间
+}
+
+func (dc *diffController) storageFiles() map[string][]byte {
+	files, err := dc.engine.Search()
+	util.PanicIfError(err)
+	storageFiles := make(map[string][]byte, len(files))
+	for _, file := range files {
+		storageFiles[file.Name] = file.Content
+	}
+	return storageFiles
+}
+
+//比较存储中的配置、本地文件和nginx加载的配置
+func (dc *diffController) Diff() *diffResult {
+	local, err := nginx.LocalFiles()
+	util.PanicIfError(err)
+	result := &diffResult{Storage: nginx.DiffFiles(dc.storageFiles(), local), Loaded: []*nginx.FileDiff{}}
+	if loaded, loadedAt := dc.process.Loaded(); loaded != nil {
+		result.Loaded, result.LoadedAt = nginx.DiffFiles(loaded, local), loadedAt
+	}
+	return result
+}
+
+//同步存储和本地文件的差异：source=storage 使用存储中的文件覆盖本地文件，source=local 使用本地文件覆盖存储中的文件。
+//只同步修改和新增的文件，不会删除文件，测试配置通过后同步并重启nginx
+func (dc *diffController) Reconcile(ctx iris.Context, client *nginx.Client) []*nginx.FileDiff {
+	source := ctx.URLParamDefault("source", "storage")
+	util.AssertTrue(source == "storage" || source == "local", "the source must be storage or local")
+
+	local, err := nginx.LocalFiles()
+	util.PanicIfError(err)
+	storage := dc.storageFiles()
+
+	from, to := storage, local
+	if source == "local" {
+		from, to = local, storage
+	}
+	synced := make([]*nginx.FileDiff, 0)
+	for _, diff := range nginx.DiffFiles(to, from) {
+		if diff.Status != nginx.DiffRemoved {
+			synced = append(synced, diff)
+		}
+	}
+	if len(synced) == 0 {
+		return synced
+	}
+	//同步前测试配置
+	util.PanicIfError(dc.process.Test(client.Configuration(), func(testDir string) error {
+		for _, diff := range synced {
+			if err := util.WriteFile(filepath.Join(testDir, diff.File), from[diff.File]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	for _, diff := range synced {
+		if source == "local" {
+			util.PanicIfError(requestEngine(ctx, dc.engine).Put(diff.File, from[diff.File]))
+		} else {
+			util.PanicIfError(util.WriteFile(filepath.Join(nginx.MustConfigDir(), diff.File), from[diff.File]))
+		}
+	}
+	util.PanicIfError(dc.process.Reload())
+	return synced
+}
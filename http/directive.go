@@ -11,37 +11,104 @@ type directiveController struct {
 	process *nginx.Process
 }
 
-func (as *directiveController) queryDirective(client *nginx.Client, queries []string) []*nginx.Directive {
+func (as *directiveController) queryDirective(ctx iris.Context, client *nginx.Client, queries []string) []*nginx.Directive {
 	directives, err := client.Select(queries...)
 	util.PanicIfError(err)
+	setETag(ctx, client.Revision())
 	return directives
 }
 
-func (as *directiveController) addDirective(client *nginx.Client, queries []string, directives []*nginx.Directive) int {
+func (as *directiveController) addDirective(ctx iris.Context, client *nginx.Client, queries []string, directives []*nginx.Directive) int {
+	assertRevision(ctx, client)
 	util.PanicIfError(client.Add(queries, directives...))
 	util.PanicIfError(as.process.Test(client.Configuration()))
 	util.PanicIfError(client.Store())
+	setETag(ctx, client.Revision())
 	return as.reload()
 }
 
-func (as *directiveController) deleteDirective(client *nginx.Client, queries []string) int {
+func (as *directiveController) deleteDirective(ctx iris.Context, client *nginx.Client, queries []string) int {
+	assertRevision(ctx, client)
 	util.PanicIfError(client.Delete(queries...))
 	util.PanicIfError(as.process.Test(client.Configuration()))
 	util.PanicIfError(client.Store())
+	setETag(ctx, client.Revision())
 	return as.reload()
 }
 
-func (as *directiveController) modifyDirective(client *nginx.Client, queries []string, directives []*nginx.Directive) int {
+func (as *directiveController) modifyDirective(ctx iris.Context, client *nginx.Client, queries []string, directives []*nginx.Directive) int {
 	if len(directives) == 0 {
 		panic(errors.New("new directive is empty"))
 	}
+	assertRevision(ctx, client)
 	util.PanicIfError(client.Modify(queries, directives[0]))
 	util.PanicIfError(as.process.Test(client.Configuration()))
 	util.PanicIfError(client.Store())
+	setETag(ctx, client.Revision())
 	return as.reload()
 }
 
+//服务发现频繁调用，设置了 --reload-debounce 时合并重启
 func (as *directiveController) reload() int {
-	util.PanicIfError(as.process.Reload())
+	util.PanicIfError(as.process.MergeReload())
 	return iris.StatusNoContent
 }
+
+//nginx程序信息：版本、编译的模块
+func (as *directiveController) info() *nginx.Info {
+	info, err := as.process.Info()
+	util.PanicIfError(err)
+	return info
+}
+
+//nginx的连接和请求统计(stub_status)
+func (as *directiveController) status() *nginx.StubStatus {
+	status, err := as.process.StubStatus()
+	util.PanicIfError(err)
+	return status
+}
+
+type validateResult struct {
+	Success bool     `json:"success"`
+	Output  string   `json:"output"`
+	Files   []string `json:"files"` //保存时会写入的文件
+}
+
+//测试修改后的配置是否正确，不会保存配置
+func (as *directiveController) validate(ctx iris.Context, client *nginx.Client, queries []string, directives []*nginx.Directive) *validateResult {
+	op := &nginx.Operation{
+		Action:  ctx.URLParamDefault("action", nginx.ActionAdd),
+		Queries: queries, Directives: directives,
+	}
+	util.PanicIfError(op.Apply(client))
+	output, err := as.process.Validate(client.Configuration())
+	return &validateResult{Success: err == nil, Output: output, Files: client.Changes()}
+}
+
+//批量修改，全部成功后才会保存，并且只重启一次
+func (as *directiveController) batch(ctx iris.Context, client *nginx.Client) int {
+	batch := client.Batch()
+	util.PanicIfError(ctx.ReadJSON(&batch.Operations))
+	util.AssertTrue(len(batch.Operations) > 0, "the operations is empty")
+	assertRevision(ctx, client)
+	util.PanicIfError(batch.Commit())
+	setETag(ctx, client.Revision())
+	return iris.StatusNoContent
+}
+
+type sandboxRequest struct {
+	Operations []*nginx.Operation `json:"operations"`
+	Probes     []*nginx.Probe     `json:"probes"`
+}
+
+//在沙箱中运行修改后的配置并发送探测请求，不会保存配置
+func (as *directiveController) sandbox(ctx iris.Context, client *nginx.Client) *nginx.SandboxResult {
+	request := new(sandboxRequest)
+	util.PanicIfError(ctx.ReadJSON(request))
+	for _, op := range request.Operations {
+		util.PanicIfError(op.Apply(client))
+	}
+	result, err := as.process.Sandbox(client.Configuration(), request.Probes)
+	util.PanicIfError(err)
+	return result
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/hero"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//批量修改中间的一个修改失败时，存储中的配置不变，也不会执行nginx
+func TestBatchRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	//记录nginx的调用
+	nginxLog := filepath.Join(dir, "nginx.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + nginxLog + "\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "nginx"), []byte(script), 0755))
+	defer func(path string) { _ = os.Setenv("PATH", path) }(os.Getenv("PATH"))
+	assert.Nil(t, os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH")))
+	assert.Nil(t, nginx.UseNginx("file://"+filepath.ToSlash(filepath.Join(dir, "nginx.conf"))))
+	defer func() { _ = nginx.UseNginx("") }()
+
+	engine := memory.Storage{
+		"nginx.conf":     []byte("events {}\nhttp {\n\tinclude hosts.d/*.conf;\n}\n"),
+		"hosts.d/a.conf": []byte("server {\n\tlisten 80;\n\tserver_name a.aginx.io;\n}\n"),
+	}
+	stored := memory.Storage{}
+	for name, content := range engine {
+		stored[name] = content
+	}
+
+	process := new(nginx.Process)
+	h := hero.New()
+	h.Register(func(ctx iris.Context) *nginx.Client {
+		return nginx.MustClient("", engine, nil, process)
+	})
+	directive := &directiveController{process: process}
+	app := iris.New()
+	app.Use(recoverHandler)
+	app.Post("/api/batch", h.Handler(directive.batch))
+	assert.Nil(t, app.Build())
+
+	body := `[
+		{"action": "add", "queries": ["http"], "directives": [{"name": "upstream", "args": ["demo"], "body": [{"name": "server", "args": ["127.0.0.1:8080"]}]}]},
+		{"action": "delete", "queries": ["http", "server.server_name('missing.aginx.io')"]},
+		{"action": "modify", "queries": ["http", "server.server_name('a.aginx.io')", "listen"], "directives": [{"name": "listen", "args": ["81"]}]}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+
+	assert.Equal(t, iris.StatusInternalServerError, resp.Code)
+	assert.Contains(t, resp.Body.String(), "operation 1")
+	assert.Equal(t, stored, engine)
+	_, err = os.Stat(nginxLog)
+	assert.True(t, os.IsNotExist(err))
+}
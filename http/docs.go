@@ -0,0 +1,23 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type docsController struct {
+}
+
+//全部有文档的指令名称
+func (dc *docsController) Directives() []string {
+	return nginx.DirectiveDocNames()
+}
+
+//指令的文档：模块、语法、默认值和可以使用的context
+func (dc *docsController) Directive(ctx iris.Context) *nginx.DirectiveDoc {
+	name := ctx.Params().Get("name")
+	doc := nginx.GetDirectiveDoc(name)
+	util.AssertTrue(doc != nil, "the directive document not found: "+name)
+	return doc
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"net/http"
+	"time"
+)
+
+type eventsController struct {
+	upgrader websocket.Upgrader
+}
+
+func newEventsController() *eventsController {
+	return &eventsController{
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+//使用WebSocket推送nginx生命周期事件(reload, test-failure, reload-rollback, certificate-renewal, upstream-change, upstream-health, external-edit, sync-conflict, backup)
+func (ec *eventsController) Events(ctx iris.Context) {
+	conn, err := ec.upgrader.Upgrade(ctx.ResponseWriter(), ctx.Request(), nil)
+	if err != nil {
+		logger.WithError(err).Warn("upgrade websocket")
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	events := make(chan *util.Event, 100)
+	unsubscribe := util.SubscribeEvent(func(event *util.Event) {
+		select {
+		case events <- event:
+		default:
+			logger.Warn("events client is too slow, drop event ", event.Name)
+		}
+	})
+	defer unsubscribe()
+
+	//客户端不会发送消息，读取只用于处理关闭
+	closeC := make(chan struct{})
+	go func() {
+		defer close(closeC)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(time.Second * 30)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closeC:
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second*5)); err != nil {
+				return
+			}
+		case event := <-events:
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
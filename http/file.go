@@ -10,7 +10,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 type fileController struct {
@@ -18,6 +17,21 @@ type fileController struct {
 	process *nginx.Process
 }
 
+//aginx自己的数据（api key、证书、历史版本等）不能通过文件接口读写
+func notReserved(file string) {
+	if plugins.Reserved(file) {
+		panic(plugins.ErrReserved)
+	}
+}
+
+//文件接口的文件：配置目录中的相对路径，并且不是aginx自己的数据
+func relativePath(file string) string {
+	name, err := util.RelativePath(file)
+	util.PanicIfError(err)
+	notReserved(name)
+	return name
+}
+
 func (as *fileController) Search(queries []string) map[string]string {
 	files := make(map[string]string, 0)
 	cfgFiles, err := as.engine.Search(queries...)
@@ -40,48 +54,106 @@ func (as *fileController) readFile(ctx iris.Context) []byte {
 	return out.Bytes()
 }
 
-func (as *fileController) New(ctx iris.Context, client *nginx.Client) int {
-	filePath := ctx.FormValue("path")
-	if strings.HasPrefix(filePath, "/") {
-		panic("path must be relative")
+//测试配置文件，文件没有被include时临时添加include后测试
+func (as *fileController) test(client *nginx.Client, filePath string, bodys []byte) {
+	if filepath.Ext(filePath) != ".conf" {
+		return
 	}
-	bodys := as.readFile(ctx)
-	//如果是配置文件需要测试是否可用
-	if filepath.Ext(filePath) == ".conf" {
-		need := true
-		if includes, err := client.Select("http", "include"); err == nil {
-			for _, include := range includes {
-				if matched, _ := filepath.Match(include.Args[0], filePath); matched {
-					need = false
-				}
+	need := true
+	if includes, err := client.Select("http", "include"); err == nil {
+		for _, include := range includes {
+			if matched, _ := filepath.Match(include.Args[0], filePath); matched {
+				need = false
 			}
 		}
-		if need {
-			_ = client.Add(nginx.Queries("http"), nginx.NewDirective("include", filePath))
-		}
-		util.PanicIfError(as.process.Test(client.Configuration(), func(testDir string) error {
-			path := filepath.Join(testDir, filePath)
-			return util.WriteFile(path, bodys)
-		}))
-		if need {
-			_ = client.Delete("http", fmt.Sprintf("include('%s')", filePath))
+	}
+	if filePath == nginx.NGINX_CONF {
+		need = false
+	}
+	//严格模式检查文件中的指令，nginx.conf之外的文件include在http中
+	if conf, err := nginx.ReaderReadable(nil, plugins.NewFile(filePath, bodys)); err == nil {
+		context := "http"
+		if filePath == nginx.NGINX_CONF {
+			context = "main"
 		}
+		util.PanicIfError(as.process.CheckStrict(context, conf.Body...))
+	}
+	if need {
+		_ = client.Add(nginx.Queries("http"), nginx.NewDirective("include", filePath))
+	}
+	util.PanicIfError(as.process.Test(client.Configuration(), func(testDir string) error {
+		path := filepath.Join(testDir, filePath)
+		return util.WriteFile(path, bodys)
+	}))
+	if need {
+		_ = client.Delete("http", fmt.Sprintf("include('%s')", filePath))
+	}
+}
+
+func (as *fileController) New(ctx iris.Context, client *nginx.Client) int {
+	filePath := relativePath(ctx.FormValue("path"))
+	bodys := as.readFile(ctx)
+	//如果是配置文件需要测试是否可用
+	as.test(client, filePath, bodys)
+	as.put(ctx, filePath, bodys)
+	util.PanicIfError(as.process.Reload())
+	return iris.StatusNoContent
+}
+
+//保存文件，请求有If-Match时检查文件的版本，返回新的版本
+func (as *fileController) put(ctx iris.Context, filePath string, bodys []byte) {
+	engine := requestEngine(ctx, as.engine)
+	util.PanicIfError(nginx.IfMatch(engine, filePath, ifMatch(ctx), func(engine plugins.StorageEngine) error {
+		return engine.Put(filePath, bodys)
+	}))
+	setETag(ctx, nginx.FileRevision(bodys))
+}
+
+//配置文件的include树
+func (as *fileController) Tree(client *nginx.Client) *nginx.IncludeFile {
+	return nginx.IncludeTree(client.Configuration())
+}
+
+//配置文件的include关系图，包括每个文件中的server和upstream
+func (as *fileController) Graph(client *nginx.Client) *nginx.IncludeGraph {
+	return nginx.NewIncludeGraph(client.Configuration())
+}
+
+//读取文件原始内容
+func (as *fileController) Raw(ctx iris.Context) {
+	filePath := relativePath(ctx.Params().Get("file"))
+	file, err := requestEngine(ctx, as.engine).Get(filePath)
+	util.PanicIfError(err)
+	setETag(ctx, nginx.FileRevision(file.Content))
+	ctx.ContentType("text/plain")
+	_, _ = ctx.Write(file.Content)
+}
+
+//使用请求内容替换整个文件，配置文件先检查语法并测试(nginx -t)，通过后保存并重启nginx
+func (as *fileController) PutRaw(ctx iris.Context, client *nginx.Client) int {
+	filePath := relativePath(ctx.Params().Get("file"))
+	bodys, err := ctx.GetBody()
+	util.PanicIfError(err)
+	if filepath.Ext(filePath) == ".conf" {
+		_, err = nginx.ReaderReadable(nil, plugins.NewFile(filePath, bodys))
+		util.PanicIfError(err)
 	}
-	util.PanicIfError(as.engine.Put(filePath, bodys))
+	as.test(client, filePath, bodys)
+	as.put(ctx, filePath, bodys)
 	util.PanicIfError(as.process.Reload())
 	return iris.StatusNoContent
 }
 
 func (as *fileController) Remove(ctx iris.Context, client *nginx.Client) int {
-	file := ctx.URLParam("file")
-	if strings.HasPrefix(file, "/") {
-		panic("Get path must be relative")
-	}
+	engine := requestEngine(ctx, as.engine)
+	file := relativePath(ctx.URLParam("file"))
 	util.PanicIfError(as.process.Test(client.Configuration(), func(testDir string) error {
 		path := filepath.Join(testDir, file)
 		return os.Remove(path)
 	}))
-	util.PanicMessage(as.engine.Remove(file), "remove file error")
+	util.PanicMessage(nginx.IfMatch(engine, file, ifMatch(ctx), func(engine plugins.StorageEngine) error {
+		return engine.Remove(file)
+	}), "remove file error")
 	util.PanicIfError(as.process.Reload())
 	return iris.StatusNoContent
 }
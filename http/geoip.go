@@ -0,0 +1,70 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/geoip"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type geoController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+	updater *geoip.Updater //没有使用 --geoip 时为nil
+}
+
+func (gc *geoController) List() []*nginx.GeoPolicy {
+	policies, err := nginx.LoadGeoPolicies(gc.engine)
+	util.PanicIfError(err)
+	return policies
+}
+
+func (gc *geoController) Get(ctx iris.Context) *nginx.GeoPolicy {
+	policy, err := nginx.LoadGeoPolicy(requestEngine(ctx, gc.engine), ctx.Params().Get("domain"))
+	util.PanicIfError(err)
+	return policy
+}
+
+//设置地域策略，{block, location, routes, default}，已经存在时先删除原来的配置
+func (gc *geoController) Set(ctx iris.Context, client *nginx.Client) *nginx.GeoPolicy {
+	engine := requestEngine(ctx, gc.engine)
+	policy := new(nginx.GeoPolicy)
+	util.PanicIfError(ctx.ReadJSON(policy))
+	policy.Domain = ctx.Params().Get("domain")
+	if old, err := nginx.LoadGeoPolicy(engine, policy.Domain); err == nil {
+		util.PanicIfError(client.DeleteGeoPolicy(old))
+	}
+	util.PanicIfError(client.SetGeoPolicy(policy))
+	gc.store(client)
+	util.PanicIfError(nginx.SaveGeoPolicy(engine, policy))
+	return policy
+}
+
+func (gc *geoController) Delete(ctx iris.Context, client *nginx.Client) int {
+	engine := requestEngine(ctx, gc.engine)
+	policy, err := nginx.LoadGeoPolicy(engine, ctx.Params().Get("domain"))
+	util.PanicIfError(err)
+	util.PanicIfError(client.DeleteGeoPolicy(policy))
+	gc.store(client)
+	util.PanicIfError(nginx.RemoveGeoPolicy(engine, policy))
+	return iris.StatusNoContent
+}
+
+func (gc *geoController) Database() *geoip.Database {
+	util.AssertTrue(gc.updater != nil, nginx.ErrGeoNotConfigured.Error())
+	return gc.updater.Database()
+}
+
+//立即更新数据库并重启nginx
+func (gc *geoController) Update() int {
+	util.AssertTrue(gc.updater != nil, nginx.ErrGeoNotConfigured.Error())
+	util.PanicIfError(gc.updater.Update())
+	return iris.StatusNoContent
+}
+
+func (gc *geoController) store(client *nginx.Client) {
+	util.PanicIfError(gc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(gc.process.Reload())
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/history"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type historyController struct {
+	history *history.History
+	process *nginx.Process
+	client  func(engine plugins.StorageEngine) (*nginx.Client, error)
+}
+
+//每个修改请求生成一个版本
+func (hc *historyController) Handler(ctx iris.Context) {
+	switch ctx.Method() {
+	case iris.MethodGet, iris.MethodHead, iris.MethodOptions:
+		ctx.Next()
+		return
+	}
+	requestCtx := hc.history.Begin(ctx.Request().Context(), requestUser(ctx))
+	resetContext(ctx, requestCtx)
+	defer hc.history.End(requestCtx)
+	ctx.Next()
+}
+
+func (hc *historyController) Versions() []*history.Version {
+	return hc.history.Versions()
+}
+
+func (hc *historyController) Rollback(ctx iris.Context) []*history.Version {
+	version, err := ctx.URLParamInt64("version")
+	util.PanicMessage(err, "version parameter error")
+
+	//先检查回滚后的配置，检查失败不会修改任何文件
+	rollbacks, err := hc.history.Rollback(version, func(restored plugins.StorageEngine) error {
+		client, err := hc.client(restored)
+		if err != nil {
+			return err
+		}
+		return hc.process.Test(client.Configuration())
+	})
+	util.PanicIfError(err)
+	util.PanicIfError(hc.process.Reload())
+	return rollbacks
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type htpasswdController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+}
+
+func (hc *htpasswdController) List() []*nginx.Htpasswd {
+	list, err := nginx.ListHtpasswd(hc.engine)
+	util.PanicIfError(err)
+	return list
+}
+
+//添加用户或者修改密码，{password}，nginx每次请求读取文件，不需要重启
+func (hc *htpasswdController) SetUser(ctx iris.Context) int {
+	body := struct {
+		Password string `json:"password"`
+	}{}
+	util.PanicIfError(ctx.ReadJSON(&body))
+	util.PanicIfError(nginx.SetHtpasswdUser(requestEngine(ctx, hc.engine), ctx.Params().Get("name"), ctx.Params().Get("user"), body.Password))
+	return iris.StatusNoContent
+}
+
+func (hc *htpasswdController) DeleteUser(ctx iris.Context) int {
+	util.PanicIfError(nginx.DeleteHtpasswdUser(requestEngine(ctx, hc.engine), ctx.Params().Get("name"), ctx.Params().Get("user")))
+	return iris.StatusNoContent
+}
+
+func (hc *htpasswdController) Delete(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.DeleteHtpasswd(ctx.Params().Get("name")))
+	return iris.StatusNoContent
+}
+
+//location开启basic认证，{domain, location, realm}
+func (hc *htpasswdController) Attach(ctx iris.Context, client *nginx.Client) int {
+	body := struct {
+		Domain   string `json:"domain"`
+		Location string `json:"location"`
+		Realm    string `json:"realm"`
+	}{}
+	util.PanicIfError(ctx.ReadJSON(&body))
+	if body.Location == "" {
+		body.Location = "/"
+	}
+	util.PanicIfError(client.SetAuthBasic(body.Domain, body.Location, body.Realm, ctx.Params().Get("name")))
+	return hc.store(client)
+}
+
+func (hc *htpasswdController) Detach(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.RemoveAuthBasic(ctx.URLParam("domain"), ctx.URLParamDefault("location", "/")))
+	return hc.store(client)
+}
+
+func (hc *htpasswdController) store(client *nginx.Client) int {
+	util.PanicIfError(hc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(hc.process.Reload())
+	return iris.StatusNoContent
+}
@@ -2,16 +2,24 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/ownership"
+	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/util"
 	"github.com/kataras/iris/v12"
+	"net"
 	"time"
 )
 
 type Http struct {
-	app     *iris.Application
-	address string
-	routers func(app *iris.Application)
+	app       *iris.Application
+	address   string
+	routers   func(app *iris.Application)
+	tlsConfig *tls.Config
+	drain     time.Duration
 }
 
 func NewHttp(address string, routers func(*iris.Application)) *Http {
@@ -20,26 +28,31 @@ func NewHttp(address string, routers func(*iris.Application)) *Http {
 	}
 }
 
+//使用https，tls.Config中设置ClientCAs将验证客户端证书
+func (this *Http) TLS(tlsConfig *tls.Config) *Http {
+	this.tlsConfig = tlsConfig
+	return this
+}
+
+//停止时等待处理中的请求完成的最长时间，0一直等待
+func (this *Http) Drain(timeout time.Duration) *Http {
+	this.drain = timeout
+	return this
+}
+
+func (this *Http) runner() (iris.Runner, error) {
+	if this.tlsConfig == nil {
+		return iris.Addr(this.address), nil
+	}
+	listener, err := net.Listen("tcp", this.address)
+	if err != nil {
+		return nil, err
+	}
+	return iris.Listener(tls.NewListener(listener, this.tlsConfig)), nil
+}
+
 func (this *Http) Start() error {
-	this.app.Use(func(ctx iris.Context) {
-		defer func() {
-			if err := recover(); err != nil {
-				if ctx.IsStopped() {
-					return
-				}
-				ctx.StatusCode(iris.StatusInternalServerError)
-				_, _ = ctx.JSON(map[string]string{
-					"error":   "InternalServerError",
-					"message": fmt.Sprintf("%v", err),
-				})
-				if _, match := err.(*util.WrapError); !match {
-					logger.Error("handler error: ", err)
-				}
-				ctx.StopExecution()
-			}
-		}()
-		ctx.Next()
-	})
+	this.app.Use(recoverHandler)
 	this.app.OnErrorCode(iris.StatusNotFound, func(ctx iris.Context) {
 		_, _ = ctx.JSON(map[string]string{
 			"error":   "notfound",
@@ -54,9 +67,13 @@ func (this *Http) Start() error {
 
 	this.routers(this.app)
 
+	runner, err := this.runner()
+	if err != nil {
+		return err
+	}
 	if err := util.Async(time.Second, func() error {
 		return this.app.Run(
-			iris.Addr(this.address),
+			runner,
 			iris.WithoutBanner,
 			iris.WithoutServerError(iris.ErrServerClosed),
 		)
@@ -69,5 +86,46 @@ func (this *Http) Start() error {
 
 func (this *Http) Stop() error {
 	logger.Info("http server stop.")
-	return this.app.Shutdown(context.TODO())
+	ctx := context.TODO()
+	if this.drain > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, this.drain)
+		defer cancel()
+	}
+	return this.app.Shutdown(ctx)
+}
+
+//处理请求中的panic，返回错误信息
+func recoverHandler(ctx iris.Context) {
+	defer func() {
+		if err := recover(); err != nil {
+			if ctx.IsStopped() {
+				return
+			}
+			//修改受保护的域名，读写aginx自己的数据
+			if e, match := err.(error); match && (errors.Is(e, ownership.ErrProtected) || errors.Is(e, plugins.ErrReserved)) {
+				ctx.StatusCode(iris.StatusForbidden)
+				_, _ = ctx.JSON(map[string]string{"error": "Forbidden", "message": e.Error()})
+				ctx.StopExecution()
+				return
+			}
+			//读取后配置被其他请求修改
+			if e, match := err.(error); match && errors.Is(e, nginx.ErrConflict) {
+				ctx.StatusCode(iris.StatusConflict)
+				_, _ = ctx.JSON(map[string]string{"error": "Conflict", "message": e.Error()})
+				ctx.StopExecution()
+				return
+			}
+			ctx.StatusCode(iris.StatusInternalServerError)
+			_, _ = ctx.JSON(map[string]string{
+				"error":   "InternalServerError",
+				"message": fmt.Sprintf("%v", err),
+			})
+			if _, match := err.(*util.WrapError); !match {
+				logger.Error("handler error: ", err)
+			}
+			ctx.StopExecution()
+		}
+	}()
+	ctx.Next()
 }
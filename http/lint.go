@@ -0,0 +1,72 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"runtime"
+	"strings"
+)
+
+type lintController struct {
+	engine plugins.StorageEngine
+}
+
+//检查配置中 nginx -t 不能发现的问题
+func (lc *lintController) Lint(ctx iris.Context) []*nginx.Lint {
+	lints, err := nginx.LintConfiguration(requestEngine(ctx, lc.engine), ctx.Request().URL.Query()["skip"]...)
+	util.PanicIfError(err)
+	return lints
+}
+
+//对照最佳实践检查配置并打分
+func (lc *lintController) Analyze() *nginx.Analysis {
+	analysis, err := nginx.Analyze(lc.engine, runtime.NumCPU())
+	util.PanicIfError(err)
+	return analysis
+}
+
+//格式化请求内容，没有请求内容时格式化存储中的文件(file)，check时只返回差异
+func (lc *lintController) Format(ctx iris.Context) {
+	file := ctx.URLParam("file")
+	content, err := ctx.GetBody()
+	util.PanicIfError(err)
+	if len(content) == 0 {
+		util.AssertTrue(file != "", "the request body or file is required")
+		file = relativePath(file)
+		stored, err := requestEngine(ctx, lc.engine).Get(file)
+		util.PanicIfError(err)
+		content = stored.Content
+	} else if file == "" {
+		file = "-"
+	}
+	formatted, err := nginx.Format(file, content)
+	util.PanicIfError(err)
+
+	if check, _ := ctx.URLParamBool("check"); check {
+		diffs := nginx.DiffFiles(map[string][]byte{file: content}, map[string][]byte{file: formatted})
+		_, _ = ctx.JSON(diffs)
+		return
+	}
+	ctx.ContentType("text/plain")
+	_, _ = ctx.Write(formatted)
+}
+
+//模拟nginx处理请求，说明匹配的server、location和map变量的值
+func (lc *lintController) Resolve(ctx iris.Context, client *nginx.Client) *nginx.Resolution {
+	request := &nginx.ResolveRequest{
+		Host: ctx.URLParam("host"), Uri: ctx.URLParamDefault("uri", "/"), Port: ctx.URLParamIntDefault("port", 0),
+		Scheme: ctx.URLParam("scheme"), Method: ctx.URLParam("method"), Remote: ctx.URLParam("remote"),
+		Headers: map[string]string{},
+	}
+	util.AssertTrue(request.Host != "", "the host is required")
+	for _, header := range ctx.Request().URL.Query()["header"] {
+		idx := strings.Index(header, ":")
+		util.AssertTrue(idx > 0, "the header format is Name: value")
+		request.Headers[strings.TrimSpace(header[:idx])] = strings.TrimSpace(header[idx+1:])
+	}
+	resolution, err := nginx.Resolve(client.Configuration(), request)
+	util.PanicIfError(err)
+	return resolution
+}
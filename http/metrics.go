@@ -0,0 +1,17 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/metrics"
+	"github.com/kataras/iris/v12"
+	"time"
+)
+
+//记录api请求数量和耗时，使用路由地址作为标签，避免标签数量过多
+func metricsHandler(ctx iris.Context) {
+	start := time.Now()
+	defer func() {
+		route := ctx.GetCurrentRoute().Path()
+		metrics.ObserveRequest(ctx.Method(), route, ctx.GetStatusCode(), time.Since(start))
+	}()
+	ctx.Next()
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/ihaiker/aginx/auth"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	oidcStateCookie    = "aginx_oidc_state"
+	oidcRedirectCookie = "aginx_oidc_redirect"
+)
+
+type oidcController struct {
+	auth *auth.Auth
+}
+
+func (oc *oidcController) oidc() *auth.OIDC {
+	oidc := oc.auth.OIDC()
+	util.AssertTrue(oidc != nil, "oidc not enabled, use --auth oidc://")
+	return oidc
+}
+
+//跳转到认证服务登录，redirect为登录成功后跳转的页面（只能是本站地址）
+func (oc *oidcController) Login(ctx iris.Context) {
+	oidc := oc.oidc()
+	bs := make([]byte, 16)
+	_, err := rand.Read(bs)
+	util.PanicIfError(err)
+	state := hex.EncodeToString(bs)
+
+	address, err := oidc.AuthCodeURL(state)
+	util.PanicIfError(err)
+
+	expires := iris.CookieExpires(time.Minute * 10)
+	ctx.SetCookieKV(oidcStateCookie, state, iris.CookieHTTPOnly(true), expires)
+	if redirect := ctx.URLParam("redirect"); strings.HasPrefix(redirect, "/") && !strings.HasPrefix(redirect, "//") {
+		ctx.SetCookieKV(oidcRedirectCookie, redirect, iris.CookieHTTPOnly(true), expires)
+	}
+	ctx.Redirect(address, iris.StatusFound)
+}
+
+//认证服务回调，返回id_token，之后使用 Authorization: Bearer <token> 访问api
+func (oc *oidcController) Callback(ctx iris.Context) {
+	oidc := oc.oidc()
+	errMsg := ctx.URLParam("error")
+	util.AssertTrue(errMsg == "", errMsg+" "+ctx.URLParam("error_description"))
+	state := ctx.GetCookie(oidcStateCookie)
+	util.AssertTrue(state != "" && state == ctx.URLParam("state"), "oidc state did not match")
+	ctx.RemoveCookie(oidcStateCookie)
+
+	token, expire, err := oidc.Exchange(ctx.URLParam("code"))
+	util.PanicIfError(err)
+
+	if redirect := ctx.GetCookie(oidcRedirectCookie); redirect != "" {
+		ctx.RemoveCookie(oidcRedirectCookie)
+		params := url.Values{}
+		params.Set("token", token)
+		params.Set("expire", expire.Format(time.RFC3339))
+		ctx.Redirect(redirect+"#"+params.Encode(), iris.StatusFound)
+		return
+	}
+	_, _ = ctx.JSON(&tokenResult{Token: token, Expire: expire})
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/auth"
+	"github.com/ihaiker/aginx/ownership"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type ownershipController struct {
+	auth      *auth.Auth
+	ownership *ownership.Ownership
+}
+
+//修改请求检查域名的保护规则，管理员和未开启认证时不检查
+func (oc *ownershipController) Handler(ctx iris.Context) {
+	switch ctx.Method() {
+	case iris.MethodGet, iris.MethodHead, iris.MethodOptions:
+		ctx.Next()
+		return
+	}
+	identity := requestIdentity(ctx)
+	bypass := !oc.auth.Enabled() || (identity != nil && identity.Tenant == "" && identity.Can(auth.PermAdmin))
+	resetContext(ctx, oc.ownership.Begin(ctx.Request().Context(), requestUser(ctx), bypass))
+	ctx.Next()
+}
+
+func (oc *ownershipController) List() []*ownership.Rule {
+	return oc.ownership.List()
+}
+
+//设置域名的所有者，参数owner可以多个
+func (oc *ownershipController) Protect(ctx iris.Context) *ownership.Rule {
+	rule, err := oc.ownership.Protect(ctx.Params().Get("domain"), ctx.Request().URL.Query()["owner"], requestUser(ctx))
+	util.PanicIfError(err)
+	return rule
+}
+
+func (oc *ownershipController) Unprotect(ctx iris.Context) int {
+	util.PanicIfError(oc.ownership.Unprotect(ctx.Params().Get("domain")))
+	return iris.StatusNoContent
+}
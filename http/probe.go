@@ -0,0 +1,55 @@
+package http
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"time"
+)
+
+//检查存储是否可以访问的最长时间
+var probeTimeout = time.Second * 3
+
+//aginx自身的健康检查，不需要认证，提供给编排系统和负载均衡使用
+type probeController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+}
+
+//aginx进程存活
+func (pc *probeController) Healthz(ctx iris.Context) {
+	_, _ = ctx.JSON(map[string]string{"status": "ok"})
+}
+
+//可以处理请求：存储可以访问，nginx正在运行并且已经加载了配置
+func (pc *probeController) Readyz(ctx iris.Context) {
+	ready := true
+	checks := map[string]string{}
+	check := func(name string, err error) {
+		if err != nil {
+			ready = false
+			checks[name] = err.Error()
+		} else {
+			checks[name] = "ok"
+		}
+	}
+	check("storage", util.Async(probeTimeout, func() error {
+		_, err := pc.engine.Get(nginx.NGINX_CONF)
+		return err
+	}))
+	check("nginx", pc.process.Running())
+	if _, loaded := pc.process.Loaded(); loaded.IsZero() {
+		check("config", errors.New("nginx has not loaded the configuration"))
+	} else {
+		check("config", nil)
+	}
+
+	status := "ok"
+	if !ready {
+		status = "unavailable"
+		ctx.StatusCode(iris.StatusServiceUnavailable)
+	}
+	_, _ = ctx.JSON(map[string]interface{}{"status": status, "checks": checks})
+}
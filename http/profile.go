@@ -0,0 +1,45 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/storage"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type profileController struct {
+	profiles *storage.Profiles
+}
+
+type profilesResult struct {
+	Current  string   `json:"current,omitempty"`
+	Profiles []string `json:"profiles"`
+}
+
+func (pc *profileController) List() *profilesResult {
+	profiles, err := pc.profiles.List()
+	util.PanicIfError(err)
+	return &profilesResult{Current: pc.profiles.Current, Profiles: profiles}
+}
+
+//提升的环境，from默认为当前环境
+func (pc *profileController) fromTo(ctx iris.Context) (string, string) {
+	from, to := ctx.URLParamDefault("from", pc.profiles.Current), ctx.URLParam("to")
+	util.AssertTrue(from != "" && to != "", "the from and to profile is required")
+	return from, to
+}
+
+//预览提升环境时文件的变化
+func (pc *profileController) Preview(ctx iris.Context) []*storage.PromoteFile {
+	from, to := pc.fromTo(ctx)
+	changes, err := pc.profiles.Diff(from, to)
+	util.PanicIfError(err)
+	return changes
+}
+
+//使用from环境的配置替换to环境的配置，to环境的节点同步配置后重启nginx
+func (pc *profileController) Promote(ctx iris.Context) []*storage.PromoteFile {
+	from, to := pc.fromTo(ctx)
+	changes, err := pc.profiles.Promote(from, to)
+	util.PanicIfError(err)
+	return changes
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type rateLimitController struct {
+	process *nginx.Process
+}
+
+func (rc *rateLimitController) List(client *nginx.Client) []*nginx.RateLimitZone {
+	return client.RateLimits()
+}
+
+//设置限流，{zone, key, size, rate, domain, location, burst, nodelay}
+func (rc *rateLimitController) Set(ctx iris.Context, client *nginx.Client) int {
+	limit := new(nginx.RateLimit)
+	util.PanicIfError(ctx.ReadJSON(limit))
+	util.PanicIfError(client.SetRateLimit(limit))
+	return rc.store(client)
+}
+
+//没有domain参数时删除区域和全部使用此区域的limit_req
+func (rc *rateLimitController) Delete(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.DeleteRateLimit(ctx.Params().Get("zone"),
+		ctx.URLParam("domain"), ctx.URLParam("location")))
+	return rc.store(client)
+}
+
+func (rc *rateLimitController) store(client *nginx.Client) int {
+	util.PanicIfError(rc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(rc.process.Reload())
+	return iris.StatusNoContent
+}
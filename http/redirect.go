@@ -0,0 +1,59 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type redirectController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+}
+
+func (rc *redirectController) List() []*nginx.Redirect {
+	redirects, err := nginx.LoadRedirects(rc.engine)
+	util.PanicIfError(err)
+	return redirects
+}
+
+//批量添加跳转，[{domain, path, to, code}]，域名和路径相同的跳转被替换
+func (rc *redirectController) Add(ctx iris.Context, client *nginx.Client) []*nginx.Redirect {
+	adds := make([]*nginx.Redirect, 0)
+	util.PanicIfError(ctx.ReadJSON(&adds))
+	for _, redirect := range adds {
+		util.PanicIfError(redirect.Validate())
+	}
+	redirects := make([]*nginx.Redirect, 0)
+	for _, redirect := range rc.List() {
+		replaced := false
+		for _, add := range adds {
+			replaced = replaced || (add.Domain == redirect.Domain && add.Path == redirect.Path)
+		}
+		if !replaced {
+			redirects = append(redirects, redirect)
+		}
+	}
+	return rc.apply(client, append(redirects, adds...))
+}
+
+func (rc *redirectController) Delete(ctx iris.Context, client *nginx.Client) []*nginx.Redirect {
+	domain, path := ctx.URLParam("domain"), ctx.URLParam("path")
+	redirects := make([]*nginx.Redirect, 0)
+	for _, redirect := range rc.List() {
+		if redirect.Domain != domain || redirect.Path != path {
+			redirects = append(redirects, redirect)
+		}
+	}
+	return rc.apply(client, redirects)
+}
+
+func (rc *redirectController) apply(client *nginx.Client, redirects []*nginx.Redirect) []*nginx.Redirect {
+	util.PanicIfError(client.SetRedirects(redirects))
+	util.PanicIfError(rc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(rc.process.Reload())
+	util.PanicIfError(nginx.SaveRedirects(client.Engine, redirects))
+	return redirects
+}
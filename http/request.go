@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/ihaiker/aginx/audit"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	requestIdHeader = "X-Request-Id"
+	requestIdKey    = "request-id"
+)
+
+//客户端（例如：网关）传入的请求ID，不符合格式时重新生成
+var requestIdPattern = regexp.MustCompile(`^[a-zA-Z0-9._\-]{1,64}$`)
+
+func requestId(ctx iris.Context) string {
+	return ctx.Values().GetString(requestIdKey)
+}
+
+func newRequestId() string {
+	bs := make([]byte, 8)
+	_, _ = rand.Read(bs)
+	return hex.EncodeToString(bs)
+}
+
+//为每个请求分配ID，在响应头 X-Request-Id 中返回，请求结束后记录日志
+func requestHandler(ctx iris.Context) {
+	start := time.Now()
+	id := ctx.GetHeader(requestIdHeader)
+	if !requestIdPattern.MatchString(id) {
+		id = newRequestId()
+	}
+	ctx.Values().Set(requestIdKey, id)
+	ctx.Header(requestIdHeader, id)
+
+	defer func() {
+		status := ctx.GetStatusCode()
+		err := recover()
+		if err != nil {
+			status = iris.StatusInternalServerError
+		}
+		entry := logger.WithFields(logrus.Fields{
+			"request_id": id, "method": ctx.Method(), "path": ctx.Path(), "status": status,
+			"latency": time.Since(start).String(), "user": requestUser(ctx), "remote": ctx.RemoteAddr(),
+		})
+		if err != nil {
+			entry.WithField("error", fmt.Sprintf("%v", err)).Info("api request")
+			panic(err)
+		}
+		entry.Info("api request")
+	}()
+	ctx.Next()
+}
+
+//保存修改请求的信息（用户、审计记录、历史版本等）到请求的context中
+func resetContext(ctx iris.Context, requestCtx context.Context) {
+	ctx.ResetRequest(ctx.Request().WithContext(requestCtx))
+}
+
+//绑定请求context的存储，修改记录到此请求中
+func requestEngine(ctx iris.Context, engine plugins.StorageEngine) plugins.StorageEngine {
+	return plugins.WithContext(ctx.Request().Context(), engine)
+}
+
+//正在处理修改请求时重启nginx，记录请求ID
+func logReload(auditor *audit.Auditor) {
+	util.SubscribeEvent(func(event *util.Event) {
+		if event.Name != util.EventReload {
+			return
+		}
+		if ids := auditor.Running(); len(ids) > 0 {
+			entry := logger.WithField("request_id", strings.Join(ids, ","))
+			if event.Error != "" {
+				entry = entry.WithError(fmt.Errorf("%s", event.Error))
+			}
+			entry.Info(event.Message)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/kataras/iris/v12"
+	"strings"
+)
+
+func setETag(ctx iris.Context, revision string) {
+	ctx.Header("ETag", `"`+revision+`"`)
+}
+
+//请求头If-Match的版本检查，没有If-Match时返回nil（不检查）。*匹配任意存在的版本
+func ifMatch(ctx iris.Context) func(revision string) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+	return func(revision string) bool {
+		for _, value := range strings.Split(header, ",") {
+			value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+			if (value == "*" && revision != "") || strings.Trim(value, `"`) == revision {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//检查请求的配置版本，修改前调用
+func assertRevision(ctx iris.Context, client *nginx.Client) {
+	if matched := ifMatch(ctx); matched != nil && !matched(client.Revision()) {
+		panic(nginx.ErrConflict)
+	}
+}
+
+//配置的版本
+func configRevision(ctx iris.Context, client *nginx.Client) map[string]string {
+	setETag(ctx, client.Revision())
+	return map[string]string{"revision": client.Revision()}
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/rotate"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type rotateController struct {
+	rotator *rotate.Rotator
+}
+
+func (rc *rotateController) Policies() []string {
+	policies := make([]string, 0)
+	for _, policy := range rc.rotator.Policies() {
+		policies = append(policies, policy.String())
+	}
+	return policies
+}
+
+//替换日志切割策略，内容为策略数组：["/var/log/nginx/*.log?size=100M&keep=7&compress=true"]，
+//日志文件只能是启动参数中切割策略的目录或者nginx配置中日志目录中的文件
+func (rc *rotateController) SetPolicies(ctx iris.Context, client *nginx.Client) []string {
+	configs := make([]string, 0)
+	util.PanicIfError(ctx.ReadJSON(&configs))
+	policies, err := rotate.ParsePolicies(configs)
+	util.PanicIfError(err)
+	prefix, _, _ := nginx.GetInfo()
+	util.PanicIfError(rc.rotator.SetPolicies(policies, nginx.LogDirs(client.Configuration(), prefix)...))
+	return rc.Policies()
+}
+
+//立即切割全部日志文件
+func (rc *rotateController) Rotate() []string {
+	rotated, err := rc.rotator.Rotate(true)
+	util.PanicIfError(err)
+	return rotated
+}
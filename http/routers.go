@@ -2,30 +2,42 @@ package http
 
 import (
 	"fmt"
+	"github.com/ihaiker/aginx/access"
+	"github.com/ihaiker/aginx/approval"
+	"github.com/ihaiker/aginx/audit"
+	"github.com/ihaiker/aginx/auth"
+	"github.com/ihaiker/aginx/backup"
+	"github.com/ihaiker/aginx/cluster"
+	"github.com/ihaiker/aginx/geoip"
+	"github.com/ihaiker/aginx/health"
+	"github.com/ihaiker/aginx/history"
 	"github.com/ihaiker/aginx/lego"
 	"github.com/ihaiker/aginx/logs"
 	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/ownership"
 	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/rotate"
+	"github.com/ihaiker/aginx/site"
+	"github.com/ihaiker/aginx/storage"
 	"github.com/ihaiker/aginx/util"
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/context"
 	"github.com/kataras/iris/v12/hero"
-	"github.com/kataras/iris/v12/middleware/basicauth"
-	"strings"
-	"time"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var logger = logs.New("http")
 
-func Routers(email, auth string, process *nginx.Process, engine plugins.StorageEngine, manager *lego.Manager) func(*iris.Application) {
-	handlers := make([]context.Handler, 0)
-	if auth != "" {
-		authConfig := strings.SplitN(auth, ":", 2)
-		handlers = append(handlers, basicauth.New(basicauth.Config{
-			Users: map[string]string{authConfig[0]: authConfig[1]},
-			Realm: "Authorization Required", Expires: time.Duration(30) * time.Minute,
-		}))
-	}
+func Routers(email string, authenticator *auth.Auth, process *nginx.Process, engine plugins.StorageEngine, manager *lego.Manager,
+	auditor *audit.Auditor, histories *history.History, rotator *rotate.Rotator, conflicts *storage.Conflicts, scheduler *backup.Scheduler,
+	checker *health.Checker, bans *access.Bans, jails *access.Jails, geoUpdater *geoip.Updater, sites *site.Sites,
+	nodes *cluster.Nodes, approvals *approval.Approvals, owners *ownership.Ownership, profiles *storage.Profiles, pprof bool) func(*iris.Application) {
+
+	authCtl := &authController{auth: authenticator}
+	apiKeyCtl := &apiKeyController{auth: authenticator}
+	ownershipCtl := &ownershipController{auth: authenticator, ownership: owners}
+	oidcCtl := &oidcController{auth: authenticator}
+	handlers := []context.Handler{authCtl.Handler}
 
 	h := hero.New()
 	h.Register(
@@ -33,29 +45,192 @@ func Routers(email, auth string, process *nginx.Process, engine plugins.StorageE
 			return ctx.Request().URL.Query()["q"]
 		},
 		func(ctx iris.Context) *nginx.Client {
-			return nginx.MustClient(email, engine, manager, process)
+			return nginx.MustClient(email, requestEngine(ctx, engine), manager, process)
 		},
 		func(ctx iris.Context) []*nginx.Directive {
 			body, err := ctx.GetBody()
 			util.PanicIfError(err)
-			conf, err := nginx.ReaderReadable(engine, plugins.NewFile("", body))
+			conf, err := nginx.ReaderReadable(requestEngine(ctx, engine), plugins.NewFile("", body))
 			util.PanicIfError(err)
 			return conf.Body
 		},
 	)
 
-	fileCtrl := &fileController{engine: engine, process: process}
+	fileCtrl := &fileController{engine: plugins.Files(engine), process: process}
 	directive := &directiveController{process: process}
-	ssl := &sslController{email: email}
+	ssl := &sslController{email: email, process: process}
 	simpleCtl := &simpleController{}
+	auditCtl := &auditController{auditor: auditor}
+	watchCtl := &watchController{}
+	eventsCtl := newEventsController()
+	rotateCtl := &rotateController{rotator: rotator}
+	clusterCtl := &clusterController{nodes: nodes, process: process}
+	approvalCtl := &approvalController{approvals: approvals, process: process}
+	diffCtl := &diffController{engine: engine, process: process}
+	lintCtl := &lintController{engine: engine}
+	docsCtl := &docsController{}
+	backupCtl := &backupController{engine: engine, process: process, scheduler: scheduler}
+	upstreamCtl := &upstreamController{process: process, checker: checker}
+	serverCtl := &serverController{email: email, process: process}
+	streamCtl := &streamController{process: process}
+	splitCtl := &splitController{engine: engine, process: process}
+	rateLimitCtl := &rateLimitController{process: process}
+	accessCtl := &accessController{process: process, bans: bans, jails: jails}
+	securityCtl := &securityController{process: process}
+	wafCtl := &wafController{engine: engine, process: process}
+	geoCtl := &geoController{engine: engine, process: process, updater: geoUpdater}
+	htpasswdCtl := &htpasswdController{engine: engine, process: process}
+	siteCtl := &siteController{email: email, process: process, sites: sites}
+	redirectCtl := &redirectController{engine: engine, process: process}
+	tenantCtl := &tenantController{engine: engine, process: process}
+	profileCtl := &profileController{profiles: profiles}
+	conflictCtl := &conflictController{engine: plugins.Files(engine), conflicts: conflicts, process: process}
+	probeCtl := &probeController{engine: engine, process: process}
+	runtimeCtl := &runtimeController{}
+	swaggerCtl := &swaggerController{security: authenticator.Enabled()}
+	historyCtl := &historyController{history: histories, process: process, client: func(engine plugins.StorageEngine) (*nginx.Client, error) {
+		return nginx.NewClient(email, engine, manager, process)
+	}}
 
+	logReload(auditor)
 	manager.Expire(func(domain string) {
 		ssl.Renew(nginx.MustClient(email, engine, manager, process), domain)
 	})
 
 	return func(app *iris.Application) {
+		app.Use(requestHandler, metricsHandler, authCtl.Identify, auditCtl.Handler, historyCtl.Handler, approvalCtl.Handler, ownershipCtl.Handler)
+		swaggerCtl.app = app
+
+		//只需要认证，签发的角色在Token中检查
+		app.Post("/api/token", authCtl.Require(auth.PermRead), h.Handler(authCtl.Token))
+		//健康检查不需要认证
+		app.Get("/healthz", probeCtl.Healthz)
+		app.Get("/readyz", probeCtl.Readyz)
+		//管理页面不需要认证，页面中的api请求需要认证
+		app.Get("/ui", dashboard)
+		//OIDC登录不需要认证
+		app.Get("/api/oidc/login", oidcCtl.Login)
+		app.Get("/api/oidc/callback", oidcCtl.Callback)
+
 		api := app.Party("/api", handlers...)
 		{
+			api.Get("/keys", authCtl.Require(auth.PermAdmin), h.Handler(apiKeyCtl.List))
+			api.Post("/keys", authCtl.Require(auth.PermAdmin), h.Handler(apiKeyCtl.Create))
+			api.Delete("/keys/{id:string}", authCtl.Require(auth.PermAdmin), h.Handler(apiKeyCtl.Revoke))
+			api.Get("/owners", authCtl.Require(auth.PermAdmin), h.Handler(ownershipCtl.List))
+			api.Put("/owners/{domain:string}", authCtl.Require(auth.PermAdmin), h.Handler(ownershipCtl.Protect))
+			api.Delete("/owners/{domain:string}", authCtl.Require(auth.PermAdmin), h.Handler(ownershipCtl.Unprotect))
+			api.Get("/admin/runtime", authCtl.Require(auth.PermAdmin), h.Handler(runtimeCtl.Runtime))
+			api.Get("/swagger.json", swaggerCtl.JSON)
+			api.Get("/swagger", swaggerCtl.UI)
+			api.Get("/audit", authCtl.Require(auth.PermAdmin), h.Handler(auditCtl.Query))
+			api.Get("/backup", authCtl.Require(auth.PermAdmin), backupCtl.Backup)
+			api.Get("/backup/schedule", authCtl.Require(auth.PermAdmin), h.Handler(backupCtl.Schedule))
+			api.Put("/backup/schedule", authCtl.Require(auth.PermAdmin), h.Handler(backupCtl.SetSchedule))
+			api.Post("/backup/schedule", authCtl.Require(auth.PermAdmin), h.Handler(backupCtl.BackupNow))
+			api.Post("/restore", authCtl.Require(auth.PermAdmin), iris.LimitRequestBodySize(1024*1024*100), h.Handler(backupCtl.Restore))
+			api.Get("/watch", watchCtl.Watch)
+			api.Get("/events", eventsCtl.Events)
+			api.Get("/certs", h.Handler(ssl.List))
+			api.Get("/certs/expiry", h.Handler(ssl.Expiry))
+			api.Get("/changes", h.Handler(approvalCtl.List))
+			api.Get("/changes/{id:string}", h.Handler(approvalCtl.Get))
+			api.Post("/changes/{id:string}/approve", h.Handler(approvalCtl.Approve))
+			api.Post("/changes/{id:string}/reject", h.Handler(approvalCtl.Reject))
+			api.Get("/history", h.Handler(historyCtl.Versions))
+			api.Post("/rollback", h.Handler(historyCtl.Rollback))
+			api.Get("/files", h.Handler(fileCtrl.Tree))
+			api.Get("/files/graph", h.Handler(fileCtrl.Graph))
+			api.Get("/files/{file:path}", fileCtrl.Raw)
+			api.Put("/files/{file:path}", iris.LimitRequestBodySize(1024*1024*10), h.Handler(fileCtrl.PutRaw))
+			api.Get("/diff", h.Handler(diffCtl.Diff))
+			api.Post("/diff/reconcile", h.Handler(diffCtl.Reconcile))
+			api.Get("/lint", h.Handler(lintCtl.Lint))
+			api.Get("/analyze", h.Handler(lintCtl.Analyze))
+			api.Post("/format", h.Handler(lintCtl.Format))
+			api.Get("/resolve", h.Handler(lintCtl.Resolve))
+			api.Get("/docs/directives", h.Handler(docsCtl.Directives))
+			api.Get("/docs/directive/{name:string}", h.Handler(docsCtl.Directive))
+			api.Get("/conflicts", h.Handler(conflictCtl.List))
+			api.Post("/conflicts/{file:path}", h.Handler(conflictCtl.Resolve))
+			api.Get("/profiles", h.Handler(profileCtl.List))
+			api.Get("/profiles/promote", h.Handler(profileCtl.Preview))
+			api.Post("/profiles/promote", authCtl.Require(auth.PermAdmin), h.Handler(profileCtl.Promote))
+			api.Get("/tenant/files", h.Handler(tenantCtl.Files))
+			api.Get("/tenant/files/{file:string}", tenantCtl.Raw)
+			api.Put("/tenant/files/{file:string}", iris.LimitRequestBodySize(1024*1024*10), h.Handler(tenantCtl.Put))
+			api.Delete("/tenant/files/{file:string}", h.Handler(tenantCtl.Delete))
+			api.Get("/upstreams", h.Handler(upstreamCtl.List))
+			api.Get("/upstreams/{name:string}", h.Handler(upstreamCtl.Get))
+			api.Post("/upstreams/{name:string}", h.Handler(upstreamCtl.New))
+			api.Put("/upstreams/{name:string}", h.Handler(upstreamCtl.Modify))
+			api.Delete("/upstreams/{name:string}", h.Handler(upstreamCtl.Delete))
+			api.Get("/upstreams/{name:string}/health", h.Handler(upstreamCtl.Health))
+			api.Put("/upstreams/{name:string}/servers/{address:path}", h.Handler(upstreamCtl.SetServer))
+			api.Delete("/upstreams/{name:string}/servers/{address:path}", h.Handler(upstreamCtl.RemoveServer))
+			api.Get("/servers", h.Handler(serverCtl.List))
+			api.Post("/servers", h.Handler(serverCtl.New))
+			api.Get("/servers/{domain:string}", h.Handler(serverCtl.Get))
+			api.Delete("/servers/{domain:string}", h.Handler(serverCtl.Delete))
+			api.Get("/streams", h.Handler(streamCtl.List))
+			api.Post("/streams", h.Handler(streamCtl.New))
+			api.Delete("/streams/{listen:string}", h.Handler(streamCtl.Delete))
+			api.Get("/splits", h.Handler(splitCtl.List))
+			api.Get("/splits/{name:string}", h.Handler(splitCtl.Get))
+			api.Put("/splits/{name:string}", h.Handler(splitCtl.Set))
+			api.Post("/splits/{name:string}/promote", h.Handler(splitCtl.Promote))
+			api.Get("/ratelimits", h.Handler(rateLimitCtl.List))
+			api.Post("/ratelimits", h.Handler(rateLimitCtl.Set))
+			api.Delete("/ratelimits/{zone:string}", h.Handler(rateLimitCtl.Delete))
+			api.Get("/access", h.Handler(accessCtl.List))
+			api.Post("/access", h.Handler(accessCtl.Add))
+			api.Delete("/access", h.Handler(accessCtl.Delete))
+			api.Get("/access/bans", h.Handler(accessCtl.Bans))
+			api.Delete("/access/bans", h.Handler(accessCtl.Lift))
+			api.Get("/access/jails", h.Handler(accessCtl.Jails))
+			api.Get("/security-headers", h.Handler(securityCtl.List))
+			api.Get("/security-headers/profiles", h.Handler(securityCtl.Profiles))
+			api.Put("/security-headers", h.Handler(securityCtl.Set))
+			api.Delete("/security-headers", h.Handler(securityCtl.Delete))
+			api.Get("/waf", h.Handler(wafCtl.List))
+			api.Put("/waf/{domain:string}", h.Handler(wafCtl.Set))
+			api.Delete("/waf/{domain:string}", h.Handler(wafCtl.Delete))
+			api.Get("/waf/rules", h.Handler(wafCtl.Rules))
+			api.Get("/waf/rules/{name:string}", wafCtl.Rule)
+			api.Put("/waf/rules/{name:string}", iris.LimitRequestBodySize(1024*1024*10), h.Handler(wafCtl.PutRule))
+			api.Delete("/waf/rules/{name:string}", h.Handler(wafCtl.DeleteRule))
+			api.Get("/geoip", h.Handler(geoCtl.List))
+			api.Get("/geoip/database", h.Handler(geoCtl.Database))
+			api.Post("/geoip/database", h.Handler(geoCtl.Update))
+			api.Get("/geoip/{domain:string}", h.Handler(geoCtl.Get))
+			api.Put("/geoip/{domain:string}", h.Handler(geoCtl.Set))
+			api.Delete("/geoip/{domain:string}", h.Handler(geoCtl.Delete))
+			api.Get("/htpasswd", h.Handler(htpasswdCtl.List))
+			api.Delete("/htpasswd/{name:string}", h.Handler(htpasswdCtl.Delete))
+			api.Put("/htpasswd/{name:string}/users/{user:string}", h.Handler(htpasswdCtl.SetUser))
+			api.Delete("/htpasswd/{name:string}/users/{user:string}", h.Handler(htpasswdCtl.DeleteUser))
+			api.Put("/htpasswd/{name:string}/locations", h.Handler(htpasswdCtl.Attach))
+			api.Delete("/htpasswd/{name:string}/locations", h.Handler(htpasswdCtl.Detach))
+			api.Get("/sites", h.Handler(siteCtl.List))
+			api.Get("/sites/{domain:string}", h.Handler(siteCtl.Get))
+			api.Post("/sites/{domain:string}", iris.LimitRequestBodySize(1024*1024*100), h.Handler(siteCtl.Deploy))
+			api.Delete("/sites/{domain:string}", h.Handler(siteCtl.Delete))
+			api.Put("/sites/{domain:string}/rollback", h.Handler(siteCtl.Rollback))
+			api.Get("/redirects", h.Handler(redirectCtl.List))
+			api.Post("/redirects", h.Handler(redirectCtl.Add))
+			api.Delete("/redirects", h.Handler(redirectCtl.Delete))
+			api.Get("/nginx/info", h.Handler(directive.info))
+			api.Get("/nginx/status", h.Handler(directive.status))
+			api.Get("/cluster", h.Handler(clusterCtl.Info))
+			api.Get("/cluster/nodes", h.Handler(clusterCtl.Nodes))
+			api.Post("/cluster/reload", h.Handler(clusterCtl.Reload))
+			api.Get("/logs/rotate", h.Handler(rotateCtl.Policies))
+			api.Put("/logs/rotate", authCtl.Require(auth.PermAdmin), h.Handler(rotateCtl.SetPolicies))
+			api.Post("/logs/rotate", authCtl.Require(auth.PermAdmin), h.Handler(rotateCtl.Rotate))
+			api.Post("/validate", h.Handler(directive.validate))
+			api.Post("/sandbox", h.Handler(directive.sandbox))
+			api.Post("/batch", h.Handler(directive.batch))
+			api.Get("/revision", h.Handler(configRevision))
 			api.Get("", h.Handler(directive.queryDirective))
 			api.Put("", h.Handler(directive.addDirective))
 			api.Delete("", h.Handler(directive.deleteDirective))
@@ -78,16 +253,26 @@ func Routers(email, auth string, process *nginx.Process, engine plugins.StorageE
 		}
 
 		limit := iris.LimitRequestBodySize(1024 * 1024 * 10)
-		app.Post("/file", limit, h.Handler(fileCtrl.New))
-		app.Delete("/file", h.Handler(fileCtrl.Remove))
-		app.Get("/file", h.Handler(fileCtrl.Search))
+		app.Post("/file", authCtl.Handler, limit, h.Handler(fileCtrl.New))
+		app.Delete("/file", authCtl.Handler, h.Handler(fileCtrl.Remove))
+		app.Get("/file", authCtl.Handler, h.Handler(fileCtrl.Search))
 
-		sslRouter := app.Party("/ssl", handlers...)
+		sslRouter := app.Party("/ssl", authCtl.Require(auth.PermCert))
 		{
 			sslRouter.Put("/{domain:string}", h.Handler(ssl.New))
 			sslRouter.Post("/{domain:string}", h.Handler(ssl.Renew))
 		}
+		manualRouter := app.Party("/api/certs/manual", authCtl.Require(auth.PermCert))
+		{
+			manualRouter.Post("", h.Handler(ssl.Manual))
+			manualRouter.Delete("/{domain:string}", h.Handler(ssl.RemoveManual))
+		}
+		app.Post("/api/certs/self-signed/{domain:string}", authCtl.Require(auth.PermCert), h.Handler(ssl.SelfSigned))
 
-		app.Any("/reload", h.Handler(directive.reload))
+		app.Get("/metrics", authCtl.Handler, iris.FromStd(promhttp.Handler()))
+		if pprof {
+			pprofRouters(app, authCtl.Require(auth.PermAdmin))
+		}
+		app.Any("/reload", authCtl.Require(auth.PermWrite), h.Handler(directive.reload))
 	}
 }
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/metrics"
+	"github.com/kataras/iris/v12"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+var startedAt = time.Now()
+
+type runtimeMemory struct {
+	Alloc        uint64    `json:"alloc"`
+	TotalAlloc   uint64    `json:"totalAlloc"`
+	Sys          uint64    `json:"sys"`
+	HeapAlloc    uint64    `json:"heapAlloc"`
+	HeapInuse    uint64    `json:"heapInuse"`
+	HeapObjects  uint64    `json:"heapObjects"`
+	NumGC        uint32    `json:"numGC"`
+	PauseTotalNs uint64    `json:"pauseTotalNs"`
+	LastGC       time.Time `json:"lastGC"`
+}
+
+type runtimeInfo struct {
+	GoVersion  string                         `json:"goVersion"`
+	CPUs       int                            `json:"cpus"`
+	Goroutines int                            `json:"goroutines"`
+	Uptime     string                         `json:"uptime"`
+	Memory     runtimeMemory                  `json:"memory"`
+	Storage    map[string]metrics.StorageStat `json:"storage"`
+}
+
+//aginx的运行状态，排查内存增长等问题
+type runtimeController struct{}
+
+func (rc *runtimeController) Runtime() *runtimeInfo {
+	stats := new(runtime.MemStats)
+	runtime.ReadMemStats(stats)
+	return &runtimeInfo{
+		GoVersion: runtime.Version(), CPUs: runtime.NumCPU(), Goroutines: runtime.NumGoroutine(),
+		Uptime: time.Since(startedAt).Round(time.Second).String(),
+		Memory: runtimeMemory{
+			Alloc: stats.Alloc, TotalAlloc: stats.TotalAlloc, Sys: stats.Sys,
+			HeapAlloc: stats.HeapAlloc, HeapInuse: stats.HeapInuse, HeapObjects: stats.HeapObjects,
+			NumGC: stats.NumGC, PauseTotalNs: stats.PauseTotalNs, LastGC: time.Unix(0, int64(stats.LastGC)),
+		},
+		Storage: metrics.StorageStats(),
+	}
+}
+
+//注册 /debug/pprof，需要管理员权限
+func pprofRouters(app *iris.Application, handlers ...iris.Handler) {
+	debug := app.Party("/debug/pprof", handlers...)
+	{
+		debug.Get("/", iris.FromStd(pprof.Index))
+		debug.Get("/cmdline", iris.FromStd(pprof.Cmdline))
+		debug.Get("/profile", iris.FromStd(pprof.Profile))
+		debug.Get("/symbol", iris.FromStd(pprof.Symbol))
+		debug.Post("/symbol", iris.FromStd(pprof.Symbol))
+		debug.Get("/trace", iris.FromStd(pprof.Trace))
+		debug.Get("/{name:string}", iris.FromStd(pprof.Index))
+	}
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type securityController struct {
+	process *nginx.Process
+}
+
+func (sc *securityController) List(client *nginx.Client) []*nginx.SecurityHeaders {
+	return client.SecurityHeaders()
+}
+
+func (sc *securityController) Profiles() map[string]map[string]string {
+	return nginx.SecurityProfiles
+}
+
+//设置安全响应头，{domain, profile, headers}，domain为空时作用于全部http
+func (sc *securityController) Set(ctx iris.Context, client *nginx.Client) int {
+	headers := new(nginx.SecurityHeaders)
+	util.PanicIfError(ctx.ReadJSON(headers))
+	util.PanicIfError(client.SetSecurityHeaders(headers))
+	return sc.store(client)
+}
+
+func (sc *securityController) Delete(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.RemoveSecurityHeaders(ctx.URLParam("domain")))
+	return sc.store(client)
+}
+
+func (sc *securityController) store(client *nginx.Client) int {
+	util.PanicIfError(sc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(sc.process.Reload())
+	return iris.StatusNoContent
+}
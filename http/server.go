@@ -0,0 +1,43 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type serverController struct {
+	email   string
+	process *nginx.Process
+}
+
+func (sc *serverController) List(client *nginx.Client) []*nginx.Server {
+	return client.Servers()
+}
+
+func (sc *serverController) Get(ctx iris.Context, client *nginx.Client) []*nginx.Server {
+	servers, err := client.GetServers(ctx.Params().Get("domain"))
+	util.PanicIfError(err)
+	return servers
+}
+
+//创建server，{domains, listen, proxy, root, ssl, grpc, websocket, http2, http3, security}，ssl=true时为每个域名申请证书
+func (sc *serverController) New(ctx iris.Context, client *nginx.Client) int {
+	server := new(nginx.Server)
+	util.PanicIfError(ctx.ReadJSON(server))
+	util.PanicIfError(client.NewServer(server, ctx.URLParamDefault("email", sc.email)))
+	return sc.store(client)
+}
+
+//从server_name中删除域名，没有其他域名的server将被删除
+func (sc *serverController) Delete(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.DeleteServer(ctx.Params().Get("domain")))
+	return sc.store(client)
+}
+
+func (sc *serverController) store(client *nginx.Client) int {
+	util.PanicIfError(sc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(sc.process.Reload())
+	return iris.StatusNoContent
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/site"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"io"
+	"strings"
+)
+
+type siteController struct {
+	email   string
+	process *nginx.Process
+	sites   *site.Sites //没有使用 --site-root 时为nil
+}
+
+func (sc *siteController) enabled() {
+	util.AssertTrue(sc.sites != nil, site.ErrNotEnabled.Error())
+}
+
+func (sc *siteController) List() []*site.Site {
+	sc.enabled()
+	sites, err := sc.sites.List()
+	util.PanicIfError(err)
+	return sites
+}
+
+func (sc *siteController) Get(ctx iris.Context) *site.Site {
+	sc.enabled()
+	s, err := sc.sites.Get(ctx.Params().Get("domain"))
+	util.PanicIfError(err)
+	return s
+}
+
+//上传的压缩包，表单文件file或者请求内容
+func (sc *siteController) archive(ctx iris.Context) []byte {
+	if !strings.HasPrefix(ctx.GetHeader("Content-Type"), "multipart/") {
+		content, err := ctx.GetBody()
+		util.PanicIfError(err)
+		return content
+	}
+	file, _, err := ctx.FormFile("file")
+	util.PanicIfError(err)
+	defer func() { _ = file.Close() }()
+	out := bytes.NewBuffer(make([]byte, 0))
+	_, err = io.Copy(out, file)
+	util.PanicIfError(err)
+	return out.Bytes()
+}
+
+//发布新版本，域名没有server时创建root指向站点的server，ssl=true时申请证书
+func (sc *siteController) Deploy(ctx iris.Context, client *nginx.Client) *site.Release {
+	sc.enabled()
+	domain := ctx.Params().Get("domain")
+	release, err := sc.sites.Deploy(domain, sc.archive(ctx))
+	util.PanicIfError(err)
+	if _, err = client.GetServers(domain); err == nil {
+		return release
+	}
+	server := &nginx.Server{Domains: []string{domain}, Root: sc.sites.Root(domain), SSL: ctx.URLParam("ssl") == "true"}
+	util.PanicIfError(client.NewServer(server, ctx.URLParamDefault("email", sc.email)))
+	sc.store(client)
+	return release
+}
+
+//切换链接到指定版本，version为空时回滚到上一个版本，不需要重启nginx
+func (sc *siteController) Rollback(ctx iris.Context) *site.Release {
+	sc.enabled()
+	release, err := sc.sites.Rollback(ctx.Params().Get("domain"), ctx.URLParam("version"))
+	util.PanicIfError(err)
+	return release
+}
+
+//删除站点的全部版本，root指向站点的server同时删除
+func (sc *siteController) Delete(ctx iris.Context, client *nginx.Client) int {
+	sc.enabled()
+	domain := ctx.Params().Get("domain")
+	if servers, err := client.GetServers(domain); err == nil {
+		for _, server := range servers {
+			if server.Root == sc.sites.Root(domain) {
+				util.PanicIfError(client.DeleteServer(domain))
+				sc.store(client)
+				break
+			}
+		}
+	}
+	util.PanicIfError(sc.sites.Delete(domain))
+	return iris.StatusNoContent
+}
+
+func (sc *siteController) store(client *nginx.Client) {
+	util.PanicIfError(sc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(sc.process.Reload())
+}
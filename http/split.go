@@ -0,0 +1,55 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type splitController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+}
+
+func (sc *splitController) List() []*nginx.Split {
+	splits, err := nginx.LoadSplits(sc.engine)
+	util.PanicIfError(err)
+	return splits
+}
+
+func (sc *splitController) Get(ctx iris.Context) *nginx.Split {
+	split, err := nginx.LoadSplit(requestEngine(ctx, sc.engine), ctx.Params().Get("name"))
+	util.PanicIfError(err)
+	return split
+}
+
+//创建或者修改灰度，{domain, location, stable, canary, weight}
+func (sc *splitController) Set(ctx iris.Context, client *nginx.Client) *nginx.Split {
+	engine := requestEngine(ctx, sc.engine)
+	split := new(nginx.Split)
+	util.PanicIfError(ctx.ReadJSON(split))
+	split.Name = ctx.Params().Get("name")
+	_, err := nginx.LoadSplit(engine, split.Name)
+	util.PanicIfError(client.SetSplit(split, err == nil))
+	sc.store(client)
+	util.PanicIfError(nginx.SaveSplit(engine, split))
+	return split
+}
+
+//全部流量切换到 to=canary（默认）或者 to=stable，结束灰度
+func (sc *splitController) Promote(ctx iris.Context, client *nginx.Client) int {
+	engine := requestEngine(ctx, sc.engine)
+	split, err := nginx.LoadSplit(engine, ctx.Params().Get("name"))
+	util.PanicIfError(err)
+	util.PanicIfError(client.PromoteSplit(split, ctx.URLParamDefault("to", nginx.SplitCanary)))
+	sc.store(client)
+	util.PanicIfError(nginx.RemoveSplit(engine, split))
+	return iris.StatusNoContent
+}
+
+func (sc *splitController) store(client *nginx.Client) {
+	util.PanicIfError(sc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(sc.process.Reload())
+}
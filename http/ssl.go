@@ -1,25 +1,105 @@
 package http
 
 import (
+	"fmt"
 	"github.com/ihaiker/aginx/lego"
 	"github.com/ihaiker/aginx/nginx"
 	"github.com/ihaiker/aginx/util"
 	"github.com/kataras/iris/v12"
+	"time"
 )
 
 type sslController struct {
-	email string
+	email   string
+	process *nginx.Process
+}
+
+//证书修改了配置（泛域名证书替换server使用的证书等）时测试并保存配置，reload为true或者配置修改后重新加载nginx
+func (self *sslController) store(api *nginx.Client, reload bool) {
+	if len(api.Changes()) > 0 {
+		util.PanicIfError(self.process.Test(api.Configuration()))
+		util.PanicIfError(api.Store())
+		reload = true
+	}
+	if reload {
+		util.PanicIfError(self.process.Reload())
+	}
 }
 
 func (self *sslController) New(ctx iris.Context, api *nginx.Client, domain string) *lego.StoreFile {
 	email := ctx.URLParamDefault("email", self.email)
-	return api.NewCertificate(email, domain)
+	file := api.NewCertificate(email, domain)
+	self.store(api, false)
+	return file
 }
 
 func (self *sslController) Renew(api *nginx.Client, domain string) *lego.StoreFile {
-	cert, has := api.Lego.CertificateStorage.Get(domain)
-	if !has {
-		util.PanicIfError(nginx.ErrNotFound)
+	defer func() {
+		if e := recover(); e != nil {
+			util.PublishEvent(util.EventCertRenewal, domain, fmt.Errorf("%v", e), nil)
+			panic(e)
+		}
+		util.PublishEvent(util.EventCertRenewal, domain, nil, nil)
+	}()
+	file := api.RenewCertificate(domain)
+	//证书文件路径不变，重新加载nginx使用新的证书
+	self.store(api, true)
+	return file
+}
+
+type certificateInfo struct {
+	Domain string          `json:"domain"`
+	Email  string          `json:"email"`
+	Expire time.Time       `json:"expire"`
+	Days   int             `json:"days"` //距离过期的天数
+	Files  *lego.StoreFile `json:"files"`
+}
+
+//全部证书和过期时间
+func (self *sslController) List(api *nginx.Client) []*certificateInfo {
+	certs := make([]*certificateInfo, 0)
+	for _, cert := range api.Lego.CertificateStorage.List() {
+		certs = append(certs, &certificateInfo{
+			Domain: cert.Domain, Email: cert.Email,
+			Expire: cert.ExpireTime, Days: cert.Days(), Files: cert.GetStoreFile(),
+		})
 	}
-	return api.NewCertificate(cert.Email, cert.Domain)
+	return certs
+}
+
+type manualCertificate struct {
+	Domain      string `json:"domain"`
+	Certificate string `json:"certificate"`
+	PrivateKey  string `json:"privateKey"`
+}
+
+//全部证书距离过期的天数，按照过期时间排序
+func (self *sslController) Expiry(api *nginx.Client) []*lego.Expiry {
+	return api.Lego.Expiries()
+}
+
+//上传或者替换证书
+func (self *sslController) Manual(ctx iris.Context, api *nginx.Client) *lego.StoreFile {
+	manual := new(manualCertificate)
+	util.PanicIfError(ctx.ReadJSON(manual))
+	util.AssertTrue(manual.Certificate != "", "the certificate is empty")
+	util.AssertTrue(manual.PrivateKey != "", "the private key is empty")
+	file := api.ManualCertificate(manual.Domain, []byte(manual.Certificate), []byte(manual.PrivateKey))
+	self.store(api, true)
+	return file
+}
+
+//生成自签名证书，days为证书有效期，默认365天
+func (self *sslController) SelfSigned(ctx iris.Context, api *nginx.Client, domain string) *lego.StoreFile {
+	days := ctx.URLParamIntDefault("days", 365)
+	util.AssertTrue(days > 0, "the days must be greater than 0")
+	file := api.SelfSignedCertificate(domain, time.Hour*24*time.Duration(days))
+	self.store(api, true)
+	return file
+}
+
+//删除上传的证书
+func (self *sslController) RemoveManual(api *nginx.Client, domain string) int {
+	util.PanicIfError(api.RemoveCertificate(domain))
+	return iris.StatusNoContent
 }
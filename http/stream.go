@@ -0,0 +1,35 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type streamController struct {
+	process *nginx.Process
+}
+
+func (sc *streamController) List(client *nginx.Client) []*nginx.Stream {
+	return client.Streams()
+}
+
+//创建TCP/UDP代理，{listen, udp, proxy, load_balance, servers, timeout}
+func (sc *streamController) New(ctx iris.Context, client *nginx.Client) int {
+	stream := new(nginx.Stream)
+	util.PanicIfError(ctx.ReadJSON(stream))
+	util.PanicIfError(client.NewStream(stream))
+	return sc.store(client)
+}
+
+func (sc *streamController) Delete(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.DeleteStream(ctx.Params().Get("listen"), ctx.URLParam("udp") == "true"))
+	return sc.store(client)
+}
+
+func (sc *streamController) store(client *nginx.Client) int {
+	util.PanicIfError(sc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(sc.process.Reload())
+	return iris.StatusNoContent
+}
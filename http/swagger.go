@@ -0,0 +1,291 @@
+package http
+
+import (
+	"github.com/kataras/iris/v12"
+	"regexp"
+	"strings"
+)
+
+type paramDoc struct {
+	name, in, description string
+	array, required       bool
+}
+
+type operationDoc struct {
+	summary     string
+	params      []paramDoc
+	contentType string //请求内容类型
+	response    string //返回内容类型，空表示 204
+}
+
+var (
+	queryParam = paramDoc{name: "q", in: "query", description: "查询条件，可以多个", array: true}
+	matchParam = paramDoc{name: "If-Match", in: "header", description: "查询时返回的版本(ETag)，版本不同时返回409"}
+	pathParam  = regexp.MustCompile(`\{(\w+)(:[^}]*)?\}`)
+	docMethods = []string{iris.MethodGet, iris.MethodPost, iris.MethodPut, iris.MethodDelete}
+)
+
+//接口说明，没有说明的路由也会出现在文档中
+var operationDocs = map[string]operationDoc{
+	"GET /api":                                       {summary: "查询配置", params: []paramDoc{queryParam}, response: "application/json"},
+	"PUT /api":                                       {summary: "添加配置", params: []paramDoc{queryParam, matchParam}, contentType: "text/plain"},
+	"DELETE /api":                                    {summary: "删除配置", params: []paramDoc{queryParam, matchParam}},
+	"POST /api":                                      {summary: "修改配置", params: []paramDoc{queryParam, matchParam}, contentType: "text/plain"},
+	"POST /api/batch":                                {summary: "批量修改，全部成功后才会保存", params: []paramDoc{matchParam}, contentType: "application/json"},
+	"GET /api/revision":                              {summary: "配置的版本，修改时使用If-Match检查", response: "application/json"},
+	"POST /api/validate":                             {summary: "测试修改后的配置，不会保存", params: []paramDoc{queryParam, {name: "action", in: "query", description: "add, delete, modify"}}, contentType: "text/plain", response: "application/json"},
+	"GET /api/audit":                                 {summary: "查询审计记录", params: []paramDoc{{name: "user", in: "query"}, {name: "file", in: "query"}, {name: "since", in: "query", description: "RFC3339"}, {name: "limit", in: "query"}}, response: "application/json"},
+	"GET /api/backup":                                {summary: "下载全部配置文件和证书的tar.gz备份", response: "application/gzip"},
+	"GET /api/backup/schedule":                       {summary: "查询定时备份配置和上一次备份的结果", response: "application/json"},
+	"PUT /api/backup/schedule":                       {summary: "修改定时备份配置，{target, interval, keep}", contentType: "application/json", response: "application/json"},
+	"POST /api/backup/schedule":                      {summary: "立即备份到备份位置", response: "application/json"},
+	"POST /api/restore":                              {summary: "使用tar.gz备份恢复，测试配置通过后恢复并重启nginx", params: []paramDoc{{name: "clean", in: "query", description: "true: 删除备份中没有的文件"}}, contentType: "application/gzip", response: "application/json"},
+	"GET /api/certs":                                 {summary: "查询全部证书和过期时间", response: "application/json"},
+	"GET /api/certs/expiry":                          {summary: "查询全部证书距离过期的天数", response: "application/json"},
+	"POST /api/certs/manual":                         {summary: "上传或者替换证书，{domain, certificate, privateKey}", contentType: "application/json", response: "application/json"},
+	"DELETE /api/certs/manual/{domain}":              {summary: "删除上传的证书"},
+	"POST /api/certs/self-signed/{domain}":           {summary: "生成自签名证书", params: []paramDoc{{name: "days", in: "query", description: "证书有效期，默认365天"}}, response: "application/json"},
+	"GET /api/files":                                 {summary: "查询配置文件的include树", response: "application/json"},
+	"GET /api/files/{file}":                          {summary: "读取文件原始内容", response: "text/plain"},
+	"PUT /api/files/{file}":                          {summary: "替换整个文件，配置文件测试通过后保存并重启nginx", params: []paramDoc{matchParam}, contentType: "text/plain"},
+	"GET /api/diff":                                  {summary: "比较存储中的配置、本地文件和nginx加载的配置", response: "application/json"},
+	"POST /api/diff/reconcile":                       {summary: "同步存储和本地文件的差异", params: []paramDoc{{name: "source", in: "query", description: "storage(默认): 存储覆盖本地, local: 本地覆盖存储"}}, response: "application/json"},
+	"GET /api/lint":                                  {summary: "检查配置中nginx -t不能发现的问题：server_name重复，proxy_pass结尾的/，ssl_protocols，指令使用的context", params: []paramDoc{{name: "skip", in: "query", description: "忽略的规则，可以多个"}}, response: "application/json"},
+	"GET /api/analyze":                               {summary: "对照最佳实践检查配置并打分(0-100)：gzip，upstream keepalive，ssl_ciphers，client_max_body_size，worker_processes，worker_connections，server_tokens", response: "application/json"},
+	"GET /api/docs/directives":                       {summary: "全部有文档的nginx指令名称", response: "application/json"},
+	"GET /api/docs/directive/{name}":                 {summary: "nginx指令的文档：模块、语法、默认值、可以使用的context和nginx.org的文档地址", response: "application/json"},
+	"POST /api/format":                               {summary: "格式化配置：4个空格缩进，合并连续空行，对齐map、geo、types中的值，保留注释", params: []paramDoc{{name: "file", in: "query", description: "没有请求内容时格式化存储中的此文件"}, {name: "check", in: "query", description: "true: 只返回和格式化结果的差异"}}, contentType: "text/plain", response: "text/plain"},
+	"GET /api/files/graph":                           {summary: "配置文件的include关系图：文件节点（包括文件中的server和upstream）和include关系", response: "application/json"},
+	"GET /api/resolve":                               {summary: "模拟nginx处理请求，说明匹配的server、location和map变量的值", params: []paramDoc{{name: "host", in: "query", description: "请求的Host"}, {name: "uri", in: "query", description: "请求地址，包括查询参数，默认 /"}, {name: "port", in: "query", description: "端口，默认80，scheme为https时默认443"}, {name: "scheme", in: "query", description: "http或者https"}, {name: "method", in: "query", description: "请求方法，默认GET"}, {name: "remote", in: "query", description: "客户端地址"}, {name: "header", in: "query", description: "请求头，格式：Name: value，可以多个"}}, response: "application/json"},
+	"POST /api/sandbox":                              {summary: "在临时目录中使用随机端口启动nginx运行修改后的配置，发送探测请求并返回状态码，{operations, probes}，不会保存配置", contentType: "application/json", response: "application/json"},
+	"GET /api/tenant/files":                          {summary: "租户的配置文件(tenants/<tenant>/)和文件中的server、upstream", params: []paramDoc{{name: "tenant", in: "query", description: "租户名称，租户用户使用绑定的租户"}}, response: "application/json"},
+	"GET /api/tenant/files/{file}":                   {summary: "读取租户配置文件的内容", params: []paramDoc{{name: "tenant", in: "query", description: "租户名称，租户用户使用绑定的租户"}}, response: "text/plain"},
+	"PUT /api/tenant/files/{file}":                   {summary: "替换或者新建租户的配置文件，只能使用server、upstream、map、geo、split_clients，server_name、upstream和map的变量不能和其他租户重复，测试通过后保存并重启nginx", params: []paramDoc{{name: "tenant", in: "query", description: "租户名称，租户用户使用绑定的租户"}}, contentType: "text/plain"},
+	"DELETE /api/tenant/files/{file}":                {summary: "删除租户的配置文件", params: []paramDoc{{name: "tenant", in: "query", description: "租户名称，租户用户使用绑定的租户"}}},
+	"GET /api/profiles":                              {summary: "查询存储中的环境(profiles/<profile>/)和当前节点使用的环境", response: "application/json"},
+	"GET /api/profiles/promote":                      {summary: "预览from环境的配置提升到to环境时文件的变化", params: []paramDoc{{name: "from", in: "query", description: "默认为当前节点的环境"}, {name: "to", in: "query"}}, response: "application/json"},
+	"POST /api/profiles/promote":                     {summary: "使用from环境的配置替换to环境的配置，to环境的节点同步后重启nginx", params: []paramDoc{{name: "from", in: "query", description: "默认为当前节点的环境"}, {name: "to", in: "query"}}, response: "application/json"},
+	"GET /api/conflicts":                             {summary: "查询本地和存储中同时修改的冲突文件", response: "application/json"},
+	"POST /api/conflicts/{file}":                     {summary: "处理冲突，测试配置通过后同步并重启nginx", params: []paramDoc{{name: "use", in: "query", description: "local: 使用本地文件, storage: 使用存储中的文件，为空时使用请求内容"}}, contentType: "text/plain", response: "application/json"},
+	"GET /api/upstreams":                             {summary: "查询全部upstream", response: "application/json"},
+	"GET /api/upstreams/{name}":                      {summary: "查询upstream", response: "application/json"},
+	"POST /api/upstreams/{name}":                     {summary: "创建upstream，{load_balance, servers, stream}", contentType: "application/json"},
+	"PUT /api/upstreams/{name}":                      {summary: "替换upstream的负载均衡方式和全部server", contentType: "application/json"},
+	"DELETE /api/upstreams/{name}":                   {summary: "删除upstream"},
+	"GET /api/upstreams/{name}/health":               {summary: "查询upstream中server的健康检查状态，需要开启 --health-check", response: "application/json"},
+	"PUT /api/upstreams/{name}/servers/{address}":    {summary: "添加或者修改server，{weight, max_fails, fail_timeout, backup, down}，down=true摘除流量", contentType: "application/json"},
+	"DELETE /api/upstreams/{name}/servers/{address}": {summary: "删除upstream中的server"},
+	"GET /api/servers":                               {summary: "查询全部server", response: "application/json"},
+	"POST /api/servers":                              {summary: "创建server，{domains, listen, proxy, root, ssl, grpc, websocket, http2, http3, security}，ssl=true时为每个域名申请证书", params: []paramDoc{{name: "email", in: "query", description: "申请证书使用的邮箱"}}, contentType: "application/json"},
+	"GET /api/servers/{domain}":                      {summary: "查询server_name包含域名的server", response: "application/json"},
+	"DELETE /api/servers/{domain}":                   {summary: "从server_name中删除域名，没有其他域名的server将被删除"},
+	"GET /api/streams":                               {summary: "查询stream中全部的server", response: "application/json"},
+	"POST /api/streams":                              {summary: "创建TCP/UDP代理，{listen, udp, proxy, load_balance, servers, timeout}", contentType: "application/json"},
+	"DELETE /api/streams/{listen}":                   {summary: "删除TCP/UDP代理和生成的upstream", params: []paramDoc{{name: "udp", in: "query", description: "删除UDP代理"}}},
+	"GET /api/splits":                                {summary: "查询全部灰度发布", response: "application/json"},
+	"GET /api/splits/{name}":                         {summary: "查询灰度发布", response: "application/json"},
+	"PUT /api/splits/{name}":                         {summary: "创建或者修改灰度发布，{domain, location, stable, canary, weight}，weight为canary流量的百分比", contentType: "application/json", response: "application/json"},
+	"POST /api/splits/{name}/promote":                {summary: "全部流量切换到canary或者stable，结束灰度发布", params: []paramDoc{{name: "to", in: "query", description: "canary(默认) 或者 stable"}}},
+	"GET /api/ratelimits":                            {summary: "查询全部限流区域(limit_req_zone)和使用的位置(limit_req)", response: "application/json"},
+	"POST /api/ratelimits":                           {summary: "设置限流，{zone, key, size, rate, domain, location, burst, nodelay}，domain为空时作用于全部http，区域不存在时创建", contentType: "application/json"},
+	"DELETE /api/ratelimits/{zone}":                  {summary: "删除限流，没有domain参数时删除区域和全部使用此区域的limit_req", params: []paramDoc{{name: "domain", in: "query", description: "只删除此域名server中的limit_req"}, {name: "location", in: "query", description: "只删除此location中的limit_req"}}},
+	"GET /api/access":                                {summary: "查询http和server中的allow、deny规则，expires为自动删除的时间", response: "application/json"},
+	"POST /api/access":                               {summary: "批量添加allow、deny规则，请求内容为IP或者CIDR列表，每行一个，#后面为注释", params: []paramDoc{{name: "domain", in: "query", description: "server_name包含此域名的server，为空时作用于全部http"}, {name: "action", in: "query", description: "deny(默认) 或者 allow"}, {name: "ttl", in: "query", description: "有效期，过期后自动删除，例如：30m, 1d"}, {name: "address", in: "query", description: "地址，可以多个"}}, contentType: "text/plain"},
+	"DELETE /api/access":                             {summary: "批量删除allow、deny规则，请求内容同添加", params: []paramDoc{{name: "domain", in: "query", description: "server_name包含此域名的server，为空时为http"}, {name: "address", in: "query", description: "地址，可以多个"}}, contentType: "text/plain"},
+	"GET /api/access/bans":                           {summary: "查询设置了有效期的规则，reason为自动封禁(--jail)的原因", response: "application/json"},
+	"DELETE /api/access/bans":                        {summary: "解除封禁，删除deny规则和有效期", params: []paramDoc{{name: "domain", in: "query", description: "server_name包含此域名的server，为空时为http"}, {name: "address", in: "query", required: true, description: "地址，可以多个"}}},
+	"GET /api/access/jails":                          {summary: "查询自动封禁(--jail)的配置", response: "application/json"},
+	"GET /api/security-headers":                      {summary: "查询http和server中设置的安全响应头，和预设相同时返回预设名称", response: "application/json"},
+	"GET /api/security-headers/profiles":             {summary: "安全响应头预设：strict, moderate", response: "application/json"},
+	"PUT /api/security-headers":                      {summary: "设置安全响应头，{domain, profile, headers}，domain为空时作用于全部http，headers覆盖预设（值为空时不添加）", contentType: "application/json"},
+	"DELETE /api/security-headers":                   {summary: "删除安全响应头", params: []paramDoc{{name: "domain", in: "query", description: "域名，为空时删除http中的安全响应头"}}},
+	"GET /api/waf":                                   {summary: "查询开启了ModSecurity(WAF)的server", response: "application/json"},
+	"PUT /api/waf/{domain}":                          {summary: "server开启ModSecurity，{mode, rules}，mode为 blocking(默认) 或者 detection(只记录)，rules为规则文件名称或者绝对路径，为空时使用原来的规则文件。需要nginx支持ModSecurity-nginx模块", contentType: "application/json"},
+	"DELETE /api/waf/{domain}":                       {summary: "server关闭ModSecurity"},
+	"GET /api/waf/rules":                             {summary: "查询全部规则文件", response: "application/json"},
+	"GET /api/waf/rules/{name}":                      {summary: "规则文件内容", response: "text/plain"},
+	"PUT /api/waf/rules/{name}":                      {summary: "上传规则文件，已经被使用时测试配置并重启nginx", contentType: "text/plain"},
+	"DELETE /api/waf/rules/{name}":                   {summary: "删除规则文件，还在使用时返回错误"},
+	"GET /api/geoip":                                 {summary: "查询全部地域策略", response: "application/json"},
+	"GET /api/geoip/database":                        {summary: "查询GeoIP数据库信息，需要开启 --geoip", response: "application/json"},
+	"POST /api/geoip/database":                       {summary: "立即下载GeoIP数据库并重启nginx"},
+	"GET /api/geoip/{domain}":                        {summary: "查询域名的地域策略", response: "application/json"},
+	"PUT /api/geoip/{domain}":                        {summary: "设置地域策略，{block, location, routes, default}，block的国家返回403，routes按照国家代码代理到upstream", contentType: "application/json", response: "application/json"},
+	"DELETE /api/geoip/{domain}":                     {summary: "删除地域策略，location代理到默认的upstream"},
+	"GET /api/htpasswd":                              {summary: "查询全部htpasswd文件和用户", response: "application/json"},
+	"DELETE /api/htpasswd/{name}":                    {summary: "删除htpasswd文件，还在使用时返回错误"},
+	"PUT /api/htpasswd/{name}/users/{user}":          {summary: "添加用户或者修改密码，{password}，文件不存在时创建", contentType: "application/json"},
+	"DELETE /api/htpasswd/{name}/users/{user}":       {summary: "删除用户"},
+	"PUT /api/htpasswd/{name}/locations":             {summary: "location使用htpasswd文件开启basic认证，{domain, location, realm}", contentType: "application/json"},
+	"DELETE /api/htpasswd/{name}/locations":          {summary: "关闭location的basic认证", params: []paramDoc{{name: "domain", in: "query", description: "域名"}, {name: "location", in: "query", description: "location，默认：/"}}},
+	"GET /api/sites":                                 {summary: "查询全部静态站点和版本", response: "application/json"},
+	"GET /api/sites/{domain}":                        {summary: "查询静态站点的版本", response: "application/json"},
+	"POST /api/sites/{domain}":                       {summary: "上传zip、tar.gz或tar发布新版本，域名没有server时创建", params: []paramDoc{{name: "file", in: "formData", description: "压缩包，也可以直接作为请求内容"}, {name: "ssl", in: "query", description: "创建server时申请证书"}}, contentType: "multipart/form-data", response: "application/json"},
+	"DELETE /api/sites/{domain}":                     {summary: "删除静态站点的全部版本和server"},
+	"PUT /api/sites/{domain}/rollback":               {summary: "回滚静态站点，不需要重启nginx", params: []paramDoc{{name: "version", in: "query", description: "回滚的版本，默认上一个版本"}}, response: "application/json"},
+	"GET /api/redirects":                             {summary: "查询全部URL跳转", response: "application/json"},
+	"POST /api/redirects":                            {summary: "批量添加URL跳转，[{domain, path, to, code}]，域名和路径相同时替换", contentType: "application/json", response: "application/json"},
+	"DELETE /api/redirects":                          {summary: "删除URL跳转", params: []paramDoc{{name: "domain", in: "query", required: true, description: "域名"}, {name: "path", in: "query", description: "路径，为空时删除整个域名的跳转"}}, response: "application/json"},
+	"GET /api/nginx/info":                            {summary: "查询nginx程序信息：版本、编译的模块", response: "application/json"},
+	"GET /api/nginx/status":                          {summary: "查询nginx的连接和请求统计(stub_status)", response: "application/json"},
+	"GET /api/cluster":                               {summary: "查询当前节点名称和是否为leader，集群中只有leader续期证书和运行服务发现", response: "application/json"},
+	"GET /api/cluster/nodes":                         {summary: "查询注册在存储中的全部节点和最后一次重启nginx的结果", response: "application/json"},
+	"POST /api/cluster/reload":                       {summary: "全部节点重启nginx，返回每个节点的结果", params: []paramDoc{{name: "local", in: "query", description: "true: 只重启当前节点"}}, response: "application/json"},
+	"GET /api/logs/rotate":                           {summary: "查询日志切割策略", response: "application/json"},
+	"PUT /api/logs/rotate":                           {summary: "替换日志切割策略，[\"/var/log/nginx/*.log?size=100M&interval=1d&keep=7&compress=true\"]", contentType: "application/json", response: "application/json"},
+	"POST /api/logs/rotate":                          {summary: "立即切割全部日志文件，返回切割后的历史文件", response: "application/json"},
+	"GET /api/changes":                               {summary: "查询等待审批的修改(--approval)", response: "application/json"},
+	"GET /api/changes/{id}":                          {summary: "查询等待审批的修改内容", response: "application/json"},
+	"POST /api/changes/{id}/approve":                 {summary: "审批通过：测试配置通过后保存修改并重启nginx，审批人不能是修改的创建人", response: "application/json"},
+	"POST /api/changes/{id}/reject":                  {summary: "拒绝修改", response: "application/json"},
+	"GET /api/history":                               {summary: "查询历史版本", response: "application/json"},
+	"POST /api/rollback":                             {summary: "回滚到指定版本", params: []paramDoc{{name: "version", in: "query", required: true}}, response: "application/json"},
+	"GET /api/watch":                                 {summary: "监听配置变更(SSE)", params: []paramDoc{{name: "file", in: "query"}}, response: "text/event-stream"},
+	"GET /api/events":                                {summary: "nginx事件(WebSocket)"},
+	"POST /api/token":                                {summary: "签发JWT token", params: []paramDoc{{name: "role", in: "query", description: "viewer, editor, cert-manager, admin"}, {name: "expire", in: "query", description: "24h"}, {name: "tenant", in: "query", description: "绑定的租户，租户用户只能签发自己租户的"}}, response: "application/json"},
+	"GET /api/oidc/login":                            {summary: "跳转到OIDC服务登录", params: []paramDoc{{name: "redirect", in: "query", description: "登录成功后跳转的页面"}}},
+	"GET /api/oidc/callback":                         {summary: "OIDC登录回调，返回id_token", response: "application/json"},
+	"GET /api/keys":                                  {summary: "查询api key", response: "application/json"},
+	"POST /api/keys":                                 {summary: "创建api key", params: []paramDoc{{name: "label", in: "query"}, {name: "role", in: "query", description: "viewer, editor, cert-manager, admin"}, {name: "tenant", in: "query", description: "绑定的租户"}}, response: "application/json"},
+	"DELETE /api/keys/{id}":                          {summary: "删除api key"},
+	"GET /api/owners":                                {summary: "查询域名的保护规则", response: "application/json"},
+	"PUT /api/owners/{domain}":                       {summary: "设置域名的所有者，只有所有者（和admin）可以修改或者删除包含此域名的server，其他用户返回403", params: []paramDoc{{name: "owner", in: "query", description: "所有者的用户名，可以多个"}}, response: "application/json"},
+	"DELETE /api/owners/{domain}":                    {summary: "删除域名的保护规则"},
+	"GET /api/swagger.json":                          {summary: "OpenAPI文档", response: "application/json"},
+	"GET /api/swagger":                               {summary: "Swagger UI", response: "text/html"},
+	"PUT /simple/server":                             {summary: "添加简单代理", contentType: "application/json"},
+	"GET /file":                                      {summary: "查询文件", params: []paramDoc{queryParam}, response: "application/json"},
+	"POST /file":                                     {summary: "上传文件", params: []paramDoc{{name: "path", in: "formData", required: true}, {name: "file", in: "formData", required: true}, matchParam}, contentType: "multipart/form-data"},
+	"DELETE /file":                                   {summary: "删除文件", params: []paramDoc{{name: "file", in: "query", required: true}, matchParam}},
+	"PUT /ssl/{domain}":                              {summary: "申请证书", params: []paramDoc{{name: "email", in: "query"}}, response: "application/json"},
+	"POST /ssl/{domain}":                             {summary: "更新证书", response: "application/json"},
+	"GET /reload":                                    {summary: "重启nginx"},
+	"POST /reload":                                   {summary: "重启nginx"},
+	"GET /ui":                                        {summary: "管理页面", response: "text/html"},
+	"GET /metrics":                                   {summary: "Prometheus监控指标", response: "text/plain"},
+	"GET /api/admin/runtime":                         {summary: "aginx运行状态：goroutine数量、内存、存储操作耗时统计", response: "application/json"},
+	"GET /healthz":                                   {summary: "aginx进程存活，不需要认证", response: "application/json"},
+	"GET /readyz":                                    {summary: "aginx可以处理请求（存储可以访问，nginx正在运行并且已经加载配置），否则返回503，不需要认证", response: "application/json"},
+	"GET /health":                                    {summary: "健康检查", response: "application/json"},
+}
+
+type swaggerController struct {
+	app      *iris.Application
+	security bool
+}
+
+func (sc *swaggerController) operation(method, path string) map[string]interface{} {
+	doc, has := operationDocs[method+" "+path]
+	if !has {
+		doc = operationDoc{summary: path, response: "application/json"}
+	}
+	operation := map[string]interface{}{"summary": doc.summary}
+
+	parameters := make([]map[string]interface{}, 0)
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]string{"type": "string"},
+		})
+	}
+	formData := map[string]interface{}{}
+	for _, param := range doc.params {
+		if param.in == "formData" {
+			formData[param.name] = map[string]string{"type": "string"}
+			continue
+		}
+		schema := map[string]interface{}{"type": "string"}
+		if param.array {
+			schema = map[string]interface{}{"type": "array", "items": map[string]string{"type": "string"}}
+		}
+		parameters = append(parameters, map[string]interface{}{
+			"name": param.name, "in": param.in, "required": param.required,
+			"description": param.description, "schema": schema,
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if doc.contentType != "" {
+		schema := map[string]interface{}{"type": "string"}
+		if len(formData) > 0 {
+			schema = map[string]interface{}{"type": "object", "properties": formData}
+		} else if doc.contentType == "application/json" {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{doc.contentType: map[string]interface{}{"schema": schema}},
+		}
+	}
+	if doc.response == "" {
+		operation["responses"] = map[string]interface{}{"204": map[string]string{"description": "success"}}
+	} else {
+		operation["responses"] = map[string]interface{}{"200": map[string]interface{}{
+			"description": "success", "content": map[string]interface{}{doc.response: map[string]interface{}{}},
+		}}
+	}
+	return operation
+}
+
+//根据注册的路由生成 OpenAPI 3 文档
+func (sc *swaggerController) JSON(ctx iris.Context) {
+	paths := map[string]map[string]interface{}{}
+	for _, route := range sc.app.GetRoutes() {
+		method := route.Method
+		if !contains(docMethods, method) {
+			continue
+		}
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		if _, has := paths[path]; !has {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(method)] = sc.operation(method, path)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.0",
+		"info":    map[string]string{"title": "AGINX", "version": "v1"},
+		"servers": []map[string]string{{"url": "/"}},
+		"paths":   paths,
+	}
+	if sc.security {
+		doc["components"] = map[string]interface{}{"securitySchemes": map[string]interface{}{
+			"basicAuth":  map[string]string{"type": "http", "scheme": "basic"},
+			"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		}}
+		doc["security"] = []map[string][]string{{"basicAuth": {}}, {"bearerAuth": {}}}
+	}
+	_, _ = ctx.JSON(doc)
+}
+
+const swaggerUI = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>AGINX API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@3/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@3/swagger-ui-bundle.js"></script>
+<script>
+  window.ui = SwaggerUIBundle({url: "swagger.json", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>`
+
+func (sc *swaggerController) UI(ctx iris.Context) {
+	_, _ = ctx.HTML(swaggerUI)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
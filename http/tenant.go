@@ -0,0 +1,90 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/auth"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"path/filepath"
+	"strings"
+)
+
+//租户用户可以使用的api
+func tenantPath(path string) bool {
+	return path == "/api/token" || path == "/api/tenant" || strings.HasPrefix(path, "/api/tenant/")
+}
+
+type tenantController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+}
+
+//请求的租户，租户用户为绑定的租户，全局用户使用参数 tenant 指定
+func (tc *tenantController) tenant(ctx iris.Context) string {
+	if identity := requestIdentity(ctx); identity != nil && identity.Tenant != "" {
+		return identity.Tenant
+	}
+	tenant := ctx.URLParam("tenant")
+	util.AssertTrue(auth.ValidTenant(tenant), "tenant format error: "+tenant)
+	return tenant
+}
+
+func (tc *tenantController) file(ctx iris.Context) (string, string) {
+	tenant := tc.tenant(ctx)
+	file, err := nginx.TenantFile(tenant, ctx.Params().Get("file"))
+	util.PanicIfError(err)
+	return tenant, file
+}
+
+//租户的配置文件和文件中的server、upstream
+func (tc *tenantController) Files(ctx iris.Context, client *nginx.Client) []*nginx.GraphFile {
+	return nginx.TenantFiles(client.Configuration(), tc.tenant(ctx))
+}
+
+func (tc *tenantController) Raw(ctx iris.Context) {
+	_, file := tc.file(ctx)
+	content, err := requestEngine(ctx, tc.engine).Get(file)
+	util.PanicIfError(err)
+	ctx.ContentType("text/plain")
+	_, _ = ctx.Write(content.Content)
+}
+
+//使用请求内容替换（或者新建）租户的配置文件，测试通过后保存并重启nginx
+func (tc *tenantController) Put(ctx iris.Context, client *nginx.Client) int {
+	tenant, file := tc.file(ctx)
+	body, err := ctx.GetBody()
+	util.PanicIfError(err)
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile(file, body))
+	util.PanicIfError(err)
+	util.PanicIfError(tc.process.CheckStrict("http", conf.Body...))
+	util.PanicIfError(nginx.CheckTenant(client.Configuration(), tenant, conf.Body))
+
+	//第一次添加租户配置时在http中include全部租户的配置文件
+	include := !nginx.HasTenantInclude(client.Configuration())
+	if include {
+		util.PanicIfError(client.Add(nginx.Queries("http"), nginx.NewDirective("include", nginx.TenantInclude)))
+	}
+	util.PanicIfError(tc.process.Test(client.Configuration(), func(testDir string) error {
+		return util.WriteFile(filepath.Join(testDir, file), body)
+	}))
+	if include {
+		util.PanicIfError(client.Store())
+	}
+	util.PanicIfError(requestEngine(ctx, tc.engine).Put(file, body))
+	util.PanicIfError(tc.process.Reload())
+	return iris.StatusNoContent
+}
+
+func (tc *tenantController) Delete(ctx iris.Context, client *nginx.Client) int {
+	engine := requestEngine(ctx, tc.engine)
+	_, file := tc.file(ctx)
+	_, err := engine.Get(file)
+	util.PanicIfError(err)
+	util.PanicIfError(tc.process.Test(client.Configuration(), func(testDir string) error {
+		return util.WriteFile(filepath.Join(testDir, file), []byte{})
+	}))
+	util.PanicIfError(engine.Remove(file))
+	util.PanicIfError(tc.process.Reload())
+	return iris.StatusNoContent
+}
@@ -0,0 +1,345 @@
+package http
+
+import (
+	"github.com/kataras/iris/v12"
+)
+
+//管理页面：浏览编辑配置文件和指令，查看证书和upstream，重启nginx。页面不需要认证，api请求使用页面中填写的认证信息。
+//页面（包括编辑器和语法高亮）全部内嵌，不加载外部的资源，认证信息只保存在当前标签页的sessionStorage中
+const dashboardUI = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>AGINX</title>
+  <style>
+    body { margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 14px; color: #333; }
+    header { display: flex; align-items: center; padding: 8px 16px; background: #263238; color: #fff; }
+    header h1 { font-size: 18px; margin: 0 24px 0 0; }
+    header nav a { color: #cfd8dc; margin-right: 16px; cursor: pointer; text-decoration: none; }
+    header nav a.active { color: #fff; font-weight: bold; }
+    header .right { margin-left: auto; display: flex; gap: 8px; }
+    main { padding: 16px; }
+    section { display: none; }
+    section.active { display: block; }
+    button { padding: 4px 12px; cursor: pointer; }
+    input { padding: 4px; }
+    table { border-collapse: collapse; width: 100%; }
+    th, td { border-bottom: 1px solid #eceff1; padding: 6px; text-align: left; }
+    .files { display: flex; gap: 16px; }
+    .files ul { list-style: none; margin: 0; padding: 0; width: 260px; max-height: 80vh; overflow: auto; }
+    .files li { padding: 4px; cursor: pointer; word-break: break-all; }
+    .files li.active, .files li:hover { background: #eceff1; }
+    .editor { flex: 1; }
+    .code { position: relative; border: 1px solid #cfd8dc; height: 70vh; }
+    .code pre, .code textarea { position: absolute; top: 0; left: 0; box-sizing: border-box; width: 100%; height: 100%; margin: 0;
+      padding: 4px 8px; border: 0; overflow: auto; font: 13px/1.5 Menlo, Consolas, monospace; white-space: pre; tab-size: 4; }
+    .code pre { color: #333; pointer-events: none; }
+    .code textarea { color: transparent; background: transparent; caret-color: #333; resize: none; outline: none; }
+    .code .comment { color: #90a4ae; font-style: italic; }
+    .code .string { color: #2e7d32; }
+    .code .variable { color: #6a1b9a; }
+    .code .directive { color: #1565c0; font-weight: bold; }
+    .code .punct { color: #d84315; }
+    .toolbar { margin: 8px 0; display: flex; gap: 8px; align-items: center; }
+    #message { padding: 8px 16px; white-space: pre-wrap; }
+    #message.error { background: #ffebee; color: #c62828; }
+    #message.success { background: #e8f5e9; color: #2e7d32; }
+    .expiring { color: #c62828; }
+  </style>
+</head>
+<body>
+<header>
+  <h1>AGINX</h1>
+  <nav>
+    <a data-tab="files" class="active">Files</a>
+    <a data-tab="directives">Directives</a>
+    <a data-tab="certs">Certificates</a>
+    <a data-tab="upstreams">Upstreams</a>
+  </nav>
+  <div class="right">
+    <input id="auth" placeholder="user:password or token" size="28">
+    <button onclick="reload()">Reload NGINX</button>
+  </div>
+</header>
+<div id="message"></div>
+<main>
+  <section id="files" class="active">
+    <div class="files">
+      <ul id="fileList"></ul>
+      <div class="editor">
+        <div class="toolbar"><input id="filePath" placeholder="path, example: hosts.d/api.conf" size="50"><button onclick="saveFile()">Save</button></div>
+        <textarea id="fileContent"></textarea>
+      </div>
+    </div>
+  </section>
+  <section id="directives">
+    <div class="toolbar">
+      <input id="queries" placeholder="queries separated by |, example: http | server.server_name('api.aginx.io')" size="80">
+      <button onclick="queryDirectives()">Query</button>
+      <button onclick="changeDirectives('PUT')">Add</button>
+      <button onclick="changeDirectives('POST')">Modify</button>
+      <button onclick="changeDirectives('DELETE')">Delete</button>
+      <button onclick="validateDirectives()">Validate</button>
+    </div>
+    <textarea id="directiveContent"></textarea>
+  </section>
+  <section id="certs">
+    <table>
+      <thead><tr><th>Domain</th><th>Email</th><th>Expire</th><th>Days</th><th></th></tr></thead>
+      <tbody id="certList"></tbody>
+    </table>
+  </section>
+  <section id="upstreams">
+    <table>
+      <thead><tr><th>Block</th><th>Name</th><th>Servers</th></tr></thead>
+      <tbody id="upstreamList"></tbody>
+    </table>
+  </section>
+</main>
+<script>
+  function escapeHTML(text) {
+    return text.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;");
+  }
+
+  //nginx配置的语法高亮：注释、字符串、变量、指令名称和 { } ;
+  function highlight(text) {
+    var out = "", statement = true;
+    var token = /(#[^\n]*)|("(?:[^"\\]|\\.)*"?|'(?:[^'\\]|\\.)*'?)|(\$\{?\w+\}?)|([{};])|(\s+)|([^\s{};#"'$]+|[$])/g;
+    var match;
+    while ((match = token.exec(text)) !== null) {
+      var value = escapeHTML(match[0]);
+      if (match[1]) {
+        out += '<span class="comment">' + value + "</span>";
+      } else if (match[2]) {
+        out += '<span class="string">' + value + "</span>";
+        statement = false;
+      } else if (match[3]) {
+        out += '<span class="variable">' + value + "</span>";
+        statement = false;
+      } else if (match[4]) {
+        out += '<span class="punct">' + value + "</span>";
+        statement = true;
+      } else if (match[5]) {
+        out += value;
+      } else {
+        out += statement ? '<span class="directive">' + value + "</span>" : value;
+        statement = false;
+      }
+    }
+    return out;
+  }
+
+  //使用textarea编辑，背后的pre显示高亮后的内容
+  function Editor(textarea) {
+    var code = document.createElement("div"), pre = document.createElement("pre");
+    code.className = "code";
+    textarea.parentNode.insertBefore(code, textarea);
+    code.appendChild(pre);
+    code.appendChild(textarea);
+    textarea.spellcheck = false;
+    var render = function () {
+      pre.innerHTML = highlight(textarea.value) + "\n";
+      pre.scrollTop = textarea.scrollTop;
+      pre.scrollLeft = textarea.scrollLeft;
+    };
+    textarea.addEventListener("input", render);
+    textarea.addEventListener("scroll", render);
+    textarea.addEventListener("keydown", function (e) {
+      if (e.key !== "Tab") { return; }
+      e.preventDefault();
+      var start = textarea.selectionStart;
+      textarea.value = textarea.value.substring(0, start) + "    " + textarea.value.substring(textarea.selectionEnd);
+      textarea.selectionStart = textarea.selectionEnd = start + 4;
+      render();
+    });
+    this.getValue = function () { return textarea.value; };
+    this.setValue = function (value) {
+      textarea.value = value;
+      render();
+    };
+    this.refresh = render;
+    render();
+  }
+
+  var fileEditor = new Editor(document.getElementById("fileContent"));
+  var directiveEditor = new Editor(document.getElementById("directiveContent"));
+  var authInput = document.getElementById("auth");
+  authInput.value = sessionStorage.getItem("aginx.auth") || "";
+  authInput.onchange = function () { sessionStorage.setItem("aginx.auth", authInput.value); };
+
+  function message(text, error) {
+    var el = document.getElementById("message");
+    el.textContent = text || "";
+    el.className = text ? (error ? "error" : "success") : "";
+  }
+
+  function request(method, url, body) {
+    var headers = {};
+    var auth = authInput.value;
+    if (auth) {
+      headers["Authorization"] = auth.indexOf(":") > 0 ? "Basic " + btoa(auth) : "Bearer " + auth;
+    }
+    return fetch(url, {method: method, headers: headers, body: body}).then(function (resp) {
+      return resp.text().then(function (text) {
+        var data = text;
+        try { data = text ? JSON.parse(text) : null; } catch (e) {}
+        if (!resp.ok) {
+          throw new Error(data && data.message ? data.message : resp.status + " " + text);
+        }
+        return data;
+      });
+    }).catch(function (err) {
+      message(err.message, true);
+      throw err;
+    });
+  }
+
+  //指令转换为nginx配置，include的文件内容不输出
+  function pretty(directives, indent) {
+    var out = "";
+    (directives || []).forEach(function (d) {
+      if (d.virtual) { return; }
+      out += indent + d.name + (d.args && d.args.length ? " " + d.args.join(" ") : "");
+      var body = (d.body || []).filter(function (b) { return !b.virtual; });
+      if (d.body && d.name !== "include") {
+        out += " {\n" + pretty(body, indent + "    ") + indent + "}\n";
+      } else {
+        out += ";\n";
+      }
+    });
+    return out;
+  }
+
+  function queryString() {
+    return document.getElementById("queries").value.split("|").map(function (q) {
+      return q.trim();
+    }).filter(function (q) {
+      return q !== "";
+    }).map(function (q) {
+      return "q=" + encodeURIComponent(q);
+    }).join("&");
+  }
+
+  function loadFiles() {
+    request("GET", "/file").then(function (files) {
+      var list = document.getElementById("fileList");
+      list.innerHTML = "";
+      Object.keys(files || {}).sort().forEach(function (name) {
+        var li = document.createElement("li");
+        li.textContent = name;
+        li.onclick = function () {
+          Array.prototype.forEach.call(list.children, function (item) { item.className = ""; });
+          li.className = "active";
+          document.getElementById("filePath").value = name;
+          fileEditor.setValue(files[name]);
+        };
+        list.appendChild(li);
+      });
+    });
+  }
+
+  function saveFile() {
+    var form = new FormData();
+    form.append("path", document.getElementById("filePath").value);
+    form.append("file", new Blob([fileEditor.getValue()]), "file");
+    request("POST", "/file", form).then(function () {
+      message("saved and reloaded");
+      loadFiles();
+    });
+  }
+
+  function queryDirectives() {
+    request("GET", "/api?" + queryString()).then(function (directives) {
+      directiveEditor.setValue(pretty(directives, ""));
+      message("");
+    });
+  }
+
+  function changeDirectives(method) {
+    var body = method === "DELETE" ? null : directiveEditor.getValue();
+    request(method, "/api?" + queryString(), body).then(function () {
+      message(method === "DELETE" ? "deleted" : "saved and reloaded");
+    });
+  }
+
+  function validateDirectives() {
+    request("POST", "/api/validate?action=add&" + queryString(), directiveEditor.getValue()).then(function (result) {
+      message(result.output, !result.success);
+    });
+  }
+
+  function loadCerts() {
+    request("GET", "/api/certs").then(function (certs) {
+      var tbody = document.getElementById("certList");
+      tbody.innerHTML = "";
+      (certs || []).forEach(function (cert) {
+        var tr = document.createElement("tr");
+        [cert.domain, cert.email, cert.expire, cert.days].forEach(function (value) {
+          var td = document.createElement("td");
+          td.textContent = value;
+          tr.appendChild(td);
+        });
+        if (cert.days < 15) { tr.className = "expiring"; }
+        var td = document.createElement("td");
+        var renew = document.createElement("button");
+        renew.textContent = "Renew";
+        renew.onclick = function () {
+          request("POST", "/ssl/" + encodeURIComponent(cert.domain)).then(function () {
+            message("renewed " + cert.domain);
+            loadCerts();
+          });
+        };
+        td.appendChild(renew);
+        tr.appendChild(td);
+        tbody.appendChild(tr);
+      });
+    });
+  }
+
+  function loadUpstreams() {
+    var tbody = document.getElementById("upstreamList");
+    tbody.innerHTML = "";
+    ["http", "stream"].forEach(function (block) {
+      request("GET", "/simple/" + block + "/upstream").then(function (upstreams) {
+        (upstreams || []).forEach(function (upstream) {
+          var servers = (upstream.body || []).filter(function (d) {
+            return d.name === "server";
+          }).map(function (d) {
+            return d.args.join(" ");
+          });
+          var tr = document.createElement("tr");
+          [block, (upstream.args || []).join(" "), servers.join(", ")].forEach(function (value) {
+            var td = document.createElement("td");
+            td.textContent = value;
+            tr.appendChild(td);
+          });
+          tbody.appendChild(tr);
+        });
+      });
+    });
+  }
+
+  function reload() {
+    request("POST", "/reload").then(function () { message("reloaded"); });
+  }
+
+  var loaders = {files: loadFiles, certs: loadCerts, upstreams: loadUpstreams};
+  document.querySelectorAll("header nav a").forEach(function (tab) {
+    tab.onclick = function () {
+      document.querySelectorAll("header nav a, section").forEach(function (el) { el.classList.remove("active"); });
+      tab.classList.add("active");
+      document.getElementById(tab.dataset.tab).classList.add("active");
+      message("");
+      if (loaders[tab.dataset.tab]) { loaders[tab.dataset.tab](); }
+      fileEditor.refresh();
+      directiveEditor.refresh();
+    };
+  });
+  loadFiles();
+</script>
+</body>
+</html>`
+
+func dashboard(ctx iris.Context) {
+	_, _ = ctx.HTML(dashboardUI)
+}
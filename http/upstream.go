@@ -0,0 +1,75 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/health"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+)
+
+type upstreamController struct {
+	process *nginx.Process
+	checker *health.Checker
+}
+
+func (uc *upstreamController) List(client *nginx.Client) []*nginx.Upstream {
+	return client.Upstreams()
+}
+
+func (uc *upstreamController) Get(ctx iris.Context, client *nginx.Client) *nginx.Upstream {
+	upstream, err := client.GetUpstream(ctx.Params().Get("name"))
+	util.PanicIfError(err)
+	return upstream
+}
+
+//健康检查的结果，没有开启健康检查时返回错误
+func (uc *upstreamController) Health(ctx iris.Context) []*health.Member {
+	members, err := uc.checker.Health(ctx.Params().Get("name"))
+	util.PanicIfError(err)
+	return members
+}
+
+func (uc *upstreamController) read(ctx iris.Context) *nginx.Upstream {
+	upstream := new(nginx.Upstream)
+	util.PanicIfError(ctx.ReadJSON(upstream))
+	upstream.Name = ctx.Params().Get("name")
+	return upstream
+}
+
+//创建upstream，{load_balance, servers, stream}
+func (uc *upstreamController) New(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.NewUpstream(uc.read(ctx)))
+	return uc.store(client)
+}
+
+//替换upstream的负载均衡方式和全部server
+func (uc *upstreamController) Modify(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.SetUpstream(uc.read(ctx)))
+	return uc.store(client)
+}
+
+func (uc *upstreamController) Delete(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.DeleteUpstream(ctx.Params().Get("name")))
+	return uc.store(client)
+}
+
+//添加或者修改server：权重、max_fails、fail_timeout，down=true时摘除流量
+func (uc *upstreamController) SetServer(ctx iris.Context, client *nginx.Client) int {
+	server := new(nginx.UpstreamServer)
+	util.PanicIfError(ctx.ReadJSON(server))
+	server.Address = ctx.Params().Get("address")
+	util.PanicIfError(client.SetUpstreamServer(ctx.Params().Get("name"), server))
+	return uc.store(client)
+}
+
+func (uc *upstreamController) RemoveServer(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.RemoveUpstreamServer(ctx.Params().Get("name"), ctx.Params().Get("address")))
+	return uc.store(client)
+}
+
+func (uc *upstreamController) store(client *nginx.Client) int {
+	util.PanicIfError(uc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(uc.process.Reload())
+	return iris.StatusNoContent
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"os"
+)
+
+type wafController struct {
+	engine  plugins.StorageEngine
+	process *nginx.Process
+}
+
+func (wc *wafController) List(client *nginx.Client) []*nginx.WAF {
+	return client.WAFs()
+}
+
+//server开启ModSecurity，{mode, rules}，mode为 blocking(默认) 或者 detection，rules为空时使用原来的规则文件
+func (wc *wafController) Set(ctx iris.Context, client *nginx.Client) int {
+	waf := new(nginx.WAF)
+	util.PanicIfError(ctx.ReadJSON(waf))
+	waf.Domain = ctx.Params().Get("domain")
+	util.PanicIfError(client.SetWAF(waf))
+	return wc.store(client)
+}
+
+func (wc *wafController) Delete(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.RemoveWAF(ctx.Params().Get("domain")))
+	return wc.store(client)
+}
+
+func (wc *wafController) Rules() []*nginx.WAFRule {
+	rules, err := nginx.ListWAFRules(wc.engine)
+	util.PanicIfError(err)
+	return rules
+}
+
+func (wc *wafController) Rule(ctx iris.Context) {
+	content, err := nginx.GetWAFRule(requestEngine(ctx, wc.engine), ctx.Params().Get("name"))
+	util.PanicIfError(err)
+	ctx.ContentType("text/plain")
+	_, _ = ctx.Write(content)
+}
+
+//上传规则文件，请求内容为规则文件内容。规则文件已经被使用时测试配置并重启nginx，测试失败时恢复原来的规则文件
+func (wc *wafController) PutRule(ctx iris.Context, client *nginx.Client) int {
+	engine := requestEngine(ctx, wc.engine)
+	name := ctx.Params().Get("name")
+	content, err := ctx.GetBody()
+	util.PanicIfError(err)
+	old, err := nginx.GetWAFRule(engine, name)
+	if err != nil && !os.IsNotExist(err) {
+		util.PanicIfError(err)
+	}
+	util.PanicIfError(nginx.PutWAFRule(engine, name, content))
+	if len(client.WAFRuleUsages(name)) == 0 {
+		return iris.StatusNoContent
+	}
+	if err = wc.process.Test(client.Configuration()); err != nil {
+		if old != nil {
+			_ = nginx.PutWAFRule(engine, name, old)
+		} else {
+			_ = engine.Remove(nginx.WAFRulePath(name))
+		}
+		util.PanicIfError(err)
+	}
+	util.PanicIfError(wc.process.Reload())
+	return iris.StatusNoContent
+}
+
+func (wc *wafController) DeleteRule(ctx iris.Context, client *nginx.Client) int {
+	util.PanicIfError(client.DeleteWAFRule(ctx.Params().Get("name")))
+	return iris.StatusNoContent
+}
+
+func (wc *wafController) store(client *nginx.Client) int {
+	util.PanicIfError(wc.process.Test(client.Configuration()))
+	util.PanicIfError(client.Store())
+	util.PanicIfError(wc.process.Reload())
+	return iris.StatusNoContent
+}
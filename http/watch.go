@@ -0,0 +1,57 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ihaiker/aginx/util"
+	"github.com/kataras/iris/v12"
+	"strings"
+	"time"
+)
+
+type watchController struct {
+}
+
+//使用SSE(Server-Sent Events)推送配置变更事件，file参数可以只订阅指定文件或者目录的变更
+func (wc *watchController) Watch(ctx iris.Context) {
+	file := ctx.URLParam("file")
+
+	events := make(chan *util.ChangeEvent, 100)
+	unsubscribe := util.SubscribeChanged(func(event *util.ChangeEvent) {
+		if file != "" && event.File != file && !strings.HasPrefix(event.File, file+"/") {
+			return
+		}
+		select {
+		case events <- event:
+		default:
+			logger.Warn("watch client is too slow, drop event ", event.File)
+		}
+	})
+	defer unsubscribe()
+
+	ctx.ContentType("text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.StatusCode(iris.StatusOK)
+	ctx.ResponseWriter().Flush()
+
+	heartbeat := time.NewTicker(time.Second * 30)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request().Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := ctx.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event := <-events:
+			bs, _ := json.Marshal(event)
+			if _, err := ctx.WriteString(fmt.Sprintf("event: change\ndata: %s\n\n", string(bs))); err != nil {
+				return
+			}
+		}
+		ctx.ResponseWriter().Flush()
+	}
+}
@@ -0,0 +1,142 @@
+package ingress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var ErrNotFound = errors.New("not found")
+
+//访问kubernetes api的简单客户端，默认使用集群内的 service account
+type Client struct {
+	host        string
+	token       string
+	http        *http.Client
+	ingressPath string
+}
+
+func NewClient(host, tokenFile, caFile string, insecure bool) (*Client, error) {
+	if host == "" {
+		serviceHost, servicePort := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if serviceHost == "" || servicePort == "" {
+			return nil, errors.New("not running in kubernetes cluster, use --kube-api")
+		}
+		host = "https://" + net.JoinHostPort(serviceHost, servicePort)
+		if tokenFile == "" {
+			tokenFile = serviceAccountDir + "/token"
+		}
+		if caFile == "" {
+			caFile = serviceAccountDir + "/ca.crt"
+		}
+	}
+	client := &Client{host: strings.TrimSuffix(host, "/")}
+	if tokenFile != "" {
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		client.token = strings.TrimSpace(string(token))
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid ca file: %s", caFile)
+		}
+	}
+	client.http = &http.Client{
+		Timeout:   time.Second * 30,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	return client, nil
+}
+
+func (c *Client) get(path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.host+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("kubernetes api %s: %s %s", path, resp.Status, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func namespaced(group, namespace, resource string) string {
+	if namespace == "" {
+		return fmt.Sprintf("%s/%s", group, resource)
+	}
+	return fmt.Sprintf("%s/namespaces/%s/%s", group, namespace, resource)
+}
+
+//查询Ingress，依次尝试 networking.k8s.io/v1、networking.k8s.io/v1beta1、extensions/v1beta1
+func (c *Client) Ingresses(namespace string) ([]*Ingress, error) {
+	groups := []string{"/apis/networking.k8s.io/v1", "/apis/networking.k8s.io/v1beta1", "/apis/extensions/v1beta1"}
+	if c.ingressPath != "" {
+		groups = []string{c.ingressPath}
+	}
+	list := &struct {
+		Items []*Ingress `json:"items"`
+	}{}
+	for _, group := range groups {
+		err := c.get(namespaced(group, namespace, "ingresses"), list)
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		c.ingressPath = group
+		return list.Items, nil
+	}
+	return nil, errors.New("kubernetes ingress api not found")
+}
+
+func (c *Client) Services(namespace string) ([]*Service, error) {
+	list := &struct {
+		Items []*Service `json:"items"`
+	}{}
+	err := c.get(namespaced("/api/v1", namespace, "services"), list)
+	return list.Items, err
+}
+
+func (c *Client) Endpoints(namespace string) ([]*Endpoints, error) {
+	list := &struct {
+		Items []*Endpoints `json:"items"`
+	}{}
+	err := c.get(namespaced("/api/v1", namespace, "endpoints"), list)
+	return list.Items, err
+}
+
+func (c *Client) Secret(namespace, name string) (*Secret, error) {
+	secret := new(Secret)
+	err := c.get(fmt.Sprintf("/api/v1/namespaces/%s/secrets/%s", namespace, name), secret)
+	return secret, err
+}
@@ -0,0 +1,180 @@
+package ingress
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+var logger = logs.New("ingress")
+
+//同步kubernetes的Ingress到nginx，配置文件保存在 ingress.d 目录中，其他配置依然可以使用api修改
+type Controller struct {
+	kube      *Client
+	aginx     api.Aginx
+	namespace string
+	class     string
+	interval  time.Duration
+	files     map[string]string //已经发布的文件
+	closeC    chan struct{}
+}
+
+func NewController(kube *Client, aginx api.Aginx, namespace, class string, interval time.Duration) *Controller {
+	return &Controller{
+		kube: kube, aginx: aginx, namespace: namespace, class: class, interval: interval,
+		files: map[string]string{}, closeC: make(chan struct{}),
+	}
+}
+
+func (c *Controller) snapshot() (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Ingresses: make([]*Ingress, 0), Services: map[string]*Service{},
+		Endpoints: map[string]*Endpoints{}, Secrets: map[string]*Secret{},
+	}
+	ingresses, err := c.kube.Ingresses(c.namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, ingress := range ingresses {
+		if MatchClass(ingress, c.class) {
+			snapshot.Ingresses = append(snapshot.Ingresses, ingress)
+		}
+	}
+	services, err := c.kube.Services(c.namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services {
+		snapshot.Services[key(service.Metadata.Namespace, service.Metadata.Name)] = service
+	}
+	endpoints, err := c.kube.Endpoints(c.namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range endpoints {
+		snapshot.Endpoints[key(endpoint.Metadata.Namespace, endpoint.Metadata.Name)] = endpoint
+	}
+	for _, ingress := range snapshot.Ingresses {
+		for _, tls := range ingress.Spec.TLS {
+			secretKey := key(ingress.Metadata.Namespace, tls.SecretName)
+			if _, has := snapshot.Secrets[secretKey]; has || tls.SecretName == "" {
+				continue
+			}
+			if secret, err := c.kube.Secret(ingress.Metadata.Namespace, tls.SecretName); err != nil {
+				logger.Warn("get secret ", secretKey, " error: ", err)
+			} else {
+				snapshot.Secrets[secretKey] = secret
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+//配置文件需要在证书之后发布，删除时先删除配置文件
+func sortFiles(names []string, confFirst bool) {
+	sort.Slice(names, func(i, j int) bool {
+		ci, cj := filepath.Ext(names[i]) == ".conf", filepath.Ext(names[j]) == ".conf"
+		if ci != cj {
+			return ci == confFirst
+		}
+		return names[i] < names[j]
+	})
+}
+
+func (c *Controller) sync() {
+	snapshot, err := c.snapshot()
+	if err != nil {
+		logger.Warn("list kubernetes resources error: ", err)
+		return
+	}
+	files := snapshot.Files()
+	changed := make([]string, 0)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sortFiles(names, false)
+	for _, name := range names {
+		content := string(files[name])
+		if old, has := c.files[name]; has && old == content {
+			continue
+		}
+		logger.Info("publish ", name)
+		if err := c.aginx.File().NewWithContent(name, files[name]); err != nil {
+			logger.Warn("publish ", name, " error: ", err)
+			continue
+		}
+		c.files[name] = content
+		changed = append(changed, name)
+	}
+
+	removes := make([]string, 0)
+	for name := range c.files {
+		if _, has := files[name]; !has {
+			removes = append(removes, name)
+		}
+	}
+	sortFiles(removes, true)
+	for _, name := range removes {
+		logger.Info("remove ", name)
+		if err := c.aginx.File().Remove(name); err != nil {
+			logger.Warn("remove ", name, " error: ", err)
+			continue
+		}
+		delete(c.files, name)
+		changed = append(changed, name)
+	}
+	if len(changed) > 0 {
+		util.PublishEvent(util.EventUpstreamChange, "ingress", nil, changed)
+	}
+}
+
+func (c *Controller) createInclude() error {
+	include := fmt.Sprintf("%s/*.ngx.conf", Dir)
+	if _, err := c.aginx.Directive().Select("http", fmt.Sprintf("include('%s')", include)); err != nil {
+		logger.Debugf("create NGINX directive (include %s)", include)
+		if err = c.aginx.Directive().Add(api.Queries("http"), nginx.NewDirective("include", include)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Controller) Start() error {
+	logger.Info("start ingress controller")
+	if err := c.createInclude(); err != nil {
+		return err
+	}
+	//已经存在的文件，没有变化不需要重新发布，ingress删除后需要删除
+	if files, err := c.aginx.File().Search(Dir+"/*.ngx.conf", Dir+"/ssl/*"); err != nil {
+		logger.Warn("search exists files error: ", err)
+	} else {
+		c.files = files
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		c.sync()
+		for {
+			select {
+			case <-c.closeC:
+				return
+			case <-ticker.C:
+				c.sync()
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *Controller) Stop() error {
+	close(c.closeC)
+	return nil
+}
@@ -0,0 +1,223 @@
+package ingress
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ihaiker/aginx/nginx"
+	"sort"
+	"strings"
+)
+
+const (
+	Dir          = "ingress.d"
+	classAnnoKey = "kubernetes.io/ingress.class"
+	defaultHost  = "_"
+)
+
+//生成配置使用的kubernetes资源
+type Snapshot struct {
+	Ingresses []*Ingress
+	Services  map[string]*Service   //namespace/name
+	Endpoints map[string]*Endpoints //namespace/name
+	Secrets   map[string]*Secret    //namespace/name
+}
+
+type location struct {
+	path     string
+	exact    bool
+	upstream string
+}
+
+type host struct {
+	name      string
+	secret    string //证书文件名称，不带后缀
+	locations []*location
+	upstreams map[string][]string
+}
+
+func key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+//ingress是否需要当前控制器处理，未指定class的都需要处理
+func MatchClass(ingress *Ingress, class string) bool {
+	name := ingress.Spec.IngressClassName
+	if name == "" {
+		name = ingress.Metadata.Annotations[classAnnoKey]
+	}
+	return name == "" || class == "" || name == class
+}
+
+func fileName(hostName string) string {
+	return fmt.Sprintf("%s/%s.ngx.conf", Dir, strings.ReplaceAll(hostName, "*", "_x_"))
+}
+
+//获取服务端口对应的所有地址
+func (s *Snapshot) addresses(namespace, name string, port IntOrString) []string {
+	service, has := s.Services[key(namespace, name)]
+	if !has {
+		return nil
+	}
+	var servicePort *ServicePort
+	for i, sp := range service.Spec.Ports {
+		if number, isNumber := port.Int(); (isNumber && sp.Port == number) || sp.Name == string(port) {
+			servicePort = &service.Spec.Ports[i]
+			break
+		}
+	}
+	endpoints, has := s.Endpoints[key(namespace, name)]
+	if servicePort == nil || !has {
+		return nil
+	}
+	addresses := make([]string, 0)
+	for _, subset := range endpoints.Subsets {
+		for _, endpointPort := range subset.Ports {
+			//endpoints使用服务端口的名称对应，只有一个端口时名称可以为空
+			if endpointPort.Name != servicePort.Name {
+				continue
+			}
+			for _, address := range subset.Addresses {
+				addresses = append(addresses, fmt.Sprintf("%s:%d", address.IP, endpointPort.Port))
+			}
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+func (s *Snapshot) addLocation(h *host, ingress *Ingress, path, pathType string, backend *IngressBackend) {
+	if path == "" {
+		path = "/"
+	}
+	exact := pathType == "Exact"
+	for _, l := range h.locations {
+		if l.path == path && l.exact == exact {
+			return //相同路径先定义的生效
+		}
+	}
+	serviceName, port := backend.service()
+	namespace := ingress.Metadata.Namespace
+	upstream := nginx.UpstreamName(fmt.Sprintf("ingress_%s_%s_%s_%s", h.name, namespace, serviceName, port))
+	upstream = strings.ReplaceAll(upstream, "-", "_")
+	if _, has := h.upstreams[upstream]; !has {
+		h.upstreams[upstream] = s.addresses(namespace, serviceName, port)
+	}
+	h.locations = append(h.locations, &location{path: path, exact: exact, upstream: upstream})
+}
+
+func (s *Snapshot) hosts() map[string]*host {
+	hosts := map[string]*host{}
+	getHost := func(name string) *host {
+		if name == "" {
+			name = defaultHost
+		}
+		if h, has := hosts[name]; has {
+			return h
+		}
+		h := &host{name: name, locations: make([]*location, 0), upstreams: map[string][]string{}}
+		hosts[name] = h
+		return h
+	}
+
+	ingresses := make([]*Ingress, len(s.Ingresses))
+	copy(ingresses, s.Ingresses)
+	sort.Slice(ingresses, func(i, j int) bool {
+		return key(ingresses[i].Metadata.Namespace, ingresses[i].Metadata.Name) <
+			key(ingresses[j].Metadata.Namespace, ingresses[j].Metadata.Name)
+	})
+
+	for _, ingress := range ingresses {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			h := getHost(rule.Host)
+			for i := range rule.HTTP.Paths {
+				path := &rule.HTTP.Paths[i]
+				s.addLocation(h, ingress, path.Path, path.PathType, &path.Backend)
+			}
+		}
+		backend := ingress.Spec.DefaultBackend
+		if backend == nil {
+			backend = ingress.Spec.Backend
+		}
+		if backend != nil {
+			s.addLocation(getHost(defaultHost), ingress, "/", "Prefix", backend)
+		}
+	}
+	//证书可能定义在其他ingress中
+	for _, ingress := range ingresses {
+		namespace := ingress.Metadata.Namespace
+		for _, tls := range ingress.Spec.TLS {
+			secret, has := s.Secrets[key(namespace, tls.SecretName)]
+			if !has || len(secret.Data["tls.crt"]) == 0 || len(secret.Data["tls.key"]) == 0 {
+				continue
+			}
+			for _, hostName := range tls.Hosts {
+				if h, has := hosts[hostName]; has && h.secret == "" {
+					h.secret = fmt.Sprintf("%s/ssl/%s-%s", Dir, namespace, tls.SecretName)
+				}
+			}
+		}
+	}
+	return hosts
+}
+
+func (h *host) directives() []*nginx.Directive {
+	directives := make([]*nginx.Directive, 0)
+	names := make([]string, 0, len(h.upstreams))
+	for name := range h.upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		directives = append(directives, nginx.SimpleUpstream(name, h.upstreams[name]...))
+	}
+
+	server := nginx.NewDirective("server")
+	server.AddBody("listen", "80")
+	if h.secret != "" {
+		server.AddBody("listen", "443", "ssl")
+		server.AddBody("ssl_certificate", h.secret+".crt")
+		server.AddBody("ssl_certificate_key", h.secret+".key")
+	}
+	server.AddBody("server_name", h.name)
+	for _, l := range h.locations {
+		var location *nginx.Directive
+		if l.exact {
+			location = server.AddBody("location", "=", l.path)
+		} else {
+			location = server.AddBody("location", l.path)
+		}
+		location.AddBody("proxy_pass", "http://"+l.upstream)
+		location.AddBody("proxy_set_header", "Host", "$host")
+		location.AddBody("proxy_set_header", "X-Real-IP", "$remote_addr")
+		location.AddBody("proxy_set_header", "X-Forwarded-For", "$proxy_add_x_forwarded_for")
+		location.AddBody("proxy_set_header", "X-Forwarded-Proto", "$scheme")
+	}
+	return append(directives, server)
+}
+
+//生成的配置文件和证书文件
+func (s *Snapshot) Files() map[string][]byte {
+	files := map[string][]byte{}
+	for _, h := range s.hosts() {
+		out := bytes.NewBufferString("# generate by aginx ingress\n")
+		for _, directive := range h.directives() {
+			out.WriteString(directive.Pretty(0))
+			out.WriteString("\n")
+		}
+		files[fileName(h.name)] = out.Bytes()
+	}
+	for _, ingress := range s.Ingresses {
+		for _, tls := range ingress.Spec.TLS {
+			if secret, has := s.Secrets[key(ingress.Metadata.Namespace, tls.SecretName)]; has &&
+				len(secret.Data["tls.crt"]) > 0 && len(secret.Data["tls.key"]) > 0 {
+				name := fmt.Sprintf("%s/ssl/%s-%s", Dir, ingress.Metadata.Namespace, tls.SecretName)
+				files[name+".crt"] = secret.Data["tls.crt"]
+				files[name+".key"] = secret.Data["tls.key"]
+			}
+		}
+	}
+	return files
+}
@@ -0,0 +1,61 @@
+package ingress
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const ingressJson = `{
+	"metadata": {"name": "api", "namespace": "default", "annotations": {"kubernetes.io/ingress.class": "aginx"}},
+	"spec": {
+		"tls": [{"hosts": ["api.aginx.io"], "secretName": "api-tls"}],
+		"rules": [{
+			"host": "api.aginx.io",
+			"http": {"paths": [
+				{"path": "/", "pathType": "Prefix", "backend": {"service": {"name": "api", "port": {"number": 80}}}},
+				{"path": "/status", "pathType": "Exact", "backend": {"serviceName": "status", "servicePort": "http"}}
+			]}
+		}]
+	}
+}`
+
+const serviceJson = `{
+	"metadata": {"name": "api", "namespace": "default"},
+	"spec": {"ports": [{"name": "http", "port": 80, "targetPort": 8080}]}
+}`
+
+const endpointsJson = `{
+	"metadata": {"name": "api", "namespace": "default"},
+	"subsets": [{"addresses": [{"ip": "10.0.0.2"}, {"ip": "10.0.0.1"}], "ports": [{"name": "http", "port": 8080}]}]
+}`
+
+func TestSnapshot(t *testing.T) {
+	ingress, service, endpoints := new(Ingress), new(Service), new(Endpoints)
+	assert.Nil(t, json.Unmarshal([]byte(ingressJson), ingress))
+	assert.Nil(t, json.Unmarshal([]byte(serviceJson), service))
+	assert.Nil(t, json.Unmarshal([]byte(endpointsJson), endpoints))
+
+	assert.True(t, MatchClass(ingress, "aginx"))
+	assert.False(t, MatchClass(ingress, "nginx"))
+
+	snapshot := &Snapshot{
+		Ingresses: []*Ingress{ingress},
+		Services:  map[string]*Service{"default/api": service},
+		Endpoints: map[string]*Endpoints{"default/api": endpoints},
+		Secrets: map[string]*Secret{"default/api-tls": {
+			Data: map[string][]byte{"tls.crt": []byte("crt"), "tls.key": []byte("key")},
+		}},
+	}
+	files := snapshot.Files()
+	assert.Len(t, files, 3)
+	assert.Equal(t, "crt", string(files["ingress.d/ssl/default-api-tls.crt"]))
+
+	conf := string(files["ingress.d/api.aginx.io.ngx.conf"])
+	assert.Contains(t, conf, "upstream ingress_api_aginx_io_default_api_80 {\n    server 10.0.0.1:8080;\n    server 10.0.0.2:8080;\n}")
+	//服务不存在时使用不可用的地址
+	assert.Contains(t, conf, "upstream ingress_api_aginx_io_default_status_http {\n    server 127.0.0.1:65535;\n}")
+	assert.Contains(t, conf, "ssl_certificate ingress.d/ssl/default-api-tls.crt;")
+	assert.Contains(t, conf, "location = /status {")
+	assert.Contains(t, conf, "proxy_pass http://ingress_api_aginx_io_default_api_80;")
+}
@@ -0,0 +1,128 @@
+package ingress
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+//kubernetes资源，只定义了使用的字段，同时兼容 networking.k8s.io/v1 和 v1beta1 的 Ingress
+
+//端口可以是数字或者名称
+type IntOrString string
+
+func (is *IntOrString) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*is = IntOrString(strconv.Itoa(number))
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	*is = IntOrString(name)
+	return nil
+}
+
+func (is IntOrString) Int() (int, bool) {
+	number, err := strconv.Atoi(string(is))
+	return number, err == nil
+}
+
+type ObjectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type Ingress struct {
+	Metadata ObjectMeta  `json:"metadata"`
+	Spec     IngressSpec `json:"spec"`
+}
+
+type IngressSpec struct {
+	IngressClassName string          `json:"ingressClassName"`
+	DefaultBackend   *IngressBackend `json:"defaultBackend"` //v1
+	Backend          *IngressBackend `json:"backend"`        //v1beta1
+	TLS              []IngressTLS    `json:"tls"`
+	Rules            []IngressRule   `json:"rules"`
+}
+
+type IngressTLS struct {
+	Hosts      []string `json:"hosts"`
+	SecretName string   `json:"secretName"`
+}
+
+type IngressRule struct {
+	Host string `json:"host"`
+	HTTP *struct {
+		Paths []HTTPIngressPath `json:"paths"`
+	} `json:"http"`
+}
+
+type HTTPIngressPath struct {
+	Path     string         `json:"path"`
+	PathType string         `json:"pathType"`
+	Backend  IngressBackend `json:"backend"`
+}
+
+type IngressBackend struct {
+	//v1beta1
+	ServiceName string      `json:"serviceName"`
+	ServicePort IntOrString `json:"servicePort"`
+	//v1
+	Service *struct {
+		Name string `json:"name"`
+		Port struct {
+			Name   string `json:"name"`
+			Number int    `json:"number"`
+		} `json:"port"`
+	} `json:"service"`
+}
+
+func (b *IngressBackend) service() (name string, port IntOrString) {
+	if b.Service != nil {
+		name = b.Service.Name
+		if b.Service.Port.Name != "" {
+			port = IntOrString(b.Service.Port.Name)
+		} else {
+			port = IntOrString(strconv.Itoa(b.Service.Port.Number))
+		}
+		return
+	}
+	return b.ServiceName, b.ServicePort
+}
+
+type Service struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Ports []ServicePort `json:"ports"`
+	} `json:"spec"`
+}
+
+type ServicePort struct {
+	Name       string      `json:"name"`
+	Port       int         `json:"port"`
+	TargetPort IntOrString `json:"targetPort"`
+}
+
+type Endpoints struct {
+	Metadata ObjectMeta       `json:"metadata"`
+	Subsets  []EndpointSubset `json:"subsets"`
+}
+
+type EndpointSubset struct {
+	Addresses []struct {
+		IP string `json:"ip"`
+	} `json:"addresses"`
+	Ports []struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	} `json:"ports"`
+}
+
+type Secret struct {
+	Metadata ObjectMeta        `json:"metadata"`
+	Type     string            `json:"type"`
+	Data     map[string][]byte `json:"data"`
+}
@@ -13,6 +13,7 @@ import (
 type Account struct {
 	KeyType      certcrypto.KeyType
 	Email        string
+	Server       string `json:",omitempty"` //注册账户的ACME服务地址
 	Registration *registration.Resource
 	Key          string
 	privateKey   crypto.PrivateKey
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"github.com/go-acme/lego/v3/certcrypto"
 	"github.com/go-acme/lego/v3/lego"
-	"github.com/go-acme/lego/v3/registration"
 	"github.com/ihaiker/aginx/plugins"
 )
 
@@ -13,10 +12,14 @@ const accountDir = "lego/accounts"
 type AccountStorage struct {
 	store  map[string]*Account
 	engine plugins.StorageEngine
+	acme   *ACME
 }
 
+//查找在当前ACME服务上注册的账户
 func (acs *AccountStorage) Get(email string) (account *Account, has bool) {
-	account, has = acs.store[email]
+	if account, has = acs.store[email]; has && !acs.acme.registered(account) {
+		account, has = nil, false
+	}
 	return
 }
 
@@ -34,18 +37,16 @@ func (acs *AccountStorage) restore(email string) error {
 }
 
 func (acs *AccountStorage) registration(account *Account) error {
-	config := lego.NewConfig(account)
-	config.Certificate.KeyType = account.KeyType
-
-	client, err := lego.NewClient(config)
+	client, err := lego.NewClient(acs.acme.config(account))
 	if err != nil {
 		return err
 	}
 
-	reg, err := client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+	reg, err := acs.acme.register(client)
 	if err != nil {
 		return err
 	}
+	account.Server = acs.acme.Server
 	account.Registration = reg
 	return nil
 }
@@ -76,7 +77,7 @@ func (acs *AccountStorage) New(email string, keyType certcrypto.KeyType) (*Accou
 
 func LoadAccounts(engine plugins.StorageEngine) (accountStorage *AccountStorage, err error) {
 	accountStorage = &AccountStorage{
-		store: map[string]*Account{}, engine: engine,
+		store: map[string]*Account{}, engine: engine, acme: defaultACME,
 	}
 
 	files, err := engine.Search(accountDir + "/*.json")
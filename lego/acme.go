@@ -0,0 +1,84 @@
+package lego
+
+import (
+	"errors"
+	"github.com/go-acme/lego/v3/lego"
+	"github.com/go-acme/lego/v3/registration"
+	"os"
+	"strings"
+)
+
+//常用的ACME服务地址
+var acmeServers = map[string]string{
+	"letsencrypt":         lego.LEDirectoryProduction,
+	"letsencrypt-staging": lego.LEDirectoryStaging,
+	"zerossl":             "https://acme.zerossl.com/v2/DV90",
+	"buypass":             "https://api.buypass.com/acme/directory",
+	"buypass-staging":     "https://api.test4.buypass.no/acme/directory",
+}
+
+//需要 External Account Binding 的服务
+var eabRequired = map[string]bool{
+	"https://acme.zerossl.com/v2/DV90": true,
+}
+
+//ACME服务配置
+type ACME struct {
+	Server  string //ACME directory 地址
+	EABKid  string //External Account Binding key id
+	EABHmac string //External Account Binding hmac key (base64 url)
+}
+
+var defaultACME = &ACME{Server: lego.LEDirectoryProduction}
+
+//server 可以是ACME directory地址，或者：letsencrypt, letsencrypt-staging, zerossl, buypass, buypass-staging，
+//caFile 为内部ACME服务（例如：step-ca）使用的根证书
+func NewACME(server, eabKid, eabHmac, caFile string) (*ACME, error) {
+	if server == "" {
+		server = "letsencrypt"
+	}
+	if directory, has := acmeServers[strings.ToLower(server)]; has {
+		server = directory
+	} else if !strings.HasPrefix(server, "https://") && !strings.HasPrefix(server, "http://") {
+		return nil, errors.New("acme server not support: " + server)
+	}
+	if (eabKid == "") != (eabHmac == "") {
+		return nil, errors.New("the eab kid and hmac must be set together")
+	}
+	if eabRequired[server] && eabKid == "" {
+		return nil, errors.New("the acme server requires external account binding: " + server)
+	}
+	if caFile != "" {
+		//lego 使用此环境变量中的证书验证ACME服务
+		if err := os.Setenv("LEGO_CA_CERTIFICATES", caFile); err != nil {
+			return nil, err
+		}
+	}
+	return &ACME{Server: server, EABKid: eabKid, EABHmac: eabHmac}, nil
+}
+
+func (acme *ACME) config(account *Account) *lego.Config {
+	config := lego.NewConfig(account)
+	config.CADirURL = acme.Server
+	config.Certificate.KeyType = account.KeyType
+	return config
+}
+
+//注册账户，配置了EAB时使用EAB注册
+func (acme *ACME) register(client *lego.Client) (*registration.Resource, error) {
+	if acme.EABKid != "" {
+		return client.Registration.RegisterWithExternalAccountBinding(registration.RegisterEABOptions{
+			TermsOfServiceAgreed: true, Kid: acme.EABKid, HmacEncoded: acme.EABHmac,
+		})
+	}
+	return client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+}
+
+//账户是否是在此服务上注册的，没有记录服务地址的账户是在 Let's Encrypt 上注册的
+func (acme *ACME) registered(account *Account) bool {
+	server := account.Server
+	if server == "" {
+		server = lego.LEDirectoryProduction
+	}
+	return server == acme.Server
+}
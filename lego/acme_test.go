@@ -0,0 +1,38 @@
+package lego
+
+import (
+	"github.com/go-acme/lego/v3/lego"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewACME(t *testing.T) {
+	acme, err := NewACME("", "", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, lego.LEDirectoryProduction, acme.Server)
+
+	acme, err = NewACME("buypass", "", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.buypass.com/acme/directory", acme.Server)
+
+	acme, err = NewACME("https://ca.internal:9000/acme/acme/directory", "", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://ca.internal:9000/acme/acme/directory", acme.Server)
+
+	_, err = NewACME("zerossl", "", "", "")
+	assert.NotNil(t, err)
+	acme, err = NewACME("zerossl", "kid", "hmac", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "kid", acme.EABKid)
+
+	_, err = NewACME("letsencrypt", "kid", "", "")
+	assert.NotNil(t, err)
+	_, err = NewACME("unknown", "", "", "")
+	assert.NotNil(t, err)
+}
+
+func TestACMERegistered(t *testing.T) {
+	assert.True(t, defaultACME.registered(&Account{}))
+	assert.False(t, defaultACME.registered(&Account{Server: "https://api.buypass.com/acme/directory"}))
+	assert.True(t, (&ACME{Server: "https://api.buypass.com/acme/directory"}).registered(&Account{Server: "https://api.buypass.com/acme/directory"}))
+}
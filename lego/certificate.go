@@ -1,14 +1,18 @@
 package lego
 
 import (
+	"github.com/go-acme/lego/v3/certcrypto"
 	"github.com/go-acme/lego/v3/certificate"
 	"github.com/ihaiker/aginx/plugins"
+	"math"
+	"strings"
 	"time"
 )
 
 type Certificate struct {
 	ExpireTime time.Time `json:"expire"`
 	Email      string    `json:"email"`
+	Manual     bool      `json:"manual,omitempty"` //用户上传的证书，不会自动更新
 
 	*certificate.Resource
 
@@ -19,6 +23,11 @@ type Certificate struct {
 	PEM string `json:"pem"`
 }
 
+//证书保存使用的名称，泛域名 *.example.com 使用 wildcard_.example.com
+func storeName(domain string) string {
+	return strings.Replace(domain, "*", "wildcard_", 1)
+}
+
 type StoreFile struct {
 	Certificate       string `json:"certificate"`
 	IssuerCertificate string `json:"issuerCertificate"`
@@ -27,7 +36,7 @@ type StoreFile struct {
 }
 
 func (cfs *Certificate) GetStoreFile() *StoreFile {
-	storePath := certificateDir + "/" + cfs.Domain
+	storePath := certificateDir + "/" + storeName(cfs.Domain)
 	return &StoreFile{
 		Certificate:       storePath + "/server.crt",
 		IssuerCertificate: storePath + "/server.issuer.crt",
@@ -72,5 +81,36 @@ func (cfs *Certificate) LoadCertificate() error {
 		//.pem
 		cfs.PEM = cfs.Certificate + cfs.PrivateKey
 	}
+	cfs.LoadExpireTime()
 	return nil
 }
+
+//从证书中读取过期时间，读取失败时使用默认的三个月
+func (cfs *Certificate) LoadExpireTime() {
+	if cert, err := certcrypto.ParsePEMCertificate([]byte(cfs.Certificate)); err == nil {
+		cfs.ExpireTime = cert.NotAfter
+	} else if cfs.ExpireTime.IsZero() {
+		cfs.ExpireTime = time.Now().AddDate(0, 3, 0)
+	}
+}
+
+//距离过期的天数，已经过期时为负数
+func (cfs *Certificate) Days() int {
+	return int(math.Floor(time.Until(cfs.ExpireTime).Hours() / 24))
+}
+
+//证书过期信息
+type Expiry struct {
+	Domain   string    `json:"domain"`
+	Expire   time.Time `json:"expire"`
+	Days     int       `json:"days"` //距离过期的天数
+	Manual   bool      `json:"manual,omitempty"`
+	Expiring bool      `json:"expiring"` //是否在告警范围内
+}
+
+func (cfs *Certificate) Expiry(alertBefore time.Duration) *Expiry {
+	return &Expiry{
+		Domain: cfs.Domain, Expire: cfs.ExpireTime, Days: cfs.Days(), Manual: cfs.Manual,
+		Expiring: cfs.ExpireTime.Before(time.Now().Add(alertBefore)),
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/go-acme/lego/v3/lego"
 	"github.com/ihaiker/aginx/plugins"
 	"net"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -16,16 +18,57 @@ const certificateDir = "lego/certificates"
 type CertificateStorage struct {
 	data   map[string]*Certificate
 	engine plugins.StorageEngine
+	acme   *ACME
+	lock   sync.RWMutex
 }
 
 func (cfs *CertificateStorage) Get(domain string) (cert *Certificate, has bool) {
+	cfs.lock.RLock()
+	defer cfs.lock.RUnlock()
 	cert, has = cfs.data[domain]
 	return
 }
 
+//全部证书，按照域名排序
+func (cfs *CertificateStorage) List() []*Certificate {
+	cfs.lock.RLock()
+	defer cfs.lock.RUnlock()
+	certs := make([]*Certificate, 0, len(cfs.data))
+	for _, cert := range cfs.data {
+		certs = append(certs, cert)
+	}
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].Domain < certs[j].Domain
+	})
+	return certs
+}
+
+//查找可以用于domain的证书，不存在domain的证书时查找对应的泛域名证书
+func (cfs *CertificateStorage) Match(domain string) (cert *Certificate, has bool) {
+	if cert, has = cfs.Get(domain); has {
+		return
+	}
+	if wildcard := WildcardDomain(domain); wildcard != "" && wildcard != domain {
+		cert, has = cfs.Get(wildcard)
+	}
+	return
+}
+
 func (cfs *CertificateStorage) NewWithProvider(account *Account, domain string, provider challenge.Provider) (cert *Certificate, err error) {
-	config := lego.NewConfig(account)
-	config.Certificate.KeyType = account.KeyType
+	return cfs.obtain(account, domain, func(client *lego.Client) error {
+		return client.Challenge.SetHTTP01Provider(provider)
+	})
+}
+
+//使用DNS-01验证申请证书，泛域名证书（*.example.com）只能使用此方式
+func (cfs *CertificateStorage) NewWithDNSProvider(account *Account, domain string, provider challenge.Provider) (cert *Certificate, err error) {
+	return cfs.obtain(account, domain, func(client *lego.Client) error {
+		return client.Challenge.SetDNS01Provider(provider)
+	})
+}
+
+func (cfs *CertificateStorage) obtain(account *Account, domain string, setProvider func(client *lego.Client) error) (cert *Certificate, err error) {
+	config := cfs.acme.config(account)
 	config.Certificate.Timeout = time.Minute
 
 	var client *lego.Client
@@ -33,7 +76,7 @@ func (cfs *CertificateStorage) NewWithProvider(account *Account, domain string,
 		return
 	}
 
-	if err = client.Challenge.SetHTTP01Provider(provider); err != nil {
+	if err = setProvider(client); err != nil {
 		return
 	}
 
@@ -46,15 +89,29 @@ func (cfs *CertificateStorage) NewWithProvider(account *Account, domain string,
 	}
 
 	cert.Email = account.Email
-	cert.ExpireTime = time.Now().AddDate(0, 3, 0)
+	if err = cfs.save(cert); err != nil {
+		return nil, err
+	}
+	return
+}
 
+//保存证书文件和证书信息
+func (cfs *CertificateStorage) save(cert *Certificate) (err error) {
 	if _, err = cert.StoreFile(cfs.engine); err != nil {
-		return nil, err
+		return
 	}
 
+	domain := cert.Domain
+	cfs.lock.Lock()
+	defer cfs.lock.Unlock()
+	old, has := cfs.data[domain]
 	cfs.data[domain] = cert
-	if err = cfs.restore(domain); err != nil {
-		delete(cfs.data, domain)
+	if err = cfs.restore(cert); err != nil {
+		if has {
+			cfs.data[domain] = old
+		} else {
+			delete(cfs.data, domain)
+		}
 		return
 	}
 	return
@@ -69,9 +126,8 @@ func (cfs *CertificateStorage) New(account *Account, domain, address string) (*C
 	}
 }
 
-func (cfs *CertificateStorage) restore(domain string) error {
-	cert, _ := cfs.Get(domain)
-	file := certificateDir + "/" + domain + ".json"
+func (cfs *CertificateStorage) restore(cert *Certificate) error {
+	file := certificateDir + "/" + storeName(cert.Domain) + ".json"
 	bs, err := json.MarshalIndent(cert, "", "\t")
 	if err != nil {
 		return err
@@ -84,7 +140,7 @@ func (cfs *CertificateStorage) restore(domain string) error {
 
 func LoadCertificates(engine plugins.StorageEngine) (certificateStorage *CertificateStorage, err error) {
 	certificateStorage = &CertificateStorage{
-		data: map[string]*Certificate{}, engine: engine,
+		data: map[string]*Certificate{}, engine: engine, acme: defaultACME,
 	}
 
 	var files []*plugins.ConfigurationFile
@@ -98,6 +154,7 @@ func LoadCertificates(engine plugins.StorageEngine) (certificateStorage *Certifi
 		cert := new(Certificate)
 		err = json.Unmarshal(keyBytes, cert)
 		if err == nil {
+			cert.LoadExpireTime()
 			certificateStorage.data[cert.Domain] = cert
 		}
 		logrus.WithError(err).Debug("load certificate ", path)
@@ -0,0 +1,73 @@
+package lego
+
+import (
+	"fmt"
+	"github.com/go-acme/lego/v3/challenge"
+	"github.com/go-acme/lego/v3/providers/dns/alidns"
+	"github.com/go-acme/lego/v3/providers/dns/cloudflare"
+	"github.com/go-acme/lego/v3/providers/dns/dnspod"
+	"github.com/go-acme/lego/v3/providers/dns/route53"
+	"net/url"
+	"os"
+	"strings"
+)
+
+//DNS-01 验证支持的服务商，认证信息使用lego定义的环境变量，例如：CLOUDFLARE_DNS_API_TOKEN、AWS_ACCESS_KEY_ID、ALICLOUD_ACCESS_KEY、DNSPOD_API_KEY
+var dnsProviders = map[string]func() (challenge.Provider, error){
+	"cloudflare": func() (challenge.Provider, error) {
+		return cloudflare.NewDNSProvider()
+	},
+	"route53": func() (challenge.Provider, error) {
+		return route53.NewDNSProvider()
+	},
+	"alidns": func() (challenge.Provider, error) {
+		return alidns.NewDNSProvider()
+	},
+	"dnspod": func() (challenge.Provider, error) {
+		return dnspod.NewDNSProvider()
+	},
+}
+
+//创建DNS-01验证服务商，config格式：name[?ENV_KEY=value&...]，参数会设置到环境变量中，
+//例如：cloudflare?CLOUDFLARE_DNS_API_TOKEN=token
+func NewDNSProvider(config string) (challenge.Provider, error) {
+	name, query := config, ""
+	if idx := strings.Index(config, "?"); idx != -1 {
+		name, query = config[:idx], config[idx+1:]
+	}
+	newProvider, has := dnsProviders[strings.ToLower(name)]
+	if !has {
+		return nil, fmt.Errorf("dns provider not support: %s", name)
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	for key := range params {
+		if err = os.Setenv(key, params.Get(key)); err != nil {
+			return nil, err
+		}
+	}
+	return newProvider()
+}
+
+//是否是泛域名，例如：*.example.com
+func IsWildcard(domain string) bool {
+	return strings.HasPrefix(domain, "*.")
+}
+
+//子域名使用的泛域名，例如：api.example.com 使用 *.example.com，顶级域名 example.com 返回空
+func WildcardDomain(domain string) string {
+	if IsWildcard(domain) {
+		return domain
+	}
+	if idx := strings.Index(domain, "."); idx != -1 && strings.Contains(domain[idx+1:], ".") {
+		return "*" + domain[idx:]
+	}
+	return ""
+}
+
+//泛域名证书是否可以用于domain，泛域名只匹配一级子域名
+func MatchWildcard(wildcard, domain string) bool {
+	return IsWildcard(wildcard) && !IsWildcard(domain) && WildcardDomain(domain) == wildcard
+}
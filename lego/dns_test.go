@@ -0,0 +1,52 @@
+package lego
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestNewDNSProvider(t *testing.T) {
+	_, err := NewDNSProvider("unknown")
+	assert.NotNil(t, err)
+
+	provider, err := NewDNSProvider("cloudflare?CLOUDFLARE_DNS_API_TOKEN=token")
+	assert.Nil(t, err)
+	assert.NotNil(t, provider)
+	assert.Equal(t, "token", os.Getenv("CLOUDFLARE_DNS_API_TOKEN"))
+}
+
+func TestWildcard(t *testing.T) {
+	assert.True(t, IsWildcard("*.example.com"))
+	assert.False(t, IsWildcard("api.example.com"))
+	assert.Equal(t, "wildcard_.example.com", storeName("*.example.com"))
+	assert.Equal(t, "api.example.com", storeName("api.example.com"))
+}
+
+func TestWildcardDomain(t *testing.T) {
+	assert.Equal(t, "*.example.com", WildcardDomain("api.example.com"))
+	assert.Equal(t, "*.api.example.com", WildcardDomain("v1.api.example.com"))
+	assert.Equal(t, "*.example.com", WildcardDomain("*.example.com"))
+	assert.Equal(t, "", WildcardDomain("example.com"))
+
+	assert.True(t, MatchWildcard("*.example.com", "api.example.com"))
+	assert.False(t, MatchWildcard("*.example.com", "v1.api.example.com"))
+	assert.False(t, MatchWildcard("*.example.com", "example.com"))
+	assert.False(t, MatchWildcard("api.example.com", "api.example.com"))
+}
+
+func TestCertificateStorageMatch(t *testing.T) {
+	storage := &CertificateStorage{data: map[string]*Certificate{
+		"*.example.com": {Email: "wildcard"}, "api.example.com": {Email: "api"},
+	}}
+	cert, has := storage.Match("api.example.com")
+	assert.True(t, has)
+	assert.Equal(t, "api", cert.Email)
+
+	cert, has = storage.Match("web.example.com")
+	assert.True(t, has)
+	assert.Equal(t, "wildcard", cert.Email)
+
+	_, has = storage.Match("example.com")
+	assert.False(t, has)
+}
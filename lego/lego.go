@@ -1,15 +1,21 @@
 package lego
 
 import (
+	"github.com/go-acme/lego/v3/challenge"
 	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/util"
+	"sort"
 	"time"
 )
 
 type Manager struct {
 	AccountStorage     *AccountStorage
 	CertificateStorage *CertificateStorage
-	ticker             *time.Ticker
+	DNSProvider        challenge.Provider //DNS-01验证服务商，未设置时使用HTTP-01验证
+	Wildcard           bool               //使用DNS-01验证时子域名申请泛域名证书
+	RenewBefore        time.Duration      //证书过期前多久更新，默认30天
+	AlertBefore        time.Duration      //证书过期前多久发出告警事件，默认15天
+	closeC             chan struct{}
 
 	expireFunc func(domain string)
 }
@@ -22,10 +28,17 @@ func NewManager(engine plugins.StorageEngine) (manager *Manager, err error) {
 	if manager.CertificateStorage, err = LoadCertificates(engine); err != nil {
 		return
 	}
-	manager.ticker = time.NewTicker(time.Hour)
+	manager.RenewBefore = time.Hour * 24 * 30
+	manager.AlertBefore = time.Hour * 24 * 15
 	return
 }
 
+//设置申请证书使用的ACME服务，默认使用 Let's Encrypt
+func (manager *Manager) UseACME(acme *ACME) {
+	manager.AccountStorage.acme = acme
+	manager.CertificateStorage.acme = acme
+}
+
 func (manager *Manager) Expire(expireFunc func(domain string)) {
 	manager.expireFunc = expireFunc
 }
@@ -38,16 +51,55 @@ func (manager *Manager) applyForACertificate(domain string) {
 		manager.expireFunc(domain)
 	}
 }
+
+//检查证书过期时间，即将过期的证书重新申请
+func (manager *Manager) renewal() {
+	for _, certificate := range manager.CertificateStorage.List() {
+		if !certificate.Manual && certificate.ExpireTime.Before(time.Now().Add(manager.RenewBefore)) {
+			logrus.Infof("the certificate %s will expire at %s, renew it", certificate.Domain, certificate.ExpireTime.Format(time.RFC3339))
+			manager.applyForACertificate(certificate.Domain)
+		}
+	}
+}
+
+//全部证书的过期信息，按照过期时间排序
+func (manager *Manager) Expiries() []*Expiry {
+	expiries := make([]*Expiry, 0)
+	for _, certificate := range manager.CertificateStorage.List() {
+		expiries = append(expiries, certificate.Expiry(manager.AlertBefore))
+	}
+	sort.SliceStable(expiries, func(i, j int) bool {
+		return expiries[i].Expire.Before(expiries[j].Expire)
+	})
+	return expiries
+}
+
+//即将过期的证书（包括更新失败的）发出告警事件
+func (manager *Manager) alert() {
+	for _, expiry := range manager.Expiries() {
+		if expiry.Expiring {
+			logrus.Warnf("the certificate %s will expire in %d days", expiry.Domain, expiry.Days)
+			util.PublishEvent(util.EventCertExpiring, expiry.Domain, nil, expiry)
+		}
+	}
+}
+
+//每天检查一次证书过期时间，Stop后可以再次Start（集群中成为leader时启动，失去leader时停止）
 func (manager *Manager) Start() error {
+	closeC := make(chan struct{})
+	manager.closeC = closeC
 	go func() {
+		ticker := time.NewTicker(time.Hour * 24)
+		defer ticker.Stop()
+		manager.renewal()
+		manager.alert()
 		for {
 			select {
-			case <-manager.ticker.C:
-				for domain, certificate := range manager.CertificateStorage.data {
-					if certificate.ExpireTime.Before(time.Now().Add(time.Hour)) {
-						manager.applyForACertificate(domain)
-					}
-				}
+			case <-closeC:
+				return
+			case <-ticker.C:
+				manager.renewal()
+				manager.alert()
 			}
 		}
 	}()
@@ -55,8 +107,9 @@ func (manager *Manager) Start() error {
 }
 
 func (manager *Manager) Stop() error {
-	if manager.ticker != nil {
-		manager.ticker.Stop()
+	if manager.closeC != nil {
+		close(manager.closeC)
+		manager.closeC = nil
 	}
 	return nil
 }
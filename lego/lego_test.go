@@ -0,0 +1,44 @@
+package lego
+
+import (
+	"github.com/go-acme/lego/v3/certificate"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestManagerRenewal(t *testing.T) {
+	manager := &Manager{
+		RenewBefore: time.Hour * 24 * 30,
+		CertificateStorage: &CertificateStorage{data: map[string]*Certificate{
+			"a.aginx.io": {Resource: &certificate.Resource{Domain: "a.aginx.io"}, ExpireTime: time.Now().AddDate(0, 0, 10)},
+			"b.aginx.io": {Resource: &certificate.Resource{Domain: "b.aginx.io"}, ExpireTime: time.Now().AddDate(0, 0, 60)},
+		}},
+	}
+	renewals := make([]string, 0)
+	manager.Expire(func(domain string) {
+		renewals = append(renewals, domain)
+	})
+	manager.renewal()
+	assert.Equal(t, []string{"a.aginx.io"}, renewals)
+
+	certs := manager.CertificateStorage.List()
+	assert.Equal(t, "a.aginx.io", certs[0].Domain)
+	assert.Equal(t, 9, certs[0].Days())
+}
+
+func TestManagerExpiries(t *testing.T) {
+	manager := &Manager{
+		AlertBefore: time.Hour * 24 * 15,
+		CertificateStorage: &CertificateStorage{data: map[string]*Certificate{
+			"a.aginx.io": {Resource: &certificate.Resource{Domain: "a.aginx.io"}, ExpireTime: time.Now().AddDate(0, 0, 60)},
+			"b.aginx.io": {Resource: &certificate.Resource{Domain: "b.aginx.io"}, ExpireTime: time.Now().AddDate(0, 0, 5), Manual: true},
+		}},
+	}
+	expiries := manager.Expiries()
+	assert.Equal(t, "b.aginx.io", expiries[0].Domain)
+	assert.True(t, expiries[0].Expiring)
+	assert.True(t, expiries[0].Manual)
+	assert.Equal(t, 4, expiries[0].Days)
+	assert.False(t, expiries[1].Expiring)
+}
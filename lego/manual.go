@@ -0,0 +1,125 @@
+package lego
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/go-acme/lego/v3/certcrypto"
+	"github.com/go-acme/lego/v3/certificate"
+	"math/big"
+	"net"
+	"time"
+)
+
+var ErrManualCertificate = errors.New("manual certificate can not be renewed")
+
+//解析上传的证书和私钥，验证私钥和证书是否匹配，domain为空时使用证书中的第一个域名
+func ParseManual(domain string, certPEM, keyPEM []byte) (*Certificate, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("the certificate and private key do not match: %s", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if domain == "" {
+		if len(leaf.DNSNames) > 0 {
+			domain = leaf.DNSNames[0]
+		} else {
+			domain = leaf.Subject.CommonName
+		}
+	}
+	if domain == "" {
+		return nil, errors.New("the domain is empty")
+	}
+	if err = leaf.VerifyHostname(domain); err != nil && !IsWildcard(domain) {
+		return nil, err
+	}
+
+	cert := &Certificate{
+		Resource: &certificate.Resource{Domain: domain},
+		Manual:   true, ExpireTime: leaf.NotAfter,
+		Certificate: string(certPEM), PrivateKey: string(keyPEM),
+	}
+	cert.PEM = cert.Certificate + cert.PrivateKey
+	return cert, nil
+}
+
+//保存上传的证书，已经存在的证书将被替换
+func (cfs *CertificateStorage) Manual(domain string, certPEM, keyPEM []byte) (cert *Certificate, err error) {
+	if cert, err = ParseManual(domain, certPEM, keyPEM); err != nil {
+		return
+	}
+	if err = cfs.save(cert); err != nil {
+		return nil, err
+	}
+	return
+}
+
+//删除证书和证书文件
+func (cfs *CertificateStorage) Remove(domain string) error {
+	cfs.lock.Lock()
+	defer cfs.lock.Unlock()
+	cert, has := cfs.data[domain]
+	if !has {
+		return nil
+	}
+	file := cert.GetStoreFile()
+	for _, path := range []string{
+		file.Certificate, file.IssuerCertificate, file.PrivateKey, file.PEM,
+		certificateDir + "/" + storeName(domain) + ".json",
+	} {
+		if err := cfs.engine.Remove(path); err != nil {
+			logrus.WithError(err).Debug("remove certificate file ", path)
+		}
+	}
+	delete(cfs.data, domain)
+	return nil
+}
+
+//生成自签名证书，开发和测试环境使用
+func NewSelfSigned(domain string, validity time.Duration) (certPEM, keyPEM []byte, err error) {
+	privateKey, err := certcrypto.GeneratePrivateKey(certcrypto.EC256)
+	if err != nil {
+		return
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: domain, Organization: []string{"AGINX self-signed"}},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(domain); ip != nil {
+		template.DNSNames, template.IPAddresses = nil, []net.IP{ip}
+	}
+	signer := privateKey.(crypto.Signer)
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), privateKey)
+	if err != nil {
+		return
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(certcrypto.PEMBlock(privateKey))
+	return
+}
+
+//生成并保存自签名证书，保存为上传的证书，不会自动更新
+func (cfs *CertificateStorage) SelfSigned(domain string, validity time.Duration) (*Certificate, error) {
+	certPEM, keyPEM, err := NewSelfSigned(domain, validity)
+	if err != nil {
+		return nil, err
+	}
+	return cfs.Manual(domain, certPEM, keyPEM)
+}
@@ -0,0 +1,62 @@
+package lego
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newPair(t *testing.T, domains ...string) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: domains[0]}, DNSNames: domains,
+		NotBefore: time.Now(), NotAfter: time.Now().AddDate(1, 0, 0),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return
+}
+
+func TestParseManual(t *testing.T) {
+	certPEM, keyPEM := newPair(t, "api.aginx.io", "www.aginx.io")
+
+	cert, err := ParseManual("", certPEM, keyPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, "api.aginx.io", cert.Domain)
+	assert.True(t, cert.Manual)
+	assert.True(t, cert.Days() >= 364)
+
+	cert, err = ParseManual("www.aginx.io", certPEM, keyPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, "www.aginx.io", cert.Domain)
+
+	_, err = ParseManual("other.aginx.io", certPEM, keyPEM)
+	assert.NotNil(t, err)
+
+	_, otherKey := newPair(t, "api.aginx.io")
+	_, err = ParseManual("", certPEM, otherKey)
+	assert.NotNil(t, err)
+}
+
+func TestNewSelfSigned(t *testing.T) {
+	for _, domain := range []string{"foo.local", "*.foo.local", "127.0.0.1"} {
+		certPEM, keyPEM, err := NewSelfSigned(domain, time.Hour*24*30)
+		assert.Nil(t, err)
+		cert, err := ParseManual(domain, certPEM, keyPEM)
+		assert.Nil(t, err)
+		assert.Equal(t, domain, cert.Domain)
+		assert.Equal(t, 29, cert.Days())
+	}
+}
@@ -2,11 +2,14 @@ package logs
 
 import (
 	"github.com/sirupsen/logrus"
+	"io"
 	"os"
 )
 
 var loggers = make([]*logrus.Logger, 0)
 
+var output io.Writer = os.Stdout
+
 var STD = New("root")
 
 func SetLevel(level logrus.Level) {
@@ -16,6 +19,15 @@ func SetLevel(level logrus.Level) {
 	}
 }
 
+//修改全部日志的输出，windows服务没有控制台时输出到文件
+func SetOutput(out io.Writer) {
+	output = out
+	logrus.SetOutput(out)
+	for _, logger := range loggers {
+		logger.SetOutput(out)
+	}
+}
+
 func SetLogger(debug bool, level string) error {
 	if debug {
 		SetLevel(logrus.DebugLevel)
@@ -37,7 +49,7 @@ func NewLogger(module string, fns ...func(*FieldsHook, *logrus.Logger)) *logrus.
 	logger.SetFormatter(&Formatter{
 		TimestampFormat: "2006-01-02 15:04:05.000", FieldsOrder: []string{"module", "engine"},
 	})
-	logger.SetOutput(os.Stdout)
+	logger.SetOutput(output)
 	//logger.SetReportCaller(true)
 	hook := &FieldsHook{fields: map[string]interface{}{}}
 	hook.fields["module"] = module
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"time"
+)
+
+const namespace = "aginx"
+
+var logger = logs.New("metrics")
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "http_requests_total", Help: "The number of api requests.",
+	}, []string{"method", "route", "code"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "http_request_duration_seconds", Help: "The latency of api requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	reloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "nginx_reloads_total", Help: "The number of nginx reloads.",
+	}, []string{"result"})
+	reloadFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Name: "nginx_reload_failures_total", Help: "The number of failed nginx reloads.",
+	})
+	reloadDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "nginx_reload_duration_seconds", Help: "The duration of nginx reloads.",
+		Buckets: prometheus.DefBuckets,
+	})
+	reloadRollbacks = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Name: "nginx_reload_rollbacks_total", Help: "The number of configuration rollbacks after unhealthy reloads.",
+	})
+	testFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Name: "nginx_test_failures_total", Help: "The number of failed configuration tests (nginx -t).",
+	})
+
+	renewals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "certificate_renewals_total", Help: "The number of certificate renewals.",
+	}, []string{"result"})
+
+	externalEdits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Name: "external_edits_total", Help: "The number of local files changed outside aginx and not imported.",
+	})
+
+	syncConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "sync_conflicts_total", Help: "The number of files changed both in local and storage.",
+	}, []string{"policy"})
+
+	upstreamHealth = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "upstream_health_changes_total", Help: "The number of upstream servers removed or recovered by health checks.",
+	}, []string{"upstream", "state"})
+
+	syncEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "storage_sync_events_total", Help: "The number of configuration file changes.",
+	}, []string{"source", "type"})
+)
+
+func init() {
+	prometheus.MustRegister(requests, requestDuration,
+		reloads, reloadFailures, reloadDuration, reloadRollbacks, testFailures,
+		renewals, externalEdits, syncConflicts, upstreamHealth, syncEvents)
+}
+
+func result(event *util.Event) string {
+	if event.Error != "" {
+		return "failure"
+	}
+	return "success"
+}
+
+//记录api请求，route为匹配的路由
+func ObserveRequest(method, route string, code int, duration time.Duration) {
+	requests.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+	requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func observeEvent(event *util.Event) {
+	switch event.Name {
+	case util.EventReload:
+		reloads.WithLabelValues(result(event)).Inc()
+		if event.Error != "" {
+			reloadFailures.Inc()
+		}
+		if data, match := event.Data.(map[string]interface{}); match {
+			if duration, match := data["duration"].(float64); match {
+				reloadDuration.Observe(duration)
+			}
+		}
+	case util.EventReloadRollback:
+		reloadRollbacks.Inc()
+	case util.EventTestFailure:
+		testFailures.Inc()
+	case util.EventCertRenewal:
+		renewals.WithLabelValues(result(event)).Inc()
+	case util.EventExternalEdit:
+		externalEdits.Inc()
+	case util.EventUpstreamHealth:
+		if data, match := event.Data.(map[string]interface{}); match {
+			state := "unhealthy"
+			if healthy, _ := data["healthy"].(bool); healthy {
+				state = "healthy"
+			}
+			upstreamHealth.WithLabelValues(event.Message, state).Inc()
+		}
+	case util.EventSyncConflict:
+		if data, match := event.Data.(map[string]interface{}); match {
+			if policy, match := data["policy"].(string); match {
+				syncConflicts.WithLabelValues(policy).Inc()
+			}
+		}
+	}
+}
+
+func observeChanged(event *util.ChangeEvent) {
+	syncEvents.WithLabelValues(event.Source, event.Type).Inc()
+}
+
+//订阅nginx事件和配置变更事件，返回取消订阅的方法
+func Subscribe() (unsubscribe func()) {
+	unsubscribeEvent := util.SubscribeEvent(observeEvent)
+	unsubscribeChanged := util.SubscribeChanged(observeChanged)
+	return func() {
+		unsubscribeEvent()
+		unsubscribeChanged()
+	}
+}
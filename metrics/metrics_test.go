@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	util.PublishEvent(util.EventReload, "reload NGINX", nil, map[string]interface{}{"duration": 0.2})
+	util.PublishEvent(util.EventReload, "reload NGINX", errors.New("nginx: [emerg]"), map[string]interface{}{"duration": 0.1})
+	util.PublishEvent(util.EventCertRenewal, "api.aginx.io", errors.New("timeout"), nil)
+	util.PublishChanged(&util.ChangeEvent{Source: util.ChangeSourceCluster, File: "nginx.conf", Type: "update"})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(reloads.WithLabelValues("success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(reloads.WithLabelValues("failure")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(reloadFailures))
+	assert.Equal(t, 1.0, testutil.ToFloat64(renewals.WithLabelValues("failure")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(syncEvents.WithLabelValues("cluster", "update")))
+}
+
+func TestObserveRequest(t *testing.T) {
+	ObserveRequest("GET", "/api", 200, time.Millisecond*5)
+	assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues("GET", "/api", "200")))
+}
+
+func TestNginxCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	assert.Nil(t, registry.Register(&nginxCollector{status: func() (*nginx.StubStatus, error) {
+		return &nginx.StubStatus{Active: 3, Accepts: 10, Handled: 10, Requests: 20, Reading: 1, Writing: 1, Waiting: 1}, nil
+	}}))
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	values := map[string]float64{}
+	for _, family := range families {
+		metric := family.GetMetric()[0]
+		if family.GetType() == dto.MetricType_COUNTER {
+			values[family.GetName()] = metric.GetCounter().GetValue()
+		} else {
+			values[family.GetName()] = metric.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, 1.0, values["aginx_nginx_up"])
+	assert.Equal(t, 3.0, values["aginx_nginx_connections_active"])
+	assert.Equal(t, 20.0, values["aginx_nginx_requests_total"])
+
+	registry = prometheus.NewRegistry()
+	assert.Nil(t, registry.Register(&nginxCollector{status: func() (*nginx.StubStatus, error) {
+		return nil, nginx.ErrStubStatusDisabled
+	}}))
+	families, err = registry.Gather()
+	assert.Nil(t, err)
+	assert.Len(t, families, 1)
+	assert.Equal(t, 0.0, families[0].GetMetric()[0].GetGauge().GetValue())
+}
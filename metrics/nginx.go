@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func nginxDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "nginx", name), help, nil, nil)
+}
+
+var (
+	nginxUp       = nginxDesc("up", "Whether the nginx stub_status is scraped successfully.")
+	nginxActive   = nginxDesc("connections_active", "The number of active client connections.")
+	nginxReading  = nginxDesc("connections_reading", "The number of connections where nginx is reading the request header.")
+	nginxWriting  = nginxDesc("connections_writing", "The number of connections where nginx is writing the response.")
+	nginxWaiting  = nginxDesc("connections_waiting", "The number of idle client connections waiting for a request.")
+	nginxAccepts  = nginxDesc("connections_accepted_total", "The total number of accepted client connections.")
+	nginxHandled  = nginxDesc("connections_handled_total", "The total number of handled connections.")
+	nginxRequests = nginxDesc("requests_total", "The total number of client requests.")
+)
+
+//采集nginx stub_status的统计
+type nginxCollector struct {
+	status func() (*nginx.StubStatus, error)
+}
+
+func (nc *nginxCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{nginxUp, nginxActive, nginxReading, nginxWriting,
+		nginxWaiting, nginxAccepts, nginxHandled, nginxRequests} {
+		ch <- desc
+	}
+}
+
+func (nc *nginxCollector) Collect(ch chan<- prometheus.Metric) {
+	status, err := nc.status()
+	if err != nil {
+		logger.WithError(err).Debug("scrape nginx stub_status")
+		ch <- prometheus.MustNewConstMetric(nginxUp, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(nginxUp, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(nginxActive, prometheus.GaugeValue, float64(status.Active))
+	ch <- prometheus.MustNewConstMetric(nginxReading, prometheus.GaugeValue, float64(status.Reading))
+	ch <- prometheus.MustNewConstMetric(nginxWriting, prometheus.GaugeValue, float64(status.Writing))
+	ch <- prometheus.MustNewConstMetric(nginxWaiting, prometheus.GaugeValue, float64(status.Waiting))
+	ch <- prometheus.MustNewConstMetric(nginxAccepts, prometheus.CounterValue, float64(status.Accepts))
+	ch <- prometheus.MustNewConstMetric(nginxHandled, prometheus.CounterValue, float64(status.Handled))
+	ch <- prometheus.MustNewConstMetric(nginxRequests, prometheus.CounterValue, float64(status.Requests))
+}
+
+//每次采集时读取nginx的stub_status
+func RegisterStubStatus(status func() (*nginx.StubStatus, error)) error {
+	return prometheus.Register(&nginxCollector{status: status})
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+	"time"
+)
+
+var storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace, Name: "storage_operation_duration_seconds", Help: "The latency of storage operations.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"operation"})
+
+func init() {
+	prometheus.MustRegister(storageDuration)
+}
+
+//存储操作的耗时统计，单位毫秒
+type StorageStat struct {
+	Count  int64   `json:"count"`
+	Errors int64   `json:"errors"`
+	Avg    float64 `json:"avgMs"`
+	Max    float64 `json:"maxMs"`
+	Last   float64 `json:"lastMs"`
+	total  float64
+}
+
+var (
+	storageLock  sync.Mutex
+	storageStats = map[string]*StorageStat{}
+)
+
+func observeStorage(operation string, start time.Time, err error) {
+	duration := time.Since(start)
+	storageDuration.WithLabelValues(operation).Observe(duration.Seconds())
+
+	ms := float64(duration) / float64(time.Millisecond)
+	storageLock.Lock()
+	defer storageLock.Unlock()
+	stat, has := storageStats[operation]
+	if !has {
+		stat = new(StorageStat)
+		storageStats[operation] = stat
+	}
+	stat.Count++
+	if err != nil {
+		stat.Errors++
+	}
+	stat.total += ms
+	stat.Avg = stat.total / float64(stat.Count)
+	stat.Last = ms
+	if ms > stat.Max {
+		stat.Max = ms
+	}
+}
+
+//全部存储操作（get, search, put, remove）的耗时统计
+func StorageStats() map[string]StorageStat {
+	storageLock.Lock()
+	defer storageLock.Unlock()
+	stats := make(map[string]StorageStat, len(storageStats))
+	for operation, stat := range storageStats {
+		stats[operation] = *stat
+	}
+	return stats
+}
+
+//记录存储操作的耗时
+type storageEngine struct {
+	plugins.StorageEngine
+}
+
+func Engine(engine plugins.StorageEngine) plugins.StorageEngine {
+	return &storageEngine{StorageEngine: engine}
+}
+
+func (se *storageEngine) Get(file string) (cfgFile *plugins.ConfigurationFile, err error) {
+	defer func(start time.Time) { observeStorage("get", start, err) }(time.Now())
+	return se.StorageEngine.Get(file)
+}
+
+func (se *storageEngine) Search(pattern ...string) (files []*plugins.ConfigurationFile, err error) {
+	defer func(start time.Time) { observeStorage("search", start, err) }(time.Now())
+	return se.StorageEngine.Search(pattern...)
+}
+
+func (se *storageEngine) Put(file string, content []byte) (err error) {
+	defer func(start time.Time) { observeStorage("put", start, err) }(time.Now())
+	return se.StorageEngine.Put(file, content)
+}
+
+func (se *storageEngine) Remove(file string) (err error) {
+	defer func(start time.Time) { observeStorage("remove", start, err) }(time.Now())
+	return se.StorageEngine.Remove(file)
+}
+
+func (se *storageEngine) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &storageEngine{StorageEngine: plugins.WithContext(ctx, se.StorageEngine)}
+}
+
+func (se *storageEngine) Context() context.Context {
+	return plugins.Context(se.StorageEngine)
+}
+
+func (se *storageEngine) Start() error {
+	return util.StartService(se.StorageEngine)
+}
+
+func (se *storageEngine) Stop() error {
+	return util.StopService(se.StorageEngine)
+}
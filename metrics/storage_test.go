@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStorageStats(t *testing.T) {
+	engine := Engine(memory.Storage{})
+	assert.Nil(t, engine.Put("nginx.conf", []byte("events {}")))
+	_, err := engine.Get("nginx.conf")
+	assert.Nil(t, err)
+	_, err = engine.Get("hosts.d/none.conf")
+	assert.NotNil(t, err)
+
+	stats := StorageStats()
+	assert.Equal(t, int64(1), stats["put"].Count)
+	assert.Equal(t, int64(2), stats["get"].Count)
+	assert.Equal(t, int64(1), stats["get"].Errors)
+	assert.True(t, stats["get"].Max >= stats["get"].Last)
+}
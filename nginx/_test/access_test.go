@@ -0,0 +1,66 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAccessList(t *testing.T) {
+	addresses, err := nginx.ParseAccessList([]byte(`
+# spam
+10.0.0.1/8, 192.168.1.1
+2001:db8::/32 all # all
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "all"}, addresses)
+
+	_, err = nginx.ParseAccessList([]byte("10.0.0.256"))
+	assert.NotNil(t, err)
+	_, err = nginx.ParseAccessList([]byte("10.0.0.0/33"))
+	assert.NotNil(t, err)
+}
+
+func TestClientAccessRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+		deny 10.0.0.1;
+		server { listen 80; server_name aginx.io; allow 192.168.0.0/16; deny all; location / { root /var/www; } }
+	}`), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, nginx.ErrNotFound, client.AddAccessRules("none.aginx.io", nginx.AccessDeny, "10.0.0.2"))
+	assert.NotNil(t, client.AddAccessRules("", "block", "10.0.0.2"))
+
+	assert.Nil(t, client.AddAccessRules("", nginx.AccessDeny, "10.0.0.2", "10.0.0.3"))
+	//添加到 deny all 之前，已经存在的地址修改action
+	assert.Nil(t, client.AddAccessRules("aginx.io", nginx.AccessAllow, "172.16.0.0/12"))
+	assert.Nil(t, client.AddAccessRules("aginx.io", nginx.AccessDeny, "192.168.0.0/16"))
+
+	assert.Equal(t, []*nginx.AccessRule{
+		{Action: "deny", Address: "10.0.0.1"},
+		{Action: "deny", Address: "10.0.0.2"},
+		{Action: "deny", Address: "10.0.0.3"},
+		{Action: "deny", Address: "192.168.0.0/16", Domain: "aginx.io"},
+		{Action: "allow", Address: "172.16.0.0/12", Domain: "aginx.io"},
+		{Action: "deny", Address: "all", Domain: "aginx.io"},
+	}, client.AccessRules())
+
+	assert.Nil(t, client.RemoveAccessRules("", "10.0.0.1", "10.0.0.3"))
+	assert.Equal(t, nginx.ErrNotFound, client.RemoveAccessRules("", "10.0.0.1"))
+	assert.Nil(t, client.RemoveAccessRules("aginx.io", "all"))
+	assert.Equal(t, []*nginx.AccessRule{
+		{Action: "deny", Address: "10.0.0.2"},
+		{Action: "deny", Address: "192.168.0.0/16", Domain: "aginx.io"},
+		{Action: "allow", Address: "172.16.0.0/12", Domain: "aginx.io"},
+	}, client.AccessRules())
+}
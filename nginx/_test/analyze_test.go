@@ -0,0 +1,63 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`worker_processes 1;
+events {
+	worker_connections 4096;
+}
+http {
+	gzip on;
+	server_tokens off;
+	upstream backend {
+		server 127.0.0.1:8080;
+	}
+	server {
+		listen 443 ssl;
+		ssl_ciphers HIGH:!aNULL:RC4-SHA;
+	}
+}`), 0644))
+
+	analysis, err := nginx.Analyze(file.New(conf), 4)
+	assert.Nil(t, err)
+	checks := map[string]*nginx.Check{}
+	for _, check := range analysis.Checks {
+		checks[check.Name] = check
+	}
+	assert.Len(t, checks, 7)
+	assert.False(t, checks[nginx.CheckWorkerProcesses].Passed)
+	assert.Equal(t, 1, checks[nginx.CheckWorkerProcesses].Line)
+	assert.True(t, checks[nginx.CheckWorkerConnections].Passed)
+	assert.True(t, checks[nginx.CheckGzip].Passed)
+	assert.True(t, checks[nginx.CheckServerTokens].Passed)
+	assert.False(t, checks[nginx.CheckUpstreamKeepalive].Passed)
+	assert.Equal(t, 8, checks[nginx.CheckUpstreamKeepalive].Line)
+	assert.False(t, checks[nginx.CheckSslCiphers].Passed)
+	assert.Equal(t, "the ssl_ciphers contains weak cipher RC4-SHA, remove it or add !RC4", checks[nginx.CheckSslCiphers].Recommendation)
+	assert.False(t, checks[nginx.CheckClientBodySize].Passed)
+	assert.Equal(t, (5+15+5)*100/(10+5+15+15+20+10+5), analysis.Score)
+
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`worker_processes auto;
+http {
+	gzip on;
+	client_max_body_size 10m;
+	server_tokens off;
+}`), 0644))
+	analysis, err = nginx.Analyze(file.New(conf), 4)
+	assert.Nil(t, err)
+	assert.Len(t, analysis.Checks, 5)
+	assert.Equal(t, (10+15+10+5)*100/(10+5+15+10+5), analysis.Score)
+}
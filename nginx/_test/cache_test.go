@@ -0,0 +1,135 @@
+package nginx_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//记录读取和写入存储的次数
+type countEngine struct {
+	plugins.StorageEngine
+	gets int
+	puts []string
+}
+
+func (ce *countEngine) Get(name string) (*plugins.ConfigurationFile, error) {
+	ce.gets++
+	return ce.StorageEngine.Get(name)
+}
+
+func (ce *countEngine) Put(file string, content []byte) error {
+	ce.puts = append(ce.puts, file)
+	return ce.StorageEngine.Put(file, content)
+}
+
+func largeConfiguration(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "hosts.d"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "nginx.conf"), []byte(`http {
+    include hosts.d/*.conf;
+}
+`), 0644))
+	hosts := bytes.NewBufferString("")
+	for i := 0; i < 100; i++ {
+		_, _ = fmt.Fprintf(hosts, "upstream u%d {\n    server 127.0.0.1:%d;\n}\n", i, 8000+i)
+		_, _ = fmt.Fprintf(hosts, "server {\n    listen 80;\n    server_name s%d.aginx.io;\n}\n", i)
+	}
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "hosts.d", "hosts.conf"), hosts.Bytes(), 0644))
+	return filepath.Join(dir, "nginx.conf"), func() { _ = os.RemoveAll(dir) }
+}
+
+//直接修改查询到的指令后，之后的查询使用修改后的配置
+func TestSelectAfterEdit(t *testing.T) {
+	conf, cleanup := largeConfiguration(t)
+	defer cleanup()
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	hosts := []string{"http", "include('hosts.d/*.conf')", "file('hosts.d/hosts.conf')"}
+	upstream := client.MustSelect(append(hosts, "upstream('u42')")...)[0]
+	upstream.Args = []string{"backend"}
+	_, err = client.Select(append(hosts, "upstream('u42')")...)
+	assert.Equal(t, nginx.ErrNotFound, err)
+	assert.Len(t, client.MustSelect(append(hosts, "upstream('backend')")...), 1)
+
+	files := client.MustSelect(hosts...)
+	files[0].Body = append(files[0].Body, nginx.NewDirective("upstream", "u42"))
+	assert.Len(t, client.MustSelect(append(hosts, "upstream('u42')")...), 1)
+
+	assert.Nil(t, client.Delete(append(hosts, "upstream('u42')")...))
+	_, err = client.Select(append(hosts, "upstream('u42')")...)
+	assert.Equal(t, nginx.ErrNotFound, err)
+	assert.Nil(t, client.NewUpstream(&nginx.Upstream{Name: "u100", Servers: []*nginx.UpstreamServer{{Address: "127.0.0.1:8100"}}}))
+	assert.Len(t, client.MustSelect("http", "upstream('u100')"), 1)
+}
+
+func TestParseCache(t *testing.T) {
+	conf, cleanup := largeConfiguration(t)
+	defer cleanup()
+	first, err := nginx.Readable(file.New(conf))
+	assert.Nil(t, err)
+	second, err := nginx.Readable(file.New(conf))
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	//缓存的结果复制后使用，修改不会影响其他的配置
+	first.MustSelect("http", "include", "*", "server")[0].AddBody("root", "/var/www")
+	third, err := nginx.Readable(file.New(conf))
+	assert.Nil(t, err)
+	assert.Equal(t, second, third)
+
+	//只重新解析修改的文件
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(filepath.Dir(conf), "hosts.d", "hosts.conf"),
+		[]byte("server {\n    server_name changed.aginx.io;\n}\n"), 0644))
+	changed, err := nginx.Readable(file.New(conf))
+	assert.Nil(t, err)
+	assert.Len(t, changed.MustSelect("http", "include", "*", "server"), 1)
+}
+
+func TestStoreChangedFiles(t *testing.T) {
+	conf, cleanup := largeConfiguration(t)
+	defer cleanup()
+	engine := &countEngine{StorageEngine: file.New(conf)}
+	client, err := nginx.NewClient("", engine, nil, nil)
+	assert.Nil(t, err)
+
+	//没有修改的文件不需要读取存储比较，也不会写入
+	engine.gets = 0
+	assert.Empty(t, client.Changes())
+	assert.Nil(t, client.Store())
+	assert.Equal(t, 0, engine.gets)
+	assert.Empty(t, engine.puts)
+
+	//只写入修改过的文件，保存前读取修改过的文件检查是否被其他请求修改
+	server := client.MustSelect("http", "include", "*", "server.server_name('s1.aginx.io')")[0]
+	server.AddBody("root", "/var/www")
+	assert.Equal(t, []string{"hosts.d/hosts.conf"}, client.Changes())
+	assert.Equal(t, 0, engine.gets)
+	assert.Nil(t, client.Store())
+	assert.Equal(t, 1, engine.gets)
+	assert.Equal(t, []string{"hosts.d/hosts.conf"}, engine.puts)
+	content, err := ioutil.ReadFile(filepath.Join(filepath.Dir(conf), "hosts.d", "hosts.conf"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "root /var/www;")
+
+	engine.gets, engine.puts = 0, nil
+	assert.Empty(t, client.Changes())
+	assert.Nil(t, client.Store())
+	assert.Equal(t, 0, engine.gets)
+	assert.Empty(t, engine.puts)
+
+	//新增的文件和存储中的内容比较
+	engine.gets = 0
+	assert.Nil(t, client.SimpleServer("api.aginx.io", false, "127.0.0.1:8080"))
+	assert.Equal(t, []string{"hosts.d/api.aginx.io.ngx.conf"}, client.Changes())
+	assert.Equal(t, 1, engine.gets)
+}
@@ -0,0 +1,33 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestUseNginx(t *testing.T) {
+	defer func() { _ = nginx.UseNginx("local") }()
+
+	assert.Nil(t, nginx.UseNginx("docker://nginx?conf=/etc/nginx/nginx.conf"))
+	assert.True(t, nginx.InDocker())
+	assert.Equal(t, "/etc/nginx/nginx.conf", nginx.MustConf())
+
+	assert.Nil(t, nginx.UseNginx("local"))
+	assert.False(t, nginx.InDocker())
+
+	assert.NotNil(t, nginx.UseNginx("docker://"))
+	assert.NotNil(t, nginx.UseNginx("ssh://nginx"))
+}
+
+func TestUseNginxFile(t *testing.T) {
+	defer func() { _ = nginx.UseNginx("local") }()
+
+	assert.Nil(t, nginx.UseNginx("file:///etc/nginx/nginx.conf"))
+	assert.Equal(t, filepath.FromSlash("/etc/nginx/nginx.conf"), nginx.MustConf())
+
+	assert.Nil(t, nginx.UseNginx("file:///C:/nginx/conf/nginx.conf"))
+	assert.Equal(t, filepath.FromSlash("C:/nginx/conf/nginx.conf"), nginx.MustConf())
+	assert.False(t, nginx.InDocker())
+}
@@ -0,0 +1,27 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	storage := map[string][]byte{
+		"nginx.conf":       []byte("user nginx;\nworker_processes 1;\n"),
+		"hosts.d/api.conf": []byte("server {}\n"),
+		"hosts.d/old.conf": []byte("server {}\n"),
+	}
+	local := map[string][]byte{
+		"nginx.conf":       []byte("user nginx;\nworker_processes 4;\n"),
+		"hosts.d/api.conf": []byte("server {}\n"),
+		"hosts.d/new.conf": []byte("server {}\n"),
+	}
+	diffs := nginx.DiffFiles(storage, local)
+	assert.Len(t, diffs, 3)
+	assert.Equal(t, &nginx.FileDiff{File: "hosts.d/new.conf", Status: nginx.DiffAdded, Diff: "+server {}\n"}, diffs[0])
+	assert.Equal(t, &nginx.FileDiff{File: "hosts.d/old.conf", Status: nginx.DiffRemoved, Diff: "-server {}\n"}, diffs[1])
+	assert.Equal(t, &nginx.FileDiff{File: "nginx.conf", Status: nginx.DiffModified, Diff: "-worker_processes 1;\n+worker_processes 4;\n"}, diffs[2])
+
+	assert.Len(t, nginx.DiffFiles(local, local), 0)
+}
@@ -0,0 +1,127 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIncludeTree(t *testing.T) {
+	file := func(name string, body ...*nginx.Directive) *nginx.Directive {
+		return &nginx.Directive{Virtual: nginx.Include, Name: "file", Args: []string{name}, Body: body}
+	}
+	include := func(pattern string, files ...*nginx.Directive) *nginx.Directive {
+		return &nginx.Directive{Name: "include", Args: []string{pattern}, Body: files}
+	}
+	conf := &nginx.Configuration{Name: "nginx.conf", Body: []*nginx.Directive{
+		include("modules.d/*.conf"),
+		{Name: "http", Body: []*nginx.Directive{
+			include("mime.types", file("mime.types")),
+			include("hosts.d/*.conf",
+				file("hosts.d/api.conf", &nginx.Directive{Name: "server", Body: []*nginx.Directive{
+					include("snippets/ssl.conf", file("snippets/ssl.conf")),
+				}}),
+				file("hosts.d/web.conf"),
+			),
+		}},
+	}}
+
+	assert.Equal(t, &nginx.IncludeFile{Name: "nginx.conf", Includes: []*nginx.IncludeFile{
+		{Name: "mime.types", Include: "mime.types"},
+		{Name: "hosts.d/api.conf", Include: "hosts.d/*.conf", Includes: []*nginx.IncludeFile{
+			{Name: "snippets/ssl.conf", Include: "snippets/ssl.conf"},
+		}},
+		{Name: "hosts.d/web.conf", Include: "hosts.d/*.conf"},
+	}}, nginx.IncludeTree(conf))
+}
+
+func TestPathReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "api.crt"), []byte("cert"), 0666))
+
+	conf := &nginx.Configuration{Name: "nginx.conf", Body: []*nginx.Directive{
+		{Name: "include", Args: []string{"modules.d/*.conf"}},
+		{Name: "http", Body: []*nginx.Directive{
+			{Name: "include", Args: []string{"mime.types"}},
+			{Name: "include", Args: []string{"hosts.d/*.conf"}, Body: []*nginx.Directive{
+				{Virtual: nginx.Include, Name: "file", Args: []string{"hosts.d/api.conf"}, Body: []*nginx.Directive{
+					{Name: "server", Body: []*nginx.Directive{
+						nginx.NewDirective("ssl_certificate", "api.crt"),
+						nginx.NewDirective("ssl_certificate_key", "/etc/ssl/api.key"),
+						nginx.NewDirective("root", "/var/www/$host"),
+					}},
+				}},
+			}},
+		}},
+	}}
+
+	refs := nginx.PathReferences(conf, dir)
+	assert.Len(t, refs, 5)
+	assert.Equal(t, &nginx.PathReference{
+		File: "nginx.conf", Directive: "include modules.d/*.conf",
+		Path: filepath.Join(dir, "modules.d/*.conf"), Exists: true, Inside: true,
+	}, refs[0])
+	assert.Equal(t, &nginx.PathReference{
+		File: "nginx.conf", Directive: "include mime.types",
+		Path: filepath.Join(dir, "mime.types"), Exists: false, Inside: true,
+	}, refs[1])
+	assert.Equal(t, &nginx.PathReference{
+		File: "hosts.d/api.conf", Directive: "ssl_certificate api.crt",
+		Path: filepath.Join(dir, "api.crt"), Exists: true, Inside: true,
+	}, refs[3])
+	assert.Equal(t, "/etc/ssl/api.key", refs[4].Path)
+	assert.False(t, refs[4].Inside)
+}
+
+func TestIncludeGraph(t *testing.T) {
+	file := func(name string, body ...*nginx.Directive) *nginx.Directive {
+		return &nginx.Directive{Virtual: nginx.Include, Name: "file", Args: []string{name}, Body: body}
+	}
+	include := func(pattern string, files ...*nginx.Directive) *nginx.Directive {
+		return &nginx.Directive{Name: "include", Args: []string{pattern}, Body: files}
+	}
+	server := func(name, listen string, body ...*nginx.Directive) *nginx.Directive {
+		return &nginx.Directive{Name: "server", Body: append([]*nginx.Directive{
+			nginx.NewDirective("listen", listen), nginx.NewDirective("server_name", name),
+		}, body...)}
+	}
+	conf := &nginx.Configuration{Name: "nginx.conf", Body: []*nginx.Directive{
+		{Name: "http", Body: []*nginx.Directive{
+			include("hosts.d/*.conf",
+				file("hosts.d/api.conf",
+					&nginx.Directive{Name: "upstream", Args: []string{"api"}},
+					server("api.aginx.io", "443 ssl", include("snippets/ssl.conf", file("snippets/ssl.conf"))),
+				),
+				file("hosts.d/web.conf", server("web.aginx.io", "443 ssl", include("snippets/ssl.conf", file("snippets/ssl.conf")))),
+			),
+		}},
+		{Name: "stream", Body: []*nginx.Directive{
+			{Name: "server", Body: []*nginx.Directive{nginx.NewDirective("listen", "53", "udp")}},
+		}},
+	}}
+
+	assert.Equal(t, &nginx.IncludeGraph{
+		Root: "nginx.conf",
+		Files: []*nginx.GraphFile{
+			{Name: "nginx.conf", Servers: []*nginx.GraphServer{{Context: "stream", Listen: []string{"53 udp"}}}},
+			{Name: "hosts.d/api.conf", Upstreams: []string{"api"}, Servers: []*nginx.GraphServer{
+				{Context: "http", ServerName: []string{"api.aginx.io"}, Listen: []string{"443 ssl"}},
+			}},
+			{Name: "snippets/ssl.conf"},
+			{Name: "hosts.d/web.conf", Servers: []*nginx.GraphServer{
+				{Context: "http", ServerName: []string{"web.aginx.io"}, Listen: []string{"443 ssl"}},
+			}},
+		},
+		Edges: []*nginx.GraphEdge{
+			{From: "nginx.conf", To: "hosts.d/api.conf", Include: "hosts.d/*.conf", Context: "http"},
+			{From: "hosts.d/api.conf", To: "snippets/ssl.conf", Include: "snippets/ssl.conf", Context: "server"},
+			{From: "nginx.conf", To: "hosts.d/web.conf", Include: "hosts.d/*.conf", Context: "http"},
+			{From: "hosts.d/web.conf", To: "snippets/ssl.conf", Include: "snippets/ssl.conf", Context: "server"},
+		},
+	}, nginx.NewIncludeGraph(conf))
+}
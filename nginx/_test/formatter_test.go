@@ -0,0 +1,58 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	formatted, err := nginx.Format("nginx.conf", []byte(`
+
+# main configuration
+worker_processes   auto;
+
+
+http {
+  map $http_upgrade $connection_upgrade { # websocket
+    default upgrade;
+    '' close;
+  }
+  server {
+
+    listen 80;  # http
+
+
+    location / { proxy_pass http://127.0.0.1:8080; }
+    # end of server
+
+  }
+}`))
+	assert.Nil(t, err)
+	expected := `# main configuration
+worker_processes auto;
+
+http {
+    map $http_upgrade $connection_upgrade { # websocket
+        default upgrade;
+        ''      close;
+    }
+    server {
+        listen 80; # http
+
+        location / {
+            proxy_pass http://127.0.0.1:8080;
+        }
+        # end of server
+    }
+}
+`
+	assert.Equal(t, expected, string(formatted))
+
+	again, err := nginx.Format("nginx.conf", formatted)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(again))
+
+	_, err = nginx.Format("nginx.conf", []byte("http {"))
+	assert.NotNil(t, err)
+}
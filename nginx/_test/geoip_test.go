@@ -0,0 +1,62 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientGeoPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+		server { listen 80; server_name api.aginx.io; location / { proxy_pass http://api; } }
+	}`), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	policy := &nginx.GeoPolicy{Domain: "api.aginx.io", Block: []string{"ru", "kp"}, Routes: map[string]string{"cn": "api_cn"}, Default: "api"}
+	assert.Equal(t, nginx.ErrGeoNotConfigured, client.SetGeoPolicy(policy))
+
+	changed, err := client.SetGeoDatabase("/var/lib/aginx/GeoLite2-Country.mmdb")
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	changed, _ = client.SetGeoDatabase("/var/lib/aginx/GeoLite2-Country.mmdb")
+	assert.False(t, changed)
+	assert.Equal(t, "/var/lib/aginx/GeoLite2-Country.mmdb", client.GeoDatabase())
+
+	assert.Equal(t, nginx.ErrNotFound, client.SetGeoPolicy(&nginx.GeoPolicy{Domain: "none.aginx.io", Block: []string{"RU"}}))
+	assert.NotNil(t, client.SetGeoPolicy(&nginx.GeoPolicy{Domain: "api.aginx.io", Block: []string{"RUS"}}))
+	assert.NotNil(t, client.SetGeoPolicy(&nginx.GeoPolicy{Domain: "api.aginx.io", Routes: map[string]string{"CN": "api_cn"}}))
+
+	assert.Nil(t, client.SetGeoPolicy(policy))
+	//再次设置不会重复添加
+	assert.Nil(t, client.SetGeoPolicy(policy))
+	assert.Equal(t, []string{"RU", "KP"}, policy.Block)
+
+	maps := client.MustSelect("http", "map")
+	assert.Len(t, maps, 2)
+	assert.Equal(t, []string{nginx.GeoCountry, "$geo_block_api_aginx_io"}, maps[0].Args)
+	assert.Equal(t, "KP", maps[0].Body[1].Name)
+	assert.Equal(t, []string{"1"}, maps[0].Body[1].Args)
+	assert.Equal(t, []string{"api"}, maps[1].MustSelect("default")[0].Args)
+	assert.Equal(t, []string{"api_cn"}, maps[1].MustSelect("CN")[0].Args)
+
+	server := client.MustSelect("http", "server")[0]
+	assert.Equal(t, "if", server.Body[0].Name)
+	assert.Equal(t, []string{"403"}, server.Body[0].MustSelect("return")[0].Args)
+	assert.Equal(t, []string{"http://$geo_route_api_aginx_io"}, server.MustSelect("location", "proxy_pass")[0].Args)
+
+	assert.Nil(t, client.DeleteGeoPolicy(policy))
+	_, err = client.Select("http", "map")
+	assert.NotNil(t, err)
+	assert.Len(t, client.MustSelect("http", "server")[0].Body, 3)
+	assert.Equal(t, []string{"http://api"}, client.MustSelect("http", "server", "location", "proxy_pass")[0].Args)
+}
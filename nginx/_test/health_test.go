@@ -0,0 +1,57 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//恢复上一次正常的配置时，配置目录中不是aginx管理的文件保持不变
+func TestRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	read := func(name string) string {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		return string(content)
+	}
+
+	write("nginx.conf", "events {}\nhttp {\n\tinclude conf.d/*.conf;\n}\n")
+	write("conf.d/a.conf", "server {\n\tlisten 80;\n}\n")
+	write("ssl/a.pem", "certificate a")
+	good := map[string][]byte{}
+	for _, name := range []string{"nginx.conf", "conf.d/a.conf", "ssl/a.pem"} {
+		good[filepath.Join(dir, name)] = []byte(read(name))
+	}
+
+	//nginx加载的配置修改后，其他人在配置目录中添加了文件
+	write("conf.d/a.conf", "server {\n\tlisten 81;\n}\n")
+	write("conf.d/b.conf", "server {\n\tlisten 82;\n}\n")
+	write("conf.d/a.conf.bak", "server {\n\tlisten 80;\n}\n")
+	write("ssl/b.pem", "certificate b")
+	write("ssl/a.pem", "certificate a renewed")
+
+	engine := file.New(filepath.Join(dir, "nginx.conf"))
+	assert.Nil(t, nginx.Restore(engine, dir, good))
+
+	assert.Equal(t, "server {\n\tlisten 80;\n}\n", read("conf.d/a.conf"))
+	_, err = os.Stat(filepath.Join(dir, "conf.d/b.conf"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Equal(t, "server {\n\tlisten 80;\n}\n", read("conf.d/a.conf.bak"))
+	assert.Equal(t, "certificate b", read("ssl/b.pem"))
+	assert.Equal(t, "certificate a renewed", read("ssl/a.pem"))
+}
@@ -0,0 +1,66 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClientHtpasswd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+		server { listen 80; server_name api.aginx.io; location / { proxy_pass http://api; } location /admin { proxy_pass http://admin; } }
+	}`), 0644))
+
+	engine := file.New(conf)
+	client, err := nginx.NewClient("", engine, nil, nil)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, nginx.SetHtpasswdUser(engine, "../admin", "haiker", "123456"))
+	assert.NotNil(t, nginx.SetHtpasswdUser(engine, "admin", "hai:ker", "123456"))
+	assert.Nil(t, nginx.SetHtpasswdUser(engine, "admin", "haiker", "123456"))
+	assert.Nil(t, nginx.SetHtpasswdUser(engine, "admin", "aginx", "123456"))
+	//修改密码
+	assert.Nil(t, nginx.SetHtpasswdUser(engine, "admin", "haiker", "654321"))
+
+	list, err := nginx.ListHtpasswd(engine)
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "admin", list[0].Name)
+	assert.Equal(t, []string{"haiker", "aginx"}, list[0].Users)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "htpasswd", "admin"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "haiker:{SSHA}"))
+
+	assert.Equal(t, nginx.ErrNotFound, nginx.DeleteHtpasswdUser(engine, "admin", "none"))
+	assert.Nil(t, nginx.DeleteHtpasswdUser(engine, "admin", "aginx"))
+
+	assert.NotNil(t, client.SetAuthBasic("api.aginx.io", "/admin", "", "none"))
+	assert.Equal(t, nginx.ErrNotFound, client.SetAuthBasic("none.aginx.io", "/admin", "", "admin"))
+	assert.Nil(t, client.SetAuthBasic("api.aginx.io", "/admin", "Admin", "admin"))
+	//再次设置替换原来的配置
+	assert.Nil(t, client.SetAuthBasic("api.aginx.io", "/admin", "", "admin"))
+
+	location := client.MustSelect("http", "server", "location('/admin')")[0]
+	assert.Equal(t, []string{`"Restricted"`}, location.MustSelect("auth_basic")[0].Args)
+	assert.Equal(t, []string{"htpasswd/admin"}, location.MustSelect("auth_basic_user_file")[0].Args)
+	assert.Len(t, location.Body, 3)
+
+	err = client.DeleteHtpasswd("admin")
+	assert.True(t, strings.Contains(err.Error(), nginx.ErrHtpasswdInUse.Error()))
+
+	assert.Nil(t, client.RemoveAuthBasic("api.aginx.io", "/admin"))
+	assert.Len(t, location.Body, 1)
+	assert.Nil(t, client.DeleteHtpasswd("admin"))
+	list, _ = nginx.ListHtpasswd(engine)
+	assert.Len(t, list, 0)
+}
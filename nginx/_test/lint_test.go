@@ -0,0 +1,128 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0755))
+
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+http {
+	server {
+		listen 80;
+		server_name api.aginx.io;
+	}
+	include conf.d/*.conf;
+}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "conf.d", "api.conf"), []byte(`server {
+	listen 80;
+	server_name api.aginx.io www.aginx.io;
+	location /api/ {
+		proxy_pass http://backend/v1;
+	}
+	location /web/ {
+		proxy_pass http://backend/web/;
+	}
+}
+server {
+	listen 443 ssl;
+	server_name api.aginx.io;
+	location /static {
+		proxy_pass http://backend/;
+	}
+}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "conf.d", "www.conf"), []byte(`server {
+	listen 443 ssl;
+	server_name www.aginx.io;
+	ssl_protocols TLSv1.1 TLSv1.2;
+}`), 0644))
+
+	lints, err := nginx.LintConfiguration(file.New(conf))
+	assert.Nil(t, err)
+	assert.Equal(t, []*nginx.Lint{
+		{Rule: nginx.LintDuplicateServerName, File: "conf.d/api.conf", Line: 3,
+			Message: "server_name api.aginx.io on port 80 is already defined at nginx.conf:5, this server is ignored"},
+		{Rule: nginx.LintProxyPassSlash, File: "conf.d/api.conf", Line: 5,
+			Message: "the trailing slash of location /api/ and proxy_pass http://backend/v1 is inconsistent, /api/index.html is proxied as /v1index.html"},
+		{Rule: nginx.LintSslProtocols, File: "conf.d/api.conf", Line: 11,
+			Message: "server api.aginx.io enables ssl without ssl_protocols"},
+		{Rule: nginx.LintProxyPassSlash, File: "conf.d/api.conf", Line: 15,
+			Message: "the trailing slash of location /static and proxy_pass http://backend/ is inconsistent, /static/index.html is proxied as //index.html"},
+		{Rule: nginx.LintSslInsecure, File: "conf.d/www.conf", Line: 4,
+			Message: "ssl_protocols TLSv1.1 is insecure, use TLSv1.2 TLSv1.3"},
+	}, lints)
+
+	lints, err = nginx.LintConfiguration(file.New(conf), nginx.LintProxyPassSlash, nginx.LintSslInsecure)
+	assert.Nil(t, err)
+	assert.Len(t, lints, 2)
+}
+
+func TestLintContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`gzip on;
+http {
+	upstream backend {
+		server 127.0.0.1:8080;
+		keepalive 16;
+	}
+	server {
+		listen 80;
+		proxy_pass http://backend;
+		if ($host = "www.aginx.io") {
+			return 301 https://aginx.io;
+			root /var/www;
+		}
+		location / {
+			if ($request_method = POST) {
+				root /var/www;
+			}
+			listen 8080;
+		}
+	}
+}
+stream {
+	server {
+		listen 53;
+		proxy_pass dns;
+	}
+}`), 0644))
+
+	lints, err := nginx.LintConfiguration(file.New(conf), nginx.LintDuplicateServerName)
+	assert.Nil(t, err)
+	assert.Equal(t, []*nginx.Lint{
+		{Rule: nginx.LintInvalidContext, File: "nginx.conf", Line: 1,
+			Message: "gzip is not allowed in main, allowed in: http, server, location, if in location"},
+		{Rule: nginx.LintInvalidContext, File: "nginx.conf", Line: 9,
+			Message: "proxy_pass is not allowed in server, allowed in: location, if in location, limit_except"},
+		{Rule: nginx.LintInvalidContext, File: "nginx.conf", Line: 12,
+			Message: "root is not allowed in if in server, allowed in: http, server, location, if in location"},
+		{Rule: nginx.LintInvalidContext, File: "nginx.conf", Line: 18,
+			Message: "listen is not allowed in location, allowed in: server"},
+	}, lints)
+}
+
+func TestDirectiveDoc(t *testing.T) {
+	doc := nginx.GetDirectiveDoc("proxy_pass")
+	assert.Equal(t, "ngx_http_proxy_module", doc.Module)
+	assert.Equal(t, "https://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_pass", doc.URL)
+	assert.True(t, doc.Allowed("if in location"))
+	assert.False(t, doc.Allowed("server"))
+	assert.True(t, nginx.GetDirectiveDoc("return").Allowed("if in server"))
+	assert.Equal(t, "https://nginx.org/en/docs/ngx_core_module.html#worker_processes", nginx.GetDirectiveDoc("worker_processes").URL)
+	assert.Nil(t, nginx.GetDirectiveDoc("none"))
+	assert.Contains(t, nginx.DirectiveDocNames(), "gzip")
+}
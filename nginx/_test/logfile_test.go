@@ -0,0 +1,29 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLogDirs(t *testing.T) {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+error_log logs/error.log;
+http {
+	access_log /var/log/nginx/access.log main;
+	server {
+		access_log off;
+		error_log syslog:server=127.0.0.1;
+		location / {
+			access_log /data/logs/$host/access.log;
+			access_log /data/sites/$host.log;
+		}
+	}
+}
+`)))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/data/sites", "/usr/share/nginx/logs", "/var/log/nginx"},
+		nginx.LogDirs(conf, "/usr/share/nginx"))
+	assert.Equal(t, []string{"/data/sites", "/var/log/nginx"}, nginx.LogDirs(conf, ""))
+}
@@ -0,0 +1,62 @@
+package nginx_test
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientWAF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("http { server { listen 80; server_name waf.aginx.io; } }"), 0644))
+
+	engine := file.New(conf)
+	client, err := nginx.NewClient("", engine, nil, nil)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, nginx.PutWAFRule(engine, "../crs.conf", []byte("")))
+	assert.Nil(t, nginx.PutWAFRule(engine, "custom.conf", []byte(`SecRule ARGS "@contains attack" "id:1000,deny,status:403"`)))
+	rules, err := nginx.ListWAFRules(engine)
+	assert.Nil(t, err)
+	assert.Equal(t, []*nginx.WAFRule{{Name: "custom.conf", Size: 57}}, rules)
+
+	assert.NotNil(t, client.SetWAF(&nginx.WAF{Domain: "waf.aginx.io", Mode: "off"}))
+	assert.NotNil(t, client.SetWAF(&nginx.WAF{Domain: "waf.aginx.io", Rules: []string{"crs/main.conf"}}))
+	assert.True(t, errors.Is(client.SetWAF(&nginx.WAF{Domain: "waf.aginx.io", Rules: []string{"none.conf"}}), nginx.ErrNotFound))
+	assert.Equal(t, nginx.ErrNotFound, client.SetWAF(&nginx.WAF{Domain: "none.aginx.io"}))
+
+	assert.Nil(t, client.SetWAF(&nginx.WAF{Domain: "waf.aginx.io", Mode: nginx.WAFDetectionOnly,
+		Rules: []string{"/etc/nginx/owasp-crs/crs-setup.conf", "custom.conf"}}))
+	server := client.MustSelect("http", "server")[0]
+	assert.Equal(t, []string{"on"}, server.MustSelect("modsecurity")[0].Args)
+	assert.Len(t, server.MustSelect("modsecurity_rules_file"), 2)
+	assert.Equal(t, []string{"'SecRuleEngine DetectionOnly'"}, server.MustSelect("modsecurity_rules")[0].Args)
+
+	wafs := client.WAFs()
+	assert.Equal(t, []*nginx.WAF{{Domain: "waf.aginx.io", Mode: nginx.WAFDetectionOnly,
+		Rules: []string{"/etc/nginx/owasp-crs/crs-setup.conf", "custom.conf"}}}, wafs)
+
+	//只修改模式，使用原来的规则文件
+	assert.Nil(t, client.SetWAF(&nginx.WAF{Domain: "waf.aginx.io", Mode: nginx.WAFBlocking}))
+	assert.Equal(t, []*nginx.WAF{{Domain: "waf.aginx.io", Mode: nginx.WAFBlocking,
+		Rules: []string{"/etc/nginx/owasp-crs/crs-setup.conf", "custom.conf"}}}, client.WAFs())
+	assert.Len(t, client.MustSelect("http", "server", "modsecurity"), 1)
+
+	assert.Equal(t, []string{"waf.aginx.io"}, client.WAFRuleUsages("custom.conf"))
+	assert.True(t, errors.Is(client.DeleteWAFRule("custom.conf"), nginx.ErrWAFRuleInUse))
+
+	assert.Nil(t, client.RemoveWAF("waf.aginx.io"))
+	assert.Len(t, client.WAFs(), 0)
+	assert.Equal(t, nginx.ErrNotFound, client.RemoveWAF("waf.aginx.io"))
+	assert.Nil(t, client.DeleteWAFRule("custom.conf"))
+	_, err = nginx.GetWAFRule(engine, "custom.conf")
+	assert.True(t, os.IsNotExist(err))
+}
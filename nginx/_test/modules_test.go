@@ -0,0 +1,104 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const nginxV = `nginx version: nginx/1.17.8
+built by gcc 8.3.0 (Debian 8.3.0-6)
+built with OpenSSL 1.1.1d  10 Sep 2019
+TLS SNI support enabled
+configure arguments: --prefix=/etc/nginx --sbin-path=/usr/sbin/nginx --conf-path=/etc/nginx/nginx.conf --with-compat --with-http_ssl_module --with-stream=dynamic --without-http_grpc_module --with-cc-opt='-g -O2'`
+
+func TestParseInfo(t *testing.T) {
+	info, err := nginx.ParseInfo(nginxV)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.17.8", info.Version)
+	assert.Equal(t, "/usr/sbin/nginx", info.Binary)
+	assert.Equal(t, "/etc/nginx/nginx.conf", info.Conf)
+	assert.Equal(t, "OpenSSL 1.1.1d  10 Sep 2019", info.OpenSSL)
+	assert.Equal(t, []string{"http_ssl"}, info.Modules)
+	assert.Equal(t, []string{"stream"}, info.Dynamic)
+	assert.Equal(t, []string{"http_grpc"}, info.Without)
+
+	assert.True(t, info.Supports("http_ssl"))
+	assert.True(t, info.Supports("http_proxy"))
+	assert.False(t, info.Supports("http_grpc"))
+	assert.False(t, info.Supports("http_v2"))
+	assert.False(t, info.Supports("stream"))
+	assert.True(t, info.Supports("stream", "modules/ngx_stream_module.so"))
+
+	_, err = nginx.ParseInfo("nginx: command not found")
+	assert.NotNil(t, err)
+}
+
+func TestInfoModSecurity(t *testing.T) {
+	info, err := nginx.ParseInfo(nginxV + " --add-dynamic-module=/usr/src/ModSecurity-nginx")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stream", "http_modsecurity"}, info.Dynamic)
+	assert.False(t, info.Supports("http_modsecurity"))
+	assert.True(t, info.Supports("http_modsecurity", "modules/ngx_http_modsecurity_module.so"))
+
+	//使用 --with-compat 单独编译的动态模块
+	info, _ = nginx.ParseInfo(nginxV)
+	assert.False(t, info.Supports("http_modsecurity"))
+	assert.True(t, info.Supports("http_modsecurity", "/usr/lib/nginx/modules/ngx_http_modsecurity_module.so"))
+}
+
+func TestInfoAtLeast(t *testing.T) {
+	info := &nginx.Info{Version: "1.25.1"}
+	assert.True(t, info.AtLeast("1.25.1"))
+	assert.True(t, info.AtLeast("1.25"))
+	assert.True(t, info.AtLeast("1.9.5"))
+	assert.False(t, info.AtLeast("1.25.2"))
+	assert.False(t, info.AtLeast("1.100"))
+}
+
+func TestInfoCheck(t *testing.T) {
+	info, err := nginx.ParseInfo(nginxV)
+	assert.Nil(t, err)
+
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+http {
+	server {
+		listen 443 ssl http2;
+		server_name api.example.com;
+		location / {
+			grpc_pass grpc://127.0.0.1:9000;
+		}
+	}
+}
+stream {
+	server {
+		listen 3306;
+		proxy_pass 127.0.0.1:3307;
+	}
+}`)))
+	assert.Nil(t, err)
+	err = info.Check(conf)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "'listen 443 ssl http2' requires module http_v2")
+	assert.Contains(t, err.Error(), "'grpc_pass grpc://127.0.0.1:9000' requires module http_grpc")
+	assert.Contains(t, err.Error(), "'stream' requires module stream")
+	assert.NotContains(t, err.Error(), "http_ssl")
+
+	conf, err = nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+load_module modules/ngx_stream_module.so;
+http {
+	server {
+		listen 443 ssl;
+		ssl_certificate server.crt;
+	}
+}
+stream {
+	server {
+		listen 3306;
+		proxy_pass 127.0.0.1:3307;
+	}
+}`)))
+	assert.Nil(t, err)
+	assert.Nil(t, info.Check(conf))
+}
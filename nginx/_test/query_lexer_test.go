@@ -3,6 +3,7 @@ package nginx_test
 import (
 	"github.com/ihaiker/aginx/nginx"
 	"github.com/kr/pretty"
+	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
@@ -24,3 +25,61 @@ func TestLexer(t *testing.T) {
 	}
 	_, _ = pretty.Println(expr)
 }
+
+func TestQueryMatch(t *testing.T) {
+	http := nginx.NewDirective("http")
+	for _, name := range []string{"a.example.com", "b.example.com", "aginx.io"} {
+		server := http.AddBody("server")
+		server.AddBody("server_name", name)
+		server.AddBody("listen", "80")
+	}
+
+	directives, err := http.Select("server.server_name(~`.*\\.example\\.com`)")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(directives))
+
+	directives, err = http.Select("server.server_name('aginx.io' | 'b.example.com')")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(directives))
+
+	directives, err = http.Select("server.[!server_name(~`example`) & listen('80')]")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(directives))
+	assert.Equal(t, "aginx.io", directives[0].Body[0].Args[0])
+}
+
+func TestQueryInvalidRegexp(t *testing.T) {
+	http := nginx.NewDirective("http")
+	http.AddBody("server").AddBody("server_name", "aginx.io")
+
+	_, err := nginx.Parser("server.server_name(~`aginx.(io`)")
+	assert.NotNil(t, err)
+	_, err = http.Select("server.server_name(~`aginx.(io`)")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, nginx.ErrNotFound, err)
+	assert.Contains(t, err.Error(), "invalid regular expression")
+
+	directives, err := http.Select("server.server_name(~`aginx\\.(io|com)`)")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(directives))
+}
+
+func TestQueryIndex(t *testing.T) {
+	upstream := nginx.NewDirective("upstream", "backend")
+	for _, address := range []string{"127.0.0.1:8080", "127.0.0.1:8081", "127.0.0.1:8082"} {
+		upstream.AddBody("server", address)
+	}
+	http := nginx.NewDirective("http")
+	http.AddBodyDirective(upstream)
+
+	directives, err := http.Select("upstream('backend')", "server[2]")
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:8082", directives[0].Args[0])
+
+	directives, err = http.Select("upstream('backend')", "server[-1]")
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:8082", directives[0].Args[0])
+
+	_, err = http.Select("upstream('backend')", "server[3]")
+	assert.Equal(t, nginx.ErrNotFound, err)
+}
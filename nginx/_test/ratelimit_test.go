@@ -0,0 +1,79 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRateLimitZone(t *testing.T) {
+	directive := nginx.NewDirective("limit_req_zone", "$binary_remote_addr", "zone=api:10m", "rate=10r/s")
+	zone := nginx.ParseRateLimitZone(directive)
+	assert.Equal(t, &nginx.RateLimitZone{
+		Name: "api", Key: "$binary_remote_addr", Size: "10m", Rate: "10r/s", Limits: []*nginx.RateLimit{},
+	}, zone)
+	assert.Equal(t, directive.Args, zone.Directive().Args)
+
+	assert.Nil(t, (&nginx.RateLimit{Zone: "api", Rate: "60r/m", Domain: "aginx.io", Location: "/api"}).Validate())
+	for _, limit := range []*nginx.RateLimit{
+		{Zone: "api zone"}, {Zone: "api", Rate: "10"}, {Zone: "api", Size: "big"},
+		{Zone: "api", Burst: -1}, {Zone: "api", Location: "/api"},
+	} {
+		assert.NotNil(t, limit.Validate(), limit)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+		limit_req_zone $binary_remote_addr zone=global:10m rate=100r/s;
+		limit_req zone=global burst=50;
+		server { listen 80; server_name aginx.io www.aginx.io; location / { root /var/www; } location /api { proxy_pass http://api; } }
+	}`), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	zones := client.RateLimits()
+	assert.Len(t, zones, 1)
+	assert.Equal(t, []*nginx.RateLimit{{Zone: "global", Burst: 50}}, zones[0].Limits)
+
+	//新的区域必须设置rate
+	assert.NotNil(t, client.SetRateLimit(&nginx.RateLimit{Zone: "api", Domain: "aginx.io", Location: "/api"}))
+	assert.Equal(t, nginx.ErrNotFound, client.SetRateLimit(&nginx.RateLimit{Zone: "api", Rate: "10r/s", Domain: "none.aginx.io"}))
+
+	limit := &nginx.RateLimit{Zone: "api", Rate: "10r/s", Domain: "www.aginx.io", Location: "/api", Burst: 20, NoDelay: true}
+	assert.Nil(t, client.SetRateLimit(limit))
+	limit.Burst = 5
+	assert.Nil(t, client.SetRateLimit(limit))
+	assert.Nil(t, client.SetRateLimit(&nginx.RateLimit{Zone: "global", Rate: "200r/s", Size: "20m"}))
+
+	zones = client.RateLimits()
+	assert.Len(t, zones, 2)
+	assert.Equal(t, &nginx.RateLimitZone{
+		Name: "global", Key: "$binary_remote_addr", Size: "20m", Rate: "200r/s",
+		Limits: []*nginx.RateLimit{{Zone: "global"}},
+	}, zones[0])
+	assert.Equal(t, &nginx.RateLimitZone{
+		Name: "api", Key: "$binary_remote_addr", Size: "10m", Rate: "10r/s",
+		Limits: []*nginx.RateLimit{{Zone: "api", Domain: "aginx.io", Location: "/api", Burst: 5, NoDelay: true}},
+	}, zones[1])
+
+	assert.Equal(t, nginx.ErrNotFound, client.DeleteRateLimit("api", "aginx.io", "/"))
+	assert.Nil(t, client.DeleteRateLimit("api", "www.aginx.io", "/api"))
+	assert.Len(t, client.RateLimits()[1].Limits, 0)
+
+	assert.Nil(t, client.DeleteRateLimit("global", "", ""))
+	zones = client.RateLimits()
+	assert.Len(t, zones, 1)
+	assert.Equal(t, "api", zones[0].Name)
+	_, err = client.Select("http", "limit_req")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,68 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientRedirects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+		server { listen 80; server_name www.aginx.io; location / { proxy_pass http://api; } }
+	}`), 0644))
+
+	engine := file.New(conf)
+	client, err := nginx.NewClient("", engine, nil, nil)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, client.SetRedirects([]*nginx.Redirect{{Domain: "www.aginx.io", Path: "promo", To: "/"}}))
+	assert.NotNil(t, client.SetRedirects([]*nginx.Redirect{{Domain: "www.aginx.io", Path: "/promo", To: "/", Code: 307}}))
+	assert.NotNil(t, client.SetRedirects([]*nginx.Redirect{
+		{Domain: "www.aginx.io", Path: "/promo", To: "/a"}, {Domain: "WWW.aginx.io", Path: "/promo", To: "/b"},
+	}))
+
+	redirects := []*nginx.Redirect{
+		{Domain: "www.aginx.io", Path: "/promo", To: "https://www.aginx.io/activity", Code: 302},
+		{Domain: "old.aginx.io", To: "https://www.aginx.io/"},
+	}
+	assert.Nil(t, client.SetRedirects(redirects))
+	//再次设置不会重复添加
+	assert.Nil(t, client.SetRedirects(redirects))
+	assert.Equal(t, 301, redirects[1].Code)
+
+	file := client.MustSelect("http", "include('redirects.ngx.conf')", "file('redirects.ngx.conf')")[0]
+	maps := file.MustSelect("map")
+	assert.Len(t, maps, 2)
+	assert.Equal(t, []string{"$host$uri", "$aginx_redirect_301"}, maps[0].Args)
+	assert.Equal(t, `"~^old\.aginx\.io/"`, maps[0].Body[1].Name)
+	assert.Equal(t, []string{`"https://www.aginx.io$request_uri"`}, maps[0].Body[1].Args)
+	assert.Equal(t, `"www.aginx.io/promo"`, maps[1].Body[1].Name)
+	assert.Equal(t, []string{`"https://www.aginx.io/activity"`}, maps[1].Body[1].Args)
+
+	//没有server的域名
+	servers := file.MustSelect("server")
+	assert.Len(t, servers, 1)
+	assert.Equal(t, []string{"old.aginx.io"}, servers[0].MustSelect("server_name")[0].Args)
+
+	server := client.MustSelect("http", "server")[0]
+	assert.Len(t, server.Body, 5)
+	assert.Equal(t, "if", server.Body[0].Name)
+	assert.Equal(t, []string{"301", "$aginx_redirect_301"}, server.Body[0].MustSelect("return")[0].Args)
+
+	assert.Nil(t, nginx.SaveRedirects(engine, redirects))
+	loaded, err := nginx.LoadRedirects(engine)
+	assert.Nil(t, err)
+	assert.Equal(t, redirects, loaded)
+
+	assert.Nil(t, client.SetRedirects(nil))
+	assert.Len(t, file.Body, 0)
+	assert.Len(t, server.Body, 3)
+}
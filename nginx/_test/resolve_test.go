@@ -0,0 +1,86 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+http {
+	map $http_user_agent $is_bot {
+		default 0;
+		~*(googlebot|bingbot) 1;
+	}
+	map $host $tenant {
+		hostnames;
+		default none;
+		*.aginx.io $is_bot-wildcard;
+		api.aginx.io api;
+	}
+	server {
+		listen 80 default_server;
+		server_name _;
+		return 444;
+	}
+	server {
+		listen 80;
+		server_name api.aginx.io *.aginx.io;
+		location / {
+			proxy_pass http://web;
+		}
+		location ^~ /static/ {
+			root /var/www;
+		}
+		location /api/ {
+			proxy_pass http://api;
+			location ~ \.json$ {
+				default_type application/json;
+			}
+		}
+		location = /health {
+			return 200;
+		}
+		location ~* \.(png|jpg)$ {
+			expires 7d;
+		}
+	}
+}`)))
+	assert.Nil(t, err)
+
+	resolve := func(host, uri string, headers map[string]string) *nginx.Resolution {
+		resolution, err := nginx.Resolve(conf, &nginx.ResolveRequest{Host: host, Uri: uri, Headers: headers})
+		assert.Nil(t, err)
+		return resolution
+	}
+
+	resolution := resolve("API.aginx.io:80", "/api/users?page=1", nil)
+	assert.Equal(t, &nginx.ResolvedServer{File: "nginx.conf", Match: "exact",
+		ServerName: []string{"api.aginx.io *.aginx.io"}, Listen: []string{"80"}}, resolution.Server)
+	assert.Equal(t, &nginx.ResolvedLocation{File: "nginx.conf", Path: "/api/", Match: "prefix",
+		Directives: []string{"proxy_pass http://api;"}}, resolution.Location)
+	assert.Equal(t, []*nginx.ResolvedMap{
+		{File: "nginx.conf", Variable: "$is_bot", Source: "", Matched: "default", Value: "0"},
+		{File: "nginx.conf", Variable: "$tenant", Source: "api.aginx.io", Matched: "api.aginx.io", Value: "api"},
+	}, resolution.Maps)
+
+	resolution = resolve("www.aginx.io", "/api/users.json", map[string]string{"User-Agent": "Mozilla/5.0 (compatible; Googlebot/2.1)"})
+	assert.Equal(t, "wildcard", resolution.Server.Match)
+	assert.Equal(t, "regex", resolution.Location.Match)
+	assert.Equal(t, `\.json$`, resolution.Location.Path)
+	assert.Equal(t, "1-wildcard", resolution.Maps[1].Value)
+	assert.Equal(t, "*.aginx.io", resolution.Maps[1].Matched)
+
+	assert.Equal(t, "exact", resolve("api.aginx.io", "/health", nil).Location.Match)
+	assert.Equal(t, "/static/", resolve("api.aginx.io", "/static/logo.png", nil).Location.Path)
+	assert.Equal(t, `\.(png|jpg)$`, resolve("api.aginx.io", "/images/logo.PNG", nil).Location.Path)
+	assert.Equal(t, "/", resolve("api.aginx.io", "/index.html", nil).Location.Path)
+
+	resolution = resolve("unknown.io", "/", nil)
+	assert.Equal(t, "default_server", resolution.Server.Match)
+	assert.Nil(t, resolution.Location)
+	assert.Equal(t, "no location matches uri /", resolution.Explain[1])
+	assert.Equal(t, "none", resolution.Maps[1].Value)
+}
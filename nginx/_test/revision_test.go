@@ -0,0 +1,80 @@
+package nginx_test
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreConflict(t *testing.T) {
+	conf, cleanup := largeConfiguration(t)
+	defer cleanup()
+	first, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+	second, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Revision(), second.Revision())
+
+	first.MustSelect("http", "include", "*", "server.server_name('s1.aginx.io')")[0].AddBody("root", "/var/www")
+	assert.Nil(t, first.Store())
+	assert.NotEqual(t, first.Revision(), second.Revision())
+
+	//读取后文件被修改，不会覆盖
+	second.MustSelect("http", "include", "*", "server.server_name('s2.aginx.io')")[0].AddBody("root", "/var/www")
+	err = second.Store()
+	assert.True(t, errors.Is(err, nginx.ErrConflict))
+	content, err := ioutil.ReadFile(filepath.Join(filepath.Dir(conf), "hosts.d", "hosts.conf"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "s1.aginx.io;\n    root /var/www;")
+	assert.NotContains(t, string(content), "s2.aginx.io;\n    root /var/www;")
+
+	//没有修改的文件不检查
+	second, err = nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Revision(), second.Revision())
+	first.MustSelect("http")[0].AddBody("server_tokens", "off")
+	assert.Nil(t, first.Store())
+	second.MustSelect("http", "include", "*", "server.server_name('s2.aginx.io')")[0].AddBody("root", "/var/www")
+	assert.Nil(t, second.Store())
+}
+
+//读取时不存在的文件，保存前被其他请求创建了
+func TestStoreCreatedConflict(t *testing.T) {
+	conf, cleanup := largeConfiguration(t)
+	defer cleanup()
+	first, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+	second, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	assert.Nil(t, second.SimpleServer("api.aginx.io", false, "127.0.0.1:8080"))
+	assert.Nil(t, second.Store())
+	assert.Nil(t, first.SimpleServer("api.aginx.io", false, "127.0.0.1:8081"))
+	err = first.Store()
+	assert.True(t, errors.Is(err, nginx.ErrConflict))
+	content, err := ioutil.ReadFile(filepath.Join(filepath.Dir(conf), "hosts.d", "api.aginx.io.ngx.conf"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "127.0.0.1:8080")
+}
+
+func TestIfMatch(t *testing.T) {
+	conf, cleanup := largeConfiguration(t)
+	defer cleanup()
+	engine := file.New(conf)
+	cfgFile, err := engine.Get(nginx.NGINX_CONF)
+	assert.Nil(t, err)
+	revision := nginx.FileRevision(cfgFile.Content)
+
+	put := func(engine plugins.StorageEngine) error { return engine.Put(nginx.NGINX_CONF, []byte("events {}\n")) }
+	err = nginx.IfMatch(engine, nginx.NGINX_CONF, func(current string) bool { return current == "none" }, put)
+	assert.Equal(t, nginx.ErrConflict, err)
+	assert.Nil(t, nginx.IfMatch(engine, nginx.NGINX_CONF, func(current string) bool { return current == revision }, put))
+	err = nginx.IfMatch(engine, nginx.NGINX_CONF, func(current string) bool { return current == revision }, put)
+	assert.Equal(t, nginx.ErrConflict, err)
+	assert.Nil(t, nginx.IfMatch(engine, nginx.NGINX_CONF, nil, put))
+}
@@ -0,0 +1,99 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+//server中的listen，包括include的文件
+func serverListens(directive *nginx.Directive) []string {
+	listens := make([]string, 0)
+	for _, child := range directive.Body {
+		if child.Name == "listen" {
+			listens = append(listens, child.Args[0])
+		} else if child.Name == "include" || child.Virtual == nginx.Include {
+			listens = append(listens, serverListens(child)...)
+		}
+	}
+	return listens
+}
+
+//server_name对应的server的listen
+func servers(directive *nginx.Directive, found map[string][]string) map[string][]string {
+	for _, child := range directive.Body {
+		if child.Name == "server" {
+			for _, name := range child.Body {
+				if name.Name == "server_name" {
+					found[name.Args[0]] = serverListens(child)
+				}
+			}
+		} else {
+			servers(child, found)
+		}
+	}
+	return found
+}
+
+//没有listen的server默认监听80端口，沙箱中也要替换为随机端口
+func TestSandboxConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	for name, content := range map[string]string{
+		"conf.d/c.conf":       "server {\n\tserver_name c.aginx.io;\n}\n",
+		"conf.d/d.conf":       "server {\n\tserver_name d.aginx.io;\n\tinclude snippets/listen.inc;\n}\n",
+		"snippets/listen.inc": "listen 8443 ssl;\n",
+	} {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	engine := file.New(filepath.Join(dir, "nginx.conf"))
+	conf, err := nginx.ReaderReadable(engine, plugins.NewFile("nginx.conf", []byte(`
+events {}
+http {
+	server {
+		server_name a.aginx.io;
+	}
+	server {
+		listen [::]:8080 ipv6only=on;
+		listen 8080;
+		server_name b.aginx.io;
+	}
+	include conf.d/*.conf;
+}
+stream {
+	server {
+		listen 53 udp;
+		proxy_pass 127.0.0.1:5353;
+	}
+}
+`)))
+	assert.Nil(t, err)
+
+	sandbox, ports, err := nginx.SandboxConfiguration(conf)
+	assert.Nil(t, err)
+	assert.Len(t, ports, 4)
+	for _, port := range []string{"80", "8080", "8443", "53"} {
+		assert.NotEqual(t, 0, ports[port], port)
+	}
+	listen := func(port string) string {
+		return "127.0.0.1:" + strconv.Itoa(ports[port])
+	}
+
+	found := servers(sandbox, map[string][]string{})
+	assert.Equal(t, []string{listen("80")}, found["a.aginx.io"])
+	assert.Equal(t, []string{listen("8080")}, found["b.aginx.io"])
+	assert.Equal(t, []string{listen("80")}, found["c.aginx.io"])
+	assert.Equal(t, []string{listen("8443")}, found["d.aginx.io"])
+
+	//原配置不修改
+	assert.Equal(t, []string{}, servers(conf, map[string][]string{})["a.aginx.io"])
+}
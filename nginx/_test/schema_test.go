@@ -0,0 +1,82 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSchema(t *testing.T) {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+worker_processes auto;
+http {
+	server {
+		listen 80;
+		location / {
+			porxy_pass http://backend;
+			lua_shared_dict cache 10m;
+		}
+		proxy_pass http://backend;
+	}
+	map $host $name {
+		default unknown_value;
+	}
+}
+stream {
+	server {
+		listen 53;
+		proxy_pass dns;
+	}
+}`)))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"unknown directive porxy_pass in location, did you mean proxy_pass?",
+		"unknown directive lua_shared_dict in location",
+		"proxy_pass is not allowed in server, allowed in: location, if in location, limit_except",
+	}, nginx.CheckSchema("main", conf.Body...))
+
+	nginx.AllowDirectives("lua_shared_dict")
+	assert.Equal(t, []string{
+		"unknown directive porxy_pass in location, did you mean proxy_pass?",
+		"proxy_pass is not allowed in server, allowed in: location, if in location, limit_except",
+	}, nginx.CheckSchema("http", conf.Body[1].Body[0]))
+}
+
+func TestLintUnknownDirective(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+	server {
+		listen 80;
+		server_nme aginx.io;
+	}
+}`), 0644))
+
+	lints, err := nginx.LintConfiguration(file.New(conf))
+	assert.Nil(t, err)
+	assert.Equal(t, []*nginx.Lint{
+		{Rule: nginx.LintUnknownDirective, File: "nginx.conf", Line: 4,
+			Message: "unknown directive server_nme in server, did you mean server_name?"},
+	}, lints)
+}
+
+func TestProcessStrict(t *testing.T) {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`http { gzipp on; }`)))
+	assert.Nil(t, err)
+
+	process := new(nginx.Process)
+	assert.Nil(t, process.CheckStrict("main", conf.Body...))
+	process.Strict = nginx.StrictWarn
+	assert.Nil(t, process.CheckStrict("main", conf.Body...))
+	process.Strict = nginx.StrictReject
+	err = process.CheckStrict("main", conf.Body...)
+	assert.NotNil(t, err)
+	assert.Equal(t, "unknown directive gzipp in http, did you mean gzip?", err.Error())
+}
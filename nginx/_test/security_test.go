@@ -0,0 +1,91 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSecurityHeaders(t *testing.T) {
+	headers, err := nginx.ParseSecurityHeaders("strict")
+	assert.Nil(t, err)
+	assert.Equal(t, "", headers.Domain)
+	assert.Equal(t, nginx.SecurityProfiles[nginx.SecurityStrict], headers.Values())
+
+	headers, err = nginx.ParseSecurityHeaders("admin.aginx.io=moderate,x-frame-options=DENY,Permissions-Policy=camera=(), microphone=(),Referrer-Policy=")
+	assert.Nil(t, err)
+	assert.Equal(t, "admin.aginx.io", headers.Domain)
+	values := headers.Values()
+	assert.Equal(t, "DENY", values["X-Frame-Options"])
+	assert.Equal(t, "camera=(), microphone=()", values["Permissions-Policy"])
+	_, has := values["Referrer-Policy"]
+	assert.False(t, has)
+
+	_, err = nginx.ParseSecurityHeaders("unknown")
+	assert.NotNil(t, err)
+	_, err = nginx.ParseSecurityHeaders("strict,Server=aginx")
+	assert.NotNil(t, err)
+	_, err = nginx.ParseSecurityHeaders("strict,X-Frame-Options=\"DENY\"")
+	assert.NotNil(t, err)
+}
+
+func TestClientSecurityHeaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+	server { listen 80; server_name web.aginx.io; add_header X-Frame-Options SAMEORIGIN; add_header X-Powered-By aginx; }
+	server { listen 443 ssl; server_name admin.aginx.io; }
+}`), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	assert.Nil(t, client.SetSecurityHeaders(&nginx.SecurityHeaders{Domain: "web.aginx.io", Profile: nginx.SecurityStrict}))
+	server := client.MustSelect("http", "server.server_name('web.aginx.io')")[0]
+	headers := server.MustSelect("add_header")
+	//替换原来的安全响应头，不是https的server不添加HSTS
+	assert.Len(t, headers, 6)
+	assert.Equal(t, []string{"X-Powered-By", "aginx"}, headers[0].Args)
+	assert.Equal(t, []string{"X-Frame-Options", `"DENY"`, "always"}, headers[1].Args)
+	assert.Equal(t, nginx.SecurityStrict, nginx.ParseServer(server).Security)
+
+	assert.Nil(t, client.SetSecurityHeaders(&nginx.SecurityHeaders{
+		Domain: "admin.aginx.io", Profile: nginx.SecurityModerate,
+		Headers: map[string]string{"Content-Security-Policy": "default-src 'self'"},
+	}))
+	server = client.MustSelect("http", "server.server_name('admin.aginx.io')")[0]
+	assert.Equal(t, []string{"Strict-Transport-Security", `"max-age=31536000"`, "always"}, server.MustSelect("add_header")[0].Args)
+
+	list := client.SecurityHeaders()
+	assert.Len(t, list, 2)
+	assert.Equal(t, "web.aginx.io", list[0].Domain)
+	assert.Equal(t, nginx.SecurityStrict, list[0].Profile)
+	assert.Equal(t, "admin.aginx.io", list[1].Domain)
+	assert.Equal(t, "", list[1].Profile)
+	assert.Equal(t, "default-src 'self'", list[1].Headers["Content-Security-Policy"])
+
+	assert.Nil(t, client.RemoveSecurityHeaders("web.aginx.io"))
+	assert.Len(t, client.MustSelect("http", "server.server_name('web.aginx.io')", "add_header"), 1)
+	assert.Equal(t, nginx.ErrNotFound, client.RemoveSecurityHeaders("web.aginx.io"))
+	assert.Equal(t, nginx.ErrNotFound, client.RemoveSecurityHeaders("none.aginx.io"))
+}
+
+func TestServerSecurity(t *testing.T) {
+	assert.NotNil(t, (&nginx.Server{Domains: []string{"aginx.io"}, Root: "/var/www", Security: "unknown"}).Validate())
+
+	server := &nginx.Server{Domains: []string{"aginx.io"}, Root: "/var/www", SSL: true, Security: nginx.SecurityModerate}
+	assert.Nil(t, server.Validate())
+	directives, err := server.Directives(map[string]*lego.StoreFile{
+		"aginx.io": {Certificate: "aginx.io.crt", PrivateKey: "aginx.io.key"},
+	}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, directives[1].MustSelect("add_header"), len(nginx.SecurityProfiles[nginx.SecurityModerate]))
+	assert.Equal(t, nginx.SecurityModerate, nginx.ParseServer(directives[1]).Security)
+}
@@ -0,0 +1,158 @@
+package nginx_test
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerDirectives(t *testing.T) {
+	server := &nginx.Server{Domains: []string{"aginx.io", "www.aginx.io"}, Proxy: "127.0.0.1:8080", SSL: true}
+	assert.Nil(t, server.Validate())
+
+	_, err := server.Directives(map[string]*lego.StoreFile{"aginx.io": {}}, nil)
+	assert.NotNil(t, err)
+
+	directives, err := server.Directives(map[string]*lego.StoreFile{
+		"aginx.io":     {Certificate: "aginx.io.crt", PrivateKey: "aginx.io.key"},
+		"www.aginx.io": {Certificate: "www.aginx.io.crt", PrivateKey: "www.aginx.io.key"},
+	}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, directives, 3)
+	assert.Equal(t, []string{"aginx.io", "www.aginx.io"}, directives[0].MustSelect("server_name")[0].Args)
+	assert.Equal(t, []string{"www.aginx.io.crt"}, directives[2].MustSelect("ssl_certificate")[0].Args)
+
+	parsed := nginx.ParseServer(directives[1])
+	assert.Equal(t, &nginx.Server{Domains: []string{"aginx.io"}, Listen: []string{"443"},
+		Proxy: "http://127.0.0.1:8080", SSL: true}, parsed)
+
+	assert.NotNil(t, (&nginx.Server{Domains: []string{"aginx.io"}}).Validate())
+	assert.NotNil(t, (&nginx.Server{Domains: []string{"aginx.io"}, Proxy: "api", Root: "/var/www"}).Validate())
+}
+
+func TestServerGRPC(t *testing.T) {
+	assert.NotNil(t, (&nginx.Server{Domains: []string{"grpc.aginx.io"}, Root: "/var/www", GRPC: true}).Validate())
+
+	server := &nginx.Server{Domains: []string{"grpc.aginx.io"}, Proxy: "127.0.0.1:9090", SSL: true, GRPC: true}
+	assert.Nil(t, server.Validate())
+	directives, err := server.Directives(map[string]*lego.StoreFile{
+		"grpc.aginx.io": {Certificate: "grpc.aginx.io.crt", PrivateKey: "grpc.aginx.io.key"},
+	}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, directives, 2)
+	assert.Equal(t, []string{"443", "ssl", "http2"}, directives[1].MustSelect("listen")[0].Args)
+
+	location := directives[1].MustSelect("location")[0]
+	assert.Equal(t, []string{"grpc://127.0.0.1:9090"}, location.MustSelect("grpc_pass")[0].Args)
+	assert.Equal(t, []string{"1h"}, location.MustSelect("grpc_read_timeout")[0].Args)
+	_, err = location.Select("proxy_pass")
+	assert.NotNil(t, err)
+
+	parsed := nginx.ParseServer(directives[1])
+	assert.Equal(t, &nginx.Server{Domains: []string{"grpc.aginx.io"}, Listen: []string{"443"},
+		Proxy: "grpc://127.0.0.1:9090", SSL: true, GRPC: true, HTTP2: true}, parsed)
+
+	//没有开启ssl时使用h2c
+	server = &nginx.Server{Domains: []string{"grpc.aginx.io"}, Proxy: "grpcs://127.0.0.1:9090", GRPC: true}
+	directives, _ = server.Directives(nil, nil)
+	assert.Equal(t, []string{"80", "http2"}, directives[0].MustSelect("listen")[0].Args)
+	assert.Equal(t, []string{"grpcs://127.0.0.1:9090"}, directives[0].MustSelect("location", "grpc_pass")[0].Args)
+}
+
+func TestServerHTTP3(t *testing.T) {
+	assert.NotNil(t, (&nginx.Server{Domains: []string{"h3.aginx.io"}, Proxy: "api", HTTP3: true}).Validate())
+
+	server := &nginx.Server{Domains: []string{"h3.aginx.io"}, Proxy: "127.0.0.1:8080", SSL: true, HTTP2: true, HTTP3: true}
+	assert.Nil(t, server.Validate())
+	assert.Nil(t, server.Supported(nil))
+	assert.NotNil(t, server.Supported(&nginx.Info{Version: "1.24.0", Modules: []string{"http_v2", "http_v3"}}))
+	assert.NotNil(t, server.Supported(&nginx.Info{Version: "1.25.3", Modules: []string{"http_v2"}}))
+
+	info := &nginx.Info{Version: "1.25.3", Modules: []string{"http_v2", "http_v3"}}
+	assert.Nil(t, server.Supported(info))
+	certificates := map[string]*lego.StoreFile{
+		"h3.aginx.io": {Certificate: "h3.aginx.io.crt", PrivateKey: "h3.aginx.io.key"},
+	}
+	directives, err := server.Directives(certificates, info)
+	assert.Nil(t, err)
+	listens := directives[1].MustSelect("listen")
+	assert.Len(t, listens, 2)
+	assert.Equal(t, []string{"443", "ssl"}, listens[0].Args)
+	assert.Equal(t, []string{"443", "quic"}, listens[1].Args)
+	assert.Equal(t, []string{"on"}, directives[1].MustSelect("http2")[0].Args)
+	assert.Equal(t, []string{"Alt-Svc", `'h3=":443"; ma=86400'`}, directives[1].MustSelect("add_header")[0].Args)
+	assert.Equal(t, []string{"TLSv1.2", "TLSv1.3"}, directives[1].MustSelect("ssl_protocols")[0].Args)
+
+	parsed := nginx.ParseServer(directives[1])
+	assert.Equal(t, &nginx.Server{Domains: []string{"h3.aginx.io"}, Listen: []string{"443"},
+		Proxy: "http://127.0.0.1:8080", SSL: true, HTTP2: true, HTTP3: true}, parsed)
+
+	//低版本在listen中添加http2
+	server.HTTP3 = false
+	directives, _ = server.Directives(certificates, &nginx.Info{Version: "1.18.0", Modules: []string{"http_v2"}})
+	assert.Equal(t, []string{"443", "ssl", "http2"}, directives[1].MustSelect("listen")[0].Args)
+	assert.True(t, nginx.ParseServer(directives[1]).HTTP2)
+}
+
+func TestServerWebSocket(t *testing.T) {
+	assert.NotNil(t, (&nginx.Server{Domains: []string{"ws.aginx.io"}, Root: "/var/www", WebSocket: true}).Validate())
+
+	server := &nginx.Server{Domains: []string{"ws.aginx.io"}, Proxy: "127.0.0.1:8080", WebSocket: true}
+	assert.Nil(t, server.Validate())
+	directives, err := server.Directives(nil, nil)
+	assert.Nil(t, err)
+
+	location := directives[0].MustSelect("location")[0]
+	assert.Equal(t, []string{"1.1"}, location.MustSelect("proxy_http_version")[0].Args)
+	assert.Equal(t, []string{"Connection", nginx.ConnectionUpgrade}, location.MustSelect("proxy_set_header('Connection')")[0].Args)
+	assert.Equal(t, []string{"3600s"}, location.MustSelect("proxy_read_timeout")[0].Args)
+	assert.True(t, nginx.ParseServer(directives[0]).WebSocket)
+
+	//再次开启替换原来的配置
+	nginx.EnableWebSocket(location)
+	assert.Len(t, location.MustSelect("proxy_set_header('Upgrade')"), 1)
+	assert.Len(t, location.MustSelect("proxy_read_timeout"), 1)
+}
+
+func TestClientServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("http { server { listen 80; server_name web.aginx.io; } }"), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	server := &nginx.Server{Domains: []string{"aginx.io", "www.aginx.io"}, Listen: []string{"8080"}, Root: "/var/www"}
+	assert.Nil(t, client.NewServer(server, ""))
+	assert.True(t, errors.Is(client.NewServer(server, ""), nginx.ErrServerExists))
+	assert.Len(t, client.Servers(), 2)
+
+	servers, err := client.GetServers("www.aginx.io")
+	assert.Nil(t, err)
+	assert.Equal(t, []*nginx.Server{{Domains: []string{"aginx.io", "www.aginx.io"}, Listen: []string{"8080"}, Root: "/var/www"}}, servers)
+
+	assert.Nil(t, client.DeleteServer("aginx.io"))
+	servers, err = client.GetServers("www.aginx.io")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"www.aginx.io"}, servers[0].Domains)
+
+	assert.Nil(t, client.DeleteServer("www.aginx.io"))
+	assert.Equal(t, nginx.ErrNotFound, client.DeleteServer("www.aginx.io"))
+	assert.Len(t, client.Servers(), 1)
+
+	ws := &nginx.Server{Domains: []string{"ws.aginx.io"}, Proxy: "127.0.0.1:8080", WebSocket: true}
+	assert.Nil(t, client.NewServer(ws, ""))
+	ws.Domains = []string{"ws2.aginx.io"}
+	assert.Nil(t, client.NewServer(ws, ""))
+	maps := client.MustSelect("http", "map")
+	assert.Len(t, maps, 1)
+	assert.Equal(t, []string{"$http_upgrade", nginx.ConnectionUpgrade}, maps[0].Args)
+}
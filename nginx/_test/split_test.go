@@ -0,0 +1,62 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitUpstream(t *testing.T) {
+	stable := &nginx.Upstream{Name: "v1", Servers: []*nginx.UpstreamServer{{Address: "a"}, {Address: "b"}}}
+	canary := &nginx.Upstream{Name: "v2", Servers: []*nginx.UpstreamServer{{Address: "c"}}}
+
+	weights := func(upstream *nginx.Upstream) map[string]int {
+		values := map[string]int{}
+		for _, server := range upstream.Servers {
+			values[server.Address] = server.Weight
+		}
+		return values
+	}
+	assert.Equal(t, map[string]int{"a": 9, "b": 9, "c": 2}, weights(nginx.SplitUpstream("api", stable, canary, 10)))
+	assert.Equal(t, map[string]int{"a": 0, "b": 0}, weights(nginx.SplitUpstream("api", stable, canary, 0)))
+	assert.Equal(t, map[string]int{"c": 0}, weights(nginx.SplitUpstream("api", stable, canary, 100)))
+}
+
+func TestClientSplit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http {
+		upstream v1 { server 127.0.0.1:8080; }
+		upstream v2 { server 127.0.0.1:8081; }
+		server { listen 80; server_name api.aginx.io; location / { proxy_pass http://v1; } }
+	}`), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+	proxyPass := func() []string {
+		return client.MustSelect("http", "server", "location('/')", "proxy_pass")[0].Args
+	}
+
+	split := &nginx.Split{Name: "api", Domain: "api.aginx.io", Stable: "v1", Canary: "v2", Weight: 20}
+	assert.Nil(t, client.SetSplit(split, false))
+	assert.Equal(t, []string{"http://api"}, proxyPass())
+	upstream, err := client.GetUpstream("api")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"127.0.0.1:8080", "weight=4"}, upstream.Servers[0].Args())
+	assert.Equal(t, []string{"127.0.0.1:8081"}, upstream.Servers[1].Args())
+
+	assert.Equal(t, nginx.ErrUpstreamExists, client.SetSplit(split, false))
+	split.Weight = 50
+	assert.Nil(t, client.SetSplit(split, true))
+
+	assert.Nil(t, client.PromoteSplit(split, nginx.SplitCanary))
+	assert.Equal(t, []string{"http://v2"}, proxyPass())
+	_, err = client.GetUpstream("api")
+	assert.Equal(t, nginx.ErrNotFound, err)
+}
@@ -0,0 +1,102 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAttachCertificate(t *testing.T) {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+http {
+	server {
+		listen 443 ssl;
+		server_name api.example.com;
+		ssl_certificate lego/certificates/api.example.com/server.crt;
+		ssl_certificate_key lego/certificates/api.example.com/server.pem;
+	}
+	server {
+		listen 443 ssl;
+		server_name example.com;
+		ssl_certificate lego/certificates/example.com/server.crt;
+		ssl_certificate_key lego/certificates/example.com/server.pem;
+	}
+	server {
+		listen 80;
+		server_name web.example.com;
+	}
+}`)))
+	assert.Nil(t, err)
+
+	file := &lego.StoreFile{
+		Certificate: "lego/certificates/wildcard_.example.com/server.crt",
+		PrivateKey:  "lego/certificates/wildcard_.example.com/server.pem",
+	}
+	assert.Equal(t, 1, nginx.AttachCertificate(conf, "*.example.com", file))
+	assert.Equal(t, 0, nginx.AttachCertificate(conf, "*.example.com", file))
+
+	servers := conf.MustSelect("http", "server")
+	assert.Equal(t, []string{file.Certificate}, servers[0].MustSelect("ssl_certificate")[0].Args)
+	assert.Equal(t, []string{file.PrivateKey}, servers[0].MustSelect("ssl_certificate_key")[0].Args)
+	assert.Equal(t, []string{"lego/certificates/example.com/server.crt"}, servers[1].MustSelect("ssl_certificate")[0].Args)
+}
+
+func TestEnableCertificate(t *testing.T) {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+http {
+	server {
+		listen 80;
+		server_name foo.local;
+		location / { proxy_pass http://127.0.0.1:8080; }
+	}
+	server {
+		listen 80;
+		server_name bar.local;
+	}
+}`)))
+	assert.Nil(t, err)
+
+	file := &lego.StoreFile{
+		Certificate: "lego/certificates/foo.local/server.crt",
+		PrivateKey:  "lego/certificates/foo.local/server.pem",
+	}
+	assert.Equal(t, 1, nginx.EnableCertificate(conf, "foo.local", file))
+	assert.Equal(t, 0, nginx.EnableCertificate(conf, "foo.local", file))
+
+	servers := conf.MustSelect("http", "server")
+	assert.Equal(t, 2, len(servers[0].MustSelect("listen")))
+	assert.Equal(t, []string{file.Certificate}, servers[0].MustSelect("ssl_certificate")[0].Args)
+	_, err = servers[1].Select("ssl_certificate")
+	assert.NotNil(t, err)
+}
+
+//UseCertificate只修改配置，由调用者测试并保存
+func TestUseCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	content := "http {\n\tserver {\n\t\tlisten 443 ssl;\n\t\tserver_name api.example.com;\n" +
+		"\t\tssl_certificate lego/certificates/api.example.com/server.crt;\n" +
+		"\t\tssl_certificate_key lego/certificates/api.example.com/server.pem;\n\t}\n}\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "nginx.conf"), []byte(content), 0644))
+
+	client, err := nginx.NewClient("", file.New(filepath.Join(dir, "nginx.conf")), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, client.UseCertificate("*.example.com", &lego.StoreFile{
+		Certificate: "lego/certificates/wildcard_.example.com/server.crt",
+		PrivateKey:  "lego/certificates/wildcard_.example.com/server.pem",
+	}))
+	assert.Equal(t, []string{"lego/certificates/wildcard_.example.com/server.crt"},
+		client.MustSelect("http", "server", "ssl_certificate")[0].Args)
+	assert.Equal(t, []string{"nginx.conf"}, client.Changes())
+
+	stored, err := ioutil.ReadFile(filepath.Join(dir, "nginx.conf"))
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(stored))
+}
@@ -0,0 +1,61 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const stubStatus = `Active connections: 291 
+server accepts handled requests
+ 16630948 16630948 31070465 
+Reading: 6 Writing: 179 Waiting: 106 
+`
+
+func TestParseStubStatus(t *testing.T) {
+	status, err := nginx.ParseStubStatus(stubStatus)
+	assert.Nil(t, err)
+	assert.Equal(t, &nginx.StubStatus{
+		Active: 291, Accepts: 16630948, Handled: 16630948, Requests: 31070465,
+		Reading: 6, Writing: 179, Waiting: 106,
+	}, status)
+
+	_, err = nginx.ParseStubStatus("<html>404 Not Found</html>")
+	assert.NotNil(t, err)
+}
+
+func TestStubStatusURL(t *testing.T) {
+	assert.Equal(t, "http://127.0.0.1:8090/stub_status", nginx.StubStatusURL("8090"))
+	assert.Equal(t, "http://127.0.0.1:8090/stub_status", nginx.StubStatusURL("0.0.0.0:8090"))
+	assert.Equal(t, "http://172.17.0.2:8090/stub_status", nginx.StubStatusURL("172.17.0.2:8090"))
+}
+
+func TestProcessStubStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/stub_status", r.URL.Path)
+		_, _ = w.Write([]byte(stubStatus))
+	}))
+	defer server.Close()
+
+	process := new(nginx.Process)
+	_, err := process.StubStatus()
+	assert.Equal(t, nginx.ErrStubStatusDisabled, err)
+
+	process.StubStatusListen = strings.TrimPrefix(server.URL, "http://")
+	status, err := process.StubStatus()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(31070465), status.Requests)
+
+	server.Close()
+	_, err = process.StubStatus()
+	assert.NotNil(t, err)
+}
+
+func TestStubStatusDirective(t *testing.T) {
+	server := nginx.StubStatusDirective("127.0.0.1:8090")
+	assert.Equal(t, []string{"127.0.0.1:8090"}, server.MustSelect("listen")[0].Args)
+	assert.Len(t, server.MustSelect("location('/stub_status')", "stub_status"), 1)
+}
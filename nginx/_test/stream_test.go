@@ -0,0 +1,57 @@
+package nginx_test
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientNewStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`http { server { listen 80; } }`), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	assert.NotNil(t, client.NewStream(&nginx.Stream{Listen: "mysql", Proxy: "10.0.0.1:3306"}))
+	assert.NotNil(t, client.NewStream(&nginx.Stream{Listen: "3306"}))
+	assert.NotNil(t, client.NewStream(&nginx.Stream{Listen: "3306", Proxy: "10.0.0.1:3306", Timeout: "ten"}))
+
+	assert.Nil(t, client.NewStream(&nginx.Stream{
+		Listen: "3306", Timeout: "10m",
+		Servers: []*nginx.UpstreamServer{{Address: "10.0.0.1:3306"}, {Address: "10.0.0.2:3306", Backup: true}},
+	}))
+	assert.Nil(t, client.NewStream(&nginx.Stream{Listen: "53", UDP: true, Proxy: "10.0.0.1:53"}))
+	assert.True(t, errors.Is(client.NewStream(&nginx.Stream{Listen: "3306", Proxy: "10.0.0.3:3306"}), nginx.ErrStreamExists))
+
+	assert.Len(t, client.MustSelect("stream", "include('streams.d/*.conf')"), 1)
+	file := client.MustSelect("stream", "include('streams.d/*.conf')", "file('streams.d/stream_3306.ngx.conf')")[0]
+	assert.Len(t, file.Body, 2)
+	assert.Equal(t, []string{"stream_3306"}, file.Body[0].Args)
+	assert.Equal(t, []string{"stream_3306"}, file.Body[1].MustSelect("proxy_pass")[0].Args)
+
+	streams := client.Streams()
+	assert.Len(t, streams, 2)
+	assert.Equal(t, "3306", streams[0].Listen)
+	assert.Equal(t, "", streams[0].Proxy)
+	assert.Equal(t, "10m", streams[0].Timeout)
+	assert.Len(t, streams[0].Servers, 2)
+	assert.True(t, streams[0].Servers[1].Backup)
+	assert.True(t, streams[1].UDP)
+	assert.Equal(t, "10.0.0.1:53", streams[1].Proxy)
+
+	assert.Equal(t, nginx.ErrNotFound, client.DeleteStream("53", false))
+	assert.Nil(t, client.DeleteStream("3306", false))
+	assert.Len(t, file.Body, 0)
+	_, err = client.GetUpstream("stream_3306")
+	assert.Equal(t, nginx.ErrNotFound, err)
+	assert.Len(t, client.Streams(), 1)
+}
@@ -0,0 +1,101 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func tenantDirectives(t *testing.T, content string) []*nginx.Directive {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("tenant.conf", []byte(content)))
+	assert.Nil(t, err)
+	return conf.Body
+}
+
+func TestTenant(t *testing.T) {
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(`
+http {
+	upstream backend {
+		server 127.0.0.1:8080;
+	}
+	server {
+		listen 80;
+		server_name aginx.io;
+	}
+}`)))
+	assert.Nil(t, err)
+	assert.False(t, nginx.HasTenantInclude(conf))
+
+	blog := &nginx.Directive{Virtual: nginx.Include, Name: "file", Args: []string{"tenants/blog/blog.conf"},
+		Body: tenantDirectives(t, `server { server_name blog.aginx.io; } upstream blog { server 127.0.0.1:8081; }`)}
+	include := nginx.NewDirective("include", nginx.TenantInclude)
+	include.Body = []*nginx.Directive{blog}
+	conf.Body[0].Body = append(conf.Body[0].Body, include)
+	assert.True(t, nginx.HasTenantInclude(conf))
+
+	file, err := nginx.TenantFile("shop", "shop.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenants/shop/shop.conf", file)
+	_, err = nginx.TenantFile("shop", "../blog/blog.conf")
+	assert.NotNil(t, err)
+	_, err = nginx.TenantFile("shop", "shop.txt")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, `
+server { server_name shop.aginx.io; location / { proxy_pass http://shop; } }
+upstream shop { server 127.0.0.1:8082; }
+map $uri $shop_cache { default 0; }`)))
+	//租户自己的文件可以修改
+	assert.Nil(t, nginx.CheckTenant(conf, "blog", tenantDirectives(t, `server { server_name blog.aginx.io; }`)))
+
+	assert.Error(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, `server { server_name blog.aginx.io; }`)))
+	assert.Error(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, `server { server_name AGINX.IO; }`)))
+	assert.Error(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, `upstream backend { server 127.0.0.1:8082; }`)))
+	assert.Error(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, `worker_processes 1;`)))
+	assert.Error(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, `server { include /etc/passwd; }`)))
+	assert.Nil(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, `server { include tenants/shop/common; }`)))
+
+	//租户只能使用自己目录中的文件
+	for _, allowed := range []string{
+		`server { root tenants/shop/html; }`,
+		`server { location /static { alias tenants/shop/static/; } }`,
+		`server { access_log tenants/shop/logs/access.log main; error_log tenants/shop/logs/error.log; }`,
+		`server { access_log off; error_log stderr; location / { access_log syslog:server=127.0.0.1; } }`,
+		`server { ssl_certificate tenants/shop/ssl/shop.crt; ssl_certificate_key tenants/shop/ssl/shop.key; }`,
+		`server { location / { proxy_temp_path tenants/shop/temp; client_body_temp_path tenants/shop/body; } }`,
+		`server { auth_basic_user_file tenants/shop/htpasswd; rewrite_log on; }`,
+		`server { listen 80; listen 443 ssl http2; }`,
+	} {
+		assert.Nil(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, allowed)), allowed)
+	}
+	for _, denied := range []string{
+		`server { root /; }`,
+		`server { root tenants/blog/html; }`,
+		`server { root tenants/shop/../../; }`,
+		`server { root /var/www/$host; }`,
+		`server { location /etc { alias /etc/; } }`,
+		`server { location / { if ($uri) { root /etc; } } }`,
+		`server { access_log /etc/cron.d/shop; }`,
+		`server { error_log /var/log/nginx/error.log; }`,
+		`server { location / { access_log /root/.ssh/authorized_keys; } }`,
+		`server { ssl_certificate /etc/nginx/ssl/aginx.io.crt; }`,
+		`server { ssl_certificate_key /etc/ssl/private/aginx.io.key; }`,
+		`server { proxy_ssl_certificate /etc/ssl/client.crt; }`,
+		`server { ssl_trusted_certificate /etc/ssl/certs/ca.crt; }`,
+		`server { location / { proxy_temp_path /tmp/shop; } }`,
+		`server { client_body_temp_path /etc/nginx; }`,
+		`server { auth_basic_user_file /etc/shadow; }`,
+		`server { listen 80 default_server; }`,
+		`server { listen 80 default; }`,
+		`server { listen 443 ssl reuseport; }`,
+	} {
+		assert.Error(t, nginx.CheckTenant(conf, "shop", tenantDirectives(t, denied)), denied)
+	}
+
+	files := nginx.TenantFiles(conf, "blog")
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "tenants/blog/blog.conf", files[0].Name)
+	assert.Equal(t, []string{"blog"}, files[0].Upstreams)
+	assert.Equal(t, 0, len(nginx.TenantFiles(conf, "shop")))
+}
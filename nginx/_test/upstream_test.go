@@ -0,0 +1,77 @@
+package nginx_test
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/storage/file"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpstreamServer(t *testing.T) {
+	args := []string{"127.0.0.1:8080", "weight=2", "max_fails=3", "fail_timeout=10s", "max_conns=100", "down"}
+	server := nginx.ParseUpstreamServer(args)
+	assert.Equal(t, &nginx.UpstreamServer{
+		Address: "127.0.0.1:8080", Weight: 2, MaxFails: 3, FailTimeout: "10s",
+		Down: true, Params: []string{"max_conns=100"},
+	}, server)
+	assert.Equal(t, args, server.Args())
+}
+
+func TestUpstreamApply(t *testing.T) {
+	directive := nginx.NewDirective("upstream", "api")
+	directive.AddBody("ip_hash")
+	directive.AddBody("server", "127.0.0.1:8080")
+	directive.AddBody("keepalive", "16")
+
+	upstream := nginx.ParseUpstream(directive, false)
+	assert.Equal(t, "ip_hash", upstream.LoadBalance)
+	assert.Len(t, upstream.Servers, 1)
+
+	upstream.LoadBalance = "hash $request_uri consistent"
+	upstream.Servers = append(upstream.Servers, &nginx.UpstreamServer{Address: "127.0.0.1:8081", Backup: true})
+	assert.Nil(t, upstream.Validate())
+	upstream.Apply(directive)
+	assert.Equal(t, "upstream api {\n"+
+		"    hash $request_uri consistent;\n"+
+		"    keepalive 16;\n"+
+		"    server 127.0.0.1:8080;\n"+
+		"    server 127.0.0.1:8081 backup;\n"+
+		"}", directive.Pretty(0))
+
+	upstream.LoadBalance = "sticky"
+	assert.NotNil(t, upstream.Validate())
+}
+
+func TestClientUpstream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	conf := filepath.Join(dir, "nginx.conf")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte("http { upstream web { server 127.0.0.1:80; } }"), 0644))
+
+	client, err := nginx.NewClient("", file.New(conf), nil, nil)
+	assert.Nil(t, err)
+
+	api := &nginx.Upstream{Name: "api", Servers: []*nginx.UpstreamServer{{Address: "127.0.0.1:8080"}}}
+	assert.Nil(t, client.NewUpstream(api))
+	assert.Equal(t, nginx.ErrUpstreamExists, client.NewUpstream(api))
+	assert.Len(t, client.Upstreams(), 2)
+
+	assert.Nil(t, client.SetUpstreamServer("api", &nginx.UpstreamServer{Address: "127.0.0.1:8081", Weight: 5}))
+	assert.Nil(t, client.SetUpstreamServer("api", &nginx.UpstreamServer{Address: "127.0.0.1:8080", Down: true}))
+	upstream, err := client.GetUpstream("api")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"127.0.0.1:8080", "down"}, upstream.Servers[0].Args())
+	assert.Equal(t, 5, upstream.Servers[1].Weight)
+
+	assert.Nil(t, client.RemoveUpstreamServer("api", "127.0.0.1:8080"))
+	assert.Equal(t, nginx.ErrNotFound, client.RemoveUpstreamServer("api", "127.0.0.1:8080"))
+	assert.Equal(t, nginx.ErrUpstreamLastServer, client.RemoveUpstreamServer("api", "127.0.0.1:8081"))
+
+	assert.Nil(t, client.DeleteUpstream("web"))
+	_, err = client.GetUpstream("web")
+	assert.Equal(t, nginx.ErrNotFound, err)
+}
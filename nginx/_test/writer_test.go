@@ -2,9 +2,12 @@ package nginx_test
 
 import (
 	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/storage"
 	. "github.com/ihaiker/aginx/util"
+	"github.com/stretchr/testify/assert"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -25,3 +28,51 @@ func TestName(t *testing.T) {
 
 	PanicIfError(nginx.Write(c.Configuration(), nginx.FileDiffer(path), nginx.FileWriter(path)))
 }
+
+func TestWriteComments(t *testing.T) {
+	content := `# main configuration
+worker_processes auto; # one worker per cpu
+
+events {
+	worker_connections 1024;
+}
+
+http {
+	# upstream for the api
+	upstream api {
+		server 127.0.0.1:8080; # primary
+		server 127.0.0.1:8081 backup;
+	}
+
+	server { # default server
+		listen 80;
+		add_header X-Tag "#not-comment";
+
+		location / {
+			proxy_pass http://api;
+		}
+		# location /old {
+		#	return 410;
+		# }
+	}
+}
+# end of file
+`
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile("nginx.conf", []byte(content)))
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(conf.BodyBytes()))
+
+	servers := conf.MustSelect("http", "server")
+	servers[0].AddBody("server_name", "aginx.io")
+	assert.Equal(t, `		location / {
+			proxy_pass http://api;
+		}
+		server_name aginx.io;
+		# location /old {
+		#	return 410;
+		# }
+	}
+}
+# end of file
+`, string(conf.BodyBytes())[strings.Index(content, "\t\tlocation /"):])
+}
@@ -0,0 +1,162 @@
+package nginx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	AccessAllow = "allow"
+	AccessDeny  = "deny"
+)
+
+//allow、deny规则，Domain为空时作用于全部http
+type AccessRule struct {
+	Action  string     `json:"action"`
+	Address string     `json:"address"` //IP、CIDR 或者 all
+	Domain  string     `json:"domain,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"` //设置了有效期的规则，过期后自动删除
+}
+
+func isAccess(directive *Directive) bool {
+	return (directive.Name == AccessAllow || directive.Name == AccessDeny) && len(directive.Args) > 0
+}
+
+//检查并格式化地址，CIDR使用网络地址：10.0.0.1/8 => 10.0.0.0/8
+func ParseAccessAddress(address string) (string, error) {
+	if address == "all" {
+		return address, nil
+	}
+	if strings.Contains(address, "/") {
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return "", errors.New("invalid cidr: " + address)
+		}
+		return network.String(), nil
+	}
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String(), nil
+	}
+	return "", errors.New("invalid address: " + address)
+}
+
+//批量导入的地址列表，每行一个或者多个（空格、逗号分隔），#后面为注释
+func ParseAccessList(content []byte) ([]string, error) {
+	addresses := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == ';'
+		}) {
+			address, err := ParseAccessAddress(field)
+			if err != nil {
+				return nil, err
+			}
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, scanner.Err()
+}
+
+func (client *Client) accessTargets(domain string) ([]*Directive, error) {
+	var targets []*Directive
+	if domain == "" {
+		targets, _ = client.Select("http")
+	} else {
+		for _, queries := range serverQueries(domain) {
+			if directives, err := client.Select(queries...); err == nil {
+				targets = append(targets, directives...)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, ErrNotFound
+	}
+	return targets, nil
+}
+
+//http和server中的allow、deny规则，按照配置的顺序
+func (client *Client) AccessRules() []*AccessRule {
+	rules := make([]*AccessRule, 0)
+	for _, target := range client.scopes() {
+		if target.location != "" {
+			continue
+		}
+		for _, directive := range target.directive.Body {
+			if isAccess(directive) {
+				rules = append(rules, &AccessRule{Action: directive.Name, Address: directive.Args[0], Domain: target.domain()})
+			}
+		}
+	}
+	return rules
+}
+
+//添加规则，地址已经存在时修改为action。新规则添加在 allow all 或者 deny all 之前
+func (client *Client) AddAccessRules(domain, action string, addresses ...string) error {
+	if action != AccessAllow && action != AccessDeny {
+		return errors.New("the action must be allow or deny")
+	}
+	targets, err := client.accessTargets(domain)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		for _, address := range addresses {
+			exists := false
+			for _, directive := range target.Body {
+				if isAccess(directive) && directive.Args[0] == address {
+					directive.Name, exists = action, true
+				}
+			}
+			if exists {
+				continue
+			}
+			idx := len(target.Body)
+			for i, directive := range target.Body {
+				if isAccess(directive) {
+					if directive.Args[0] == "all" {
+						idx = i
+						break
+					}
+					idx = i + 1
+				}
+			}
+			body := append([]*Directive{}, target.Body[:idx]...)
+			body = append(body, NewDirective(action, address))
+			target.Body = append(body, target.Body[idx:]...)
+		}
+	}
+	return nil
+}
+
+func (client *Client) RemoveAccessRules(domain string, addresses ...string) error {
+	targets, err := client.accessTargets(domain)
+	if err != nil {
+		return err
+	}
+	removes := make(map[string]bool)
+	for _, address := range addresses {
+		removes[address] = true
+	}
+	err = ErrNotFound
+	for _, target := range targets {
+		body := make([]*Directive, 0, len(target.Body))
+		for _, directive := range target.Body {
+			if isAccess(directive) && removes[directive.Args[0]] {
+				err = nil
+				continue
+			}
+			body = append(body, directive)
+		}
+		target.Body = body
+	}
+	return err
+}
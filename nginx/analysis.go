@@ -10,34 +10,61 @@ import (
 )
 
 func Readable(store plugins.StorageEngine) (*Configuration, error) {
+	cfg, _, err := readTree(store)
+	return cfg, err
+}
+
+//读取全部配置文件，返回读取的每个文件内容的摘要，保存时没有变化的文件不需要再和存储比较
+func readTree(store plugins.StorageEngine) (*Configuration, map[string]fileSum, error) {
 	reader, err := store.Get("nginx.conf")
 	if err != nil {
-		return nil, util.Wrap(err, "get nginx.conf")
+		return nil, nil, util.Wrap(err, "get nginx.conf")
 	}
-	return ReaderReadable(store, reader)
+	loaded := make(map[string]fileSum)
+	cfg, _, err := readFile(store, reader, loaded)
+	return cfg, loaded, err
 }
 
 func ReaderReadable(store plugins.StorageEngine, cfgFile *plugins.ConfigurationFile) (*Configuration, error) {
+	cfg, _, err := readable(store, cfgFile)
+	return cfg, err
+}
+
+//解析配置文件，formatted 为是否保留了注释和空行
+func readable(store plugins.StorageEngine, cfgFile *plugins.ConfigurationFile) (cfg *Configuration, formatted bool, err error) {
+	return readFile(store, cfgFile, nil)
+}
+
+//解析配置文件（内容没有变化时使用缓存）后读取include的文件，loaded不为空时记录读取的文件
+func readFile(store plugins.StorageEngine, cfgFile *plugins.ConfigurationFile, loaded map[string]fileSum) (cfg *Configuration, formatted bool, err error) {
+	if cfg, formatted, err = parseCached(cfgFile, loaded); err != nil {
+		return
+	}
+	if store != nil { //未指定存储时不解析include
+		err = resolveIncludes(store, cfg, loaded)
+	}
+	return
+}
+
+//解析单个文件，不读取include的文件
+func parse(cfgFile *plugins.ConfigurationFile) (cfg *Configuration, formatted bool, err error) {
 	parser := codf.NewParser()
-	if err := parser.Parse(codf.NewLexer(bytes.NewBuffer(cfgFile.Content))); err != nil {
-		return nil, util.Wrap(err, "parse config: "+cfgFile.Name)
+	if err = parser.Parse(codf.NewLexer(bytes.NewBuffer(cfgFile.Content))); err != nil {
+		return nil, false, util.Wrap(err, "parse config: "+cfgFile.Name)
 	}
 	doc := parser.Document()
-	cfg := &Configuration{
+	cfg = &Configuration{
 		Name: cfgFile.Name,
 		Body: make([]*Directive, 0),
 	}
 	for _, child := range doc.Children {
-		node, err := analysisNode(store, child)
-		if err != nil {
-			return nil, err
-		}
-		cfg.Body = append(cfg.Body, node)
+		cfg.Body = append(cfg.Body, analysisNode(child))
 	}
-	return cfg, nil
+	formatted = formatConfiguration(cfgFile.Content, doc, cfg)
+	return
 }
 
-func analysisNode(store plugins.StorageEngine, child codf.Node) (directive *Directive, err error) {
+func analysisNode(child codf.Node) (directive *Directive) {
 	directive = new(Directive)
 	switch child.(type) {
 	case *codf.Section:
@@ -49,9 +76,7 @@ func analysisNode(store plugins.StorageEngine, child codf.Node) (directive *Dire
 		}
 		directive.Body = make([]*Directive, len(s.Nodes()))
 		for i, n := range s.Nodes() {
-			if directive.Body[i], err = analysisNode(store, n); err != nil {
-				return
-			}
+			directive.Body[i] = analysisNode(n)
 		}
 	case codf.ParamNode:
 		s := child.(codf.ParamNode)
@@ -60,7 +85,6 @@ func analysisNode(store plugins.StorageEngine, child codf.Node) (directive *Dire
 		for i, param := range s.Parameters() {
 			directive.Args[i] = string(param.Token().Raw)
 		}
-		err = virtual(store, directive)
 	case codf.ExprNode:
 		s := child.(codf.ExprNode)
 		directive.Name = string(s.Token().Raw)
@@ -68,33 +92,49 @@ func analysisNode(store plugins.StorageEngine, child codf.Node) (directive *Dire
 	return
 }
 
-func includes(store plugins.StorageEngine, node *Directive) error {
+//读取配置中include的文件，include的文件中的include在读取文件时处理
+func resolveIncludes(store plugins.StorageEngine, directive *Directive, loaded map[string]fileSum) error {
+	for _, body := range directive.Body {
+		if body.Virtual == "" && body.Name == "include" && body.Body == nil {
+			if err := virtual(store, body, loaded); err != nil {
+				return err
+			}
+		} else if err := resolveIncludes(store, body, loaded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func includes(store plugins.StorageEngine, node *Directive, loaded map[string]fileSum) error {
 	files, err := store.Search(node.Args...)
 	if err != nil {
 		return err
 	}
 	for _, file := range files {
 		includeDirective := &Directive{Virtual: Include, Name: "file", Args: Queries(file.Name)}
-		if doc, err := ReaderReadable(store, file); err != nil {
+		if doc, _, err := readFile(store, file, loaded); err != nil {
 			return err
 		} else {
 			includeDirective.Body = doc.Body
+			includeDirective.EndComments, includeDirective.Indent = doc.EndComments, doc.Indent
 		}
 		node.Body = append(node.Body, includeDirective)
 	}
 	return nil
 }
 
-func virtual(store plugins.StorageEngine, directive *Directive) (err error) {
+func virtual(store plugins.StorageEngine, directive *Directive, loaded map[string]fileSum) (err error) {
 	switch directive.Name {
 	case "include":
 		configDir := MustConfigDir()
 		for i, arg := range directive.Args {
 			if strings.HasPrefix(arg, configDir) {
-				directive.Args[i], _ = filepath.Rel(configDir, arg)
+				relative, _ := filepath.Rel(configDir, arg)
+				directive.Args[i] = filepath.ToSlash(relative)
 			}
 		}
-		if err = includes(store, directive); err != nil {
+		if err = includes(store, directive, loaded); err != nil {
 			return
 		}
 	}
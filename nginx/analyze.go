@@ -0,0 +1,215 @@
+package nginx
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"strconv"
+	"strings"
+)
+
+const (
+	CheckGzip              = "gzip"
+	CheckUpstreamKeepalive = "upstream-keepalive"
+	CheckSslCiphers        = "ssl-ciphers"
+	CheckClientBodySize    = "client-body-size"
+	CheckWorkerProcesses   = "worker-processes"
+	CheckWorkerConnections = "worker-connections"
+	CheckServerTokens      = "server-tokens"
+)
+
+//最佳实践检查项
+type Check struct {
+	Name           string `json:"name"`
+	Weight         int    `json:"weight"`
+	Passed         bool   `json:"passed"`
+	File           string `json:"file,omitempty"`
+	Line           int    `json:"line,omitempty"`
+	Recommendation string `json:"recommendation,omitempty"`
+}
+
+//配置分析结果，score为通过检查项的权重占比（0-100）
+type Analysis struct {
+	Score  int      `json:"score"`
+	Checks []*Check `json:"checks"`
+}
+
+func (a *Analysis) add(check *Check) {
+	a.Checks = append(a.Checks, check)
+}
+
+//指令的第一个参数，没有指令时返回false
+func firstArg(directives []*lintDirective) (*lintDirective, string, bool) {
+	if len(directives) == 0 || len(directives[0].args) == 0 {
+		return nil, "", false
+	}
+	return directives[0], directives[0].args[0], true
+}
+
+func (c *Check) at(directive *lintDirective) *Check {
+	if directive != nil {
+		c.File, c.Line = directive.file, directive.line
+	}
+	return c
+}
+
+func checkWorkers(root *lintDirective, cpus int) []*Check {
+	processes := &Check{Name: CheckWorkerProcesses, Weight: 10}
+	directive, value, has := firstArg(root.find("worker_processes"))
+	if !has {
+		value = "1"
+	}
+	if processes.at(directive).Passed = value == "auto" || value == strconv.Itoa(cpus); !processes.Passed {
+		processes.Recommendation = fmt.Sprintf("worker_processes is %s but the server has %d cpus, use: worker_processes auto;", value, cpus)
+	}
+
+	connections := &Check{Name: CheckWorkerConnections, Weight: 5}
+	count := "512"
+	if events := root.find("events"); len(events) > 0 {
+		connections.at(events[0])
+		if directive, value, has := firstArg(events[0].find("worker_connections")); has {
+			connections.at(directive)
+			count = value
+		}
+	}
+	number, _ := strconv.Atoi(count)
+	if connections.Passed = number >= 1024; !connections.Passed {
+		connections.Recommendation = fmt.Sprintf("worker_connections is %s, use at least: events { worker_connections 1024; }", count)
+	}
+	return []*Check{processes, connections}
+}
+
+func checkGzip(http *lintDirective) *Check {
+	check := &Check{Name: CheckGzip, Weight: 15}
+	directive, value, _ := firstArg(http.find("gzip"))
+	if check.at(directive).Passed = value == "on"; !check.Passed {
+		check.Recommendation = "compress the text responses in http: gzip on; gzip_types text/css application/javascript application/json;"
+	}
+	return check
+}
+
+//upstream没有keepalive时每个请求都会新建到后端的连接
+func checkUpstreamKeepalive(http *lintDirective) *Check {
+	upstreams := http.find("upstream")
+	if len(upstreams) == 0 {
+		return nil
+	}
+	check := &Check{Name: CheckUpstreamKeepalive, Weight: 15, Passed: true}
+	missing := make([]string, 0)
+	for _, upstream := range upstreams {
+		if len(upstream.find("keepalive")) == 0 {
+			if check.Passed {
+				check.at(upstream)
+			}
+			check.Passed = false
+			missing = append(missing, strings.Join(upstream.args, " "))
+		}
+	}
+	if !check.Passed {
+		check.Recommendation = fmt.Sprintf("reuse the connections to upstream %s: keepalive 16; "+
+			"and in location: proxy_http_version 1.1; proxy_set_header Connection \"\";", strings.Join(missing, ", "))
+	}
+	return check
+}
+
+var weakCiphers = []string{"RC4", "DES", "MD5", "NULL", "EXPORT", "EXP"}
+
+//有开启ssl的server时检查ssl_ciphers，不能包含弱加密算法
+func checkSslCiphers(http *lintDirective) *Check {
+	servers := make([]*lintDirective, 0)
+	for _, server := range http.find("server") {
+		if sslEnabled(server) {
+			servers = append(servers, server)
+		}
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+
+	check := &Check{Name: CheckSslCiphers, Weight: 20, Passed: true}
+	httpCiphers := http.find("ssl_ciphers")
+	for _, server := range servers {
+		ciphers := server.find("ssl_ciphers")
+		if len(ciphers) == 0 {
+			ciphers = httpCiphers
+		}
+		directive, value, has := firstArg(ciphers)
+		if !has {
+			check.at(server).Passed = false
+			check.Recommendation = "set ssl_ciphers, for example: ssl_ciphers ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384;"
+			return check
+		}
+		for _, cipher := range strings.Split(strings.Trim(value, `"'`), ":") {
+			if strings.HasPrefix(cipher, "!") || strings.HasPrefix(cipher, "-") {
+				continue
+			}
+			for _, weak := range weakCiphers {
+				if strings.Contains(strings.ToUpper(cipher), weak) {
+					check.at(directive).Passed = false
+					check.Recommendation = fmt.Sprintf("the ssl_ciphers contains weak cipher %s, remove it or add !%s", cipher, weak)
+					return check
+				}
+			}
+		}
+	}
+	return check
+}
+
+func checkClientBodySize(http *lintDirective) *Check {
+	check := &Check{Name: CheckClientBodySize, Weight: 10}
+	directive, _, has := firstArg(http.find("client_max_body_size"))
+	if check.at(directive).Passed = has; !has {
+		check.Recommendation = "limit the request body size explicitly in http (default 1m): client_max_body_size 10m;"
+	}
+	return check
+}
+
+func checkServerTokens(http *lintDirective) *Check {
+	check := &Check{Name: CheckServerTokens, Weight: 5}
+	directive, value, _ := firstArg(http.find("server_tokens"))
+	if check.at(directive).Passed = value == "off"; !check.Passed {
+		check.Recommendation = "hide the nginx version in http: server_tokens off;"
+	}
+	return check
+}
+
+//对照最佳实践检查配置并打分，cpus为服务器的cpu数量
+func Analyze(store plugins.StorageEngine, cpus int) (*Analysis, error) {
+	conf, err := store.Get("nginx.conf")
+	if err != nil {
+		return nil, util.Wrap(err, "get nginx.conf")
+	}
+	body, err := lintRead(store, conf, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	root := &lintDirective{body: body}
+
+	analysis := &Analysis{Checks: make([]*Check, 0)}
+	for _, check := range checkWorkers(root, cpus) {
+		analysis.add(check)
+	}
+	if https := root.find("http"); len(https) > 0 {
+		http := https[0]
+		for _, check := range []*Check{
+			checkGzip(http), checkUpstreamKeepalive(http), checkSslCiphers(http),
+			checkClientBodySize(http), checkServerTokens(http),
+		} {
+			if check != nil { //不适用的检查项
+				analysis.add(check)
+			}
+		}
+	}
+
+	total, passed := 0, 0
+	for _, check := range analysis.Checks {
+		total += check.Weight
+		if check.Passed {
+			passed += check.Weight
+		}
+	}
+	if total > 0 {
+		analysis.Score = passed * 100 / total
+	}
+	return analysis, nil
+}
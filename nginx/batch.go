@@ -0,0 +1,112 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	ActionAdd    = "add"
+	ActionDelete = "delete"
+	ActionModify = "modify"
+)
+
+type Operation struct {
+	Action     string       `json:"action"`
+	Queries    []string     `json:"queries,omitempty"`
+	Directives []*Directive `json:"directives,omitempty"`
+}
+
+func (op *Operation) Apply(client *Client) error {
+	switch op.Action {
+	case ActionAdd:
+		return client.Add(op.Queries, op.Directives...)
+	case ActionDelete:
+		return client.Delete(op.Queries...)
+	case ActionModify:
+		if len(op.Directives) == 0 {
+			return errors.New("new directive is empty")
+		}
+		return client.Modify(op.Queries, op.Directives[0])
+	default:
+		return errors.New("action not support: " + op.Action)
+	}
+}
+
+//批量修改，所有的修改全部成功后才会保存并重启一次nginx，任意一个失败则全部放弃
+type Batch struct {
+	client     *Client
+	Operations []*Operation
+}
+
+func (client *Client) Batch() *Batch {
+	return &Batch{client: client, Operations: make([]*Operation, 0)}
+}
+
+func (b *Batch) Add(queries []string, directives ...*Directive) *Batch {
+	b.Operations = append(b.Operations, &Operation{Action: ActionAdd, Queries: queries, Directives: directives})
+	return b
+}
+
+func (b *Batch) Delete(queries ...string) *Batch {
+	b.Operations = append(b.Operations, &Operation{Action: ActionDelete, Queries: queries})
+	return b
+}
+
+func (b *Batch) Modify(queries []string, directive *Directive) *Batch {
+	b.Operations = append(b.Operations, &Operation{Action: ActionModify, Queries: queries, Directives: []*Directive{directive}})
+	return b
+}
+
+//在配置副本上执行所有修改，不会修改client中的配置
+func (b *Batch) apply() (*Configuration, error) {
+	staging := &Client{
+		Email: b.client.Email, Engine: b.client.Engine, Lego: b.client.Lego, Process: b.client.Process,
+		doc: b.client.doc.Clone(),
+	}
+	for i, op := range b.Operations {
+		if err := op.Apply(staging); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %v) error: %s", i, op.Action, op.Queries, err)
+		}
+	}
+	return staging.doc, nil
+}
+
+//测试修改后的配置，如果client未设置Process则不测试
+func (b *Batch) Test() error {
+	doc, err := b.apply()
+	if err != nil {
+		return err
+	}
+	if b.client.Process != nil {
+		return b.client.Process.Test(doc)
+	}
+	return nil
+}
+
+//执行所有修改，测试通过后保存配置并重启nginx。保存失败会恢复原有的配置文件
+func (b *Batch) Commit() error {
+	doc, err := b.apply()
+	if err != nil {
+		return err
+	}
+	if b.client.Process != nil {
+		if err = b.client.Process.Test(doc); err != nil {
+			return err
+		}
+	}
+
+	previous := b.client.doc
+	b.client.doc = doc
+	if err = b.client.Store(); err != nil {
+		b.client.doc = previous
+		if restoreErr := b.client.Store(); restoreErr != nil {
+			logger.WithError(restoreErr).Warn("restore configuration")
+		}
+		return err
+	}
+	if b.client.Process != nil {
+		return b.client.Process.Reload()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package nginx
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"github.com/ihaiker/aginx/plugins"
+	"sync"
+)
+
+type fileSum [sha256.Size]byte
+
+//解析过的文件，cfg为没有读取include的结果，使用时复制
+type parsedFile struct {
+	name      string
+	sum       fileSum
+	cfg       *Configuration
+	formatted bool
+}
+
+//缓存的文件数量超过后删除最久没有使用的文件，避免删除的文件一直占用内存
+var maxParsedFiles = 10000
+
+var (
+	parsedLock  sync.Mutex
+	parsedFiles = make(map[string]*list.Element)
+	parsedOrder = list.New() //最近使用的在前
+)
+
+//解析配置文件，文件内容和上次解析时相同时复制缓存的结果，只有修改过的文件需要重新解析
+func parseCached(cfgFile *plugins.ConfigurationFile, loaded map[string]fileSum) (*Configuration, bool, error) {
+	sum := fileSum(sha256.Sum256(cfgFile.Content))
+	if loaded != nil {
+		loaded[cfgFile.Name] = sum
+	}
+	//没有名称的内容（例如：请求中的配置）不缓存
+	if cfgFile.Name == "" {
+		return parse(cfgFile)
+	}
+
+	if cached := cachedFile(cfgFile.Name, sum); cached != nil {
+		return cached.cfg.Clone(), cached.formatted, nil
+	}
+
+	cfg, formatted, err := parse(cfgFile)
+	if err != nil {
+		return nil, false, err
+	}
+	cacheFile(&parsedFile{name: cfgFile.Name, sum: sum, cfg: cfg.Clone(), formatted: formatted})
+	return cfg, formatted, nil
+}
+
+//内容相同的缓存结果，没有时返回nil
+func cachedFile(name string, sum fileSum) *parsedFile {
+	parsedLock.Lock()
+	defer parsedLock.Unlock()
+	element, has := parsedFiles[name]
+	if !has || element.Value.(*parsedFile).sum != sum {
+		return nil
+	}
+	parsedOrder.MoveToFront(element)
+	return element.Value.(*parsedFile)
+}
+
+func cacheFile(parsed *parsedFile) {
+	parsedLock.Lock()
+	defer parsedLock.Unlock()
+	if element, has := parsedFiles[parsed.name]; has {
+		element.Value = parsed
+		parsedOrder.MoveToFront(element)
+		return
+	}
+	parsedFiles[parsed.name] = parsedOrder.PushFront(parsed)
+	for parsedOrder.Len() > maxParsedFiles {
+		oldest := parsedOrder.Back()
+		parsedOrder.Remove(oldest)
+		delete(parsedFiles, oldest.Value.(*parsedFile).name)
+	}
+}
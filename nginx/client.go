@@ -2,6 +2,7 @@ package nginx
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"github.com/go-acme/lego/v3/certcrypto"
@@ -10,6 +11,7 @@ import (
 	"github.com/ihaiker/aginx/util"
 	"os"
 	"strings"
+	"time"
 )
 
 var (
@@ -23,6 +25,7 @@ func Queries(query ...string) []string {
 
 type Client struct {
 	doc     *Configuration
+	loaded  map[string]fileSum //读取时的文件内容，保存时内容没有变化的文件不需要读取存储比较
 	Email   string
 	Engine  plugins.StorageEngine
 	Lego    *lego.Manager
@@ -30,13 +33,13 @@ type Client struct {
 }
 
 func NewClient(email string, engine plugins.StorageEngine, lego *lego.Manager, process *Process) (*Client, error) {
-	doc, err := Readable(engine)
+	doc, loaded, err := readTree(engine)
 	if err != nil {
 		return nil, err
 	}
 	return &Client{
 		Email: email,
-		doc:   doc, Engine: engine, Lego: lego,
+		doc:   doc, loaded: loaded, Engine: engine, Lego: lego,
 		Process: process,
 	}, nil
 }
@@ -51,26 +54,71 @@ func (client Client) Configuration() *Configuration {
 	return client.doc
 }
 
+//文件是否修改过：读取过的文件和读取时的内容比较，新增的文件（例如：新的include）和存储中的内容比较
+func (client Client) changed(file string, content []byte) bool {
+	if sum, has := client.loaded[file]; has {
+		return sum != sha256.Sum256(content)
+	}
+	if cfgFile, err := client.Engine.Get(file); err == nil {
+		return !bytes.Equal(cfgFile.Content, content)
+	}
+	return true //匹配not_found
+}
+
+//修改过还没有保存的文件和文件的内容，按照保存的顺序
+func (client Client) changes() ([]string, map[string][]byte, error) {
+	files, contents := make([]string, 0), make(map[string][]byte)
+	err := Write(client.doc, client.changed, func(file string, content []byte) error {
+		files = append(files, file)
+		contents[file] = content
+		return nil
+	})
+	return files, contents, err
+}
+
+//修改过还没有保存的文件
+func (client Client) Changes() []string {
+	files, _, _ := client.changes()
+	return files
+}
+
+//只保存修改过的文件，没有修改的文件不会写入存储。
+//读取后其他请求修改过的文件不会覆盖，返回ErrConflict，此时所有的文件都不会保存
 func (client Client) Store() error {
-	return Write(client.doc,
-		func(file string, content []byte) bool {
-			if cfgFile, err := client.Engine.Get(file); err == nil {
-				return !bytes.Equal(cfgFile.Content, content)
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	files, contents, err := client.changes()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if client.modified(file) {
+			return fmt.Errorf("%w: %s", ErrConflict, file)
+		}
+	}
+	//多个文件在一个批次中保存，集群存储（例如：git）最后只同步一次
+	return plugins.Batch(client.Engine, func(engine plugins.StorageEngine) error {
+		ctx := plugins.Context(engine)
+		for _, file := range files {
+			//存储支持比较后写入时（例如：consul、etcd），写入时再次检查文件没有被其他节点修改
+			expected := plugins.WithContext(plugins.Expect(ctx, file, client.revision(file)), engine)
+			if err := expected.Put(file, contents[file]); err != nil {
+				return err
+			}
+			logger.Debug("store file ", file)
+			if client.loaded != nil {
+				client.loaded[file] = sha256.Sum256(contents[file])
 			}
-			return true //匹配not_found
-		},
-		func(file string, content []byte) error {
-			return client.Engine.Put(file, content)
-		},
-	)
+		}
+		return nil
+	})
 }
 
 func (client *Client) Select(queries ...string) ([]*Directive, error) {
 	if len(queries) == 0 {
 		return client.doc.Body, nil
-	} else {
-		return client.doc.Select(queries...)
 	}
+	return client.doc.Select(queries...)
 }
 
 func (client *Client) MustSelect(queries ...string) []*Directive {
@@ -109,19 +157,24 @@ func (client *Client) Delete(queries ...string) error {
 	err = ErrNotFound
 	for _, directive := range directives {
 
-		deleteDirectiveIdx := make([]int, 0)
-		for i, body := range directive.Body {
+		matched := make([]*Directive, 0)
+		for _, body := range directive.Body {
 			if expr.Match(body) {
-				deleteDirectiveIdx = append(deleteDirectiveIdx, i)
+				matched = append(matched, body)
 			}
 		}
-		if len(deleteDirectiveIdx) > 0 {
+		deleteDirectives := make(map[*Directive]bool)
+		for _, body := range expr.Pick(matched) {
+			deleteDirectives[body] = true
+		}
+		if len(deleteDirectives) > 0 {
 			err = nil
 		}
 
-		for i := len(deleteDirectiveIdx) - 1; i >= 0; i-- {
-			idx := deleteDirectiveIdx[i]
-			directive.Body = append(directive.Body[:idx], directive.Body[idx+1:]...)
+		for i := len(directive.Body) - 1; i >= 0; i-- {
+			if deleteDirectives[directive.Body[i]] {
+				directive.Body = append(directive.Body[:i], directive.Body[i+1:]...)
+			}
 		}
 	}
 	return err
@@ -141,8 +194,8 @@ func (client *Client) Modify(queries []string, directive *Directive) error {
 }
 
 func (client *Client) hostsd(include string) {
-	directives, err := client.Select("http", "include")
-	exists := os.IsNotExist(err)
+	directives, _ := client.Select("http", "include")
+	exists := false
 	for _, directive := range directives {
 		if include == directive.Args[0] {
 			exists = true
@@ -158,8 +211,38 @@ func (client *Client) SimpleServer(domain string, ssl bool, address ...string) (
 	defer util.Catch(func(e error) {
 		logger.WithError(err).Debug("new simple server ", domain, strings.Join(address, ","))
 	})
-	client.hostsd("hosts.d/*.conf")
 
+	upstream, server := SimpleServer(domain, address...)
+	if !ssl {
+		return client.HostServer(domain, upstream, server)
+	}
+
+	sslFile := client.NewCertificate(client.Email, domain)
+	listen := server.MustSelect("listen")[0]
+	listen.Args = []string{"443", "ssl"}
+
+	server.AddBody("ssl_certificate", sslFile.Certificate)
+	server.AddBody("ssl_certificate_key", sslFile.PrivateKey)
+	server.AddBody("ssl_session_timeout", "5m")
+	server.AddBody("ssl_ciphers", "ECDHE-RSA-AES128-GCM-SHA256:ECDHE:ECDH:AES:HIGH:!NULL:!aNULL:!MD5:!ADH:!RC4")
+	server.AddBody("ssl_protocols", "TLSv1", "TLSv1.1", "TLSv1.2")
+	server.AddBody("ssl_prefer_server_ciphers", "on")
+
+	rewrite := NewDirective("server")
+	{
+		rewrite.AddBody("listen", "80")
+		rewrite.AddBody("server_name", domain)
+		rewrite.AddBody("return", "301", "https://$host$request_uri")
+	}
+	return client.HostServer(domain, upstream, rewrite, server)
+}
+
+//发布domain的配置到 hosts.d/<domain>.ngx.conf，已经存在的server和对应的upstream会被删除
+func (client *Client) HostServer(domain string, directives ...*Directive) (err error) {
+	defer util.Catch(func(e error) {
+		err = e
+		logger.WithError(e).Debug("new host server ", domain)
+	})
 	upstreamName := UpstreamName(domain)
 
 	serverQueries, selectServers := client.selectServer("http", domain)
@@ -180,42 +263,20 @@ func (client *Client) SimpleServer(domain string, ssl bool, address ...string) (
 		_ = client.Delete(upstreamQueries...)
 	}
 
-	upstream, server := SimpleServer(domain, address...)
-	if ssl {
-		sslFile := client.NewCertificate(client.Email, domain)
-		listen := server.MustSelect("listen")[0]
-		listen.Args = []string{"443", "ssl"}
-
-		server.AddBody("ssl_certificate", sslFile.Certificate)
-		server.AddBody("ssl_certificate_key", sslFile.PrivateKey)
-		server.AddBody("ssl_session_timeout", "5m")
-		server.AddBody("ssl_ciphers", "ECDHE-RSA-AES128-GCM-SHA256:ECDHE:ECDH:AES:HIGH:!NULL:!aNULL:!MD5:!ADH:!RC4")
-		server.AddBody("ssl_protocols", "TLSv1", "TLSv1.1", "TLSv1.2")
-		server.AddBody("ssl_prefer_server_ciphers", "on")
-	}
-	rewrite := NewDirective("server")
-	{
-		rewrite.AddBody("listen", "80")
-		rewrite.AddBody("server_name", domain)
-		rewrite.AddBody("return", "301", "https://$host$request_uri")
-	}
+	return client.hostFile(domain, directives...)
+}
 
+//添加配置到 hosts.d/<domain>.ngx.conf
+func (client *Client) hostFile(domain string, directives ...*Directive) (err error) {
+	client.hostsd("hosts.d/*.conf")
 	files, err := client.Select("http", "include('hosts.d/*.conf')", fmt.Sprintf("file('hosts.d/%s.ngx.conf')", domain))
 	if os.IsNotExist(err) {
 		file := NewDirective("file", fmt.Sprintf("hosts.d/%s.ngx.conf", domain))
 		file.Virtual = Include
-		if ssl {
-			file.AddBodyDirective(upstream, rewrite, server)
-		} else {
-			file.AddBodyDirective(upstream, server)
-		}
+		file.AddBodyDirective(directives...)
 		err = client.Add(Queries("http", "include('hosts.d/*.conf')"), file)
 	} else {
-		if ssl {
-			files[0].Body = append(files[0].Body, upstream, rewrite, server)
-		} else {
-			files[0].Body = append(files[0].Body, upstream, server)
-		}
+		files[0].Body = append(files[0].Body, directives...)
 	}
 	return
 }
@@ -254,10 +315,28 @@ func (self *Client) NewCertificate(email, domain string) *lego.StoreFile {
 	if email == "" {
 		email = self.Email
 	}
-	if cert, has := self.Lego.CertificateStorage.Get(domain); has {
+	if cert, has := self.Lego.CertificateStorage.Match(domain); has {
 		return cert.GetStoreFile()
 	}
+	//申请泛域名证书，所有子域名共用
+	if wildcard := lego.WildcardDomain(domain); self.Lego.DNSProvider != nil && self.Lego.Wildcard && wildcard != "" {
+		domain = wildcard
+	}
+	return self.obtainCertificate(email, domain)
+}
 
+//重新申请证书，证书文件路径不变。泛域名证书替换匹配的server使用的证书，只修改配置，由调用者测试、保存并重新加载nginx
+func (self *Client) RenewCertificate(domain string) *lego.StoreFile {
+	cert, has := self.Lego.CertificateStorage.Get(domain)
+	if !has {
+		util.PanicIfError(ErrNotFound)
+	} else if cert.Manual {
+		util.PanicIfError(lego.ErrManualCertificate)
+	}
+	return self.obtainCertificate(cert.Email, cert.Domain)
+}
+
+func (self *Client) obtainCertificate(email, domain string) *lego.StoreFile {
 	var err error
 	account, has := self.Lego.AccountStorage.Get(email)
 	if !has {
@@ -265,9 +344,136 @@ func (self *Client) NewCertificate(email, domain string) *lego.StoreFile {
 		util.PanicIfError(err)
 	}
 
-	provider := NewAginxProvider(self, self.Process)
-	cert, err := self.Lego.CertificateStorage.NewWithProvider(account, domain, provider)
+	var cert *lego.Certificate
+	if self.Lego.DNSProvider != nil {
+		cert, err = self.Lego.CertificateStorage.NewWithDNSProvider(account, domain, self.Lego.DNSProvider)
+	} else {
+		util.AssertTrue(!lego.IsWildcard(domain), "wildcard certificate requires --dns-provider: "+domain)
+		provider := NewAginxProvider(self, self.Process)
+		cert, err = self.Lego.CertificateStorage.NewWithProvider(account, domain, provider)
+	}
 	util.PanicIfError(err)
 
-	return cert.GetStoreFile()
+	storeFile := cert.GetStoreFile()
+	if lego.IsWildcard(domain) {
+		self.UseCertificate(domain, storeFile)
+	}
+	return storeFile
+}
+
+//上传证书，替换匹配的server使用的证书（只修改配置）
+func (self *Client) ManualCertificate(domain string, certPEM, keyPEM []byte) *lego.StoreFile {
+	cert, err := self.Lego.CertificateStorage.Manual(domain, certPEM, keyPEM)
+	util.PanicIfError(err)
+	storeFile := cert.GetStoreFile()
+	self.UseCertificate(cert.Domain, storeFile)
+	return storeFile
+}
+
+//生成自签名证书，server_name为domain的server使用此证书，未开启ssl的server添加443端口监听（只修改配置）
+func (self *Client) SelfSignedCertificate(domain string, validity time.Duration) *lego.StoreFile {
+	cert, err := self.Lego.CertificateStorage.SelfSigned(domain, validity)
+	util.PanicIfError(err)
+	storeFile := cert.GetStoreFile()
+	AttachCertificate(self.doc, domain, storeFile)
+	EnableCertificate(self.doc, domain, storeFile)
+	return storeFile
+}
+
+//删除上传的证书，证书正在被使用时返回错误
+func (self *Client) RemoveCertificate(domain string) error {
+	cert, has := self.Lego.CertificateStorage.Get(domain)
+	if !has {
+		return ErrNotFound
+	} else if !cert.Manual {
+		return fmt.Errorf("the certificate %s is not uploaded manually", domain)
+	}
+	if usedCertificate(self.doc, cert.GetStoreFile().Certificate) {
+		return fmt.Errorf("the certificate %s is in use", domain)
+	}
+	return self.Lego.CertificateStorage.Remove(domain)
+}
+
+func usedCertificate(directive *Directive, certificate string) bool {
+	for _, body := range directive.Body {
+		if body.Name == "ssl_certificate" && len(body.Args) > 0 && body.Args[0] == certificate {
+			return true
+		} else if usedCertificate(body, certificate) {
+			return true
+		}
+	}
+	return false
+}
+
+//配置了ssl_certificate并且server_name匹配domain的server使用此证书，返回修改的server数量。
+//只修改配置，由调用者测试、保存并重新加载nginx（例如：NewServer中申请证书时配置还没有修改完成）
+func (self *Client) UseCertificate(domain string, file *lego.StoreFile) int {
+	return AttachCertificate(self.doc, domain, file)
+}
+
+//替换所有匹配的server（server_name为domain，domain为泛域名时包括其一级子域名，并且配置了ssl_certificate）使用的证书，返回替换的server数量
+func AttachCertificate(conf *Configuration, domain string, file *lego.StoreFile) (count int) {
+	for _, directive := range conf.Body {
+		if directive.Name != "server" {
+			count += AttachCertificate(directive, domain, file)
+			continue
+		}
+		certificate, err := directive.Select("ssl_certificate")
+		if err != nil {
+			continue
+		}
+		serverNames, err := directive.Select("server_name")
+		if err != nil || !matchServerName(serverNames[0].Args, domain) {
+			continue
+		}
+		if certificate[0].Args[0] == file.Certificate {
+			continue
+		}
+		certificate[0].Args = []string{file.Certificate}
+		if certificateKey, err := directive.Select("ssl_certificate_key"); err == nil {
+			certificateKey[0].Args = []string{file.PrivateKey}
+		} else {
+			directive.AddBody("ssl_certificate_key", file.PrivateKey)
+		}
+		count++
+	}
+	return
+}
+
+//server_name为domain并且没有配置ssl_certificate的http server添加 listen 443 ssl 和证书，返回修改的server数量
+func EnableCertificate(conf *Configuration, domain string, file *lego.StoreFile) (count int) {
+	for _, directive := range conf.Body {
+		if directive.Name == "stream" {
+			continue
+		} else if directive.Name != "server" {
+			count += EnableCertificate(directive, domain, file)
+			continue
+		}
+		if _, err := directive.Select("ssl_certificate"); err == nil {
+			continue
+		}
+		serverNames, err := directive.Select("server_name")
+		if err != nil || !matchServerName(serverNames[0].Args, domain) {
+			continue
+		}
+		if _, err := directive.Select("return"); err == nil {
+			continue //跳转使用的server
+		}
+		directive.Body = append([]*Directive{
+			NewDirective("listen", "443", "ssl"),
+			NewDirective("ssl_certificate", file.Certificate),
+			NewDirective("ssl_certificate_key", file.PrivateKey),
+		}, directive.Body...)
+		count++
+	}
+	return
+}
+
+func matchServerName(serverNames []string, domain string) bool {
+	for _, serverName := range serverNames {
+		if serverName != domain && !lego.MatchWildcard(domain, serverName) {
+			return false
+		}
+	}
+	return len(serverNames) > 0
 }
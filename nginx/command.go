@@ -0,0 +1,93 @@
+package nginx
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/util"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+)
+
+//nginx运行在docker容器中时的容器名称，使用 docker exec 执行nginx命令
+var dockerContainer = ""
+
+//指定的nginx配置文件，为空时使用 nginx -h 中的默认配置
+var confFile = ""
+
+//设置管理nginx的方式：为空或者local 使用本地的nginx命令，
+//docker://container[?conf=/etc/nginx/nginx.conf] 管理docker容器中的nginx，nginx配置目录需要同时挂载到aginx中
+func UseNginx(config string) error {
+	if config == "" || config == "local" {
+		dockerContainer, confFile = "", ""
+		return nil
+	}
+	//windows的配置文件路径，例如：C:\nginx\conf\nginx.conf
+	if filepath.VolumeName(config) != "" {
+		dockerContainer, confFile = "", config
+		return nil
+	}
+	u, err := url.Parse(config)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "docker":
+		if u.Host == "" {
+			return errors.New("the nginx container is empty: " + config)
+		}
+		dockerContainer = u.Host
+		confFile = u.Query().Get("conf")
+		return nil
+	case "local", "file":
+		dockerContainer = ""
+		confFile = localPath(u.Path)
+		return nil
+	}
+	return errors.New("nginx not support: " + config)
+}
+
+//url中的本地路径，file:///C:/nginx/conf/nginx.conf 的路径为 C:\nginx\conf\nginx.conf
+func localPath(path string) string {
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+//nginx是否运行在docker容器中
+func InDocker() bool {
+	return dockerContainer != ""
+}
+
+//执行nginx命令使用的程序和参数
+func command(args ...string) (string, []string) {
+	if InDocker() {
+		return "docker", append([]string{"exec", dockerContainer, "nginx"}, args...)
+	}
+	if prefix := localPrefix(); prefix != "" {
+		return "nginx", append([]string{"-p", prefix}, args...)
+	}
+	return "nginx", args
+}
+
+//执行nginx命令并等待结束，例如：nginx -s reload
+func runCommand(args ...string) error {
+	name, args := command(args...)
+	return util.CmdRun(name, args...)
+}
+
+func nginxCommand(args ...string) *exec.Cmd {
+	name, args := command(args...)
+	return exec.Command(name, args...)
+}
+
+//在容器中测试临时目录中的配置，临时目录复制到容器中相同的位置
+func dockerTest(testDir string) ([]byte, error) {
+	if out, err := exec.Command("docker", "cp", testDir, dockerContainer+":"+testDir).CombinedOutput(); err != nil {
+		return out, err
+	}
+	defer func() {
+		_ = exec.Command("docker", "exec", dockerContainer, "rm", "-rf", testDir).Run()
+	}()
+	return nginxCommand("-t", "-c", filepath.Join(testDir, NGINX_CONF)).CombinedOutput()
+}
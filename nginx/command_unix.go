@@ -0,0 +1,27 @@
+// +build !windows
+
+package nginx
+
+import (
+	"os"
+	"syscall"
+)
+
+//本地nginx的prefix，使用nginx编译时的默认值
+func localPrefix() string {
+	return ""
+}
+
+//通知nginx重新打开日志文件
+func reopenProcess(process *os.Process) error {
+	return process.Signal(syscall.SIGUSR1)
+}
+
+//优雅停止nginx，args为启动时的 -p、-c 参数
+func quitProcess(process *os.Process, args ...string) error {
+	return process.Signal(syscall.SIGQUIT)
+}
+
+func stopProcess(process *os.Process) error {
+	return process.Kill()
+}
@@ -0,0 +1,43 @@
+package nginx
+
+import (
+	"github.com/ihaiker/aginx/util"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	prefixOnce sync.Once
+	prefix     string
+)
+
+//windows中nginx的prefix为当前目录，使用nginx.exe所在的目录
+func localPrefix() string {
+	prefixOnce.Do(func() {
+		if exe, err := exec.LookPath("nginx"); err == nil {
+			if exe, err = filepath.Abs(exe); err == nil {
+				prefix = filepath.Dir(exe) + string(filepath.Separator)
+			}
+		}
+	})
+	return prefix
+}
+
+//windows不支持信号，使用 nginx -s 通知nginx
+func reopenProcess(process *os.Process) error {
+	return runCommand("-s", "reopen")
+}
+
+func quitProcess(process *os.Process, args ...string) error {
+	return runCommand(append(args, "-s", "quit")...)
+}
+
+//结束master进程不会结束worker进程，先使用 nginx -s quit
+func stopProcess(process *os.Process) error {
+	if err := runCommand("-s", "quit"); err == nil {
+		return nil
+	}
+	return process.Kill()
+}
@@ -18,29 +18,78 @@ type Directive struct {
 	Name    string       `json:"name"`
 	Args    []string     `json:"args,omitempty"`
 	Body    []*Directive `json:"body,omitempty"`
+
+	Comments    []string `json:"comments,omitempty"`     //指令前的注释，空字符串为空行
+	Comment     string   `json:"comment,omitempty"`      //指令行尾的注释
+	EndComments []string `json:"end_comments,omitempty"` //块结束（文件结尾）前的注释
+	Indent      string   `json:"indent,omitempty"`       //文件的缩进，只用于配置文件（Configuration和include的文件）
 }
 
+const defaultIndent = "    "
+
 type Configuration = Directive
 
 func NewDirective(name string, args ...string) *Directive {
 	return &Directive{Name: name, Args: args}
 }
 
+//深度拷贝
+func (d *Directive) Clone() *Directive {
+	clone := &Directive{Virtual: d.Virtual, Name: d.Name, Comment: d.Comment, Indent: d.Indent}
+	if d.Comments != nil {
+		clone.Comments = append([]string{}, d.Comments...)
+	}
+	if d.EndComments != nil {
+		clone.EndComments = append([]string{}, d.EndComments...)
+	}
+	if d.Args != nil {
+		clone.Args = make([]string, len(d.Args))
+		copy(clone.Args, d.Args)
+	}
+	if d.Body != nil {
+		clone.Body = make([]*Directive, len(d.Body))
+		for i, body := range d.Body {
+			clone.Body[i] = body.Clone()
+		}
+	}
+	return clone
+}
+
 func (d *Directive) String() string {
 	return d.Pretty(0)
 }
 
+//配置文件的内容，使用文件原有的缩进
 func (d *Directive) BodyBytes() []byte {
+	indent := d.Indent
+	if indent == "" {
+		indent = defaultIndent
+	}
 	out := bytes.NewBufferString("")
 	for _, body := range d.Body {
-		out.WriteString(body.Pretty(0))
-		out.WriteString("\n")
+		if body.Virtual == "" {
+			out.WriteString(body.pretty(indent, 0))
+			out.WriteString("\n")
+		}
 	}
+	writeComments(out, d.EndComments, "")
 	return out.Bytes()
 }
 
+func writeComments(out *bytes.Buffer, comments []string, prefix string) {
+	for _, comment := range comments {
+		if comment != "" {
+			out.WriteString(prefix)
+			out.WriteString(comment)
+		}
+		out.WriteString("\n")
+	}
+}
+
 func (d *Directive) noBody() bool {
-	if len(d.Body) == 0 {
+	if len(d.EndComments) > 0 {
+		return false
+	} else if len(d.Body) == 0 {
 		return true
 	} else {
 		for _, body := range d.Body {
@@ -66,43 +115,64 @@ func (d *Directive) AddBodyDirective(directive ...*Directive) {
 }
 
 func (d *Directive) Pretty(prefix int) string {
-	prefixString := strings.Repeat(" ", prefix*4)
+	return d.pretty(defaultIndent, prefix)
+}
+
+func (d *Directive) pretty(indent string, prefix int) string {
+	prefixString := strings.Repeat(indent, prefix)
 	if d.Virtual != "" {
 		return ""
 	} else {
-		out := bytes.NewBufferString(prefixString)
+		out := bytes.NewBufferString("")
+		writeComments(out, d.Comments, prefixString)
+		out.WriteString(prefixString)
 		out.WriteString(d.Name)
-		out.WriteString(" ")
 		if len(d.Args) > 0 {
+			out.WriteString(" ")
 			out.WriteString(strings.Join(d.Args, " "))
 		}
 
 		if d.noBody() {
 			out.WriteString(";")
+			d.writeComment(out)
 		} else {
 			out.WriteString(" {")
+			d.writeComment(out)
 			for _, body := range d.Body {
-				out.WriteString("\n")
-				out.WriteString(body.Pretty(prefix + 1))
+				if body.Virtual == "" {
+					out.WriteString("\n")
+					out.WriteString(body.pretty(indent, prefix+1))
+				}
 			}
-			out.WriteString(fmt.Sprintf("\n%s}", prefixString))
+			out.WriteString("\n")
+			writeComments(out, d.EndComments, prefixString+indent)
+			out.WriteString(fmt.Sprintf("%s}", prefixString))
 		}
 		return out.String()
 	}
 }
 
+func (d *Directive) writeComment(out *bytes.Buffer) {
+	if d.Comment != "" {
+		out.WriteString(" ")
+		out.WriteString(d.Comment)
+	}
+}
+
 func (d *Directive) find(directives []*Directive, query string) ([]*Directive, error) {
 	expr, err := Parser(query)
 	if err != nil {
-		return nil, fmt.Errorf("Search condition error：[%s]", query)
+		return nil, fmt.Errorf("Search condition error：[%s] %w", query, err)
 	}
 	matched := make([]*Directive, 0)
 	for _, directive := range directives {
+		children := make([]*Directive, 0)
 		for _, body := range directive.Body {
 			if expr.Match(body) {
-				matched = append(matched, body)
+				children = append(children, body)
 			}
 		}
+		matched = append(matched, expr.Pick(children)...)
 	}
 	return matched, nil
 }
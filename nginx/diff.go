@@ -0,0 +1,72 @@
+package nginx
+
+import (
+	"github.com/ihaiker/aginx/util"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	DiffModified = "modified"
+	DiffAdded    = "added"   //只存在于比较的目标中
+	DiffRemoved  = "removed" //只存在于比较的来源中
+)
+
+//文件差异
+type FileDiff struct {
+	File   string `json:"file"`
+	Status string `json:"status"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+//比较两组文件(相对路径:内容)，返回有差异的文件，按文件名排序
+func DiffFiles(from, to map[string][]byte) []*FileDiff {
+	diffs := make([]*FileDiff, 0)
+	for name, content := range from {
+		if toContent, has := to[name]; !has {
+			diffs = append(diffs, &FileDiff{File: name, Status: DiffRemoved, Diff: util.Diff(string(content), "")})
+		} else if string(content) != string(toContent) {
+			diffs = append(diffs, &FileDiff{File: name, Status: DiffModified, Diff: util.Diff(string(content), string(toContent))})
+		}
+	}
+	for name, content := range to {
+		if _, has := from[name]; !has {
+			diffs = append(diffs, &FileDiff{File: name, Status: DiffAdded, Diff: util.Diff("", string(content))})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].File < diffs[j].File
+	})
+	return diffs
+}
+
+func relative(dir string, files map[string][]byte) map[string][]byte {
+	relativeFiles := make(map[string][]byte, len(files))
+	for path, content := range files {
+		if name, err := filepath.Rel(dir, path); err == nil {
+			relativeFiles[filepath.ToSlash(name)] = content
+		}
+	}
+	return relativeFiles
+}
+
+//本地配置目录中的全部文件(相对路径:内容)
+func LocalFiles() (map[string][]byte, error) {
+	dir := MustConfigDir()
+	files, err := snapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return relative(dir, files), nil
+}
+
+//nginx最后一次成功加载(启动或者重启)时的本地配置文件，nginx没有加载过配置时返回nil
+func (sp *Process) Loaded() (map[string][]byte, time.Time) {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	if sp.lastGood == nil {
+		return nil, sp.lastGoodAt
+	}
+	return relative(MustConfigDir(), sp.lastGood), sp.lastGoodAt
+}
@@ -0,0 +1,207 @@
+package nginx
+
+import (
+	"sort"
+	"strings"
+)
+
+//nginx指令文档
+type DirectiveDoc struct {
+	Name     string   `json:"name"`
+	Module   string   `json:"module"`
+	Syntax   string   `json:"syntax"`
+	Default  string   `json:"default,omitempty"`
+	Contexts []string `json:"contexts"`
+	URL      string   `json:"url"`
+}
+
+//指令是否可以在context中使用，if in server, if in location 的指令可以使用在 if 中
+func (doc *DirectiveDoc) Allowed(context string) bool {
+	for _, allowed := range doc.Contexts {
+		if allowed == "any" || allowed == context ||
+			(allowed == "if" && strings.HasPrefix(context, "if in ")) {
+			return true
+		}
+	}
+	return false
+}
+
+var directiveDocs = map[string]*DirectiveDoc{}
+
+//syntax的第一个单词为指令名称，contexts使用逗号分隔
+func docDirective(module, syntax, defaultValue, contexts string) {
+	name := strings.Fields(syntax)[0]
+	name = strings.TrimSuffix(name, ";")
+	path := module
+	if strings.HasPrefix(module, "ngx_http_") {
+		path = "http/" + module
+	} else if strings.HasPrefix(module, "ngx_stream_") {
+		path = "stream/" + module
+	}
+	directive := &DirectiveDoc{
+		Name: name, Module: module, Syntax: syntax, Default: defaultValue,
+		Contexts: strings.Split(contexts, ", "),
+		URL:      "https://nginx.org/en/docs/" + path + ".html#" + name,
+	}
+	directiveDocs[name] = directive
+}
+
+//查询指令文档，没有时返回nil
+func GetDirectiveDoc(name string) *DirectiveDoc {
+	return directiveDocs[name]
+}
+
+//全部有文档的指令名称
+func DirectiveDocNames() []string {
+	names := make([]string, 0, len(directiveDocs))
+	for name := range directiveDocs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	core := "ngx_core_module"
+	docDirective(core, "daemon on | off;", "daemon on;", "main")
+	docDirective(core, "env variable[=value];", "env TZ;", "main")
+	docDirective(core, "error_log file [level];", "error_log logs/error.log error;", "main, http, mail, stream, server, location")
+	docDirective(core, "events { ... }", "", "main")
+	docDirective(core, "include file | mask;", "", "any")
+	docDirective(core, "load_module file;", "", "main")
+	docDirective(core, "pcre_jit on | off;", "pcre_jit off;", "main")
+	docDirective(core, "pid file;", "pid logs/nginx.pid;", "main")
+	docDirective(core, "user user [group];", "user nobody nobody;", "main")
+	docDirective(core, "worker_cpu_affinity cpumask ...;", "", "main")
+	docDirective(core, "worker_processes number | auto;", "worker_processes 1;", "main")
+	docDirective(core, "worker_rlimit_nofile number;", "", "main")
+	docDirective(core, "worker_shutdown_timeout time;", "", "main")
+	docDirective(core, "worker_connections number;", "worker_connections 512;", "events")
+	docDirective(core, "use method;", "", "events")
+	docDirective(core, "multi_accept on | off;", "multi_accept off;", "events")
+	docDirective(core, "accept_mutex on | off;", "accept_mutex off;", "events")
+
+	http := "ngx_http_core_module"
+	docDirective(http, "http { ... }", "", "main")
+	//http中的server块和upstream中的server
+	docDirective(http, "server { ... } | server address [parameters];", "", "http, upstream")
+	docDirective(http, "location [ = | ~ | ~* | ^~ ] uri { ... } | location @name { ... }", "", "server, location")
+	docDirective(http, "listen address[:port] [default_server] [ssl] [http2 | quic] [proxy_protocol] ...;", "listen *:80 | *:8000;", "server")
+	docDirective(http, "server_name name ...;", `server_name "";`, "server")
+	docDirective(http, "root path;", "root html;", "http, server, location, if in location")
+	docDirective(http, "alias path;", "", "location")
+	docDirective(http, "try_files file ... uri | try_files file ... =code;", "", "server, location")
+	docDirective(http, "error_page code ... [=[response]] uri;", "", "http, server, location, if in location")
+	docDirective(http, "internal;", "", "location")
+	docDirective(http, "limit_except method ... { ... }", "", "location")
+	docDirective(http, "client_max_body_size size;", "client_max_body_size 1m;", "http, server, location")
+	docDirective(http, "client_body_buffer_size size;", "client_body_buffer_size 8k|16k;", "http, server, location")
+	docDirective(http, "client_body_timeout time;", "client_body_timeout 60s;", "http, server, location")
+	docDirective(http, "client_header_timeout time;", "client_header_timeout 60s;", "http, server")
+	docDirective(http, "large_client_header_buffers number size;", "large_client_header_buffers 4 8k;", "http, server")
+	docDirective(http, "keepalive_timeout timeout [header_timeout];", "keepalive_timeout 75s;", "http, server, location")
+	docDirective(http, "keepalive_requests number;", "keepalive_requests 1000;", "http, server, location")
+	docDirective(http, "send_timeout time;", "send_timeout 60s;", "http, server, location")
+	docDirective(http, "sendfile on | off;", "sendfile off;", "http, server, location, if in location")
+	docDirective(http, "tcp_nopush on | off;", "tcp_nopush off;", "http, server, location")
+	docDirective(http, "tcp_nodelay on | off;", "tcp_nodelay on;", "http, server, location")
+	docDirective(http, "server_tokens on | off | build | string;", "server_tokens on;", "http, server, location")
+	docDirective(http, "types { ... }", "", "http, server, location")
+	docDirective(http, "default_type mime-type;", "default_type text/plain;", "http, server, location")
+	docDirective(http, "resolver address ... [valid=time] [ipv4=on|off] [ipv6=on|off];", "", "http, server, location")
+	docDirective(http, "resolver_timeout time;", "resolver_timeout 30s;", "http, server, location")
+	docDirective(http, "limit_rate rate;", "limit_rate 0;", "http, server, location, if in location")
+	docDirective(http, "server_names_hash_bucket_size size;", "server_names_hash_bucket_size 32|64|128;", "http")
+	docDirective(http, "server_names_hash_max_size size;", "server_names_hash_max_size 512;", "http")
+	docDirective(http, "types_hash_max_size size;", "types_hash_max_size 1024;", "http, server, location")
+
+	docDirective("ngx_http_index_module", "index file ...;", "index index.html;", "http, server, location")
+	docDirective("ngx_http_autoindex_module", "autoindex on | off;", "autoindex off;", "http, server, location")
+	docDirective("ngx_http_charset_module", "charset charset | off;", "charset off;", "http, server, location, if in location")
+	docDirective("ngx_http_log_module", "access_log path [format [buffer=size] [gzip[=level]] [flush=time] [if=condition]] | off;",
+		"access_log logs/access.log combined;", "http, server, location, if in location, limit_except")
+	docDirective("ngx_http_log_module", "log_format name [escape=default|json|none] string ...;", `log_format combined "...";`, "http")
+
+	rewrite := "ngx_http_rewrite_module"
+	docDirective(rewrite, "if (condition) { ... }", "", "server, location")
+	docDirective(rewrite, "return code [text] | return code URL | return URL;", "", "server, location, if")
+	docDirective(rewrite, "rewrite regex replacement [flag];", "", "server, location, if")
+	docDirective(rewrite, "set $variable value;", "", "server, location, if")
+	docDirective(rewrite, "break;", "", "server, location, if")
+	docDirective(rewrite, "rewrite_log on | off;", "rewrite_log off;", "http, server, location, if")
+
+	headers := "ngx_http_headers_module"
+	docDirective(headers, "add_header name value [always];", "", "http, server, location, if in location")
+	docDirective(headers, "expires [modified] time | epoch | max | off;", "expires off;", "http, server, location, if in location")
+
+	access := "ngx_http_access_module"
+	docDirective(access, "allow address | CIDR | unix: | all;", "", "http, server, location, limit_except")
+	docDirective(access, "deny address | CIDR | unix: | all;", "", "http, server, location, limit_except")
+	docDirective("ngx_http_auth_basic_module", "auth_basic string | off;", "auth_basic off;", "http, server, location, limit_except")
+	docDirective("ngx_http_auth_basic_module", "auth_basic_user_file file;", "", "http, server, location, limit_except")
+	docDirective("ngx_http_auth_request_module", "auth_request uri | off;", "auth_request off;", "http, server, location")
+
+	gzip := "ngx_http_gzip_module"
+	docDirective(gzip, "gzip on | off;", "gzip off;", "http, server, location, if in location")
+	docDirective(gzip, "gzip_types mime-type ...;", "gzip_types text/html;", "http, server, location")
+	docDirective(gzip, "gzip_comp_level level;", "gzip_comp_level 1;", "http, server, location")
+	docDirective(gzip, "gzip_min_length length;", "gzip_min_length 20;", "http, server, location")
+	docDirective(gzip, "gzip_vary on | off;", "gzip_vary off;", "http, server, location")
+	docDirective(gzip, "gzip_proxied off | expired | no-cache | no-store | private | no_last_modified | no_etag | auth | any ...;", "gzip_proxied off;", "http, server, location")
+
+	upstream := "ngx_http_upstream_module"
+	docDirective(upstream, "upstream name { ... }", "", "http")
+	docDirective(upstream, "keepalive connections;", "", "upstream")
+	docDirective(upstream, "least_conn;", "", "upstream")
+	docDirective(upstream, "ip_hash;", "", "upstream")
+	docDirective(upstream, "hash key [consistent];", "", "upstream")
+
+	proxy := "ngx_http_proxy_module"
+	docDirective(proxy, "proxy_pass URL;", "", "location, if in location, limit_except")
+	docDirective(proxy, "proxy_set_header field value;", "proxy_set_header Host $proxy_host; proxy_set_header Connection close;", "http, server, location")
+	docDirective(proxy, "proxy_hide_header field;", "", "http, server, location")
+	docDirective(proxy, "proxy_http_version 1.0 | 1.1;", "proxy_http_version 1.0;", "http, server, location")
+	docDirective(proxy, "proxy_connect_timeout time;", "proxy_connect_timeout 60s;", "http, server, location")
+	docDirective(proxy, "proxy_read_timeout time;", "proxy_read_timeout 60s;", "http, server, location")
+	docDirective(proxy, "proxy_send_timeout time;", "proxy_send_timeout 60s;", "http, server, location")
+	docDirective(proxy, "proxy_buffering on | off;", "proxy_buffering on;", "http, server, location")
+	docDirective(proxy, "proxy_buffers number size;", "proxy_buffers 8 4k|8k;", "http, server, location")
+	docDirective(proxy, "proxy_buffer_size size;", "proxy_buffer_size 4k|8k;", "http, server, location")
+	docDirective(proxy, "proxy_redirect default | off | redirect replacement;", "proxy_redirect default;", "http, server, location")
+	docDirective(proxy, "proxy_next_upstream error | timeout | invalid_header | http_500 | http_502 | http_503 | http_504 | http_403 | http_404 | http_429 | non_idempotent | off ...;",
+		"proxy_next_upstream error timeout;", "http, server, location")
+	docDirective(proxy, "proxy_intercept_errors on | off;", "proxy_intercept_errors off;", "http, server, location")
+	docDirective(proxy, "proxy_cache zone | off;", "proxy_cache off;", "http, server, location")
+	docDirective(proxy, "proxy_cache_path path [levels=levels] keys_zone=name:size [inactive=time] [max_size=size] ...;", "", "http")
+	docDirective(proxy, "proxy_cache_valid [code ...] time;", "", "http, server, location")
+	docDirective("ngx_http_fastcgi_module", "fastcgi_pass address;", "", "location, if in location")
+	docDirective("ngx_http_fastcgi_module", "fastcgi_param parameter value [if_not_empty];", "", "http, server, location")
+	docDirective("ngx_http_grpc_module", "grpc_pass address;", "", "location, if in location")
+
+	ssl := "ngx_http_ssl_module"
+	docDirective(ssl, "ssl_certificate file;", "", "http, server")
+	docDirective(ssl, "ssl_certificate_key file;", "", "http, server")
+	docDirective(ssl, "ssl_protocols [SSLv2] [SSLv3] [TLSv1] [TLSv1.1] [TLSv1.2] [TLSv1.3];", "ssl_protocols TLSv1.2 TLSv1.3;", "http, server")
+	docDirective(ssl, "ssl_ciphers ciphers;", "ssl_ciphers HIGH:!aNULL:!MD5;", "http, server")
+	docDirective(ssl, "ssl_prefer_server_ciphers on | off;", "ssl_prefer_server_ciphers off;", "http, server")
+	docDirective(ssl, "ssl_session_cache off | none | [builtin[:size]] [shared:name:size];", "ssl_session_cache none;", "http, server")
+	docDirective(ssl, "ssl_session_timeout time;", "ssl_session_timeout 5m;", "http, server")
+	docDirective(ssl, "ssl_stapling on | off;", "ssl_stapling off;", "http, server")
+	docDirective(ssl, "ssl_dhparam file;", "", "http, server")
+	docDirective(ssl, "ssl_client_certificate file;", "", "http, server")
+	docDirective(ssl, "ssl_verify_client on | off | optional | optional_no_ca;", "ssl_verify_client off;", "http, server")
+	docDirective("ngx_http_v2_module", "http2 on | off;", "http2 off;", "http, server")
+
+	docDirective("ngx_http_limit_req_module", "limit_req zone=name [burst=number] [nodelay | delay=number];", "", "http, server, location")
+	docDirective("ngx_http_limit_req_module", "limit_req_zone key zone=name:size rate=rate [sync];", "", "http")
+	docDirective("ngx_http_limit_conn_module", "limit_conn zone number;", "", "http, server, location")
+	docDirective("ngx_http_limit_conn_module", "limit_conn_zone key zone=name:size;", "", "http")
+	docDirective("ngx_http_realip_module", "real_ip_header field | X-Real-IP | X-Forwarded-For | proxy_protocol;", "real_ip_header X-Real-IP;", "http, server, location")
+	docDirective("ngx_http_realip_module", "set_real_ip_from address | CIDR | unix:;", "", "http, server, location")
+	docDirective("ngx_http_map_module", "map string $variable { ... }", "", "http")
+	docDirective("ngx_http_geo_module", "geo [$address] $variable { ... }", "", "http")
+	docDirective("ngx_http_split_clients_module", "split_clients string $variable { ... }", "", "http")
+	docDirective("ngx_http_stub_status_module", "stub_status;", "", "server, location")
+	docDirective("ngx_http_sub_module", "sub_filter string replacement;", "", "http, server, location")
+	docDirective("ngx_stream_core_module", "stream { ... }", "", "main")
+}
@@ -0,0 +1,176 @@
+package nginx
+
+import (
+	"github.com/ihaiker/aginx/util"
+	"path/filepath"
+	"strings"
+)
+
+//配置文件的include关系
+type IncludeFile struct {
+	Name     string         `json:"name"`
+	Include  string         `json:"include,omitempty"` //引用此文件的include参数
+	Includes []*IncludeFile `json:"includes,omitempty"`
+}
+
+func includeTree(directive *Directive, file *IncludeFile) {
+	for _, body := range directive.Body {
+		if body.Name == "include" && body.Virtual == "" {
+			for _, included := range body.Body {
+				if included.Virtual != Include || len(included.Args) == 0 {
+					continue
+				}
+				child := &IncludeFile{Name: included.Args[0], Include: body.Args[0]}
+				includeTree(included, child)
+				file.Includes = append(file.Includes, child)
+			}
+		} else {
+			includeTree(body, file)
+		}
+	}
+}
+
+//配置文件的include树，根节点为nginx.conf
+func IncludeTree(conf *Configuration) *IncludeFile {
+	root := &IncludeFile{Name: conf.Name}
+	if root.Name == "" {
+		root.Name = NGINX_CONF
+	}
+	includeTree(conf, root)
+	return root
+}
+
+//include关系图中的文件和文件中定义的server、upstream
+type GraphFile struct {
+	Name      string         `json:"name"`
+	Servers   []*GraphServer `json:"servers,omitempty"`
+	Upstreams []string       `json:"upstreams,omitempty"`
+}
+
+type GraphServer struct {
+	Context    string   `json:"context"` //http 或者 stream
+	ServerName []string `json:"server_name,omitempty"`
+	Listen     []string `json:"listen,omitempty"`
+}
+
+//from 文件中的 include 指令引用了 to 文件
+type GraphEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Include string `json:"include"` //include的参数
+	Context string `json:"context"` //include所在的位置，例如：main, http, server
+}
+
+//配置文件的include关系图，同一个文件被多次include时只有一个文件节点
+type IncludeGraph struct {
+	Root  string       `json:"root"`
+	Files []*GraphFile `json:"files"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+func graphArgs(directive *Directive, name string) (args []string) {
+	for _, body := range directive.Body {
+		if body.Name == name {
+			args = append(args, strings.Join(body.Args, " "))
+		}
+	}
+	return
+}
+
+func includeGraph(directive *Directive, file *GraphFile, context string, graph *IncludeGraph, files map[string]*GraphFile) {
+	for _, body := range directive.Body {
+		switch {
+		case body.Name == "include" && body.Virtual == "":
+			for _, included := range body.Body {
+				if included.Virtual != Include || len(included.Args) == 0 {
+					continue
+				}
+				name := included.Args[0]
+				graph.Edges = append(graph.Edges, &GraphEdge{From: file.Name, To: name, Include: body.Args[0], Context: context})
+				if _, has := files[name]; !has {
+					files[name] = &GraphFile{Name: name}
+					graph.Files = append(graph.Files, files[name])
+					includeGraph(included, files[name], context, graph, files)
+				}
+			}
+		case body.Virtual != "":
+		case body.Name == "server" && (context == "http" || context == "stream"):
+			file.Servers = append(file.Servers, &GraphServer{
+				Context: context, ServerName: graphArgs(body, "server_name"), Listen: graphArgs(body, "listen"),
+			})
+			includeGraph(body, file, body.Name, graph, files)
+		case body.Name == "upstream" && len(body.Args) > 0:
+			file.Upstreams = append(file.Upstreams, body.Args[0])
+		default:
+			includeGraph(body, file, body.Name, graph, files)
+		}
+	}
+}
+
+//配置文件的include关系图，包括每个文件中定义的server和upstream
+func NewIncludeGraph(conf *Configuration) *IncludeGraph {
+	root := &GraphFile{Name: conf.Name}
+	if root.Name == "" {
+		root.Name = NGINX_CONF
+	}
+	graph := &IncludeGraph{Root: root.Name, Files: []*GraphFile{root}, Edges: make([]*GraphEdge, 0)}
+	includeGraph(conf, root, "main", graph, map[string]*GraphFile{root.Name: root})
+	return graph
+}
+
+//引用文件或者目录的指令，include单独处理
+var pathDirectives = map[string]bool{
+	"ssl_certificate": true, "ssl_certificate_key": true, "ssl_trusted_certificate": true,
+	"ssl_client_certificate": true, "ssl_dhparam": true, "ssl_crl": true, "ssl_password_file": true,
+	"auth_basic_user_file": true, "load_module": true, "root": true, "alias": true,
+}
+
+//配置中引用的文件路径
+type PathReference struct {
+	File      string `json:"file"`      //引用所在的配置文件
+	Directive string `json:"directive"` //引用的指令
+	Path      string `json:"path"`      //绝对路径
+	Exists    bool   `json:"exists"`
+	Inside    bool   `json:"inside"` //是否在配置目录中，配置目录之外的文件不会保存到存储中
+}
+
+func pathReferences(directive *Directive, file, configDir string, refs *[]*PathReference) {
+	for _, body := range directive.Body {
+		if body.Virtual == Include {
+			if len(body.Args) > 0 {
+				pathReferences(body, body.Args[0], configDir, refs)
+			}
+			continue
+		}
+		if (body.Name == "include" || pathDirectives[body.Name]) && len(body.Args) > 0 &&
+			!strings.Contains(body.Args[0], "$") {
+			path := strings.Trim(body.Args[0], `"'`)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(configDir, path)
+			}
+			ref := &PathReference{
+				File: file, Directive: strings.TrimSpace(body.Name + " " + strings.Join(body.Args, " ")),
+				Path: path, Exists: util.Exists(path),
+			}
+			//通配符的include没有匹配的文件nginx不会报错
+			if body.Name == "include" {
+				ref.Exists = len(body.Body) > 0 || strings.ContainsAny(path, "*?[")
+			}
+			rel, err := filepath.Rel(configDir, path)
+			ref.Inside = err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+			*refs = append(*refs, ref)
+		}
+		pathReferences(body, file, configDir, refs)
+	}
+}
+
+//查找配置中引用的文件路径，相对路径使用配置目录
+func PathReferences(conf *Configuration, configDir string) []*PathReference {
+	refs := make([]*PathReference, 0)
+	name := conf.Name
+	if name == "" {
+		name = NGINX_CONF
+	}
+	pathReferences(conf, name, configDir, &refs)
+	return refs
+}
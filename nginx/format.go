@@ -0,0 +1,149 @@
+package nginx
+
+import (
+	"github.com/xhaiker/codf"
+	"strings"
+)
+
+//配置文件中一条指令的注释，按照指令出现的顺序（先序）排列
+type statementFormat struct {
+	comments    []string
+	comment     string
+	endComments []string
+}
+
+//配置文件的注释、空行和缩进，codf 解析时会丢弃这些内容
+type fileFormat struct {
+	statements  []*statementFormat
+	endComments []string
+	indent      string
+}
+
+func isBoundary(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '{' || c == '}'
+}
+
+//扫描配置文件内容，记录每条指令前的注释和空行、行尾注释和块结束前的注释
+func scanFormat(content []byte) *fileFormat {
+	format := &fileFormat{statements: make([]*statementFormat, 0)}
+	pending := make([]string, 0)
+	stack := make([]*statementFormat, 0)
+	var current *statementFormat //正在读取的指令
+	var trailing *string         //本行结束的指令，用于行尾注释
+	lineContent, lineStart, indented := false, 0, false
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\n':
+			if !lineContent {
+				pending = append(pending, "")
+			}
+			lineContent, lineStart, trailing = false, i+1, nil
+		case c == ' ' || c == '\t' || c == '\r':
+		case c == '#' && (i == 0 || isBoundary(content[i-1])):
+			end := i
+			for end < len(content) && content[end] != '\n' {
+				end++
+			}
+			comment := strings.TrimRight(string(content[i:end]), " \t\r")
+			if trailing != nil && current == nil {
+				*trailing = comment
+			} else {
+				pending = append(pending, comment)
+			}
+			lineContent, i = true, end-1
+		case c == ';':
+			if current != nil {
+				trailing, current = &current.comment, nil
+			}
+			lineContent = true
+		case c == '{':
+			if current != nil {
+				stack = append(stack, current)
+				trailing, current = &current.comment, nil
+			}
+			lineContent = true
+		case c == '}':
+			if current == nil && len(stack) > 0 {
+				section := stack[len(stack)-1]
+				section.endComments, pending = trimBlank(pending), make([]string, 0)
+				stack, trailing = stack[:len(stack)-1], nil
+			}
+			lineContent = true
+		default:
+			if current == nil {
+				current = &statementFormat{comments: pending}
+				pending = make([]string, 0)
+				format.statements = append(format.statements, current)
+				//第一层块中第一条独占一行的指令的缩进为文件的缩进
+				if len(stack) == 1 && !indented && !lineContent {
+					format.indent, indented = string(content[lineStart:i]), true
+				}
+			}
+			if c == '"' || c == '\'' {
+				for i++; i < len(content) && content[i] != c; i++ {
+					if content[i] == '\\' {
+						i++
+					}
+				}
+			} else if c == '$' && i+1 < len(content) && content[i+1] == '{' {
+				for i++; i < len(content) && content[i] != '}'; i++ {
+				}
+			}
+			lineContent = true
+		}
+	}
+	format.endComments = trimBlank(pending)
+	return format
+}
+
+//块结束和文件结尾前只有空行时不保留
+func trimBlank(comments []string) []string {
+	for _, comment := range comments {
+		if comment != "" {
+			return comments
+		}
+	}
+	return nil
+}
+
+func countNodes(nodes []codf.Node) int {
+	count := len(nodes)
+	for _, node := range nodes {
+		if section, ok := node.(*codf.Section); ok {
+			count += countNodes(section.Nodes())
+		}
+	}
+	return count
+}
+
+func applyFormat(nodes []codf.Node, directives []*Directive, statements []*statementFormat) []*statementFormat {
+	for i, node := range nodes {
+		statement := statements[0]
+		statements = statements[1:]
+		directive := directives[i]
+		if len(statement.comments) > 0 {
+			directive.Comments = statement.comments
+		}
+		directive.Comment = statement.comment
+		if section, ok := node.(*codf.Section); ok {
+			directive.EndComments = statement.endComments
+			statements = applyFormat(section.Nodes(), directive.Body, statements)
+		}
+	}
+	return statements
+}
+
+//把注释、空行和缩进添加到解析后的配置中，写入时保持文件原有的格式
+func formatConfiguration(content []byte, doc *codf.Document, cfg *Configuration) bool {
+	format := scanFormat(content)
+	//扫描结果和codf解析的指令不一致时不保留格式
+	if len(format.statements) != countNodes(doc.Children) {
+		return false
+	}
+	applyFormat(doc.Children, cfg.Body, format.statements)
+	cfg.EndComments = format.endComments
+	cfg.Indent = format.indent
+	return true
+}
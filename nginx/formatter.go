@@ -0,0 +1,80 @@
+package nginx
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"strings"
+)
+
+//内容为键值对的块，格式化时对齐键和值
+var alignBlocks = map[string]bool{
+	"map": true, "geo": true, "types": true, "split_clients": true, "charset_map": true,
+}
+
+//合并连续的空行，块（文件）开始的空行删除
+func compactComments(comments []string, first bool) []string {
+	compacted := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		if comment == "" && (first || (len(compacted) > 0 && compacted[len(compacted)-1] == "")) {
+			continue
+		}
+		compacted, first = append(compacted, comment), false
+	}
+	if len(compacted) == 0 {
+		return nil
+	}
+	return compacted
+}
+
+//块结束前的注释，删除结尾的空行
+func compactEndComments(comments []string) []string {
+	compacted := compactComments(comments, false)
+	for len(compacted) > 0 && compacted[len(compacted)-1] == "" {
+		compacted = compacted[:len(compacted)-1]
+	}
+	if len(compacted) == 0 {
+		return nil
+	}
+	return compacted
+}
+
+//键值对块中使用空格补齐指令名称，值从同一列开始
+func alignDirectives(directives []*Directive) {
+	width := 0
+	for _, directive := range directives {
+		if len(directive.Args) > 0 && len(directive.Name) > width {
+			width = len(directive.Name)
+		}
+	}
+	for _, directive := range directives {
+		if len(directive.Args) > 0 {
+			directive.Name += strings.Repeat(" ", width-len(directive.Name))
+		}
+	}
+}
+
+func canonical(directives []*Directive) {
+	for i, directive := range directives {
+		directive.Comments = compactComments(directive.Comments, i == 0)
+		directive.EndComments = compactEndComments(directive.EndComments)
+		if alignBlocks[directive.Name] {
+			alignDirectives(directive.Body)
+		}
+		canonical(directive.Body)
+	}
+}
+
+//格式化配置文件：统一使用4个空格缩进，合并连续的空行，对齐键值对块（map, geo, types等），保留注释
+func Format(name string, content []byte) ([]byte, error) {
+	cfg, formatted, err := readable(nil, plugins.NewFile(name, content))
+	if err != nil {
+		return nil, err
+	}
+	if !formatted {
+		return nil, errors.New("can not keep the comments of " + name)
+	}
+	canonical(cfg.Body)
+	cfg.EndComments = compactEndComments(cfg.EndComments)
+	cfg.Indent = defaultIndent
+	return cfg.BodyBytes(), nil
+}
@@ -0,0 +1,285 @@
+package nginx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	geoDir = "geo"
+
+	//geoip2中定义的国家代码变量
+	GeoCountry = "$geoip2_country_code"
+)
+
+var (
+	ErrGeoNotConfigured = errors.New("the geoip database is not configured, use --geoip")
+
+	countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+	notWord     = regexp.MustCompile(`\W`)
+)
+
+//按照国家代码拒绝访问或者代理到不同的upstream，配置保存在存储的 geo/<domain>.json 中
+type GeoPolicy struct {
+	Domain string   `json:"domain"`
+	Block  []string `json:"block,omitempty"` //拒绝访问(403)的国家代码，例如：CN, RU
+
+	Location string            `json:"location,omitempty"` //路由的location，默认：/
+	Routes   map[string]string `json:"routes,omitempty"`   //国家代码对应的upstream
+	Default  string            `json:"default,omitempty"`  //其他国家使用的upstream，设置routes时必须设置
+}
+
+func (policy *GeoPolicy) path() string {
+	return fmt.Sprintf("%s/%s.json", geoDir, policy.Domain)
+}
+
+//检查并把国家代码转为大写
+func (policy *GeoPolicy) Validate() error {
+	if policy.Domain == "" || strings.ContainsAny(policy.Domain, " ;'\"/") {
+		return errors.New("invalid domain: " + policy.Domain)
+	}
+	if len(policy.Block) == 0 && len(policy.Routes) == 0 {
+		return errors.New("the block or routes of geo policy is required")
+	}
+	for i, code := range policy.Block {
+		if policy.Block[i] = strings.ToUpper(code); !countryCode.MatchString(policy.Block[i]) {
+			return errors.New("invalid country code: " + code)
+		}
+	}
+	routes := make(map[string]string)
+	for code, upstream := range policy.Routes {
+		if !countryCode.MatchString(strings.ToUpper(code)) {
+			return errors.New("invalid country code: " + code)
+		}
+		if upstream == "" {
+			return errors.New("the upstream of country is empty: " + code)
+		}
+		routes[strings.ToUpper(code)] = upstream
+	}
+	policy.Routes = routes
+	if len(policy.Routes) > 0 && policy.Default == "" {
+		return errors.New("the default upstream of routes is empty")
+	}
+	return nil
+}
+
+func (policy *GeoPolicy) location() string {
+	if policy.Location == "" {
+		return "/"
+	}
+	return policy.Location
+}
+
+//生成的map变量：$geo_block_api_aginx_io
+func geoVariable(kind, domain string) string {
+	return "$geo_" + kind + "_" + notWord.ReplaceAllString(domain, "_")
+}
+
+func geoMap(variable, def string, values map[string]string) *Directive {
+	directive := NewDirective("map", GeoCountry, variable)
+	directive.AddBody("default", def)
+	codes := make([]string, 0, len(values))
+	for code := range values {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		directive.AddBody(code, values[code])
+	}
+	return directive
+}
+
+//if ($variable)
+func isIfVariable(directive *Directive, variable string) bool {
+	return directive.Name == "if" && strings.Replace(strings.Join(directive.Args, ""), " ", "", -1) == "("+variable+")"
+}
+
+//http中的geoip2数据库，没有配置时返回空
+func (client *Client) GeoDatabase() string {
+	for _, parent := range client.zoneParents() {
+		for _, directive := range parent.Body {
+			if directive.Name == "geoip2" && len(directive.Args) > 0 {
+				return directive.Args[0]
+			}
+		}
+	}
+	return ""
+}
+
+//在http中添加geoip2配置，定义国家代码变量。已经配置时修改数据库位置，返回配置是否修改
+func (client *Client) SetGeoDatabase(path string) (bool, error) {
+	for _, parent := range client.zoneParents() {
+		for _, directive := range parent.Body {
+			if directive.Name == "geoip2" && len(directive.Args) > 0 {
+				if directive.Args[0] == path {
+					return false, nil
+				}
+				directive.Args[0] = path
+				return true, nil
+			}
+		}
+	}
+	directive := NewDirective("geoip2", path)
+	directive.AddBody(GeoCountry, "country", "iso_code")
+	return true, client.Add(Queries("http"), directive)
+}
+
+func (client *Client) domainServers(domain string) ([]*Directive, error) {
+	servers := make([]*Directive, 0)
+	for _, queries := range serverQueries(domain) {
+		if directives, err := client.Select(queries...); err == nil {
+			servers = append(servers, directives...)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, ErrNotFound
+	}
+	return servers, nil
+}
+
+//删除生成的map和if
+func (client *Client) removeGeo(domain string, servers []*Directive) {
+	block, route := geoVariable("block", domain), geoVariable("route", domain)
+	for _, parent := range client.zoneParents() {
+		body := make([]*Directive, 0, len(parent.Body))
+		for _, directive := range parent.Body {
+			if directive.Name == "map" && len(directive.Args) == 2 &&
+				(directive.Args[1] == block || directive.Args[1] == route) {
+				continue
+			}
+			body = append(body, directive)
+		}
+		parent.Body = body
+	}
+	for _, server := range servers {
+		body := make([]*Directive, 0, len(server.Body))
+		for _, directive := range server.Body {
+			if !isIfVariable(directive, block) {
+				body = append(body, directive)
+			}
+		}
+		server.Body = body
+	}
+}
+
+func geoLocations(servers []*Directive, location string) []*Directive {
+	locations := make([]*Directive, 0)
+	for _, server := range servers {
+		if directives, err := server.Select(fmt.Sprintf("location('%s')", location)); err == nil {
+			locations = append(locations, directives...)
+		}
+	}
+	return locations
+}
+
+//设置地域策略：拒绝访问的国家返回403，按照国家代码代理到不同的upstream
+func (client *Client) SetGeoPolicy(policy *GeoPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if client.GeoDatabase() == "" {
+		return ErrGeoNotConfigured
+	}
+	servers, err := client.domainServers(policy.Domain)
+	if err != nil {
+		return err
+	}
+	var locations []*Directive
+	if len(policy.Routes) > 0 {
+		if locations = geoLocations(servers, policy.location()); len(locations) == 0 {
+			return fmt.Errorf("%w: location %s", ErrNotFound, policy.location())
+		}
+	}
+
+	client.removeGeo(policy.Domain, servers)
+	if len(policy.Block) > 0 {
+		block := geoVariable("block", policy.Domain)
+		codes := make(map[string]string)
+		for _, code := range policy.Block {
+			codes[code] = "1"
+		}
+		if err = client.Add(Queries("http"), geoMap(block, "0", codes)); err != nil {
+			return err
+		}
+		for _, server := range servers {
+			deny := NewDirective("if", "("+block+")")
+			deny.AddBody("return", "403")
+			server.Body = append([]*Directive{deny}, server.Body...)
+		}
+	}
+	if len(policy.Routes) > 0 {
+		route := geoVariable("route", policy.Domain)
+		if err = client.Add(Queries("http"), geoMap(route, policy.Default, policy.Routes)); err != nil {
+			return err
+		}
+		for _, location := range locations {
+			setProxyPass(location, route)
+		}
+	}
+	return nil
+}
+
+//删除地域策略，设置了路由的location代理到默认的upstream
+func (client *Client) DeleteGeoPolicy(policy *GeoPolicy) error {
+	servers, err := client.domainServers(policy.Domain)
+	if err != nil {
+		return err
+	}
+	client.removeGeo(policy.Domain, servers)
+	if len(policy.Routes) > 0 {
+		for _, location := range geoLocations(servers, policy.location()) {
+			setProxyPass(location, policy.Default)
+		}
+	}
+	return nil
+}
+
+func LoadGeoPolicies(engine plugins.StorageEngine) ([]*GeoPolicy, error) {
+	files, err := engine.Search(geoDir + "/*.json")
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]*GeoPolicy, 0, len(files))
+	for _, file := range files {
+		policy := new(GeoPolicy)
+		if err := json.Unmarshal(file.Content, policy); err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Domain < policies[j].Domain
+	})
+	return policies, nil
+}
+
+func LoadGeoPolicy(engine plugins.StorageEngine, domain string) (*GeoPolicy, error) {
+	policy := &GeoPolicy{Domain: domain}
+	file, err := engine.Get(policy.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(file.Content, policy)
+	return policy, err
+}
+
+func SaveGeoPolicy(engine plugins.StorageEngine, policy *GeoPolicy) error {
+	content, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return err
+	}
+	return engine.Put(policy.path(), content)
+}
+
+func RemoveGeoPolicy(engine plugins.StorageEngine, policy *GeoPolicy) error {
+	return engine.Remove(policy.path())
+}
@@ -0,0 +1,221 @@
+package nginx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//nginx重启后的健康检查，检查失败时恢复到上一次正常的配置
+type HealthCheck struct {
+	URL     string        //检查地址，返回状态码小于500为正常。为空时只检查nginx进程是否运行
+	Timeout time.Duration //重启后等待nginx正常的最长时间
+}
+
+//nginx进程是否运行
+func (sp *Process) running() error {
+	if InDocker() {
+		out, err := exec.Command("docker", "inspect", "-f", "{{.State.Running}}", dockerContainer).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("inspect container %s: %s", dockerContainer, strings.TrimSpace(string(out)))
+		}
+		if strings.TrimSpace(string(out)) != "true" {
+			return fmt.Errorf("the nginx container %s is not running", dockerContainer)
+		}
+		return nil
+	}
+	if sp.startCmd != nil && sp.startCmd.ProcessState != nil {
+		return fmt.Errorf("nginx exited: %s", sp.startCmd.ProcessState.String())
+	}
+	return nil
+}
+
+//nginx是否正在运行，aginx启动的nginx检查进程是否退出，docker中检查容器是否运行
+func (sp *Process) Running() error {
+	return sp.running()
+}
+
+func (sp *Process) probe(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("health check %s response %s", url, resp.Status)
+	}
+	return nil
+}
+
+//在超时时间内检查nginx是否正常，没有设置健康检查时直接返回
+func (sp *Process) healthy() (err error) {
+	if sp.Health == nil {
+		return nil
+	}
+	timeout := sp.Health.Timeout
+	if timeout <= 0 {
+		timeout = time.Second * 5
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if err = sp.running(); err == nil && sp.Health.URL != "" {
+			err = sp.probe(sp.Health.URL, time.Second)
+		}
+		if err == nil || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(time.Millisecond * 500)
+	}
+}
+
+//记录当前配置目录的全部文件，作为回滚使用的配置
+func snapshot(configDir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[path] = content
+		return nil
+	})
+	return files, err
+}
+
+//快照中的配置文件，用于读取上一次正常的配置中nginx加载的文件。文件名为配置目录中的相对路径
+type snapshotEngine struct {
+	plugins.StorageEngine
+	configDir string
+	files     map[string][]byte
+}
+
+func (se *snapshotEngine) name(file string) string {
+	if filepath.IsAbs(file) {
+		if relative, err := filepath.Rel(se.configDir, file); err == nil {
+			return filepath.ToSlash(relative)
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
+func (se *snapshotEngine) Get(file string) (*plugins.ConfigurationFile, error) {
+	if content, has := se.files[se.name(file)]; has {
+		return plugins.NewFile(se.name(file), content), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (se *snapshotEngine) Search(patterns ...string) ([]*plugins.ConfigurationFile, error) {
+	names := make([]string, 0)
+	for name := range se.files {
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(se.name(pattern), name); matched {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	files := make([]*plugins.ConfigurationFile, 0, len(names))
+	for _, name := range names {
+		files = append(files, plugins.NewFile(name, se.files[name]))
+	}
+	return files, nil
+}
+
+//nginx加载的配置文件（nginx.conf和include的文件），文件名为配置目录中的相对路径
+func configFiles(engine plugins.StorageEngine, configDir string) (map[string]bool, error) {
+	_, loaded, err := readTree(engine)
+	if err != nil {
+		return nil, err
+	}
+	names := &snapshotEngine{configDir: configDir}
+	files := make(map[string]bool, len(loaded))
+	for file := range loaded {
+		files[names.name(file)] = true
+	}
+	return files, nil
+}
+
+//通过存储恢复上一次正常的配置（集群中同时恢复存储和其他节点的配置），files为配置目录的快照。
+//只恢复nginx加载的配置文件：写回快照中修改过的配置文件，删除之后新增的配置文件，
+//配置目录中的其他文件（证书、备份以及不是aginx管理的文件）不会修改
+func Restore(engine plugins.StorageEngine, configDir string, files map[string][]byte) error {
+	good := &snapshotEngine{configDir: configDir, files: relative(configDir, files)}
+	goodFiles, err := configFiles(good, configDir)
+	if err != nil {
+		return util.Wrap(err, "read the previous configuration")
+	}
+	currentFiles, err := configFiles(engine, configDir)
+	if err != nil {
+		logger.WithError(err).Warn("read the current configuration")
+		currentFiles = map[string]bool{}
+	}
+
+	current, err := snapshot(configDir)
+	if err != nil {
+		return err
+	}
+	current = relative(configDir, current)
+	return plugins.Batch(engine, func(engine plugins.StorageEngine) error {
+		for name := range currentFiles {
+			if goodFiles[name] || plugins.Reserved(name) {
+				continue
+			}
+			if err := engine.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		for name := range goodFiles {
+			if content, has := current[name]; has && bytes.Equal(content, good.files[name]) {
+				continue
+			}
+			if err := engine.Put(name, good.files[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//保存nginx已经加载的配置，用于回滚和比较差异
+func (sp *Process) keep() {
+	files, err := snapshot(MustConfigDir())
+	if err != nil {
+		logger.WithError(err).Warn("snapshot NGINX configuration")
+		return
+	}
+	sp.lastGood, sp.lastGoodAt = files, time.Now()
+}
+
+//重启后nginx不正常时恢复上一次正常的配置并再次重启
+func (sp *Process) rollback(cause error) error {
+	err := fmt.Errorf("NGINX is unhealthy after reload: %s", cause)
+	if sp.lastGood == nil || sp.Engine == nil {
+		util.PublishEvent(util.EventReloadRollback, "no configuration to rollback", err, nil)
+		return err
+	}
+	if restoreErr := Restore(sp.Engine, MustConfigDir(), sp.lastGood); restoreErr != nil {
+		err = fmt.Errorf("%s, rollback error: %s", err, restoreErr)
+	} else if reloadErr := runCommand("-s", "reload"); reloadErr != nil {
+		err = fmt.Errorf("%s, reload previous configuration error: %s", err, reloadErr)
+	} else {
+		err = fmt.Errorf("%s, rollback to the previous configuration", err)
+	}
+	logger.Warn(err)
+	util.PublishEvent(util.EventReloadRollback, "rollback NGINX configuration", err, nil)
+	return err
+}
@@ -0,0 +1,222 @@
+package nginx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//htpasswd文件保存在存储的 htpasswd/<name> 中，nginx每次请求都会读取文件，修改用户不需要重启
+const htpasswdDir = "htpasswd"
+
+var (
+	ErrHtpasswdInUse = errors.New("the htpasswd file is used by auth_basic_user_file")
+
+	htpasswdName = regexp.MustCompile(`^[\w.-]+$`)
+)
+
+//htpasswd文件和其中的用户
+type Htpasswd struct {
+	Name  string   `json:"name"`
+	Users []string `json:"users"`
+}
+
+func HtpasswdPath(name string) string {
+	return htpasswdDir + "/" + name
+}
+
+//{SSHA}格式的密码，nginx可以直接读取，不依赖系统的crypt
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := sha1.Sum(append([]byte(password), salt...))
+	return "{SSHA}" + base64.StdEncoding.EncodeToString(append(hash[:], salt...)), nil
+}
+
+type htpasswdLine struct {
+	user, password string
+}
+
+func readHtpasswd(engine plugins.StorageEngine, name string) ([]*htpasswdLine, error) {
+	if !htpasswdName.MatchString(name) {
+		return nil, errors.New("invalid htpasswd name: " + name)
+	}
+	file, err := engine.Get(HtpasswdPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return parseHtpasswd(file.Content)
+}
+
+func parseHtpasswd(content []byte) ([]*htpasswdLine, error) {
+	lines := make([]*htpasswdLine, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, ":"); idx > 0 && !strings.HasPrefix(line, "#") {
+			lines = append(lines, &htpasswdLine{user: line[:idx], password: line[idx+1:]})
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func writeHtpasswd(engine plugins.StorageEngine, name string, lines []*htpasswdLine) error {
+	out := bytes.NewBufferString("")
+	for _, line := range lines {
+		out.WriteString(line.user + ":" + line.password + "\n")
+	}
+	return engine.Put(HtpasswdPath(name), out.Bytes())
+}
+
+//全部的htpasswd文件，不包含密码
+func ListHtpasswd(engine plugins.StorageEngine) ([]*Htpasswd, error) {
+	files, err := engine.Search(htpasswdDir + "/*")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Htpasswd, 0, len(files))
+	for _, file := range files {
+		htpasswd := &Htpasswd{Name: filepath.Base(file.Name), Users: make([]string, 0)}
+		lines, err := parseHtpasswd(file.Content)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			htpasswd.Users = append(htpasswd.Users, line.user)
+		}
+		list = append(list, htpasswd)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+//添加用户，用户已经存在时修改密码。文件不存在时创建
+func SetHtpasswdUser(engine plugins.StorageEngine, name, user, password string) error {
+	if user == "" || strings.ContainsAny(user, ":\r\n") {
+		return errors.New("invalid user: " + user)
+	}
+	if password == "" {
+		return errors.New("the password is empty")
+	}
+	lines, err := readHtpasswd(engine, name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	exists := false
+	for _, line := range lines {
+		if line.user == user {
+			line.password, exists = hashed, true
+		}
+	}
+	if !exists {
+		lines = append(lines, &htpasswdLine{user: user, password: hashed})
+	}
+	return writeHtpasswd(engine, name, lines)
+}
+
+func DeleteHtpasswdUser(engine plugins.StorageEngine, name, user string) error {
+	lines, err := readHtpasswd(engine, name)
+	if err != nil {
+		return err
+	}
+	remains := make([]*htpasswdLine, 0, len(lines))
+	for _, line := range lines {
+		if line.user != user {
+			remains = append(remains, line)
+		}
+	}
+	if len(remains) == len(lines) {
+		return ErrNotFound
+	}
+	return writeHtpasswd(engine, name, remains)
+}
+
+//删除htpasswd文件，还在使用时返回错误
+func (client *Client) DeleteHtpasswd(name string) error {
+	for _, target := range client.scopes() {
+		for _, directive := range target.directive.Body {
+			if directive.Name == "auth_basic_user_file" && len(directive.Args) > 0 &&
+				strings.Trim(directive.Args[0], `"'`) == HtpasswdPath(name) {
+				return fmt.Errorf("%w: %s %s", ErrHtpasswdInUse, target.domain(), target.location)
+			}
+		}
+	}
+	if _, err := readHtpasswd(client.Engine, name); err != nil {
+		return err
+	}
+	return client.Engine.Remove(HtpasswdPath(name))
+}
+
+func (client *Client) authLocations(domain, location string) ([]*Directive, error) {
+	locations := make([]*Directive, 0)
+	for _, queries := range serverQueries(domain) {
+		if directives, err := client.Select(append(queries, fmt.Sprintf("location('%s')", location))...); err == nil {
+			locations = append(locations, directives...)
+		}
+	}
+	if len(locations) == 0 {
+		return nil, ErrNotFound
+	}
+	return locations, nil
+}
+
+func removeAuthBasic(location *Directive) {
+	body := make([]*Directive, 0, len(location.Body))
+	for _, directive := range location.Body {
+		if directive.Name != "auth_basic" && directive.Name != "auth_basic_user_file" {
+			body = append(body, directive)
+		}
+	}
+	location.Body = body
+}
+
+//location使用htpasswd文件开启basic认证，realm为浏览器显示的提示
+func (client *Client) SetAuthBasic(domain, location, realm, name string) error {
+	if _, err := readHtpasswd(client.Engine, name); err != nil {
+		return err
+	}
+	if realm == "" {
+		realm = "Restricted"
+	} else if strings.ContainsAny(realm, "\"\\\r\n") {
+		return errors.New("invalid realm: " + realm)
+	}
+	locations, err := client.authLocations(domain, location)
+	if err != nil {
+		return err
+	}
+	for _, directive := range locations {
+		removeAuthBasic(directive)
+		directive.AddBody("auth_basic", `"`+realm+`"`)
+		directive.AddBody("auth_basic_user_file", HtpasswdPath(name))
+	}
+	return nil
+}
+
+func (client *Client) RemoveAuthBasic(domain, location string) error {
+	locations, err := client.authLocations(domain, location)
+	if err != nil {
+		return err
+	}
+	for _, directive := range locations {
+		removeAuthBasic(directive)
+	}
+	return nil
+}
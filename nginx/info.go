@@ -4,12 +4,14 @@ import (
 	"bufio"
 	"bytes"
 	"io"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
 func MustConf() string {
+	if confFile != "" {
+		return confFile
+	}
 	if _, conf, err := GetInfo(); err != nil {
 		return "/etc/nginx/nginx.conf"
 	} else {
@@ -23,7 +25,7 @@ func MustConfigDir() string {
 
 func GetInfo() (path, file string, err error) {
 	writer := bytes.NewBufferString("")
-	cmd := exec.Command("nginx", "-h")
+	cmd := nginxCommand("-h")
 	cmd.Stdout = writer
 	cmd.Stderr = writer
 	if err = cmd.Run(); err != nil {
@@ -39,13 +41,17 @@ func GetInfo() (path, file string, err error) {
 		if strings.HasPrefix(line, "-p prefix") {
 			idx := strings.Index(line, "default:")
 			path = filepath.Dir(line[idx+9 : len(line)-1])
+			//windows中没有默认的prefix（NONE），使用nginx.exe所在的目录
+			if prefix := localPrefix(); prefix != "" {
+				path = filepath.Clean(prefix)
+			}
 		} else if strings.HasPrefix(line, "-c filename") {
 			idx := strings.Index(line, "default:")
 			file = line[idx+9 : len(line)-1]
 		}
 	}
-	if !strings.HasPrefix(file, "/") {
-		file = path + "/" + file
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(path, file)
 	}
 	return
 }
@@ -0,0 +1,348 @@
+package nginx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/xhaiker/codf"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	LintDuplicateServerName = "duplicate-server-name"
+	LintProxyPassSlash      = "proxy-pass-slash"
+	LintSslProtocols        = "ssl-protocols"
+	LintSslInsecure         = "ssl-insecure-protocols"
+	LintInvalidContext      = "invalid-context"
+)
+
+//nginx -t 之外的配置检查结果
+type Lint struct {
+	Rule    string `json:"rule"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+//带有文件和行号的指令，include的文件内容在include指令的body中
+type lintDirective struct {
+	file string
+	line int
+	name string
+	args []string
+	body []*lintDirective
+}
+
+//展开include后的子指令
+func (d *lintDirective) children() []*lintDirective {
+	children := make([]*lintDirective, 0)
+	for _, body := range d.body {
+		if body.name == "include" {
+			children = append(children, (&lintDirective{body: body.body}).children()...)
+		} else {
+			children = append(children, body)
+		}
+	}
+	return children
+}
+
+func (d *lintDirective) find(name string) []*lintDirective {
+	found := make([]*lintDirective, 0)
+	for _, child := range d.children() {
+		if child.name == name {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+func lintRead(store plugins.StorageEngine, file *plugins.ConfigurationFile, including map[string]bool) ([]*lintDirective, error) {
+	parser := codf.NewParser()
+	if err := parser.Parse(codf.NewLexer(bytes.NewBuffer(file.Content))); err != nil {
+		return nil, util.Wrap(err, "parse config: "+file.Name)
+	}
+	including[file.Name] = true
+	defer delete(including, file.Name)
+	return lintNodes(store, file.Name, parser.Document().Children, including)
+}
+
+func lintArgs(params []codf.ExprNode) []string {
+	args := make([]string, len(params))
+	for i, param := range params {
+		args[i] = string(param.Token().Raw)
+	}
+	return args
+}
+
+func lintNodes(store plugins.StorageEngine, file string, nodes []codf.Node, including map[string]bool) (directives []*lintDirective, err error) {
+	directives = make([]*lintDirective, 0, len(nodes))
+	for _, node := range nodes {
+		directive := &lintDirective{file: file, line: node.Token().Start.Line}
+		switch n := node.(type) {
+		case *codf.Section:
+			directive.name = n.Name()
+			directive.args = lintArgs(n.Parameters())
+			if directive.body, err = lintNodes(store, file, n.Nodes(), including); err != nil {
+				return
+			}
+		case codf.ParamNode:
+			directive.name = n.Name()
+			directive.args = lintArgs(n.Parameters())
+			if directive.name == "include" {
+				if directive.body, err = lintInclude(store, directive.args, including); err != nil {
+					return
+				}
+			}
+		case codf.ExprNode:
+			directive.name = string(n.Token().Raw)
+		}
+		directives = append(directives, directive)
+	}
+	return
+}
+
+func lintInclude(store plugins.StorageEngine, args []string, including map[string]bool) ([]*lintDirective, error) {
+	configDir := MustConfigDir()
+	queries := make([]string, len(args))
+	for i, arg := range args {
+		queries[i] = arg
+		if strings.HasPrefix(arg, configDir) {
+			queries[i], _ = filepath.Rel(configDir, arg)
+		}
+	}
+	files, err := store.Search(queries...)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]*lintDirective, 0)
+	for _, file := range files {
+		if including[file.Name] { //循环引用
+			continue
+		}
+		directives, err := lintRead(store, file, including)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, directives...)
+	}
+	return body, nil
+}
+
+//listen的端口，没有listen时为80
+func listenPorts(server *lintDirective) []string {
+	ports := make([]string, 0)
+	for _, listen := range server.find("listen") {
+		if len(listen.args) == 0 {
+			continue
+		}
+		address := listen.args[0]
+		if strings.HasPrefix(address, "unix:") {
+			ports = append(ports, address)
+		} else if idx := strings.LastIndex(address, ":"); idx != -1 {
+			ports = append(ports, address[idx+1:])
+		} else if strings.Contains(address, ".") {
+			ports = append(ports, "80")
+		} else {
+			ports = append(ports, address)
+		}
+	}
+	if len(ports) == 0 {
+		ports = append(ports, "80")
+	}
+	return ports
+}
+
+//相同端口上server_name重复时，nginx只使用第一个server（nginx -t 只有警告）
+func lintDuplicateServerName(http *lintDirective) []*Lint {
+	lints := make([]*Lint, 0)
+	defined := map[string]*lintDirective{}
+	for _, server := range http.find("server") {
+		ports := listenPorts(server)
+		for _, serverName := range server.find("server_name") {
+			for _, name := range serverName.args {
+				if name == "_" || name == `""` || name == "''" {
+					continue
+				}
+				for _, port := range ports {
+					key := strings.ToLower(name) + " " + port
+					first, has := defined[key]
+					if !has {
+						defined[key] = serverName
+					} else if first.file != serverName.file || first.line != serverName.line {
+						lints = append(lints, &Lint{
+							Rule: LintDuplicateServerName, File: serverName.file, Line: serverName.line,
+							Message: fmt.Sprintf("server_name %s on port %s is already defined at %s:%d, this server is ignored",
+								name, port, first.file, first.line),
+						})
+					}
+				}
+			}
+		}
+	}
+	return lints
+}
+
+func proxyPassUri(address string) (uri string, has bool) {
+	if idx := strings.Index(address, "://"); idx != -1 {
+		address = address[idx+3:]
+	}
+	if idx := strings.Index(address, "/"); idx != -1 {
+		return address[idx:], true
+	}
+	return "", false
+}
+
+//proxy_pass带有uri时，请求地址中location匹配的部分会替换为uri，两者结尾的 / 不一致时地址拼接错误
+func lintProxyPassSlash(location *lintDirective) []*Lint {
+	lints := make([]*Lint, 0)
+	if len(location.args) == 0 {
+		return lints
+	}
+	switch location.args[0] {
+	case "~", "~*", "=":
+		return lints
+	}
+	path := location.args[len(location.args)-1]
+	if strings.HasPrefix(path, "@") {
+		return lints
+	}
+	for _, proxyPass := range location.find("proxy_pass") {
+		if len(proxyPass.args) == 0 || strings.Contains(proxyPass.args[0], "$") {
+			continue
+		}
+		uri, has := proxyPassUri(proxyPass.args[0])
+		if !has || strings.HasSuffix(path, "/") == strings.HasSuffix(uri, "/") {
+			continue
+		}
+		request := strings.TrimSuffix(path, "/") + "/index.html"
+		proxied := uri + strings.TrimPrefix(request, path)
+		lints = append(lints, &Lint{
+			Rule: LintProxyPassSlash, File: proxyPass.file, Line: proxyPass.line,
+			Message: fmt.Sprintf("the trailing slash of location %s and proxy_pass %s is inconsistent, %s is proxied as %s",
+				path, proxyPass.args[0], request, proxied),
+		})
+	}
+	for _, child := range location.find("location") {
+		lints = append(lints, lintProxyPassSlash(child)...)
+	}
+	return lints
+}
+
+var insecureProtocols = map[string]bool{"SSLv2": true, "SSLv3": true, "TLSv1": true, "TLSv1.1": true}
+
+func lintInsecureProtocols(protocols *lintDirective) []*Lint {
+	lints := make([]*Lint, 0)
+	for _, protocol := range protocols.args {
+		if insecureProtocols[protocol] {
+			lints = append(lints, &Lint{
+				Rule: LintSslInsecure, File: protocols.file, Line: protocols.line,
+				Message: fmt.Sprintf("ssl_protocols %s is insecure, use TLSv1.2 TLSv1.3", protocol),
+			})
+		}
+	}
+	return lints
+}
+
+func sslEnabled(server *lintDirective) bool {
+	for _, listen := range server.find("listen") {
+		for _, arg := range listen.args {
+			if arg == "ssl" {
+				return true
+			}
+		}
+	}
+	for _, ssl := range server.find("ssl") {
+		if len(ssl.args) > 0 && ssl.args[0] == "on" {
+			return true
+		}
+	}
+	return false
+}
+
+//开启ssl没有设置ssl_protocols时使用nginx的默认值，旧版本nginx默认包含不安全的TLSv1、TLSv1.1
+func lintSslProtocols(http *lintDirective) []*Lint {
+	lints := make([]*Lint, 0)
+	httpProtocols := http.find("ssl_protocols")
+	for _, protocols := range httpProtocols {
+		lints = append(lints, lintInsecureProtocols(protocols)...)
+	}
+	for _, server := range http.find("server") {
+		protocols := server.find("ssl_protocols")
+		for _, protocol := range protocols {
+			lints = append(lints, lintInsecureProtocols(protocol)...)
+		}
+		if len(protocols) == 0 && len(httpProtocols) == 0 && sslEnabled(server) {
+			name := "server"
+			if names := server.find("server_name"); len(names) > 0 && len(names[0].args) > 0 {
+				name += " " + names[0].args[0]
+			}
+			lints = append(lints, &Lint{
+				Rule: LintSslProtocols, File: server.file, Line: server.line,
+				Message: name + " enables ssl without ssl_protocols",
+			})
+		}
+	}
+	return lints
+}
+
+//未知指令和指令使用的位置不在文档的context中
+func lintSchema(directive *lintDirective, context string) []*Lint {
+	lints := make([]*Lint, 0)
+	for _, child := range directive.children() {
+		if rule, message := schemaCheck(child.name, context); message != "" {
+			lints = append(lints, &Lint{Rule: rule, File: child.file, Line: child.line, Message: message})
+			continue
+		}
+		if childContext, ok := schemaContext(child.name, context); ok {
+			lints = append(lints, lintSchema(child, childContext)...)
+		}
+	}
+	return lints
+}
+
+func lintHttp(http *lintDirective) []*Lint {
+	lints := lintDuplicateServerName(http)
+	for _, server := range http.find("server") {
+		for _, location := range server.find("location") {
+			lints = append(lints, lintProxyPassSlash(location)...)
+		}
+	}
+	return append(lints, lintSslProtocols(http)...)
+}
+
+//检查配置中 nginx -t 不能发现的问题，skip为忽略的规则
+func LintConfiguration(store plugins.StorageEngine, skip ...string) ([]*Lint, error) {
+	conf, err := store.Get("nginx.conf")
+	if err != nil {
+		return nil, util.Wrap(err, "get nginx.conf")
+	}
+	body, err := lintRead(store, conf, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	root := &lintDirective{body: body}
+	lints := make([]*Lint, 0)
+	for _, http := range root.find("http") {
+		for _, lint := range lintHttp(http) {
+			if !contains(skip, lint.Rule) {
+				lints = append(lints, lint)
+			}
+		}
+	}
+	for _, lint := range lintSchema(root, "main") {
+		if !contains(skip, lint.Rule) {
+			lints = append(lints, lint)
+		}
+	}
+	sort.SliceStable(lints, func(i, j int) bool {
+		if lints[i].File != lints[j].File {
+			return lints[i].File < lints[j].File
+		}
+		return lints[i].Line < lints[j].Line
+	})
+	return lints, nil
+}
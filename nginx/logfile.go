@@ -0,0 +1,50 @@
+package nginx
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//配置中access_log、error_log写入的日志文件所在的目录，相对路径使用nginx的prefix，prefix为空时忽略相对路径。
+//包括nginx默认的日志目录（prefix/logs）
+func LogDirs(conf *Configuration, prefix string) []string {
+	dirs := map[string]bool{}
+	if prefix != "" {
+		dirs[filepath.Join(prefix, "logs")] = true
+	}
+	var walk func(directive *Directive)
+	walk = func(directive *Directive) {
+		for _, child := range directive.Body {
+			if (child.Name == "access_log" || child.Name == "error_log") && len(child.Args) > 0 {
+				if file := child.Args[0]; isLogFile(file) {
+					if !filepath.IsAbs(file) {
+						if prefix == "" {
+							continue
+						}
+						file = filepath.Join(prefix, file)
+					}
+					//文件名中可以使用变量，目录中有变量时无法确定
+					if dir := filepath.Dir(filepath.Clean(file)); !strings.Contains(dir, "$") {
+						dirs[dir] = true
+					}
+				}
+			}
+			walk(child)
+		}
+	}
+	walk(conf)
+
+	logDirs := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		logDirs = append(logDirs, dir)
+	}
+	sort.Strings(logDirs)
+	return logDirs
+}
+
+//日志写入文件，不是关闭、标准错误、syslog或者内存
+func isLogFile(file string) bool {
+	return file != "off" && file != "stderr" && file != "/dev/null" &&
+		!strings.HasPrefix(file, "syslog:") && !strings.HasPrefix(file, "memory:")
+}
@@ -0,0 +1,263 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	modsecurityDir = "modsecurity"
+
+	WAFBlocking      = "blocking"  //SecRuleEngine On，拦截匹配规则的请求
+	WAFDetectionOnly = "detection" //SecRuleEngine DetectionOnly，只记录日志不拦截
+)
+
+var (
+	ErrWAFRuleInUse = errors.New("the rule file is used by modsecurity_rules_file")
+
+	wafRuleName = regexp.MustCompile(`^[\w.-]+$`)
+	secRuleMode = map[string]string{WAFBlocking: "On", WAFDetectionOnly: "DetectionOnly"}
+)
+
+//ModSecurity规则文件，保存在存储的 modsecurity/<name> 中
+type WAFRule struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+//server的ModSecurity(WAF)配置
+type WAF struct {
+	Domain string `json:"domain"`
+	Mode   string `json:"mode"` //blocking 或者 detection
+
+	//使用的规则文件：存储中的规则文件名称，或者nginx服务器上规则文件的绝对路径（例如OWASP CRS）
+	Rules []string `json:"rules"`
+}
+
+func WAFRulePath(name string) string {
+	return modsecurityDir + "/" + name
+}
+
+//modsecurity_rules_file 使用绝对路径，ModSecurity-nginx 不会按照nginx的配置目录查找相对路径
+func wafRuleFile(rule string) string {
+	if strings.Contains(rule, "/") {
+		return rule
+	}
+	return filepath.Join(MustConfigDir(), WAFRulePath(rule))
+}
+
+func wafRule(file string) string {
+	file = unquote(file)
+	if filepath.Dir(file) == filepath.Join(MustConfigDir(), modsecurityDir) {
+		return filepath.Base(file)
+	}
+	return file
+}
+
+func isModSecurity(directive *Directive) bool {
+	return strings.HasPrefix(directive.Name, "modsecurity")
+}
+
+func (waf *WAF) Validate() error {
+	if waf.Domain == "" {
+		return errors.New("the domain is empty")
+	}
+	if waf.Mode == "" {
+		waf.Mode = WAFBlocking
+	}
+	if _, has := secRuleMode[waf.Mode]; !has {
+		return errors.New("invalid waf mode: " + waf.Mode + ", support: blocking, detection")
+	}
+	for _, rule := range waf.Rules {
+		if strings.Contains(rule, "/") {
+			if !filepath.IsAbs(rule) || strings.ContainsAny(rule, " ;'\"") {
+				return errors.New("the rule file must be an absolute path: " + rule)
+			}
+		} else if !wafRuleName.MatchString(rule) {
+			return errors.New("invalid rule name: " + rule)
+		}
+	}
+	return nil
+}
+
+func (waf *WAF) directives() []*Directive {
+	directives := []*Directive{NewDirective("modsecurity", "on")}
+	for _, rule := range waf.Rules {
+		directives = append(directives, NewDirective("modsecurity_rules_file", wafRuleFile(rule)))
+	}
+	//放在规则文件之后，覆盖规则文件中的 SecRuleEngine
+	return append(directives, NewDirective("modsecurity_rules", fmt.Sprintf("'SecRuleEngine %s'", secRuleMode[waf.Mode])))
+}
+
+//从server配置中解析，没有开启时返回nil
+func parseWAF(directive *Directive) *WAF {
+	waf := &WAF{Mode: WAFBlocking, Rules: make([]string, 0)}
+	enabled := false
+	for _, body := range directive.Body {
+		if len(body.Args) == 0 {
+			continue
+		}
+		switch body.Name {
+		case "modsecurity":
+			enabled = body.Args[0] == "on"
+		case "modsecurity_rules_file":
+			waf.Rules = append(waf.Rules, wafRule(body.Args[0]))
+		case "modsecurity_rules":
+			rules := unquote(strings.Join(body.Args, " "))
+			for mode, value := range secRuleMode {
+				if strings.Contains(rules, "SecRuleEngine "+value) {
+					waf.Mode = mode
+				}
+			}
+		}
+	}
+	if !enabled {
+		return nil
+	}
+	return waf
+}
+
+//全部的规则文件
+func ListWAFRules(engine plugins.StorageEngine) ([]*WAFRule, error) {
+	files, err := engine.Search(modsecurityDir + "/*")
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*WAFRule, 0, len(files))
+	for _, file := range files {
+		rules = append(rules, &WAFRule{Name: filepath.Base(file.Name), Size: len(file.Content)})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	return rules, nil
+}
+
+func GetWAFRule(engine plugins.StorageEngine, name string) ([]byte, error) {
+	if !wafRuleName.MatchString(name) {
+		return nil, errors.New("invalid rule name: " + name)
+	}
+	file, err := engine.Get(WAFRulePath(name))
+	if err != nil {
+		return nil, err
+	}
+	return file.Content, nil
+}
+
+//保存规则文件，已经存在时替换
+func PutWAFRule(engine plugins.StorageEngine, name string, content []byte) error {
+	if !wafRuleName.MatchString(name) {
+		return errors.New("invalid rule name: " + name)
+	}
+	return engine.Put(WAFRulePath(name), content)
+}
+
+//使用此规则文件的server
+func (client *Client) WAFRuleUsages(name string) []string {
+	domains := make([]string, 0)
+	for _, waf := range client.WAFs() {
+		for _, rule := range waf.Rules {
+			if rule == name {
+				domains = append(domains, waf.Domain)
+				break
+			}
+		}
+	}
+	return domains
+}
+
+//删除规则文件，还在使用时返回错误
+func (client *Client) DeleteWAFRule(name string) error {
+	if domains := client.WAFRuleUsages(name); len(domains) > 0 {
+		return fmt.Errorf("%w: %s", ErrWAFRuleInUse, strings.Join(domains, ", "))
+	}
+	if _, err := GetWAFRule(client.Engine, name); err != nil {
+		return err
+	}
+	return client.Engine.Remove(WAFRulePath(name))
+}
+
+//开启了ModSecurity的server
+func (client *Client) WAFs() []*WAF {
+	wafs := make([]*WAF, 0)
+	for _, target := range client.scopes() {
+		if target.location != "" || target.directive.Name != "server" {
+			continue
+		}
+		if waf := parseWAF(target.directive); waf != nil {
+			waf.Domain = target.domain()
+			wafs = append(wafs, waf)
+		}
+	}
+	return wafs
+}
+
+func removeModSecurity(server *Directive) bool {
+	removed := false
+	body := make([]*Directive, 0, len(server.Body))
+	for _, directive := range server.Body {
+		if isModSecurity(directive) {
+			removed = true
+			continue
+		}
+		body = append(body, directive)
+	}
+	server.Body = body
+	return removed
+}
+
+//server开启ModSecurity，替换原来的配置。Rules为nil时使用原来的规则文件
+func (client *Client) SetWAF(waf *WAF) error {
+	if err := waf.Validate(); err != nil {
+		return err
+	}
+	servers, err := client.accessTargets(waf.Domain)
+	if err != nil {
+		return err
+	}
+	if waf.Rules == nil {
+		waf.Rules = make([]string, 0)
+		if current := parseWAF(servers[0]); current != nil {
+			waf.Rules = current.Rules
+		}
+	}
+	for _, rule := range waf.Rules {
+		if strings.Contains(rule, "/") {
+			continue
+		}
+		if _, err := client.Engine.Get(WAFRulePath(rule)); os.IsNotExist(err) {
+			return fmt.Errorf("%w: rule %s", ErrNotFound, rule)
+		} else if err != nil {
+			return err
+		}
+	}
+	for _, server := range servers {
+		removeModSecurity(server)
+		server.AddBodyDirective(waf.directives()...)
+	}
+	return nil
+}
+
+//server关闭ModSecurity
+func (client *Client) RemoveWAF(domain string) error {
+	if domain == "" {
+		return errors.New("the domain is empty")
+	}
+	servers, err := client.accessTargets(domain)
+	if err != nil {
+		return err
+	}
+	err = ErrNotFound
+	for _, server := range servers {
+		if removeModSecurity(server) {
+			err = nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,279 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//nginx程序信息，来自 nginx -V
+type Info struct {
+	Binary    string   `json:"binary"`
+	Version   string   `json:"version"`
+	Prefix    string   `json:"prefix,omitempty"`
+	Conf      string   `json:"conf"`
+	OpenSSL   string   `json:"openssl,omitempty"`
+	Modules   []string `json:"modules"`             //编译的模块，例如：http_v2, stream
+	Dynamic   []string `json:"dynamic,omitempty"`   //动态模块，需要使用 load_module 加载
+	Without   []string `json:"without,omitempty"`   //编译时去掉的默认模块
+	Arguments []string `json:"arguments,omitempty"` //全部编译参数
+}
+
+//默认编译的模块，可以使用 --without-xxx_module 去掉
+var defaultModules = map[string]bool{
+	"http_proxy": true, "http_fastcgi": true, "http_uwsgi": true, "http_scgi": true, "http_grpc": true,
+	"http_gzip": true, "http_rewrite": true, "http_limit_req": true, "http_limit_conn": true,
+	"http_auth_basic": true, "http_map": true, "http_memcached": true,
+	"stream_proxy": true, "stream_limit_conn": true, "stream_map": true,
+}
+
+//第三方模块的名称，ModSecurity-nginx的目录名称转为 http_modsecurity
+func addedModule(path string) string {
+	name := filepath.Base(path)
+	if strings.Contains(strings.ToLower(name), "modsecurity") {
+		return "http_modsecurity"
+	}
+	return name
+}
+
+//解析 nginx -V 的输出
+func ParseInfo(output string) (*Info, error) {
+	info := &Info{Modules: make([]string, 0)}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "nginx version:"):
+			version := strings.TrimSpace(strings.TrimPrefix(line, "nginx version:"))
+			if idx := strings.Index(version, "/"); idx != -1 {
+				version = version[idx+1:]
+			}
+			info.Version = strings.SplitN(version, " ", 2)[0]
+		case strings.HasPrefix(line, "built with "):
+			info.OpenSSL = strings.TrimPrefix(line, "built with ")
+		case strings.HasPrefix(line, "configure arguments:"):
+			info.Arguments = strings.Fields(strings.TrimPrefix(line, "configure arguments:"))
+		}
+	}
+	if info.Version == "" {
+		return nil, errors.New("not found nginx version: " + output)
+	}
+	for _, argument := range info.Arguments {
+		name, value := argument, ""
+		if idx := strings.Index(argument, "="); idx != -1 {
+			name, value = argument[:idx], argument[idx+1:]
+		}
+		switch {
+		case name == "--prefix":
+			info.Prefix = value
+		case name == "--sbin-path":
+			info.Binary = value
+		case name == "--conf-path":
+			info.Conf = value
+		case name == "--add-module":
+			info.Modules = append(info.Modules, addedModule(value))
+		case name == "--add-dynamic-module":
+			info.Dynamic = append(info.Dynamic, addedModule(value))
+		case strings.HasPrefix(name, "--without-") && strings.HasSuffix(name, "_module"):
+			info.Without = append(info.Without, strings.TrimSuffix(strings.TrimPrefix(name, "--without-"), "_module"))
+		case strings.HasPrefix(name, "--with-"):
+			module := strings.TrimSuffix(strings.TrimPrefix(name, "--with-"), "_module")
+			if module != "stream" && module != "mail" && !strings.HasSuffix(name, "_module") {
+				continue //--with-cc-opt 等不是模块的参数
+			}
+			if value == "dynamic" {
+				info.Dynamic = append(info.Dynamic, module)
+			} else {
+				info.Modules = append(info.Modules, module)
+			}
+		}
+	}
+	return info, nil
+}
+
+//查询nginx程序信息
+func GetNginxInfo() (*Info, error) {
+	out, err := nginxCommand("-V").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("nginx -V: %s %s", err, strings.TrimSpace(string(out)))
+	}
+	info, err := ParseInfo(string(out))
+	if err != nil {
+		return nil, err
+	}
+	if !InDocker() {
+		if binary, err := exec.LookPath("nginx"); err == nil {
+			info.Binary = binary
+		}
+	}
+	info.Conf = MustConf()
+	return info, nil
+}
+
+//nginx程序信息，查询成功后缓存
+func (sp *Process) Info() (*Info, error) {
+	sp.infoLock.Lock()
+	defer sp.infoLock.Unlock()
+	if sp.info == nil {
+		info, err := GetNginxInfo()
+		if err != nil {
+			return nil, err
+		}
+		sp.info = info
+	}
+	return sp.info, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//是否支持模块，动态模块需要在配置中使用 load_module 加载。
+//使用 --with-compat 单独编译的动态模块不在 nginx -V 中，使用 load_module 加载了也认为支持
+func (info *Info) Supports(module string, loaded ...string) bool {
+	if contains(info.Modules, module) {
+		return true
+	}
+	for _, load := range loaded {
+		if strings.Contains(filepath.Base(load), "ngx_"+module+"_module") {
+			return true
+		}
+	}
+	if contains(info.Dynamic, module) {
+		return false
+	}
+	return defaultModules[module] && !contains(info.Without, module)
+}
+
+//版本是否大于等于version，例如：info.AtLeast("1.25.1")
+func (info *Info) AtLeast(version string) bool {
+	current, want := strings.Split(info.Version, "."), strings.Split(version, ".")
+	for i := 0; i < len(want); i++ {
+		c, w := 0, 0
+		if i < len(current) {
+			c, _ = strconv.Atoi(current[i])
+		}
+		w, _ = strconv.Atoi(want[i])
+		if c != w {
+			return c > w
+		}
+	}
+	return true
+}
+
+//指令需要的模块，context为所在的顶级块：http, stream, mail
+func requireModules(context string, directive *Directive) []string {
+	prefix := "http_"
+	if context == "stream" || context == "mail" {
+		prefix = context + "_"
+	}
+	switch directive.Name {
+	case "stream", "mail":
+		return []string{directive.Name}
+	case "listen":
+		modules := make([]string, 0)
+		for _, arg := range directive.Args {
+			switch arg {
+			case "ssl":
+				modules = append(modules, prefix+"ssl")
+			case "http2":
+				modules = append(modules, "http_v2")
+			case "http3", "quic":
+				modules = append(modules, "http_v3")
+			}
+		}
+		return modules
+	case "ssl_certificate":
+		return []string{prefix + "ssl"}
+	case "http2":
+		return []string{"http_v2"}
+	case "set_real_ip_from":
+		return []string{prefix + "realip"}
+	case "geoip_country", "geoip_city":
+		return []string{prefix + "geoip"}
+	case "ssl_preread":
+		return []string{"stream_ssl_preread"}
+	case "proxy_pass":
+		return []string{prefix + "proxy"}
+	case "grpc_pass":
+		return []string{"http_grpc"}
+	case "fastcgi_pass":
+		return []string{"http_fastcgi"}
+	case "uwsgi_pass":
+		return []string{"http_uwsgi"}
+	case "scgi_pass":
+		return []string{"http_scgi"}
+	case "stub_status":
+		return []string{"http_stub_status"}
+	case "sub_filter":
+		return []string{"http_sub"}
+	case "auth_request":
+		return []string{"http_auth_request"}
+	case "gzip_static":
+		return []string{"http_gzip_static"}
+	case "gunzip":
+		return []string{"http_gunzip"}
+	case "secure_link":
+		return []string{"http_secure_link"}
+	case "image_filter":
+		return []string{"http_image_filter"}
+	case "mp4":
+		return []string{"http_mp4"}
+	case "flv":
+		return []string{"http_flv"}
+	case "dav_methods":
+		return []string{"http_dav"}
+	case "add_before_body", "add_after_body":
+		return []string{"http_addition"}
+	case "random_index":
+		return []string{"http_random_index"}
+	case "modsecurity", "modsecurity_rules", "modsecurity_rules_file", "modsecurity_rules_remote", "modsecurity_transaction_id":
+		return []string{"http_modsecurity"}
+	}
+	return nil
+}
+
+func loadModules(conf *Configuration) []string {
+	loaded := make([]string, 0)
+	for _, directive := range conf.Body {
+		if directive.Name == "load_module" && len(directive.Args) > 0 {
+			loaded = append(loaded, directive.Args[0])
+		} else if directive.Name == "include" || directive.Virtual == Include {
+			loaded = append(loaded, loadModules(directive)...)
+		}
+	}
+	return loaded
+}
+
+func (info *Info) check(context string, directive *Directive, loaded []string, unsupported *[]string) {
+	for _, body := range directive.Body {
+		for _, module := range requireModules(context, body) {
+			if !info.Supports(module, loaded...) {
+				usage := strings.TrimSpace(body.Name + " " + strings.Join(body.Args, " "))
+				*unsupported = append(*unsupported, fmt.Sprintf("'%s' requires module %s", usage, module))
+			}
+		}
+		if context == "" && (body.Name == "http" || body.Name == "stream" || body.Name == "mail") {
+			info.check(body.Name, body, loaded, unsupported)
+		} else {
+			info.check(context, body, loaded, unsupported)
+		}
+	}
+}
+
+//检查配置中使用的指令是否被nginx支持
+func (info *Info) Check(conf *Configuration) error {
+	unsupported := make([]string, 0)
+	info.check("", conf, loadModules(conf), &unsupported)
+	if len(unsupported) > 0 {
+		return fmt.Errorf("not supported by nginx %s: %s", info.Version, strings.Join(unsupported, "; "))
+	}
+	return nil
+}
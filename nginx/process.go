@@ -1,11 +1,16 @@
 package nginx
 
 import (
+	"errors"
 	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,15 +20,50 @@ var logger = logs.New("nginx")
 
 type Process struct {
 	startCmd *exec.Cmd
+	exited   chan struct{} //startCmd启动的nginx已经退出
+	Health   *HealthCheck  //重启后的健康检查，为空时不检查
+
+	StubStatusListen string //stub_status server的监听地址，为空时没有开启
+
+	Engine plugins.StorageEngine //配置文件的存储，重启后不正常时通过存储恢复配置，为空时不恢复
+
+	Overlay func(testDir string) error //测试前写入节点覆盖后的配置文件，测试节点实际使用的配置，为空时不使用
+
+	lock       sync.Mutex
+	lastGood   map[string][]byte
+	lastGoodAt time.Time
+
+	infoLock sync.Mutex
+	info     *Info
+
+	Debounce     time.Duration //合并此时间内存储同步和服务发现的多次重启为一次，为0时立即重启
+	debounceLock sync.Mutex
+	pending      *pendingReload
+
+	Strict string //检查未知指令和指令的context：off, warn(记录日志), reject(测试失败)
+
+	KeepRunning bool //aginx退出时不停止nginx，启动时使用已经运行的nginx
+}
+
+//等待中的重启，等待的调用者共享重启结果
+type pendingReload struct {
+	done chan struct{}
+	err  error
 }
 
 func (sp *Process) start() error {
+	if InDocker() { //容器中的nginx由docker管理
+		return nil
+	}
 	err := util.Async(time.Second*5, func() (err error) {
 		sp.startCmd, err = util.CmdStart("nginx", "-g", "daemon off;")
 		if err == nil {
+			exited := make(chan struct{})
+			sp.exited = exited
 			sp.startCmd.Stdout = os.Stdout
 			sp.startCmd.Stderr = os.Stderr
 			err = util.CmdAfterWait(sp.startCmd)
+			close(exited)
 		}
 		return
 	})
@@ -34,44 +74,194 @@ func (sp *Process) start() error {
 }
 
 func (sp *Process) Start() (err error) {
-	util.SubscribeFileChanged(sp.Reload)
+	util.SubscribeFileChanged(sp.fileChanged)
+
+	//上次退出时保留的nginx，重新加载配置后继续使用
+	if sp.KeepRunning && !InDocker() && runCommand("-s", "reload") == nil {
+		logger.Info("use the running NGINX")
+		sp.keep()
+		return nil
+	}
 
 	if err = sp.start(); err != nil {
 		logger.Warn("start NGINX error ", err)
-		err = sp.Stop()
+		err = sp.stop()
 		logger.WithError(err).Debug("first stop NGINX")
 		err = sp.start()
 	}
 	logger.WithError(err).Info("start NGINX")
+	if err == nil {
+		sp.keep()
+	}
 	return
 }
 
+//存储同步的文件变更，合并重启时不阻塞同步
+func (sp *Process) fileChanged() error {
+	if sp.Debounce > 0 {
+		go func() { _ = sp.MergeReload() }()
+		return nil
+	}
+	return sp.Reload()
+}
+
+//立即重启nginx。api的修改使用此方法，不等待合并，避免修改请求长时间阻塞
 func (sp *Process) Reload() error {
-	err := util.CmdRun("nginx", "-s", "reload")
+	return sp.reload()
+}
+
+//合并重启：设置了Debounce时等待Debounce时间，期间的全部合并重启请求合并为一次。
+//用于存储同步和服务发现（/reload）等频繁的重启
+func (sp *Process) MergeReload() error {
+	if sp.Debounce <= 0 {
+		return sp.reload()
+	}
+	sp.debounceLock.Lock()
+	pending := sp.pending
+	if pending == nil {
+		pending = &pendingReload{done: make(chan struct{})}
+		sp.pending = pending
+		time.AfterFunc(sp.Debounce, func() {
+			sp.debounceLock.Lock()
+			sp.pending = nil
+			sp.debounceLock.Unlock()
+
+			pending.err = sp.reload()
+			close(pending.done)
+		})
+	}
+	sp.debounceLock.Unlock()
+	<-pending.done
+	return pending.err
+}
+
+func (sp *Process) reload() error {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+
+	start := time.Now()
+	err := runCommand("-s", "reload")
 	logger.Info("reload NGINX ", err)
+	util.PublishEvent(util.EventReload, "reload NGINX", err,
+		map[string]interface{}{"duration": time.Since(start).Seconds()})
+	if err != nil {
+		return err
+	}
+	if err = sp.healthy(); err != nil {
+		return sp.rollback(err)
+	}
+	sp.keep()
+	return nil
+}
+
+//通知nginx重新打开日志文件
+func (sp *Process) Reopen() error {
+	if sp.startCmd != nil {
+		return reopenProcess(sp.startCmd.Process)
+	}
+	return runCommand("-s", "reopen")
+}
+
+func (sp *Process) Test(cfg *Configuration, beforeHocks ...func(testDir string) error) error {
+	_, err := sp.Validate(cfg, beforeHocks...)
 	return err
 }
 
-func (sp *Process) Test(cfg *Configuration, beforeHocks ...func(testDir string) error) (err error) {
-	defer util.CatchError(err)
+//在临时目录中写入配置并执行 nginx -t，返回nginx输出内容，不会影响正在使用的配置
+func (sp *Process) Validate(cfg *Configuration, beforeHocks ...func(testDir string) error) (output string, err error) {
+	defer util.Catch(func(e error) {
+		err = e
+	})
+	if err = sp.CheckStrict("main", cfg.Body...); err != nil {
+		output = err.Error()
+		util.PublishEvent(util.EventTestFailure, "test NGINX configuration", err, nil)
+		return
+	}
+	//使用nginx编译的模块检查配置，查询不到nginx信息时只使用 nginx -t
+	if info, infoErr := sp.Info(); infoErr == nil {
+		if err = info.Check(cfg); err != nil {
+			output = err.Error()
+			util.PublishEvent(util.EventTestFailure, "test NGINX configuration", err, nil)
+			return
+		}
+	} else {
+		logger.WithError(infoErr).Debug("get NGINX info")
+	}
+
 	configDir := MustConfigDir()
-	testDir := filepath.Dir(os.TempDir()) + "/aginx"
-	util.PanicIfError(os.RemoveAll(testDir))
+	testDir, err := ioutil.TempDir("", "aginx")
+	util.PanicIfError(err)
+	defer func() { _ = os.RemoveAll(testDir) }()
+
 	util.PanicIfError(util.CopyDir(configDir, testDir))
 	util.PanicIfError(WriteTo(testDir, cfg))
+	if sp.Overlay != nil {
+		util.PanicIfError(sp.Overlay(testDir))
+	}
 
 	for _, beforeHock := range beforeHocks {
 		util.PanicIfError(beforeHock(testDir))
 	}
 
-	util.PanicIfError(util.CmdRun("nginx", "-t" /*"-p", path,*/, "-c", filepath.Join(testDir, NGINX_CONF)))
+	var out []byte
+	if InDocker() {
+		out, err = dockerTest(testDir)
+	} else {
+		out, err = nginxCommand("-t" /*"-p", path,*/, "-c", filepath.Join(testDir, NGINX_CONF)).CombinedOutput()
+	}
+	output = strings.TrimSpace(string(out))
+	if err != nil {
+		err = errors.New(output)
+		util.PublishEvent(util.EventTestFailure, "test NGINX configuration", err, nil)
+	}
 	return
 }
 
+//严格模式检查指令，warn 时只记录日志，reject 时返回错误
+func (sp *Process) CheckStrict(context string, directives ...*Directive) error {
+	if sp.Strict != StrictWarn && sp.Strict != StrictReject {
+		return nil
+	}
+	problems := CheckSchema(context, directives...)
+	if len(problems) == 0 {
+		return nil
+	}
+	if sp.Strict == StrictReject {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	logger.Warn("strict check: ", strings.Join(problems, "; "))
+	return nil
+}
+
+//等待合并中的重启完成，KeepRunning时不停止nginx
 func (sp *Process) Stop() error {
+	sp.debounceLock.Lock()
+	pending := sp.pending
+	sp.debounceLock.Unlock()
+	if pending != nil {
+		<-pending.done
+	}
+	if sp.KeepRunning {
+		logger.Info("keep NGINX running")
+		return nil
+	}
+	return sp.stop()
+}
+
+func (sp *Process) stop() error {
 	if sp.startCmd != nil {
-		return sp.startCmd.Process.Kill()
+		//先优雅停止，等待处理中的请求完成
+		if err := quitProcess(sp.startCmd.Process); err == nil && sp.exited != nil {
+			select {
+			case <-sp.exited:
+				return nil
+			case <-time.After(time.Second * 5):
+			}
+		}
+		return stopProcess(sp.startCmd.Process)
+	} else if InDocker() {
+		return nil
 	} else {
-		return util.CmdRun("nginx", "-s", "quit")
+		return runCommand("-s", "quit")
 	}
 }
@@ -1,12 +1,17 @@
 package nginx
 
 import (
+	"fmt"
 	"github.com/alecthomas/participle"
+	"regexp"
+	"sync"
 )
 
 type QueryArg struct {
-	Comparison string `[@("!" | "@" | "^" | "$")]`
+	Comparison string `[@("!" | "@" | "^" | "$" | "~")]`
 	Value      string `@(String|RawString|Ident)`
+
+	regexp *regexp.Regexp //解析时编译的正则（~）
 }
 
 type QueryArgAddition struct {
@@ -20,12 +25,14 @@ type QueryArgs struct {
 }
 
 type QueryDirective struct {
-	Comparison string `( ( [@("!" | "@" | "^" | "$")]`
+	Comparison string `( ( [@("!" | "@" | "^" | "$" | "~")]`
 	Name       string `@Ident )`
 
 	All string ` | @"*" )`
 
 	Args *QueryArgs `["(" [@@] ")"]`
+
+	regexp *regexp.Regexp //解析时编译的正则（~）
 }
 
 type QueryChildren struct {
@@ -43,14 +50,105 @@ type QueryChildrenAddition struct {
 	Next     *QueryDirective `@@`
 }
 
+//位置选择，[-1]表示最后一个
+type QueryIndex struct {
+	Negative bool `"[" [@"-"]`
+	Value    int  `@Int "]"`
+}
+
 type Expression struct {
 	Directive *QueryDirective  `@@`
 	Children  []*QueryChildren `("." @@)*`
+	Index     *QueryIndex      `[@@]`
 }
 
+//缓存的查询条件数量超过后清空
+var maxCachedQueries = 1000
+
+var (
+	queryLock   sync.Mutex
+	queryParser *participle.Parser
+	queryCache  = make(map[string]*Expression)
+)
+
+//解析查询条件，解析结果会被缓存，调用者不能修改返回的结果
 func Parser(str string) (expr *Expression, err error) {
+	queryLock.Lock()
+	defer queryLock.Unlock()
+	if cached, has := queryCache[str]; has {
+		return cached, nil
+	}
+	if queryParser == nil {
+		queryParser = participle.MustBuild(&Expression{})
+	}
 	expr = &Expression{}
-	parser := participle.MustBuild(expr)
-	err = parser.ParseString(str, expr)
+	if err = queryParser.ParseString(str, expr); err != nil {
+		return
+	}
+	if err = expr.compile(); err != nil {
+		return nil, err
+	}
+	if len(queryCache) >= maxCachedQueries {
+		queryCache = make(map[string]*Expression)
+	}
+	queryCache[str] = expr
 	return
 }
+
+func compile(comparison, value string) (*regexp.Regexp, error) {
+	if comparison != "~" {
+		return nil, nil
+	}
+	reg, err := regexp.Compile(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %s: %w", value, err)
+	}
+	return reg, nil
+}
+
+func (a *QueryArg) compile() (err error) {
+	a.regexp, err = compile(a.Comparison, a.Value)
+	return
+}
+
+func (e *QueryDirective) compile() (err error) {
+	if e.regexp, err = compile(e.Comparison, e.Name); err != nil {
+		return
+	}
+	if e.Args == nil || e.Args.Arg == nil {
+		return
+	}
+	if err = e.Args.Arg.compile(); err != nil {
+		return
+	}
+	for _, addition := range e.Args.Next {
+		if err = addition.Arg.compile(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+//编译查询条件中的正则，查询时不再重复编译
+func (e *Expression) compile() error {
+	if err := e.Directive.compile(); err != nil {
+		return err
+	}
+	for _, child := range e.Children {
+		if child.Directive != nil {
+			if err := child.Directive.compile(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := child.Group.First.compile(); err != nil {
+			return err
+		}
+		for _, addition := range child.Group.Next {
+			if err := addition.Next.compile(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -1,39 +1,32 @@
 package nginx
 
 import (
+	"regexp"
 	"strings"
 )
 
 func (a QueryArgs) Match(directive *Directive) bool {
-	if !match(a.Arg.Comparison, directive.Args, a.Arg.Value) {
-		return false
-	}
-	if a.Next != nil {
-		ret := true
-		for _, addition := range a.Next {
-			if addition.Operator == "&" {
-				ret = ret && match(addition.Arg.Comparison, directive.Args, addition.Arg.Value)
-			} else if addition.Operator == "|" {
-				ret = ret || match(addition.Arg.Comparison, directive.Args, addition.Arg.Value)
-			}
-		}
-		if ret == false {
-			return false
+	ret := a.Arg.match(directive.Args)
+	for _, addition := range a.Next {
+		if addition.Operator == "&" {
+			ret = ret && addition.Arg.match(directive.Args)
+		} else if addition.Operator == "|" {
+			ret = ret || addition.Arg.match(directive.Args)
 		}
 	}
-	return true
+	return ret
 }
 
 func (c *QueryChildren) Match(directive *Directive) bool {
 	if c.Directive != nil {
-		return c.Directive.MatchAny(directive.Body)
+		return c.Directive.MatchChild(directive.Body)
 	} else {
-		ret := c.Group.First.MatchAny(directive.Body)
+		ret := c.Group.First.MatchChild(directive.Body)
 		for _, addition := range c.Group.Next {
 			if addition.Operator == "&" {
-				ret = ret && addition.Next.MatchAny(directive.Body)
+				ret = ret && addition.Next.MatchChild(directive.Body)
 			} else if addition.Operator == "|" {
-				ret = ret || addition.Next.MatchAny(directive.Body)
+				ret = ret || addition.Next.MatchChild(directive.Body)
 			}
 		}
 
@@ -46,7 +39,7 @@ func (c *QueryChildren) Match(directive *Directive) bool {
 
 func (e *QueryDirective) Match(directive *Directive) bool {
 
-	if e.Name != "" && !match(e.Comparison, []string{directive.Name}, e.Name) {
+	if e.Name != "" && !match(e.Comparison, []string{directive.Name}, e.Name, e.regexp) {
 		return false
 	} else if e.All == "*" {
 		//true
@@ -70,6 +63,15 @@ func (e *QueryDirective) MatchAny(directive []*Directive) bool {
 	return false
 }
 
+//子条件匹配，名称取反(!server_name('a'))表示不存在匹配的子指令
+func (e *QueryDirective) MatchChild(directive []*Directive) bool {
+	if e.Comparison == "!" && e.Name != "" {
+		positive := &QueryDirective{Name: e.Name, Args: e.Args}
+		return !positive.MatchAny(directive)
+	}
+	return e.MatchAny(directive)
+}
+
 func (e *Expression) Match(directive *Directive) bool {
 	if !e.Directive.Match(directive) {
 		return false
@@ -93,10 +95,30 @@ func (e *Expression) Match(directive *Directive) bool {
 	return true
 }
 
-func match(comparison string, values []string, query string) bool {
+//从同一个父指令下匹配的指令中选择指定位置的指令，没有设置位置将返回全部
+func (e *Expression) Pick(matched []*Directive) []*Directive {
+	if e.Index == nil {
+		return matched
+	}
+	idx := e.Index.Value
+	if e.Index.Negative {
+		idx = len(matched) - idx
+	}
+	if idx < 0 || idx >= len(matched) {
+		return []*Directive{}
+	}
+	return []*Directive{matched[idx]}
+}
+
+func (a *QueryArg) match(values []string) bool {
+	return match(a.Comparison, values, a.Value, a.regexp)
+}
+
+//reg为解析查询条件时编译的正则（~）
+func match(comparison string, values []string, query string, reg *regexp.Regexp) bool {
 	switch comparison {
 	case "!":
-		return !match("", values, query)
+		return !match("", values, query, nil)
 	case "@":
 		for _, value := range values {
 			if strings.Contains(value, query) {
@@ -115,6 +137,15 @@ func match(comparison string, values []string, query string) bool {
 				return true
 			}
 		}
+	case "~":
+		if reg == nil { //没有通过Parser解析的查询条件
+			reg = regexp.MustCompile(query)
+		}
+		for _, value := range values {
+			if reg.MatchString(value) {
+				return true
+			}
+		}
 	default:
 		for _, value := range values {
 			if value == query {
@@ -0,0 +1,311 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	zoneName  = regexp.MustCompile(`^[\w-]+$`)
+	zoneSize  = regexp.MustCompile(`^\d+[kKmM]?$`)
+	limitRate = regexp.MustCompile(`^\d+r/[sm]$`)
+)
+
+//限流：在http中定义limit_req_zone，在http、server或者location中使用limit_req
+type RateLimit struct {
+	Zone string `json:"zone"`
+	Key  string `json:"key,omitempty"`  //限流的key，默认：$binary_remote_addr
+	Size string `json:"size,omitempty"` //共享内存大小，默认：10m
+	Rate string `json:"rate,omitempty"` //速率：10r/s, 60r/m，区域不存在时必须设置
+
+	//Domain为空时作用于全部http，Location为空时作用于整个server
+	Domain   string `json:"domain,omitempty"`
+	Location string `json:"location,omitempty"`
+	Burst    int    `json:"burst,omitempty"`
+	NoDelay  bool   `json:"nodelay,omitempty"`
+}
+
+//limit_req_zone定义的区域和使用此区域的limit_req
+type RateLimitZone struct {
+	Name   string       `json:"name"`
+	Key    string       `json:"key"`
+	Size   string       `json:"size"`
+	Rate   string       `json:"rate"`
+	Limits []*RateLimit `json:"limits"`
+}
+
+func (limit *RateLimit) Validate() error {
+	if !zoneName.MatchString(limit.Zone) {
+		return errors.New("invalid zone name: " + limit.Zone)
+	}
+	if limit.Size != "" && !zoneSize.MatchString(limit.Size) {
+		return errors.New("invalid zone size: " + limit.Size)
+	}
+	if limit.Rate != "" && !limitRate.MatchString(limit.Rate) {
+		return errors.New("invalid rate: " + limit.Rate + ", example: 10r/s, 60r/m")
+	}
+	if limit.Burst < 0 {
+		return errors.New("the burst must not be negative")
+	}
+	if limit.Domain == "" && limit.Location != "" {
+		return errors.New("the domain of location is empty")
+	}
+	return nil
+}
+
+//limit_req_zone $binary_remote_addr zone=name:10m rate=10r/s;
+func ParseRateLimitZone(directive *Directive) *RateLimitZone {
+	zone := &RateLimitZone{Limits: make([]*RateLimit, 0)}
+	for i, arg := range directive.Args {
+		if strings.HasPrefix(arg, "zone=") {
+			zone.Name = strings.TrimPrefix(arg, "zone=")
+			if idx := strings.Index(zone.Name, ":"); idx != -1 {
+				zone.Name, zone.Size = zone.Name[:idx], zone.Name[idx+1:]
+			}
+		} else if strings.HasPrefix(arg, "rate=") {
+			zone.Rate = strings.TrimPrefix(arg, "rate=")
+		} else if i == 0 {
+			zone.Key = arg
+		}
+	}
+	return zone
+}
+
+func (zone *RateLimitZone) Directive() *Directive {
+	return NewDirective("limit_req_zone", zone.Key, fmt.Sprintf("zone=%s:%s", zone.Name, zone.Size), "rate="+zone.Rate)
+}
+
+//limit_req zone=name burst=20 nodelay;
+func parseLimitReq(directive *Directive) *RateLimit {
+	limit := new(RateLimit)
+	for _, arg := range directive.Args {
+		if strings.HasPrefix(arg, "zone=") {
+			limit.Zone = strings.TrimPrefix(arg, "zone=")
+		} else if strings.HasPrefix(arg, "burst=") {
+			limit.Burst, _ = strconv.Atoi(strings.TrimPrefix(arg, "burst="))
+		} else if arg == "nodelay" {
+			limit.NoDelay = true
+		}
+	}
+	return limit
+}
+
+func (limit *RateLimit) limitReq() *Directive {
+	directive := NewDirective("limit_req", "zone="+limit.Zone)
+	if limit.Burst > 0 {
+		directive.Args = append(directive.Args, fmt.Sprintf("burst=%d", limit.Burst))
+	}
+	if limit.NoDelay {
+		directive.Args = append(directive.Args, "nodelay")
+	}
+	return directive
+}
+
+//http、server和location，可以使用limit_req、allow、deny等指令
+type scope struct {
+	names     []string //server_name
+	location  string
+	directive *Directive
+}
+
+func (target *scope) domain() string {
+	if len(target.names) > 0 {
+		return target.names[0]
+	}
+	return ""
+}
+
+func (target *scope) match(domain, location string) bool {
+	if target.location != location {
+		return false
+	}
+	for _, name := range target.names {
+		if name == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func (client *Client) scopes() []*scope {
+	targets := make([]*scope, 0)
+	if https, err := client.Select("http"); err == nil {
+		for _, http := range https {
+			targets = append(targets, &scope{directive: http})
+		}
+	}
+	for _, queries := range [][]string{Queries("http", "server"), Queries("http", "include", "*", "server")} {
+		servers, err := client.Select(queries...)
+		if err != nil {
+			continue
+		}
+		for _, server := range servers {
+			names := make([]string, 0)
+			if serverNames, err := server.Select("server_name"); err == nil {
+				for _, serverName := range serverNames {
+					names = append(names, serverName.Args...)
+				}
+			}
+			targets = append(targets, &scope{names: names, directive: server})
+			if locations, err := server.Select("location"); err == nil {
+				for _, location := range locations {
+					targets = append(targets, &scope{
+						names: names, location: strings.Join(location.Args, " "), directive: location,
+					})
+				}
+			}
+		}
+	}
+	return targets
+}
+
+//定义limit_req_zone的http和include文件
+func (client *Client) zoneParents() []*Directive {
+	parents := make([]*Directive, 0)
+	for _, queries := range [][]string{Queries("http"), Queries("http", "include", "*")} {
+		if directives, err := client.Select(queries...); err == nil {
+			parents = append(parents, directives...)
+		}
+	}
+	return parents
+}
+
+func (client *Client) findRateLimitZone(name string) *Directive {
+	for _, parent := range client.zoneParents() {
+		for _, directive := range parent.Body {
+			if directive.Name == "limit_req_zone" && ParseRateLimitZone(directive).Name == name {
+				return directive
+			}
+		}
+	}
+	return nil
+}
+
+//全部的限流区域和使用的位置
+func (client *Client) RateLimits() []*RateLimitZone {
+	zones := make([]*RateLimitZone, 0)
+	named := make(map[string]*RateLimitZone)
+	for _, parent := range client.zoneParents() {
+		for _, directive := range parent.Body {
+			if directive.Name == "limit_req_zone" {
+				zone := ParseRateLimitZone(directive)
+				zones = append(zones, zone)
+				named[zone.Name] = zone
+			}
+		}
+	}
+	for _, target := range client.scopes() {
+		for _, directive := range target.directive.Body {
+			if directive.Name != "limit_req" {
+				continue
+			}
+			limit := parseLimitReq(directive)
+			limit.Domain, limit.Location = target.domain(), target.location
+			if zone, has := named[limit.Zone]; has {
+				zone.Limits = append(zone.Limits, limit)
+			}
+		}
+	}
+	return zones
+}
+
+//设置限流：区域不存在时创建，已经存在时修改设置的key、size、rate；目标位置已经使用此区域时替换burst和nodelay
+func (client *Client) SetRateLimit(limit *RateLimit) error {
+	if err := limit.Validate(); err != nil {
+		return err
+	}
+	var targets []*Directive
+	if limit.Domain == "" {
+		targets, _ = client.Select("http")
+	} else {
+		for _, queries := range serverQueries(limit.Domain) {
+			if limit.Location != "" {
+				queries = append(queries, fmt.Sprintf("location('%s')", limit.Location))
+			}
+			if directives, err := client.Select(queries...); err == nil {
+				targets = append(targets, directives...)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return ErrNotFound
+	}
+
+	if directive := client.findRateLimitZone(limit.Zone); directive == nil {
+		if limit.Rate == "" {
+			return errors.New("the rate of new zone is empty")
+		}
+		zone := &RateLimitZone{Name: limit.Zone, Key: limit.Key, Size: limit.Size, Rate: limit.Rate}
+		if zone.Key == "" {
+			zone.Key = "$binary_remote_addr"
+		}
+		if zone.Size == "" {
+			zone.Size = "10m"
+		}
+		if err := client.Add(Queries("http"), zone.Directive()); err != nil {
+			return err
+		}
+	} else {
+		zone := ParseRateLimitZone(directive)
+		if limit.Key != "" {
+			zone.Key = limit.Key
+		}
+		if limit.Size != "" {
+			zone.Size = limit.Size
+		}
+		if limit.Rate != "" {
+			zone.Rate = limit.Rate
+		}
+		directive.Args = zone.Directive().Args
+	}
+
+	for _, target := range targets {
+		replaced := false
+		for i, directive := range target.Body {
+			if directive.Name == "limit_req" && parseLimitReq(directive).Zone == limit.Zone {
+				target.Body[i], replaced = limit.limitReq(), true
+			}
+		}
+		if !replaced {
+			target.AddBodyDirective(limit.limitReq())
+		}
+	}
+	return nil
+}
+
+//删除限流：domain为空时删除区域和全部使用此区域的limit_req，否则只删除server（或者location）中的limit_req
+func (client *Client) DeleteRateLimit(zone, domain, location string) error {
+	err := ErrNotFound
+	for _, target := range client.scopes() {
+		if domain != "" && !target.match(domain, location) {
+			continue
+		}
+		body := make([]*Directive, 0, len(target.directive.Body))
+		for _, directive := range target.directive.Body {
+			if directive.Name == "limit_req" && parseLimitReq(directive).Zone == zone {
+				err = nil
+				continue
+			}
+			body = append(body, directive)
+		}
+		target.directive.Body = body
+	}
+	if domain != "" {
+		return err
+	}
+	for _, parent := range client.zoneParents() {
+		body := make([]*Directive, 0, len(parent.Body))
+		for _, directive := range parent.Body {
+			if directive.Name == "limit_req_zone" && ParseRateLimitZone(directive).Name == zone {
+				err = nil
+				continue
+			}
+			body = append(body, directive)
+		}
+		parent.Body = body
+	}
+	return err
+}
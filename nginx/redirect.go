@@ -0,0 +1,204 @@
+package nginx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	//生成的map和没有server的域名使用的server
+	redirectFile = "redirects.ngx.conf"
+	//跳转规则保存在存储中
+	redirectStore = "redirect/redirects.json"
+)
+
+var (
+	redirectVariables = map[int]string{301: "$aginx_redirect_301", 302: "$aginx_redirect_302"}
+	redirectInvalid   = regexp.MustCompile(`[\s"';{}\\]`)
+)
+
+//URL跳转，Path为空时跳转整个域名（保留请求地址）
+type Redirect struct {
+	Domain string `json:"domain"`
+	Path   string `json:"path,omitempty"` //精确匹配的请求路径，例如：/promo
+	To     string `json:"to"`             //跳转的地址，跳转整个域名时为：https://www.aginx.io
+	Code   int    `json:"code,omitempty"` //301或者302，默认：301
+}
+
+//检查并把域名转为小写
+func (r *Redirect) Validate() error {
+	if r.Domain = strings.ToLower(r.Domain); r.Domain == "" || redirectInvalid.MatchString(r.Domain) || strings.ContainsAny(r.Domain, "/*") {
+		return errors.New("invalid domain: " + r.Domain)
+	}
+	if r.Path != "" && (!strings.HasPrefix(r.Path, "/") || redirectInvalid.MatchString(r.Path)) {
+		return errors.New("invalid path: " + r.Path)
+	}
+	if r.To == "" || redirectInvalid.MatchString(r.To) {
+		return errors.New("invalid redirect to: " + r.To)
+	}
+	if r.Code == 0 {
+		r.Code = 301
+	} else if _, has := redirectVariables[r.Code]; !has {
+		return fmt.Errorf("the code of redirect must be 301 or 302: %d", r.Code)
+	}
+	return nil
+}
+
+//map $host$uri 中的匹配值，跳转整个域名时使用正则，nginx优先使用精确匹配
+func (r *Redirect) key() string {
+	if r.Path == "" {
+		return `"~^` + regexp.QuoteMeta(r.Domain) + `/"`
+	}
+	return `"` + r.Domain + r.Path + `"`
+}
+
+func (r *Redirect) target() string {
+	if r.Path == "" {
+		return `"` + strings.TrimSuffix(r.To, "/") + `$request_uri"`
+	}
+	return `"` + r.To + `"`
+}
+
+//if ($aginx_redirect_301) { return 301 $aginx_redirect_301; }
+func redirectIf(code int) *Directive {
+	variable := redirectVariables[code]
+	directive := NewDirective("if", "("+variable+")")
+	directive.AddBody("return", fmt.Sprintf("%d", code), variable)
+	return directive
+}
+
+func isRedirectIf(directive *Directive) bool {
+	for _, variable := range redirectVariables {
+		if isIfVariable(directive, variable) {
+			return true
+		}
+	}
+	return false
+}
+
+//http中include的跳转文件，不存在时创建
+func (client *Client) redirectFile() (file *Directive, err error) {
+	include := fmt.Sprintf("include('%s')", redirectFile)
+	if files, err := client.Select("http", include, fmt.Sprintf("file('%s')", redirectFile)); err == nil {
+		return files[0], nil
+	}
+	if _, err = client.Select("http", include); err != nil {
+		if err = client.Add(Queries("http"), NewDirective("include", redirectFile)); err != nil {
+			return
+		}
+	}
+	file = NewDirective("file", redirectFile)
+	file.Virtual = Include
+	err = client.Add(Queries("http", include), file)
+	return
+}
+
+//使用全部跳转规则重新生成跳转文件，在域名的server中添加跳转，没有server的域名生成只用于跳转的server
+func (client *Client) SetRedirects(redirects []*Redirect) error {
+	keys := make(map[string]bool)
+	for _, redirect := range redirects {
+		if err := redirect.Validate(); err != nil {
+			return err
+		}
+		if keys[redirect.key()] {
+			return errors.New("duplicate redirect: " + redirect.Domain + redirect.Path)
+		}
+		keys[redirect.key()] = true
+	}
+	file, err := client.redirectFile()
+	if err != nil {
+		return err
+	}
+	file.Body = make([]*Directive, 0)
+	for _, target := range client.scopes() {
+		if target.location == "" {
+			body := make([]*Directive, 0, len(target.directive.Body))
+			for _, directive := range target.directive.Body {
+				if !isRedirectIf(directive) {
+					body = append(body, directive)
+				}
+			}
+			target.directive.Body = body
+		}
+	}
+	if len(redirects) == 0 {
+		return nil
+	}
+
+	maps := make(map[int]*Directive)
+	for _, code := range []int{301, 302} {
+		maps[code] = NewDirective("map", "$host$uri", redirectVariables[code])
+		maps[code].AddBody("default", `""`)
+		file.AddBodyDirective(maps[code])
+	}
+	domains := make([]string, 0)
+	for _, redirect := range redirects {
+		maps[redirect.Code].AddBody(redirect.key(), redirect.target())
+		if servers, err := client.domainServers(redirect.Domain); err != nil {
+			domains = append(domains, redirect.Domain)
+		} else {
+			for _, server := range servers {
+				if !hasRedirectIf(server) {
+					server.Body = append([]*Directive{redirectIf(301), redirectIf(302)}, server.Body...)
+				}
+			}
+		}
+	}
+	if len(domains) > 0 {
+		sort.Strings(domains)
+		server := NewDirective("server")
+		server.AddBody("listen", "80")
+		server.AddBody("server_name", unique(domains)...)
+		server.AddBodyDirective(redirectIf(301), redirectIf(302))
+		server.AddBody("return", "404")
+		file.AddBodyDirective(server)
+	}
+	return nil
+}
+
+func hasRedirectIf(server *Directive) bool {
+	for _, directive := range server.Body {
+		if isRedirectIf(directive) {
+			return true
+		}
+	}
+	return false
+}
+
+func unique(values []string) []string {
+	out := make([]string, 0, len(values))
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+//存储中的全部跳转规则
+func LoadRedirects(engine plugins.StorageEngine) ([]*Redirect, error) {
+	redirects := make([]*Redirect, 0)
+	file, err := engine.Get(redirectStore)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return redirects, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(file.Content, &redirects)
+	return redirects, err
+}
+
+func SaveRedirects(engine plugins.StorageEngine, redirects []*Redirect) error {
+	content, err := json.MarshalIndent(redirects, "", "  ")
+	if err != nil {
+		return err
+	}
+	return engine.Put(redirectStore, content)
+}
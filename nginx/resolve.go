@@ -0,0 +1,500 @@
+package nginx
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//模拟的请求
+type ResolveRequest struct {
+	Host    string            `json:"host"`
+	Uri     string            `json:"uri"`              //包括查询参数，例如：/api/users?page=1
+	Port    int               `json:"port,omitempty"`   //默认80，scheme为https时默认443
+	Scheme  string            `json:"scheme,omitempty"` //http或者https
+	Method  string            `json:"method,omitempty"`
+	Remote  string            `json:"remote,omitempty"` //客户端地址
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type ResolvedServer struct {
+	File       string   `json:"file"`
+	ServerName []string `json:"server_name,omitempty"`
+	Listen     []string `json:"listen,omitempty"`
+	Match      string   `json:"match"` //exact, wildcard, regex, default_server, first
+}
+
+type ResolvedLocation struct {
+	File       string   `json:"file"`
+	Modifier   string   `json:"modifier,omitempty"` //=, ^~, ~, ~*
+	Path       string   `json:"path"`
+	Match      string   `json:"match"` //exact, prefix, regex
+	Directives []string `json:"directives,omitempty"`
+}
+
+type ResolvedMap struct {
+	File     string `json:"file"`
+	Variable string `json:"variable"`
+	Source   string `json:"source"`  //map的源变量计算后的值
+	Matched  string `json:"matched"` //匹配的条目，没有匹配时为default
+	Value    string `json:"value"`
+}
+
+//nginx处理请求时选择的server、location和map变量的值
+type Resolution struct {
+	Server   *ResolvedServer   `json:"server,omitempty"`
+	Location *ResolvedLocation `json:"location,omitempty"`
+	Maps     []*ResolvedMap    `json:"maps,omitempty"`
+	Explain  []string          `json:"explain"`
+}
+
+//指令和指令所在的文件
+type locatedDirective struct {
+	*Directive
+	file string
+}
+
+//展开include后的子指令
+func locatedChildren(directive *Directive, file string) []*locatedDirective {
+	children := make([]*locatedDirective, 0)
+	for _, body := range directive.Body {
+		if body.Virtual == Include {
+			if len(body.Args) > 0 {
+				children = append(children, locatedChildren(body, body.Args[0])...)
+			}
+		} else if body.Name == "include" {
+			for _, included := range body.Body {
+				if included.Virtual == Include && len(included.Args) > 0 {
+					children = append(children, locatedChildren(included, included.Args[0])...)
+				}
+			}
+		} else {
+			children = append(children, &locatedDirective{Directive: body, file: file})
+		}
+	}
+	return children
+}
+
+func findLocated(directive *locatedDirective, name string) []*locatedDirective {
+	found := make([]*locatedDirective, 0)
+	for _, child := range locatedChildren(directive.Directive, directive.file) {
+		if child.Name == name {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+//listen的端口和是否为默认server，没有listen时为80
+func listenOf(server *locatedDirective) (ports map[int]bool, defaults map[int]bool) {
+	ports, defaults = map[int]bool{}, map[int]bool{}
+	listens := findLocated(server, "listen")
+	if len(listens) == 0 {
+		ports[80] = true
+	}
+	for _, listen := range listens {
+		if len(listen.Args) == 0 {
+			continue
+		}
+		address, port := listen.Args[0], 80
+		if idx := strings.LastIndex(address, ":"); idx != -1 && !strings.HasSuffix(address, "]") {
+			address = address[idx+1:]
+		}
+		if number, err := strconv.Atoi(address); err == nil {
+			port = number
+		}
+		ports[port] = true
+		for _, arg := range listen.Args[1:] {
+			if arg == "default_server" || arg == "default" {
+				defaults[port] = true
+			}
+		}
+	}
+	return
+}
+
+type resolver struct {
+	http      *locatedDirective
+	request   *ResolveRequest
+	path      string
+	query     url.Values
+	server    *locatedDirective
+	maps      map[string]*locatedDirective
+	variables map[string]string
+	resolving map[string]bool
+	result    *Resolution
+}
+
+func (r *resolver) explain(format string, args ...interface{}) {
+	r.result.Explain = append(r.result.Explain, fmt.Sprintf(format, args...))
+}
+
+var variablePattern = regexp.MustCompile(`\$(\{\w+\}|\w+)`)
+
+//替换文本中的变量，captures为正则匹配的分组（$1..$9）
+func (r *resolver) expand(text string, captures []string) string {
+	return variablePattern.ReplaceAllStringFunc(text, func(variable string) string {
+		name := strings.Trim(variable[1:], "{}")
+		if index, err := strconv.Atoi(name); err == nil {
+			if index < len(captures) {
+				return captures[index]
+			}
+			return ""
+		}
+		return r.variable(name)
+	})
+}
+
+//nginx变量的值，map定义的变量计算map
+func (r *resolver) variable(name string) string {
+	if value, has := r.variables[name]; has {
+		return value
+	}
+	if mapDirective, has := r.maps[name]; has && !r.resolving[name] {
+		r.resolving[name] = true
+		value := r.evaluateMap(mapDirective)
+		r.variables[name] = value
+		return value
+	}
+	switch {
+	case strings.HasPrefix(name, "http_"):
+		header := strings.ReplaceAll(strings.TrimPrefix(name, "http_"), "_", "-")
+		for key, value := range r.request.Headers {
+			if strings.EqualFold(key, header) {
+				return value
+			}
+		}
+	case strings.HasPrefix(name, "arg_"):
+		return r.query.Get(strings.TrimPrefix(name, "arg_"))
+	case name == "server_name" && r.server != nil:
+		if names := findLocated(r.server, "server_name"); len(names) > 0 && len(names[0].Args) > 0 {
+			return names[0].Args[0]
+		}
+	}
+	return ""
+}
+
+func compileRegex(pattern string, insensitive bool) (*regexp.Regexp, error) {
+	if insensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+//map的匹配顺序：完全匹配，最长的前缀通配符，最长的后缀通配符（hostnames），第一个匹配的正则，default
+func (r *resolver) evaluateMap(mapDirective *locatedDirective) string {
+	variable := strings.TrimPrefix(mapDirective.Args[1], "$")
+	source := r.expand(unquote(mapDirective.Args[0]), nil)
+	resolved := &ResolvedMap{File: mapDirective.file, Variable: "$" + variable, Source: source, Matched: "default"}
+	r.result.Maps = append(r.result.Maps, resolved)
+
+	entries := locatedChildren(mapDirective.Directive, mapDirective.file)
+	hostnames := false
+	for _, entry := range entries {
+		hostnames = hostnames || entry.Name == "hostnames"
+	}
+	var defaultEntry, exact, leading, trailing, regex *locatedDirective
+	var captures []string
+	leadingLength, trailingLength := 0, 0
+	for _, entry := range entries {
+		if len(entry.Args) == 0 {
+			continue
+		}
+		key := unquote(entry.Name)
+		switch {
+		case key == "default":
+			defaultEntry = entry
+		case strings.HasPrefix(key, "~"):
+			if regex != nil {
+				continue
+			}
+			pattern, insensitive := key[1:], false
+			if strings.HasPrefix(pattern, "*") {
+				pattern, insensitive = pattern[1:], true
+			}
+			if compiled, err := compileRegex(pattern, insensitive); err != nil {
+				r.explain("map %s: can not check the regex %s: %s", resolved.Variable, key, err)
+			} else if captures = compiled.FindStringSubmatch(source); captures != nil {
+				regex = entry
+			}
+		case hostnames && (strings.HasPrefix(key, "*.") || strings.HasPrefix(key, ".")):
+			suffix := strings.ToLower(strings.TrimPrefix(key, "*"))
+			if key[0] == '.' && strings.EqualFold(source, key[1:]) {
+				if exact == nil {
+					exact = entry
+				}
+			} else if strings.HasSuffix(strings.ToLower(source), suffix) && len(suffix) > leadingLength {
+				leading, leadingLength = entry, len(suffix)
+			}
+		case hostnames && strings.HasSuffix(key, ".*"):
+			prefix := strings.ToLower(strings.TrimSuffix(key, "*"))
+			if strings.HasPrefix(strings.ToLower(source), prefix) && len(prefix) > trailingLength {
+				trailing, trailingLength = entry, len(prefix)
+			}
+		case key == source || (hostnames && strings.EqualFold(key, source)):
+			if exact == nil {
+				exact = entry
+			}
+		}
+	}
+
+	var value string
+	for _, candidate := range []*locatedDirective{exact, leading, trailing, regex} {
+		if candidate != nil {
+			resolved.Matched, value = unquote(candidate.Name), unquote(candidate.Args[0])
+			if candidate != regex {
+				captures = nil
+			}
+			break
+		}
+	}
+	if resolved.Matched == "default" {
+		captures = nil
+		if defaultEntry != nil {
+			value = unquote(defaultEntry.Args[0])
+		}
+	}
+	resolved.Value = r.expand(value, captures)
+	r.explain("map %s: %s matches %s, the value is %s", resolved.Variable,
+		strconv.Quote(source), resolved.Matched, strconv.Quote(resolved.Value))
+	return resolved.Value
+}
+
+//server的选择顺序：完全匹配，最长的前缀通配符，最长的后缀通配符，第一个匹配的正则，default_server，端口的第一个server
+func (r *resolver) selectServer(port int, host string) {
+	var first, defaultServer, exact, leading, trailing, regex *locatedDirective
+	leadingLength, trailingLength := 0, 0
+	for _, server := range findLocated(r.http, "server") {
+		ports, defaults := listenOf(server)
+		if !ports[port] {
+			continue
+		}
+		if first == nil {
+			first = server
+		}
+		if defaults[port] && defaultServer == nil {
+			defaultServer = server
+		}
+		for _, serverName := range findLocated(server, "server_name") {
+			for _, name := range serverName.Args {
+				name = strings.ToLower(unquote(name))
+				switch {
+				case strings.HasPrefix(name, "~"):
+					if regex == nil {
+						if compiled, err := compileRegex(name[1:], false); err != nil {
+							r.explain("server_name %s: can not check the regex: %s", name, err)
+						} else if compiled.MatchString(host) {
+							regex = server
+						}
+					}
+				case strings.HasPrefix(name, "*.") || strings.HasPrefix(name, "."):
+					suffix := strings.TrimPrefix(name, "*")
+					if name[0] == '.' && host == name[1:] && exact == nil {
+						exact = server
+					} else if strings.HasSuffix(host, suffix) && len(suffix) > leadingLength {
+						leading, leadingLength = server, len(suffix)
+					}
+				case strings.HasSuffix(name, ".*"):
+					prefix := strings.TrimSuffix(name, "*")
+					if strings.HasPrefix(host, prefix) && len(prefix) > trailingLength {
+						trailing, trailingLength = server, len(prefix)
+					}
+				case name == host && exact == nil:
+					exact = server
+				}
+			}
+		}
+	}
+
+	for _, candidate := range []struct {
+		server *locatedDirective
+		match  string
+	}{{exact, "exact"}, {leading, "wildcard"}, {trailing, "wildcard"}, {regex, "regex"},
+		{defaultServer, "default_server"}, {first, "first"}} {
+		if candidate.server != nil {
+			r.server = candidate.server
+			r.result.Server = &ResolvedServer{
+				File: candidate.server.file, Match: candidate.match,
+				ServerName: argsOf(candidate.server, "server_name"), Listen: argsOf(candidate.server, "listen"),
+			}
+			break
+		}
+	}
+	switch {
+	case r.server == nil:
+		r.explain("no server listens on port %d", port)
+	case r.result.Server.Match == "default_server":
+		r.explain("no server_name matches host %s, use the default_server of port %d in %s", host, port, r.server.file)
+	case r.result.Server.Match == "first":
+		r.explain("no server_name matches host %s and no default_server, use the first server of port %d in %s", host, port, r.server.file)
+	default:
+		r.explain("host %s matches server_name %s (%s) in %s", host,
+			strings.Join(r.result.Server.ServerName, " "), r.result.Server.Match, r.server.file)
+	}
+}
+
+func argsOf(directive *locatedDirective, name string) (args []string) {
+	for _, child := range findLocated(directive, name) {
+		args = append(args, strings.Join(child.Args, " "))
+	}
+	return
+}
+
+func locationOf(location *locatedDirective) (modifier, path string) {
+	switch len(location.Args) {
+	case 0:
+		return "", ""
+	case 1:
+		path = location.Args[0]
+		if strings.HasPrefix(path, "=") && len(path) > 1 {
+			return "=", path[1:]
+		}
+		return "", path
+	default:
+		return location.Args[0], unquote(location.Args[1])
+	}
+}
+
+//location的选择顺序：完全匹配(=)，最长的前缀匹配（^~ 时不再检查正则），第一个匹配的正则(~, ~*)，最长的前缀匹配
+//最长的前缀匹配中嵌套的location优先检查
+func (r *resolver) selectLocation(parent *locatedDirective) (*locatedDirective, string) {
+	var longest *locatedDirective
+	regexes := make([]*locatedDirective, 0)
+	for _, location := range findLocated(parent, "location") {
+		modifier, path := locationOf(location)
+		switch modifier {
+		case "=":
+			if path == r.path {
+				return location, "exact"
+			}
+		case "~", "~*":
+			regexes = append(regexes, location)
+		case "", "^~":
+			if strings.HasPrefix(path, "@") {
+				continue
+			}
+			if strings.HasPrefix(r.path, path) {
+				if longest == nil {
+					longest = location
+				} else if _, longestPath := locationOf(longest); len(path) > len(longestPath) {
+					longest = location
+				}
+			}
+		}
+	}
+	if longest != nil {
+		if nested, match := r.selectLocation(longest); nested != nil {
+			return nested, match
+		}
+		if modifier, _ := locationOf(longest); modifier == "^~" {
+			return longest, "prefix"
+		}
+	}
+	for _, location := range regexes {
+		modifier, path := locationOf(location)
+		regex, err := compileRegex(path, modifier == "~*")
+		if err != nil {
+			r.explain("location %s %s: can not check the regex: %s", modifier, path, err)
+			continue
+		}
+		if regex.MatchString(r.path) {
+			return location, "regex"
+		}
+	}
+	if longest != nil {
+		return longest, "prefix"
+	}
+	return nil, ""
+}
+
+//模拟nginx处理请求：选择server和location，计算http中的map变量
+func Resolve(conf *Configuration, request *ResolveRequest) (*Resolution, error) {
+	uri, err := url.ParseRequestURI(request.Uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uri %s: %s", request.Uri, err)
+	}
+	port, host := request.Port, strings.ToLower(request.Host)
+	if idx := strings.LastIndex(host, ":"); idx != -1 && !strings.HasSuffix(host, "]") {
+		if port == 0 {
+			port, _ = strconv.Atoi(host[idx+1:])
+		}
+		host = host[:idx]
+	}
+	if request.Scheme == "" {
+		request.Scheme = "http"
+	}
+	if port == 0 {
+		port = 80
+		if request.Scheme == "https" {
+			port = 443
+		}
+	}
+	if request.Method == "" {
+		request.Method = "GET"
+	}
+
+	root := &locatedDirective{Directive: conf, file: conf.Name}
+	if root.file == "" {
+		root.file = NGINX_CONF
+	}
+	https := findLocated(root, "http")
+	if len(https) == 0 {
+		return nil, fmt.Errorf("http not found in the configuration")
+	}
+	r := &resolver{
+		http: https[0], request: request, path: uri.Path, query: uri.Query(),
+		maps: map[string]*locatedDirective{}, resolving: map[string]bool{},
+		result: &Resolution{Explain: make([]string, 0)},
+		variables: map[string]string{
+			"host": host, "uri": uri.Path, "document_uri": uri.Path, "request_uri": request.Uri,
+			"args": uri.RawQuery, "query_string": uri.RawQuery, "is_args": "",
+			"scheme": request.Scheme, "https": "", "request_method": request.Method,
+			"remote_addr": request.Remote, "server_port": strconv.Itoa(port),
+		},
+	}
+	if uri.RawQuery != "" {
+		r.variables["is_args"] = "?"
+	}
+	if request.Scheme == "https" {
+		r.variables["https"] = "on"
+	}
+	for _, mapDirective := range findLocated(r.http, "map") {
+		if len(mapDirective.Args) == 2 {
+			r.maps[strings.TrimPrefix(mapDirective.Args[1], "$")] = mapDirective
+		}
+	}
+
+	r.selectServer(port, host)
+	if r.server != nil {
+		if location, match := r.selectLocation(r.server); location == nil {
+			r.explain("no location matches uri %s", r.path)
+		} else {
+			modifier, path := locationOf(location)
+			r.result.Location = &ResolvedLocation{File: location.file, Modifier: modifier, Path: path, Match: match}
+			for _, child := range locatedChildren(location.Directive, location.file) {
+				if child.Name != "location" {
+					r.result.Location.Directives = append(r.result.Location.Directives, strings.TrimSpace(child.Pretty(0)))
+				}
+			}
+			r.explain("uri %s matches location %s (%s) in %s", r.path,
+				strings.TrimSpace(modifier+" "+path), match, location.file)
+		}
+	}
+
+	//计算全部map变量，按照变量名排序
+	names := make([]string, 0, len(r.maps))
+	for name := range r.maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r.variable(name)
+	}
+	sort.SliceStable(r.result.Maps, func(i, j int) bool {
+		return r.result.Maps[i].Variable < r.result.Maps[j].Variable
+	})
+	return r.result, nil
+}
@@ -0,0 +1,72 @@
+package nginx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"sort"
+	"sync"
+)
+
+var ErrConflict = plugins.ErrConflict
+
+//检查版本和保存文件之间不能有其他的保存
+var storeLock sync.Mutex
+
+//文件的版本，使用内容的sha256，不存在的文件版本为空
+func FileRevision(content []byte) string {
+	return plugins.Revision(content)
+}
+
+//配置的版本，读取时全部文件的版本，任意一个文件修改后版本都会变化
+func (client Client) Revision() string {
+	files := make([]string, 0, len(client.loaded))
+	for file := range client.loaded {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	hash := sha256.New()
+	for _, file := range files {
+		sum := client.loaded[file]
+		_, _ = hash.Write([]byte(file))
+		_, _ = hash.Write(sum[:])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+//读取时文件的版本，没有读取过的文件（新增的文件）版本为空
+func (client Client) revision(file string) string {
+	if sum, has := client.loaded[file]; has {
+		return hex.EncodeToString(sum[:])
+	}
+	return ""
+}
+
+//读取后存储中的文件是否被修改过，读取时不存在的文件被其他请求创建了也是修改
+func (client Client) modified(file string) bool {
+	cfgFile, err := client.Engine.Get(file)
+	if os.IsNotExist(err) {
+		return client.revision(file) != ""
+	}
+	return err != nil || client.revision(file) != FileRevision(cfgFile.Content)
+}
+
+//matched检查存储中文件当前的版本，通过后使用参数中的存储执行fn（保存或者删除文件），否则返回ErrConflict。
+//存储支持比较后写入时，fn修改文件时再次检查版本。matched为空时不检查版本
+func IfMatch(engine plugins.StorageEngine, file string, matched func(revision string) bool,
+	fn func(engine plugins.StorageEngine) error) error {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	if matched == nil {
+		return fn(engine)
+	}
+	revision := ""
+	if cfgFile, err := engine.Get(file); err == nil {
+		revision = FileRevision(cfgFile.Content)
+	}
+	if !matched(revision) {
+		return ErrConflict
+	}
+	return fn(plugins.WithContext(plugins.Expect(plugins.Context(engine), file, revision), engine))
+}
@@ -0,0 +1,325 @@
+package nginx
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//沙箱中的探测请求
+type Probe struct {
+	Method  string            `json:"method,omitempty"` //默认GET
+	Host    string            `json:"host,omitempty"`   //Host请求头
+	Port    int               `json:"port,omitempty"`   //配置中listen的端口，默认80
+	Uri     string            `json:"uri,omitempty"`    //默认 /
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Expect  int               `json:"expect,omitempty"` //期望的状态码，为0时状态码小于500为通过
+}
+
+type ProbeResult struct {
+	*Probe
+	Status   int    `json:"status"`
+	Location string `json:"location,omitempty"` //跳转的地址
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+}
+
+type SandboxResult struct {
+	Success bool           `json:"success"` //nginx启动成功并且全部探测请求通过
+	Output  string         `json:"output"`  //nginx -t 的输出和沙箱nginx的错误日志
+	Probes  []*ProbeResult `json:"probes"`
+}
+
+//沙箱nginx的启动超时时间
+var SandboxStartTimeout = time.Second * 5
+
+func randomPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = listener.Close() }()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+//沙箱中监听的地址
+type sandboxListen struct {
+	port int
+	ssl  bool
+	tcp  bool //udp和quic不等待监听
+}
+
+//listen地址的端口，unix socket返回地址本身
+func listenKey(address string) string {
+	if strings.HasPrefix(address, "unix:") {
+		return address
+	}
+	if idx := strings.LastIndex(address, ":"); idx != -1 && !strings.HasSuffix(address, "]") {
+		address = address[idx+1:]
+	}
+	if _, err := strconv.Atoi(address); err != nil {
+		return "80"
+	}
+	return address
+}
+
+//server中是否有listen，包括server中include的文件
+func hasListen(directive *Directive) bool {
+	for _, child := range directive.Body {
+		if child.Name == "listen" {
+			return true
+		} else if (child.Name == "include" || child.Virtual == Include) && hasListen(child) {
+			return true
+		}
+	}
+	return false
+}
+
+//http中没有listen的server，nginx默认监听80端口，沙箱中添加 listen 80 后和其他listen一样替换为随机端口
+func defaultListen(directive *Directive) {
+	for _, child := range directive.Body {
+		if child.Name == "server" {
+			if !hasListen(child) {
+				child.Body = append([]*Directive{NewDirective("listen", "80")}, child.Body...)
+			}
+		} else if child.Name == "include" || child.Virtual == Include {
+			defaultListen(child)
+		}
+	}
+}
+
+//修改配置在沙箱中运行：listen使用127.0.0.1的随机端口，日志和pid写入沙箱目录
+func sandboxDirectives(directive *Directive, listens map[string]*sandboxListen) error {
+	body := make([]*Directive, 0, len(directive.Body))
+	listened := map[string]bool{}
+	for _, child := range directive.Body {
+		switch child.Name {
+		case "daemon":
+			continue
+		case "user":
+			if os.Geteuid() != 0 {
+				continue
+			}
+		case "pid":
+			child.Args = []string{"logs/nginx.pid"}
+		case "error_log":
+			child.Args = []string{"logs/error.log"}
+		case "access_log":
+			child.Args = []string{"off"}
+		case "http":
+			defaultListen(child)
+		case "listen":
+			if len(child.Args) == 0 {
+				break
+			}
+			key := listenKey(child.Args[0])
+			listen, has := listens[key]
+			if !has {
+				port, err := randomPort()
+				if err != nil {
+					return err
+				}
+				listen = &sandboxListen{port: port}
+				listens[key] = listen
+			}
+			args := []string{"127.0.0.1:" + strconv.Itoa(listen.port)}
+			for _, arg := range child.Args[1:] {
+				if strings.HasPrefix(arg, "ipv6only") {
+					continue
+				}
+				listen.ssl = listen.ssl || arg == "ssl"
+				args = append(args, arg)
+			}
+			listen.tcp = listen.tcp || (!contains(child.Args, "udp") && !contains(child.Args, "quic"))
+			//ipv4和ipv6监听相同端口时合并为一个
+			if listened[args[0]] {
+				continue
+			}
+			listened[args[0]] = true
+			child.Args = args
+		}
+		if err := sandboxDirectives(child, listens); err != nil {
+			return err
+		}
+		body = append(body, child)
+	}
+	directive.Body = body
+	return nil
+}
+
+func sandboxConfiguration(cfg *Configuration) (*Configuration, map[string]*sandboxListen, error) {
+	sandbox := cfg.Clone()
+	body := []*Directive{NewDirective("pid", "logs/nginx.pid"), NewDirective("error_log", "logs/error.log")}
+	for _, directive := range sandbox.Body {
+		if directive.Name != "pid" && directive.Name != "error_log" {
+			body = append(body, directive)
+		}
+	}
+	sandbox.Body = body
+	listens := map[string]*sandboxListen{}
+	if err := sandboxDirectives(sandbox, listens); err != nil {
+		return nil, nil, err
+	}
+	return sandbox, listens, nil
+}
+
+//沙箱中运行的配置，返回配置中listen的端口（unix socket为地址）对应的沙箱端口
+func SandboxConfiguration(cfg *Configuration) (*Configuration, map[string]int, error) {
+	sandbox, listens, err := sandboxConfiguration(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	ports := make(map[string]int, len(listens))
+	for key, listen := range listens {
+		ports[key] = listen.port
+	}
+	return sandbox, ports, nil
+}
+
+func (probe *Probe) run(listens map[string]*sandboxListen) (result *ProbeResult) {
+	result = &ProbeResult{Probe: probe}
+	if probe.Method == "" {
+		probe.Method = http.MethodGet
+	}
+	if probe.Port == 0 {
+		probe.Port = 80
+	}
+	if probe.Uri == "" {
+		probe.Uri = "/"
+	}
+	listen, has := listens[strconv.Itoa(probe.Port)]
+	if !has {
+		result.Error = fmt.Sprintf("no server listens on port %d", probe.Port)
+		return
+	}
+	scheme := "http"
+	if listen.ssl {
+		scheme = "https"
+	}
+	req, err := http.NewRequest(probe.Method, fmt.Sprintf("%s://127.0.0.1:%d%s", scheme, listen.port, probe.Uri),
+		strings.NewReader(probe.Body))
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	for name, value := range probe.Headers {
+		req.Header.Set(name, value)
+	}
+	if probe.Host != "" {
+		req.Host = probe.Host
+	}
+	serverName, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		serverName = req.Host
+	}
+	client := &http.Client{
+		Timeout: time.Second * 10,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true, ServerName: serverName}},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	_ = resp.Body.Close()
+	result.Status, result.Location = resp.StatusCode, resp.Header.Get("Location")
+	if probe.Expect != 0 {
+		result.Passed = result.Status == probe.Expect
+	} else {
+		result.Passed = result.Status < 500
+	}
+	return
+}
+
+//等待沙箱nginx监听全部端口，nginx退出时返回错误
+func waitListen(exited <-chan struct{}, listens map[string]*sandboxListen) error {
+	deadline := time.Now().Add(SandboxStartTimeout)
+	for _, listen := range listens {
+		for listen.tcp {
+			select {
+			case <-exited:
+				return errors.New("the sandbox NGINX exited")
+			default:
+			}
+			conn, err := net.DialTimeout("tcp", "127.0.0.1:"+strconv.Itoa(listen.port), time.Millisecond*100)
+			if err == nil {
+				_ = conn.Close()
+				break
+			}
+			if time.Now().After(deadline) {
+				return errors.New("timeout waiting for the sandbox NGINX")
+			}
+			time.Sleep(time.Millisecond * 100)
+		}
+	}
+	return nil
+}
+
+//在临时目录中使用随机端口启动nginx运行配置，发送探测请求并返回状态码，不会影响正在使用的配置
+func (sp *Process) Sandbox(cfg *Configuration, probes []*Probe) (result *SandboxResult, err error) {
+	defer util.Catch(func(e error) {
+		err = e
+	})
+	util.AssertTrue(!InDocker(), "the sandbox is not supported when NGINX runs in docker")
+	result = &SandboxResult{Probes: make([]*ProbeResult, 0)}
+	if result.Output, err = sp.Validate(cfg); err != nil {
+		return result, nil
+	}
+
+	sandboxDir, err := ioutil.TempDir("", "aginx-sandbox")
+	util.PanicIfError(err)
+	defer func() { _ = os.RemoveAll(sandboxDir) }()
+	util.PanicIfError(util.CopyDir(MustConfigDir(), sandboxDir))
+	//nginx的worker进程需要访问沙箱目录
+	util.PanicIfError(os.Chmod(sandboxDir, 0755))
+	util.PanicIfError(os.MkdirAll(filepath.Join(sandboxDir, "logs"), 0755))
+
+	sandbox, listens, err := sandboxConfiguration(cfg)
+	util.PanicIfError(err)
+	util.PanicIfError(WriteTo(sandboxDir, sandbox))
+
+	sandboxArgs := []string{"-p", sandboxDir + "/", "-c", filepath.Join(sandboxDir, NGINX_CONF)}
+	cmd := nginxCommand(append(sandboxArgs, "-g", "daemon off;")...)
+	util.PanicIfError(cmd.Start())
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	defer func() {
+		_ = quitProcess(cmd.Process, sandboxArgs...)
+		select {
+		case <-exited:
+		case <-time.After(time.Second * 5):
+			_ = cmd.Process.Kill()
+		}
+		if errorLog, err := ioutil.ReadFile(filepath.Join(sandboxDir, "logs", "error.log")); err == nil {
+			result.Output = strings.TrimSpace(result.Output + "\n" + string(errorLog))
+		}
+	}()
+
+	if err := waitListen(exited, listens); err != nil {
+		result.Output = err.Error()
+		return result, nil
+	}
+	result.Success = true
+	for _, probe := range probes {
+		probeResult := probe.run(listens)
+		result.Success = result.Success && probeResult.Passed
+		result.Probes = append(result.Probes, probeResult)
+	}
+	return result, nil
+}
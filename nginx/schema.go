@@ -0,0 +1,238 @@
+package nginx
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	StrictOff    = "off"
+	StrictWarn   = "warn"
+	StrictReject = "reject"
+)
+
+const LintUnknownDirective = "unknown-directive"
+
+//核心模块和常用模块的指令名称，有文档的指令（directiveDocs）同时检查context
+var knownDirectives = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		daemon debug_points env error_log events include load_module lock_file master_process pcre_jit pid
+		ssl_engine thread_pool timer_resolution user worker_cpu_affinity worker_priority worker_processes
+		worker_rlimit_core worker_rlimit_nofile worker_shutdown_timeout working_directory
+		accept_mutex accept_mutex_delay debug_connection multi_accept use worker_aio_requests worker_connections
+
+		http server location listen server_name root alias index try_files error_page internal limit_except
+		absolute_redirect aio aio_write chunked_transfer_encoding client_body_buffer_size client_body_in_file_only
+		client_body_in_single_buffer client_body_temp_path client_body_timeout client_header_buffer_size
+		client_header_timeout client_max_body_size connection_pool_size default_type directio directio_alignment
+		disable_symlinks etag if_modified_since ignore_invalid_headers keepalive_disable keepalive_requests
+		keepalive_time keepalive_timeout large_client_header_buffers limit_rate limit_rate_after lingering_close
+		lingering_time lingering_timeout log_not_found log_subrequest max_ranges merge_slashes msie_padding
+		msie_refresh open_file_cache open_file_cache_errors open_file_cache_min_uses open_file_cache_valid
+		output_buffers port_in_redirect postpone_output read_ahead recursive_error_pages request_pool_size
+		reset_timedout_connection resolver resolver_timeout satisfy send_lowat send_timeout sendfile
+		sendfile_max_chunk server_name_in_redirect server_names_hash_bucket_size server_names_hash_max_size
+		server_tokens subrequest_output_buffer_size tcp_nodelay tcp_nopush types types_hash_bucket_size
+		types_hash_max_size underscores_in_headers variables_hash_bucket_size variables_hash_max_size
+		http2 http2_body_preread_size http2_chunk_size http2_idle_timeout http2_max_concurrent_streams
+		http2_max_field_size http2_max_header_size http2_max_requests http2_push http2_push_preload
+		http2_recv_buffer_size http2_recv_timeout http3 http3_hq http3_max_concurrent_streams
+		http3_stream_buffer_size quic_active_connection_id_limit quic_bpf quic_gso quic_host_key quic_retry
+
+		access_log log_format open_log_file_cache allow deny auth_basic auth_basic_user_file
+		auth_request auth_request_set autoindex autoindex_exact_size autoindex_format autoindex_localtime
+		charset charset_map charset_types override_charset source_charset
+		add_header add_trailer expires empty_gif
+		break if return rewrite rewrite_log set uninitialized_variable_warn
+		gzip gzip_buffers gzip_comp_level gzip_disable gzip_http_version gzip_min_length gzip_proxied
+		gzip_types gzip_vary gzip_static gunzip gunzip_buffers
+		geo geoip_city geoip_country geoip_org geoip_proxy geoip_proxy_recursive geoip2
+		limit_conn limit_conn_dry_run limit_conn_log_level limit_conn_status limit_conn_zone
+		limit_req limit_req_dry_run limit_req_log_level limit_req_status limit_req_zone
+		map map_hash_bucket_size map_hash_max_size split_clients
+		real_ip_header real_ip_recursive set_real_ip_from
+		referer_hash_bucket_size referer_hash_max_size valid_referers
+		secure_link secure_link_md5 secure_link_secret
+		ssi ssi_last_modified ssi_min_file_chunk ssi_silent_errors ssi_types ssi_value_length
+		stub_status sub_filter sub_filter_last_modified sub_filter_once sub_filter_types
+		userid userid_domain userid_expires userid_flags userid_mark userid_name userid_p3p userid_path userid_service
+		mirror mirror_request_body random_index slice addition_types add_after_body add_before_body
+		dav_access dav_methods create_full_put_path min_delete_depth mp4 mp4_buffer_size mp4_max_buffer_size flv
+		image_filter image_filter_buffer image_filter_interlace image_filter_jpeg_quality image_filter_sharpen
+		image_filter_transparency image_filter_webp_quality xslt_stylesheet xslt_types xml_entities
+		perl perl_modules perl_require perl_set
+
+		upstream hash ip_hash keepalive keepalive_requests keepalive_time keepalive_timeout least_conn
+		least_time random zone sticky ntlm queue resolve
+
+		ssl_buffer_size ssl_certificate ssl_certificate_key ssl_ciphers ssl_client_certificate ssl_conf_command
+		ssl_crl ssl_dhparam ssl_early_data ssl_ecdh_curve ssl_password_file ssl_prefer_server_ciphers
+		ssl_protocols ssl_reject_handshake ssl_session_cache ssl_session_ticket_key ssl_session_tickets
+		ssl_session_timeout ssl_stapling ssl_stapling_file ssl_stapling_responder ssl_stapling_verify
+		ssl_trusted_certificate ssl_verify_client ssl_verify_depth ssl ssl_handshake_timeout ssl_preread
+
+		proxy_bind proxy_buffer_size proxy_buffering proxy_buffers proxy_busy_buffers_size proxy_cache
+		proxy_cache_background_update proxy_cache_bypass proxy_cache_convert_head proxy_cache_key
+		proxy_cache_lock proxy_cache_lock_age proxy_cache_lock_timeout proxy_cache_max_range_offset
+		proxy_cache_methods proxy_cache_min_uses proxy_cache_path proxy_cache_purge proxy_cache_revalidate
+		proxy_cache_use_stale proxy_cache_valid proxy_connect_timeout proxy_cookie_domain proxy_cookie_flags
+		proxy_cookie_path proxy_force_ranges proxy_headers_hash_bucket_size proxy_headers_hash_max_size
+		proxy_hide_header proxy_http_version proxy_ignore_client_abort proxy_ignore_headers
+		proxy_intercept_errors proxy_limit_rate proxy_max_temp_file_size proxy_method proxy_next_upstream
+		proxy_next_upstream_timeout proxy_next_upstream_tries proxy_no_cache proxy_pass proxy_pass_header
+		proxy_pass_request_body proxy_pass_request_headers proxy_read_timeout proxy_redirect proxy_request_buffering
+		proxy_send_lowat proxy_send_timeout proxy_set_body proxy_set_header proxy_socket_keepalive
+		proxy_ssl_certificate proxy_ssl_certificate_key proxy_ssl_ciphers proxy_ssl_conf_command proxy_ssl_crl
+		proxy_ssl_name proxy_ssl_password_file proxy_ssl_protocols proxy_ssl_server_name proxy_ssl_session_reuse
+		proxy_ssl_trusted_certificate proxy_ssl_verify proxy_ssl_verify_depth proxy_store proxy_store_access
+		proxy_temp_file_write_size proxy_temp_path proxy_timeout proxy_connect_timeout proxy_protocol
+		proxy_download_rate proxy_upload_rate proxy_responses proxy_requests proxy_half_close proxy_ssl
+		fastcgi_buffer_size fastcgi_buffering fastcgi_buffers fastcgi_cache fastcgi_cache_key fastcgi_cache_path
+		fastcgi_cache_valid fastcgi_connect_timeout fastcgi_hide_header fastcgi_index fastcgi_intercept_errors
+		fastcgi_keep_conn fastcgi_next_upstream fastcgi_param fastcgi_pass fastcgi_pass_header fastcgi_read_timeout
+		fastcgi_send_timeout fastcgi_split_path_info fastcgi_temp_path
+		grpc_buffer_size grpc_connect_timeout grpc_hide_header grpc_intercept_errors grpc_next_upstream grpc_pass
+		grpc_pass_header grpc_read_timeout grpc_send_timeout grpc_set_header grpc_socket_keepalive grpc_ssl_certificate
+		grpc_ssl_certificate_key grpc_ssl_name grpc_ssl_server_name grpc_ssl_trusted_certificate grpc_ssl_verify
+		uwsgi_pass uwsgi_param uwsgi_read_timeout uwsgi_send_timeout uwsgi_buffers uwsgi_buffer_size
+		scgi_pass scgi_param scgi_read_timeout scgi_send_timeout
+		memcached_pass memcached_connect_timeout memcached_read_timeout memcached_send_timeout
+
+		stream preread_buffer_size preread_timeout proxy_protocol_timeout tcp_nodelay variables_hash_max_size
+		return set js_import js_path js_set js_content js_access js_filter js_header_filter js_body_filter
+
+		modsecurity modsecurity_rules modsecurity_rules_file modsecurity_rules_remote modsecurity_transaction_id
+		more_set_headers more_clear_headers more_set_input_headers more_clear_input_headers
+		vhost_traffic_status vhost_traffic_status_zone vhost_traffic_status_display
+		vhost_traffic_status_display_format health_check match status_zone api
+		mail smtp_auth pop3_auth imap_auth protocol auth_http starttls
+	`) {
+		knownDirectives[name] = true
+	}
+}
+
+//添加第三方模块的指令名称，严格模式中不会作为未知指令
+func AllowDirectives(names ...string) {
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			knownDirectives[name] = true
+		}
+	}
+}
+
+func IsKnownDirective(name string) bool {
+	return knownDirectives[name] || directiveDocs[name] != nil
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if current[j] > previous[j]+1 {
+				current[j] = previous[j] + 1
+			}
+			if current[j] > current[j-1]+1 {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+//编辑距离不超过2的已知指令，例如：porxy_pass -> proxy_pass
+func similarDirective(name string) string {
+	similar, distance := "", 3
+	for known := range knownDirectives {
+		if d := editDistance(name, known); d < distance || (d == distance && known < similar) {
+			similar, distance = known, d
+		}
+	}
+	return similar
+}
+
+//检查指令名称和使用的context，返回违反的规则和说明，stream中只检查名称
+func schemaCheck(name, context string) (rule, message string) {
+	if !IsKnownDirective(name) {
+		message = fmt.Sprintf("unknown directive %s in %s", name, context)
+		if similar := similarDirective(name); similar != "" {
+			message += ", did you mean " + similar + "?"
+		}
+		return LintUnknownDirective, message
+	}
+	if strings.HasPrefix(context, "stream") {
+		return "", ""
+	}
+	if doc := GetDirectiveDoc(name); doc != nil && !doc.Allowed(context) {
+		return LintInvalidContext, fmt.Sprintf("%s is not allowed in %s, allowed in: %s",
+			name, context, strings.Join(doc.Contexts, ", "))
+	}
+	return "", ""
+}
+
+//块指令中子指令的context，内容不是指令的块（map, types, geo等）和mail返回false
+func schemaContext(name, context string) (string, bool) {
+	switch {
+	case name == "stream":
+		return "stream", true
+	case strings.HasPrefix(context, "stream"):
+		if name == "server" || name == "upstream" {
+			return "stream " + name, true
+		}
+	case name == "events" || name == "http" || name == "location" || name == "upstream" || name == "limit_except":
+		return name, true
+	case name == "server" && context == "http":
+		return "server", true
+	case name == "if" && (context == "server" || context == "location"):
+		return "if in " + context, true
+	}
+	return "", false
+}
+
+//展开include后的子指令
+func schemaChildren(directive *Directive) []*Directive {
+	children := make([]*Directive, 0)
+	for _, body := range directive.Body {
+		if body.Virtual == Include {
+			children = append(children, schemaChildren(body)...)
+		} else if body.Name == "include" {
+			for _, file := range body.Body {
+				children = append(children, schemaChildren(file)...)
+			}
+		} else {
+			children = append(children, body)
+		}
+	}
+	return children
+}
+
+func checkSchema(directive *Directive, context string, problems *[]string) {
+	for _, child := range schemaChildren(directive) {
+		if _, message := schemaCheck(child.Name, context); message != "" {
+			*problems = append(*problems, message)
+			continue
+		}
+		if childContext, ok := schemaContext(child.Name, context); ok {
+			checkSchema(child, childContext, problems)
+		}
+	}
+}
+
+//使用内置的指令列表检查未知指令和指令使用的context，context为指令所在的位置，例如：main, http
+func CheckSchema(context string, directives ...*Directive) []string {
+	problems := make([]string, 0)
+	checkSchema(&Directive{Body: directives}, context, &problems)
+	return problems
+}
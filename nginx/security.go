@@ -0,0 +1,259 @@
+package nginx
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+const (
+	SecurityStrict   = "strict"
+	SecurityModerate = "moderate"
+
+	hsts = "Strict-Transport-Security"
+)
+
+//安全响应头预设
+var SecurityProfiles = map[string]map[string]string{
+	SecurityStrict: {
+		hsts:                      "max-age=63072000; includeSubDomains; preload",
+		"X-Frame-Options":         "DENY",
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "default-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+		"Referrer-Policy":         "no-referrer",
+		"Permissions-Policy":      "camera=(), microphone=(), geolocation=()",
+	},
+	SecurityModerate: {
+		hsts:                      "max-age=31536000",
+		"X-Frame-Options":         "SAMEORIGIN",
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "frame-ancestors 'self'",
+		"Referrer-Policy":         "strict-origin-when-cross-origin",
+	},
+}
+
+//aginx管理的安全响应头，设置时先删除这些响应头
+var SecurityHeaderNames = []string{
+	hsts, "X-Frame-Options", "X-Content-Type-Options", "Content-Security-Policy",
+	"Referrer-Policy", "Permissions-Policy", "X-XSS-Protection",
+	"Cross-Origin-Opener-Policy", "Cross-Origin-Resource-Policy", "Cross-Origin-Embedder-Policy",
+}
+
+//安全响应头，Domain为空时作用于全部http
+type SecurityHeaders struct {
+	Domain  string            `json:"domain,omitempty"`
+	Profile string            `json:"profile,omitempty"` //预设：strict, moderate
+	Headers map[string]string `json:"headers,omitempty"` //覆盖预设中的响应头，值为空时不添加此响应头
+}
+
+func securityHeaderName(name string) (string, bool) {
+	for _, header := range SecurityHeaderNames {
+		if strings.EqualFold(header, name) {
+			return header, true
+		}
+	}
+	return name, false
+}
+
+func (s *SecurityHeaders) Validate() error {
+	if _, has := SecurityProfiles[s.Profile]; s.Profile != "" && !has {
+		return errors.New("invalid security profile: " + s.Profile + ", support: strict, moderate")
+	}
+	for name, value := range s.Headers {
+		if _, has := securityHeaderName(name); !has {
+			return errors.New("unsupported security header: " + name)
+		}
+		if strings.ContainsAny(value, "\"\n") {
+			return errors.New("invalid value of header " + name)
+		}
+	}
+	if len(s.Values()) == 0 {
+		return errors.New("the security headers is empty")
+	}
+	return nil
+}
+
+//预设和覆盖合并后的响应头
+func (s *SecurityHeaders) Values() map[string]string {
+	values := make(map[string]string)
+	for name, value := range SecurityProfiles[s.Profile] {
+		values[name] = value
+	}
+	for name, value := range s.Headers {
+		name, _ = securityHeaderName(name)
+		if value == "" {
+			delete(values, name)
+		} else {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+//add_header指令，HSTS只添加在开启ssl的server中
+func securityDirectives(values map[string]string, ssl bool) []*Directive {
+	directives := make([]*Directive, 0, len(values))
+	for _, name := range SecurityHeaderNames {
+		if value, has := values[name]; has && (ssl || name != hsts) {
+			directives = append(directives, NewDirective("add_header", name, `"`+value+`"`, "always"))
+		}
+	}
+	return directives
+}
+
+func isSecurityHeader(directive *Directive) bool {
+	if directive.Name != "add_header" || len(directive.Args) < 2 {
+		return false
+	}
+	_, has := securityHeaderName(directive.Args[0])
+	return has
+}
+
+//和预设完全相同时返回预设名称
+func matchSecurityProfile(values map[string]string, ssl bool) string {
+	names := make([]string, 0, len(SecurityProfiles))
+	for name := range SecurityProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profile := SecurityProfiles[name]
+		count := len(profile)
+		if _, has := profile[hsts]; has && !ssl {
+			count--
+		}
+		if count != len(values) {
+			continue
+		}
+		matched := true
+		for header, value := range values {
+			if profile[header] != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return name
+		}
+	}
+	return ""
+}
+
+func unquote(value string) string {
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func parseSecurityHeaders(directive *Directive) map[string]string {
+	values := make(map[string]string)
+	for _, body := range directive.Body {
+		if isSecurityHeader(body) {
+			name, _ := securityHeaderName(body.Args[0])
+			values[name] = unquote(body.Args[1])
+		}
+	}
+	return values
+}
+
+func isSSLServer(directive *Directive) bool {
+	for _, body := range directive.Body {
+		if body.Name == "listen" && contains(body.Args, "ssl") {
+			return true
+		}
+	}
+	return false
+}
+
+//http和server中设置的安全响应头
+func (client *Client) SecurityHeaders() []*SecurityHeaders {
+	headers := make([]*SecurityHeaders, 0)
+	for _, target := range client.scopes() {
+		if target.location != "" {
+			continue
+		}
+		values := parseSecurityHeaders(target.directive)
+		if len(values) == 0 {
+			continue
+		}
+		ssl := target.directive.Name == "http" || isSSLServer(target.directive)
+		headers = append(headers, &SecurityHeaders{
+			Domain: target.domain(), Profile: matchSecurityProfile(values, ssl), Headers: values,
+		})
+	}
+	return headers
+}
+
+func removeSecurityHeaders(directive *Directive) bool {
+	removed := false
+	body := make([]*Directive, 0, len(directive.Body))
+	for _, d := range directive.Body {
+		if isSecurityHeader(d) {
+			removed = true
+			continue
+		}
+		body = append(body, d)
+	}
+	directive.Body = body
+	return removed
+}
+
+//设置安全响应头，替换原来的安全响应头。
+//注意：location中使用了add_header时不会继承http和server中的add_header
+func (client *Client) SetSecurityHeaders(headers *SecurityHeaders) error {
+	if err := headers.Validate(); err != nil {
+		return err
+	}
+	targets, err := client.accessTargets(headers.Domain)
+	if err != nil {
+		return err
+	}
+	values := headers.Values()
+	for _, target := range targets {
+		removeSecurityHeaders(target)
+		ssl := target.Name == "http" || isSSLServer(target)
+		target.AddBodyDirective(securityDirectives(values, ssl)...)
+	}
+	return nil
+}
+
+//删除安全响应头，domain为空时删除http中的安全响应头
+func (client *Client) RemoveSecurityHeaders(domain string) error {
+	targets, err := client.accessTargets(domain)
+	if err != nil {
+		return err
+	}
+	err = ErrNotFound
+	for _, target := range targets {
+		if removeSecurityHeaders(target) {
+			err = nil
+		}
+	}
+	return err
+}
+
+//解析参数：[domain=]profile[,header=value]，例如：strict 或者 aginx.io=moderate,X-Frame-Options=DENY
+func ParseSecurityHeaders(config string) (*SecurityHeaders, error) {
+	options := strings.Split(config, ",")
+	headers := &SecurityHeaders{Profile: options[0], Headers: make(map[string]string)}
+	if idx := strings.Index(headers.Profile, "="); idx != -1 {
+		headers.Domain, headers.Profile = headers.Profile[:idx], headers.Profile[idx+1:]
+	}
+	last := ""
+	for _, option := range options[1:] {
+		kv := strings.SplitN(option, "=", 2)
+		if name, has := securityHeaderName(strings.TrimSpace(kv[0])); has && len(kv) == 2 {
+			last, headers.Headers[name] = name, strings.TrimSpace(kv[1])
+		} else if last != "" {
+			//响应头的值中包含逗号，例如：Permissions-Policy=camera=(), microphone=()
+			headers.Headers[last] += "," + option
+		} else {
+			return nil, errors.New("invalid security header: " + option)
+		}
+	}
+	if err := headers.Validate(); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
@@ -0,0 +1,364 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/util"
+	"strings"
+)
+
+var ErrServerExists = errors.New("server already exists")
+
+//http.server的简化描述
+type Server struct {
+	Domains []string `json:"domains"`
+
+	//监听端口，为空时使用80，开启SSL时使用443
+	Listen []string `json:"listen,omitempty"`
+
+	//location / 的代理地址：http://127.0.0.1:8080，127.0.0.1:8080 或者 upstream名称
+	Proxy string `json:"proxy,omitempty"`
+
+	//location / 的静态文件目录，和Proxy只能使用一个
+	Root string `json:"root,omitempty"`
+
+	//开启https，创建时为每个域名申请证书，并添加80端口跳转到https的server
+	SSL bool `json:"ssl,omitempty"`
+
+	//代理gRPC服务：使用grpc_pass（Proxy为 127.0.0.1:9090 或者 grpcs://127.0.0.1:9090），listen开启http2
+	GRPC bool `json:"grpc,omitempty"`
+
+	//代理WebSocket服务：传递Upgrade和Connection头，使用较长的超时时间
+	WebSocket bool `json:"websocket,omitempty"`
+
+	//开启http2，nginx 1.25.1 以上使用 http2 on，以下在listen中添加http2
+	HTTP2 bool `json:"http2,omitempty"`
+
+	//开启http3(QUIC)，需要开启ssl，添加 listen quic 和 Alt-Svc 响应头，需要 nginx 1.25.0 以上并且编译了 http_v3 模块
+	HTTP3 bool `json:"http3,omitempty"`
+
+	//安全响应头预设：strict, moderate，为空时不添加
+	Security string `json:"security,omitempty"`
+}
+
+func (s *Server) Validate() error {
+	if len(s.Domains) == 0 {
+		return errors.New("the domains of server is empty")
+	}
+	for _, domain := range s.Domains {
+		if domain == "" || strings.ContainsAny(domain, " ;'\"") {
+			return errors.New("invalid domain: " + domain)
+		}
+	}
+	if (s.Proxy == "") == (s.Root == "") {
+		return errors.New("one of proxy and root must be set")
+	}
+	if s.GRPC && s.Proxy == "" {
+		return errors.New("the proxy of grpc server is empty")
+	}
+	if s.WebSocket && (s.Proxy == "" || s.GRPC) {
+		return errors.New("the websocket must be used with proxy")
+	}
+	if s.HTTP3 && !s.SSL {
+		return errors.New("the http3 must be used with ssl")
+	}
+	if _, has := SecurityProfiles[s.Security]; s.Security != "" && !has {
+		return errors.New("invalid security profile: " + s.Security)
+	}
+	return nil
+}
+
+func (s *Server) http2() bool {
+	return s.HTTP2 || s.GRPC
+}
+
+//检查nginx是否支持http2和http3，info为空时不检查
+func (s *Server) Supported(info *Info) error {
+	if info == nil {
+		return nil
+	}
+	if s.http2() && !info.Supports("http_v2") {
+		return errors.New("nginx is not built with the http_v2 module")
+	}
+	if s.HTTP3 && (!info.AtLeast("1.25.0") || !info.Supports("http_v3")) {
+		return fmt.Errorf("the http3 requires nginx 1.25.0+ with the http_v3 module, current: %s", info.Version)
+	}
+	return nil
+}
+
+func (s *Server) listen() []string {
+	if len(s.Listen) > 0 {
+		return s.Listen
+	} else if s.SSL {
+		return []string{"443"}
+	}
+	return []string{"80"}
+}
+
+func (s *Server) location() *Directive {
+	location := NewDirective("location", "/")
+	if s.Proxy == "" {
+		location.AddBody("root", s.Root)
+		return location
+	}
+	if s.GRPC {
+		return s.grpcLocation(location)
+	}
+	proxy := s.Proxy
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	location.AddBody("proxy_pass", proxy)
+	location.AddBody("proxy_set_header", "Host", "$host")
+	location.AddBody("proxy_set_header", "X-Real-IP", "$remote_addr")
+	location.AddBody("proxy_set_header", "X-Forwarded-For", "$proxy_add_x_forwarded_for")
+	if s.WebSocket {
+		EnableWebSocket(location)
+	}
+	return location
+}
+
+//gRPC使用的超时时间较长，流式调用不会被中断，并且不限制消息的大小
+func (s *Server) grpcLocation(location *Directive) *Directive {
+	proxy := s.Proxy
+	if !strings.Contains(proxy, "://") {
+		proxy = "grpc://" + proxy
+	}
+	location.AddBody("grpc_pass", proxy)
+	location.AddBody("grpc_set_header", "X-Real-IP", "$remote_addr")
+	location.AddBody("grpc_set_header", "X-Forwarded-For", "$proxy_add_x_forwarded_for")
+	location.AddBody("grpc_read_timeout", "1h")
+	location.AddBody("grpc_send_timeout", "1h")
+	location.AddBody("client_max_body_size", "0")
+	return location
+}
+
+//info为nginx程序信息，为空时按照 nginx 1.25.1 以下的方式生成http2配置
+func (s *Server) server(domains []string, certificate *lego.StoreFile, info *Info) *Directive {
+	server := NewDirective("server")
+	http2On := s.http2() && info != nil && info.AtLeast("1.25.1")
+	for _, listen := range s.listen() {
+		args := []string{listen}
+		if certificate != nil {
+			args = append(args, "ssl")
+		}
+		if s.http2() && !http2On {
+			args = append(args, "http2")
+		}
+		server.AddBody("listen", args...)
+	}
+	if certificate != nil && s.HTTP3 {
+		for _, listen := range s.listen() {
+			server.AddBody("listen", listen, "quic")
+		}
+	}
+	if http2On {
+		server.AddBody("http2", "on")
+	}
+	server.AddBody("server_name", domains...)
+	if certificate != nil {
+		server.AddBody("ssl_certificate", certificate.Certificate)
+		server.AddBody("ssl_certificate_key", certificate.PrivateKey)
+		server.AddBody("ssl_session_timeout", "5m")
+		if s.HTTP3 {
+			//QUIC只能使用TLSv1.3
+			server.AddBody("ssl_protocols", "TLSv1.2", "TLSv1.3")
+			listen := s.listen()[0]
+			server.AddBody("add_header", "Alt-Svc", fmt.Sprintf(`'h3=":%s"; ma=86400'`, listen[strings.LastIndex(listen, ":")+1:]))
+		} else {
+			server.AddBody("ssl_protocols", "TLSv1", "TLSv1.1", "TLSv1.2")
+		}
+		server.AddBody("ssl_prefer_server_ciphers", "on")
+	}
+	if s.Security != "" {
+		server.AddBodyDirective(securityDirectives(SecurityProfiles[s.Security], certificate != nil)...)
+	}
+	server.AddBodyDirective(s.location())
+	return server
+}
+
+//生成server配置，开启SSL时每个域名使用各自的证书生成一个server，info为nginx程序信息（可以为空）
+func (s *Server) Directives(certificates map[string]*lego.StoreFile, info *Info) ([]*Directive, error) {
+	if !s.SSL {
+		return []*Directive{s.server(s.Domains, nil, info)}, nil
+	}
+	rewrite := NewDirective("server")
+	rewrite.AddBody("listen", "80")
+	rewrite.AddBody("server_name", s.Domains...)
+	rewrite.AddBody("return", "301", "https://$host$request_uri")
+	directives := []*Directive{rewrite}
+	for _, domain := range s.Domains {
+		certificate, has := certificates[domain]
+		if !has {
+			return nil, errors.New("the certificate not found: " + domain)
+		}
+		directives = append(directives, s.server([]string{domain}, certificate, info))
+	}
+	return directives, nil
+}
+
+//从server配置中解析
+func ParseServer(directive *Directive) *Server {
+	server := &Server{Domains: make([]string, 0), Listen: make([]string, 0)}
+	for _, body := range directive.Body {
+		switch body.Name {
+		case "server_name":
+			server.Domains = append(server.Domains, body.Args...)
+		case "listen":
+			if len(body.Args) > 1 && contains(body.Args[1:], "quic") {
+				server.HTTP3 = true
+				continue
+			}
+			for i, arg := range body.Args {
+				if i == 0 {
+					server.Listen = append(server.Listen, arg)
+				} else if arg == "http2" {
+					server.HTTP2 = true
+				} else if arg == "ssl" {
+					server.SSL = true
+				}
+			}
+		case "http2":
+			server.HTTP2 = server.HTTP2 || (len(body.Args) > 0 && body.Args[0] == "on")
+		case "root":
+			server.Root = strings.Join(body.Args, " ")
+		case "location":
+			if len(body.Args) == 1 && body.Args[0] == "/" {
+				server.WebSocket = IsWebSocket(body)
+				for _, d := range body.Body {
+					if d.Name == "proxy_pass" && len(d.Args) > 0 {
+						server.Proxy = d.Args[0]
+					} else if d.Name == "grpc_pass" && len(d.Args) > 0 {
+						server.Proxy, server.GRPC = d.Args[0], true
+					} else if d.Name == "root" && len(d.Args) > 0 {
+						server.Root = d.Args[0]
+					}
+				}
+			}
+		}
+	}
+	if values := parseSecurityHeaders(directive); len(values) > 0 {
+		server.Security = matchSecurityProfile(values, server.SSL)
+	}
+	return server
+}
+
+func serverQueries(domain string) [][]string {
+	query := fmt.Sprintf("server.server_name('%s')", domain)
+	return [][]string{Queries("http", query), Queries("http", "include", "*", query)}
+}
+
+//全部的http.server
+func (client *Client) Servers() []*Server {
+	servers := make([]*Server, 0)
+	for _, queries := range [][]string{Queries("http", "server"), Queries("http", "include", "*", "server")} {
+		if directives, err := client.Select(queries...); err == nil {
+			for _, directive := range directives {
+				servers = append(servers, ParseServer(directive))
+			}
+		}
+	}
+	return servers
+}
+
+//server_name包含domain的server
+func (client *Client) GetServers(domain string) ([]*Server, error) {
+	servers := make([]*Server, 0)
+	for _, queries := range serverQueries(domain) {
+		if directives, err := client.Select(queries...); err == nil {
+			for _, directive := range directives {
+				servers = append(servers, ParseServer(directive))
+			}
+		}
+	}
+	if len(servers) == 0 {
+		return nil, ErrNotFound
+	}
+	return servers, nil
+}
+
+//创建server，保存到 hosts.d/<第一个域名>.ngx.conf，email为申请证书使用的邮箱
+func (client *Client) NewServer(server *Server, email string) (err error) {
+	defer util.Catch(func(e error) {
+		err = e
+	})
+	util.PanicIfError(server.Validate())
+	for _, domain := range server.Domains {
+		if _, err := client.GetServers(domain); err == nil {
+			return fmt.Errorf("%w: %s", ErrServerExists, domain)
+		}
+	}
+
+	certificates := make(map[string]*lego.StoreFile)
+	if server.SSL {
+		util.AssertTrue(client.Lego != nil, "the certificate manager is not enabled")
+		for _, domain := range server.Domains {
+			certificates[domain] = client.NewCertificate(email, domain)
+		}
+	}
+	var info *Info
+	if client.Process != nil {
+		if info, err = client.Process.Info(); err != nil {
+			logger.WithError(err).Debug("get NGINX info")
+			info = nil
+		}
+	}
+	util.PanicIfError(server.Supported(info))
+
+	if server.WebSocket {
+		util.PanicIfError(client.SetConnectionUpgrade())
+	}
+	directives, err := server.Directives(certificates, info)
+	util.PanicIfError(err)
+	return client.hostFile(server.Domains[0], directives...)
+}
+
+//删除server_name中的domain，没有其他域名的server将被删除
+func (client *Client) DeleteServer(domain string) error {
+	err := ErrNotFound
+	for _, queries := range serverQueries(domain) {
+		parents, e := client.Select(queries[:len(queries)-1]...)
+		if e != nil {
+			continue
+		}
+		for _, parent := range parents {
+			body := make([]*Directive, 0, len(parent.Body))
+			for _, directive := range parent.Body {
+				if directive.Name == "server" {
+					if matched, empty := removeServerName(directive, domain); matched {
+						err = nil
+						if empty {
+							continue
+						}
+					}
+				}
+				body = append(body, directive)
+			}
+			parent.Body = body
+		}
+	}
+	return err
+}
+
+//从server_name中删除domain，返回是否包含domain和删除后是否没有其他域名
+func removeServerName(server *Directive, domain string) (matched, empty bool) {
+	names, err := server.Select("server_name")
+	if err != nil {
+		return
+	}
+	empty = true
+	for _, name := range names {
+		args := make([]string, 0, len(name.Args))
+		for _, arg := range name.Args {
+			if arg == domain {
+				matched = true
+			} else {
+				args = append(args, arg)
+			}
+		}
+		name.Args = args
+		empty = empty && len(args) == 0
+	}
+	return
+}
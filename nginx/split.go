@@ -0,0 +1,242 @@
+package nginx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"regexp"
+	"sort"
+)
+
+const (
+	splitDir = "splits"
+
+	SplitStable = "stable"
+	SplitCanary = "canary"
+)
+
+var splitName = regexp.MustCompile(`^[\w.-]+$`)
+
+//灰度发布：location代理到名称为Name的upstream，按照权重合并stable和canary两个upstream的server，
+//canary得到Weight%的流量。配置保存在存储的 splits/<name>.json 中
+type Split struct {
+	Name     string `json:"name"`
+	Domain   string `json:"domain"`
+	Location string `json:"location"`
+	Stable   string `json:"stable"`
+	Canary   string `json:"canary"`
+	Weight   int    `json:"weight"` //canary流量的百分比：0-100
+}
+
+func (split *Split) path() string {
+	return fmt.Sprintf("%s/%s.json", splitDir, split.Name)
+}
+
+func (split *Split) Validate() error {
+	if !splitName.MatchString(split.Name) {
+		return errors.New("invalid split name: " + split.Name)
+	}
+	if split.Domain == "" {
+		return errors.New("the domain of split is empty")
+	}
+	if split.Stable == "" || split.Canary == "" || split.Stable == split.Canary {
+		return errors.New("the stable and canary must be two upstreams")
+	}
+	if split.Weight < 0 || split.Weight > 100 {
+		return errors.New("the weight must be between 0 and 100")
+	}
+	return nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+//参与负载的server权重之和，没有时为1
+func activeWeight(servers []*UpstreamServer) int {
+	sum := 0
+	for _, server := range servers {
+		if !server.Backup && !server.Down {
+			sum += serverWeight(server)
+		}
+	}
+	if sum == 0 {
+		return 1
+	}
+	return sum
+}
+
+func serverWeight(server *UpstreamServer) int {
+	if server.Weight > 0 {
+		return server.Weight
+	}
+	return 1
+}
+
+//合并stable和canary的server，stable的每个server权重乘以(100-weight)*canary总权重，
+//canary的每个server权重乘以weight*stable总权重，这样canary得到的流量比例为weight%
+func SplitUpstream(name string, stable, canary *Upstream, weight int) *Upstream {
+	upstream := &Upstream{Name: name, LoadBalance: stable.LoadBalance, Servers: make([]*UpstreamServer, 0)}
+	stableSum, canarySum := activeWeight(stable.Servers), activeWeight(canary.Servers)
+	sides := []struct {
+		servers []*UpstreamServer
+		factor  int
+	}{
+		{stable.Servers, (100 - weight) * canarySum},
+		{canary.Servers, weight * stableSum},
+	}
+	divisor := 0
+	for _, side := range sides {
+		if side.factor == 0 {
+			continue
+		}
+		for _, server := range side.servers {
+			copied := *server
+			copied.Weight = serverWeight(server) * side.factor
+			divisor = gcd(divisor, copied.Weight)
+			upstream.Servers = append(upstream.Servers, &copied)
+		}
+	}
+	for _, server := range upstream.Servers {
+		if server.Weight /= divisor; server.Weight == 1 {
+			server.Weight = 0
+		}
+	}
+	return upstream
+}
+
+func (client *Client) splitLocations(split *Split) ([]*Directive, error) {
+	location := split.Location
+	if location == "" {
+		location = "/"
+	}
+	locations := make([]*Directive, 0)
+	for _, queries := range serverQueries(split.Domain) {
+		if directives, err := client.Select(append(queries, fmt.Sprintf("location('%s')", location))...); err == nil {
+			locations = append(locations, directives...)
+		}
+	}
+	if len(locations) == 0 {
+		return nil, ErrNotFound
+	}
+	return locations, nil
+}
+
+func setProxyPass(location *Directive, upstream string) {
+	for _, directive := range location.Body {
+		if directive.Name == "proxy_pass" {
+			directive.Args = []string{"http://" + upstream}
+			return
+		}
+	}
+	location.AddBody("proxy_pass", "http://"+upstream)
+}
+
+//设置灰度：创建或者更新名称为split.Name的upstream，并修改location的proxy_pass
+func (client *Client) SetSplit(split *Split, exists bool) error {
+	if err := split.Validate(); err != nil {
+		return err
+	}
+	locations, err := client.splitLocations(split)
+	if err != nil {
+		return err
+	}
+	upstreams := make([]*Upstream, 0, 2)
+	for _, name := range []string{split.Stable, split.Canary} {
+		_, directive, stream := FindUpstream(client.doc, name)
+		if directive == nil || stream {
+			return fmt.Errorf("%w: upstream %s", ErrNotFound, name)
+		}
+		upstreams = append(upstreams, ParseUpstream(directive, false))
+	}
+	upstream := SplitUpstream(split.Name, upstreams[0], upstreams[1], split.Weight)
+	if err = upstream.Validate(); err != nil {
+		return err
+	}
+
+	if _, directive, _ := FindUpstream(client.doc, split.Name); directive == nil {
+		err = client.Add(Queries("http"), upstream.Directive())
+	} else if exists {
+		upstream.Apply(directive)
+	} else {
+		//同名的upstream不是灰度创建的
+		err = ErrUpstreamExists
+	}
+	if err != nil {
+		return err
+	}
+	for _, location := range locations {
+		setProxyPass(location, split.Name)
+	}
+	return nil
+}
+
+//全部流量切换到target（SplitStable或者SplitCanary），删除灰度使用的upstream
+func (client *Client) PromoteSplit(split *Split, target string) error {
+	upstream := split.Canary
+	if target == SplitStable {
+		upstream = split.Stable
+	} else if target != SplitCanary {
+		return errors.New("the promote target must be stable or canary")
+	}
+	locations, err := client.splitLocations(split)
+	if err != nil {
+		return err
+	}
+	for _, location := range locations {
+		setProxyPass(location, upstream)
+	}
+	if queries, directive, _ := FindUpstream(client.doc, split.Name); directive != nil {
+		return client.Delete(queries...)
+	}
+	return nil
+}
+
+func LoadSplits(engine plugins.StorageEngine) ([]*Split, error) {
+	files, err := engine.Search(splitDir + "/*.json")
+	if err != nil {
+		return nil, err
+	}
+	splits := make([]*Split, 0, len(files))
+	for _, file := range files {
+		split := new(Split)
+		if err := json.Unmarshal(file.Content, split); err != nil {
+			return nil, err
+		}
+		splits = append(splits, split)
+	}
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].Name < splits[j].Name
+	})
+	return splits, nil
+}
+
+func LoadSplit(engine plugins.StorageEngine, name string) (*Split, error) {
+	split := &Split{Name: name}
+	file, err := engine.Get(split.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(file.Content, split)
+	return split, err
+}
+
+func SaveSplit(engine plugins.StorageEngine, split *Split) error {
+	content, err := json.MarshalIndent(split, "", "  ")
+	if err != nil {
+		return err
+	}
+	return engine.Put(split.path(), content)
+}
+
+func RemoveSplit(engine plugins.StorageEngine, split *Split) error {
+	return engine.Remove(split.path())
+}
@@ -0,0 +1,107 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//aginx添加的 stub_status server 使用的 server_name
+const StubStatusServer = "aginx-stub-status"
+
+var ErrStubStatusDisabled = errors.New("the stub_status is not enabled, use --stub-status")
+
+//nginx stub_status 的连接和请求统计
+type StubStatus struct {
+	Active   int64 `json:"active"`   //当前活动连接数
+	Accepts  int64 `json:"accepts"`  //接受的连接总数
+	Handled  int64 `json:"handled"`  //处理的连接总数
+	Requests int64 `json:"requests"` //请求总数
+	Reading  int64 `json:"reading"`  //正在读取请求头的连接数
+	Writing  int64 `json:"writing"`  //正在返回响应的连接数
+	Waiting  int64 `json:"waiting"`  //空闲的keepalive连接数
+}
+
+//解析 stub_status 的输出内容：
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func ParseStubStatus(content string) (*StubStatus, error) {
+	fields := strings.Fields(content)
+	if len(fields) != 16 || fields[0] != "Active" {
+		return nil, fmt.Errorf("invalid stub_status: %s", content)
+	}
+	values := make([]int64, 0, 7)
+	for _, idx := range []int{2, 7, 8, 9, 11, 13, 15} {
+		value, err := strconv.ParseInt(fields[idx], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stub_status: %s", content)
+		}
+		values = append(values, value)
+	}
+	return &StubStatus{
+		Active: values[0], Accepts: values[1], Handled: values[2], Requests: values[3],
+		Reading: values[4], Writing: values[5], Waiting: values[6],
+	}, nil
+}
+
+//提供 stub_status 的server，listen为监听地址，例如：127.0.0.1:8090
+func StubStatusDirective(listen string) *Directive {
+	server := NewDirective("server")
+	server.AddBody("listen", listen)
+	server.AddBody("server_name", StubStatusServer)
+	server.AddBody("access_log", "off")
+	location := NewDirective("location", "/stub_status")
+	location.AddBody("stub_status")
+	server.AddBodyDirective(location)
+	return server
+}
+
+//读取 stub_status 使用的地址，没有指定host时使用127.0.0.1
+func StubStatusURL(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		host, port = "", listen
+	}
+	if host == "" || host == "0.0.0.0" || host == "*" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s/stub_status", net.JoinHostPort(host, port))
+}
+
+//查询nginx的连接和请求统计
+func (sp *Process) StubStatus() (*StubStatus, error) {
+	if sp.StubStatusListen == "" {
+		return nil, ErrStubStatusDisabled
+	}
+	client := &http.Client{Timeout: time.Second * 3}
+	resp, err := client.Get(StubStatusURL(sp.StubStatusListen))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("stub_status response %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStubStatus(string(body))
+}
+
+//添加 stub_status 的server到配置中，nginx不支持 stub_status 模块时返回错误
+func (client *Client) StubStatus(listen string) error {
+	if client.Process != nil {
+		if info, err := client.Process.Info(); err == nil && !info.Supports("http_stub_status") {
+			return errors.New("nginx is not built with the http_stub_status module")
+		}
+	}
+	return client.HostServer(StubStatusServer, StubStatusDirective(listen))
+}
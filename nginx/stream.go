@@ -0,0 +1,211 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrStreamExists = errors.New("stream server already exists")
+
+	streamListen  = regexp.MustCompile(`^(\S+:)?\d+$`)
+	streamTimeout = regexp.MustCompile(`^\d+(ms|s|m|h|d)?$`)
+)
+
+//stream.server的简化描述，代理TCP/UDP服务（例如：mysql, mqtt）
+type Stream struct {
+	Listen string `json:"listen"`        //监听地址：3306 或者 127.0.0.1:3306
+	UDP    bool   `json:"udp,omitempty"` //监听UDP
+
+	//代理地址或者stream中的upstream名称，和Servers只能使用一个
+	Proxy string `json:"proxy,omitempty"`
+
+	//后端服务，创建时生成名称为 stream_<listen> 的upstream
+	LoadBalance string            `json:"load_balance,omitempty"`
+	Servers     []*UpstreamServer `json:"servers,omitempty"`
+
+	//连接空闲的超时时间(proxy_timeout)，例如：10m
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func (s *Stream) Validate() error {
+	if !streamListen.MatchString(s.Listen) {
+		return errors.New("invalid listen of stream: " + s.Listen)
+	}
+	if (s.Proxy == "") == (len(s.Servers) == 0) {
+		return errors.New("one of proxy and servers must be set")
+	}
+	if strings.ContainsAny(s.Proxy, " ;'\"{}") {
+		return errors.New("invalid proxy: " + s.Proxy)
+	}
+	if s.Timeout != "" && !streamTimeout.MatchString(s.Timeout) {
+		return errors.New("invalid timeout: " + s.Timeout)
+	}
+	if len(s.Servers) > 0 {
+		return s.upstream().Validate()
+	}
+	return nil
+}
+
+//生成的upstream和配置文件的名称：stream_3306, stream_127_0_0_1_53_udp
+func (s *Stream) name() string {
+	name := "stream_" + notWord.ReplaceAllString(s.Listen, "_")
+	if s.UDP {
+		name += "_udp"
+	}
+	return name
+}
+
+func (s *Stream) upstream() *Upstream {
+	return &Upstream{Name: s.name(), Stream: true, LoadBalance: s.LoadBalance, Servers: s.Servers}
+}
+
+func (s *Stream) listen() []string {
+	if s.UDP {
+		return []string{s.Listen, "udp"}
+	}
+	return []string{s.Listen}
+}
+
+//生成upstream（使用Servers时）和server配置
+func (s *Stream) Directives() []*Directive {
+	directives := make([]*Directive, 0)
+	proxy := s.Proxy
+	if len(s.Servers) > 0 {
+		directives = append(directives, s.upstream().Directive())
+		proxy = s.name()
+	}
+	server := NewDirective("server")
+	server.AddBody("listen", s.listen()...)
+	server.AddBody("proxy_pass", proxy)
+	if s.Timeout != "" {
+		server.AddBody("proxy_timeout", s.Timeout)
+	}
+	return append(directives, server)
+}
+
+//从stream.server配置中解析
+func ParseStream(directive *Directive) *Stream {
+	stream := new(Stream)
+	for _, body := range directive.Body {
+		switch {
+		case body.Name == "listen" && len(body.Args) > 0:
+			stream.Listen = body.Args[0]
+			for _, arg := range body.Args[1:] {
+				stream.UDP = stream.UDP || arg == "udp"
+			}
+		case body.Name == "proxy_pass" && len(body.Args) > 0:
+			stream.Proxy = body.Args[0]
+		case body.Name == "proxy_timeout" && len(body.Args) > 0:
+			stream.Timeout = body.Args[0]
+		}
+	}
+	return stream
+}
+
+func streamServerQueries() [][]string {
+	return [][]string{Queries("stream", "server"), Queries("stream", "include", "*", "server")}
+}
+
+//stream中全部的server，代理到生成的upstream时包含upstream的server
+func (client *Client) Streams() []*Stream {
+	streams := make([]*Stream, 0)
+	for _, queries := range streamServerQueries() {
+		if directives, err := client.Select(queries...); err == nil {
+			for _, directive := range directives {
+				stream := ParseStream(directive)
+				if stream.Proxy == stream.name() {
+					if upstream, err := client.GetUpstream(stream.Proxy); err == nil {
+						stream.LoadBalance, stream.Servers, stream.Proxy = upstream.LoadBalance, upstream.Servers, ""
+					}
+				}
+				streams = append(streams, stream)
+			}
+		}
+	}
+	return streams
+}
+
+func (client *Client) streamServers(listen string, udp bool) []*Directive {
+	servers := make([]*Directive, 0)
+	for _, queries := range streamServerQueries() {
+		if directives, err := client.Select(queries...); err == nil {
+			for _, directive := range directives {
+				if stream := ParseStream(directive); stream.Listen == listen && stream.UDP == udp {
+					servers = append(servers, directive)
+				}
+			}
+		}
+	}
+	return servers
+}
+
+//没有stream时在nginx.conf中添加，并include streams.d/*.conf
+func (client *Client) streamsd() error {
+	if _, err := client.Select("stream"); err != nil {
+		client.doc.Body = append(client.doc.Body, NewDirective("stream"))
+	}
+	if _, err := client.Select("stream", "include('streams.d/*.conf')"); err != nil {
+		return client.Add(Queries("stream"), NewDirective("include", "streams.d/*.conf"))
+	}
+	return nil
+}
+
+//创建stream server，保存到 streams.d/stream_<listen>.ngx.conf
+func (client *Client) NewStream(stream *Stream) error {
+	if err := stream.Validate(); err != nil {
+		return err
+	}
+	if len(client.streamServers(stream.Listen, stream.UDP)) > 0 {
+		return fmt.Errorf("%w: %s", ErrStreamExists, strings.Join(stream.listen(), " "))
+	}
+	if len(stream.Servers) > 0 {
+		if _, directive, _ := FindUpstream(client.doc, stream.name()); directive != nil {
+			return ErrUpstreamExists
+		}
+	}
+	if err := client.streamsd(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("streams.d/%s.ngx.conf", stream.name())
+	if files, err := client.Select("stream", "include('streams.d/*.conf')", fmt.Sprintf("file('%s')", name)); err == nil {
+		files[0].Body = append(files[0].Body, stream.Directives()...)
+		return nil
+	}
+	file := NewDirective("file", name)
+	file.Virtual = Include
+	file.AddBodyDirective(stream.Directives()...)
+	return client.Add(Queries("stream", "include('streams.d/*.conf')"), file)
+}
+
+//删除stream server，同时删除生成的upstream
+func (client *Client) DeleteStream(listen string, udp bool) error {
+	servers := client.streamServers(listen, udp)
+	if len(servers) == 0 {
+		return ErrNotFound
+	}
+	deletes := make(map[*Directive]bool)
+	for _, server := range servers {
+		deletes[server] = true
+	}
+	name := (&Stream{Listen: listen, UDP: udp}).name()
+	for _, queries := range [][]string{Queries("stream"), Queries("stream", "include", "*")} {
+		parents, err := client.Select(queries...)
+		if err != nil {
+			continue
+		}
+		for _, parent := range parents {
+			body := make([]*Directive, 0, len(parent.Body))
+			for _, directive := range parent.Body {
+				if deletes[directive] || (directive.Name == "upstream" && len(directive.Args) > 0 && directive.Args[0] == name) {
+					continue
+				}
+				body = append(body, directive)
+			}
+			parent.Body = body
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package nginx
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//租户的配置文件保存在 tenants/<name>/ 中，http中使用 include tenants/*/*.conf 加载
+const (
+	TenantDir     = "tenants"
+	TenantInclude = TenantDir + "/*/*.conf"
+)
+
+//租户配置文件中可以使用的顶层指令
+var tenantDirectives = map[string]bool{
+	"server": true, "upstream": true, "map": true, "geo": true, "split_clients": true,
+}
+
+//租户的配置文件路径，文件必须在租户目录中
+func TenantFile(tenant, file string) (string, error) {
+	if strings.Contains(file, "/") || strings.Contains(file, "..") || path.Ext(file) != ".conf" {
+		return "", fmt.Errorf("the tenant file must be a .conf file in the tenant directory: %s", file)
+	}
+	return path.Join(TenantDir, tenant, file), nil
+}
+
+//文件是否是租户的配置文件
+func IsTenantFile(tenant, file string) bool {
+	return strings.HasPrefix(file, path.Join(TenantDir, tenant)+"/")
+}
+
+//配置中http是否已经include租户的配置文件
+func HasTenantInclude(cfg *Configuration) bool {
+	for _, http := range cfg.Body {
+		if http.Name != "http" {
+			continue
+		}
+		for _, include := range http.Body {
+			if include.Name == "include" && len(include.Args) > 0 && include.Args[0] == TenantInclude {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//租户配置中的命名（server_name、upstream、map的变量）
+func tenantNames(directive *Directive) []string {
+	switch directive.Name {
+	case "server":
+		names := make([]string, 0)
+		for _, child := range directive.Body {
+			if child.Name == "server_name" {
+				for _, name := range child.Args {
+					if name != "" && name != "_" {
+						names = append(names, "server_name "+strings.ToLower(name))
+					}
+				}
+			}
+		}
+		return names
+	case "upstream":
+		if len(directive.Args) > 0 {
+			return []string{"upstream " + directive.Args[0]}
+		}
+	case "map", "geo", "split_clients":
+		if len(directive.Args) > 0 {
+			return []string{"variable " + directive.Args[len(directive.Args)-1]}
+		}
+	}
+	return nil
+}
+
+//使用文件路径的指令，文件必须在租户目录中
+func tenantPathDirective(name string) bool {
+	switch name {
+	case "root", "alias", "proxy_cache_path", "fastcgi_cache_path", "uwsgi_cache_path", "scgi_cache_path":
+		return true
+	}
+	return strings.HasSuffix(name, "_log") || strings.HasSuffix(name, "_temp_path") ||
+		strings.HasSuffix(name, "_file") || strings.Contains(name, "ssl_certificate") ||
+		strings.HasSuffix(name, "ssl_crl") || strings.HasSuffix(name, "ssl_dhparam") ||
+		strings.HasSuffix(name, "ssl_session_ticket_key")
+}
+
+//路径是否在租户目录中：相对路径为 tenants/<name>/ 中的文件，绝对路径为配置目录中的 tenants/<name>/，不能使用变量
+func isTenantPath(tenant, file string) bool {
+	if strings.Contains(file, "$") || strings.Contains(file, "..") {
+		return false
+	}
+	if filepath.IsAbs(file) {
+		rel, err := filepath.Rel(filepath.Join(MustConfigDir(), TenantDir, tenant), filepath.Clean(file))
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	file = path.Clean(filepath.ToSlash(file))
+	return file == path.Join(TenantDir, tenant) || IsTenantFile(tenant, file)
+}
+
+//检查租户配置中的全部指令（包括server、location中的指令）
+func checkTenantDirectives(tenant string, directives []*Directive) error {
+	for _, directive := range directives {
+		switch {
+		case directive.Name == "include":
+			for _, arg := range directive.Args {
+				if !IsTenantFile(tenant, arg) || strings.Contains(arg, "..") {
+					return fmt.Errorf("the tenant %s can only include the files in %s/%s: %s", tenant, TenantDir, tenant, arg)
+				}
+			}
+		case directive.Name == "listen":
+			//default_server会接收其他租户和全局配置中没有匹配的请求
+			for _, arg := range directive.Args {
+				if arg == "default_server" || arg == "default" || arg == "reuseport" {
+					return fmt.Errorf("the tenant %s can not use %s in listen", tenant, arg)
+				}
+			}
+		case tenantPathDirective(directive.Name) && len(directive.Args) > 0:
+			file := directive.Args[0]
+			//关闭日志、不写入文件的日志、内嵌的证书（data:）
+			if file == "off" || file == "on" || file == "stderr" || strings.HasPrefix(file, "syslog:") ||
+				strings.HasPrefix(file, "memory:") || strings.HasPrefix(file, "data:") {
+				break
+			}
+			if !isTenantPath(tenant, file) {
+				return fmt.Errorf("the tenant %s can only use the files in %s/%s: %s %s",
+					tenant, TenantDir, tenant, directive.Name, file)
+			}
+		}
+		if err := checkTenantDirectives(tenant, directive.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//检查租户的配置文件：只能使用 server, upstream, map, geo, split_clients，只能include和使用租户目录中的文件（root、alias、日志、证书等），
+//listen不能使用default_server和reuseport，server_name、upstream和map的变量不能和其他租户或者全局配置重复
+func CheckTenant(cfg *Configuration, tenant string, directives []*Directive) error {
+	for _, directive := range directives {
+		if !tenantDirectives[directive.Name] {
+			return fmt.Errorf("the directive %s is not allowed in the tenant configuration", directive.Name)
+		}
+	}
+	if err := checkTenantDirectives(tenant, directives); err != nil {
+		return err
+	}
+
+	used := map[string]string{}
+	for _, http := range cfg.Body {
+		if http.Name != "http" {
+			continue
+		}
+		for _, directive := range locatedChildren(http, NGINX_CONF) {
+			//租户自己的文件可以重复定义，由nginx -t检查
+			if IsTenantFile(tenant, directive.file) {
+				continue
+			}
+			for _, name := range tenantNames(directive.Directive) {
+				used[name] = directive.file
+			}
+		}
+	}
+	for _, directive := range directives {
+		for _, name := range tenantNames(directive) {
+			if usedFile, has := used[name]; has {
+				return fmt.Errorf("the %s is used in %s", name, usedFile)
+			}
+		}
+	}
+	return nil
+}
+
+//租户的配置文件
+func TenantFiles(cfg *Configuration, tenant string) []*GraphFile {
+	files := make([]*GraphFile, 0)
+	for _, file := range NewIncludeGraph(cfg).Files {
+		if IsTenantFile(tenant, file.Name) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
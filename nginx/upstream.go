@@ -0,0 +1,270 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUpstreamExists     = errors.New("upstream already exists")
+	ErrUpstreamLastServer = errors.New("upstream must have at least one server")
+)
+
+//负载均衡使用的指令
+var loadBalances = map[string]bool{
+	"least_conn": true, "ip_hash": true, "hash": true, "random": true, "least_time": true,
+}
+
+//upstream中的server
+type UpstreamServer struct {
+	Address     string   `json:"address"`
+	Weight      int      `json:"weight,omitempty"`
+	MaxFails    int      `json:"max_fails,omitempty"`
+	FailTimeout string   `json:"fail_timeout,omitempty"`
+	Backup      bool     `json:"backup,omitempty"`
+	Down        bool     `json:"down,omitempty"`   //摘除流量（drain）
+	Params      []string `json:"params,omitempty"` //其他参数：max_conns=100, resolve 等
+}
+
+func ParseUpstreamServer(args []string) *UpstreamServer {
+	server := &UpstreamServer{Params: make([]string, 0)}
+	if len(args) == 0 {
+		return server
+	}
+	server.Address = args[0]
+	for _, arg := range args[1:] {
+		name, value := arg, ""
+		if idx := strings.Index(arg, "="); idx != -1 {
+			name, value = arg[:idx], arg[idx+1:]
+		}
+		switch name {
+		case "weight":
+			if weight, err := strconv.Atoi(value); err == nil {
+				server.Weight = weight
+				continue
+			}
+		case "max_fails":
+			if maxFails, err := strconv.Atoi(value); err == nil {
+				server.MaxFails = maxFails
+				continue
+			}
+		case "fail_timeout":
+			server.FailTimeout = value
+			continue
+		case "backup":
+			server.Backup = true
+			continue
+		case "down":
+			server.Down = true
+			continue
+		}
+		server.Params = append(server.Params, arg)
+	}
+	return server
+}
+
+func (s *UpstreamServer) Args() []string {
+	args := []string{s.Address}
+	if s.Weight > 0 {
+		args = append(args, fmt.Sprintf("weight=%d", s.Weight))
+	}
+	if s.MaxFails > 0 {
+		args = append(args, fmt.Sprintf("max_fails=%d", s.MaxFails))
+	}
+	if s.FailTimeout != "" {
+		args = append(args, "fail_timeout="+s.FailTimeout)
+	}
+	args = append(args, s.Params...)
+	if s.Backup {
+		args = append(args, "backup")
+	}
+	if s.Down {
+		args = append(args, "down")
+	}
+	return args
+}
+
+type Upstream struct {
+	Name   string `json:"name"`
+	Stream bool   `json:"stream,omitempty"` //stream.upstream
+
+	//负载均衡方式，为空时使用轮询：least_conn, ip_hash, hash $request_uri consistent, random
+	LoadBalance string            `json:"load_balance,omitempty"`
+	Servers     []*UpstreamServer `json:"servers"`
+}
+
+func ParseUpstream(directive *Directive, stream bool) *Upstream {
+	upstream := &Upstream{Stream: stream, Servers: make([]*UpstreamServer, 0)}
+	if len(directive.Args) > 0 {
+		upstream.Name = directive.Args[0]
+	}
+	for _, body := range directive.Body {
+		if body.Name == "server" {
+			upstream.Servers = append(upstream.Servers, ParseUpstreamServer(body.Args))
+		} else if loadBalances[body.Name] {
+			upstream.LoadBalance = strings.Join(append([]string{body.Name}, body.Args...), " ")
+		}
+	}
+	return upstream
+}
+
+func (u *Upstream) Validate() error {
+	if u.Name == "" {
+		return errors.New("the name of upstream is empty")
+	}
+	if len(u.Servers) == 0 {
+		return ErrUpstreamLastServer
+	}
+	for _, server := range u.Servers {
+		if server.Address == "" {
+			return errors.New("the address of upstream server is empty")
+		}
+	}
+	if fields := strings.Fields(u.LoadBalance); len(fields) > 0 && !loadBalances[fields[0]] {
+		return errors.New("load balance not support: " + u.LoadBalance)
+	}
+	return nil
+}
+
+//使用负载均衡方式和server替换upstream中的配置，其他配置（keepalive, zone等）保留
+func (u *Upstream) Apply(directive *Directive) {
+	body := make([]*Directive, 0)
+	if fields := strings.Fields(u.LoadBalance); len(fields) > 0 {
+		body = append(body, NewDirective(fields[0], fields[1:]...))
+	}
+	for _, d := range directive.Body {
+		if d.Name != "server" && !loadBalances[d.Name] {
+			body = append(body, d)
+		}
+	}
+	for _, server := range u.Servers {
+		body = append(body, NewDirective("server", server.Args()...))
+	}
+	directive.Body = body
+}
+
+func (u *Upstream) Directive() *Directive {
+	directive := NewDirective("upstream", u.Name)
+	u.Apply(directive)
+	return directive
+}
+
+//查找http或者stream中的upstream，包含include的文件
+func FindUpstream(conf *Configuration, name string) (queries []string, directive *Directive, stream bool) {
+	query := fmt.Sprintf("upstream('%s')", name)
+	for _, top := range []string{"http", "stream"} {
+		for _, queries = range [][]string{Queries(top, query), Queries(top, "include", "*", query)} {
+			if directives, err := conf.Select(queries...); err == nil {
+				return queries, directives[0], top == "stream"
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+//全部的upstream
+func (client *Client) Upstreams() []*Upstream {
+	upstreams := make([]*Upstream, 0)
+	for _, top := range []string{"http", "stream"} {
+		for _, queries := range [][]string{Queries(top, "upstream"), Queries(top, "include", "*", "upstream")} {
+			if directives, err := client.Select(queries...); err == nil {
+				for _, directive := range directives {
+					upstreams = append(upstreams, ParseUpstream(directive, top == "stream"))
+				}
+			}
+		}
+	}
+	return upstreams
+}
+
+func (client *Client) GetUpstream(name string) (*Upstream, error) {
+	_, directive, stream := FindUpstream(client.doc, name)
+	if directive == nil {
+		return nil, ErrNotFound
+	}
+	return ParseUpstream(directive, stream), nil
+}
+
+//添加upstream，Stream为true时添加到stream中
+func (client *Client) NewUpstream(upstream *Upstream) error {
+	if err := upstream.Validate(); err != nil {
+		return err
+	}
+	if _, directive, _ := FindUpstream(client.doc, upstream.Name); directive != nil {
+		return ErrUpstreamExists
+	}
+	top := "http"
+	if upstream.Stream {
+		top = "stream"
+	}
+	return client.Add(Queries(top), upstream.Directive())
+}
+
+//修改upstream的负载均衡方式和server
+func (client *Client) SetUpstream(upstream *Upstream) error {
+	if err := upstream.Validate(); err != nil {
+		return err
+	}
+	_, directive, _ := FindUpstream(client.doc, upstream.Name)
+	if directive == nil {
+		return ErrNotFound
+	}
+	upstream.Apply(directive)
+	return nil
+}
+
+func (client *Client) DeleteUpstream(name string) error {
+	queries, directive, _ := FindUpstream(client.doc, name)
+	if directive == nil {
+		return ErrNotFound
+	}
+	return client.Delete(queries...)
+}
+
+//添加server，地址相同的server将被替换
+func (client *Client) SetUpstreamServer(name string, server *UpstreamServer) error {
+	if server.Address == "" {
+		return errors.New("the address of upstream server is empty")
+	}
+	_, directive, stream := FindUpstream(client.doc, name)
+	if directive == nil {
+		return ErrNotFound
+	}
+	upstream := ParseUpstream(directive, stream)
+	replaced := false
+	for i, s := range upstream.Servers {
+		if s.Address == server.Address {
+			upstream.Servers[i], replaced = server, true
+		}
+	}
+	if !replaced {
+		upstream.Servers = append(upstream.Servers, server)
+	}
+	upstream.Apply(directive)
+	return nil
+}
+
+func (client *Client) RemoveUpstreamServer(name, address string) error {
+	_, directive, stream := FindUpstream(client.doc, name)
+	if directive == nil {
+		return ErrNotFound
+	}
+	upstream := ParseUpstream(directive, stream)
+	servers := make([]*UpstreamServer, 0, len(upstream.Servers))
+	for _, s := range upstream.Servers {
+		if s.Address != address {
+			servers = append(servers, s)
+		}
+	}
+	if len(servers) == len(upstream.Servers) {
+		return ErrNotFound
+	}
+	if len(servers) == 0 {
+		return ErrUpstreamLastServer
+	}
+	upstream.Servers = servers
+	upstream.Apply(directive)
+	return nil
+}
@@ -0,0 +1,58 @@
+package nginx
+
+//WebSocket使用的Connection头，请求没有Upgrade头时为close
+const ConnectionUpgrade = "$connection_upgrade"
+
+//map $http_upgrade $connection_upgrade { default upgrade; '' close; }
+func ConnectionUpgradeMap() *Directive {
+	directive := NewDirective("map", "$http_upgrade", ConnectionUpgrade)
+	directive.AddBody("default", "upgrade")
+	directive.AddBody("''", "close")
+	return directive
+}
+
+func isWebSocketHeader(directive *Directive) bool {
+	return directive.Name == "proxy_set_header" && len(directive.Args) > 0 &&
+		(directive.Args[0] == "Upgrade" || directive.Args[0] == "Connection")
+}
+
+//location是否传递了Upgrade头
+func IsWebSocket(location *Directive) bool {
+	for _, directive := range location.Body {
+		if isWebSocketHeader(directive) && directive.Args[0] == "Upgrade" {
+			return true
+		}
+	}
+	return false
+}
+
+//location开启WebSocket：使用http1.1，传递Upgrade和Connection头，长连接使用较长的超时时间
+func EnableWebSocket(location *Directive) {
+	body := make([]*Directive, 0, len(location.Body))
+	for _, directive := range location.Body {
+		switch {
+		case isWebSocketHeader(directive):
+		case directive.Name == "proxy_http_version", directive.Name == "proxy_read_timeout", directive.Name == "proxy_send_timeout":
+		default:
+			body = append(body, directive)
+		}
+	}
+	location.Body = body
+	location.AddBody("proxy_http_version", "1.1")
+	location.AddBody("proxy_set_header", "Upgrade", "$http_upgrade")
+	location.AddBody("proxy_set_header", "Connection", ConnectionUpgrade)
+	location.AddBody("proxy_read_timeout", "3600s")
+	location.AddBody("proxy_send_timeout", "3600s")
+}
+
+//在http中定义$connection_upgrade，已经定义时不修改
+func (client *Client) SetConnectionUpgrade() error {
+	for _, parent := range client.zoneParents() {
+		for _, directive := range parent.Body {
+			if directive.Name == "map" && len(directive.Args) == 2 && directive.Args[1] == ConnectionUpgrade {
+				return nil
+			}
+		}
+	}
+	return client.Add(Queries("http"), ConnectionUpgradeMap())
+}
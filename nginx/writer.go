@@ -40,16 +40,13 @@ func writeVirtual(directive *Directive, writer Writer, differ Differ) error {
 		switch body.Virtual {
 		case Include:
 			filePath := body.Args[0]
-			content := bytes.NewBufferString("")
 			for _, d := range body.Body {
-				content.WriteString(d.Pretty(0))
-				content.WriteString("\n")
 				if err := writeVirtual(d, writer, differ); err != nil {
 					return err
 				}
 			}
-			if differ(filePath, content.Bytes()) {
-				if err := writer(filePath, content.Bytes()); err != nil {
+			if content := body.BodyBytes(); differ(filePath, content) {
+				if err := writer(filePath, content); err != nil {
 					return err
 				}
 			}
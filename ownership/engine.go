@@ -0,0 +1,77 @@
+package ownership
+
+import (
+	"context"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"os"
+)
+
+//修改文件前检查域名的保护规则
+type ownershipEngine struct {
+	plugins.StorageEngine
+	ownership *Ownership
+	ctx       context.Context
+}
+
+func (o *Ownership) Engine(engine plugins.StorageEngine) plugins.StorageEngine {
+	return &ownershipEngine{StorageEngine: engine, ownership: o, ctx: context.Background()}
+}
+
+func (oe *ownershipEngine) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &ownershipEngine{StorageEngine: plugins.WithContext(ctx, oe.StorageEngine), ownership: oe.ownership, ctx: ctx}
+}
+
+func (oe *ownershipEngine) Context() context.Context {
+	return oe.ctx
+}
+
+func (oe *ownershipEngine) content(file string) ([]byte, error) {
+	cfgFile, err := oe.Get(file)
+	if err == nil {
+		return cfgFile.Content, nil
+	} else if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return nil, err
+}
+
+func (oe *ownershipEngine) Put(file string, content []byte) error {
+	old, err := oe.content(file)
+	if err != nil {
+		return err
+	}
+	if err = oe.ownership.check(oe.ctx, file, old, content); err != nil {
+		return err
+	}
+	return oe.StorageEngine.Put(file, content)
+}
+
+func (oe *ownershipEngine) Remove(file string) error {
+	old, err := oe.content(file)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		err = oe.ownership.check(oe.ctx, file, old, nil)
+	} else if files, searchErr := oe.Search(file + "/*"); searchErr == nil {
+		//可能是文件夹
+		for _, f := range files {
+			if err = oe.ownership.check(oe.ctx, f.Name, f.Content, nil); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return oe.StorageEngine.Remove(file)
+}
+
+func (oe *ownershipEngine) Start() error {
+	return util.StartService(oe.StorageEngine)
+}
+
+func (oe *ownershipEngine) Stop() error {
+	return util.StopService(oe.StorageEngine)
+}
@@ -0,0 +1,215 @@
+package ownership
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("ownership")
+
+const rulesFile = "ownership/rules.json" //域名的保护规则
+
+var ErrProtected = errors.New("the domain is protected")
+
+//域名的保护规则，只有owners中的用户可以修改或者删除包含此域名的server
+type Rule struct {
+	Domain  string    `json:"domain"`
+	Owners  []string  `json:"owners"`
+	User    string    `json:"user,omitempty"` //创建者
+	Created time.Time `json:"created"`
+}
+
+func (r *Rule) owned(user string) bool {
+	for _, owner := range r.Owners {
+		if owner == user {
+			return true
+		}
+	}
+	return false
+}
+
+//正在执行的修改请求
+type request struct {
+	user   string
+	bypass bool //管理员不检查保护规则
+}
+
+type Ownership struct {
+	engine plugins.StorageEngine
+	lock   sync.RWMutex
+	rules  map[string]*Rule
+}
+
+func New(engine plugins.StorageEngine) (*Ownership, error) {
+	o := &Ownership{engine: engine}
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	//集群中其他节点修改后重新加载
+	util.SubscribeChanged(func(event *util.ChangeEvent) {
+		if event.Source != util.ChangeSourceApi && event.File == rulesFile {
+			if err := o.load(); err != nil {
+				logger.WithError(err).Warn("reload ownership rules")
+			}
+		}
+	})
+	return o, nil
+}
+
+func (o *Ownership) load() error {
+	rules := map[string]*Rule{}
+	file, err := o.engine.Get(rulesFile)
+	if err == nil {
+		list := make([]*Rule, 0)
+		if err = json.Unmarshal(file.Content, &list); err != nil {
+			return err
+		}
+		for _, rule := range list {
+			rules[rule.Domain] = rule
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	o.lock.Lock()
+	o.rules = rules
+	o.lock.Unlock()
+	return nil
+}
+
+func (o *Ownership) list() []*Rule {
+	rules := make([]*Rule, 0, len(o.rules))
+	for _, rule := range o.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Domain < rules[j].Domain
+	})
+	return rules
+}
+
+func (o *Ownership) save() error {
+	content, err := json.MarshalIndent(o.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return o.engine.Put(rulesFile, content)
+}
+
+func (o *Ownership) List() []*Rule {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return o.list()
+}
+
+//设置域名的所有者，覆盖原有的规则
+func (o *Ownership) Protect(domain string, owners []string, user string) (*Rule, error) {
+	domain = strings.ToLower(domain)
+	if domain == "" || len(owners) == 0 {
+		return nil, errors.New("the domain and owners is required")
+	}
+	rule := &Rule{Domain: domain, Owners: owners, User: user, Created: time.Now()}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	old, has := o.rules[domain]
+	o.rules[domain] = rule
+	if err := o.save(); err != nil {
+		if has {
+			o.rules[domain] = old
+		} else {
+			delete(o.rules, domain)
+		}
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (o *Ownership) Unprotect(domain string) error {
+	domain = strings.ToLower(domain)
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	rule, has := o.rules[domain]
+	if !has {
+		return os.ErrNotExist
+	}
+	delete(o.rules, domain)
+	if err := o.save(); err != nil {
+		o.rules[domain] = rule
+		return err
+	}
+	return nil
+}
+
+type requestKey struct{}
+
+//开始一个修改请求，bypass为true时（管理员）不检查保护规则。
+//使用返回的context绑定存储引擎（plugins.WithContext）后，修改时检查此请求的用户
+func (o *Ownership) Begin(ctx context.Context, user string, bypass bool) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{user: user, bypass: bypass})
+}
+
+//配置文件中的server，key为server_name中的域名，值为包含此域名的server的内容
+func servers(name string, content []byte) map[string]string {
+	domains := map[string]string{}
+	if content == nil || (filepath.Ext(name) != ".conf" && filepath.Base(name) != nginx.NGINX_CONF) {
+		return domains
+	}
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile(name, content))
+	if err != nil {
+		return domains
+	}
+	var walk func(directives []*nginx.Directive)
+	walk = func(directives []*nginx.Directive) {
+		for _, directive := range directives {
+			if directive.Name != "server" {
+				walk(directive.Body)
+				continue
+			}
+			for _, serverName := range directive.Body {
+				if serverName.Name != "server_name" {
+					continue
+				}
+				for _, domain := range serverName.Args {
+					domains[strings.ToLower(domain)] += directive.Pretty(0)
+				}
+			}
+		}
+	}
+	walk(conf.Body)
+	return domains
+}
+
+//检查修改：包含受保护域名的server被修改、删除或者新增时，当前用户必须是域名的所有者。old和content为nil表示文件不存在
+func (o *Ownership) check(ctx context.Context, name string, old, content []byte) error {
+	current, match := ctx.Value(requestKey{}).(*request)
+	if !match || current.bypass {
+		return nil
+	}
+	//保护规则只能通过管理接口修改
+	if strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), "/") == rulesFile {
+		return fmt.Errorf("%w: %s", ErrProtected, rulesFile)
+	}
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	if len(o.rules) == 0 {
+		return nil
+	}
+	before, after := servers(name, old), servers(name, content)
+	for domain, rule := range o.rules {
+		if before[domain] != after[domain] && !rule.owned(current.user) {
+			return fmt.Errorf("%w: %s is owned by %s", ErrProtected, domain, strings.Join(rule.Owners, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//模拟一个修改请求
+func modify(o *Ownership, engine plugins.StorageEngine, user string, bypass bool, fn func(engine plugins.StorageEngine) error) error {
+	ctx := o.Begin(context.Background(), user, bypass)
+	return fn(plugins.WithContext(ctx, engine))
+}
+
+func TestOwnership(t *testing.T) {
+	storage := memory.Storage{
+		"hosts.d/shop.conf": []byte("server {\n    server_name shop.aginx.io;\n    root /var/www/shop;\n}\n"),
+		"hosts.d/blog.conf": []byte("server {\n    server_name blog.aginx.io;\n}\n"),
+	}
+	o, err := New(storage)
+	assert.Nil(t, err)
+	engine := o.Engine(storage)
+
+	_, err = o.Protect("Shop.Aginx.io", []string{"alice"}, "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, "shop.aginx.io", o.List()[0].Domain)
+	_, err = storage.Get(rulesFile)
+	assert.Nil(t, err)
+
+	modified := []byte("server {\n    server_name shop.aginx.io;\n    root /var/www/other;\n}\n")
+	err = modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Put("hosts.d/shop.conf", modified)
+	})
+	assert.True(t, errors.Is(err, ErrProtected))
+	err = modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Remove("hosts.d/shop.conf")
+	})
+	assert.True(t, errors.Is(err, ErrProtected))
+	err = modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Remove("hosts.d")
+	})
+	assert.True(t, errors.Is(err, ErrProtected))
+	//新增包含受保护域名的server
+	err = modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Put("hosts.d/other.conf", []byte("server { server_name shop.aginx.io; }"))
+	})
+	assert.True(t, errors.Is(err, ErrProtected))
+
+	//通过文件接口覆盖保护规则
+	err = modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Put("./ownership/rules.json", []byte("[]"))
+	})
+	assert.True(t, errors.Is(err, ErrProtected))
+	err = modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Remove(rulesFile)
+	})
+	assert.True(t, errors.Is(err, ErrProtected))
+	assert.True(t, errors.Is(plugins.Files(engine).Put(rulesFile, []byte("[]")), plugins.ErrReserved))
+	assert.Equal(t, 1, len(o.List()))
+
+	//其他域名和不修改受保护server的修改
+	assert.Nil(t, modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Put("hosts.d/blog.conf", []byte("server { server_name blog.aginx.io; root /var/www/blog; }"))
+	}))
+	assert.Nil(t, modify(o, engine, "alice", false, func(engine plugins.StorageEngine) error {
+		return engine.Put("hosts.d/shop.conf", modified)
+	}))
+	assert.Nil(t, modify(o, engine, "admin", true, func(engine plugins.StorageEngine) error {
+		return engine.Remove("hosts.d/shop.conf")
+	}))
+	//不在修改请求中（例如证书续期），即使同时有其他用户的修改请求也不检查
+	assert.Nil(t, modify(o, engine, "bob", false, func(_ plugins.StorageEngine) error {
+		return engine.Put("hosts.d/shop.conf", modified)
+	}))
+
+	assert.Nil(t, o.Unprotect("shop.aginx.io"))
+	assert.Equal(t, 0, len(o.List()))
+	assert.Nil(t, modify(o, engine, "bob", false, func(engine plugins.StorageEngine) error {
+		return engine.Remove("hosts.d/shop.conf")
+	}))
+}
@@ -0,0 +1,92 @@
+package plugins
+
+import (
+	"context"
+	"sync"
+)
+
+//存储引擎的包装（审计、历史版本、审批、保护规则）实现此接口。修改请求把请求信息（用户、审计记录等）保存在context中，
+//通过 WithContext 绑定到存储引擎后，每次修改都能对应到自己的请求；
+//没有绑定context的修改（例如：健康检查、封禁、证书续期）不属于任何请求
+type Contextual interface {
+	WithContext(ctx context.Context) StorageEngine
+	Context() context.Context
+}
+
+//返回绑定ctx的存储引擎，engine不支持时返回engine
+func WithContext(ctx context.Context, engine StorageEngine) StorageEngine {
+	if contextual, match := engine.(Contextual); match {
+		return contextual.WithContext(ctx)
+	}
+	return engine
+}
+
+//存储引擎绑定的context，没有绑定时返回 context.Background()
+func Context(engine StorageEngine) context.Context {
+	if contextual, match := engine.(Contextual); match && contextual.Context() != nil {
+		return contextual.Context()
+	}
+	return context.Background()
+}
+
+type userKey struct{}
+
+//修改请求的用户
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+//context中修改请求的用户，不在修改请求中时为空
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+type batchKey struct{}
+
+//一次操作中的多个修改，存储可以把一些操作推迟到批次结束时执行（例如：git每个修改提交一次，最后只推送一次）
+type batch struct {
+	lock sync.Mutex
+	keys []string
+	fns  map[string]func() error
+}
+
+//在一个批次中执行fn，fn使用参数中的存储修改，结束后执行存储推迟的操作。已经在批次中时使用外层的批次
+func Batch(engine StorageEngine, fn func(engine StorageEngine) error) error {
+	ctx := Context(engine)
+	if _, has := ctx.Value(batchKey{}).(*batch); has {
+		return fn(engine)
+	}
+	b := &batch{fns: make(map[string]func() error)}
+	err := fn(WithContext(context.WithValue(ctx, batchKey{}, b), engine))
+	if flushErr := b.flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+//在批次中时推迟fn到批次结束后执行，同一个key只执行最后一次添加的fn。不在批次中时返回false，由调用者立即执行
+func Defer(ctx context.Context, key string, fn func() error) bool {
+	b, has := ctx.Value(batchKey{}).(*batch)
+	if !has {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, has := b.fns[key]; !has {
+		b.keys = append(b.keys, key)
+	}
+	b.fns[key] = fn
+	return true
+}
+
+func (b *batch) flush() (err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, key := range b.keys {
+		if fnErr := b.fns[key](); fnErr != nil && err == nil {
+			err = fnErr
+		}
+	}
+	return
+}
@@ -65,3 +65,17 @@ type StorageEngine interface {
 	//获取文件
 	Get(file string) (*ConfigurationFile, error)
 }
+
+//集群存储实现此接口时支持leader选举，多个aginx使用同一个存储时只有leader执行证书续期和服务发现
+type Elector interface {
+	//使用node参加选举直到closeC关闭，成为leader时发送true，失去leader时发送false，退出选举时关闭返回的channel
+	Elect(node string, closeC <-chan struct{}) <-chan bool
+}
+
+//集群存储实现此接口时支持节点注册，节点信息不在配置目录中（不会同步为配置文件），节点停止或者宕机后自动删除
+type Membership interface {
+	//注册或者更新节点信息，节点信息保持到closeC关闭
+	Join(node string, info []byte, closeC <-chan struct{}) error
+	//全部注册的节点信息
+	Members() (map[string][]byte, error)
+}
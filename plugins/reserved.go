@@ -0,0 +1,89 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+)
+
+var ErrReserved = errors.New("the file is reserved by aginx")
+
+//aginx自己的数据（api key、审批、历史版本、审计记录、证书等）保存在这些目录中，文件接口不能读写
+var reserved = struct {
+	sync.RWMutex
+	dirs []string
+}{dirs: []string{"keys/", "ownership/", "approval/", "history/", "audit/", "lego/", "access/", "health/"}}
+
+//添加保留目录，例如：审计记录保存在自定义的目录中
+func Reserve(dir string) {
+	reserved.Lock()
+	defer reserved.Unlock()
+	reserved.dirs = append(reserved.dirs, strings.Trim(dir, "/")+"/")
+}
+
+//文件是否在保留目录中，文件名先清理，./keys/a.json 和 conf.d/../keys/a.json 都是保留文件
+func Reserved(file string) bool {
+	file = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(file, "\\", "/")), "/") + "/"
+	reserved.RLock()
+	defer reserved.RUnlock()
+	for _, dir := range reserved.dirs {
+		if strings.HasPrefix(file, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+//文件接口使用的存储：不能读写保留目录中的文件，搜索结果中不包含保留文件
+type filesEngine struct {
+	StorageEngine
+}
+
+func Files(engine StorageEngine) StorageEngine {
+	return &filesEngine{StorageEngine: engine}
+}
+
+func (fe *filesEngine) Put(file string, content []byte) error {
+	if Reserved(file) {
+		return ErrReserved
+	}
+	return fe.StorageEngine.Put(file, content)
+}
+
+func (fe *filesEngine) Remove(file string) error {
+	if Reserved(file) {
+		return ErrReserved
+	}
+	return fe.StorageEngine.Remove(file)
+}
+
+func (fe *filesEngine) Get(file string) (*ConfigurationFile, error) {
+	if Reserved(file) {
+		return nil, ErrReserved
+	}
+	return fe.StorageEngine.Get(file)
+}
+
+func (fe *filesEngine) Search(pattern ...string) ([]*ConfigurationFile, error) {
+	files, err := fe.StorageEngine.Search(pattern...)
+	if err != nil {
+		return nil, err
+	}
+	visible := make([]*ConfigurationFile, 0, len(files))
+	for _, file := range files {
+		if !Reserved(file.Name) {
+			visible = append(visible, file)
+		}
+	}
+	return visible, nil
+}
+
+func (fe *filesEngine) WithContext(ctx context.Context) StorageEngine {
+	return &filesEngine{StorageEngine: WithContext(ctx, fe.StorageEngine)}
+}
+
+func (fe *filesEngine) Context() context.Context {
+	return Context(fe.StorageEngine)
+}
@@ -0,0 +1,51 @@
+package plugins_test
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReserved(t *testing.T) {
+	assert.True(t, plugins.Reserved("keys/x.json"))
+	assert.True(t, plugins.Reserved("./keys/x.json"))
+	assert.True(t, plugins.Reserved("/keys/x.json"))
+	assert.True(t, plugins.Reserved("conf.d/../keys/x.json"))
+	assert.True(t, plugins.Reserved("keys//x.json"))
+	assert.True(t, plugins.Reserved("keys"))
+	assert.True(t, plugins.Reserved("lego/certificates/aginx.io.json"))
+	assert.True(t, plugins.Reserved("ownership/rules.json"))
+	assert.False(t, plugins.Reserved("keys.conf"))
+	assert.False(t, plugins.Reserved("conf.d/keys/x.json"))
+
+	plugins.Reserve("/records/")
+	assert.True(t, plugins.Reserved("records/2020-01-01.log"))
+}
+
+//编辑者通过文件接口写入管理员角色的api key
+func TestFilesEngine(t *testing.T) {
+	storage := memory.Storage{
+		"nginx.conf":  []byte("http {}"),
+		"keys/a.json": []byte(`{"id":"a","role":"admin","hash":"..."}`),
+	}
+	files := plugins.Files(storage)
+
+	admin := []byte(`{"id":"x","role":"admin","hash":"..."}`)
+	assert.True(t, errors.Is(files.Put("keys/x.json", admin), plugins.ErrReserved))
+	assert.True(t, errors.Is(files.Put("conf.d/../keys/x.json", admin), plugins.ErrReserved))
+	_, has := storage["keys/x.json"]
+	assert.False(t, has)
+
+	assert.True(t, errors.Is(files.Remove("keys/a.json"), plugins.ErrReserved))
+	_, err := files.Get("keys/a.json")
+	assert.True(t, errors.Is(err, plugins.ErrReserved))
+	found, err := files.Search("*")
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "nginx.conf", found[0].Name)
+
+	assert.Nil(t, files.Put("conf.d/api.conf", []byte("server {}")))
+	assert.Equal(t, "server {}", string(storage["conf.d/api.conf"]))
+}
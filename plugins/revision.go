@@ -0,0 +1,32 @@
+package plugins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var ErrConflict = errors.New("the configuration has been modified by others")
+
+//文件的版本，使用内容的sha256
+func Revision(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+type expectKey struct {
+	file string
+}
+
+//修改file时期望的存储中文件的版本，期望文件不存在时版本为空。
+//支持比较后写入的存储（例如：consul、etcd）在修改时检查版本，和存储中的版本不同时返回ErrConflict
+func Expect(ctx context.Context, file, revision string) context.Context {
+	return context.WithValue(ctx, expectKey{file: file}, revision)
+}
+
+//修改file时期望的版本，has为false时不检查版本
+func Expected(ctx context.Context, file string) (revision string, has bool) {
+	revision, has = ctx.Value(expectKey{file: file}).(string)
+	return
+}
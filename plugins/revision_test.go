@@ -0,0 +1,22 @@
+package plugins_test
+
+import (
+	"context"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExpect(t *testing.T) {
+	ctx := plugins.Expect(context.Background(), "nginx.conf", plugins.Revision([]byte("http {}")))
+	ctx = plugins.Expect(ctx, "hosts.d/a.conf", "")
+
+	revision, has := plugins.Expected(ctx, "nginx.conf")
+	assert.True(t, has)
+	assert.Equal(t, plugins.Revision([]byte("http {}")), revision)
+	revision, has = plugins.Expected(ctx, "hosts.d/a.conf")
+	assert.True(t, has)
+	assert.Equal(t, "", revision)
+	_, has = plugins.Expected(ctx, "hosts.d/b.conf")
+	assert.False(t, has)
+}
@@ -1,13 +1,13 @@
 package bridge
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/ihaiker/aginx/api"
 	"github.com/ihaiker/aginx/logs"
 	"github.com/ihaiker/aginx/nginx"
 	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/registry/functions"
+	"github.com/ihaiker/aginx/templates"
 	"github.com/ihaiker/aginx/util"
 	"io/ioutil"
 	"os"
@@ -17,38 +17,6 @@ import (
 
 var logger = logs.New("registry", "module", "bridge")
 
-const default_template = `
-upstream {{ upstreamName .Data.Domain }} { {{range .Data.Servers}}
-	server {{.Address}} {{if ne .Weight 0}} weight={{.Weight}}{{end}};{{end}}
-}
-{{if .Data.AutoSSL}}server {
-	listen       80;
-	server_name {{.Data.Domain}};	
-	return 301 https://$host$request_uri;
-}{{end}}
-server { {{if .Data.AutoSSL}}
-	listen 443 ssl;
-	ssl_certificate     {{.Data.SSL.Certificate}};        
-	ssl_certificate_key {{.Data.SSL.PrivateKey}};
-	ssl_session_timeout 5m;
-	ssl_ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE:ECDH:AES:HIGH:!NULL:!aNULL:!MD5:!ADH:!RC4;
-	ssl_protocols TLSv1 TLSv1.1 TLSv1.2;
-	ssl_prefer_server_ciphers on; {{else}}
-	listen 80; {{end}}
-
-    server_name {{.Data.Domain}};
-    try_files $uri @tornado;
-
-    location @tornado {
-        proxy_set_header        X-Scheme        $scheme;
-        proxy_set_header        Host            $host;
-        proxy_set_header        X-Real-IP       $remote_addr;
-        proxy_set_header        X-Forwarded-For $proxy_add_x_forwarded_for;
-        proxy_pass http://{{ upstreamName .Data.Domain }};
-    }
-}
-`
-
 type LabelRegisterBridge struct {
 	Aginx api.Aginx
 	plugins.Register
@@ -68,14 +36,18 @@ func (rb *LabelRegisterBridge) listenChange() error {
 						if len(servers) == 0 {
 							relPath := fmt.Sprintf("%s.d/%s.ngx.conf", rb.Name, domain)
 							logger.Info("Publishing service changes ", domain, " remove ", relPath)
-							if err := rb.Aginx.File().Remove(relPath); err != nil {
+							err := rb.Aginx.File().Remove(relPath)
+							if err != nil {
 								logger.WithError(err).Warn("Publishing service changes ", domain, " remove ", relPath)
 							}
+							util.PublishEvent(util.EventUpstreamChange, domain, err, servers)
 						} else {
 							logger.Info("Publishing service changes ", domain)
-							if err := rb.publishServer(domain, servers); err != nil {
+							err := rb.publishServer(domain, servers)
+							if err != nil {
 								logger.Warn("Publishing service changes ", domain, " error ", err)
 							}
+							util.PublishEvent(util.EventUpstreamChange, domain, err, servers)
 						}
 					}
 					_ = rb.Aginx.Reload()
@@ -100,64 +72,64 @@ func (rb *LabelRegisterBridge) createInclude() (err error) {
 	return
 }
 
-func (rb *LabelRegisterBridge) findTemplate(domain string) string {
-	{
-		configDir := nginx.MustConfigDir()
-		localDomainTemplate := filepath.Join(configDir, rb.TemplateDir, domain+".ngx.tpl")
-		if content, err := ioutil.ReadFile(localDomainTemplate); err == nil {
-			return string(content)
-		} else if !os.IsNotExist(err) {
-			logger.Warn("read template file ", localDomainTemplate, " error ", err)
-		}
+//查找模板，依次查找：域名模板、标签指定的模板（aginx.template）、用户定义的全局模板、所有服务发现共用的模板，
+//每个模板先查找本地文件再查找存储，标签指定的模板都不存在时使用内置模板库
+func (rb *LabelRegisterBridge) findTemplate(domain, name string) string {
+	domainTemplatePath := filepath.Join(rb.TemplateDir, domain+".ngx.tpl")      //针对domain服务的模板
+	userDefinedTemplatePath := filepath.Join(rb.TemplateDir, "default.ngx.tpl") //用户定义的全局模板
+	serverTemplatePath := templates.Path(templates.Server)                      //所有服务发现共用的模板
+
+	paths := []string{domainTemplatePath}
+	if name != "" {
+		paths = append(paths, templates.Path(name))
+	}
+	paths = append(paths, userDefinedTemplatePath, serverTemplatePath)
+
+	templateFiles, err := rb.Aginx.File().Search(paths...)
+	if err != nil {
+		logger.Warnf("read store template(%s) file error: %s", rb.TemplateDir, err)
+		templateFiles = map[string]string{}
+	}
 
-		localDomainTemplate = filepath.Join(configDir, rb.TemplateDir, "default.ngx.tpl")
-		if content, err := ioutil.ReadFile(localDomainTemplate); err == nil {
+	configDir := nginx.MustConfigDir()
+	for _, path := range paths {
+		localTemplate := filepath.Join(configDir, path)
+		if content, err := ioutil.ReadFile(localTemplate); err == nil {
 			return string(content)
 		} else if !os.IsNotExist(err) {
-			logger.Warn("read template file ", localDomainTemplate, " error ", err)
+			logger.Warn("read template file ", localTemplate, " error ", err)
 		}
-	}
-
-	{
-		domainTemplatePath := filepath.Join(rb.TemplateDir, domain+".ngx.tpl")      //针对domain服务的模板
-		userDefinedTemplatePath := filepath.Join(rb.TemplateDir, "default.ngx.tpl") //用户定义的全局模板
-		if templateFiles, err := rb.Aginx.File().Search(domainTemplatePath, userDefinedTemplatePath); err != nil {
-			logger.Warnf("read store template(%s) file error: %s", rb.TemplateDir, err)
-			return default_template
-		} else if r1, has := templateFiles[domainTemplatePath]; has {
-			return r1
-		} else if r2, has := templateFiles[userDefinedTemplatePath]; has {
-			return r2
+		if content, has := templateFiles[path]; has {
+			return content
+		}
+		if path == templates.Path(name) {
+			if content, has := templates.Library[name]; has {
+				return content
+			}
+			logger.Warnf("the template %s of %s not found", name, domain)
 		}
 	}
-
-	return default_template
+	return templates.DefaultServer
 }
 
 func (rb *LabelRegisterBridge) publishServer(domain string, servers plugins.Domains) error {
-	autoSsl := servers[0].AutoSSL
-	data := map[string]interface{}{
-		"Domain":  domain,
-		"AutoSSL": autoSsl,
-		"Servers": servers,
-	}
-	if autoSsl {
+	data := templates.NewData(domain, servers[0].AutoSSL, servers)
+	if data.AutoSSL {
 		if certFile, err := rb.Aginx.SSL().New("", domain); err != nil {
 			return err
 		} else {
-			data["SSL"] = certFile
+			data.SSL = certFile
 		}
 	}
-	templateFile := rb.findTemplate(domain)
+	templateFile := rb.findTemplate(domain, data.Template)
 	funcs := functions.Merge(rb.AppendTemplateFuncMap, rb.TemplateFuncMap())
-	out := bytes.NewBufferString("")
-	if t, err := template.New("").Funcs(funcs).Parse(templateFile); err != nil {
+	if out, err := templates.Render(templateFile, funcs, Data(rb.Aginx, data)); err != nil {
 		return err
-	} else if err := t.Execute(out, Data(rb.Aginx, data)); err != nil {
+	} else if out, err = templates.Override(out, domain, data.Directives); err != nil {
 		return err
 	} else {
 		relPath := fmt.Sprintf("%s.d/%s.ngx.conf", rb.Name, domain)
-		return rb.Aginx.File().NewWithContent(relPath, util.CleanEmptyLine(out.Bytes()))
+		return rb.Aginx.File().NewWithContent(relPath, out)
 	}
 }
 
@@ -74,11 +74,13 @@ func (self *TemplateRegisterBridge) listenChange() {
 		select {
 		case event, has := <-self.Register.Listener():
 			if has {
-				if err := self.publishEvent(event); err != nil {
+				err := self.publishEvent(event)
+				if err != nil {
 					logger.Warn("publish error: ", err)
 				} else {
 					_ = self.Aginx.Reload()
 				}
+				util.PublishEvent(util.EventUpstreamChange, self.Name, err, nil)
 			}
 		}
 	}
@@ -0,0 +1,61 @@
+package cloud
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestFindLabel(t *testing.T) {
+	assert.Equal(t, &Label{Domain: "api.aginx.io", Weight: 2, AutoSSL: true},
+		FindLabel(map[string]string{"aginx.domain": "api.aginx.io,weight=2,ssl"}, "api", ""))
+	assert.Equal(t, &Label{Domain: "user-service.aginx.io"}, FindLabel(nil, "DEFAULT_GROUP@@USER-SERVICE", "*.aginx.io"))
+	assert.Nil(t, FindLabel(nil, "api", ""))
+}
+
+func TestNacos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "dev", r.URL.Query().Get("namespaceId"))
+		switch r.URL.Path {
+		case "/nacos/v1/ns/service/list":
+			_, _ = w.Write([]byte(`{"count":1,"doms":["user"]}`))
+		case "/nacos/v1/ns/instance/list":
+			_, _ = w.Write([]byte(`{"hosts":[
+				{"ip":"10.0.0.1","port":8080,"weight":2.0,"healthy":true,"enabled":true,"metadata":{}},
+				{"ip":"10.0.0.2","port":8080,"weight":1.0,"healthy":true,"enabled":false,"metadata":{}}
+			]}`))
+		}
+	}))
+	defer server.Close()
+
+	config, _ := url.Parse(server.URL + "/dev?domain=*.aginx.io")
+	config.Scheme = "nacos"
+	domains, err := newNacos(config).Instances()
+	assert.Nil(t, err)
+	assert.Len(t, domains, 1)
+	assert.Equal(t, "user.aginx.io", domains[0].Domain)
+	assert.Equal(t, "10.0.0.1:8080", domains[0].Address)
+	assert.Equal(t, 2, domains[0].Weight)
+}
+
+func TestEureka(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/eureka/apps", r.URL.Path)
+		_, _ = w.Write([]byte(`{"applications":{"application":[{"name":"USER","instance":[
+			{"instanceId":"user:1","ipAddr":"10.0.0.1","status":"UP","port":{"$":8080,"@enabled":"true"},"metadata":{"aginx.domain":"user.aginx.io,ssl"}},
+			{"instanceId":"user:2","ipAddr":"10.0.0.2","status":"DOWN","port":{"$":8080,"@enabled":"true"},"metadata":{"aginx.domain":"user.aginx.io,ssl"}}
+		]}]}}`))
+	}))
+	defer server.Close()
+
+	config, _ := url.Parse(server.URL)
+	config.Scheme = "eureka"
+	domains, err := newEureka(config).Instances()
+	assert.Nil(t, err)
+	assert.Len(t, domains, 1)
+	assert.Equal(t, "user.aginx.io", domains[0].Domain)
+	assert.Equal(t, "10.0.0.1:8080", domains[0].Address)
+	assert.True(t, domains[0].AutoSSL)
+}
@@ -0,0 +1,101 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//eureka rest api，eureka://[user:password@]127.0.0.1:8761/eureka?domain=*.example.com
+type eureka struct {
+	address        string
+	username       string
+	password       string
+	domainTemplate string
+	client         *http.Client
+}
+
+func newEureka(config *url.URL) *eureka {
+	scheme := "http"
+	if config.Query().Get("ssl") == "true" {
+		scheme = "https"
+	}
+	path := strings.TrimSuffix(config.Path, "/")
+	if path == "" {
+		path = "/eureka"
+	}
+	e := &eureka{
+		address:        fmt.Sprintf("%s://%s%s", scheme, config.Host, path),
+		domainTemplate: config.Query().Get("domain"),
+		client:         &http.Client{Timeout: time.Second * 10},
+	}
+	if config.User != nil {
+		e.username = config.User.Username()
+		e.password, _ = config.User.Password()
+	}
+	return e
+}
+
+func (e *eureka) String() string {
+	return e.address
+}
+
+func (e *eureka) Instances() (plugins.Domains, error) {
+	req, err := http.NewRequest(http.MethodGet, e.address+"/apps", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("eureka apps: %s", resp.Status)
+	}
+
+	result := &struct {
+		Applications struct {
+			Application []struct {
+				Name     string `json:"name"`
+				Instance []struct {
+					InstanceId string `json:"instanceId"`
+					IpAddr     string `json:"ipAddr"`
+					Status     string `json:"status"`
+					Port       struct {
+						Port int `json:"$"`
+					} `json:"port"`
+					Metadata map[string]string `json:"metadata"`
+				} `json:"instance"`
+			} `json:"application"`
+		} `json:"applications"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+
+	domains := plugins.Domains{}
+	for _, application := range result.Applications.Application {
+		for _, instance := range application.Instance {
+			if instance.Status != "UP" {
+				continue
+			}
+			if label := FindLabel(instance.Metadata, application.Name, e.domainTemplate); label != nil {
+				domains = append(domains, plugins.Domain{
+					ID: application.Name + "/" + instance.InstanceId, Domain: label.Domain,
+					Address: fmt.Sprintf("%s:%d", instance.IpAddr, instance.Port.Port),
+					Weight:  label.Weight, AutoSSL: label.AutoSSL, Attrs: instance.Metadata,
+				})
+			}
+		}
+	}
+	return domains, nil
+}
@@ -0,0 +1,58 @@
+package cloud
+
+import (
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"net/url"
+	"time"
+)
+
+//nacos和eureka使用相同的参数
+func AddRegistryFlags(cmd *cobra.Command) {
+	if cmd.PersistentFlags().Lookup("registry") != nil {
+		return
+	}
+	cmd.PersistentFlags().StringArrayP("registry", "", []string{}, `Automatically obtain nacos or eureka registered services and publish them to NGINX.
+example: nacos://[user:password@]127.0.0.1:8848/namespace?group=DEFAULT_GROUP&domain=*.example.com
+         eureka://[user:password@]127.0.0.1:8761/eureka?domain=*.example.com`)
+	cmd.PersistentFlags().DurationP("registry-interval", "", time.Second*5, "Interval to sync the services of nacos or eureka.")
+}
+
+func loadRegistry(scheme string, newSource func(*url.URL) source) func(cmd *cobra.Command) (plugins.Register, error) {
+	return func(cmd *cobra.Command) (plugins.Register, error) {
+		sources := make([]source, 0)
+		for _, registry := range util.GetStringArray(cmd, "registry") {
+			config, err := url.Parse(registry)
+			if err != nil {
+				return nil, err
+			}
+			if config.Scheme == scheme {
+				sources = append(sources, newSource(config))
+			}
+		}
+		if len(sources) == 0 {
+			return nil, nil
+		}
+		return newRegister(viper.GetDuration("registry-interval"), sources...), nil
+	}
+}
+
+var NacosPlugin = &plugins.RegistryPlugin{
+	Name: "nacos",
+	LoadRegistry: loadRegistry("nacos", func(config *url.URL) source {
+		return newNacos(config)
+	}),
+	AddRegistryFlags: AddRegistryFlags,
+	Support:          plugins.RegistrySupportLabel,
+}
+
+var EurekaPlugin = &plugins.RegistryPlugin{
+	Name: "eureka",
+	LoadRegistry: loadRegistry("eureka", func(config *url.URL) source {
+		return newEureka(config)
+	}),
+	AddRegistryFlags: AddRegistryFlags,
+	Support:          plugins.RegistrySupportLabel,
+}
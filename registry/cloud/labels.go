@@ -0,0 +1,38 @@
+package cloud
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var labelKeys = []string{"aginx.domain", "aginx-domain"}
+var valueRegexp = regexp.MustCompile("([a-zA-Z0-9-_\\.]*)(,(weight=(\\d+)))?(,(ssl))?")
+
+type Label struct {
+	Domain  string
+	Weight  int
+	AutoSSL bool
+}
+
+//从实例的metadata中查找，未找到时如果设置了域名模板（例如：*.example.com）使用服务名称替换*生成
+func FindLabel(metadata map[string]string, service, domainTemplate string) *Label {
+	for _, key := range labelKeys {
+		if value, has := metadata[key]; has {
+			groups := valueRegexp.FindStringSubmatch(value)
+			if groups[1] == "" {
+				return nil
+			}
+			weight, _ := strconv.Atoi(groups[4])
+			return &Label{Domain: groups[1], Weight: weight, AutoSSL: groups[6] == "ssl"}
+		}
+	}
+	if domainTemplate != "" {
+		//nacos的服务名称可能带有分组 DEFAULT_GROUP@@service
+		if idx := strings.LastIndex(service, "@@"); idx != -1 {
+			service = service[idx+2:]
+		}
+		return &Label{Domain: strings.Replace(domainTemplate, "*", strings.ToLower(service), 1)}
+	}
+	return nil
+}
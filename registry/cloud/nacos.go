@@ -0,0 +1,160 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ihaiker/aginx/plugins"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//nacos open api，nacos://[user:password@]127.0.0.1:8848/namespace?group=DEFAULT_GROUP&domain=*.example.com
+type nacos struct {
+	address        string
+	namespace      string
+	group          string
+	username       string
+	password       string
+	domainTemplate string
+	client         *http.Client
+
+	accessToken string
+	expireAt    time.Time
+}
+
+func newNacos(config *url.URL) *nacos {
+	scheme := "http"
+	if config.Query().Get("ssl") == "true" {
+		scheme = "https"
+	}
+	n := &nacos{
+		address:        fmt.Sprintf("%s://%s/nacos", scheme, config.Host),
+		namespace:      strings.Trim(config.Path, "/"),
+		group:          config.Query().Get("group"),
+		domainTemplate: config.Query().Get("domain"),
+		client:         &http.Client{Timeout: time.Second * 10},
+	}
+	if config.User != nil {
+		n.username = config.User.Username()
+		n.password, _ = config.User.Password()
+	}
+	return n
+}
+
+func (n *nacos) String() string {
+	return n.address + "/" + n.namespace
+}
+
+func (n *nacos) login() error {
+	if n.username == "" || time.Now().Before(n.expireAt) {
+		return nil
+	}
+	resp, err := n.client.PostForm(n.address+"/v1/auth/login", url.Values{"username": {n.username}, "password": {n.password}})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nacos login: %s", resp.Status)
+	}
+	result := &struct {
+		AccessToken string `json:"accessToken"`
+		TokenTtl    int64  `json:"tokenTtl"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return err
+	}
+	n.accessToken = result.AccessToken
+	//提前刷新token
+	n.expireAt = time.Now().Add(time.Duration(result.TokenTtl)*time.Second - time.Minute)
+	return nil
+}
+
+func (n *nacos) get(path string, params url.Values, out interface{}) error {
+	if err := n.login(); err != nil {
+		return err
+	}
+	if n.namespace != "" {
+		params.Set("namespaceId", n.namespace)
+	}
+	if n.group != "" {
+		params.Set("groupName", n.group)
+	}
+	if n.accessToken != "" {
+		params.Set("accessToken", n.accessToken)
+	}
+	resp, err := n.client.Get(n.address + path + "?" + params.Encode())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nacos %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (n *nacos) services() ([]string, error) {
+	services := make([]string, 0)
+	for page := 1; ; page++ {
+		result := &struct {
+			Count int      `json:"count"`
+			Doms  []string `json:"doms"`
+		}{}
+		if err := n.get("/v1/ns/service/list", url.Values{
+			"pageNo": {fmt.Sprint(page)}, "pageSize": {"500"},
+		}, result); err != nil {
+			return nil, err
+		}
+		services = append(services, result.Doms...)
+		if len(result.Doms) == 0 || len(services) >= result.Count {
+			return services, nil
+		}
+	}
+}
+
+func (n *nacos) Instances() (plugins.Domains, error) {
+	services, err := n.services()
+	if err != nil {
+		return nil, err
+	}
+	domains := plugins.Domains{}
+	for _, service := range services {
+		result := &struct {
+			Hosts []struct {
+				InstanceId string            `json:"instanceId"`
+				Ip         string            `json:"ip"`
+				Port       int               `json:"port"`
+				Weight     float64           `json:"weight"`
+				Healthy    bool              `json:"healthy"`
+				Enabled    bool              `json:"enabled"`
+				Metadata   map[string]string `json:"metadata"`
+			} `json:"hosts"`
+		}{}
+		if err := n.get("/v1/ns/instance/list", url.Values{
+			"serviceName": {service}, "healthyOnly": {"true"},
+		}, result); err != nil {
+			return nil, err
+		}
+		for _, host := range result.Hosts {
+			if !host.Healthy || !host.Enabled {
+				continue
+			}
+			if label := FindLabel(host.Metadata, service, n.domainTemplate); label != nil {
+				weight := label.Weight
+				if weight == 0 && host.Weight != 1 {
+					weight = int(math.Round(host.Weight))
+				}
+				address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+				domains = append(domains, plugins.Domain{
+					ID: service + "/" + address, Domain: label.Domain, Address: address,
+					Weight: weight, AutoSSL: label.AutoSSL, Attrs: host.Metadata,
+				})
+			}
+		}
+	}
+	return domains, nil
+}
@@ -0,0 +1,110 @@
+package cloud
+
+import (
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"text/template"
+	"time"
+)
+
+var logger = logs.New("register", "engine", "cloud")
+
+type source interface {
+	Instances() (plugins.Domains, error)
+	String() string
+}
+
+//定时查询注册中心的服务实例，变化后发布到nginx
+type CloudRegister struct {
+	sources  []source
+	interval time.Duration
+	events   chan interface{}
+	closeC   chan struct{}
+	services plugins.Domains
+}
+
+func newRegister(interval time.Duration, sources ...source) *CloudRegister {
+	return &CloudRegister{
+		sources: sources, interval: interval,
+		events: make(chan interface{}, 10), closeC: make(chan struct{}),
+	}
+}
+
+func (self *CloudRegister) TemplateFuncMap() template.FuncMap {
+	return template.FuncMap{}
+}
+
+func (self *CloudRegister) Support() plugins.RegistrySupport {
+	return plugins.RegistrySupportLabel
+}
+
+func (self *CloudRegister) Listener() <-chan interface{} {
+	return self.events
+}
+
+func (self *CloudRegister) find(domains plugins.Domains, search plugins.Domain) bool {
+	for _, domain := range domains {
+		if domain.ID == search.ID && domain.Domain == search.Domain && domain.Address == search.Address &&
+			domain.Weight == search.Weight && domain.AutoSSL == search.AutoSSL {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *CloudRegister) allServices() {
+	searchServices := plugins.Domains{}
+	for _, s := range self.sources {
+		domains, err := s.Instances()
+		if err != nil {
+			//查询失败时不更新，防止删除所有的服务
+			logger.Warn("search services from ", s, " error: ", err)
+			return
+		}
+		searchServices = append(searchServices, domains...)
+	}
+
+	changed := map[string]bool{}
+	for _, service := range searchServices {
+		if !self.find(self.services, service) {
+			changed[service.Domain] = true
+		}
+	}
+	for _, service := range self.services {
+		if !self.find(searchServices, service) {
+			changed[service.Domain] = true
+		}
+	}
+	if len(changed) > 0 {
+		event := plugins.LabelsRegistryEvent{}
+		groups := searchServices.Group()
+		for domain := range changed {
+			event[domain] = groups[domain]
+		}
+		self.events <- event
+	}
+	self.services = searchServices
+}
+
+func (self *CloudRegister) Start() error {
+	go func() {
+		defer close(self.events)
+		ticker := time.NewTicker(self.interval)
+		defer ticker.Stop()
+		self.allServices()
+		for {
+			select {
+			case <-self.closeC:
+				return
+			case <-ticker.C:
+				self.allServices()
+			}
+		}
+	}()
+	return nil
+}
+
+func (self *CloudRegister) Stop() error {
+	close(self.closeC)
+	return nil
+}
@@ -3,26 +3,61 @@ package consulLabels
 import (
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 var keyRegexp = "aginx-domain"
 var valueRegexp = regexp.MustCompile("([a-zA-Z0-9-_\\.]*)(,(weight=(\\d+)))?(,(ssl))?")
 
+//tag使用的前缀，consul的meta名称不支持.
+var tagPrefixes = []string{"aginx.domain=", "aginx-domain="}
+
 type Label struct {
 	Domain  string
 	Weight  int
 	AutoSSL bool
 }
 
-func FindLabel(labs map[string]string) *Label {
-	label, has := labs[keyRegexp]
-	if !has {
+func parseLabel(label string) *Label {
+	groups := valueRegexp.FindStringSubmatch(label)
+	if groups[1] == "" {
 		return nil
 	}
-	groups := valueRegexp.FindStringSubmatch(label)
 	weight, _ := strconv.Atoi(groups[4])
 	return &Label{
 		Domain: groups[1], Weight: weight,
 		AutoSSL: groups[6] == "ssl",
 	}
 }
+
+//从服务的meta(aginx-domain)或者tags(aginx.domain=api.aginx.io)中查找
+func FindLabel(labs map[string]string, tags []string) *Label {
+	if label, has := labs[keyRegexp]; has {
+		return parseLabel(label)
+	}
+	for _, tag := range tags {
+		for _, prefix := range tagPrefixes {
+			if strings.HasPrefix(tag, prefix) {
+				return parseLabel(tag[len(prefix):])
+			}
+		}
+	}
+	return nil
+}
+
+//服务的meta和tags中 aginx.<name>=<value> 形式的标签合并，模板选择和指令覆盖使用，例如：aginx.template=grpc
+func FindAttrs(meta map[string]string, tags []string) map[string]string {
+	attrs := map[string]string{}
+	for key, value := range meta {
+		attrs[key] = value
+	}
+	for _, tag := range tags {
+		if (strings.HasPrefix(tag, "aginx.") || strings.HasPrefix(tag, "aginx-")) && strings.Contains(tag, "=") {
+			idx := strings.Index(tag, "=")
+			if _, has := attrs[tag[:idx]]; !has {
+				attrs[tag[:idx]] = tag[idx+1:]
+			}
+		}
+	}
+	return attrs
+}
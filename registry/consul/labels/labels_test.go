@@ -0,0 +1,20 @@
+package consulLabels
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFindLabel(t *testing.T) {
+	assert.Equal(t, &Label{Domain: "api.aginx.io", Weight: 2}, FindLabel(map[string]string{"aginx-domain": "api.aginx.io,weight=2"}, nil))
+	assert.Equal(t, &Label{Domain: "api.aginx.io", AutoSSL: true}, FindLabel(nil, []string{"v1", "aginx.domain=api.aginx.io,ssl"}))
+	assert.Nil(t, FindLabel(map[string]string{"version": "1"}, []string{"v1"}))
+	assert.Nil(t, FindLabel(nil, []string{"aginx.domain="}))
+}
+
+func TestFindAttrs(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"aginx-domain": "api.aginx.io", "aginx.template": "grpc", "aginx.client_max_body_size": "100m",
+	}, FindAttrs(map[string]string{"aginx-domain": "api.aginx.io", "aginx.template": "grpc"},
+		[]string{"v1", "aginx.template=websocket", "aginx.client_max_body_size=100m"}))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/ihaiker/aginx/logs"
 	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/util"
+	"reflect"
 	"text/template"
 	"time"
 )
@@ -15,6 +16,7 @@ var logger = logs.New("register", "engine", "consul.labels")
 type ConsulLabelRegister struct {
 	consul   *consulApi.Client
 	events   chan interface{}
+	closeC   chan struct{}
 	lastIdx  uint64
 	services plugins.Domains
 }
@@ -25,7 +27,7 @@ func (self *ConsulLabelRegister) TemplateFuncMap() template.FuncMap {
 
 func NewLabelRegister(consul *consulApi.Client) *ConsulLabelRegister {
 	return &ConsulLabelRegister{
-		consul: consul, events: make(chan interface{}, 10),
+		consul: consul, events: make(chan interface{}, 10), closeC: make(chan struct{}),
 	}
 }
 
@@ -39,6 +41,7 @@ func (self *ConsulLabelRegister) allServices() {
 	})
 	if err != nil {
 		logger.Warn("list services ", err)
+		time.Sleep(time.Second * 3)
 		return
 	}
 
@@ -49,19 +52,24 @@ func (self *ConsulLabelRegister) allServices() {
 			continue
 		} else {
 			for _, serviceEntry := range catalogServiceEntries {
-				if label := FindLabel(serviceEntry.Service.Meta); label != nil {
+				if label := FindLabel(serviceEntry.Service.Meta, serviceEntry.Service.Tags); label != nil {
 					if serviceEntry.Checks.AggregatedStatus() == consulApi.HealthPassing {
 						weight := label.Weight
 						if weight == 0 {
 							weight = serviceEntry.Service.Weights.Passing
 						}
+						//服务未设置地址时使用节点地址
+						address := serviceEntry.Service.Address
+						if address == "" {
+							address = serviceEntry.Node.Address
+						}
 						searchServices = append(searchServices, plugins.Domain{
-							ID:      serviceEntry.Service.ID,
+							ID:      serviceEntry.Node.Node + "/" + serviceEntry.Service.ID,
 							Domain:  label.Domain,
-							Address: fmt.Sprintf("%s:%d", serviceEntry.Service.Address, serviceEntry.Service.Port),
+							Address: fmt.Sprintf("%s:%d", address, serviceEntry.Service.Port),
 							Weight:  weight,
 							AutoSSL: label.AutoSSL,
-							Attrs:   serviceEntry.Service.Meta,
+							Attrs:   FindAttrs(serviceEntry.Service.Meta, serviceEntry.Service.Tags),
 						})
 					}
 				}
@@ -100,9 +108,12 @@ func (self *ConsulLabelRegister) allServices() {
 	self.services = searchServices
 }
 
+//服务地址、权重变化后也需要重新发布
 func (self *ConsulLabelRegister) find(domains plugins.Domains, search plugins.Domain) bool {
 	for _, domain := range domains {
-		if domain.ID == search.ID {
+		if domain.ID == search.ID && domain.Domain == search.Domain && domain.Address == search.Address &&
+			domain.Weight == search.Weight && domain.AutoSSL == search.AutoSSL &&
+			reflect.DeepEqual(domain.Attrs, search.Attrs) {
 			return true
 		}
 	}
@@ -111,15 +122,21 @@ func (self *ConsulLabelRegister) find(domains plugins.Domains, search plugins.Do
 
 func (self *ConsulLabelRegister) Start() error {
 	go func() {
+		defer close(self.events)
 		for {
-			self.allServices()
+			select {
+			case <-self.closeC:
+				return
+			default:
+				self.allServices()
+			}
 		}
 	}()
 	return nil
 }
 
 func (self *ConsulLabelRegister) Stop() error {
-	close(self.events)
+	close(self.closeC)
 	return nil
 }
 
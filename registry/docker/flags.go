@@ -11,6 +11,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
 func AddRegistryFlags(cmd *cobra.Command) {
@@ -29,6 +30,7 @@ and AGINX flags --docker-host, --docker-tls-verify, --docker-cert-path, --docker
 	cmd.PersistentFlags().StringArrayP("docker-container-filter", "", []string{".*"}, "Filtering containers that need attention, see regexp")
 
 	cmd.PersistentFlags().StringP("docker-ip", "", "", `IP for ports mapped to the host`)
+	cmd.PersistentFlags().DurationP("docker-swarm-sync", "", time.Second*10, `Interval to sync the tasks of swarm services, 0 to disable.`)
 }
 
 func dockerEnv(keys ...string) {
@@ -64,7 +66,7 @@ func LoadRegistry(cmd *cobra.Command) (plugins.Register, error) {
 		return dockerTemplates.TemplateRegister(ip, filterServices, filterContainers)
 	}
 
-	return dockerLabels.LabelsRegister(ip)
+	return dockerLabels.LabelsRegister(ip, viper.GetDuration("docker-swarm-sync"))
 }
 
 var Plugin = &plugins.RegistryPlugin{
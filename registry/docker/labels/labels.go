@@ -49,5 +49,13 @@ func FindLabels(labs map[string]string, ignoreSwarmService bool) Labels {
 			labels[label.Port] = label
 		}
 	}
+	//使用 aginx.port 指定 aginx.domain 的端口
+	if port, err := strconv.Atoi(labs["aginx.port"]); err == nil && port > 0 {
+		if label, has := labels[0]; has {
+			delete(labels, 0)
+			label.Port = port
+			labels[port] = label
+		}
+	}
 	return labels
 }
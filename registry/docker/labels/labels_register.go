@@ -6,11 +6,13 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
 	dockerClient "github.com/docker/docker/client"
 	"github.com/ihaiker/aginx/logs"
 	"github.com/ihaiker/aginx/plugins"
 	"strings"
 	"text/template"
+	"time"
 )
 
 var logger = logs.New("register", "engine", "docker.labels")
@@ -26,6 +28,8 @@ type DockerLabelsRegister struct {
 	servers map[string] /*domain*/ map[string] /*container id or service name[1..replaced]*/ plugins.Domain
 
 	ip string
+
+	syncInterval time.Duration
 }
 
 func (self *DockerLabelsRegister) TemplateFuncMap() template.FuncMap {
@@ -36,43 +40,99 @@ func (self *DockerLabelsRegister) Support() plugins.RegistrySupport {
 	return plugins.RegistrySupportLabel
 }
 
-func LabelsRegister(ip string) (*DockerLabelsRegister, error) {
+//syncInterval: 同步swarm服务任务的间隔，0不同步
+func LabelsRegister(ip string, syncInterval time.Duration) (*DockerLabelsRegister, error) {
 	docker, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv)
 	if err != nil {
 		return nil, err
 	}
 	return &DockerLabelsRegister{
-		docker: docker, events: make(chan interface{}, 10), ip: ip,
+		docker: docker, events: make(chan interface{}, 10), ip: ip, syncInterval: syncInterval,
 		closeC: make(chan struct{}), servers: map[string]map[string]plugins.Domain{},
 	}, nil
 }
 
-func (self *DockerLabelsRegister) listService() (domains []plugins.Domain) {
-	domains = make([]plugins.Domain, 0)
+//swarm manager节点的服务，其他节点返回nil
+func (self *DockerLabelsRegister) swarmServices() []swarm.Service {
 	if info, err := self.docker.Info(context.TODO()); err != nil {
 		logger.Warn("docker info error ", err)
-
 	} else if info.Swarm.NodeID != "" {
 		if !info.Swarm.ControlAvailable {
 			logger.Debug("docker is swarm worker, ignore list services")
+		} else if services, err := self.docker.ServiceList(context.TODO(), types.ServiceListOptions{}); err != nil {
+			logger.Warn("docker list services error: ", err)
 		} else {
-			logger.Debug("docker swarm manager, list services")
-			if services, err := self.docker.ServiceList(context.TODO(), types.ServiceListOptions{}); err != nil {
-				logger.Warn("docker list services error: ", err)
-			} else {
-				for _, service := range services {
-					if ds, err := self.findFromService(service); err == nil && len(ds) > 0 {
-						logger.Info("found service ", service.Spec.Name, " domains: ", strings.Join(ds.GetDomains(), ","))
-						self.appendDomains(ds)
-						domains = append(domains, ds...)
-					}
-				}
-			}
+			return services
+		}
+	}
+	return nil
+}
+
+func (self *DockerLabelsRegister) listService() (domains []plugins.Domain) {
+	domains = make([]plugins.Domain, 0)
+	for _, service := range self.swarmServices() {
+		if ds, err := self.findFromService(service); err == nil && len(ds) > 0 {
+			logger.Info("found service ", service.Spec.Name, " domains: ", strings.Join(ds.GetDomains(), ","))
+			self.appendDomains(ds)
+			domains = append(domains, ds...)
 		}
 	}
 	return
 }
 
+//同步swarm服务的任务，任务重新调度、扩缩容后任务地址会变化，但不一定会产生服务事件
+func (self *DockerLabelsRegister) syncTasks() {
+	labelsEvents := plugins.LabelsRegistryEvent(map[string]plugins.Domains{})
+	for _, service := range self.swarmServices() {
+		labs := FindLabels(service.Spec.TaskTemplate.ContainerSpec.Labels, false)
+		if !labs.Has() {
+			continue
+		}
+		domains, err := self.findFromService(service)
+		if err != nil {
+			logger.Debug("sync service ", service.Spec.Name, " error: ", err)
+			continue
+		}
+		groups := domains.Group()
+		for _, label := range labs {
+			if self.replaceService(label.Domain, service.Spec.Name, groups[label.Domain]) {
+				logger.Info("service ", service.Spec.Name, " tasks changed, domain: ", label.Domain)
+				labelsEvents[label.Domain] = self.Get(label.Domain)
+			}
+		}
+	}
+	if len(labelsEvents) > 0 {
+		self.events <- labelsEvents
+	}
+}
+
+//替换域名下服务的地址，返回是否有变化
+func (self *DockerLabelsRegister) replaceService(domain, serviceName string, servers plugins.Domains) bool {
+	olds := map[string]string{}
+	for id, server := range self.servers[domain] {
+		if id == serviceName || strings.HasPrefix(id, serviceName+":") {
+			olds[id] = server.Address
+		}
+	}
+	changed := len(olds) != len(servers)
+	for _, server := range servers {
+		if address, has := olds[server.ID]; !has || address != server.Address {
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+	for id := range olds {
+		delete(self.servers[domain], id)
+	}
+	self.appendDomains(servers)
+	if len(self.servers[domain]) == 0 {
+		delete(self.servers, domain)
+	}
+	return true
+}
+
 func (self *DockerLabelsRegister) allDomains() plugins.Domains {
 	logger.Debug("Search all containers and services")
 	domains := self.listService()
@@ -187,8 +247,16 @@ func (self *DockerLabelsRegister) Start() error {
 
 	eventChannel, errChannel := self.docker.Events(context.TODO(), types.EventsOptions{})
 	go func() {
+		var syncTicker <-chan time.Time
+		if self.syncInterval > 0 {
+			ticker := time.NewTicker(self.syncInterval)
+			defer ticker.Stop()
+			syncTicker = ticker.C
+		}
 		for {
 			select {
+			case <-syncTicker:
+				self.syncTasks()
 			case <-self.closeC:
 				close(self.events)
 				return
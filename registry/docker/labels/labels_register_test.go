@@ -8,6 +8,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"os"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -21,7 +22,7 @@ func TestEnv(t *testing.T) {
 }
 
 func TestDocker(t *testing.T) {
-	docker, err := LabelsRegister("10.24.0.1")
+	docker, err := LabelsRegister("10.24.0.1", time.Second*10)
 	PanicIfError(err)
 
 	servers := docker.allDomains()
@@ -0,0 +1,21 @@
+package dockerLabels
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFindLabels(t *testing.T) {
+	labels := FindLabels(map[string]string{"aginx.domain": "api.aginx.io,weight=2,ssl", "aginx.port": "8080"}, true)
+	assert.Len(t, labels, 1)
+	assert.Equal(t, Label{Domain: "api.aginx.io", Port: 8080, Weight: 2, AutoSSL: true}, labels[8080])
+
+	labels = FindLabels(map[string]string{"aginx.domain.8500": "api.aginx.io", "aginx.domain": "web.aginx.io"}, true)
+	assert.Len(t, labels, 2)
+	assert.Equal(t, "api.aginx.io", labels[8500].Domain)
+	assert.Equal(t, "web.aginx.io", labels[0].Domain)
+
+	//swarm 服务的容器由服务发布
+	labels = FindLabels(map[string]string{"aginx.domain": "api.aginx.io", "com.docker.swarm.task.id": "1"}, true)
+	assert.False(t, labels.Has())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/util"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -63,17 +64,36 @@ func (self *DockerLabelsRegister) getVirtualAddress(service swarm.Service, port
 	return
 }
 
-func (self *DockerLabelsRegister) getServiceTaskAddress(service swarm.Service, port uint32) map[int]string {
+//获取正在运行的任务地址，global模式的任务slot都是0，使用节点ID区分。
+//任务连接多个网络时忽略ingress网络，可以使用标签 aginx.network 指定使用的overlay网络
+func (self *DockerLabelsRegister) getServiceTaskAddress(service swarm.Service, port uint32) map[string]string {
 	serviceName := service.Spec.Name
 	tasks, _ := self.docker.TaskList(context.TODO(), types.TaskListOptions{
 		Filters: filters.NewArgs(filters.Arg("desired-state", "running"), filters.Arg("service", serviceName))})
 
-	addresses := map[int]string{}
+	network := service.Spec.Labels["aginx.network"]
+	if network == "" {
+		network = service.Spec.TaskTemplate.ContainerSpec.Labels["aginx.network"]
+	}
+
+	addresses := map[string]string{}
 	for _, task := range tasks {
+		if task.Status.State != swarm.TaskStateRunning {
+			continue
+		}
+		key := strconv.Itoa(task.Slot)
+		if task.Slot == 0 {
+			key = task.NodeID
+		}
+	ATTACHMENTS:
 		for _, attachment := range task.NetworksAttachments {
+			if attachment.Network.Spec.Ingress || (network != "" && attachment.Network.Spec.Name != network) {
+				continue
+			}
 			for _, address := range attachment.Addresses {
 				idx := strings.Index(address, "/")
-				addresses[task.Slot] = fmt.Sprintf("%s:%d", address[0:idx], port)
+				addresses[key] = fmt.Sprintf("%s:%d", address[0:idx], port)
+				break ATTACHMENTS
 			}
 		}
 	}
@@ -141,7 +161,7 @@ func (self *DockerLabelsRegister) findFromService(service swarm.Service) (plugin
 				addresses := self.getServiceTaskAddress(service, usePort.TargetPort)
 				for slot, address := range addresses {
 					domain := self.makeDomain(service, label, address)
-					domain.ID = fmt.Sprintf("%s:%d", serviceName, slot)
+					domain.ID = fmt.Sprintf("%s:%s", serviceName, slot)
 					domains = append(domains, domain)
 				}
 			} else if usePort.PublishedPort != uint32(0) {
@@ -45,11 +45,9 @@ func withAginx() aginx.Aginx {
 	if host == "" || host == "0.0.0.0" {
 		host = "127.0.0.1"
 	}
-	api := aginx.New(fmt.Sprintf("http://%s:%s", host, port))
-	if security := viper.GetString("security"); security != "" {
-		userAndPwd := strings.SplitN(security, ":", 2)
-		api.Auth(userAndPwd[0], userAndPwd[1])
-	}
+	api, err := aginx.NewClient(net.JoinHostPort(host, port), viper.GetString("security"),
+		viper.GetString("tls-ca"), viper.GetString("tls-cert"), viper.GetString("tls-key"))
+	util.PanicIfError(err)
 	return api
 }
 
@@ -2,6 +2,7 @@ package registry
 
 import (
 	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/registry/cloud"
 	"github.com/ihaiker/aginx/registry/consul"
 	"github.com/ihaiker/aginx/registry/docker"
 	"github.com/ihaiker/aginx/util"
@@ -27,6 +28,8 @@ func findPlugins() map[string]*plugins.RegistryPlugin {
 	registryPlugins := map[string]*plugins.RegistryPlugin{
 		"docker.v1.19.3": docker.Plugin,
 		"consul.1.7.1":   consul.Plugin,
+		"nacos":          cloud.NacosPlugin,
+		"eureka":         cloud.EurekaPlugin,
 	}
 	userPlugins(registryPlugins)
 	return registryPlugins
@@ -0,0 +1,148 @@
+package rotate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/util"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//日志切割策略
+type Policy struct {
+	Path     string        //日志文件，支持通配符：/var/log/nginx/*.log
+	Size     int64         //文件超过此大小时切割，0为不按大小切割
+	Interval time.Duration //按时间间隔切割，0为不按时间切割
+	Keep     int           //保留的历史文件数量，0为全部保留
+	Compress bool          //是否使用gzip压缩历史文件
+}
+
+var sizeUnits = map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30}
+
+func parseSize(value string) (int64, error) {
+	value = strings.TrimSuffix(strings.ToUpper(value), "B")
+	unit := int64(1)
+	if len(value) > 0 {
+		if u, has := sizeUnits[value[len(value)-1:]]; has {
+			unit, value = u, value[:len(value)-1]
+		}
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+	return size * unit, nil
+}
+
+func formatSize(size int64) string {
+	for _, unit := range []string{"G", "M", "K"} {
+		if size%sizeUnits[unit] == 0 {
+			return strconv.FormatInt(size/sizeUnits[unit], 10) + unit
+		}
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+//解析切割策略：/var/log/nginx/*.log?size=100M&interval=1d&keep=7&compress=true
+func ParsePolicy(config string) (*Policy, error) {
+	path, query := config, ""
+	if idx := strings.Index(config, "?"); idx != -1 {
+		path, query = config[:idx], config[idx+1:]
+	}
+	if path == "" {
+		return nil, errors.New("the log file is empty: " + config)
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{Path: path, Compress: values.Get("compress") == "true"}
+	if size := values.Get("size"); size != "" {
+		if policy.Size, err = parseSize(size); err != nil {
+			return nil, err
+		}
+	}
+	if interval := values.Get("interval"); interval != "" {
+		if policy.Interval, err = util.ParseDuration(interval); err != nil {
+			return nil, err
+		}
+	}
+	if keep := values.Get("keep"); keep != "" {
+		if policy.Keep, err = strconv.Atoi(keep); err != nil {
+			return nil, fmt.Errorf("invalid keep: %s", keep)
+		}
+	}
+	if policy.Size <= 0 && policy.Interval <= 0 {
+		return nil, errors.New("the size or interval of log rotation is required: " + config)
+	}
+	return policy, nil
+}
+
+func ParsePolicies(configs []string) ([]*Policy, error) {
+	policies := make([]*Policy, 0, len(configs))
+	for _, config := range configs {
+		policy, err := ParsePolicy(config)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func (p *Policy) String() string {
+	values := url.Values{}
+	if p.Size > 0 {
+		values.Set("size", formatSize(p.Size))
+	}
+	if p.Interval > 0 {
+		if p.Interval%(time.Hour*24) == 0 {
+			values.Set("interval", fmt.Sprintf("%dd", p.Interval/(time.Hour*24)))
+		} else {
+			values.Set("interval", p.Interval.String())
+		}
+	}
+	if p.Keep > 0 {
+		values.Set("keep", strconv.Itoa(p.Keep))
+	}
+	if p.Compress {
+		values.Set("compress", "true")
+	}
+	return p.Path + "?" + values.Encode()
+}
+
+//日志文件所在的目录，不包括通配符部分：/var/log/nginx/*/access.log 为 /var/log/nginx
+func (p *Policy) Dir() string {
+	path := filepath.Clean(p.Path)
+	if idx := strings.IndexAny(path, "*?["); idx != -1 {
+		path = path[:idx]
+		return filepath.Clean(path[:strings.LastIndex(path, string(filepath.Separator))+1])
+	}
+	return filepath.Dir(path)
+}
+
+//日志文件是否在dirs中的目录（或者子目录）中
+func (p *Policy) under(dirs []string) bool {
+	if !filepath.IsAbs(p.Path) {
+		return false
+	}
+	dir := realPath(p.Dir())
+	for _, allowed := range dirs {
+		if rel, err := filepath.Rel(realPath(allowed), dir); err == nil &&
+			rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+//解析符号链接后的路径，不存在时为原路径
+func realPath(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return filepath.Clean(path)
+}
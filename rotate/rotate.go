@@ -0,0 +1,208 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("rotate")
+
+const timeLayout = "20060102-150405"
+
+//切割后的历史文件：access.log.20200301-120000[.gz]
+var historyRegexp = regexp.MustCompile(`\.\d{8}-\d{6}(\.gz)?$`)
+
+//nginx日志切割：按大小或者时间间隔切割，通知nginx重新打开日志文件，压缩并清理历史文件
+type Rotator struct {
+	reopen   func() error
+	lock     sync.Mutex
+	policies []*Policy
+	dirs     []string             //启动参数中切割策略的目录，接口设置的策略只能切割这些目录和nginx日志目录中的文件
+	last     map[string]time.Time //日志文件上次切割的时间
+	closeC   chan struct{}
+}
+
+//reopen 通知nginx重新打开日志文件（USR1信号）
+func New(reopen func() error, policies ...*Policy) *Rotator {
+	dirs := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		dirs = append(dirs, policy.Dir())
+	}
+	return &Rotator{
+		reopen: reopen, policies: policies, dirs: dirs,
+		last: make(map[string]time.Time), closeC: make(chan struct{}),
+	}
+}
+
+func (r *Rotator) Policies() []*Policy {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.policies
+}
+
+//替换切割策略，策略的日志文件必须在启动参数中切割策略的目录或者logDirs（nginx日志目录）中
+func (r *Rotator) SetPolicies(policies []*Policy, logDirs ...string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	dirs := append(append([]string{}, r.dirs...), logDirs...)
+	for _, policy := range policies {
+		if !policy.under(dirs) {
+			return fmt.Errorf("the log file %s is not in the log directories: %s", policy.Path, strings.Join(dirs, ","))
+		}
+	}
+	r.policies = policies
+	return nil
+}
+
+func (r *Rotator) due(policy *Policy, file string, info os.FileInfo, now time.Time) bool {
+	if info.Size() == 0 {
+		return false
+	}
+	if policy.Size > 0 && info.Size() >= policy.Size {
+		return true
+	}
+	if policy.Interval > 0 {
+		last, has := r.last[file]
+		if !has {
+			r.last[file] = now
+			return false
+		}
+		return now.Sub(last) >= policy.Interval
+	}
+	return false
+}
+
+//切割需要切割的日志文件，force为true时切割全部日志文件，返回切割后的历史文件
+func (r *Rotator) Rotate(force bool) ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	rotated := make([]string, 0)
+	rotatedPolicies := make([]*Policy, 0)
+	for _, policy := range r.policies {
+		files, err := filepath.Glob(policy.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			info, err := os.Stat(file)
+			if err != nil || info.IsDir() || historyRegexp.MatchString(file) {
+				continue
+			}
+			if !(force && info.Size() > 0) && !r.due(policy, file, info, now) {
+				continue
+			}
+			target := file + "." + now.Format(timeLayout)
+			if err = os.Rename(file, target); err != nil {
+				return rotated, err
+			}
+			r.last[file] = now
+			rotated = append(rotated, target)
+			rotatedPolicies = append(rotatedPolicies, policy)
+		}
+	}
+	if len(rotated) == 0 {
+		return rotated, nil
+	}
+	if err := r.reopen(); err != nil {
+		return rotated, err
+	}
+	for i, target := range rotated {
+		policy := rotatedPolicies[i]
+		if policy.Compress {
+			if compressed, err := compress(target); err != nil {
+				logger.WithError(err).Warn("compress log ", target)
+			} else {
+				rotated[i] = compressed
+			}
+		}
+		prune(strings.TrimSuffix(target, "."+now.Format(timeLayout)), policy.Keep)
+	}
+	logger.Info("rotate logs ", strings.Join(rotated, ","))
+	return rotated, nil
+}
+
+//压缩历史文件，返回压缩后的文件
+func compress(file string) (string, error) {
+	src, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = src.Close() }()
+
+	target := file + ".gz"
+	dest, err := os.Create(target)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = dest.Close() }()
+
+	writer := gzip.NewWriter(dest)
+	if _, err = io.Copy(writer, src); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+	return target, os.Remove(file)
+}
+
+//清理历史文件，只保留最新的keep个
+func prune(file string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	files, err := filepath.Glob(file + ".*")
+	if err != nil {
+		return
+	}
+	histories := make([]string, 0)
+	for _, history := range files {
+		if historyRegexp.MatchString(strings.TrimPrefix(history, file)) {
+			histories = append(histories, history)
+		}
+	}
+	//历史文件名中的时间可以直接排序
+	sort.Sort(sort.Reverse(sort.StringSlice(histories)))
+	if len(histories) <= keep {
+		return
+	}
+	for _, history := range histories[keep:] {
+		if err := os.Remove(history); err != nil {
+			logger.WithError(err).Warn("remove log ", history)
+		}
+	}
+}
+
+func (r *Rotator) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.closeC:
+				return
+			case <-ticker.C:
+				if _, err := r.Rotate(false); err != nil {
+					logger.WithError(err).Warn("rotate logs")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *Rotator) Stop() error {
+	close(r.closeC)
+	return nil
+}
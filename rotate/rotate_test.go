@@ -0,0 +1,99 @@
+package rotate
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("/var/log/nginx/*.log?size=100M&interval=1d&keep=7&compress=true")
+	assert.Nil(t, err)
+	assert.Equal(t, &Policy{
+		Path: "/var/log/nginx/*.log", Size: 100 << 20,
+		Interval: time.Hour * 24, Keep: 7, Compress: true,
+	}, policy)
+	assert.Equal(t, "/var/log/nginx/*.log?compress=true&interval=1d&keep=7&size=100M", policy.String())
+
+	policy, err = ParsePolicy("/var/log/nginx/access.log?interval=6h")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour*6, policy.Interval)
+
+	_, err = ParsePolicy("/var/log/nginx/access.log")
+	assert.NotNil(t, err)
+	_, err = ParsePolicy("/var/log/nginx/access.log?size=big")
+	assert.NotNil(t, err)
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	access := filepath.Join(dir, "access.log")
+	assert.Nil(t, ioutil.WriteFile(access, []byte(strings.Repeat("GET /\n", 100)), 0666))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "error.log"), []byte("error\n"), 0666))
+	//历史文件，只保留2个
+	for _, history := range []string{"access.log.20200101-000000.gz", "access.log.20200102-000000.gz"} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, history), []byte{}, 0666))
+	}
+
+	reopens := 0
+	rotator := New(func() error {
+		reopens++
+		return nil
+	}, &Policy{Path: filepath.Join(dir, "*.log"), Size: 100, Keep: 2, Compress: true})
+
+	rotated, err := rotator.Rotate(false)
+	assert.Nil(t, err)
+	assert.Len(t, rotated, 1)
+	assert.True(t, strings.HasPrefix(rotated[0], access+"."))
+	assert.True(t, strings.HasSuffix(rotated[0], ".gz"))
+	assert.Equal(t, 1, reopens)
+
+	histories, _ := filepath.Glob(access + ".*")
+	assert.Len(t, histories, 2)
+	assert.NotContains(t, histories, filepath.Join(dir, "access.log.20200101-000000.gz"))
+
+	//没有需要切割的文件时不通知nginx
+	rotated, err = rotator.Rotate(false)
+	assert.Nil(t, err)
+	assert.Len(t, rotated, 0)
+	assert.Equal(t, 1, reopens)
+
+	rotated, err = rotator.Rotate(true)
+	assert.Nil(t, err)
+	assert.Len(t, rotated, 1)
+	assert.Contains(t, rotated[0], "error.log.")
+}
+
+//接口设置的策略只能切割启动参数中策略的目录和nginx日志目录中的文件
+func TestSetPolicies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	logs := filepath.Join(dir, "logs")
+	assert.Nil(t, os.MkdirAll(filepath.Join(logs, "sites"), 0755))
+	assert.Nil(t, os.Symlink("/etc", filepath.Join(logs, "etc")))
+
+	rotator := New(func() error { return nil }, &Policy{Path: filepath.Join(logs, "*.log"), Size: 100})
+	nginxLogs := filepath.Join(dir, "nginx")
+	for _, path := range []string{
+		filepath.Join(logs, "access.log"), filepath.Join(logs, "sites", "*.log"),
+		filepath.Join(logs, "*", "*.log"), filepath.Join(nginxLogs, "*.log"),
+	} {
+		assert.Nil(t, rotator.SetPolicies([]*Policy{{Path: path, Size: 100}}, nginxLogs), path)
+		assert.Equal(t, path, rotator.Policies()[0].Path)
+	}
+	for _, path := range []string{
+		"/etc/nginx/*.conf", "/etc/passwd", "/*", "access.log", dir + "/*.log",
+		filepath.Join(logs, "..", "*.log"), filepath.Join(logs, "etc", "*"), filepath.Join(dir, "*", "*.log"),
+	} {
+		assert.NotNil(t, rotator.SetPolicies([]*Policy{{Path: path, Size: 100}}, nginxLogs), path)
+	}
+	assert.Equal(t, filepath.Join(nginxLogs, "*.log"), rotator.Policies()[0].Path)
+}
@@ -0,0 +1,1302 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: rpc/aginx.proto
+
+package rpc
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Directive struct {
+	Virtual              string       `protobuf:"bytes,1,opt,name=virtual,proto3" json:"virtual,omitempty"`
+	Name                 string       `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Args                 []string     `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
+	Body                 []*Directive `protobuf:"bytes,4,rep,name=body,proto3" json:"body,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Directive) Reset()         { *m = Directive{} }
+func (m *Directive) String() string { return proto.CompactTextString(m) }
+func (*Directive) ProtoMessage()    {}
+func (*Directive) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{0}
+}
+
+func (m *Directive) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Directive.Unmarshal(m, b)
+}
+func (m *Directive) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Directive.Marshal(b, m, deterministic)
+}
+func (m *Directive) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Directive.Merge(m, src)
+}
+func (m *Directive) XXX_Size() int {
+	return xxx_messageInfo_Directive.Size(m)
+}
+func (m *Directive) XXX_DiscardUnknown() {
+	xxx_messageInfo_Directive.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Directive proto.InternalMessageInfo
+
+func (m *Directive) GetVirtual() string {
+	if m != nil {
+		return m.Virtual
+	}
+	return ""
+}
+
+func (m *Directive) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Directive) GetArgs() []string {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+func (m *Directive) GetBody() []*Directive {
+	if m != nil {
+		return m.Body
+	}
+	return nil
+}
+
+type Empty struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Empty) Reset()         { *m = Empty{} }
+func (m *Empty) String() string { return proto.CompactTextString(m) }
+func (*Empty) ProtoMessage()    {}
+func (*Empty) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{1}
+}
+
+func (m *Empty) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Empty.Unmarshal(m, b)
+}
+func (m *Empty) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Empty.Marshal(b, m, deterministic)
+}
+func (m *Empty) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Empty.Merge(m, src)
+}
+func (m *Empty) XXX_Size() int {
+	return xxx_messageInfo_Empty.Size(m)
+}
+func (m *Empty) XXX_DiscardUnknown() {
+	xxx_messageInfo_Empty.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Empty proto.InternalMessageInfo
+
+type SelectRequest struct {
+	Queries              []string `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SelectRequest) Reset()         { *m = SelectRequest{} }
+func (m *SelectRequest) String() string { return proto.CompactTextString(m) }
+func (*SelectRequest) ProtoMessage()    {}
+func (*SelectRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{2}
+}
+
+func (m *SelectRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SelectRequest.Unmarshal(m, b)
+}
+func (m *SelectRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SelectRequest.Marshal(b, m, deterministic)
+}
+func (m *SelectRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SelectRequest.Merge(m, src)
+}
+func (m *SelectRequest) XXX_Size() int {
+	return xxx_messageInfo_SelectRequest.Size(m)
+}
+func (m *SelectRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SelectRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SelectRequest proto.InternalMessageInfo
+
+func (m *SelectRequest) GetQueries() []string {
+	if m != nil {
+		return m.Queries
+	}
+	return nil
+}
+
+type SelectResponse struct {
+	Directives           []*Directive `protobuf:"bytes,1,rep,name=directives,proto3" json:"directives,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *SelectResponse) Reset()         { *m = SelectResponse{} }
+func (m *SelectResponse) String() string { return proto.CompactTextString(m) }
+func (*SelectResponse) ProtoMessage()    {}
+func (*SelectResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{3}
+}
+
+func (m *SelectResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SelectResponse.Unmarshal(m, b)
+}
+func (m *SelectResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SelectResponse.Marshal(b, m, deterministic)
+}
+func (m *SelectResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SelectResponse.Merge(m, src)
+}
+func (m *SelectResponse) XXX_Size() int {
+	return xxx_messageInfo_SelectResponse.Size(m)
+}
+func (m *SelectResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SelectResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SelectResponse proto.InternalMessageInfo
+
+func (m *SelectResponse) GetDirectives() []*Directive {
+	if m != nil {
+		return m.Directives
+	}
+	return nil
+}
+
+type AddRequest struct {
+	Queries              []string     `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+	Directives           []*Directive `protobuf:"bytes,2,rep,name=directives,proto3" json:"directives,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *AddRequest) Reset()         { *m = AddRequest{} }
+func (m *AddRequest) String() string { return proto.CompactTextString(m) }
+func (*AddRequest) ProtoMessage()    {}
+func (*AddRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{4}
+}
+
+func (m *AddRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AddRequest.Unmarshal(m, b)
+}
+func (m *AddRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AddRequest.Marshal(b, m, deterministic)
+}
+func (m *AddRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AddRequest.Merge(m, src)
+}
+func (m *AddRequest) XXX_Size() int {
+	return xxx_messageInfo_AddRequest.Size(m)
+}
+func (m *AddRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AddRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AddRequest proto.InternalMessageInfo
+
+func (m *AddRequest) GetQueries() []string {
+	if m != nil {
+		return m.Queries
+	}
+	return nil
+}
+
+func (m *AddRequest) GetDirectives() []*Directive {
+	if m != nil {
+		return m.Directives
+	}
+	return nil
+}
+
+type DeleteRequest struct {
+	Queries              []string `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{5}
+}
+
+func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
+}
+func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteRequest.Merge(m, src)
+}
+func (m *DeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteRequest.Size(m)
+}
+func (m *DeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
+
+func (m *DeleteRequest) GetQueries() []string {
+	if m != nil {
+		return m.Queries
+	}
+	return nil
+}
+
+type ModifyRequest struct {
+	Queries              []string   `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+	Directive            *Directive `protobuf:"bytes,2,opt,name=directive,proto3" json:"directive,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ModifyRequest) Reset()         { *m = ModifyRequest{} }
+func (m *ModifyRequest) String() string { return proto.CompactTextString(m) }
+func (*ModifyRequest) ProtoMessage()    {}
+func (*ModifyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{6}
+}
+
+func (m *ModifyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModifyRequest.Unmarshal(m, b)
+}
+func (m *ModifyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModifyRequest.Marshal(b, m, deterministic)
+}
+func (m *ModifyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModifyRequest.Merge(m, src)
+}
+func (m *ModifyRequest) XXX_Size() int {
+	return xxx_messageInfo_ModifyRequest.Size(m)
+}
+func (m *ModifyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModifyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModifyRequest proto.InternalMessageInfo
+
+func (m *ModifyRequest) GetQueries() []string {
+	if m != nil {
+		return m.Queries
+	}
+	return nil
+}
+
+func (m *ModifyRequest) GetDirective() *Directive {
+	if m != nil {
+		return m.Directive
+	}
+	return nil
+}
+
+type CertificateRequest struct {
+	Email                string   `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Domain               string   `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CertificateRequest) Reset()         { *m = CertificateRequest{} }
+func (m *CertificateRequest) String() string { return proto.CompactTextString(m) }
+func (*CertificateRequest) ProtoMessage()    {}
+func (*CertificateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{7}
+}
+
+func (m *CertificateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CertificateRequest.Unmarshal(m, b)
+}
+func (m *CertificateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CertificateRequest.Marshal(b, m, deterministic)
+}
+func (m *CertificateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CertificateRequest.Merge(m, src)
+}
+func (m *CertificateRequest) XXX_Size() int {
+	return xxx_messageInfo_CertificateRequest.Size(m)
+}
+func (m *CertificateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CertificateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CertificateRequest proto.InternalMessageInfo
+
+func (m *CertificateRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *CertificateRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+type Certificate struct {
+	Certificate          string   `protobuf:"bytes,1,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	IssuerCertificate    string   `protobuf:"bytes,2,opt,name=issuer_certificate,json=issuerCertificate,proto3" json:"issuer_certificate,omitempty"`
+	Pem                  string   `protobuf:"bytes,3,opt,name=pem,proto3" json:"pem,omitempty"`
+	PrivateKey           string   `protobuf:"bytes,4,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Certificate) Reset()         { *m = Certificate{} }
+func (m *Certificate) String() string { return proto.CompactTextString(m) }
+func (*Certificate) ProtoMessage()    {}
+func (*Certificate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{8}
+}
+
+func (m *Certificate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Certificate.Unmarshal(m, b)
+}
+func (m *Certificate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Certificate.Marshal(b, m, deterministic)
+}
+func (m *Certificate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Certificate.Merge(m, src)
+}
+func (m *Certificate) XXX_Size() int {
+	return xxx_messageInfo_Certificate.Size(m)
+}
+func (m *Certificate) XXX_DiscardUnknown() {
+	xxx_messageInfo_Certificate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Certificate proto.InternalMessageInfo
+
+func (m *Certificate) GetCertificate() string {
+	if m != nil {
+		return m.Certificate
+	}
+	return ""
+}
+
+func (m *Certificate) GetIssuerCertificate() string {
+	if m != nil {
+		return m.IssuerCertificate
+	}
+	return ""
+}
+
+func (m *Certificate) GetPem() string {
+	if m != nil {
+		return m.Pem
+	}
+	return ""
+}
+
+func (m *Certificate) GetPrivateKey() string {
+	if m != nil {
+		return m.PrivateKey
+	}
+	return ""
+}
+
+type File struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Content              []byte   `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *File) Reset()         { *m = File{} }
+func (m *File) String() string { return proto.CompactTextString(m) }
+func (*File) ProtoMessage()    {}
+func (*File) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{9}
+}
+
+func (m *File) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_File.Unmarshal(m, b)
+}
+func (m *File) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_File.Marshal(b, m, deterministic)
+}
+func (m *File) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_File.Merge(m, src)
+}
+func (m *File) XXX_Size() int {
+	return xxx_messageInfo_File.Size(m)
+}
+func (m *File) XXX_DiscardUnknown() {
+	xxx_messageInfo_File.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_File proto.InternalMessageInfo
+
+func (m *File) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *File) GetContent() []byte {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+type FileRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileRequest) Reset()         { *m = FileRequest{} }
+func (m *FileRequest) String() string { return proto.CompactTextString(m) }
+func (*FileRequest) ProtoMessage()    {}
+func (*FileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{10}
+}
+
+func (m *FileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileRequest.Unmarshal(m, b)
+}
+func (m *FileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileRequest.Marshal(b, m, deterministic)
+}
+func (m *FileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileRequest.Merge(m, src)
+}
+func (m *FileRequest) XXX_Size() int {
+	return xxx_messageInfo_FileRequest.Size(m)
+}
+func (m *FileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileRequest proto.InternalMessageInfo
+
+func (m *FileRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type SearchRequest struct {
+	Patterns             []string `protobuf:"bytes,1,rep,name=patterns,proto3" json:"patterns,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchRequest) Reset()         { *m = SearchRequest{} }
+func (m *SearchRequest) String() string { return proto.CompactTextString(m) }
+func (*SearchRequest) ProtoMessage()    {}
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{11}
+}
+
+func (m *SearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchRequest.Unmarshal(m, b)
+}
+func (m *SearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchRequest.Marshal(b, m, deterministic)
+}
+func (m *SearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchRequest.Merge(m, src)
+}
+func (m *SearchRequest) XXX_Size() int {
+	return xxx_messageInfo_SearchRequest.Size(m)
+}
+func (m *SearchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchRequest proto.InternalMessageInfo
+
+func (m *SearchRequest) GetPatterns() []string {
+	if m != nil {
+		return m.Patterns
+	}
+	return nil
+}
+
+type SearchResponse struct {
+	Files                []*File  `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchResponse) Reset()         { *m = SearchResponse{} }
+func (m *SearchResponse) String() string { return proto.CompactTextString(m) }
+func (*SearchResponse) ProtoMessage()    {}
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{12}
+}
+
+func (m *SearchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchResponse.Unmarshal(m, b)
+}
+func (m *SearchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchResponse.Marshal(b, m, deterministic)
+}
+func (m *SearchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchResponse.Merge(m, src)
+}
+func (m *SearchResponse) XXX_Size() int {
+	return xxx_messageInfo_SearchResponse.Size(m)
+}
+func (m *SearchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchResponse proto.InternalMessageInfo
+
+func (m *SearchResponse) GetFiles() []*File {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	File                 string   `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{13}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetFile() string {
+	if m != nil {
+		return m.File
+	}
+	return ""
+}
+
+type ChangeEvent struct {
+	Time                 int64    `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	Source               string   `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	File                 string   `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	Type                 string   `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	User                 string   `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"`
+	Queries              []string `protobuf:"bytes,6,rep,name=queries,proto3" json:"queries,omitempty"`
+	Diff                 string   `protobuf:"bytes,7,opt,name=diff,proto3" json:"diff,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChangeEvent) Reset()         { *m = ChangeEvent{} }
+func (m *ChangeEvent) String() string { return proto.CompactTextString(m) }
+func (*ChangeEvent) ProtoMessage()    {}
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f81dd33b15f26882, []int{14}
+}
+
+func (m *ChangeEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChangeEvent.Unmarshal(m, b)
+}
+func (m *ChangeEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChangeEvent.Marshal(b, m, deterministic)
+}
+func (m *ChangeEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChangeEvent.Merge(m, src)
+}
+func (m *ChangeEvent) XXX_Size() int {
+	return xxx_messageInfo_ChangeEvent.Size(m)
+}
+func (m *ChangeEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChangeEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChangeEvent proto.InternalMessageInfo
+
+func (m *ChangeEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *ChangeEvent) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *ChangeEvent) GetFile() string {
+	if m != nil {
+		return m.File
+	}
+	return ""
+}
+
+func (m *ChangeEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *ChangeEvent) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *ChangeEvent) GetQueries() []string {
+	if m != nil {
+		return m.Queries
+	}
+	return nil
+}
+
+func (m *ChangeEvent) GetDiff() string {
+	if m != nil {
+		return m.Diff
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*Directive)(nil), "aginx.Directive")
+	proto.RegisterType((*Empty)(nil), "aginx.Empty")
+	proto.RegisterType((*SelectRequest)(nil), "aginx.SelectRequest")
+	proto.RegisterType((*SelectResponse)(nil), "aginx.SelectResponse")
+	proto.RegisterType((*AddRequest)(nil), "aginx.AddRequest")
+	proto.RegisterType((*DeleteRequest)(nil), "aginx.DeleteRequest")
+	proto.RegisterType((*ModifyRequest)(nil), "aginx.ModifyRequest")
+	proto.RegisterType((*CertificateRequest)(nil), "aginx.CertificateRequest")
+	proto.RegisterType((*Certificate)(nil), "aginx.Certificate")
+	proto.RegisterType((*File)(nil), "aginx.File")
+	proto.RegisterType((*FileRequest)(nil), "aginx.FileRequest")
+	proto.RegisterType((*SearchRequest)(nil), "aginx.SearchRequest")
+	proto.RegisterType((*SearchResponse)(nil), "aginx.SearchResponse")
+	proto.RegisterType((*WatchRequest)(nil), "aginx.WatchRequest")
+	proto.RegisterType((*ChangeEvent)(nil), "aginx.ChangeEvent")
+}
+
+func init() { proto.RegisterFile("rpc/aginx.proto", fileDescriptor_f81dd33b15f26882) }
+
+var fileDescriptor_f81dd33b15f26882 = []byte{
+	// 692 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xdb, 0x6e, 0xd3, 0x40,
+	0x10, 0x55, 0xea, 0x5c, 0xe8, 0xa4, 0x2d, 0xed, 0xd2, 0x22, 0x13, 0x21, 0x48, 0x57, 0x15, 0x6a,
+	0xb9, 0xa4, 0x55, 0x0b, 0x2f, 0xbc, 0xa0, 0x94, 0x16, 0x1e, 0x10, 0x08, 0x99, 0x07, 0x2e, 0x2f,
+	0xd5, 0xd6, 0x9e, 0x24, 0xab, 0x26, 0xb6, 0xbb, 0x5e, 0x07, 0xf2, 0x19, 0x7c, 0x03, 0xff, 0xc5,
+	0xb7, 0xa0, 0xbd, 0xd8, 0xde, 0xa4, 0x45, 0x45, 0xe2, 0x6d, 0x2e, 0xc7, 0x67, 0x66, 0x67, 0xce,
+	0x24, 0x70, 0x5b, 0xa4, 0xe1, 0x3e, 0x1b, 0xf2, 0xf8, 0x47, 0x2f, 0x15, 0x89, 0x4c, 0x48, 0x43,
+	0x3b, 0x34, 0x83, 0xe5, 0x13, 0x2e, 0x30, 0x94, 0x7c, 0x8a, 0xc4, 0x87, 0xd6, 0x94, 0x0b, 0x99,
+	0xb3, 0xb1, 0x5f, 0xeb, 0xd6, 0x76, 0x97, 0x83, 0xc2, 0x25, 0x04, 0xea, 0x31, 0x9b, 0xa0, 0xbf,
+	0xa4, 0xc3, 0xda, 0x56, 0x31, 0x26, 0x86, 0x99, 0xef, 0x75, 0x3d, 0x15, 0x53, 0x36, 0xd9, 0x81,
+	0xfa, 0x79, 0x12, 0xcd, 0xfc, 0x7a, 0xd7, 0xdb, 0x6d, 0x1f, 0xae, 0xf7, 0x4c, 0xc5, 0xb2, 0x42,
+	0xa0, 0xb3, 0xb4, 0x05, 0x8d, 0xd3, 0x49, 0x2a, 0x67, 0x74, 0x0f, 0x56, 0x3f, 0xe1, 0x18, 0x43,
+	0x19, 0xe0, 0x65, 0x8e, 0x99, 0x54, 0x1d, 0x5c, 0xe6, 0x28, 0x38, 0x66, 0x7e, 0x4d, 0xd3, 0x16,
+	0x2e, 0x3d, 0x86, 0xb5, 0x02, 0x9a, 0xa5, 0x49, 0x9c, 0x21, 0x39, 0x00, 0x88, 0x0a, 0x62, 0x03,
+	0xbf, 0xae, 0xa2, 0x83, 0xa1, 0x5f, 0x00, 0xfa, 0x51, 0x74, 0x63, 0xad, 0x05, 0xe6, 0xa5, 0x7f,
+	0x60, 0xde, 0x83, 0xd5, 0x13, 0x1c, 0xa3, 0xc4, 0x9b, 0x1f, 0xf2, 0x15, 0x56, 0xdf, 0x27, 0x11,
+	0x1f, 0xcc, 0x6e, 0xee, 0xa3, 0x07, 0xcb, 0x65, 0x0d, 0x3d, 0xfa, 0xeb, 0xda, 0xa8, 0x20, 0xf4,
+	0x18, 0xc8, 0x6b, 0x14, 0x92, 0x0f, 0x78, 0xc8, 0xaa, 0x56, 0x36, 0xa1, 0x81, 0x13, 0xc6, 0x8b,
+	0x9d, 0x1a, 0x87, 0xdc, 0x85, 0x66, 0x94, 0x4c, 0x18, 0x8f, 0xed, 0x4e, 0xad, 0x47, 0x7f, 0xd6,
+	0xa0, 0xed, 0x90, 0x90, 0x2e, 0xb4, 0xc3, 0xca, 0xb5, 0x1c, 0x6e, 0x88, 0x3c, 0x03, 0xc2, 0xb3,
+	0x2c, 0x47, 0x71, 0xe6, 0x02, 0x0d, 0xeb, 0x86, 0xc9, 0xb8, 0x84, 0xeb, 0xe0, 0xa5, 0x38, 0xf1,
+	0x3d, 0x9d, 0x57, 0x26, 0x79, 0x08, 0xed, 0x54, 0xf0, 0x29, 0x93, 0x78, 0x76, 0x81, 0x4a, 0x3b,
+	0x2a, 0x03, 0x36, 0xf4, 0x0e, 0x67, 0xf4, 0x39, 0xd4, 0xdf, 0xf0, 0x31, 0x96, 0x2a, 0xac, 0x39,
+	0x2a, 0xf4, 0xa1, 0x15, 0x26, 0xb1, 0xc4, 0x58, 0xea, 0x92, 0x2b, 0x41, 0xe1, 0xd2, 0x6d, 0x68,
+	0xab, 0xaf, 0x8a, 0x31, 0x5c, 0xf3, 0x31, 0x7d, 0xa2, 0xf4, 0xc7, 0x44, 0x38, 0x2a, 0x40, 0x1d,
+	0xb8, 0x95, 0x32, 0x29, 0x51, 0xc4, 0xc5, 0x32, 0x4a, 0x9f, 0x1e, 0xc1, 0x5a, 0x01, 0xb6, 0x0a,
+	0xdc, 0x86, 0xc6, 0x80, 0x8f, 0x4b, 0xf1, 0xb5, 0xed, 0x6e, 0x74, 0x55, 0x93, 0xa1, 0x14, 0x56,
+	0x3e, 0x33, 0x59, 0x15, 0x20, 0x50, 0x57, 0x89, 0xa2, 0x0b, 0x65, 0xd3, 0x5f, 0x6a, 0xe4, 0x23,
+	0x16, 0x0f, 0xf1, 0x74, 0x8a, 0xb1, 0xc6, 0x48, 0x6e, 0x3b, 0xf5, 0x02, 0x6d, 0xab, 0x75, 0x65,
+	0x49, 0x2e, 0xc2, 0x62, 0xb0, 0xd6, 0x2b, 0xf9, 0xbc, 0x8a, 0x4f, 0x7f, 0x3f, 0x4b, 0xd1, 0x0e,
+	0x52, 0xdb, 0x2a, 0x96, 0x67, 0x28, 0xfc, 0x86, 0x89, 0x29, 0xdb, 0x15, 0x5e, 0x73, 0x5e, 0x78,
+	0x04, 0xea, 0x11, 0x1f, 0x0c, 0xfc, 0x96, 0x41, 0x2b, 0xfb, 0xf0, 0x77, 0x1d, 0x1a, 0x7d, 0xf5,
+	0x3e, 0xf2, 0x02, 0x9a, 0xe6, 0x14, 0xc9, 0xa6, 0x7d, 0xf1, 0xdc, 0x11, 0x77, 0xb6, 0x16, 0xa2,
+	0x76, 0x5a, 0x8f, 0xc0, 0xeb, 0x47, 0x11, 0xd9, 0xb0, 0xd9, 0xea, 0x12, 0x3b, 0x2b, 0x36, 0xa4,
+	0x7f, 0x14, 0xc8, 0x53, 0x68, 0x9a, 0x5b, 0x2a, 0xe9, 0xe7, 0x4e, 0xeb, 0x2a, 0xda, 0x9c, 0x53,
+	0x89, 0x9e, 0xbb, 0xae, 0x05, 0xf4, 0x0e, 0x34, 0x03, 0x1c, 0x27, 0x2c, 0x22, 0x73, 0xf1, 0x05,
+	0xd4, 0x2b, 0x58, 0xfb, 0x80, 0xdf, 0x5d, 0xd1, 0xde, 0xb3, 0xf9, 0xab, 0xe7, 0xd5, 0x21, 0x57,
+	0x53, 0xa4, 0x0f, 0xeb, 0x01, 0xc6, 0xff, 0x45, 0xf1, 0x18, 0x5a, 0x6f, 0x51, 0x1a, 0xd9, 0xbb,
+	0xba, 0xb2, 0x9f, 0xb8, 0x5a, 0x23, 0x2f, 0xa1, 0x6d, 0x94, 0xa9, 0xbc, 0xcc, 0xd9, 0x8a, 0x23,
+	0xed, 0xce, 0xd6, 0x42, 0xd4, 0x6e, 0x65, 0x07, 0x5a, 0x1f, 0x73, 0x53, 0xc7, 0xe5, 0x5c, 0x98,
+	0x48, 0x0f, 0x20, 0xc0, 0x49, 0x32, 0xc5, 0xbf, 0x36, 0x34, 0x8f, 0x3f, 0x84, 0x86, 0x96, 0x3d,
+	0xb9, 0x63, 0xc3, 0xee, 0x11, 0x54, 0xef, 0xad, 0x44, 0x7f, 0x50, 0x3b, 0x7e, 0xf0, 0xed, 0xfe,
+	0x90, 0xcb, 0x51, 0x7e, 0xde, 0x0b, 0x93, 0xc9, 0x3e, 0x1f, 0x31, 0x7e, 0x81, 0xc2, 0xfc, 0x67,
+	0xed, 0x8b, 0x34, 0x3c, 0x6f, 0xea, 0x3f, 0xae, 0xa3, 0x3f, 0x03, 0x00, 0x8c, 0xd5, 0x17, 0x9e,
+	0xcb, 0x06, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// AginxClient is the client API for Aginx service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AginxClient interface {
+	Select(ctx context.Context, in *SelectRequest, opts ...grpc.CallOption) (*SelectResponse, error)
+	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*Empty, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*Empty, error)
+	Modify(ctx context.Context, in *ModifyRequest, opts ...grpc.CallOption) (*Empty, error)
+	Reload(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	NewCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*Certificate, error)
+	RenewCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*Certificate, error)
+	GetFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*File, error)
+	SearchFiles(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	PutFile(ctx context.Context, in *File, opts ...grpc.CallOption) (*Empty, error)
+	RemoveFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*Empty, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Aginx_WatchClient, error)
+}
+
+type aginxClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAginxClient(cc *grpc.ClientConn) AginxClient {
+	return &aginxClient{cc}
+}
+
+func (c *aginxClient) Select(ctx context.Context, in *SelectRequest, opts ...grpc.CallOption) (*SelectResponse, error) {
+	out := new(SelectResponse)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/Select", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/Add", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) Modify(ctx context.Context, in *ModifyRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/Modify", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) Reload(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/Reload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) NewCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*Certificate, error) {
+	out := new(Certificate)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/NewCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) RenewCertificate(ctx context.Context, in *CertificateRequest, opts ...grpc.CallOption) (*Certificate, error) {
+	out := new(Certificate)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/RenewCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) GetFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*File, error) {
+	out := new(File)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/GetFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) SearchFiles(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/SearchFiles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) PutFile(ctx context.Context, in *File, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/PutFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) RemoveFile(ctx context.Context, in *FileRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/aginx.Aginx/RemoveFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aginxClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Aginx_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Aginx_serviceDesc.Streams[0], "/aginx.Aginx/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aginxWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Aginx_WatchClient interface {
+	Recv() (*ChangeEvent, error)
+	grpc.ClientStream
+}
+
+type aginxWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *aginxWatchClient) Recv() (*ChangeEvent, error) {
+	m := new(ChangeEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AginxServer is the server API for Aginx service.
+type AginxServer interface {
+	Select(context.Context, *SelectRequest) (*SelectResponse, error)
+	Add(context.Context, *AddRequest) (*Empty, error)
+	Delete(context.Context, *DeleteRequest) (*Empty, error)
+	Modify(context.Context, *ModifyRequest) (*Empty, error)
+	Reload(context.Context, *Empty) (*Empty, error)
+	NewCertificate(context.Context, *CertificateRequest) (*Certificate, error)
+	RenewCertificate(context.Context, *CertificateRequest) (*Certificate, error)
+	GetFile(context.Context, *FileRequest) (*File, error)
+	SearchFiles(context.Context, *SearchRequest) (*SearchResponse, error)
+	PutFile(context.Context, *File) (*Empty, error)
+	RemoveFile(context.Context, *FileRequest) (*Empty, error)
+	Watch(*WatchRequest, Aginx_WatchServer) error
+}
+
+// UnimplementedAginxServer can be embedded to have forward compatible implementations.
+type UnimplementedAginxServer struct {
+}
+
+func (*UnimplementedAginxServer) Select(ctx context.Context, req *SelectRequest) (*SelectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Select not implemented")
+}
+func (*UnimplementedAginxServer) Add(ctx context.Context, req *AddRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (*UnimplementedAginxServer) Delete(ctx context.Context, req *DeleteRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (*UnimplementedAginxServer) Modify(ctx context.Context, req *ModifyRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Modify not implemented")
+}
+func (*UnimplementedAginxServer) Reload(ctx context.Context, req *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
+}
+func (*UnimplementedAginxServer) NewCertificate(ctx context.Context, req *CertificateRequest) (*Certificate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NewCertificate not implemented")
+}
+func (*UnimplementedAginxServer) RenewCertificate(ctx context.Context, req *CertificateRequest) (*Certificate, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCertificate not implemented")
+}
+func (*UnimplementedAginxServer) GetFile(ctx context.Context, req *FileRequest) (*File, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFile not implemented")
+}
+func (*UnimplementedAginxServer) SearchFiles(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchFiles not implemented")
+}
+func (*UnimplementedAginxServer) PutFile(ctx context.Context, req *File) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutFile not implemented")
+}
+func (*UnimplementedAginxServer) RemoveFile(ctx context.Context, req *FileRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFile not implemented")
+}
+func (*UnimplementedAginxServer) Watch(req *WatchRequest, srv Aginx_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+
+func RegisterAginxServer(s *grpc.Server, srv AginxServer) {
+	s.RegisterService(&_Aginx_serviceDesc, srv)
+}
+
+func _Aginx_Select_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).Select(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/Select",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).Select(ctx, req.(*SelectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/Add",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).Add(ctx, req.(*AddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_Modify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).Modify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/Modify",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).Modify(ctx, req.(*ModifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_Reload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).Reload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/Reload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).Reload(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_NewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).NewCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/NewCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).NewCertificate(ctx, req.(*CertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_RenewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).RenewCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/RenewCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).RenewCertificate(ctx, req.(*CertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_GetFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).GetFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/GetFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).GetFile(ctx, req.(*FileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_SearchFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).SearchFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/SearchFiles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).SearchFiles(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_PutFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(File)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).PutFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/PutFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).PutFile(ctx, req.(*File))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_RemoveFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AginxServer).RemoveFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aginx.Aginx/RemoveFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AginxServer).RemoveFile(ctx, req.(*FileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aginx_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AginxServer).Watch(m, &aginxWatchServer{stream})
+}
+
+type Aginx_WatchServer interface {
+	Send(*ChangeEvent) error
+	grpc.ServerStream
+}
+
+type aginxWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *aginxWatchServer) Send(m *ChangeEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Aginx_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "aginx.Aginx",
+	HandlerType: (*AginxServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Select",
+			Handler:    _Aginx_Select_Handler,
+		},
+		{
+			MethodName: "Add",
+			Handler:    _Aginx_Add_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Aginx_Delete_Handler,
+		},
+		{
+			MethodName: "Modify",
+			Handler:    _Aginx_Modify_Handler,
+		},
+		{
+			MethodName: "Reload",
+			Handler:    _Aginx_Reload_Handler,
+		},
+		{
+			MethodName: "NewCertificate",
+			Handler:    _Aginx_NewCertificate_Handler,
+		},
+		{
+			MethodName: "RenewCertificate",
+			Handler:    _Aginx_RenewCertificate_Handler,
+		},
+		{
+			MethodName: "GetFile",
+			Handler:    _Aginx_GetFile_Handler,
+		},
+		{
+			MethodName: "SearchFiles",
+			Handler:    _Aginx_SearchFiles_Handler,
+		},
+		{
+			MethodName: "PutFile",
+			Handler:    _Aginx_PutFile_Handler,
+		},
+		{
+			MethodName: "RemoveFile",
+			Handler:    _Aginx_RemoveFile_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Aginx_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rpc/aginx.proto",
+}
@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/util"
+)
+
+func toDirective(d *Directive) *nginx.Directive {
+	if d == nil {
+		return nil
+	}
+	directive := &nginx.Directive{Virtual: nginx.Virtual(d.Virtual), Name: d.Name, Args: d.Args}
+	for _, body := range d.Body {
+		directive.AddBodyDirective(toDirective(body))
+	}
+	return directive
+}
+
+func toDirectives(ds []*Directive) []*nginx.Directive {
+	directives := make([]*nginx.Directive, len(ds))
+	for i, d := range ds {
+		directives[i] = toDirective(d)
+	}
+	return directives
+}
+
+func fromDirective(d *nginx.Directive) *Directive {
+	directive := &Directive{Virtual: string(d.Virtual), Name: d.Name, Args: d.Args}
+	for _, body := range d.Body {
+		directive.Body = append(directive.Body, fromDirective(body))
+	}
+	return directive
+}
+
+func fromDirectives(ds []*nginx.Directive) []*Directive {
+	directives := make([]*Directive, len(ds))
+	for i, d := range ds {
+		directives[i] = fromDirective(d)
+	}
+	return directives
+}
+
+func fromCertificate(file *lego.StoreFile) *Certificate {
+	return &Certificate{
+		Certificate: file.Certificate, IssuerCertificate: file.IssuerCertificate,
+		Pem: file.PEM, PrivateKey: file.PrivateKey,
+	}
+}
+
+func fromChangeEvent(event *util.ChangeEvent) *ChangeEvent {
+	return &ChangeEvent{
+		Time: event.Time.UnixNano(), Source: event.Source, File: event.File, Type: event.Type,
+		User: event.User, Queries: event.Queries, Diff: event.Diff,
+	}
+}
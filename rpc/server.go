@@ -0,0 +1,481 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/approval"
+	"github.com/ihaiker/aginx/audit"
+	"github.com/ihaiker/aginx/auth"
+	"github.com/ihaiker/aginx/history"
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/ownership"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var logger = logs.New("rpc")
+
+//只读的方法，不需要审计和记录历史版本
+var readonlyMethods = map[string]bool{
+	"/aginx.Aginx/Select":      true,
+	"/aginx.Aginx/GetFile":     true,
+	"/aginx.Aginx/SearchFiles": true,
+	"/aginx.Aginx/Watch":       true,
+}
+
+var certificateMethods = map[string]bool{
+	"/aginx.Aginx/NewCertificate":   true,
+	"/aginx.Aginx/RenewCertificate": true,
+}
+
+type Server struct {
+	address   string
+	auth      *auth.Auth
+	server    *grpc.Server
+	tlsConfig *tls.Config
+
+	email     string
+	process   *nginx.Process
+	engine    plugins.StorageEngine
+	manager   *lego.Manager
+	auditor   *audit.Auditor
+	histories *history.History
+	approval  bool //修改需要审批时拒绝修改配置的请求
+	ownership *ownership.Ownership
+	drain     time.Duration //停止时等待处理中的请求完成的最长时间
+}
+
+func NewServer(address, email string, authenticator *auth.Auth, process *nginx.Process, engine plugins.StorageEngine,
+	manager *lego.Manager, auditor *audit.Auditor, histories *history.History) *Server {
+	return &Server{
+		address: address, auth: authenticator, email: email, process: process,
+		engine: engine, manager: manager, auditor: auditor, histories: histories,
+	}
+}
+
+//使用TLS，tls.Config中设置ClientCAs将验证客户端证书
+func (s *Server) TLS(tlsConfig *tls.Config) *Server {
+	s.tlsConfig = tlsConfig
+	return s
+}
+
+//修改配置需要审批（--approval）时，gRPC不能修改配置，使用http api创建等待审批的修改
+func (s *Server) RequireApproval(required bool) *Server {
+	s.approval = required
+	return s
+}
+
+//修改配置时检查域名的保护规则
+func (s *Server) Protect(o *ownership.Ownership) *Server {
+	s.ownership = o
+	return s
+}
+
+//停止时等待处理中的请求完成的最长时间，0一直等待
+func (s *Server) Drain(timeout time.Duration) *Server {
+	s.drain = timeout
+	return s
+}
+
+func (s *Server) Start() error {
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+	}
+	if s.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	s.server = grpc.NewServer(options...)
+	RegisterAginxServer(s.server, s)
+
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	logger.Info("grpc server listen ", s.address)
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			logger.WithError(err).Warn("grpc server")
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+	if s.drain <= 0 {
+		s.server.GracefulStop()
+		return nil
+	}
+	err := util.Async(s.drain, func() error {
+		s.server.GracefulStop()
+		return nil
+	})
+	if err == util.ErrTimeout {
+		logger.Warn("grpc server drain timeout, close all connections")
+		s.server.Stop()
+	}
+	return nil
+}
+
+//使用和restful api相同的认证，metadata: authorization=Basic base64(user:password) 或者 Bearer token
+func (s *Server) authenticate(ctx context.Context, method string) (identity *auth.Identity, err error) {
+	if !s.auth.Enabled() {
+		return nil, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := ""
+	if values := md.Get("authorization"); len(values) > 0 {
+		authorization = values[0]
+	}
+	identity, err = s.auth.Authenticate(authorization)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	//租户只能使用http的租户api
+	if identity.Tenant != "" {
+		return nil, status.Error(codes.PermissionDenied, auth.ErrForbidden.Error())
+	}
+	permission := auth.PermWrite
+	if readonlyMethods[method] {
+		permission = auth.PermRead
+	} else if certificateMethods[method] {
+		permission = auth.PermCert
+	}
+	if !identity.Can(permission) {
+		return nil, status.Error(codes.PermissionDenied, auth.ErrForbidden.Error())
+	}
+	return identity, nil
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (resp interface{}, err error) {
+
+	identity, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	user := ""
+	if identity != nil {
+		user = identity.User
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			err = status.Error(codes.Internal, fmt.Sprintf("%v", e))
+		}
+	}()
+	if readonlyMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	if s.approval && !certificateMethods[info.FullMethod] {
+		return nil, status.Error(codes.FailedPrecondition, approval.ErrApprovalRequired.Error())
+	}
+
+	record := &audit.Record{Time: time.Now(), User: user, Method: "GRPC", Path: info.FullMethod}
+	if p, has := peer.FromContext(ctx); has {
+		record.Remote = p.Addr.String()
+	}
+	ctx = s.auditor.Begin(plugins.WithUser(ctx, user), record)
+	ctx = s.histories.Begin(ctx, user)
+	if s.ownership != nil {
+		ctx = s.ownership.Begin(ctx, user, identity == nil || identity.Can(auth.PermAdmin))
+	}
+	defer func() {
+		s.histories.End(ctx)
+		if e := recover(); e != nil {
+			err = status.Error(codes.Internal, fmt.Sprintf("%v", e))
+		}
+		record.Status = 200
+		if err != nil {
+			record.Status = 500
+			record.Error = err.Error()
+		}
+		s.auditor.End(record)
+	}()
+	return handler(ctx, req)
+}
+
+func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if _, err := s.authenticate(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+//绑定请求context的存储，修改记录到此请求中
+func (s *Server) storage(ctx context.Context) plugins.StorageEngine {
+	return plugins.WithContext(ctx, s.engine)
+}
+
+func (s *Server) client(ctx context.Context) (*nginx.Client, error) {
+	return nginx.NewClient(s.email, s.storage(ctx), s.manager, s.process)
+}
+
+//测试、保存配置并重启nginx
+func (s *Server) apply(client *nginx.Client) (*Empty, error) {
+	if err := s.process.Test(client.Configuration()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := client.Store(); errors.Is(err, nginx.ErrConflict) {
+		return nil, status.Error(codes.Aborted, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	if err := s.process.Reload(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func notFound(err error) error {
+	if err == nginx.ErrNotFound {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+func (s *Server) Select(ctx context.Context, req *SelectRequest) (*SelectResponse, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	directives, err := client.Select(req.Queries...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &SelectResponse{Directives: fromDirectives(directives)}, nil
+}
+
+func (s *Server) Add(ctx context.Context, req *AddRequest) (*Empty, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Add(req.Queries, toDirectives(req.Directives)...); err != nil {
+		return nil, notFound(err)
+	}
+	return s.apply(client)
+}
+
+func (s *Server) Delete(ctx context.Context, req *DeleteRequest) (*Empty, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Delete(req.Queries...); err != nil {
+		return nil, notFound(err)
+	}
+	return s.apply(client)
+}
+
+func (s *Server) Modify(ctx context.Context, req *ModifyRequest) (*Empty, error) {
+	if req.Directive == nil {
+		return nil, status.Error(codes.InvalidArgument, "new directive is empty")
+	}
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.Modify(req.Queries, toDirective(req.Directive)); err != nil {
+		return nil, notFound(err)
+	}
+	return s.apply(client)
+}
+
+func (s *Server) Reload(ctx context.Context, req *Empty) (*Empty, error) {
+	if err := s.process.Reload(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *Server) NewCertificate(ctx context.Context, req *CertificateRequest) (cert *Certificate, err error) {
+	defer util.Catch(func(e error) {
+		err = e
+	})
+	client, err := s.client(ctx)
+	util.PanicIfError(err)
+	email := req.Email
+	if email == "" {
+		email = s.email
+	}
+	file := client.NewCertificate(email, req.Domain)
+	//泛域名证书替换了server使用的证书
+	if len(client.Changes()) > 0 {
+		if _, err = s.apply(client); err != nil {
+			return nil, err
+		}
+	}
+	return fromCertificate(file), nil
+}
+
+func (s *Server) RenewCertificate(ctx context.Context, req *CertificateRequest) (cert *Certificate, err error) {
+	defer util.Catch(func(e error) {
+		err = e
+	})
+	client, err := s.client(ctx)
+	util.PanicIfError(err)
+	if _, has := s.manager.CertificateStorage.Get(req.Domain); !has {
+		return nil, status.Error(codes.NotFound, "certificate not found: "+req.Domain)
+	}
+	file := client.RenewCertificate(req.Domain)
+	//证书文件路径不变，重新加载nginx使用新的证书
+	if len(client.Changes()) > 0 {
+		_, err = s.apply(client)
+	} else {
+		err = s.process.Reload()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromCertificate(file), nil
+}
+
+//aginx自己的数据（api key、证书、历史版本等）不能通过文件接口读写
+func reserved(name string) error {
+	if plugins.Reserved(name) {
+		return status.Error(codes.PermissionDenied, plugins.ErrReserved.Error())
+	}
+	return nil
+}
+
+//文件接口使用的文件名：配置目录中的相对路径，并且不是aginx自己的数据
+func relativePath(file string) (string, error) {
+	name, err := util.RelativePath(file)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return name, reserved(name)
+}
+
+func (s *Server) GetFile(ctx context.Context, req *FileRequest) (*File, error) {
+	name, err := relativePath(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.storage(ctx).Get(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+	return &File{Name: file.Name, Content: file.Content}, nil
+}
+
+func (s *Server) SearchFiles(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	files, err := plugins.Files(s.storage(ctx)).Search(req.Patterns...)
+	if err != nil {
+		return nil, err
+	}
+	resp := &SearchResponse{}
+	for _, file := range files {
+		resp.Files = append(resp.Files, &File{Name: file.Name, Content: file.Content})
+	}
+	return resp, nil
+}
+
+func (s *Server) PutFile(ctx context.Context, req *File) (*Empty, error) {
+	name, err := relativePath(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	//如果是配置文件需要测试是否可用
+	if filepath.Ext(name) == ".conf" {
+		client, err := s.client(ctx)
+		if err != nil {
+			return nil, err
+		}
+		need := true
+		if includes, err := client.Select("http", "include"); err == nil {
+			for _, include := range includes {
+				if matched, _ := filepath.Match(include.Args[0], name); matched {
+					need = false
+				}
+			}
+		}
+		if need {
+			_ = client.Add(nginx.Queries("http"), nginx.NewDirective("include", name))
+		}
+		if err = s.process.Test(client.Configuration(), func(testDir string) error {
+			return util.WriteFile(filepath.Join(testDir, name), req.Content)
+		}); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if err := s.storage(ctx).Put(name, req.Content); err != nil {
+		return nil, err
+	}
+	if err := s.process.Reload(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *Server) RemoveFile(ctx context.Context, req *FileRequest) (*Empty, error) {
+	name, err := relativePath(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.process.Test(client.Configuration(), func(testDir string) error {
+		return os.Remove(filepath.Join(testDir, name))
+	}); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = s.storage(ctx).Remove(name); err != nil {
+		return nil, err
+	}
+	if err = s.process.Reload(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *Server) Watch(req *WatchRequest, stream Aginx_WatchServer) error {
+	events := make(chan *util.ChangeEvent, 100)
+	unsubscribe := util.SubscribeChanged(func(event *util.ChangeEvent) {
+		if req.File != "" && event.File != req.File && !strings.HasPrefix(event.File, req.File+"/") {
+			return
+		}
+		select {
+		case events <- event:
+		default:
+			logger.Warn("watch client is too slow, drop event ", event.File)
+		}
+	})
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(fromChangeEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
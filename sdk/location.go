@@ -0,0 +1,66 @@
+package sdk
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/nginx"
+	"strings"
+)
+
+//http.server.location
+type Location struct {
+	//所属server的域名，单独Apply时使用
+	Domain string
+
+	Path string
+
+	//代理地址：http://127.0.0.1:8080，或者upstream名称
+	ProxyPass string
+
+	//静态文件目录，ProxyPass为空时使用
+	Root string
+
+	//其他配置
+	Directives []*nginx.Directive
+}
+
+func proxyPass(address string) string {
+	if strings.Contains(address, "://") {
+		return address
+	}
+	return "http://" + address
+}
+
+func (l *Location) Directive() (*nginx.Directive, error) {
+	if l.Path == "" {
+		return nil, errors.New("the path of location is empty")
+	}
+	location := nginx.NewDirective("location", strings.Fields(l.Path)...)
+	if l.ProxyPass != "" {
+		location.AddBody("proxy_pass", proxyPass(l.ProxyPass))
+		location.AddBody("proxy_set_header", "Host", "$host")
+		location.AddBody("proxy_set_header", "X-Real-IP", "$remote_addr")
+		location.AddBody("proxy_set_header", "X-Forwarded-For", "$proxy_add_x_forwarded_for")
+	} else if l.Root != "" {
+		location.AddBody("root", l.Root)
+	}
+	location.AddBodyDirective(l.Directives...)
+	return location, nil
+}
+
+func (l *Location) prepare(aginx api.Aginx, batch api.AginxBatch) error {
+	if l.Domain == "" {
+		return errors.New("the domain of location is empty")
+	}
+	location, err := l.Directive()
+	if err != nil {
+		return err
+	}
+	//跳过https跳转使用的server
+	parent := api.Queries("http", serverQuery(l.Domain)+".location")
+	return replace(aginx, batch, parent, "location("+quote(l.Path)+")", location)
+}
+
+func (l *Location) Apply(aginx api.Aginx) error {
+	return apply(aginx, l)
+}
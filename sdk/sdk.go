@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"fmt"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/nginx"
+	"os"
+	"strings"
+)
+
+//常用的nginx配置对象，Apply时已经存在的同名配置会被替换
+type Object interface {
+	//通过aginx保存配置
+	Apply(aginx api.Aginx) error
+
+	//添加到批量修改中
+	prepare(aginx api.Aginx, batch api.AginxBatch) error
+}
+
+//一次提交多个配置对象，全部成功后才会保存并重启一次nginx
+func Apply(aginx api.Aginx, objects ...Object) error {
+	batch := aginx.Batch()
+	for _, object := range objects {
+		if err := object.prepare(aginx, batch); err != nil {
+			return err
+		}
+	}
+	return batch.Commit()
+}
+
+func apply(aginx api.Aginx, object Object) error {
+	return Apply(aginx, object)
+}
+
+//替换parent下匹配query的配置，不存在时直接添加
+func replace(aginx api.Aginx, batch api.AginxBatch, parent []string, query string, directives ...*nginx.Directive) error {
+	queries := append(append([]string{}, parent...), query)
+	if _, err := aginx.Directive().Select(queries...); err == nil {
+		batch.Delete(queries...)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	batch.Add(parent, directives...)
+	return nil
+}
+
+//查询参数，包含单引号时使用反引号
+func quote(arg string) string {
+	if strings.Contains(arg, "'") {
+		return "`" + arg + "`"
+	}
+	return "'" + arg + "'"
+}
+
+func serverQuery(domain string) string {
+	return fmt.Sprintf("server.server_name(%s)", quote(domain))
+}
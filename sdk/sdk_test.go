@@ -0,0 +1,137 @@
+package sdk
+
+import (
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+//只记录修改的aginx
+type recorder struct {
+	api.Aginx
+	conf       *nginx.Configuration
+	operations []*nginx.Operation
+}
+
+func (r *recorder) Directive() api.AginxDirective {
+	return r
+}
+
+func (r *recorder) Select(queries ...string) ([]*nginx.Directive, error) {
+	return r.conf.Select(queries...)
+}
+
+func (r *recorder) Add(queries []string, addDirectives ...*nginx.Directive) error {
+	panic("implement me")
+}
+
+func (r *recorder) Delete(queries ...string) error {
+	panic("implement me")
+}
+
+func (r *recorder) Modify(queries []string, directive *nginx.Directive) error {
+	panic("implement me")
+}
+
+func (r *recorder) SSL() api.AginxSSL {
+	return r
+}
+
+func (r *recorder) New(accountEmail, domain string) (*lego.StoreFile, error) {
+	return &lego.StoreFile{Certificate: "certificates/" + domain + ".crt", PrivateKey: "certificates/" + domain + ".key"}, nil
+}
+
+func (r *recorder) ReNew(domain string) (*lego.StoreFile, error) {
+	panic("implement me")
+}
+
+func (r *recorder) SelfSigned(domain string, days int) (*lego.StoreFile, error) {
+	panic("implement me")
+}
+
+func (r *recorder) Batch() api.AginxBatch {
+	return &recorderBatch{recorder: r}
+}
+
+type recorderBatch struct {
+	*recorder
+	operations []*nginx.Operation
+}
+
+func (b *recorderBatch) Add(queries []string, addDirectives ...*nginx.Directive) api.AginxBatch {
+	b.operations = append(b.operations, &nginx.Operation{Action: nginx.ActionAdd, Queries: queries, Directives: addDirectives})
+	return b
+}
+
+func (b *recorderBatch) Delete(queries ...string) api.AginxBatch {
+	b.operations = append(b.operations, &nginx.Operation{Action: nginx.ActionDelete, Queries: queries})
+	return b
+}
+
+func (b *recorderBatch) Modify(queries []string, directive *nginx.Directive) api.AginxBatch {
+	panic("implement me")
+}
+
+func (b *recorderBatch) Commit() error {
+	b.recorder.operations = append(b.recorder.operations, b.operations...)
+	return nil
+}
+
+func newRecorder(directives ...*nginx.Directive) *recorder {
+	conf := &nginx.Configuration{Name: "nginx.conf"}
+	conf.AddBody("http").AddBodyDirective(directives...)
+	return &recorder{conf: conf}
+}
+
+func TestUpstream(t *testing.T) {
+	upstream := &Upstream{Name: "api", Servers: []string{"127.0.0.1:8080 weight=2", "127.0.0.1:8081"}, LoadBalance: LeastConn}
+	directive, err := upstream.Directive()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api"}, directive.Args)
+	assert.Equal(t, "least_conn", directive.Body[0].Name)
+	assert.Equal(t, []string{"127.0.0.1:8080", "weight=2"}, directive.Body[1].Args)
+	assert.Equal(t, []string{"127.0.0.1:8081"}, directive.Body[2].Args)
+
+	_, err = (&Upstream{Name: "api"}).Directive()
+	assert.NotNil(t, err)
+
+	aginx := newRecorder(nginx.SimpleUpstream("api", "127.0.0.1:80"))
+	assert.Nil(t, upstream.Apply(aginx))
+	assert.Len(t, aginx.operations, 2)
+	assert.Equal(t, nginx.ActionDelete, aginx.operations[0].Action)
+	assert.Equal(t, []string{"http", "upstream('api')"}, aginx.operations[0].Queries)
+	assert.Equal(t, nginx.ActionAdd, aginx.operations[1].Action)
+	assert.Equal(t, []string{"http"}, aginx.operations[1].Queries)
+}
+
+func TestServer(t *testing.T) {
+	server := &Server{Domain: "api.aginx.io", SSL: true, ProxyPass: "api",
+		Locations: []*Location{{Path: "/static", Root: "/var/www"}}}
+	aginx := newRecorder()
+	assert.Nil(t, server.Apply(aginx))
+	assert.Len(t, aginx.operations, 1)
+
+	directives := aginx.operations[0].Directives
+	assert.Len(t, directives, 2)
+	assert.Equal(t, []string{"301", "https://$host$request_uri"}, directives[0].MustSelect("return")[0].Args)
+	assert.Equal(t, []string{"443", "ssl"}, directives[1].MustSelect("listen")[0].Args)
+	assert.Equal(t, []string{"certificates/api.aginx.io.crt"}, directives[1].MustSelect("ssl_certificate")[0].Args)
+	assert.Equal(t, []string{"http://api"}, directives[1].MustSelect("location('/')", "proxy_pass")[0].Args)
+	assert.Equal(t, []string{"/var/www"}, directives[1].MustSelect("location('/static')", "root")[0].Args)
+}
+
+func TestLocation(t *testing.T) {
+	servers, err := (&Server{Domain: "api.aginx.io", SSL: true, ProxyPass: "api"}).Directives(&lego.StoreFile{})
+	assert.Nil(t, err)
+	aginx := newRecorder(servers...)
+	location := &Location{Domain: "api.aginx.io", Path: "/", ProxyPass: "127.0.0.1:8080"}
+	assert.Nil(t, location.Apply(aginx))
+	assert.Len(t, aginx.operations, 2)
+	assert.Equal(t, []string{"http", "server.server_name('api.aginx.io').location", "location('/')"}, aginx.operations[0].Queries)
+
+	selected, err := aginx.Select(aginx.operations[0].Queries...)
+	assert.Nil(t, err)
+	assert.Len(t, selected, 1)
+}
@@ -0,0 +1,100 @@
+package sdk
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/nginx"
+	"strconv"
+)
+
+//http.server
+type Server struct {
+	Domain string
+
+	//监听端口，为0时使用80，开启SSL时使用443
+	Listen int
+
+	//开启https，Apply时通过aginx申请证书，并添加80端口跳转到https的server
+	SSL bool
+
+	//申请证书使用的邮箱，为空时使用aginx的默认邮箱
+	Email string
+
+	//location / 的代理地址或者静态文件目录
+	ProxyPass string
+	Root      string
+
+	Locations []*Location
+}
+
+func (s *Server) listen() string {
+	if s.Listen != 0 {
+		return strconv.Itoa(s.Listen)
+	} else if s.SSL {
+		return "443"
+	}
+	return "80"
+}
+
+//生成server配置，开启SSL时certificate为使用的证书
+func (s *Server) Directives(certificate *lego.StoreFile) ([]*nginx.Directive, error) {
+	if s.Domain == "" {
+		return nil, errors.New("the domain of server is empty")
+	}
+	server := nginx.NewDirective("server")
+	directives := []*nginx.Directive{server}
+	if s.SSL {
+		if certificate == nil {
+			return nil, errors.New("the certificate of server is empty: " + s.Domain)
+		}
+		server.AddBody("listen", s.listen(), "ssl")
+		server.AddBody("server_name", s.Domain)
+		server.AddBody("ssl_certificate", certificate.Certificate)
+		server.AddBody("ssl_certificate_key", certificate.PrivateKey)
+		server.AddBody("ssl_session_timeout", "5m")
+		server.AddBody("ssl_protocols", "TLSv1", "TLSv1.1", "TLSv1.2")
+		server.AddBody("ssl_prefer_server_ciphers", "on")
+
+		rewrite := nginx.NewDirective("server")
+		rewrite.AddBody("listen", "80")
+		rewrite.AddBody("server_name", s.Domain)
+		rewrite.AddBody("return", "301", "https://$host$request_uri")
+		directives = append([]*nginx.Directive{rewrite}, directives...)
+	} else {
+		server.AddBody("listen", s.listen())
+		server.AddBody("server_name", s.Domain)
+	}
+
+	locations := s.Locations
+	if s.ProxyPass != "" || s.Root != "" {
+		root := &Location{Path: "/", ProxyPass: s.ProxyPass, Root: s.Root}
+		locations = append([]*Location{root}, locations...)
+	}
+	for _, location := range locations {
+		directive, err := location.Directive()
+		if err != nil {
+			return nil, err
+		}
+		server.AddBodyDirective(directive)
+	}
+	return directives, nil
+}
+
+func (s *Server) prepare(aginx api.Aginx, batch api.AginxBatch) (err error) {
+	var certificate *lego.StoreFile
+	if s.SSL && s.Domain != "" {
+		if certificate, err = aginx.SSL().New(s.Email, s.Domain); err != nil {
+			return
+		}
+	}
+	directives, err := s.Directives(certificate)
+	if err != nil {
+		return
+	}
+	return replace(aginx, batch, api.Queries("http"), serverQuery(s.Domain), directives...)
+}
+
+func (s *Server) Apply(aginx api.Aginx) error {
+	return apply(aginx, s)
+}
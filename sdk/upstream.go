@@ -0,0 +1,56 @@
+package sdk
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/api"
+	"github.com/ihaiker/aginx/nginx"
+	"strings"
+)
+
+//负载均衡方式，为空时使用轮询
+const (
+	RoundRobin = ""
+	LeastConn  = "least_conn"
+	IpHash     = "ip_hash"
+	Random     = "random"
+)
+
+//http.upstream
+type Upstream struct {
+	Name string
+
+	//服务地址，可以带参数，例如：127.0.0.1:8080 weight=2 max_fails=3
+	Servers []string
+
+	//负载均衡方式：RoundRobin, LeastConn, IpHash, Random 或者 hash $request_uri consistent
+	LoadBalance string
+}
+
+func (u *Upstream) Directive() (*nginx.Directive, error) {
+	if u.Name == "" {
+		return nil, errors.New("the name of upstream is empty")
+	}
+	if len(u.Servers) == 0 {
+		return nil, errors.New("the servers of upstream is empty: " + u.Name)
+	}
+	upstream := nginx.NewDirective("upstream", u.Name)
+	if args := strings.Fields(u.LoadBalance); len(args) > 0 {
+		upstream.AddBody(args[0], args[1:]...)
+	}
+	for _, server := range u.Servers {
+		upstream.AddBody("server", strings.Fields(server)...)
+	}
+	return upstream, nil
+}
+
+func (u *Upstream) prepare(aginx api.Aginx, batch api.AginxBatch) error {
+	upstream, err := u.Directive()
+	if err != nil {
+		return err
+	}
+	return replace(aginx, batch, api.Queries("http"), "upstream("+quote(u.Name)+")", upstream)
+}
+
+func (u *Upstream) Apply(aginx api.Aginx) error {
+	return apply(aginx, u)
+}
@@ -0,0 +1,171 @@
+package site
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//解压的限制：解压后的总大小和文件（包括目录）数量，避免压缩炸弹占满磁盘
+type quota struct {
+	maxSize, size   int64
+	maxFiles, files int
+}
+
+func newQuota(maxSize int64, maxFiles int) *quota {
+	return &quota{maxSize: maxSize, maxFiles: maxFiles}
+}
+
+func (q *quota) file() error {
+	q.files++
+	if q.maxFiles > 0 && q.files > q.maxFiles {
+		return fmt.Errorf("the archive contains more than %d files", q.maxFiles)
+	}
+	return nil
+}
+
+//写入文件内容，超过总大小时返回错误
+func (q *quota) copy(writer io.Writer, reader io.Reader) error {
+	if q.maxSize <= 0 {
+		_, err := io.Copy(writer, reader)
+		return err
+	}
+	n, err := io.Copy(writer, io.LimitReader(reader, q.maxSize-q.size+1))
+	q.size += n
+	if q.size > q.maxSize {
+		return fmt.Errorf("the uncompressed size of the archive exceeds %d bytes", q.maxSize)
+	}
+	return err
+}
+
+//解压文件到dir，支持zip、tar.gz和tar，根据文件头判断格式
+func extract(content []byte, dir string, q *quota) error {
+	if bytes.HasPrefix(content, []byte("PK\x03\x04")) {
+		return extractZip(content, dir, q)
+	}
+	var reader io.Reader = bytes.NewReader(content)
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gz.Close() }()
+		reader = gz
+	}
+	return extractTar(reader, dir, q)
+}
+
+//压缩包中的路径，不能跳出dir
+func target(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if path != dir && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", errors.New("invalid file path in the archive: " + name)
+	}
+	return path, nil
+}
+
+func writeFile(path string, reader io.Reader, q *quota) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	return q.copy(file, reader)
+}
+
+func extractZip(content []byte, dir string, q *quota) error {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+	for _, file := range archive.File {
+		path, err := target(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		if err = q.file(); err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err = os.MkdirAll(path, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		} else if !file.Mode().IsRegular() {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(path, reader, q)
+		_ = reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//只解压目录和普通文件，忽略链接等
+func extractTar(reader io.Reader, dir string, q *quota) error {
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		path, err := target(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		if err = q.file(); err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, os.ModePerm)
+		case tar.TypeReg, tar.TypeRegA:
+			err = writeFile(path, archive, q)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+//压缩包中只有一个目录时（例如：dist/），使用此目录作为站点的根目录
+func flatten(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 || !files[0].IsDir() {
+		return err
+	}
+	//先改名，避免和目录中同名的文件冲突（例如：dist/dist）
+	nested := filepath.Join(dir, ".flatten")
+	if err = os.Rename(filepath.Join(dir, files[0].Name()), nested); err != nil {
+		return err
+	}
+	children, err := ioutil.ReadDir(nested)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err = os.Rename(filepath.Join(nested, child.Name()), filepath.Join(dir, child.Name())); err != nil {
+			return err
+		}
+	}
+	return os.Remove(nested)
+}
@@ -0,0 +1,243 @@
+package site
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("site")
+
+var (
+	ErrNotFound   = errors.New("site not found")
+	ErrNotEnabled = errors.New("the static site is not enabled, use --site-root")
+)
+
+const (
+	releasesDir = "releases"
+	currentLink = "current"
+)
+
+//站点的一个版本
+type Release struct {
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	Current bool      `json:"current"`
+}
+
+type Site struct {
+	Domain   string     `json:"domain"`
+	Root     string     `json:"root"` //nginx使用的root，指向当前版本的链接
+	Releases []*Release `json:"releases"`
+}
+
+//静态站点，保存在本地磁盘：<root>/<domain>/releases/<version>，
+//<root>/<domain>/current 链接到当前版本，切换版本时只修改链接，不需要重启nginx
+type Sites struct {
+	root string
+	keep int //保留的版本数量，0为全部保留
+	lock sync.Mutex
+
+	MaxSize  int64 //一个版本解压后的总大小，0为不限制
+	MaxFiles int   //一个版本的文件（包括目录）数量，0为不限制
+}
+
+func New(root string, keep int) *Sites {
+	return &Sites{root: root, keep: keep, MaxSize: 1024 * 1024 * 1024, MaxFiles: 100000}
+}
+
+func (s *Sites) dir(domain string) (string, error) {
+	if domain == "" || domain == "." || domain == ".." || strings.ContainsAny(domain, " ;'\"/\\") {
+		return "", errors.New("invalid domain: " + domain)
+	}
+	return filepath.Join(s.root, domain), nil
+}
+
+//nginx server中使用的root
+func (s *Sites) Root(domain string) string {
+	return filepath.Join(s.root, domain, currentLink)
+}
+
+func (s *Sites) current(dir string) string {
+	link, err := os.Readlink(filepath.Join(dir, currentLink))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(link)
+}
+
+//版本按照创建顺序排列
+func (s *Sites) releases(dir string) ([]*Release, error) {
+	files, err := ioutil.ReadDir(filepath.Join(dir, releasesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	current := s.current(dir)
+	releases := make([]*Release, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
+			releases = append(releases, &Release{
+				Version: file.Name(), Created: file.ModTime(), Current: file.Name() == current,
+			})
+		}
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Version < releases[j].Version
+	})
+	return releases, nil
+}
+
+func (s *Sites) Get(domain string) (*Site, error) {
+	dir, err := s.dir(domain)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := s.releases(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Site{Domain: domain, Root: s.Root(domain), Releases: releases}, nil
+}
+
+func (s *Sites) List() ([]*Site, error) {
+	files, err := ioutil.ReadDir(s.root)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sites := make([]*Site, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() {
+			if site, err := s.Get(file.Name()); err == nil {
+				sites = append(sites, site)
+			}
+		}
+	}
+	return sites, nil
+}
+
+//切换当前版本，先创建临时链接再替换
+func (s *Sites) link(dir, version string) error {
+	temp := filepath.Join(dir, "."+currentLink)
+	_ = os.Remove(temp)
+	if err := os.Symlink(filepath.Join(releasesDir, version), temp); err != nil {
+		return err
+	}
+	return os.Rename(temp, filepath.Join(dir, currentLink))
+}
+
+//删除多余的旧版本，不会删除当前版本
+func (s *Sites) prune(dir string) error {
+	if s.keep <= 0 {
+		return nil
+	}
+	releases, err := s.releases(dir)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(releases)-s.keep; i++ {
+		if !releases[i].Current {
+			if err = os.RemoveAll(filepath.Join(dir, releasesDir, releases[i].Version)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//发布新版本：解压zip、tar.gz或tar文件，并切换为当前版本
+func (s *Sites) Deploy(domain string, content []byte) (*Release, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	dir, err := s.dir(domain)
+	if err != nil {
+		return nil, err
+	}
+	version := time.Now().Format("20060102150405")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, releasesDir, version)); os.IsNotExist(err) {
+			break
+		}
+		version = fmt.Sprintf("%s-%d", time.Now().Format("20060102150405"), i)
+	}
+	//先解压到临时目录，失败时不会留下不完整的版本
+	temp := filepath.Join(dir, releasesDir, "."+version)
+	if err = os.MkdirAll(temp, os.ModePerm); err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(temp) }()
+	if err = extract(content, temp, newQuota(s.MaxSize, s.MaxFiles)); err != nil {
+		return nil, err
+	}
+	if err = flatten(temp); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(temp, filepath.Join(dir, releasesDir, version)); err != nil {
+		return nil, err
+	}
+	if err = s.link(dir, version); err != nil {
+		return nil, err
+	}
+	logger.Info("deploy site ", domain, " release ", version)
+	if err = s.prune(dir); err != nil {
+		logger.WithError(err).Warn("prune releases of ", domain)
+	}
+	return &Release{Version: version, Created: time.Now(), Current: true}, nil
+}
+
+//回滚到指定版本，version为空时回滚到当前版本的上一个版本
+func (s *Sites) Rollback(domain, version string) (*Release, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	dir, err := s.dir(domain)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := s.releases(dir)
+	if err != nil {
+		return nil, err
+	}
+	var release *Release
+	for i, r := range releases {
+		if version == "" && r.Current && i > 0 {
+			release = releases[i-1]
+		} else if version != "" && r.Version == version {
+			release = r
+		}
+	}
+	if release == nil {
+		if version == "" {
+			return nil, errors.New("no previous release of " + domain)
+		}
+		return nil, fmt.Errorf("%w: release %s", ErrNotFound, version)
+	}
+	if err = s.link(dir, release.Version); err != nil {
+		return nil, err
+	}
+	logger.Info("rollback site ", domain, " to release ", release.Version)
+	release.Current = true
+	return release, nil
+}
+
+//删除站点的全部版本
+func (s *Sites) Delete(domain string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	dir, err := s.dir(domain)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,118 @@
+package site
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	out := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(out)
+	archive := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.Nil(t, archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := archive.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, archive.Close())
+	assert.Nil(t, gz.Close())
+	return out.Bytes()
+}
+
+func zipFile(t *testing.T, files map[string]string) []byte {
+	out := bytes.NewBuffer(nil)
+	archive := zip.NewWriter(out)
+	for name, content := range files {
+		writer, err := archive.Create(name)
+		assert.Nil(t, err)
+		_, err = writer.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, archive.Close())
+	return out.Bytes()
+}
+
+func TestSites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	sites := New(dir, 2)
+
+	_, err = sites.Deploy("../www", zipFile(t, map[string]string{"index.html": "v1"}))
+	assert.NotNil(t, err)
+	_, err = sites.Deploy("www.aginx.io", tarGz(t, map[string]string{"../index.html": "v1"}))
+	assert.NotNil(t, err)
+
+	v1, err := sites.Deploy("www.aginx.io", zipFile(t, map[string]string{"index.html": "v1"}))
+	assert.Nil(t, err)
+	//只有一个目录时使用此目录作为根目录
+	v2, err := sites.Deploy("www.aginx.io", tarGz(t, map[string]string{"dist/index.html": "v2", "dist/js/app.js": "app"}))
+	assert.Nil(t, err)
+	assert.NotEqual(t, v1.Version, v2.Version)
+
+	index := filepath.Join(sites.Root("www.aginx.io"), "index.html")
+	content, _ := ioutil.ReadFile(index)
+	assert.Equal(t, "v2", string(content))
+	_, err = os.Stat(filepath.Join(sites.Root("www.aginx.io"), "js", "app.js"))
+	assert.Nil(t, err)
+
+	release, err := sites.Rollback("www.aginx.io", "")
+	assert.Nil(t, err)
+	assert.Equal(t, v1.Version, release.Version)
+	content, _ = ioutil.ReadFile(index)
+	assert.Equal(t, "v1", string(content))
+	_, err = sites.Rollback("www.aginx.io", "")
+	assert.NotNil(t, err)
+	_, err = sites.Rollback("www.aginx.io", "none")
+	assert.NotNil(t, err)
+
+	//保留2个版本，删除最旧的版本
+	_, err = sites.Deploy("www.aginx.io", zipFile(t, map[string]string{"index.html": "v3"}))
+	assert.Nil(t, err)
+	site, err := sites.Get("www.aginx.io")
+	assert.Nil(t, err)
+	assert.Len(t, site.Releases, 2)
+	assert.Equal(t, v2.Version, site.Releases[0].Version)
+	assert.True(t, site.Releases[1].Current)
+
+	list, err := sites.List()
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+
+	assert.Nil(t, sites.Delete("www.aginx.io"))
+	assert.Equal(t, ErrNotFound, sites.Delete("www.aginx.io"))
+	_, err = sites.Get("www.aginx.io")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+//解压后的总大小和文件数量超过限制时不会发布
+func TestDeployLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	sites := New(dir, 2)
+	sites.MaxSize, sites.MaxFiles = 10, 2
+
+	_, err = sites.Deploy("www.aginx.io", zipFile(t, map[string]string{"index.html": "0123456789a"}))
+	assert.NotNil(t, err)
+	_, err = sites.Deploy("www.aginx.io", tarGz(t, map[string]string{"a.html": "012345", "b.html": "012345"}))
+	assert.NotNil(t, err)
+	_, err = sites.Deploy("www.aginx.io", tarGz(t, map[string]string{"a.html": "a", "b.html": "b", "c.html": "c"}))
+	assert.NotNil(t, err)
+	site, err := sites.Get("www.aginx.io")
+	assert.Nil(t, err)
+	assert.Empty(t, site.Releases)
+
+	_, err = sites.Deploy("www.aginx.io", zipFile(t, map[string]string{"a.html": "01234", "b.html": "56789"}))
+	assert.Nil(t, err)
+	site, err = sites.Get("www.aginx.io")
+	assert.Nil(t, err)
+	assert.Len(t, site.Releases, 1)
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+)
+
+//备份存储中的全部文件（配置文件和证书）为tar.gz，返回备份的文件数量
+func Backup(engine plugins.StorageEngine, writer io.Writer) (int, error) {
+	files, err := engine.Search()
+	if err != nil {
+		return 0, err
+	}
+	gz := gzip.NewWriter(writer)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range files {
+		header := &tar.Header{
+			Name: file.Name, Mode: 0644, Size: int64(len(file.Content)),
+			ModTime: now, Typeflag: tar.TypeReg,
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			return 0, err
+		}
+		if _, err = tw.Write(file.Content); err != nil {
+			return 0, err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return 0, err
+	}
+	return len(files), gz.Close()
+}
+
+//读取tar.gz备份中的文件
+func ReadBackup(reader io.Reader) ([]*plugins.ConfigurationFile, error) {
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+
+	files := make([]*plugins.ConfigurationFile, 0)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, errors.New("invalid file in backup: " + header.Name)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, plugins.NewFile(name, content))
+	}
+	return files, nil
+}
+
+//恢复备份中的文件到存储中，clean为true时删除备份中没有的文件
+func Restore(engine plugins.StorageEngine, files []*plugins.ConfigurationFile, clean bool) error {
+	return plugins.Batch(engine, func(engine plugins.StorageEngine) error {
+		if clean {
+			exists, err := engine.Search()
+			if err != nil {
+				return err
+			}
+			for _, file := range exists {
+				if _, has := contains(files, file); !has {
+					logger.Info("restore, remove file ", file.Name)
+					if err = engine.Remove(file.Name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		for _, file := range files {
+			logger.Info("restore file ", file.Name)
+			if err := engine.Put(file.Name, file.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
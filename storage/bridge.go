@@ -1,31 +1,57 @@
 package storage
 
 import (
-	"bytes"
+	"context"
 	"github.com/ihaiker/aginx/plugins"
 	"github.com/ihaiker/aginx/storage/file"
 	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 )
 
+//使用 --storage 时本地文件被直接修改（例如：vim）的处理方式
+const (
+	ExternalEditImport   = "import"   //同步到存储中并重启nginx
+	ExternalEditConflict = "conflict" //不同步，发送 external-edit 事件，使用 /api/diff 查看和同步
+)
+
 type bridge struct {
 	plugins.StorageEngine
 	LocalStorageEngine plugins.StorageEngine
+	cluster            plugins.StorageEngine //未使用环境目录的存储
+	Profiles           *Profiles
 
-	watcher   bool
-	configDir string
+	watcher      bool
+	configDir    string
+	ExternalEdit string
+	Conflicts    *Conflicts
+	Overlays     *Overlays //节点配置覆盖，nil时不使用
 
 	localWatcher, clusterWatcher <-chan plugins.FileEvent
 	closeC                       chan struct{}
+	doneC                        chan struct{} //监听退出，正在进行的同步已经完成
 }
 
-func NewBridge(cluster string, watcher bool, conf string) *bridge {
+//profile不为空时使用存储中 profiles/<profile>/ 中的配置
+func NewBridge(cluster string, watcher bool, conf string, overlays *Overlays, profile string) *bridge {
+	engine := FindStorage(cluster)
 	b := &bridge{
-		StorageEngine: FindStorage(cluster),
+		StorageEngine: engine,
+		cluster:       engine,
+		Profiles:      NewProfiles(engine, profile),
+		Overlays:      overlays,
 		watcher:       watcher,
 		configDir:     filepath.Dir(conf),
+		ExternalEdit:  ExternalEditImport,
+		Conflicts:     NewConflicts(ConflictRemoteWins),
 		closeC:        make(chan struct{}),
+		doneC:         make(chan struct{}),
+	}
+	if profile != "" {
+		var err error
+		b.StorageEngine, err = NewProfile(engine, profile)
+		util.PanicIfError(err)
 	}
 	b.initalize(conf)
 	return b
@@ -35,11 +61,18 @@ func NewBridge(cluster string, watcher bool, conf string) *bridge {
 func (sb *bridge) initalize(conf string) {
 	if sb.IsCluster() {
 		sb.LocalStorageEngine = file.New(conf)
-		util.PanicIfError(Sync(sb.StorageEngine, sb.LocalStorageEngine))
+		view := &overlayView{StorageEngine: sb.StorageEngine, overlays: sb.Overlays}
+		util.PanicIfError(Sync(view, sb.LocalStorageEngine))
+		files, err := view.Search()
+		util.PanicIfError(err)
+		for _, file := range files {
+			sb.Conflicts.synced(file.Name, fileContent(file.Content))
+		}
 	}
 }
 
 func (sb *bridge) StartWatcher() {
+	defer close(sb.doneC)
 
 	sb.clusterWatcher = sb.StorageEngine.StartListener()
 	if sb.watcher && sb.LocalStorageEngine != nil {
@@ -54,60 +87,218 @@ func (sb *bridge) StartWatcher() {
 			return
 		case event, has := <-sb.clusterWatcher:
 			if has {
-				changed := false
-				if event.Type == plugins.FileEventTypeRemove {
-					for _, path := range event.Paths {
-						absPath := filepath.Join(sb.configDir, path.Name)
-						if util.Exists(absPath) {
-							changed = true
-							err := os.RemoveAll(absPath)
-							logger.Info("sync cluster, remove ", path.Name, " ", err)
-						}
-					}
-				} else if event.Type == plugins.FileEventTypeUpdate {
-					for _, path := range event.Paths {
-						absPath := filepath.Join(sb.configDir, path.Name)
-						if write, _ := util.DiffWriteFile(absPath, path.Content); write {
-							changed = true
-							logger.Info("sync cluster, file ", path.Name)
-						}
-					}
-				}
-
-				if sb.watcher && (sb.LocalStorageEngine == nil || changed) {
-					logger.Info("file changed : ", event.String())
-					util.PublishFileChanged()
+				for _, e := range sb.overlay(event) {
+					sb.clusterChanged(e)
 				}
 			}
 		case event, has := <-sb.localWatcher:
 			if has {
-				changed := false
-				if event.Type == plugins.FileEventTypeRemove {
-					for _, path := range event.Paths {
-						_ = sb.StorageEngine.Remove(path.Name)
-					}
-				} else if event.Type == plugins.FileEventTypeUpdate {
-					for _, path := range event.Paths {
-						if file, err := sb.StorageEngine.Get(path.Name); err == os.ErrNotExist {
-							_ = sb.StorageEngine.Put(path.Name, path.Content)
-							changed = true
-						} else if err == nil {
-							if !bytes.Equal(file.Content, path.Content) {
-								_ = sb.StorageEngine.Put(path.Name, path.Content)
-								changed = true
-							}
-						} else {
-							logger.Warn("sync file ", path.Name, " error ", err)
-						}
-					}
+				sb.localChanged(event)
+			}
+		}
+	}
+}
+
+//存储中的文件变化转换为本地文件的变化：忽略其他节点的覆盖文件，本地文件使用优先级最高的内容
+func (sb *bridge) overlay(event plugins.FileEvent) []plugins.FileEvent {
+	if sb.Overlays == nil || sb.LocalStorageEngine == nil {
+		return []plugins.FileEvent{event}
+	}
+	updates := plugins.FileEvent{Type: plugins.FileEventTypeUpdate}
+	removes := plugins.FileEvent{Type: plugins.FileEventTypeRemove}
+	for _, path := range event.Paths {
+		name, _, ok := sb.Overlays.local(path.Name)
+		if !ok {
+			continue
+		}
+		if _, content, err := effective(sb.StorageEngine, sb.Overlays, name); err == nil {
+			updates.Paths = append(updates.Paths, plugins.ConfigurationFile{Name: name, Content: content})
+		} else if os.IsNotExist(err) {
+			removes.Paths = append(removes.Paths, plugins.ConfigurationFile{Name: name, Content: path.Content})
+		} else {
+			logger.Warn("sync file ", path.Name, " error ", err)
+		}
+	}
+	events := make([]plugins.FileEvent, 0, 2)
+	for _, e := range []plugins.FileEvent{removes, updates} {
+		if len(e.Paths) > 0 {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+//本地文件在存储中对应的文件，使用了覆盖文件时返回覆盖文件
+func (sb *bridge) target(name string) string {
+	if sb.Overlays == nil {
+		return name
+	}
+	if target, _, err := effective(sb.StorageEngine, sb.Overlays, name); err == nil {
+		return target
+	}
+	return name
+}
+
+//存储中的文件变化，同步到本地
+func (sb *bridge) clusterChanged(event plugins.FileEvent) {
+	changed := false
+	source := util.ChangeSourceCluster
+	if !sb.IsCluster() {
+		source = util.ChangeSourceLocal
+	}
+	if event.Type == plugins.FileEventTypeRemove {
+		for _, path := range event.Paths {
+			absPath := filepath.Join(sb.configDir, path.Name)
+			if sb.LocalStorageEngine != nil {
+				local, _ := ioutil.ReadFile(absPath)
+				if winner := sb.concurrent(path.Name, local, nil); winner == ConflictLocalWins {
+					changed = sb.syncStorage(path.Name, local) || changed
+					continue
+				} else if winner == ConflictManual {
+					continue
 				}
-				if changed {
-					logger.Info("file changed :", event.String())
-					util.PublishFileChanged()
+			}
+			if util.Exists(absPath) {
+				changed = true
+				err := os.RemoveAll(absPath)
+				logger.Info("sync cluster, remove ", path.Name, " ", err)
+				util.PublishChanged(&util.ChangeEvent{Source: source, File: path.Name, Type: string(event.Type)})
+			} else if sb.LocalStorageEngine == nil {
+				util.PublishChanged(&util.ChangeEvent{Source: source, File: path.Name, Type: string(event.Type)})
+			}
+			sb.Conflicts.synced(path.Name, nil)
+		}
+	} else if event.Type == plugins.FileEventTypeUpdate {
+		for _, path := range event.Paths {
+			absPath := filepath.Join(sb.configDir, path.Name)
+			old, _ := ioutil.ReadFile(absPath)
+			if sb.LocalStorageEngine != nil {
+				if winner := sb.concurrent(path.Name, old, fileContent(path.Content)); winner == ConflictLocalWins {
+					changed = sb.syncStorage(path.Name, old) || changed
+					continue
+				} else if winner == ConflictManual {
+					continue
 				}
 			}
+			if write, _ := util.DiffWriteFile(absPath, path.Content); write {
+				changed = true
+				logger.Info("sync cluster, file ", path.Name)
+				util.PublishChanged(&util.ChangeEvent{
+					Source: source, File: path.Name, Type: string(event.Type),
+					Diff: util.Diff(string(old), string(path.Content)),
+				})
+			} else if sb.LocalStorageEngine == nil {
+				util.PublishChanged(&util.ChangeEvent{Source: source, File: path.Name, Type: string(event.Type)})
+			}
+			sb.Conflicts.synced(path.Name, fileContent(path.Content))
+		}
+	}
+
+	if sb.watcher && (sb.LocalStorageEngine == nil || changed) {
+		logger.Info("file changed : ", event.String())
+		util.PublishFileChanged()
+	}
+}
+
+//本地文件变化，同步到存储中
+func (sb *bridge) localChanged(event plugins.FileEvent) {
+	changed := false
+	for _, path := range event.Paths {
+		var local, remote []byte
+		if event.Type == plugins.FileEventTypeUpdate {
+			local = fileContent(path.Content)
+		}
+		file, err := sb.StorageEngine.Get(sb.target(path.Name))
+		if err == nil {
+			remote = fileContent(file.Content)
+		} else if !os.IsNotExist(err) {
+			logger.Warn("sync file ", path.Name, " error ", err)
+			continue
+		}
+		if winner := sb.concurrent(path.Name, local, remote); winner == ConflictRemoteWins {
+			changed = sb.syncLocal(path.Name, remote) || changed
+			continue
+		} else if winner == ConflictManual || equal(local, remote) {
+			continue
+		} else if winner == "" && sb.ExternalEdit == ExternalEditConflict {
+			sb.conflict(&util.ChangeEvent{
+				Source: util.ChangeSourceLocal, File: path.Name, Type: string(event.Type),
+				Diff: util.Diff(string(remote), string(local)),
+			})
+			continue
+		}
+		if sb.syncStorage(path.Name, local) && local != nil {
+			changed = true
 		}
 	}
+	if changed {
+		logger.Info("file changed :", event.String())
+		util.PublishFileChanged()
+	}
+}
+
+//按照策略处理本地和存储中的文件同时修改，返回使用的一方，没有冲突时返回空
+func (sb *bridge) concurrent(file string, local, remote []byte) string {
+	if !sb.Conflicts.concurrent(file, local, remote) {
+		return ""
+	}
+	policy := sb.Conflicts.Policy
+	if policy == ConflictManual {
+		sb.Conflicts.add(file, local, remote)
+	}
+	logger.Warn("the file ", file, " is changed both in local and storage, ", policy)
+	util.PublishEvent(util.EventSyncConflict, file, nil, map[string]interface{}{
+		"policy": policy, "file": file, "markers": Markers(local, remote),
+	})
+	return policy
+}
+
+//使用本地文件覆盖存储中的文件（使用了覆盖文件时修改覆盖文件），content为nil时删除
+func (sb *bridge) syncStorage(file string, content []byte) bool {
+	var err error
+	eventType := plugins.FileEventTypeUpdate
+	diff := ""
+	target := sb.target(file)
+	if content == nil {
+		eventType = plugins.FileEventTypeRemove
+		err = sb.StorageEngine.Remove(target)
+	} else {
+		if old, getErr := sb.StorageEngine.Get(target); getErr == nil {
+			diff = util.Diff(string(old.Content), string(content))
+		} else {
+			diff = util.Diff("", string(content))
+		}
+		err = sb.StorageEngine.Put(target, content)
+	}
+	if err != nil {
+		logger.Warn("sync file ", file, " error ", err)
+		return false
+	}
+	sb.Conflicts.synced(file, content)
+	util.PublishChanged(&util.ChangeEvent{Source: util.ChangeSourceLocal, File: target, Type: string(eventType), Diff: diff})
+	return true
+}
+
+//使用存储中的文件覆盖本地文件，content为nil时删除
+func (sb *bridge) syncLocal(file string, content []byte) bool {
+	var err error
+	if content == nil {
+		err = sb.LocalStorageEngine.Remove(file)
+	} else {
+		err = sb.LocalStorageEngine.Put(file, content)
+	}
+	if err != nil {
+		logger.Warn("sync file ", file, " error ", err)
+		return false
+	}
+	sb.Conflicts.synced(file, content)
+	return true
+}
+
+//本地文件被直接修改，和存储中的配置不一致
+func (sb *bridge) conflict(event *util.ChangeEvent) {
+	logger.Warn("the local file ", event.File, " is changed outside aginx, ", event.Type)
+	util.PublishEvent(util.EventExternalEdit, event.File, nil, event)
 }
 
 func (sb *bridge) Start() error {
@@ -115,27 +306,131 @@ func (sb *bridge) Start() error {
 	return util.StartService(sb.StorageEngine)
 }
 
+//停止监听，等待正在进行的同步写入完成后再停止存储
 func (sb *bridge) Stop() error {
 	close(sb.closeC)
+	<-sb.doneC
+	_ = util.StopService(sb.LocalStorageEngine)
 	return util.StopService(sb.StorageEngine)
 }
 
+//绑定ctx的集群存储，本地存储不需要请求信息
+func (sb *bridge) WithContext(ctx context.Context) plugins.StorageEngine {
+	bound := *sb
+	bound.StorageEngine = plugins.WithContext(ctx, sb.StorageEngine)
+	return &bound
+}
+
+func (sb *bridge) Context() context.Context {
+	return plugins.Context(sb.StorageEngine)
+}
+
 //双向操作,put
 func (sb *bridge) Put(file string, content []byte) error {
+	if sb.Overlays != nil && sb.LocalStorageEngine != nil {
+		if err := sb.StorageEngine.Put(file, content); err != nil {
+			return err
+		}
+		return sb.refresh(file)
+	}
+	//先写入集群存储，版本冲突（ErrConflict）时本地文件不变
+	if err := sb.StorageEngine.Put(file, content); err != nil {
+		return err
+	}
 	if sb.LocalStorageEngine != nil {
 		if err := sb.LocalStorageEngine.Put(file, content); err != nil {
 			return err
 		}
 	}
-	return sb.StorageEngine.Put(file, content)
+	sb.Conflicts.synced(file, content)
+	return nil
 }
 
 //双向操作,remove
 func (sb *bridge) Remove(file string) error {
+	if sb.Overlays != nil && sb.LocalStorageEngine != nil {
+		if err := sb.StorageEngine.Remove(file); err != nil {
+			return err
+		}
+		return sb.refresh(file)
+	}
+	if err := sb.StorageEngine.Remove(file); err != nil {
+		return err
+	}
 	if sb.LocalStorageEngine != nil {
 		if err := sb.LocalStorageEngine.Remove(file); err != nil {
 			return err
 		}
 	}
-	return sb.StorageEngine.Remove(file)
+	sb.Conflicts.synced(file, nil)
+	return nil
+}
+
+//存储中的文件修改后按照覆盖规则更新本地文件，其他节点的覆盖文件忽略
+func (sb *bridge) refresh(file string) error {
+	name, _, ok := sb.Overlays.local(file)
+	if !ok {
+		return nil
+	}
+	_, content, err := effective(sb.StorageEngine, sb.Overlays, name)
+	if os.IsNotExist(err) {
+		if content, err = nil, sb.LocalStorageEngine.Remove(name); os.IsNotExist(err) {
+			err = nil
+		}
+	} else if err == nil {
+		err = sb.LocalStorageEngine.Put(name, content)
+	}
+	if err != nil {
+		return err
+	}
+	sb.Conflicts.synced(name, content)
+	return nil
+}
+
+//当前节点使用的覆盖文件（节点、标签目录中的文件）使用本地文件名写入dir，用于测试节点实际使用的配置
+func (sb *bridge) WriteOverlays(dir string) error {
+	if sb.Overlays == nil || sb.LocalStorageEngine == nil {
+		return nil
+	}
+	files, err := sb.StorageEngine.Search()
+	if err != nil {
+		return err
+	}
+	overlaid := make(map[string]bool)
+	for _, file := range files {
+		if name, priority, ok := sb.Overlays.local(file.Name); ok && priority > 0 && !overlaid[name] {
+			overlaid[name] = true
+			_, content, err := effective(sb.StorageEngine, sb.Overlays, name)
+			if err != nil {
+				return err
+			}
+			if err = util.WriteFile(filepath.Join(dir, name), content); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//集群存储支持leader选举时返回选举接口，不支持时返回nil（每个节点都是leader）
+func (sb *bridge) Elector() plugins.Elector {
+	if !sb.IsCluster() {
+		return nil
+	}
+	if elector, match := sb.cluster.(plugins.Elector); match {
+		return elector
+	}
+	logger.Warn("the storage does not support leader election, every node renews certificates and runs registries")
+	return nil
+}
+
+//集群存储支持节点注册时返回注册接口，不支持时返回nil
+func (sb *bridge) Membership() plugins.Membership {
+	if !sb.IsCluster() {
+		return nil
+	}
+	if membership, match := sb.cluster.(plugins.Membership); match {
+		return membership
+	}
+	return nil
 }
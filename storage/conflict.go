@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//本地文件和存储中的文件在同步前都被修改时的处理策略
+const (
+	ConflictRemoteWins = "remote-wins" //使用存储中的文件覆盖本地文件
+	ConflictLocalWins  = "local-wins"  //使用本地文件覆盖存储中的文件
+	ConflictManual     = "manual"      //两边都不修改，记录冲突，使用 /api/conflicts 处理
+)
+
+var ErrConflictNotFound = errors.New("not found the conflict")
+
+//同步冲突，内容为nil表示文件已经被删除
+type Conflict struct {
+	File    string    `json:"file"`
+	Time    time.Time `json:"time"`
+	Local   []byte    `json:"-"`
+	Remote  []byte    `json:"-"`
+	Markers string    `json:"markers"` //带冲突标记的内容，编辑后可以作为合并结果提交
+}
+
+//记录每个文件上一次同步的内容，用于判断两边是否都做了修改
+type Conflicts struct {
+	Policy string
+
+	lock  sync.Mutex
+	base  map[string][]byte
+	items map[string]*Conflict
+}
+
+func NewConflicts(policy string) *Conflicts {
+	return &Conflicts{
+		Policy: policy,
+		base:   make(map[string][]byte),
+		items:  make(map[string]*Conflict),
+	}
+}
+
+func equal(a, b []byte) bool {
+	return (a == nil) == (b == nil) && bytes.Equal(a, b)
+}
+
+//空文件的内容，和被删除的文件(nil)区分开
+func fileContent(data []byte) []byte {
+	if data == nil {
+		return []byte{}
+	}
+	return data
+}
+
+//记录文件同步后的内容，同时清除文件的冲突
+func (cs *Conflicts) synced(file string, content []byte) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.base[file] = content
+	delete(cs.items, file)
+}
+
+//本地和存储中的文件是否都在上一次同步之后做了修改，并且修改的内容不同。内容相同时记录为已同步
+func (cs *Conflicts) concurrent(file string, local, remote []byte) bool {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if equal(local, remote) {
+		cs.base[file] = local
+		delete(cs.items, file)
+		return false
+	}
+	if _, has := cs.items[file]; has {
+		return true
+	}
+	base, has := cs.base[file]
+	return has && !equal(local, base) && !equal(remote, base) || !has && local != nil && remote != nil
+}
+
+//记录冲突，已经存在时更新内容
+func (cs *Conflicts) add(file string, local, remote []byte) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.items[file] = &Conflict{
+		File: file, Time: time.Now(), Local: local, Remote: remote,
+		Markers: Markers(local, remote),
+	}
+}
+
+//是否有未处理的冲突
+func (cs *Conflicts) Pending(file string) bool {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	_, has := cs.items[file]
+	return has
+}
+
+//全部未处理的冲突
+func (cs *Conflicts) List() []*Conflict {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	conflicts := make([]*Conflict, 0, len(cs.items))
+	for _, conflict := range cs.items {
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].File < conflicts[j].File
+	})
+	return conflicts
+}
+
+func (cs *Conflicts) Get(file string) (*Conflict, error) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if conflict, has := cs.items[file]; has {
+		return conflict, nil
+	}
+	return nil, ErrConflictNotFound
+}
+
+func lines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{}
+	}
+	return strings.SplitAfter(strings.TrimSuffix(string(content), "\n")+"\n", "\n")
+}
+
+//生成git格式的冲突标记，相同的开头和结尾不标记。文件被删除时标记为空
+func Markers(local, remote []byte) string {
+	localLines, remoteLines := lines(local), lines(remote)
+	prefix := 0
+	for prefix < len(localLines) && prefix < len(remoteLines) && localLines[prefix] == remoteLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(localLines)-prefix && suffix < len(remoteLines)-prefix &&
+		localLines[len(localLines)-1-suffix] == remoteLines[len(remoteLines)-1-suffix] {
+		suffix++
+	}
+	out := strings.Builder{}
+	out.WriteString(strings.Join(localLines[:prefix], ""))
+	out.WriteString("<<<<<<< local\n")
+	out.WriteString(strings.Join(localLines[prefix:len(localLines)-suffix], ""))
+	out.WriteString("=======\n")
+	out.WriteString(strings.Join(remoteLines[prefix:len(remoteLines)-suffix], ""))
+	out.WriteString(">>>>>>> storage\n")
+	out.WriteString(strings.Join(localLines[len(localLines)-suffix:], ""))
+	return out.String()
+}
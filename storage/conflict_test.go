@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMarkers(t *testing.T) {
+	local := []byte("server {\n    listen 8080;\n}\n")
+	remote := []byte("server {\n    listen 8081;\n}\n")
+	assert.Equal(t, "server {\n<<<<<<< local\n    listen 8080;\n=======\n    listen 8081;\n>>>>>>> storage\n}\n",
+		Markers(local, remote))
+
+	assert.Equal(t, "<<<<<<< local\n=======\nserver {}\n>>>>>>> storage\n", Markers(nil, []byte("server {}")))
+}
+
+func TestConcurrent(t *testing.T) {
+	cs := NewConflicts(ConflictManual)
+	cs.synced("api.conf", []byte("v1"))
+
+	//只有一边修改
+	assert.False(t, cs.concurrent("api.conf", []byte("v1"), []byte("v2")))
+	assert.False(t, cs.concurrent("api.conf", []byte("v2"), []byte("v1")))
+	//两边修改相同
+	assert.False(t, cs.concurrent("api.conf", []byte("v2"), []byte("v2")))
+
+	//两边都修改
+	assert.True(t, cs.concurrent("api.conf", []byte("v3"), []byte("v4")))
+	assert.True(t, cs.concurrent("api.conf", nil, []byte("v4")))
+	cs.add("api.conf", []byte("v3"), []byte("v4"))
+	assert.Len(t, cs.List(), 1)
+	assert.True(t, cs.Pending("api.conf"))
+
+	//冲突没有处理之前，只要不同就是冲突
+	assert.True(t, cs.concurrent("api.conf", []byte("v2"), []byte("v4")))
+	//修改一致后冲突消失
+	assert.False(t, cs.concurrent("api.conf", []byte("v4"), []byte("v4")))
+	assert.False(t, cs.Pending("api.conf"))
+	_, err := cs.Get("api.conf")
+	assert.Equal(t, ErrConflictNotFound, err)
+
+	//没有同步记录的文件两边都新增
+	assert.True(t, cs.concurrent("new.conf", []byte("a"), []byte("b")))
+	assert.False(t, cs.concurrent("new.conf", []byte("a"), nil))
+	assert.False(t, cs.concurrent("empty.conf", fileContent(nil), nil))
+}
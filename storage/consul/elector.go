@@ -0,0 +1,52 @@
+package consul
+
+import (
+	consulApi "github.com/hashicorp/consul/api"
+	"github.com/ihaiker/aginx/util"
+	"time"
+)
+
+//leader锁不在配置目录中，不会被同步为配置文件
+func (cs *consulStorage) leaderKey() string {
+	return "_leader/" + cs.folder
+}
+
+//使用consul session锁选举leader
+func (cs *consulStorage) Elect(node string, closeC <-chan struct{}) <-chan bool {
+	leaderC := make(chan bool)
+	go func() {
+		defer util.Catch()
+		defer close(leaderC)
+		for {
+			lock, err := cs.client.LockOpts(&consulApi.LockOptions{
+				Key: cs.leaderKey(), Value: []byte(node), SessionTTL: "15s",
+			})
+			var lostC <-chan struct{}
+			if err == nil {
+				lostC, err = lock.Lock(closeC)
+			}
+			if err != nil {
+				logger.WithError(err).Warn("leader election")
+			} else if lostC == nil { //closeC关闭
+				return
+			} else {
+				logger.Info("elected as leader: ", node)
+				leaderC <- true
+				select {
+				case <-closeC:
+					_ = lock.Unlock()
+					return
+				case <-lostC:
+					logger.Warn("lost leadership: ", node)
+					leaderC <- false
+				}
+			}
+			select {
+			case <-closeC:
+				return
+			case <-time.After(time.Second * 5):
+			}
+		}
+	}()
+	return leaderC
+}
@@ -0,0 +1,61 @@
+package consul
+
+import (
+	consulApi "github.com/hashicorp/consul/api"
+	"github.com/ihaiker/aginx/util"
+	"strings"
+)
+
+func (cs *consulStorage) membersKey() string {
+	return "_nodes/" + cs.folder + "/"
+}
+
+//创建节点注册使用的session并定时续期，session过期后节点信息自动删除
+func (cs *consulStorage) createSession(node string, closeC <-chan struct{}) (string, error) {
+	if cs.session != "" {
+		return cs.session, nil
+	}
+	id, _, err := cs.client.Session().Create(&consulApi.SessionEntry{
+		Name: "aginx-" + node, TTL: "15s", Behavior: consulApi.SessionBehaviorDelete,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	cs.session = id
+	go func() {
+		defer util.Catch()
+		err := cs.client.Session().RenewPeriodic("15s", id, nil, closeC)
+		if err != nil {
+			logger.WithError(err).Warn("renew session of node ", node)
+		}
+		cs.lock.Lock()
+		defer cs.lock.Unlock()
+		if cs.session == id {
+			cs.session = ""
+		}
+	}()
+	return id, nil
+}
+
+func (cs *consulStorage) Join(node string, info []byte, closeC <-chan struct{}) error {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	id, err := cs.createSession(node, closeC)
+	if err != nil {
+		return err
+	}
+	_, _, err = cs.client.KV().Acquire(&consulApi.KVPair{Key: cs.membersKey() + node, Value: info, Session: id}, nil)
+	return err
+}
+
+func (cs *consulStorage) Members() (map[string][]byte, error) {
+	kvPairs, _, err := cs.client.KV().List(cs.membersKey(), nil)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string][]byte)
+	for _, kv := range kvPairs {
+		members[strings.TrimPrefix(kv.Key, cs.membersKey())] = kv.Value
+	}
+	return members, nil
+}
@@ -1,12 +1,14 @@
 package consul
 
 import (
+	"context"
 	consulApi "github.com/hashicorp/consul/api"
 	"github.com/ihaiker/aginx/logs"
 	"github.com/ihaiker/aginx/plugins"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 var logger = logs.New("storage", "engine", "consul")
@@ -15,6 +17,9 @@ type consulStorage struct {
 	address string
 	folder  string
 	client  *consulApi.Client
+
+	lock    sync.Mutex
+	session string //节点注册使用的session
 }
 
 func New(clusterConfig *url.URL) (cs *consulStorage, err error) {
@@ -50,6 +55,7 @@ func (cs *consulStorage) Search(args ...string) ([]*plugins.ConfigurationFile, e
 		for _, kv := range kvPairs {
 			if len(kv.Value) != 0 {
 				relPath, _ := filepath.Rel(cs.folder, kv.Key)
+				relPath = filepath.ToSlash(relPath)
 				if len(args) == 0 {
 					files = append(files, &plugins.ConfigurationFile{
 						Content: kv.Value, Name: relPath,
@@ -110,3 +116,72 @@ func (cs *consulStorage) store(file string, content []byte) error {
 func (cs *consulStorage) Put(file string, content []byte) error {
 	return cs.store(cs.folder+"/"+file, content)
 }
+
+func (cs *consulStorage) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &boundStorage{consulStorage: cs, ctx: ctx}
+}
+
+func (cs *consulStorage) Context() context.Context {
+	return nil
+}
+
+//绑定context的存储，context中有期望的文件版本时使用ModifyIndex比较后写入
+type boundStorage struct {
+	*consulStorage
+	ctx context.Context
+}
+
+func (bs *boundStorage) Context() context.Context {
+	return bs.ctx
+}
+
+func (bs *boundStorage) Put(file string, content []byte) error {
+	revision, has := plugins.Expected(bs.ctx, file)
+	if !has {
+		return bs.consulStorage.Put(file, content)
+	}
+	key := bs.folder + "/" + file
+	return bs.compareAndSwap(key, revision, func(index uint64) (bool, error) {
+		logger.Debug("store file ", key, " with index ", index)
+		swapped, _, err := bs.client.KV().CAS(&consulApi.KVPair{Key: key, Value: content, ModifyIndex: index}, nil)
+		return swapped, err
+	})
+}
+
+func (bs *boundStorage) Remove(file string) error {
+	revision, has := plugins.Expected(bs.ctx, file)
+	if !has {
+		return bs.consulStorage.Remove(file)
+	}
+	key := filepath.Join(bs.folder, file)
+	return bs.compareAndSwap(key, revision, func(index uint64) (bool, error) {
+		if index == 0 { //期望文件不存在
+			return true, nil
+		}
+		logger.Debug("remove ", key, " with index ", index)
+		deleted, _, err := bs.client.KV().DeleteCAS(&consulApi.KVPair{Key: key, ModifyIndex: index}, nil)
+		return deleted, err
+	})
+}
+
+//存储中文件的版本和期望的版本相同时，使用读取时的ModifyIndex执行swap（不存在的文件为0），
+//版本不同或者读取后被其他节点修改过（swap返回false）时返回ErrConflict
+func (cs *consulStorage) compareAndSwap(key, revision string, swap func(index uint64) (bool, error)) error {
+	pair, _, err := cs.client.KV().Get(key, nil)
+	if err != nil {
+		return err
+	}
+	index, current := uint64(0), ""
+	if pair != nil {
+		index, current = pair.ModifyIndex, plugins.Revision(pair.Value)
+	}
+	if current != revision {
+		return plugins.ErrConflict
+	}
+	if swapped, err := swap(index); err != nil {
+		return err
+	} else if !swapped {
+		return plugins.ErrConflict
+	}
+	return nil
+}
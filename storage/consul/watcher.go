@@ -54,6 +54,7 @@ func (cs *watcher) watchChange() {
 		for _, kv := range kvs {
 			if cs.index == 0 || kv.ModifyIndex >= query.LastIndex {
 				clusterPath, _ := filepath.Rel(cs.folder, kv.Key)
+				clusterPath = filepath.ToSlash(clusterPath)
 				events.Paths = append(events.Paths, plugins.ConfigurationFile{
 					Name: clusterPath, Content: kv.Value,
 				})
@@ -78,6 +79,7 @@ func (cs *watcher) watchChange() {
 			}
 			if !has {
 				clusterPath, _ := filepath.Rel(cs.folder, cacheFile.Key)
+				clusterPath = filepath.ToSlash(clusterPath)
 				events.Paths = append(events.Paths, plugins.ConfigurationFile{
 					Name: clusterPath, Content: cacheFile.Value,
 				})
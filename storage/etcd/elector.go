@@ -0,0 +1,69 @@
+package etcd
+
+import (
+	"context"
+	"github.com/coreos/etcd/clientv3/concurrency"
+	"github.com/ihaiker/aginx/util"
+	"time"
+)
+
+//leader选举的key不在配置目录中，不会被同步为配置文件
+func (cs *etcdV3Storage) leaderKey() string {
+	return "/_leader" + cs.folder
+}
+
+//使用etcd election选举leader
+func (cs *etcdV3Storage) Elect(node string, closeC <-chan struct{}) <-chan bool {
+	leaderC := make(chan bool)
+	go func() {
+		defer util.Catch()
+		defer close(leaderC)
+		for {
+			if session, err := concurrency.NewSession(cs.api, concurrency.WithTTL(15)); err != nil {
+				logger.WithError(err).Warn("leader election")
+			} else if !cs.campaign(session, node, leaderC, closeC) {
+				return
+			}
+			select {
+			case <-closeC:
+				return
+			case <-time.After(time.Second * 5):
+			}
+		}
+	}()
+	return leaderC
+}
+
+//参加选举直到失去leader，closeC关闭时返回false
+func (cs *etcdV3Storage) campaign(session *concurrency.Session, node string, leaderC chan<- bool, closeC <-chan struct{}) bool {
+	defer func() { _ = session.Close() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-closeC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	election := concurrency.NewElection(session, cs.leaderKey())
+	if err := election.Campaign(ctx, node); err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		logger.WithError(err).Warn("leader election")
+		return true
+	}
+	logger.Info("elected as leader: ", node)
+	leaderC <- true
+	select {
+	case <-closeC:
+		_ = election.Resign(context.Background())
+		return false
+	case <-session.Done():
+		logger.Warn("lost leadership: ", node)
+		leaderC <- false
+		return true
+	}
+}
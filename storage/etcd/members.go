@@ -0,0 +1,76 @@
+package etcd
+
+import (
+	"context"
+	v3 "github.com/coreos/etcd/clientv3"
+	"github.com/ihaiker/aginx/util"
+	"strings"
+)
+
+func (cs *etcdV3Storage) membersKey() string {
+	return "/_nodes" + cs.folder + "/"
+}
+
+//创建节点注册使用的lease并保持，lease过期后节点信息自动删除
+func (cs *etcdV3Storage) grant(node string, closeC <-chan struct{}) (v3.LeaseID, error) {
+	if cs.lease != v3.NoLease {
+		return cs.lease, nil
+	}
+	lease, err := cs.api.Grant(cs.api.Ctx(), 15)
+	if err != nil {
+		return v3.NoLease, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	keepalive, err := cs.api.KeepAlive(ctx, lease.ID)
+	if err != nil {
+		cancel()
+		return v3.NoLease, err
+	}
+	cs.lease = lease.ID
+	go func() {
+		defer util.Catch()
+		defer cancel()
+		for {
+			select {
+			case <-closeC:
+				_, _ = cs.api.Revoke(context.Background(), lease.ID)
+				return
+			case _, has := <-keepalive:
+				if has {
+					continue
+				}
+				logger.Warn("the lease of node ", node, " is expired")
+				cs.lock.Lock()
+				if cs.lease == lease.ID {
+					cs.lease = v3.NoLease
+				}
+				cs.lock.Unlock()
+				return
+			}
+		}
+	}()
+	return lease.ID, nil
+}
+
+func (cs *etcdV3Storage) Join(node string, info []byte, closeC <-chan struct{}) error {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	lease, err := cs.grant(node, closeC)
+	if err != nil {
+		return err
+	}
+	_, err = cs.api.Put(cs.api.Ctx(), cs.membersKey()+node, string(info), v3.WithLease(lease))
+	return err
+}
+
+func (cs *etcdV3Storage) Members() (map[string][]byte, error) {
+	resp, err := cs.api.Get(cs.api.Ctx(), cs.membersKey(), v3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string][]byte)
+	for _, kv := range resp.Kvs {
+		members[strings.TrimPrefix(string(kv.Key), cs.membersKey())] = kv.Value
+	}
+	return members, nil
+}
@@ -2,6 +2,7 @@ package etcd
 
 import (
 	"bytes"
+	"context"
 	v3 "github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/mvcc/mvccpb"
 	"github.com/ihaiker/aginx/logs"
@@ -10,6 +11,9 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
 var logger = logs.New("storage", "engine", "etcd")
@@ -17,19 +21,24 @@ var logger = logs.New("storage", "engine", "etcd")
 type etcdV3Storage struct {
 	api    *v3.Client
 	folder string
+	closeC chan struct{}
+
+	lock  sync.Mutex
+	lease v3.LeaseID //节点注册使用的lease
 }
 
+//etcd://127.0.0.1:2379,127.0.0.1:22379/aginx?user=&password=
 func New(clusterConfig *url.URL) (*etcdV3Storage, error) {
-	address := clusterConfig.Host
+	endpoints := strings.Split(clusterConfig.Host, ",")
 	folder := clusterConfig.EscapedPath()
 	username := clusterConfig.Query().Get("user")
 	password := clusterConfig.Query().Get("password")
 
-	cs := &etcdV3Storage{folder: folder}
+	cs := &etcdV3Storage{folder: folder, closeC: make(chan struct{})}
 
 	config := v3.Config{
-		Endpoints: []string{address},
-		Username:  username, Password: password,
+		Endpoints: endpoints, DialTimeout: time.Second * 5,
+		Username: username, Password: password,
 	}
 	if client, err := v3.New(config); err != nil {
 		return nil, err
@@ -58,6 +67,7 @@ func (cs *etcdV3Storage) Search(args ...string) ([]*plugins.ConfigurationFile, e
 				continue
 			}
 			name, _ := filepath.Rel(cs.folder, key)
+			name = filepath.ToSlash(name)
 			if len(args) == 0 {
 				files = append(files, plugins.NewFile(name, kv.Value))
 			} else {
@@ -76,8 +86,11 @@ func (cs *etcdV3Storage) Search(args ...string) ([]*plugins.ConfigurationFile, e
 func (cs *etcdV3Storage) Remove(file string) error {
 	key := cs.folder + "/" + file
 	resp, err := cs.api.Delete(cs.api.Ctx(), key, v3.WithPrefix())
-	logger.Debug("delete cluster file ", resp.Deleted)
-	return err
+	if err != nil {
+		return err
+	}
+	logger.Debug("delete cluster file ", file, " ", resp.Deleted)
+	return nil
 }
 
 func (cs *etcdV3Storage) Get(file string) (*plugins.ConfigurationFile, error) {
@@ -102,20 +115,96 @@ func (cs *etcdV3Storage) Put(file string, content []byte) error {
 	return cs.store(cs.folder+"/"+file, content)
 }
 
+func (cs *etcdV3Storage) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &boundStorage{etcdV3Storage: cs, ctx: ctx}
+}
+
+func (cs *etcdV3Storage) Context() context.Context {
+	return nil
+}
+
+//绑定context的存储，context中有期望的文件版本时使用ModRevision比较后写入
+type boundStorage struct {
+	*etcdV3Storage
+	ctx context.Context
+}
+
+func (bs *boundStorage) Context() context.Context {
+	return bs.ctx
+}
+
+func (bs *boundStorage) Put(file string, content []byte) error {
+	revision, has := plugins.Expected(bs.ctx, file)
+	if !has {
+		return bs.etcdV3Storage.Put(file, content)
+	}
+	key := bs.folder + "/" + file
+	logger.Debug("store cluster ", key, " if match ", revision)
+	return bs.compareAndSwap(key, revision, v3.OpPut(key, string(content)))
+}
+
+func (bs *boundStorage) Remove(file string) error {
+	revision, has := plugins.Expected(bs.ctx, file)
+	if !has {
+		return bs.etcdV3Storage.Remove(file)
+	}
+	key := bs.folder + "/" + file
+	logger.Debug("delete cluster file ", key, " if match ", revision)
+	return bs.compareAndSwap(key, revision, v3.OpDelete(key))
+}
+
+//存储中文件的版本和期望的版本相同时，在文件的ModRevision没有变化（不存在的文件为0）的事务中执行op，
+//版本不同或者读取后被其他节点修改过时返回ErrConflict
+func (cs *etcdV3Storage) compareAndSwap(key, revision string, op v3.Op) error {
+	resp, err := cs.api.Get(cs.api.Ctx(), key)
+	if err != nil {
+		return err
+	}
+	modRevision, current := int64(0), ""
+	if len(resp.Kvs) > 0 {
+		modRevision, current = resp.Kvs[0].ModRevision, plugins.Revision(resp.Kvs[0].Value)
+	}
+	if current != revision {
+		return plugins.ErrConflict
+	}
+	txn, err := cs.api.Txn(cs.api.Ctx()).If(v3.Compare(v3.ModRevision(key), "=", modRevision)).Then(op).Commit()
+	if err != nil {
+		return err
+	} else if !txn.Succeeded {
+		return plugins.ErrConflict
+	}
+	return nil
+}
+
 func (cs *etcdV3Storage) StartListener() <-chan plugins.FileEvent {
 	events := make(chan plugins.FileEvent)
 	go func() {
 		defer util.Catch()
+		defer close(events)
 		watch := cs.api.Watch(cs.api.Ctx(), cs.folder, v3.WithPrefix(), v3.WithPrevKV())
 		for {
 			select {
-			case resp := <-watch:
+			case <-cs.closeC:
+				return
+			case resp, has := <-watch:
+				if !has {
+					return
+				}
+				if err := resp.Err(); err != nil {
+					logger.Warn("watch error ", err)
+					continue
+				}
 				for _, event := range resp.Events {
 					file, _ := filepath.Rel(cs.folder, string(event.Kv.Key))
+					file = filepath.ToSlash(file)
 					if event.Type == mvccpb.DELETE {
+						content := event.Kv.Value
+						if event.PrevKv != nil {
+							content = event.PrevKv.Value
+						}
 						events <- plugins.FileEvent{
 							Type:  plugins.FileEventTypeRemove,
-							Paths: []plugins.ConfigurationFile{{Name: file, Content: event.Kv.Value}},
+							Paths: []plugins.ConfigurationFile{{Name: file, Content: content}},
 						}
 					} else if event.IsCreate() || event.IsModify() {
 						if !isDir(event.Kv.Value) {
@@ -131,3 +220,12 @@ func (cs *etcdV3Storage) StartListener() <-chan plugins.FileEvent {
 	}()
 	return events
 }
+
+func (cs *etcdV3Storage) Start() error {
+	return nil
+}
+
+func (cs *etcdV3Storage) Stop() error {
+	close(cs.closeC)
+	return cs.api.Close()
+}
@@ -1,7 +1,9 @@
 package etcd
 
 import (
+	"context"
 	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	url2 "net/url"
@@ -111,3 +113,22 @@ func TestWatcher(t *testing.T) {
 
 	gw.Wait()
 }
+
+//期望的版本和存储中的版本不同时不写入
+func TestCompareAndSwap(t *testing.T) {
+	api := newClient(t)
+	_ = api.Remove("cas.conf")
+	ctx := plugins.Expect(context.Background(), "cas.conf", "")
+	assert.Nil(t, api.WithContext(ctx).Put("cas.conf", []byte("v1")))
+	assert.Equal(t, plugins.ErrConflict, api.WithContext(ctx).Put("cas.conf", []byte("v2")))
+
+	ctx = plugins.Expect(context.Background(), "cas.conf", plugins.Revision([]byte("v1")))
+	assert.Nil(t, api.WithContext(ctx).Put("cas.conf", []byte("v2")))
+	assert.Equal(t, plugins.ErrConflict, api.WithContext(ctx).Remove("cas.conf"))
+	file, err := api.Get("cas.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", string(file.Content))
+
+	ctx = plugins.Expect(context.Background(), "cas.conf", plugins.Revision([]byte("v2")))
+	assert.Nil(t, api.WithContext(ctx).Remove("cas.conf"))
+}
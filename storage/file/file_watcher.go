@@ -83,6 +83,7 @@ func (fw *FileWatcher) handlerEvent(events []watcher.Event) {
 	events = fw.mergeEvents(events) //合并至最小化操作
 	for _, event := range events {
 		clusterPath, _ := filepath.Rel(fw.RootDir, event.Path)
+		clusterPath = filepath.ToSlash(clusterPath)
 
 		switch event.Op {
 		case watcher.Create, watcher.Write:
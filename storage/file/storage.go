@@ -99,6 +99,7 @@ func (fs *fileStorage) Get(file string) (reader *plugins.ConfigurationFile, err
 		return nil, err
 	}
 	rel, _ := filepath.Rel(filepath.Dir(fs.conf), path)
+	rel = filepath.ToSlash(rel)
 	return plugins.NewFile(rel, rd), nil
 }
 
@@ -112,9 +113,11 @@ func (fs *fileStorage) Put(file string, content []byte) error {
 	if fio, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666); err != nil {
 		return err
 	} else {
-		defer func() { _ = fio.Close() }()
-		_, _ = fio.Write(content)
-		return nil
+		if _, err = fio.Write(content); err != nil {
+			_ = fio.Close()
+			return err
+		}
+		return fio.Close()
 	}
 }
 
@@ -130,6 +133,18 @@ func (fs *fileStorage) StartListener() <-chan plugins.FileEvent {
 	return fs.fileWatcher.Listener
 }
 
+func (fs *fileStorage) Start() error {
+	return nil
+}
+
+//停止本地文件监听
+func (fs *fileStorage) Stop() error {
+	if fs.fileWatcher != nil {
+		return fs.fileWatcher.Stop()
+	}
+	return nil
+}
+
 func walkFile(root, appendRelativeDir string) ([]*plugins.ConfigurationFile, error) {
 	files := make([]*plugins.ConfigurationFile, 0)
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
@@ -160,7 +175,7 @@ func walkFile(root, appendRelativeDir string) ([]*plugins.ConfigurationFile, err
 		}
 		bs, _ := ioutil.ReadFile(path)
 
-		files = append(files, &plugins.ConfigurationFile{Name: file, Content: bs})
+		files = append(files, &plugins.ConfigurationFile{Name: filepath.ToSlash(file), Content: bs})
 		return nil
 	})
 	return files, err
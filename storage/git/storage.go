@@ -0,0 +1,296 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logger = logs.New("storage", "engine", "git")
+
+type gitStorage struct {
+	remote   string
+	branch   string
+	dir      string
+	author   string
+	interval time.Duration
+
+	lock   *sync.Mutex
+	closeC chan struct{}
+	ctx    context.Context //修改请求，提交的作者为请求的用户
+}
+
+//git://127.0.0.1/aginx.git, git+ssh://git@github.com/ihaiker/nginx-conf.git, git+https://...
+//query: branch=master&dir=/var/lib/aginx/git&author=aginx <aginx@renzhen.la>&interval=30s
+//author为提交者，修改请求的用户作为提交的作者
+func New(clusterConfig *url.URL) (gs *gitStorage, err error) {
+	query := clusterConfig.Query()
+	gs = &gitStorage{
+		branch: "master", author: "aginx <aginx@renzhen.la>", interval: time.Second * 30,
+		lock: new(sync.Mutex), closeC: make(chan struct{}), ctx: context.Background(),
+	}
+	if branch := query.Get("branch"); branch != "" {
+		gs.branch = branch
+	}
+	if author := query.Get("author"); author != "" {
+		gs.author = author
+	}
+	if interval := query.Get("interval"); interval != "" {
+		if gs.interval, err = time.ParseDuration(interval); err != nil {
+			return nil, err
+		}
+	}
+
+	remote := *clusterConfig
+	remote.Scheme = strings.TrimPrefix(remote.Scheme, "git+")
+	remote.RawQuery = ""
+	gs.remote = remote.String()
+
+	if gs.dir = query.Get("dir"); gs.dir == "" {
+		gs.dir = filepath.Join(os.TempDir(), "aginx-git", clusterConfig.Host, clusterConfig.Path)
+	}
+	if err = gs.initialize(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (gs *gitStorage) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = gs.dir
+	out := bytes.NewBufferString("")
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return out.String(), fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(out.String()))
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+//克隆远程仓库，如果远程仓库是空仓库（没有任何引用）则初始化本地仓库
+func (gs *gitStorage) initialize() error {
+	if util.Exists(filepath.Join(gs.dir, ".git")) {
+		_, err := gs.git("pull", "--rebase", "origin", gs.branch)
+		logger.WithError(err).Debug("pull ", gs.remote)
+		return nil
+	}
+	if err := os.MkdirAll(gs.dir, os.ModePerm); err != nil {
+		return err
+	}
+	if _, err := gs.git("clone", "--branch", gs.branch, gs.remote, "."); err != nil {
+		//只有远程仓库是空仓库时才初始化，其他错误（认证失败、网络错误、分支不存在）初始化空仓库后同步会删除本地的配置
+		if refs, lsErr := gs.git("ls-remote", gs.remote); lsErr != nil || refs != "" {
+			return err
+		}
+		logger.Info("the repository ", gs.remote, " is empty, initialize it")
+	} else {
+		return nil
+	}
+	if _, err := gs.git("init"); err != nil {
+		return err
+	}
+	if _, err := gs.git("remote", "add", "origin", gs.remote); err != nil {
+		return err
+	}
+	_, err := gs.git("checkout", "-b", gs.branch)
+	return err
+}
+
+func (gs *gitStorage) WithContext(ctx context.Context) plugins.StorageEngine {
+	bound := *gs
+	bound.ctx = ctx
+	return &bound
+}
+
+func (gs *gitStorage) Context() context.Context {
+	return gs.ctx
+}
+
+func (gs *gitStorage) abs(file string) string {
+	return filepath.Join(gs.dir, file)
+}
+
+//文件必须在仓库中：不能是绝对路径、不能使用..跳出仓库，也不能修改.git中的文件
+func (gs *gitStorage) name(file string) (string, error) {
+	name := path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	if path.IsAbs(name) || filepath.IsAbs(file) || name == "." || name == ".." || strings.HasPrefix(name, "../") ||
+		name == ".git" || strings.HasPrefix(name, ".git/") {
+		return "", errors.New("invalid file path: " + file)
+	}
+	return name, nil
+}
+
+//解析 name <email> 格式的作者
+func splitAuthor(author string) (name, email string) {
+	if start, end := strings.LastIndex(author, "<"), strings.LastIndex(author, ">"); start >= 0 && end > start {
+		return strings.TrimSpace(author[:start]), author[start+1 : end]
+	}
+	return strings.TrimSpace(author), ""
+}
+
+//提交的作者：修改请求的用户，邮箱使用配置的作者邮箱（用户是邮箱时使用用户）；不在修改请求中时为配置的作者
+func (gs *gitStorage) commitAuthor() string {
+	user := plugins.User(gs.ctx)
+	if user == "" {
+		return gs.author
+	}
+	_, email := splitAuthor(gs.author)
+	if strings.Contains(user, "@") || email == "" {
+		email = user
+	}
+	return fmt.Sprintf("%s <%s>", user, email)
+}
+
+//提交，提交者为配置的作者。在批次中时（例如：一次保存多个配置文件）批次结束后只推送一次
+func (gs *gitStorage) commit(message string) error {
+	name, email := splitAuthor(gs.author)
+	if _, err := gs.git("-c", "user.name="+name, "-c", "user.email="+email,
+		"commit", "--author", gs.commitAuthor(), "-m", message); err != nil {
+		return err
+	}
+	if plugins.Defer(gs.ctx, "git:"+gs.dir, gs.push) {
+		return nil
+	}
+	return gs.pushLocked()
+}
+
+func (gs *gitStorage) push() error {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+	return gs.pushLocked()
+}
+
+//推送到远程仓库，失败时拉取后再推送一次，调用时需要持有锁
+func (gs *gitStorage) pushLocked() error {
+	if _, err := gs.git("push", "origin", gs.branch); err != nil {
+		logger.Warn("push error, try pull and push again: ", err)
+		if _, err = gs.git("pull", "--rebase", "origin", gs.branch); err != nil {
+			return err
+		}
+		_, err = gs.git("push", "origin", gs.branch)
+		return err
+	}
+	return nil
+}
+
+func (gs *gitStorage) IsCluster() bool {
+	return true
+}
+
+func (gs *gitStorage) Search(args ...string) ([]*plugins.ConfigurationFile, error) {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	files := make([]*plugins.ConfigurationFile, 0)
+	err := filepath.Walk(gs.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name, _ := filepath.Rel(gs.dir, path)
+		name = filepath.ToSlash(name)
+		matched := len(args) == 0
+		for _, arg := range args {
+			if matched, _ = filepath.Match(arg, name); matched {
+				break
+			}
+		}
+		if matched {
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files = append(files, plugins.NewFile(name, content))
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (gs *gitStorage) Get(file string) (*plugins.ConfigurationFile, error) {
+	name, err := gs.name(file)
+	if err != nil {
+		return nil, err
+	}
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	path := gs.abs(name)
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return plugins.NewFile(file, content), nil
+}
+
+func (gs *gitStorage) Put(file string, content []byte) error {
+	name, err := gs.name(file)
+	if err != nil {
+		return err
+	}
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	if write, err := util.DiffWriteFile(gs.abs(name), content); err != nil {
+		return err
+	} else if !write {
+		return nil
+	}
+	if _, err := gs.git("add", "--", name); err != nil {
+		return err
+	}
+	logger.Debug("commit file ", name)
+	return gs.commit("aginx: update " + name)
+}
+
+func (gs *gitStorage) Remove(file string) error {
+	name, err := gs.name(file)
+	if err != nil {
+		return err
+	}
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	if !util.Exists(gs.abs(name)) {
+		return nil
+	}
+	if _, err := gs.git("rm", "-r", "--quiet", "--", name); err != nil {
+		return err
+	}
+	logger.Debug("commit remove ", name)
+	return gs.commit("aginx: remove " + name)
+}
+
+func (gs *gitStorage) StartListener() <-chan plugins.FileEvent {
+	return NewWatcher(gs)
+}
+
+func (gs *gitStorage) Start() error {
+	return nil
+}
+
+func (gs *gitStorage) Stop() error {
+	close(gs.closeC)
+	return nil
+}
@@ -0,0 +1,162 @@
+package git
+
+import (
+	"context"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//本地的空仓库作为远程仓库
+func newStorage(t *testing.T) (*gitStorage, func(args ...string) string, func()) {
+	dir, err := ioutil.TempDir("", "aginx-git")
+	assert.Nil(t, err)
+	remote := filepath.Join(dir, "remote.git")
+	out, err := exec.Command("git", "init", "--bare", remote).CombinedOutput()
+	assert.Nil(t, err, string(out))
+
+	config, err := url.Parse("git+file://" + filepath.ToSlash(remote) + "?dir=" + url.QueryEscape(filepath.Join(dir, "work")))
+	assert.Nil(t, err)
+	gs, err := New(config)
+	assert.Nil(t, err)
+
+	//在远程仓库中执行git命令
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"--git-dir", remote}, args...)...).CombinedOutput()
+		assert.Nil(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	return gs, git, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestCommitAuthor(t *testing.T) {
+	gs, git, cleanup := newStorage(t)
+	defer cleanup()
+
+	assert.Nil(t, gs.Put("nginx.conf", []byte("http {}")))
+	assert.Equal(t, "aginx <aginx@renzhen.la>", git("log", "-1", "--format=%an <%ae>", "master"))
+
+	engine := plugins.WithContext(plugins.WithUser(context.Background(), "alice"), gs)
+	assert.Nil(t, engine.Put("conf.d/a.conf", []byte("server {}")))
+	assert.Equal(t, "alice <aginx@renzhen.la>", git("log", "-1", "--format=%an <%ae>", "master"))
+	assert.Equal(t, "aginx <aginx@renzhen.la>", git("log", "-1", "--format=%cn <%ce>", "master"))
+
+	engine = plugins.WithContext(plugins.WithUser(context.Background(), "bob@aginx.io"), gs)
+	assert.Nil(t, engine.Remove("conf.d/a.conf"))
+	assert.Equal(t, "bob@aginx.io <bob@aginx.io>", git("log", "-1", "--format=%an <%ae>", "master"))
+}
+
+//一个批次中的多个修改只推送一次
+func TestBatchPush(t *testing.T) {
+	gs, git, cleanup := newStorage(t)
+	defer cleanup()
+
+	assert.Nil(t, gs.Put("nginx.conf", []byte("http {}")))
+	assert.Equal(t, "1", git("rev-list", "--count", "master"))
+
+	err := plugins.Batch(gs, func(engine plugins.StorageEngine) error {
+		for _, name := range []string{"conf.d/a.conf", "conf.d/b.conf", "conf.d/c.conf"} {
+			if err := engine.Put(name, []byte("server {}")); err != nil {
+				return err
+			}
+		}
+		assert.Equal(t, "1", git("rev-list", "--count", "master"))
+		return engine.Remove("conf.d/c.conf")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "5", git("rev-list", "--count", "master"))
+	assert.Equal(t, "conf.d/a.conf\nconf.d/b.conf\nnginx.conf", git("ls-tree", "-r", "--name-only", "master"))
+}
+
+func TestInvalidPath(t *testing.T) {
+	gs, git, cleanup := newStorage(t)
+	defer cleanup()
+	assert.Nil(t, gs.Put("nginx.conf", []byte("http {}")))
+
+	outside := filepath.Join(filepath.Dir(gs.dir), "outside.conf")
+	for _, name := range []string{"../outside.conf", "conf.d/../../outside.conf", outside, "/etc/aginx.conf", ".git/config", ".", ""} {
+		assert.NotNil(t, gs.Put(name, []byte("server {}")), name)
+		assert.NotNil(t, gs.Remove(name), name)
+		_, err := gs.Get(name)
+		assert.NotNil(t, err, name)
+	}
+	_, err := os.Stat(outside)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, "1", git("rev-list", "--count", "master"))
+
+	assert.Nil(t, gs.Put("conf.d/../hosts.d/a.conf", []byte("server {}")))
+	file, err := gs.Get("hosts.d/a.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, "server {}", string(file.Content))
+}
+
+//克隆失败时（仓库不存在、分支不存在）返回错误，不能初始化空仓库
+func TestCloneError(t *testing.T) {
+	gs, _, cleanup := newStorage(t)
+	defer cleanup()
+	assert.Nil(t, gs.Put("nginx.conf", []byte("http {}")))
+	remote := strings.TrimPrefix(gs.remote, "file://")
+
+	for _, config := range []string{
+		"git+file://" + filepath.ToSlash(filepath.Join(filepath.Dir(remote), "missing.git")),
+		"git+file://" + filepath.ToSlash(remote) + "?branch=develop",
+	} {
+		dir, err := ioutil.TempDir("", "aginx-git")
+		assert.Nil(t, err)
+		u, err := url.Parse(config)
+		assert.Nil(t, err)
+		query := u.Query()
+		query.Set("dir", dir)
+		u.RawQuery = query.Encode()
+		_, err = New(u)
+		assert.NotNil(t, err, config)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+//拉取其他节点的修改，文件名中有空格、重命名
+func TestPull(t *testing.T) {
+	gs, _, cleanup := newStorage(t)
+	defer cleanup()
+	assert.Nil(t, gs.Put("conf.d/old name.conf", []byte("server {}")))
+	assert.Nil(t, gs.Put("conf.d/removed.conf", []byte("server {}")))
+
+	other := filepath.Join(filepath.Dir(gs.dir), "other")
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=other", "-c", "user.email=other@aginx.io"}, args...)...)
+		cmd.Dir = other
+		out, err := cmd.CombinedOutput()
+		assert.Nil(t, err, string(out))
+	}
+	assert.Nil(t, os.MkdirAll(other, 0755))
+	git("clone", gs.remote, ".")
+	git("mv", "conf.d/old name.conf", "conf.d/new name.conf")
+	git("rm", "conf.d/removed.conf")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(other, "conf.d", "a \"b\".conf"), []byte("server { listen 80; }"), 0644))
+	git("add", ".")
+	git("commit", "-m", "other")
+	git("push", "origin", "master")
+
+	events := gs.pull()
+	assert.Len(t, events, 2)
+	updates := map[string]string{}
+	for _, file := range events[0].Paths {
+		updates[file.Name] = string(file.Content)
+	}
+	assert.Equal(t, map[string]string{
+		"conf.d/new name.conf": "server {}", "conf.d/a \"b\".conf": "server { listen 80; }",
+	}, updates)
+	removes := []string{}
+	for _, file := range events[1].Paths {
+		removes = append(removes, file.Name)
+	}
+	sort.Strings(removes)
+	assert.Equal(t, []string{"conf.d/old name.conf", "conf.d/removed.conf"}, removes)
+}
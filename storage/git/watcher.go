@@ -0,0 +1,90 @@
+package git
+
+import (
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+//定时从远程仓库拉取变更，并使用 git diff 找出变化的文件
+func NewWatcher(gs *gitStorage) chan plugins.FileEvent {
+	listener := make(chan plugins.FileEvent)
+	go func() {
+		defer util.Catch(func(err error) {
+			logger.Info("watcher error: ", err)
+		})
+		ticker := time.NewTicker(gs.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-gs.closeC:
+				return
+			case <-ticker.C:
+				for _, event := range gs.pull() {
+					listener <- event
+				}
+			}
+		}
+	}()
+	return listener
+}
+
+func (gs *gitStorage) pull() []plugins.FileEvent {
+	gs.lock.Lock()
+	defer gs.lock.Unlock()
+
+	before, err := gs.git("rev-parse", "HEAD")
+	if err != nil {
+		return nil
+	}
+	if _, err := gs.git("pull", "--rebase", "origin", gs.branch); err != nil {
+		logger.Warn("pull error ", err)
+		return nil
+	}
+	after, _ := gs.git("rev-parse", "HEAD")
+	if before == after {
+		return nil
+	}
+
+	//-z：文件名不转义，使用NUL分隔，文件名中可以有空格
+	output, err := gs.git("diff", "-z", "--name-status", before, after)
+	if err != nil {
+		logger.Warn("diff error ", err)
+		return nil
+	}
+
+	updates := plugins.FileEvent{Type: plugins.FileEventTypeUpdate, Paths: []plugins.ConfigurationFile{}}
+	removes := plugins.FileEvent{Type: plugins.FileEventTypeRemove, Paths: []plugins.ConfigurationFile{}}
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, name := fields[i], fields[i+1]
+		//重命名和复制：状态后是原文件和新文件
+		if strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+			if i+2 >= len(fields) {
+				break
+			}
+			if strings.HasPrefix(status, "R") {
+				removes.Paths = append(removes.Paths, plugins.ConfigurationFile{Name: name})
+			}
+			i++
+			name = fields[i+1]
+		}
+		if strings.HasPrefix(status, "D") {
+			removes.Paths = append(removes.Paths, plugins.ConfigurationFile{Name: name})
+		} else {
+			content, _ := ioutil.ReadFile(gs.abs(name))
+			updates.Paths = append(updates.Paths, plugins.ConfigurationFile{Name: name, Content: content})
+		}
+	}
+
+	events := make([]plugins.FileEvent, 0)
+	if len(updates.Paths) > 0 {
+		events = append(events, updates)
+	}
+	if len(removes.Paths) > 0 {
+		events = append(events, removes)
+	}
+	return events
+}
@@ -6,6 +6,9 @@ import (
 	"github.com/ihaiker/aginx/storage/consul"
 	"github.com/ihaiker/aginx/storage/etcd"
 	"github.com/ihaiker/aginx/storage/file"
+	"github.com/ihaiker/aginx/storage/git"
+	"github.com/ihaiker/aginx/storage/redis"
+	"github.com/ihaiker/aginx/storage/s3"
 	"github.com/ihaiker/aginx/storage/zookeeper"
 	. "github.com/ihaiker/aginx/util"
 	"net/url"
@@ -24,6 +27,12 @@ func FindStorage(cluster string) (storage plugins.StorageEngine) {
 				storage, err = etcd.New(config)
 			case "zk":
 				storage, err = zookeeper.New(config)
+			case "redis":
+				storage, err = redis.New(config)
+			case "s3":
+				storage, err = s3.New(config)
+			case "git", "git+ssh", "git+https", "git+http", "git+file":
+				storage, err = git.New(config)
 			default:
 				storagePlugins := FindPlugins("storage")
 				if storagePlugin, has := storagePlugins[config.Scheme]; has {
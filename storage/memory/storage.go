@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+//内存中的存储，文件名为key，用于测试。查询和 storage/file 相同使用 filepath.Match 匹配
+type Storage map[string][]byte
+
+func (m Storage) IsCluster() bool {
+	return false
+}
+
+func (m Storage) StartListener() <-chan plugins.FileEvent {
+	return make(chan plugins.FileEvent)
+}
+
+func (m Storage) Put(file string, content []byte) error {
+	m[file] = content
+	return nil
+}
+
+func (m Storage) Remove(file string) error {
+	if _, has := m[file]; !has {
+		return os.ErrNotExist
+	}
+	delete(m, file)
+	return nil
+}
+
+//没有查询条件时返回全部文件，匹配多个条件的文件只返回一次，按照文件名排序
+func (m Storage) Search(patterns ...string) ([]*plugins.ConfigurationFile, error) {
+	files := make([]*plugins.ConfigurationFile, 0)
+	for name, content := range m {
+		if len(patterns) == 0 || matched(name, patterns) {
+			files = append(files, plugins.NewFile(name, content))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+func (m Storage) Get(file string) (*plugins.ConfigurationFile, error) {
+	if content, has := m[file]; has {
+		return plugins.NewFile(file, content), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func matched(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, name); match {
+			return true
+		}
+	}
+	return false
+}
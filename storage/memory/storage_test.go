@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func names(storage Storage, patterns ...string) []string {
+	files, _ := storage.Search(patterns...)
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names
+}
+
+func TestSearch(t *testing.T) {
+	storage := Storage{
+		"nginx.conf":         []byte("http {}"),
+		"hosts.d/a.conf":     []byte("server {}"),
+		"hosts.d/b.conf":     []byte("server {}"),
+		"hosts.d/sub/c.conf": []byte("server {}"),
+		"certificates/a.crt": []byte("cert"),
+	}
+	assert.Equal(t, []string{"certificates/a.crt", "hosts.d/a.conf", "hosts.d/b.conf",
+		"hosts.d/sub/c.conf", "nginx.conf"}, names(storage))
+	//和 filepath.Match 相同，*不匹配目录分隔符
+	assert.Equal(t, []string{"nginx.conf"}, names(storage, "*"))
+	assert.Equal(t, []string{"hosts.d/a.conf", "hosts.d/b.conf"}, names(storage, "hosts.d/*.conf"))
+	//匹配多个条件的文件只返回一次
+	assert.Equal(t, []string{"hosts.d/a.conf", "hosts.d/b.conf"}, names(storage, "hosts.d/*", "hosts.d/a.conf"))
+	assert.Empty(t, names(storage, "conf.d/*"))
+
+	assert.Nil(t, storage.Remove("nginx.conf"))
+	assert.True(t, os.IsNotExist(storage.Remove("nginx.conf")))
+	_, err := storage.Get("nginx.conf")
+	assert.True(t, os.IsNotExist(err))
+}
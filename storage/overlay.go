@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	nodesDir  = "nodes/"  //nodes/<node>/<file> 只作用于此节点
+	labelsDir = "labels/" //labels/<key>=<value>/<file> 作用于有此标签的节点
+)
+
+//节点配置覆盖：集群中共享的配置文件可以被节点或者标签目录中的同名文件覆盖，例如监听IP、本地日志路径。
+//优先级：节点 > 标签（后设置的优先） > 共享配置，覆盖文件只同步到对应的节点
+type Overlays struct {
+	Node   string   `json:"node"`
+	Labels []string `json:"labels,omitempty"` //key=value
+}
+
+//没有设置节点名称和标签时不使用覆盖，返回nil
+func NewOverlays(node string, labels []string) (*Overlays, error) {
+	if node == "" && len(labels) == 0 {
+		return nil, nil
+	}
+	if node == "" || strings.ContainsAny(node, "/ ") {
+		return nil, errors.New("invalid node name: " + node)
+	}
+	for _, label := range labels {
+		if kv := strings.SplitN(label, "=", 2); len(kv) != 2 || kv[0] == "" || strings.ContainsAny(label, "/ ") {
+			return nil, errors.New("invalid node label: " + label + ", example: region=eu")
+		}
+	}
+	return &Overlays{Node: node, Labels: labels}, nil
+}
+
+func isOverlay(file string) bool {
+	return strings.HasPrefix(file, nodesDir) || strings.HasPrefix(file, labelsDir)
+}
+
+//当前节点使用的覆盖目录，按照优先级从低到高
+func (o *Overlays) prefixes() []string {
+	if o == nil {
+		return nil
+	}
+	prefixes := make([]string, 0, len(o.Labels)+1)
+	for _, label := range o.Labels {
+		prefixes = append(prefixes, labelsDir+label+"/")
+	}
+	return append(prefixes, nodesDir+o.Node+"/")
+}
+
+//存储中的文件对应的本地文件和优先级，其他节点的覆盖文件返回false
+func (o *Overlays) local(file string) (name string, priority int, ok bool) {
+	if o == nil {
+		return file, 0, true
+	}
+	for i, prefix := range o.prefixes() {
+		if strings.HasPrefix(file, prefix) {
+			return strings.TrimPrefix(file, prefix), i + 1, true
+		}
+	}
+	return file, 0, !isOverlay(file)
+}
+
+//本地文件在存储中可能的位置，按照优先级从高到低
+func (o *Overlays) candidates(name string) []string {
+	prefixes := o.prefixes()
+	candidates := make([]string, 0, len(prefixes)+1)
+	for i := len(prefixes) - 1; i >= 0; i-- {
+		candidates = append(candidates, prefixes[i]+name)
+	}
+	return append(candidates, name)
+}
+
+//本地文件在存储中的内容（优先级最高的文件），返回存储中的文件名
+func effective(engine plugins.StorageEngine, overlays *Overlays, name string) (string, []byte, error) {
+	for _, candidate := range overlays.candidates(name) {
+		file, err := engine.Get(candidate)
+		if err == nil {
+			return candidate, file.Content, nil
+		} else if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+	}
+	return "", nil, os.ErrNotExist
+}
+
+//应用覆盖后的存储，文件名为本地文件名，用于同步到本地
+type overlayView struct {
+	plugins.StorageEngine
+	overlays *Overlays
+}
+
+func (v *overlayView) Search(args ...string) ([]*plugins.ConfigurationFile, error) {
+	files, err := v.StorageEngine.Search()
+	if err != nil {
+		return nil, err
+	}
+	priorities := make(map[string]int)
+	views := make(map[string]*plugins.ConfigurationFile)
+	names := make([]string, 0)
+	for _, file := range files {
+		name, priority, ok := v.overlays.local(file.Name)
+		if !ok {
+			continue
+		}
+		if current, has := priorities[name]; has && current > priority {
+			continue
+		} else if !has {
+			names = append(names, name)
+		}
+		priorities[name] = priority
+		views[name] = &plugins.ConfigurationFile{Name: name, Content: file.Content}
+	}
+	results := make([]*plugins.ConfigurationFile, 0, len(names))
+	for _, name := range names {
+		if matched(name, args...) {
+			results = append(results, views[name])
+		}
+	}
+	return results, nil
+}
+
+func (v *overlayView) Get(name string) (*plugins.ConfigurationFile, error) {
+	_, content, err := effective(v.StorageEngine, v.overlays, name)
+	if err != nil {
+		return nil, err
+	}
+	return plugins.NewFile(name, content), nil
+}
+
+func matched(name string, patterns ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, name); match {
+			return true
+		}
+	}
+	return false
+}
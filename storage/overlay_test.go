@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewOverlays(t *testing.T) {
+	overlays, err := NewOverlays("", nil)
+	assert.Nil(t, err)
+	assert.Nil(t, overlays)
+	_, err = NewOverlays("", []string{"region=eu"})
+	assert.NotNil(t, err)
+	_, err = NewOverlays("edge/1", nil)
+	assert.NotNil(t, err)
+	_, err = NewOverlays("edge-1", []string{"region"})
+	assert.NotNil(t, err)
+
+	overlays, err = NewOverlays("edge-1", []string{"region=eu", "tier=edge"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nodes/edge-1/hosts.d/a.conf", "labels/tier=edge/hosts.d/a.conf",
+		"labels/region=eu/hosts.d/a.conf", "hosts.d/a.conf"}, overlays.candidates("hosts.d/a.conf"))
+
+	name, priority, ok := overlays.local("nodes/edge-1/hosts.d/a.conf")
+	assert.Equal(t, "hosts.d/a.conf", name)
+	assert.Equal(t, 3, priority)
+	assert.True(t, ok)
+	_, _, ok = overlays.local("nodes/edge-2/hosts.d/a.conf")
+	assert.False(t, ok)
+	_, _, ok = overlays.local("labels/region=us/hosts.d/a.conf")
+	assert.False(t, ok)
+	name, priority, ok = overlays.local("hosts.d/a.conf")
+	assert.Equal(t, "hosts.d/a.conf", name)
+	assert.Equal(t, 0, priority)
+	assert.True(t, ok)
+}
+
+func TestOverlayView(t *testing.T) {
+	overlays, _ := NewOverlays("edge-1", []string{"region=eu"})
+	engine := memory.Storage{
+		"nginx.conf":                      []byte("shared"),
+		"hosts.d/a.conf":                  []byte("shared"),
+		"hosts.d/b.conf":                  []byte("shared"),
+		"labels/region=eu/hosts.d/a.conf": []byte("eu"),
+		"labels/region=eu/hosts.d/b.conf": []byte("eu"),
+		"nodes/edge-1/hosts.d/a.conf":     []byte("edge-1"),
+		"nodes/edge-1/hosts.d/local.conf": []byte("edge-1"),
+		"nodes/edge-2/hosts.d/a.conf":     []byte("edge-2"),
+	}
+	view := &overlayView{StorageEngine: engine, overlays: overlays}
+	files, err := view.Search()
+	assert.Nil(t, err)
+	contents := make(map[string]string)
+	for _, file := range files {
+		contents[file.Name] = string(file.Content)
+	}
+	assert.Equal(t, map[string]string{
+		"nginx.conf": "shared", "hosts.d/a.conf": "edge-1", "hosts.d/b.conf": "eu", "hosts.d/local.conf": "edge-1",
+	}, contents)
+
+	files, err = view.Search("hosts.d/*")
+	assert.Nil(t, err)
+	assert.Len(t, files, 3)
+
+	file, err := view.Get("hosts.d/b.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, "eu", string(file.Content))
+}
+
+func TestBridgeOverlays(t *testing.T) {
+	overlays, _ := NewOverlays("edge-1", nil)
+	cluster, local := memory.Storage{}, memory.Storage{}
+	sb := &bridge{StorageEngine: cluster, LocalStorageEngine: local, Overlays: overlays, Conflicts: NewConflicts(ConflictRemoteWins)}
+
+	assert.Nil(t, sb.Put("hosts.d/a.conf", []byte("shared")))
+	assert.Equal(t, "shared", string(local["hosts.d/a.conf"]))
+
+	//覆盖文件同步到本地，共享文件修改后本地不变
+	assert.Nil(t, sb.Put("nodes/edge-1/hosts.d/a.conf", []byte("edge-1")))
+	assert.Equal(t, "edge-1", string(local["hosts.d/a.conf"]))
+	assert.Nil(t, sb.Put("hosts.d/a.conf", []byte("shared v2")))
+	assert.Equal(t, "edge-1", string(local["hosts.d/a.conf"]))
+	assert.Equal(t, "nodes/edge-1/hosts.d/a.conf", sb.target("hosts.d/a.conf"))
+
+	//其他节点的覆盖文件不同步
+	assert.Nil(t, sb.Put("nodes/edge-2/hosts.d/a.conf", []byte("edge-2")))
+	assert.Equal(t, "edge-1", string(local["hosts.d/a.conf"]))
+	_, has := local["nodes/edge-2/hosts.d/a.conf"]
+	assert.False(t, has)
+
+	//删除覆盖文件后使用共享文件
+	assert.Nil(t, sb.Remove("nodes/edge-1/hosts.d/a.conf"))
+	assert.Equal(t, "shared v2", string(local["hosts.d/a.conf"]))
+	assert.Nil(t, sb.Remove("hosts.d/a.conf"))
+	_, has = local["hosts.d/a.conf"]
+	assert.False(t, has)
+}
+
+func TestBridgeOverlayEvent(t *testing.T) {
+	overlays, _ := NewOverlays("edge-1", nil)
+	cluster := memory.Storage{
+		"hosts.d/a.conf":              []byte("shared"),
+		"nodes/edge-1/hosts.d/a.conf": []byte("edge-1"),
+		"nodes/edge-1/hosts.d/b.conf": []byte("edge-1"),
+	}
+	sb := &bridge{StorageEngine: cluster, LocalStorageEngine: memory.Storage{}, Overlays: overlays}
+
+	events := sb.overlay(plugins.FileEvent{Type: plugins.FileEventTypeUpdate, Paths: []plugins.ConfigurationFile{
+		{Name: "hosts.d/a.conf", Content: []byte("shared")},
+		{Name: "nodes/edge-2/hosts.d/a.conf", Content: []byte("edge-2")},
+		{Name: "nodes/edge-1/hosts.d/b.conf", Content: []byte("edge-1")},
+	}})
+	assert.Len(t, events, 1)
+	assert.Equal(t, plugins.FileEventTypeUpdate, events[0].Type)
+	assert.Equal(t, []plugins.ConfigurationFile{
+		{Name: "hosts.d/a.conf", Content: []byte("edge-1")},
+		{Name: "hosts.d/b.conf", Content: []byte("edge-1")},
+	}, events[0].Paths)
+
+	delete(cluster, "nodes/edge-1/hosts.d/b.conf")
+	events = sb.overlay(plugins.FileEvent{Type: plugins.FileEventTypeRemove, Paths: []plugins.ConfigurationFile{
+		{Name: "nodes/edge-1/hosts.d/b.conf"},
+	}})
+	assert.Len(t, events, 1)
+	assert.Equal(t, plugins.FileEventTypeRemove, events[0].Type)
+	assert.Equal(t, "hosts.d/b.conf", events[0].Paths[0].Name)
+}
+
+//测试配置时写入覆盖后的文件，其他节点的覆盖文件和共享文件不写入
+func TestBridgeWriteOverlays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	overlays, _ := NewOverlays("edge-1", []string{"region=eu"})
+	cluster := memory.Storage{
+		"hosts.d/a.conf":                  []byte("shared"),
+		"hosts.d/b.conf":                  []byte("shared"),
+		"hosts.d/c.conf":                  []byte("shared"),
+		"labels/region=eu/hosts.d/a.conf": []byte("eu"),
+		"labels/region=eu/hosts.d/b.conf": []byte("eu"),
+		"nodes/edge-1/hosts.d/a.conf":     []byte("edge-1"),
+		"nodes/edge-2/hosts.d/c.conf":     []byte("edge-2"),
+	}
+	sb := &bridge{StorageEngine: cluster, LocalStorageEngine: memory.Storage{}, Overlays: overlays}
+	assert.Nil(t, sb.WriteOverlays(dir))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "hosts.d", "a.conf"))
+	assert.Nil(t, err)
+	assert.Equal(t, "edge-1", string(content))
+	content, err = ioutil.ReadFile(filepath.Join(dir, "hosts.d", "b.conf"))
+	assert.Nil(t, err)
+	assert.Equal(t, "eu", string(content))
+	_, err = os.Stat(filepath.Join(dir, "hosts.d", "c.conf"))
+	assert.True(t, os.IsNotExist(err))
+
+	//没有使用覆盖时不写入
+	sb.Overlays = nil
+	assert.Nil(t, os.RemoveAll(filepath.Join(dir, "hosts.d")))
+	assert.Nil(t, sb.WriteOverlays(dir))
+	_, err = os.Stat(filepath.Join(dir, "hosts.d"))
+	assert.True(t, os.IsNotExist(err))
+}
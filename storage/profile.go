@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const profilesDir = "profiles/" //profiles/<profile>/<file> 环境（例如：dev, staging, prod）的配置
+
+//aginx自己的数据，提升环境时不复制
+var promoteExcludes = []string{
+	"history/", "keys/", "approval/", "ownership/", "lego/", "access/", "health/", "releases/",
+}
+
+var profileName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+func ValidProfile(name string) bool {
+	return profileName.MatchString(name)
+}
+
+//使用环境目录中的文件，文件名为去掉环境目录后的名称
+type profileEngine struct {
+	plugins.StorageEngine
+	prefix string
+}
+
+func NewProfile(engine plugins.StorageEngine, profile string) (plugins.StorageEngine, error) {
+	if !ValidProfile(profile) {
+		return nil, errors.New("invalid profile name: " + profile)
+	}
+	return &profileEngine{StorageEngine: engine, prefix: profilesDir + profile + "/"}, nil
+}
+
+func (pe *profileEngine) WithContext(ctx context.Context) plugins.StorageEngine {
+	return &profileEngine{StorageEngine: plugins.WithContext(ctx, pe.StorageEngine), prefix: pe.prefix}
+}
+
+func (pe *profileEngine) Context() context.Context {
+	return plugins.Context(pe.StorageEngine)
+}
+
+func (pe *profileEngine) Put(file string, content []byte) error {
+	return pe.expected(file).Put(pe.prefix+file, content)
+}
+
+func (pe *profileEngine) Remove(file string) error {
+	return pe.expected(file).Remove(pe.prefix + file)
+}
+
+//修改时期望的文件版本使用存储中的文件名
+func (pe *profileEngine) expected(file string) plugins.StorageEngine {
+	ctx := plugins.Context(pe.StorageEngine)
+	if revision, has := plugins.Expected(ctx, file); has {
+		return plugins.WithContext(plugins.Expect(ctx, pe.prefix+file, revision), pe.StorageEngine)
+	}
+	return pe.StorageEngine
+}
+
+func (pe *profileEngine) Get(file string) (*plugins.ConfigurationFile, error) {
+	cfgFile, err := pe.StorageEngine.Get(pe.prefix + file)
+	if err != nil {
+		return nil, err
+	}
+	return plugins.NewFile(file, cfgFile.Content), nil
+}
+
+func (pe *profileEngine) Search(patterns ...string) ([]*plugins.ConfigurationFile, error) {
+	return profileFiles(pe.StorageEngine, pe.prefix, patterns...)
+}
+
+//只通知环境目录中的文件变化
+func (pe *profileEngine) StartListener() <-chan plugins.FileEvent {
+	events := make(chan plugins.FileEvent)
+	go func() {
+		defer close(events)
+		for event := range pe.StorageEngine.StartListener() {
+			profileEvent := plugins.FileEvent{Type: event.Type}
+			for _, path := range event.Paths {
+				if strings.HasPrefix(path.Name, pe.prefix) {
+					path.Name = strings.TrimPrefix(path.Name, pe.prefix)
+					profileEvent.Paths = append(profileEvent.Paths, path)
+				}
+			}
+			if len(profileEvent.Paths) > 0 {
+				events <- profileEvent
+			}
+		}
+	}()
+	return events
+}
+
+func (pe *profileEngine) Start() error {
+	return util.StartService(pe.StorageEngine)
+}
+
+func (pe *profileEngine) Stop() error {
+	return util.StopService(pe.StorageEngine)
+}
+
+//目录中的文件，文件名去掉目录
+func profileFiles(engine plugins.StorageEngine, prefix string, patterns ...string) ([]*plugins.ConfigurationFile, error) {
+	files, err := engine.Search()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*plugins.ConfigurationFile, 0)
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(file.Name, prefix)
+		if matched(name, patterns...) {
+			results = append(results, plugins.NewFile(name, file.Content))
+		}
+	}
+	return results, nil
+}
+
+//提升环境时文件的变化
+type PromoteFile struct {
+	Name   string `json:"name"`
+	Action string `json:"action"` //add, modify, remove
+	Diff   string `json:"diff,omitempty"`
+}
+
+//存储中的环境
+type Profiles struct {
+	engine  plugins.StorageEngine
+	Current string `json:"current,omitempty"` //当前节点使用的环境
+}
+
+func NewProfiles(engine plugins.StorageEngine, current string) *Profiles {
+	return &Profiles{engine: engine, Current: current}
+}
+
+//存储中全部的环境名称
+func (p *Profiles) List() ([]string, error) {
+	files, err := p.engine.Search()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	exists := map[string]bool{}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name, profilesDir) {
+			continue
+		}
+		name := strings.SplitN(strings.TrimPrefix(file.Name, profilesDir), "/", 2)[0]
+		if !exists[name] {
+			exists[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func promoted(name string) bool {
+	for _, exclude := range promoteExcludes {
+		if strings.HasPrefix(name, exclude) {
+			return false
+		}
+	}
+	return true
+}
+
+//from环境的配置复制到to环境时文件的变化，aginx自己的数据（历史版本、api key、证书等）不复制
+func (p *Profiles) Diff(from, to string) ([]*PromoteFile, error) {
+	if !ValidProfile(from) || !ValidProfile(to) || from == to {
+		return nil, errors.New("invalid profiles: " + from + " -> " + to)
+	}
+	fromFiles, err := profileFiles(p.engine, profilesDir+from+"/")
+	if err != nil {
+		return nil, err
+	}
+	if len(fromFiles) == 0 {
+		return nil, os.ErrNotExist
+	}
+	toFiles, err := profileFiles(p.engine, profilesDir+to+"/")
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]*PromoteFile, 0)
+	for _, fromFile := range fromFiles {
+		if !promoted(fromFile.Name) {
+			continue
+		}
+		if toFile, has := contains(toFiles, fromFile); !has {
+			changes = append(changes, &PromoteFile{Name: fromFile.Name, Action: "add",
+				Diff: util.Diff("", string(fromFile.Content))})
+		} else if !bytes.Equal(toFile.Content, fromFile.Content) {
+			changes = append(changes, &PromoteFile{Name: fromFile.Name, Action: "modify",
+				Diff: util.Diff(string(toFile.Content), string(fromFile.Content))})
+		}
+	}
+	for _, toFile := range toFiles {
+		if _, has := contains(fromFiles, toFile); !has && promoted(toFile.Name) {
+			changes = append(changes, &PromoteFile{Name: toFile.Name, Action: "remove",
+				Diff: util.Diff(string(toFile.Content), "")})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+//使用from环境的配置替换to环境的配置，返回文件的变化
+func (p *Profiles) Promote(from, to string) ([]*PromoteFile, error) {
+	changes, err := p.Diff(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		target := profilesDir + to + "/" + change.Name
+		if change.Action == "remove" {
+			err = p.engine.Remove(target)
+		} else {
+			var file *plugins.ConfigurationFile
+			if file, err = p.engine.Get(profilesDir + from + "/" + change.Name); err == nil {
+				err = p.engine.Put(target, file.Content)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("promote ", change.Name, " from ", from, " to ", to, ", ", change.Action)
+	}
+	return changes, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"github.com/ihaiker/aginx/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestProfileEngine(t *testing.T) {
+	storage := memory.Storage{
+		"profiles/staging/nginx.conf":        []byte("staging"),
+		"profiles/staging/conf.d/a.conf":     []byte("a"),
+		"profiles/prod/nginx.conf":           []byte("prod"),
+		"nginx.conf":                         []byte("shared"),
+		"profiles/staging-old/conf.d/b.conf": []byte("b"),
+	}
+	engine, err := NewProfile(storage, "staging")
+	assert.Nil(t, err)
+	_, err = NewProfile(storage, "../prod")
+	assert.NotNil(t, err)
+
+	file, err := engine.Get("nginx.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, "staging", string(file.Content))
+	files, err := engine.Search()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(files))
+	files, err = engine.Search("conf.d/*.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "conf.d/a.conf", files[0].Name)
+
+	assert.Nil(t, engine.Put("conf.d/c.conf", []byte("c")))
+	assert.Equal(t, "c", string(storage["profiles/staging/conf.d/c.conf"]))
+	assert.Nil(t, engine.Remove("conf.d/c.conf"))
+	_, has := storage["profiles/staging/conf.d/c.conf"]
+	assert.False(t, has)
+}
+
+func TestProfilesPromote(t *testing.T) {
+	storage := memory.Storage{
+		"profiles/staging/nginx.conf":      []byte("http {}\n"),
+		"profiles/staging/conf.d/api.conf": []byte("server {}\n"),
+		"profiles/staging/history/1.json":  []byte("{}"),
+		"profiles/prod/nginx.conf":         []byte("events {}\n"),
+		"profiles/prod/conf.d/old.conf":    []byte("server {}\n"),
+		"profiles/prod/keys/1.json":        []byte("{}"),
+	}
+	profiles := NewProfiles(storage, "staging")
+	names, err := profiles.List()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"prod", "staging"}, names)
+
+	changes, err := profiles.Diff("staging", "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(changes))
+	assert.Equal(t, "conf.d/api.conf", changes[0].Name)
+	assert.Equal(t, "add", changes[0].Action)
+	assert.Equal(t, "conf.d/old.conf", changes[1].Name)
+	assert.Equal(t, "remove", changes[1].Action)
+	assert.Equal(t, "nginx.conf", changes[2].Name)
+	assert.Equal(t, "modify", changes[2].Action)
+	assert.NotEqual(t, "", changes[2].Diff)
+	//预览不修改存储
+	assert.Equal(t, "events {}\n", string(storage["profiles/prod/nginx.conf"]))
+
+	_, err = profiles.Promote("staging", "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "http {}\n", string(storage["profiles/prod/nginx.conf"]))
+	assert.Equal(t, "server {}\n", string(storage["profiles/prod/conf.d/api.conf"]))
+	_, has := storage["profiles/prod/conf.d/old.conf"]
+	assert.False(t, has)
+	_, has = storage["profiles/prod/history/1.json"]
+	assert.False(t, has)
+	_, has = storage["profiles/prod/keys/1.json"]
+	assert.True(t, has)
+
+	changes, err = profiles.Diff("staging", "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(changes))
+	_, err = profiles.Diff("dev", "prod")
+	assert.True(t, os.IsNotExist(err))
+	_, err = profiles.Diff("prod", "prod")
+	assert.NotNil(t, err)
+}
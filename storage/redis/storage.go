@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var logger = logs.New("storage", "engine", "redis")
+
+type redisStorage struct {
+	client *redis.Client
+	db     int
+	prefix string
+	closeC chan struct{}
+}
+
+//redis://[:password@]127.0.0.1:6379/0?prefix=aginx
+func New(clusterConfig *url.URL) (rs *redisStorage, err error) {
+	rs = &redisStorage{prefix: "aginx", closeC: make(chan struct{})}
+	if prefix := clusterConfig.Query().Get("prefix"); prefix != "" {
+		rs.prefix = strings.Trim(prefix, "/")
+	}
+	if db := strings.Trim(clusterConfig.EscapedPath(), "/"); db != "" {
+		if rs.db, err = strconv.Atoi(db); err != nil {
+			return nil, err
+		}
+	}
+	options := &redis.Options{Addr: clusterConfig.Host, DB: rs.db}
+	if clusterConfig.User != nil {
+		options.Password, _ = clusterConfig.User.Password()
+	}
+	rs.client = redis.NewClient(options)
+	if err = rs.client.Ping().Err(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (rs *redisStorage) key(file string) string {
+	return rs.prefix + "/" + file
+}
+
+func (rs *redisStorage) name(key string) string {
+	return strings.TrimPrefix(key, rs.prefix+"/")
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+//转义redis glob（SCAN MATCH、PSUBSCRIBE）中的特殊字符，前缀和文件名按照字面匹配
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
+
+func (rs *redisStorage) IsCluster() bool {
+	return true
+}
+
+func (rs *redisStorage) keys(pattern string) ([]string, error) {
+	keys := make([]string, 0)
+	var cursor uint64
+	for {
+		ks, next, err := rs.client.Scan(cursor, pattern, 100).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ks...)
+		if cursor = next; cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+func (rs *redisStorage) Search(args ...string) ([]*plugins.ConfigurationFile, error) {
+	files := make([]*plugins.ConfigurationFile, 0)
+	keys, err := rs.keys(escapeGlob(rs.prefix+"/") + "*")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		name := rs.name(key)
+		matched := len(args) == 0
+		for _, arg := range args {
+			if matched, _ = filepath.Match(arg, name); matched {
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if content, err := rs.client.Get(key).Bytes(); err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		} else {
+			files = append(files, plugins.NewFile(name, content))
+		}
+	}
+	return files, nil
+}
+
+func (rs *redisStorage) Remove(file string) error {
+	key := rs.key(file)
+	logger.Debug("remove ", key)
+	//文件夹形式删除
+	keys, err := rs.keys(escapeGlob(key+"/") + "*")
+	if err != nil {
+		return err
+	}
+	keys = append(keys, key)
+	return rs.client.Del(keys...).Err()
+}
+
+func (rs *redisStorage) Get(file string) (*plugins.ConfigurationFile, error) {
+	if content, err := rs.client.Get(rs.key(file)).Bytes(); err == redis.Nil {
+		return nil, os.ErrNotExist
+	} else if err != nil {
+		return nil, err
+	} else {
+		return plugins.NewFile(file, content), nil
+	}
+}
+
+func (rs *redisStorage) Put(file string, content []byte) error {
+	logger.Debug("store file ", file)
+	return rs.client.Set(rs.key(file), content, 0).Err()
+}
+
+func (rs *redisStorage) StartListener() <-chan plugins.FileEvent {
+	return NewWatcher(rs)
+}
+
+func (rs *redisStorage) Start() error {
+	return nil
+}
+
+func (rs *redisStorage) Stop() error {
+	close(rs.closeC)
+	return rs.client.Close()
+}
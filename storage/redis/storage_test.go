@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"github.com/ihaiker/aginx/logs"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	url2 "net/url"
+	"strconv"
+	"testing"
+)
+
+func init() {
+	logs.SetLevel(logrus.DebugLevel)
+}
+
+func newClient(t *testing.T) *redisStorage {
+	url, _ := url2.Parse("redis://127.0.0.1:6379/0?prefix=aginx")
+	engine, err := New(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestEngine(t *testing.T) {
+	api := newClient(t)
+
+	err := api.Put("nginx.conf", []byte("nginx configuration 2."))
+	assert.Nil(t, err)
+
+	reader, err := api.Get("nginx.conf")
+	assert.Nil(t, err, "get file")
+
+	t.Log(reader.String())
+}
+
+func TestRemove(t *testing.T) {
+	api := newClient(t)
+
+	for i := 0; i < 10; i++ {
+		err := api.Put("test/nginx"+strconv.Itoa(i)+".conf", []byte("nginx configuration ."+strconv.Itoa(i)))
+		assert.Nil(t, err)
+	}
+
+	files, err := api.Search("test/*.conf")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(files))
+
+	assert.Nil(t, api.Remove("test/nginx0.conf"))
+	assert.Nil(t, api.Remove("test"))
+}
+
+func TestMergeKeyspaceEvents(t *testing.T) {
+	merged, changed := mergeKeyspaceEvents("")
+	assert.True(t, changed)
+	assert.Equal(t, "K$gxe", merged)
+
+	//保留其他程序使用的事件
+	merged, changed = mergeKeyspaceEvents("Ex")
+	assert.True(t, changed)
+	assert.Equal(t, "ExK$ge", merged)
+
+	_, changed = mergeKeyspaceEvents("KA")
+	assert.False(t, changed)
+	merged, changed = mergeKeyspaceEvents("AE")
+	assert.True(t, changed)
+	assert.Equal(t, "AEK", merged)
+	_, changed = mergeKeyspaceEvents("Kg$xel")
+	assert.False(t, changed)
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, "aginx/", escapeGlob("aginx/"))
+	assert.Equal(t, `aginx/hosts.d/\*.conf/`, escapeGlob("aginx/hosts.d/*.conf/"))
+	assert.Equal(t, `a\?b\[c\]\\d`, escapeGlob(`a?b[c]\d`))
+}
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"strings"
+)
+
+//监听文件变化需要的keyspace notification：K（keyspace事件）$（set）g（del）x（expired）e（evicted）
+const keyspaceEvents = "K$gxe"
+
+//在redis当前的 notify-keyspace-events 中添加缺少的事件，不会去掉其他程序使用的事件。没有缺少时返回false
+func mergeKeyspaceEvents(current string) (string, bool) {
+	merged := current
+	for _, flag := range keyspaceEvents {
+		//A 是 g$lshzxet 的别名
+		if strings.ContainsRune(merged, flag) || (flag != 'K' && strings.ContainsRune(merged, 'A')) {
+			continue
+		}
+		merged += string(flag)
+	}
+	return merged, merged != current
+}
+
+//开启需要的keyspace notification，托管的redis禁用了CONFIG命令时需要提前配置
+func enableKeyspaceEvents(client *redis.Client) error {
+	values, err := client.ConfigGet("notify-keyspace-events").Result()
+	if err != nil {
+		return err
+	}
+	current := ""
+	if len(values) == 2 {
+		current, _ = values[1].(string)
+	}
+	merged, changed := mergeKeyspaceEvents(current)
+	if !changed {
+		return nil
+	}
+	logger.Infof("notify-keyspace-events %q changed to %q", current, merged)
+	return client.ConfigSet("notify-keyspace-events", merged).Err()
+}
+
+//使用redis keyspace notification监听文件变化，需要redis开启 notify-keyspace-events
+func NewWatcher(rs *redisStorage) chan plugins.FileEvent {
+	listener := make(chan plugins.FileEvent)
+
+	if err := enableKeyspaceEvents(rs.client); err != nil {
+		logger.Errorf("enable keyspace notifications error, the changes of other nodes will not be received. "+
+			"configure the redis server with notify-keyspace-events including %q: %s", keyspaceEvents, err)
+	}
+
+	channelPrefix := fmt.Sprintf("__keyspace@%d__:", rs.db)
+	pubsub := rs.client.PSubscribe(escapeGlob(channelPrefix+rs.prefix+"/") + "*")
+
+	go func() {
+		defer util.Catch(func(err error) {
+			logger.Info("watcher error: ", err)
+		})
+		defer func() { _ = pubsub.Close() }()
+
+		//停止后不再等待接收者，避免阻塞
+		send := func(event plugins.FileEvent) {
+			select {
+			case <-rs.closeC:
+			case listener <- event:
+			}
+		}
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-rs.closeC:
+				return
+			case message, has := <-messages:
+				if !has {
+					return
+				}
+				key := strings.TrimPrefix(message.Channel, channelPrefix)
+				name := rs.name(key)
+				switch message.Payload {
+				case "set":
+					if content, err := rs.client.Get(key).Bytes(); err == nil {
+						send(plugins.FileEvent{
+							Type:  plugins.FileEventTypeUpdate,
+							Paths: []plugins.ConfigurationFile{{Name: name, Content: content}},
+						})
+					} else if err != redis.Nil {
+						logger.Warn("get file ", name, " error ", err)
+					}
+				case "del", "expired", "evicted":
+					send(plugins.FileEvent{
+						Type:  plugins.FileEventTypeRemove,
+						Paths: []plugins.ConfigurationFile{{Name: name}},
+					})
+				}
+			}
+		}
+	}()
+	return listener
+}
@@ -0,0 +1,175 @@
+package s3
+
+import (
+	"bytes"
+	"github.com/ihaiker/aginx/logs"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/minio/minio-go/v6"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var logger = logs.New("storage", "engine", "s3")
+
+type s3Storage struct {
+	client   *minio.Client
+	bucket   string
+	folder   string
+	interval time.Duration
+	closeC   chan struct{}
+}
+
+//s3://bucket/aginx?endpoint=s3.amazonaws.com&access_key=&secret_key=&ssl=true&interval=10s
+func New(clusterConfig *url.URL) (s3 *s3Storage, err error) {
+	query := clusterConfig.Query()
+	s3 = &s3Storage{
+		bucket: clusterConfig.Host,
+		folder: strings.Trim(clusterConfig.EscapedPath(), "/"),
+		closeC: make(chan struct{}), interval: time.Second * 10,
+	}
+	endpoint := query.Get("endpoint")
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	accessKey := query.Get("access_key")
+	if accessKey == "" {
+		accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	secretKey := query.Get("secret_key")
+	if secretKey == "" {
+		secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if interval := query.Get("interval"); interval != "" {
+		if s3.interval, err = time.ParseDuration(interval); err != nil {
+			return nil, err
+		}
+	}
+	if s3.client, err = minio.New(endpoint, accessKey, secretKey, query.Get("ssl") != "false"); err != nil {
+		return nil, err
+	}
+	if exists, err := s3.client.BucketExists(s3.bucket); err != nil {
+		return nil, err
+	} else if !exists {
+		logger.Info("create bucket ", s3.bucket)
+		if err = s3.client.MakeBucket(s3.bucket, query.Get("region")); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func (s3 *s3Storage) object(file string) string {
+	if s3.folder == "" {
+		return file
+	}
+	return s3.folder + "/" + file
+}
+
+func (s3 *s3Storage) name(object string) string {
+	if s3.folder == "" {
+		return object
+	}
+	return strings.TrimPrefix(object, s3.folder+"/")
+}
+
+func (s3 *s3Storage) IsCluster() bool {
+	return true
+}
+
+//列出所有对象
+func (s3 *s3Storage) list(prefix string) ([]minio.ObjectInfo, error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	objects := make([]minio.ObjectInfo, 0)
+	for object := range s3.client.ListObjectsV2(s3.bucket, prefix, true, doneCh) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func (s3 *s3Storage) Search(args ...string) ([]*plugins.ConfigurationFile, error) {
+	files := make([]*plugins.ConfigurationFile, 0)
+	objects, err := s3.list(s3.object(""))
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		name := s3.name(object.Key)
+		matched := len(args) == 0
+		for _, arg := range args {
+			if matched, _ = filepath.Match(arg, name); matched {
+				break
+			}
+		}
+		if matched {
+			if file, err := s3.Get(name); err == nil {
+				files = append(files, file)
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+func (s3 *s3Storage) Remove(file string) error {
+	logger.Debug("remove ", file)
+	objects, err := s3.list(s3.object(file))
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		//前缀匹配时排除 file.conf 与 file.conf.bak 这种情况
+		if name := s3.name(object.Key); name == file || strings.HasPrefix(name, file+"/") {
+			if err := s3.client.RemoveObject(s3.bucket, object.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s3 *s3Storage) Get(file string) (*plugins.ConfigurationFile, error) {
+	object, err := s3.client.GetObject(s3.bucket, s3.object(file), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = object.Close() }()
+
+	content, err := ioutil.ReadAll(object)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, os.ErrNotExist
+		}
+		return nil, err
+	}
+	return plugins.NewFile(file, content), nil
+}
+
+func (s3 *s3Storage) Put(file string, content []byte) error {
+	logger.Debug("store file ", file)
+	_, err := s3.client.PutObject(s3.bucket, s3.object(file), bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{ContentType: "text/plain"})
+	return err
+}
+
+func (s3 *s3Storage) StartListener() <-chan plugins.FileEvent {
+	return NewWatcher(s3)
+}
+
+func (s3 *s3Storage) Start() error {
+	return nil
+}
+
+func (s3 *s3Storage) Stop() error {
+	close(s3.closeC)
+	return nil
+}
@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"time"
+)
+
+//对象存储没有统一的变更通知，这里使用定时对比ETag的方式获取变化
+type watcher struct {
+	s3       *s3Storage
+	etags    map[string]string
+	Listener chan plugins.FileEvent
+}
+
+func NewWatcher(s3 *s3Storage) chan plugins.FileEvent {
+	w := &watcher{
+		s3: s3, etags: nil,
+		Listener: make(chan plugins.FileEvent),
+	}
+	go func() {
+		defer util.Catch(func(err error) {
+			logger.Info("watcher error: ", err)
+		})
+		ticker := time.NewTicker(s3.interval)
+		defer ticker.Stop()
+		for {
+			w.watchChange()
+			select {
+			case <-s3.closeC:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return w.Listener
+}
+
+func (w *watcher) watchChange() {
+	objects, err := w.s3.list(w.s3.object(""))
+	if err != nil {
+		logger.Warn("list objects error ", err)
+		return
+	}
+
+	etags := make(map[string]string)
+	for _, object := range objects {
+		etags[w.s3.name(object.Key)] = object.ETag
+	}
+	//第一次仅记录
+	if w.etags == nil {
+		w.etags = etags
+		return
+	}
+
+	updates := plugins.FileEvent{Type: plugins.FileEventTypeUpdate, Paths: []plugins.ConfigurationFile{}}
+	for name, etag := range etags {
+		if old, has := w.etags[name]; !has || old != etag {
+			if file, err := w.s3.Get(name); err == nil {
+				updates.Paths = append(updates.Paths, *file)
+			} else {
+				logger.Warn("get file ", name, " error ", err)
+				delete(etags, name)
+			}
+		}
+	}
+	removes := plugins.FileEvent{Type: plugins.FileEventTypeRemove, Paths: []plugins.ConfigurationFile{}}
+	for name := range w.etags {
+		if _, has := etags[name]; !has {
+			removes.Paths = append(removes.Paths, plugins.ConfigurationFile{Name: name})
+		}
+	}
+	w.etags = etags
+
+	if len(updates.Paths) > 0 {
+		w.Listener <- updates
+	}
+	if len(removes.Paths) > 0 {
+		w.Listener <- removes
+	}
+}
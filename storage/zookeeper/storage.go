@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -21,15 +22,17 @@ type zkStorage struct {
 	watcher *Watcher
 }
 
+//zk://127.0.0.1:2181,127.0.0.1:2182/aginx?scheme=&auth=
 func New(clusterConfig *url.URL) (zks *zkStorage, err error) {
-	address := clusterConfig.Host
+	servers := strings.Split(clusterConfig.Host, ",")
 	folder := clusterConfig.EscapedPath()
 	scheme := clusterConfig.Query().Get("scheme")
 	auth := clusterConfig.Query().Get("auth")
 
 	zks = &zkStorage{folder: folder}
-	zks.keeper, _, err = zk.Connect([]string{address}, time.Second*3)
-	zks.keeper.SetLogger(logger)
+	if zks.keeper, _, err = zk.Connect(servers, time.Second*3, zk.WithLogger(logger)); err != nil {
+		return nil, err
+	}
 	if scheme != "" {
 		if err = zks.keeper.AddAuth(scheme, []byte(auth)); err != nil {
 			return nil, err
@@ -66,6 +69,7 @@ func (zks *zkStorage) zkList(path string, onlyFile bool) ([]*plugins.Configurati
 				}
 			} else {
 				relPath, _ := filepath.Rel(zks.folder, file)
+				relPath = filepath.ToSlash(relPath)
 				readers = append(readers, plugins.NewFile(relPath, data))
 			}
 		}
@@ -121,7 +125,7 @@ func (zks *zkStorage) Remove(file string) error {
 func (zks *zkStorage) Get(file string) (*plugins.ConfigurationFile, error) {
 	path := zks.folder + "/" + file
 	if data, _, err := zks.keeper.Get(path); err != nil {
-		if err.Error() == "zk: node does not exist" {
+		if err == zk.ErrNoNode {
 			err = os.ErrNotExist
 		}
 		return nil, err
@@ -175,8 +179,8 @@ func (zks *zkStorage) StartListener() <-chan plugins.FileEvent {
 	if zkFiles, err := zks.zkList(zks.folder, false); err == nil {
 		zks.watcher.Folder(zks.folder)
 		for _, zkFile := range zkFiles {
-			if zkFile.Content != nil { //file
-				zks.watcher.File(zkFile.Name)
+			if zkFile.Content != nil { //file, the name is relative to the storage folder
+				zks.watcher.File(zks.folder + "/" + zkFile.Name)
 			} else {
 				zks.watcher.Folder(zkFile.Name)
 			}
@@ -186,6 +190,7 @@ func (zks *zkStorage) StartListener() <-chan plugins.FileEvent {
 
 			for event := range zks.watcher.C {
 				relPath, _ := filepath.Rel(zks.folder, event.Path)
+				relPath = filepath.ToSlash(relPath)
 				switch event.Type {
 				case zk.EventNodeCreated, zk.EventNodeDataChanged:
 					data, _, _ := zks.keeper.Get(event.Path)
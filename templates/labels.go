@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//标签前缀，consul meta 等不支持 . 的使用 aginx-
+var labelPrefixes = []string{"aginx.", "aginx-"}
+
+//服务发现使用的标签，不作为指令覆盖
+var reservedLabels = map[string]bool{
+	"domain": true, "port": true, "network": true, "template": true, "websocket": true,
+}
+
+var directiveRegexp = regexp.MustCompile("^[a-z][a-z0-9_]*$")
+var templateRegexp = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+func labelName(key string) (string, bool) {
+	for _, prefix := range labelPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return key[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+//服务标签中指定的模板名称，例如：aginx.template=grpc
+func LabelTemplate(labels map[string]string) string {
+	for _, prefix := range labelPrefixes {
+		if name, has := labels[prefix+"template"]; has && templateRegexp.MatchString(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+//服务标签是否开启WebSocket，例如：aginx.websocket=true
+func LabelWebSocket(labels map[string]string) bool {
+	for _, prefix := range labelPrefixes {
+		if labels[prefix+"websocket"] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+//服务标签中指定的覆盖指令，例如：aginx.client_max_body_size=100m
+func LabelDirectives(labels map[string]string) map[string]string {
+	directives := map[string]string{}
+	for key, value := range labels {
+		if name, has := labelName(key); has && !reservedLabels[name] && directiveRegexp.MatchString(name) {
+			directives[name] = value
+		}
+	}
+	return directives
+}
+
+//将覆盖指令合并到生成配置中 server_name 为 domain 的 server 中，已存在的指令替换参数，不存在的添加
+func Override(content []byte, domain string, directives map[string]string) ([]byte, error) {
+	if len(directives) == 0 {
+		return content, nil
+	}
+	conf, err := nginx.ReaderReadable(nil, plugins.NewFile(domain, content))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(directives))
+	for name := range directives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, server := range conf.Body {
+		if server.Name != "server" || !hasServerName(server, domain) {
+			continue
+		}
+		for _, name := range names {
+			args := strings.Fields(directives[name])
+			if exists := findDirective(server, name); exists != nil {
+				exists.Args = args
+			} else {
+				server.AddBody(name, args...)
+			}
+		}
+	}
+	return conf.BodyBytes(), nil
+}
+
+func hasServerName(server *nginx.Directive, domain string) bool {
+	if serverName := findDirective(server, "server_name"); serverName != nil {
+		for _, arg := range serverName.Args {
+			if arg == domain {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findDirective(server *nginx.Directive, name string) *nginx.Directive {
+	for _, body := range server.Body {
+		if body.Name == name {
+			return body
+		}
+	}
+	return nil
+}
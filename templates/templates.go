@@ -0,0 +1,247 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ihaiker/aginx/lego"
+	"github.com/ihaiker/aginx/nginx"
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/ihaiker/aginx/util"
+	"text/template"
+)
+
+//模板保存在存储引擎的 templates 目录中
+const Dir = "templates"
+
+//服务发现和暴露api使用的模板名称
+const (
+	Server = "server"
+	Api    = "api"
+)
+
+//系统默认模板
+const DefaultServer = `
+upstream {{ .Data.Upstream }} { {{range .Data.Servers}}
+	server {{.Address}} {{if ne .Weight 0}} weight={{.Weight}}{{end}};{{end}}
+}
+{{if .Data.WebSocket}}map $http_upgrade $connection_upgrade {
+	default upgrade;
+	'' close;
+}{{end}}
+{{if .Data.AutoSSL}}server {
+	listen       80;
+	server_name {{.Data.Domain}};	
+	return 301 https://$host{{if and .Data.Expose.Port (ne .Data.Expose.Port 443)}}:{{.Data.Expose.Port}}{{end}}$request_uri;
+}{{end}}
+server { {{if .Data.AutoSSL}}
+	listen {{or .Data.Expose.Port 443}} ssl;
+	ssl_certificate     {{.Data.SSL.Certificate}};        
+	ssl_certificate_key {{.Data.SSL.PrivateKey}};
+	ssl_session_timeout 5m;
+	ssl_ciphers ECDHE-RSA-AES128-GCM-SHA256:ECDHE:ECDH:AES:HIGH:!NULL:!aNULL:!MD5:!ADH:!RC4;
+	ssl_protocols TLSv1 TLSv1.1 TLSv1.2;
+	ssl_prefer_server_ciphers on; {{else}}
+	listen {{or .Data.Expose.Port 80}}; {{end}}
+
+    server_name {{.Data.Domain}};{{range .Data.Expose.Deny}}
+    deny {{.}};{{end}}{{range .Data.Expose.Allow}}
+    allow {{.}};{{end}}{{if .Data.Expose.Allow}}
+    deny all;{{end}}{{if .Data.Expose.Htpasswd}}
+    auth_basic "Restricted";
+    auth_basic_user_file {{htpasswdPath .Data.Expose.Htpasswd}};{{end}}{{if .Data.Expose.Path}}
+
+    location {{.Data.Expose.Path}} { {{else}}
+    try_files $uri @tornado;
+
+    location @tornado { {{end}}
+        proxy_set_header        X-Scheme        $scheme;
+        proxy_set_header        Host            $host;
+        proxy_set_header        X-Real-IP       $remote_addr;
+        proxy_set_header        X-Forwarded-For $proxy_add_x_forwarded_for;{{if .Data.WebSocket}}
+        proxy_http_version      1.1;
+        proxy_set_header        Upgrade         $http_upgrade;
+        proxy_set_header        Connection      $connection_upgrade;
+        proxy_read_timeout      3600s;
+        proxy_send_timeout      3600s;{{end}}
+        proxy_pass http://{{ .Data.Upstream }}{{if .Data.Expose.Path}}/{{end}};
+    }
+}
+`
+
+//内置模板库，服务使用标签 aginx.template=<name> 选择，存储中存在 templates/<name>.ngx.tpl 时优先使用
+var Library = map[string]string{
+	"grpc": `
+upstream {{ .Data.Upstream }} { {{range .Data.Servers}}
+	server {{.Address}} {{if ne .Weight 0}} weight={{.Weight}}{{end}};{{end}}
+}
+server { {{if .Data.AutoSSL}}
+	listen 443 ssl http2;
+	ssl_certificate     {{.Data.SSL.Certificate}};
+	ssl_certificate_key {{.Data.SSL.PrivateKey}};
+	ssl_session_timeout 5m;
+	ssl_protocols TLSv1 TLSv1.1 TLSv1.2;
+	ssl_prefer_server_ciphers on; {{else}}
+	listen 80 http2; {{end}}
+
+	server_name {{.Data.Domain}};
+
+	location / {
+		grpc_set_header X-Real-IP $remote_addr;
+		grpc_pass grpc://{{ .Data.Upstream }};
+	}
+}
+`,
+	"websocket": `
+upstream {{ .Data.Upstream }} { {{range .Data.Servers}}
+	server {{.Address}} {{if ne .Weight 0}} weight={{.Weight}}{{end}};{{end}}
+}
+{{if .Data.AutoSSL}}server {
+	listen       80;
+	server_name {{.Data.Domain}};
+	return 301 https://$host$request_uri;
+}{{end}}
+server { {{if .Data.AutoSSL}}
+	listen 443 ssl;
+	ssl_certificate     {{.Data.SSL.Certificate}};
+	ssl_certificate_key {{.Data.SSL.PrivateKey}};
+	ssl_session_timeout 5m;
+	ssl_protocols TLSv1 TLSv1.1 TLSv1.2;
+	ssl_prefer_server_ciphers on; {{else}}
+	listen 80; {{end}}
+
+	server_name {{.Data.Domain}};
+
+	location / {
+		proxy_http_version 1.1;
+		proxy_set_header Upgrade $http_upgrade;
+		proxy_set_header Connection "upgrade";
+		proxy_set_header Host $host;
+		proxy_set_header X-Real-IP $remote_addr;
+		proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+		proxy_read_timeout 3600s;
+		proxy_pass http://{{ .Data.Upstream }};
+	}
+}
+`,
+}
+
+//暴露api的监听端口和访问限制，--expose-* 参数设置，服务发现时为空
+type Expose struct {
+	Port     int      //监听端口，默认80，使用ssl时443
+	Allow    []string //允许访问的IP或者IP段，设置后拒绝其他地址
+	Deny     []string //拒绝访问的IP或者IP段
+	Htpasswd string   //basic认证使用的htpasswd文件名称，htpasswd/<name>
+	Path     string   //location路径前缀，以 / 结尾，代理时去掉前缀
+}
+
+//模板数据，模板中使用 .Data 访问
+type Data struct {
+	Domain    string
+	Upstream  string
+	AutoSSL   bool
+	WebSocket bool //代理WebSocket，标签 aginx.websocket=true
+	SSL       *lego.StoreFile
+	Servers   plugins.Domains
+	Labels    map[string]string
+
+	Template   string            //标签指定的模板，aginx.template
+	Directives map[string]string //标签指定的覆盖指令，aginx.<directive>
+	Expose     Expose            //暴露api的设置
+}
+
+func NewData(domain string, autoSSL bool, servers plugins.Domains) *Data {
+	data := &Data{
+		Domain: domain, Upstream: nginx.UpstreamName(domain),
+		AutoSSL: autoSSL, Servers: servers, Labels: map[string]string{},
+	}
+	if len(servers) > 0 && servers[0].Attrs != nil {
+		data.Labels = servers[0].Attrs
+	}
+	data.Template = LabelTemplate(data.Labels)
+	data.WebSocket = LabelWebSocket(data.Labels)
+	data.Directives = LabelDirectives(data.Labels)
+	return data
+}
+
+//读取模板文件，文件不存在时返回错误
+type Getter func(path string) (string, error)
+
+func Path(name string) string {
+	return fmt.Sprintf("%s/%s.ngx.tpl", Dir, name)
+}
+
+//查找模板，依次查找 templates/<name>.ngx.tpl、templates/server.ngx.tpl、系统默认模板
+func Find(get Getter, name string) string {
+	for _, path := range []string{Path(name), Path(Server)} {
+		if content, err := get(path); err == nil && content != "" {
+			return content
+		}
+	}
+	return DefaultServer
+}
+
+//从存储引擎中读取
+func EngineGetter(engine plugins.StorageEngine) Getter {
+	return func(path string) (string, error) {
+		file, err := engine.Get(path)
+		if err != nil {
+			return "", err
+		}
+		return string(file.Content), nil
+	}
+}
+
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"upstreamName": nginx.UpstreamName,
+		"htpasswdPath": nginx.HtpasswdPath,
+	}
+}
+
+//使用模板生成配置，data 在模板中使用 .Data 访问
+func Render(content string, funcs template.FuncMap, data interface{}) ([]byte, error) {
+	t, err := template.New("").Funcs(FuncMap()).Funcs(funcs).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.NewBufferString("")
+	if err = t.Execute(out, data); err != nil {
+		return nil, err
+	}
+	return util.CleanEmptyLine(out.Bytes()), nil
+}
+
+//使用模板发布domain的配置到 hosts.d/<domain>.ngx.conf，暴露api时使用
+func PublishServer(client *nginx.Client, name, domain string, ssl, websocket bool, expose Expose, address ...string) error {
+	servers := plugins.Domains{}
+	for _, addr := range address {
+		servers = append(servers, plugins.Domain{ID: addr, Domain: domain, Address: addr, AutoSSL: ssl})
+	}
+	data := NewData(domain, ssl, servers)
+	data.WebSocket = websocket
+	data.Expose = expose
+	if ssl {
+		data.SSL = client.NewCertificate(client.Email, domain)
+	}
+	content := Find(EngineGetter(client.Engine), name)
+	body, err := Render(content, template.FuncMap{}, map[string]interface{}{"Data": data})
+	if err != nil {
+		return err
+	}
+	conf, err := nginx.ReaderReadable(client.Engine, plugins.NewFile(Path(name), body))
+	if err != nil {
+		return err
+	}
+	//$connection_upgrade定义在http中，重复发布时不会在文件中重复添加
+	directives := make([]*nginx.Directive, 0, len(conf.Body))
+	for _, directive := range conf.Body {
+		if directive.Name == "map" && len(directive.Args) == 2 && directive.Args[1] == nginx.ConnectionUpgrade {
+			if err = client.SetConnectionUpgrade(); err != nil {
+				return err
+			}
+			continue
+		}
+		directives = append(directives, directive)
+	}
+	return client.HostServer(domain, directives...)
+}
@@ -0,0 +1,113 @@
+package templates
+
+import (
+	"github.com/ihaiker/aginx/plugins"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"text/template"
+)
+
+func getter(files map[string]string) Getter {
+	return func(path string) (string, error) {
+		if content, has := files[path]; has {
+			return content, nil
+		}
+		return "", os.ErrNotExist
+	}
+}
+
+func TestFind(t *testing.T) {
+	assert.Equal(t, DefaultServer, Find(getter(map[string]string{}), Api))
+	assert.Equal(t, "server", Find(getter(map[string]string{"templates/server.ngx.tpl": "server"}), Api))
+	assert.Equal(t, "api", Find(getter(map[string]string{
+		"templates/server.ngx.tpl": "server", "templates/api.ngx.tpl": "api",
+	}), Api))
+}
+
+func TestRender(t *testing.T) {
+	data := NewData("api.aginx.io", false, plugins.Domains{
+		{Address: "127.0.0.1:8011", Weight: 2, Attrs: map[string]string{"client_max_body_size": "100m"}},
+	})
+	content := `upstream {{ .Data.Upstream }} { {{range .Data.Servers}}server {{.Address}} weight={{.Weight}};{{end}} }
+client_max_body_size {{ index .Data.Labels "client_max_body_size" }};`
+	body, err := Render(content, template.FuncMap{}, map[string]interface{}{"Data": data})
+	assert.Nil(t, err)
+	assert.Equal(t, "upstream api_aginx_io { server 127.0.0.1:8011 weight=2; }\nclient_max_body_size 100m;\n", string(body))
+}
+
+func TestRenderWebSocket(t *testing.T) {
+	data := NewData("ws.aginx.io", false, plugins.Domains{
+		{Address: "127.0.0.1:8080", Attrs: map[string]string{"aginx.websocket": "true"}},
+	})
+	assert.True(t, data.WebSocket)
+	body, err := Render(DefaultServer, template.FuncMap{}, map[string]interface{}{"Data": data})
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "map $http_upgrade $connection_upgrade {")
+	assert.Contains(t, string(body), "Connection      $connection_upgrade;")
+
+	data = NewData("api.aginx.io", false, plugins.Domains{{Address: "127.0.0.1:8011"}})
+	assert.False(t, data.WebSocket)
+	body, err = Render(DefaultServer, template.FuncMap{}, map[string]interface{}{"Data": data})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(body), "upgrade")
+}
+
+func TestRenderExpose(t *testing.T) {
+	data := NewData("api.aginx.io", false, plugins.Domains{{Address: "127.0.0.1:8011"}})
+	data.Expose = Expose{Port: 8080, Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.1"}, Htpasswd: "admin", Path: "/aginx/"}
+	body, err := Render(DefaultServer, template.FuncMap{}, map[string]interface{}{"Data": data})
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "listen 8080;")
+	assert.Contains(t, string(body), "deny 10.0.0.1;\n    allow 10.0.0.0/8;\n    deny all;")
+	assert.Contains(t, string(body), "auth_basic_user_file htpasswd/admin;")
+	assert.Contains(t, string(body), "location /aginx/ {")
+	assert.Contains(t, string(body), "proxy_pass http://api_aginx_io/;")
+	assert.NotContains(t, string(body), "@tornado")
+
+	data = NewData("api.aginx.io", false, plugins.Domains{{Address: "127.0.0.1:8011"}})
+	body, err = Render(DefaultServer, template.FuncMap{}, map[string]interface{}{"Data": data})
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "listen 80;")
+	assert.Contains(t, string(body), "location @tornado {")
+	assert.NotContains(t, string(body), "allow")
+	assert.NotContains(t, string(body), "auth_basic")
+}
+
+func TestLabels(t *testing.T) {
+	labels := map[string]string{
+		"aginx.domain": "api.aginx.io", "aginx.domain.8080": "api8080.aginx.io", "aginx.port": "8080",
+		"aginx.template": "grpc", "aginx.client_max_body_size": "100m", "aginx-proxy_read_timeout": "60s",
+		"com.docker.compose.service": "api",
+	}
+	assert.Equal(t, "grpc", LabelTemplate(labels))
+	assert.Equal(t, "", LabelTemplate(map[string]string{"aginx.template": "../grpc"}))
+	assert.Equal(t, map[string]string{
+		"client_max_body_size": "100m", "proxy_read_timeout": "60s",
+	}, LabelDirectives(labels))
+}
+
+func TestOverride(t *testing.T) {
+	content := []byte(`upstream api_aginx_io { server 127.0.0.1:8011; }
+server {
+	listen 80;
+	server_name api.aginx.io;
+	client_max_body_size 10m;
+	location / { proxy_pass http://api_aginx_io; }
+}
+server {
+	listen 80;
+	server_name other.aginx.io;
+}`)
+	out, err := Override(content, "api.aginx.io", map[string]string{
+		"client_max_body_size": "100m", "proxy_read_timeout": "60s",
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), "    client_max_body_size 100m;\n")
+	assert.Contains(t, string(out), "    proxy_read_timeout 60s;\n}\nserver {")
+	assert.NotContains(t, string(out), "10m")
+
+	out, err = Override(content, "api.aginx.io", map[string]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, content, out)
+}
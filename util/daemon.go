@@ -14,10 +14,20 @@ type Service interface {
 
 type daemon struct {
 	services []Service
+	watchdog chan struct{}
+	timeout  time.Duration //停止全部服务的最长时间，超时后直接退出
 }
 
 func NewDaemon() *daemon {
-	return &daemon{services: make([]Service, 0)}
+	return &daemon{services: make([]Service, 0), timeout: time.Second * 7}
+}
+
+//设置停止的最长时间，服务按照添加的相反顺序停止
+func (d *daemon) Timeout(timeout time.Duration) *daemon {
+	if timeout > 0 {
+		d.timeout = timeout
+	}
+	return d
 }
 
 func (d *daemon) Add(service ...Service) *daemon {
@@ -46,11 +56,26 @@ func (d *daemon) Stop() error {
 	return nil
 }
 
+//停止信号，StopDaemon 发送，windows服务停止时使用
+var stopSignals = make(chan os.Signal, 1)
+
+//停止正在运行的daemon，和收到SIGTERM相同
+func StopDaemon() {
+	select {
+	case stopSignals <- syscall.SIGTERM:
+	default:
+	}
+}
+
 func (d *daemon) await() error {
-	C := make(chan os.Signal)
+	C := stopSignals
 	signal.Notify(C, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	for _ = range C {
-		err := Async(time.Second*7, d.Stop)
+		_, _ = SdNotify("STOPPING=1")
+		if d.watchdog != nil {
+			close(d.watchdog)
+		}
+		err := Async(d.timeout, d.Stop)
 		if err == ErrTimeout {
 			os.Exit(1)
 			return nil
@@ -70,9 +95,32 @@ func (d *daemon) Start() error {
 			return err
 		}
 	}
+	d.notifyReady()
 	return d.await()
 }
 
+//通知systemd启动完成，开启WatchdogSec时定时发送心跳
+func (d *daemon) notifyReady() {
+	if notified, err := SdNotify("READY=1"); !notified || err != nil {
+		return
+	}
+	if interval := SdWatchdogInterval(); interval > 0 {
+		d.watchdog = make(chan struct{})
+		go func(stop chan struct{}) {
+			ticker := time.NewTicker(interval / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					_, _ = SdNotify("WATCHDOG=1")
+				}
+			}
+		}(d.watchdog)
+	}
+}
+
 type funcService struct {
 	StartFn func() error
 	StopFn  func() error
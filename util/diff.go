@@ -0,0 +1,38 @@
+package util
+
+import (
+	"bytes"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"strings"
+)
+
+//逐行比较两个文本内容，删除行使用 - 开头，新增行使用 + 开头，相同行忽略
+func Diff(from, to string) string {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(from, to)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines)
+
+	out := bytes.NewBufferString("")
+	for _, diff := range diffs {
+		prefix := ""
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		default:
+			continue
+		}
+		for _, line := range strings.SplitAfter(diff.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			out.WriteString(prefix)
+			out.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				out.WriteString("\n")
+			}
+		}
+	}
+	return out.String()
+}
@@ -46,6 +46,10 @@ func (w WrapError) Error() string {
 	return fmt.Sprintf("%s : %s", w.Message, w.Err)
 }
 
+func (w WrapError) Unwrap() error {
+	return w.Err
+}
+
 func Wrap(err error, message string) error {
 	if _, match := err.(*WrapError); match {
 		return err
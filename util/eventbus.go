@@ -1,13 +1,58 @@
 package util
 
-import "github.com/asaskevich/EventBus"
+import (
+	"github.com/asaskevich/EventBus"
+	"time"
+)
 
 var ebus = EventBus.New()
 
 const (
-	StorageFileChanged = "storage:file:changed"
+	StorageFileChanged   = "storage:file:changed"
+	ConfigurationChanged = "configuration:changed"
+	NginxLifecycle       = "nginx:lifecycle"
+)
+
+const (
+	ChangeSourceApi     = "api"     //通过api修改
+	ChangeSourceCluster = "cluster" //集群中其他节点修改
+	ChangeSourceLocal   = "local"   //直接修改本地文件
+)
+
+//配置文件变更事件
+type ChangeEvent struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	File    string    `json:"file"`
+	Type    string    `json:"type"` //update, remove
+	User    string    `json:"user,omitempty"`
+	Queries []string  `json:"queries,omitempty"`
+	Diff    string    `json:"diff,omitempty"`
+}
+
+const (
+	EventReload         = "reload"
+	EventTestFailure    = "test-failure"
+	EventReloadRollback = "reload-rollback"
+	EventCertRenewal    = "certificate-renewal"
+	EventCertExpiring   = "certificate-expiring"
+	EventUpstreamChange = "upstream-change"
+	EventUpstreamHealth = "upstream-health"
+	EventExternalEdit   = "external-edit"
+	EventSyncConflict   = "sync-conflict"
+	EventBackup         = "backup"
+	EventLeaderChange   = "leader-change"
 )
 
+//nginx生命周期事件
+type Event struct {
+	Time    time.Time   `json:"time"`
+	Name    string      `json:"name"`
+	Message string      `json:"message,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
 func PublishFileChanged() {
 	ebus.Publish(StorageFileChanged)
 }
@@ -17,3 +62,34 @@ func SubscribeFileChanged(fns ...func() error) {
 		_ = ebus.Subscribe(StorageFileChanged, fn)
 	}
 }
+
+func PublishChanged(event *ChangeEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	ebus.Publish(ConfigurationChanged, event)
+}
+
+//订阅配置变更事件，返回取消订阅的方法。事件是同步发布的，fn不能阻塞
+func SubscribeChanged(fn func(event *ChangeEvent)) (unsubscribe func()) {
+	_ = ebus.Subscribe(ConfigurationChanged, fn)
+	return func() {
+		_ = ebus.Unsubscribe(ConfigurationChanged, fn)
+	}
+}
+
+func PublishEvent(name, message string, err error, data interface{}) {
+	event := &Event{Time: time.Now(), Name: name, Message: message, Data: data}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	ebus.Publish(NginxLifecycle, event)
+}
+
+//订阅nginx生命周期事件，返回取消订阅的方法。事件是同步发布的，fn不能阻塞
+func SubscribeEvent(fn func(event *Event)) (unsubscribe func()) {
+	_ = ebus.Subscribe(NginxLifecycle, fn)
+	return func() {
+		_ = ebus.Unsubscribe(NginxLifecycle, fn)
+	}
+}
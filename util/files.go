@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -120,3 +122,15 @@ func CleanEmptyLine(content []byte) []byte {
 	}
 	return out.Bytes()
 }
+
+var ErrInvalidPath = errors.New("invalid file path")
+
+//配置目录中文件的相对路径：清理后的路径（使用/分隔），不能是绝对路径，也不能使用..跳出配置目录
+func RelativePath(file string) (string, error) {
+	name := path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	if file == "" || path.IsAbs(name) || filepath.IsAbs(file) || filepath.VolumeName(file) != "" ||
+		name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPath, file)
+	}
+	return name, nil
+}
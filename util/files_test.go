@@ -0,0 +1,27 @@
+package util
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRelativePath(t *testing.T) {
+	for file, name := range map[string]string{
+		"nginx.conf":               "nginx.conf",
+		"./conf.d/a.conf":          "conf.d/a.conf",
+		"conf.d//a.conf":           "conf.d/a.conf",
+		"conf.d/../hosts.d/a.conf": "hosts.d/a.conf",
+		"conf.d\\a.conf":           "conf.d/a.conf",
+	} {
+		relative, err := RelativePath(file)
+		assert.Nil(t, err, file)
+		assert.Equal(t, name, relative, file)
+	}
+	for _, file := range []string{
+		"", ".", "..", "../x", "../../etc/passwd", "conf.d/../../x", "/etc/passwd", "..\\x", "\\etc\\passwd",
+	} {
+		_, err := RelativePath(file)
+		assert.True(t, errors.Is(err, ErrInvalidPath), file)
+	}
+}
@@ -1,12 +1,25 @@
 package util
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
+//参数对应的环境变量，例如：expose-port 对应 AGINX_EXPOSE_PORT
+func EnvKey(key string) string {
+	return "AGINX_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
 func GetStringArray(cmd *cobra.Command, key string) []string {
+	//环境变量优先于命令行参数，多个值使用空格分隔
+	if os.Getenv(EnvKey(key)) != "" {
+		return viper.GetStringSlice(key)
+	}
 	services := viper.GetStringSlice(key)
 	if len(services) == 1 &&
 		strings.HasPrefix(services[0], "[") && strings.HasSuffix(services[0], "]") {
@@ -18,3 +31,15 @@ func GetStringArray(cmd *cobra.Command, key string) []string {
 	}
 	return services
 }
+
+//间隔时间，支持天：1d, 7d
+func ParseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval: %s", value)
+		}
+		return time.Hour * 24 * time.Duration(days), nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+func certPool(caFile string) (*x509.CertPool, error) {
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return pool, nil
+}
+
+//服务端TLS配置，clientCA不为空时验证客户端证书
+func ServerTLSConfig(certFile, keyFile, clientCA string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCA != "" {
+		if cfg.ClientCAs, err = certPool(clientCA); err != nil {
+			return nil, err
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+//客户端TLS配置，ca为空时不验证服务端证书，certFile不为空时使用客户端证书
+func ClientTLSConfig(ca, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: ca == ""}
+	if ca != "" {
+		var err error
+		if cfg.RootCAs, err = certPool(ca); err != nil {
+			return nil, err
+		}
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
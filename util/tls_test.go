@@ -0,0 +1,128 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+//生成证书并写入dir，parent为空时生成自签名的CA
+func newTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	tc := &testCert{cert: cert, key: key,
+		certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+"-key.pem")}
+	assert.Nil(t, ioutil.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.Nil(t, ioutil.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return tc
+}
+
+//服务端设置clientCA后，只接受该CA签发的客户端证书
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aginx-tls")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	ca := newTestCert(t, dir, "ca", nil)
+	otherCA := newTestCert(t, dir, "other-ca", nil)
+	server := newTestCert(t, dir, "server", ca)
+	client := newTestCert(t, dir, "client", ca)
+	otherClient := newTestCert(t, dir, "other-client", otherCA)
+
+	serverTLS, err := ServerTLSConfig(server.certFile, server.keyFile, ca.certFile)
+	assert.Nil(t, err)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = serverTLS
+	ts.StartTLS()
+	defer ts.Close()
+
+	get := func(certFile, keyFile string) (string, error) {
+		clientTLS, err := ClientTLSConfig(ca.certFile, certFile, keyFile)
+		if err != nil {
+			return "", err
+		}
+		//证书不是服务端要求的CA签发时，客户端默认不发送证书，这里总是发送以验证服务端的检查
+		if len(clientTLS.Certificates) > 0 {
+			cert := clientTLS.Certificates[0]
+			clientTLS.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &cert, nil
+			}
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	t.Run("no client certificate", func(t *testing.T) {
+		_, err := get("", "")
+		assert.NotNil(t, err)
+	})
+	t.Run("certificate of other CA", func(t *testing.T) {
+		_, err := get(otherClient.certFile, otherClient.keyFile)
+		assert.NotNil(t, err)
+	})
+	t.Run("valid certificate", func(t *testing.T) {
+		body, err := get(client.certFile, client.keyFile)
+		assert.Nil(t, err)
+		assert.Equal(t, "client", body)
+	})
+
+	//不设置clientCA时不验证客户端证书
+	serverTLS, err = ServerTLSConfig(server.certFile, server.keyFile, "")
+	assert.Nil(t, err)
+	assert.Nil(t, serverTLS.ClientCAs)
+}